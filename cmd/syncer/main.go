@@ -22,6 +22,11 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/prometheus"
@@ -31,8 +36,11 @@ import (
 	cnstypes "github.com/vmware/govmomi/cns/types"
 
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/debugserver"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/tracing"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common/commonco"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
 	k8s "sigs.k8s.io/vsphere-csi-driver/v2/pkg/kubernetes"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/syncer"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/syncer/admissionhandler"
@@ -51,6 +59,15 @@ var (
 	enableLeaderElection    = flag.Bool("leader-election", false, "Enable leader election.")
 	leaderElectionNamespace = flag.String("leader-election-namespace", "",
 		"Namespace where the leader election resource lives. Defaults to the pod namespace if not set.")
+	leaderElectionLeaseDuration = flag.Duration("leader-election-lease-duration", 15*time.Second,
+		"Duration that non-leader candidates will wait to force acquire leadership.")
+	leaderElectionRenewDeadline = flag.Duration("leader-election-renew-deadline", 10*time.Second,
+		"Duration that the acting leader will retry refreshing leadership before giving up.")
+	leaderElectionRetryPeriod = flag.Duration("leader-election-retry-period", 5*time.Second,
+		"Duration the leader election clients should wait between tries of actions.")
+	warmStandby = flag.Bool("warm-standby", false,
+		"Keep the informer caches and vCenter session of non-leader instances warm, to take over faster. "+
+			"Requires leader election.")
 	printVersion  = flag.Bool("version", false, "Print syncer version and exit")
 	operationMode = flag.String("operation-mode", operationModeMetaDataSync,
 		"specify operation mode METADATA_SYNC or WEBHOOK_SERVER")
@@ -79,6 +96,7 @@ func main() {
 	clusterFlavor, err := config.GetClusterFlavor(ctx)
 	if err != nil {
 		log.Errorf("Failed retrieving cluster flavor. Error: %v", err)
+		os.Exit(1)
 	}
 	commonco.SetInitParams(ctx, clusterFlavor, &syncer.COInitParams, *supervisorFSSName, *supervisorFSSNamespace,
 		*internalFSSName, *internalFSSNamespace, "")
@@ -92,6 +110,12 @@ func main() {
 	} else if *operationMode == operationModeMetaDataSync {
 		log.Infof("Starting container with operation mode: %v", operationModeMetaDataSync)
 		var err error
+		shutdownTracing := tracing.Init(ctx, "vsphere-syncer")
+		defer func() {
+			if err := shutdownTracing(ctx); err != nil {
+				log.Warnf("Failed to export the pending spans. Error: %v", err)
+			}
+		}()
 
 		// run will be executed if this instance is elected as the leader
 		// or if leader election is not enabled.
@@ -108,6 +132,10 @@ func main() {
 			}()
 		}
 
+		// Serve the liveness and readiness of the syncer on the metrics http
+		// server.
+		http.HandleFunc(syncer.HealthzPath, syncer.HealthzHandler)
+		http.HandleFunc(syncer.ReadyzPath, syncer.ReadyzHandler)
 		// Go module to keep the metrics http server running all the time.
 		go func() {
 			prometheus.SyncerInfo.WithLabelValues(syncer.Version).Set(1)
@@ -122,6 +150,12 @@ func main() {
 			}
 		}()
 
+		// Serve the Kubernetes objects using a volume handle, if enabled.
+		syncer.ServeVolumeOwners(ctx)
+
+		// Serve the debug endpoints, if enabled.
+		debugserver.Serve(ctx, syncer.DebugHandlers())
+
 		// Initialize syncer components that are dependant on the outcome of
 		// leader election, if enabled.
 		run = initSyncerComponents(ctx, clusterFlavor, &syncer.COInitParams)
@@ -134,7 +168,33 @@ func main() {
 				log.Fatalf("Creating Kubernetes client failed. Err: %v", err)
 			}
 			lockName := "vsphere-syncer"
+			if csitypes.Name != csitypes.DefaultName {
+				// Keep the lock of each driver instance distinct.
+				lockName += "-" + strings.ReplaceAll(csitypes.Name, ".", "-")
+			}
+			var leading int32
+			leaderRun := run
+			run = func(ctx context.Context) {
+				atomic.StoreInt32(&leading, 1)
+				leaderRun(ctx)
+			}
+			exitOnTermination(ctx, &leading)
+			if *warmStandby {
+				standby, err := syncer.StartWarmStandby(ctx, clusterFlavor)
+				if err != nil {
+					log.Errorf("Failed to start warm standby, waiting for leadership cold. Err: %v", err)
+				} else {
+					electedRun := run
+					run = func(ctx context.Context) {
+						standby.TakeOver(ctx)
+						electedRun(ctx)
+					}
+				}
+			}
 			le := leaderelection.NewLeaderElection(k8sClient, lockName, run)
+			le.WithLeaseDuration(*leaderElectionLeaseDuration)
+			le.WithRenewDeadline(*leaderElectionRenewDeadline)
+			le.WithRetryPeriod(*leaderElectionRetryPeriod)
 
 			if *leaderElectionNamespace != "" {
 				le.WithNamespace(*leaderElectionNamespace)
@@ -186,5 +246,27 @@ func initSyncerComponents(ctx context.Context, clusterFlavor cnstypes.CnsCluster
 			log.Errorf("Error initializing Metadata Syncer. Error: %+v", err)
 			os.Exit(1)
 		}
+		// The metadata syncer returns once it drained its work on termination.
+		os.Exit(0)
 	}
 }
+
+// exitOnTermination exits on SIGTERM or SIGINT if this instance isn't
+// leading, as it has no work to drain. The leader exits once the metadata
+// syncer drained its work, or after the shutdown timeout if it doesn't.
+func exitOnTermination(ctx context.Context, leading *int32) {
+	log := logger.GetLogger(ctx)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	go func() {
+		sig := <-signals
+		if atomic.LoadInt32(leading) == 0 {
+			log.Infof("Received signal %v while not leading, exiting", sig)
+			os.Exit(0)
+		}
+		timeout := common.GetShutdownTimeout(ctx) + common.VCLogoutTimeout
+		time.Sleep(timeout)
+		log.Errorf("Metadata syncer didn't shut down within %v of signal %v, exiting", timeout, sig)
+		os.Exit(1)
+	}()
+}
@@ -0,0 +1,68 @@
+//go:build darwin || linux
+// +build darwin linux
+
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/mounter"
+)
+
+var (
+	printVersion = flag.Bool("version", false, "Print mount helper version and exit")
+	socket       = flag.String("socket", "/var/lib/kubelet/plugins/csi.vsphere.vmware.com/mount-helper.sock",
+		"Unix socket on which the mount helper listens for requests from the vSphere CSI node plugin")
+)
+
+// main runs the host level mount helper used by the vSphere CSI node plugin
+// on distributions where mounting from within a container is not possible.
+func main() {
+	flag.Parse()
+	if *printVersion {
+		fmt.Printf("%s\n", service.Version)
+		return
+	}
+	logType := logger.LogLevel(os.Getenv(logger.EnvLoggerLevel))
+	logger.SetLoggerLevel(logType)
+	ctx, log := logger.GetNewContextWithLogger()
+	log.Infof("Version : %s", service.Version)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		log.Infof("Received signal %v. Stopping mount helper.", sig)
+		cancel()
+	}()
+
+	if err := mounter.ServeMountHelper(ctx, *socket); err != nil {
+		log.Errorf("Mount helper failed. Err: %v", err)
+		os.Exit(1)
+	}
+}
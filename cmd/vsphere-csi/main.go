@@ -24,6 +24,7 @@ import (
 
 	"github.com/rexray/gocsi"
 	csiconfig "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/tracing"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/provider"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common/commonco"
@@ -41,6 +42,8 @@ var (
 	internalFSSName      = flag.String("fss-name", "", "Name of the feature state switch configmap")
 	internalFSSNamespace = flag.String("fss-namespace", "", "Namespace of the feature state switch configmap")
 	useGocsi             = flag.Bool("use-gocsi", true, "Flag to specify to use gocsi or not")
+	kubeletRootDir       = flag.String("kubelet-root-dir", "",
+		"Root directory of kubelet on the nodes. Discovered from the paths passed by kubelet if not set")
 )
 
 // main is ignored when this package is built as a go plug-in.
@@ -59,10 +62,18 @@ func main() {
 	clusterFlavor, err := csiconfig.GetClusterFlavor(ctx)
 	if err != nil {
 		log.Errorf("Failed retrieving cluster flavor. Error: %v", err)
+		os.Exit(1)
 	}
 	serviceMode := os.Getenv(csitypes.EnvVarMode)
 	commonco.SetInitParams(ctx, clusterFlavor, &service.COInitParams, *supervisorFSSName, *supervisorFSSNamespace,
 		*internalFSSName, *internalFSSNamespace, serviceMode)
+	service.KubeletRootDir = *kubeletRootDir
+	shutdownTracing := tracing.Init(ctx, "vsphere-csi")
+	defer func() {
+		if err := shutdownTracing(ctx); err != nil {
+			log.Warnf("Failed to export the pending spans. Error: %v", err)
+		}
+	}()
 
 	if *useGocsi {
 		const usage = `VSPHERE_CSI_CONFIG
@@ -52,6 +52,8 @@ GOPROXY="${GOPROXY:-https://proxy.golang.org}"
 BUILD_RELEASE_TYPE="${BUILD_RELEASE_TYPE:-}"
 
 ARCH=amd64
+# Architectures of the Linux driver images, e.g. "amd64 arm64".
+LINUX_ARCHS=(${LINUX_ARCHS:-amd64 arm64})
 OSVERSION=1809
 # OS Version for the Windows images: 1809, 1903, 1909 2004, 20H2, ltsc2022
 OSVERSION_WIN=(1809 1903 1909 2004 20H2 ltsc2022)
@@ -83,6 +85,7 @@ usage: ${0} [FLAGS]
   GCR_KEY_FILE
   GOPROXY
   BUILD_RELEASE_TYPE
+  LINUX_ARCHS
 
 FLAGS
   -h    show this help and exit
@@ -137,19 +140,22 @@ function build_driver_images_windows() {
 }
 
 function build_driver_images_linux() {
-  echo "building ${CSI_IMAGE_NAME}:${VERSION} for linux"
   docker buildx rm vsphere-csi-builder-win || echo "builder instance not found, safe to proceed"
-  tag="${CSI_IMAGE_NAME}-linux-${ARCH}:${VERSION}"
-  docker buildx build \
-   --platform "linux/$ARCH" \
-   --output "${LINUX_IMAGE_OUTPUT}" \
-   --file images/driver/Dockerfile \
-   --tag "${tag}" \
-   --build-arg ARCH=amd64 \
-   --build-arg "VERSION=${VERSION}" \
-   --build-arg "GOPROXY=${GOPROXY}" \
-   --build-arg "GIT_COMMIT=${GIT_COMMIT}" \
-   .
+  for linux_arch in "${LINUX_ARCHS[@]}"
+  do
+    echo "building ${CSI_IMAGE_NAME}:${VERSION} for linux/${linux_arch}"
+    tag="${CSI_IMAGE_NAME}-linux-${linux_arch}:${VERSION}"
+    docker buildx build \
+     --platform "linux/${linux_arch}" \
+     --output "${LINUX_IMAGE_OUTPUT}" \
+     --file images/driver/Dockerfile \
+     --tag "${tag}" \
+     --build-arg "ARCH=${linux_arch}" \
+     --build-arg "VERSION=${VERSION}" \
+     --build-arg "GOPROXY=${GOPROXY}" \
+     --build-arg "GIT_COMMIT=${GIT_COMMIT}" \
+     .
+  done
 }
 
 function build_syncer_image_linux() {
@@ -201,13 +207,16 @@ function push_manifest_driver() {
   IMAGE_TAG_LATEST="${CSI_IMAGE_NAME}":latest
 
   echo "creating manifest ${IMAGE_TAG}"
-  linux_tags="${CSI_IMAGE_NAME}-linux-${ARCH}:${VERSION}"
+  all_tags=()
+  for linux_arch in "${LINUX_ARCHS[@]}"
+  do
+    all_tags+=( "${CSI_IMAGE_NAME}-linux-${linux_arch}:${VERSION}" )
+  done
   for OSVERSION in "${OSVERSION_WIN[@]}"
   do 
     osv=$(lcase "${OSVERSION}")
     all_tags+=( "${CSI_IMAGE_NAME}-windows-${osv}-${ARCH}:${VERSION}" )
   done
-  all_tags+=( "${linux_tags}" )
   docker manifest create --amend "${IMAGE_TAG}" "${all_tags[@]}"
 
   # add "os.version" field to windows images (based on https://github.com/kubernetes/kubernetes/blob/master/build/pause/Makefile)
@@ -0,0 +1,60 @@
+#!/bin/bash
+
+# Copyright 2022 The Kubernetes Authors.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# Runs the compatibility matrix probe against each testbed of the matrix
+# described by $COMPATIBILITY_MATRIX, and records the features found working
+# or failing per vCenter and ESX version in $COMPATIBILITY_REPORT_PATH.
+#
+# Each non-empty line of the matrix file, other than comments, describes a
+# testbed as:
+#   <name> <kubeconfig> <e2e test config file> <shared datastore URL>
+
+set -o errexit
+set -o nounset
+set -o pipefail
+
+if [ -z "${COMPATIBILITY_MATRIX-}" ]; then
+    echo "COMPATIBILITY_MATRIX must be set to the path of the matrix file"
+    exit 1
+fi
+
+# The e2e tests run from their package directory, so the report path is made
+# absolute.
+COMPATIBILITY_REPORT_PATH=${COMPATIBILITY_REPORT_PATH:-compatibility-report.json}
+if [[ "$COMPATIBILITY_REPORT_PATH" != /* ]]; then
+    COMPATIBILITY_REPORT_PATH="$(pwd)/$COMPATIBILITY_REPORT_PATH"
+fi
+export COMPATIBILITY_REPORT_PATH
+export CLUSTER_FLAVOR=VANILLA
+export GINKGO_FOCUS="csi-compatibility-matrix"
+
+FAILED=()
+while read -r NAME TESTBED_KUBECONFIG TESTBED_CONF DATASTORE_URL; do
+    if [ -z "$NAME" ] || [[ "$NAME" == \#* ]]; then
+        continue
+    fi
+    echo "Probing testbed $NAME"
+    if ! KUBECONFIG="$TESTBED_KUBECONFIG" E2E_TEST_CONF_FILE="$TESTBED_CONF" \
+        SHARED_VSPHERE_DATASTORE_URL="$DATASTORE_URL" hack/run-e2e-test.sh </dev/null; then
+        FAILED+=("$NAME")
+    fi
+done < "$COMPATIBILITY_MATRIX"
+
+echo "Compatibility report written to $COMPATIBILITY_REPORT_PATH"
+if [ ${#FAILED[@]} -ne 0 ]; then
+    echo "Failed to probe testbeds: ${FAILED[*]}"
+    exit 1
+fi
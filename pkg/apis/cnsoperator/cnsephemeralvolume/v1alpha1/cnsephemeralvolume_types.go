@@ -0,0 +1,83 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CnsEphemeralVolumeSpec defines the desired state of CnsEphemeralVolume
+// +k8s:openapi-gen=true
+type CnsEphemeralVolumeSpec struct {
+	// NodeName is the name of the node the volume is attached to.
+	NodeName string `json:"nodeName"`
+
+	// CapacityInBytes is the size of the volume. The default volume size is
+	// used if it isn't set.
+	CapacityInBytes int64 `json:"capacityInBytes,omitempty"`
+
+	// Parameters are the StorageClass parameters the volume is created with,
+	// e.g. storagepolicyname.
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+// CnsEphemeralVolumeStatus defines the observed state of CnsEphemeralVolume
+// +k8s:openapi-gen=true
+type CnsEphemeralVolumeStatus struct {
+	// VolumeID is the ID of the CNS volume created for the ephemeral inline
+	// volume.
+	VolumeID string `json:"volumeID,omitempty"`
+
+	// Indicates the volume is attached to the node in the spec.
+	// This field must only be set by the entity completing the attach
+	// operation, i.e. the CNS Operator.
+	Attached bool `json:"attached"`
+
+	// DiskUUID is the UUID of the disk of the volume on the node, set once
+	// the volume is attached.
+	DiskUUID string `json:"diskUUID,omitempty"`
+
+	// The last error encountered while provisioning the volume, if any.
+	// This field must only be set by the entity provisioning the volume, i.e.
+	// the CNS Operator.
+	Error string `json:"error,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CnsEphemeralVolume is the Schema for the cnsephemeralvolumes API. The node
+// plugin creates an instance for every CSI ephemeral inline volume it
+// publishes, named after the volume ID assigned by kubelet, and deletes it
+// once the volume is unpublished.
+// +k8s:openapi-gen=true
+// +kubebuilder:subresource:status
+type CnsEphemeralVolume struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CnsEphemeralVolumeSpec   `json:"spec,omitempty"`
+	Status CnsEphemeralVolumeStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CnsEphemeralVolumeList contains a list of CnsEphemeralVolume
+type CnsEphemeralVolumeList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CnsEphemeralVolume `json:"items"`
+}
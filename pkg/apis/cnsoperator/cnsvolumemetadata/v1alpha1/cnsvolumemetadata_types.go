@@ -141,9 +141,8 @@ func CreateCnsVolumeMetadataSpec(volumeHandle []string, gcConfig config.GCConfig
 	reference []CnsOperatorEntityReference) *CnsVolumeMetadata {
 	return &CnsVolumeMetadata{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: GetCnsVolumeMetadataName(gcConfig.TanzuKubernetesClusterUID, uid),
-			OwnerReferences: []metav1.OwnerReference{GetCnsVolumeMetadataOwnerReference(cnsoperatortypes.GCAPIVersion,
-				cnsoperatortypes.GCKind, gcConfig.TanzuKubernetesClusterName, gcConfig.TanzuKubernetesClusterUID)},
+			Name:            GetCnsVolumeMetadataName(gcConfig.TanzuKubernetesClusterUID, uid),
+			OwnerReferences: []metav1.OwnerReference{GetGuestClusterOwnerReference(gcConfig)},
 		},
 		Spec: CnsVolumeMetadataSpec{
 			VolumeNames:         volumeHandle,
@@ -186,6 +185,24 @@ func GetCnsOperatorVolumeStatus(volumeName string, errorMessage string) CnsVolum
 	}
 }
 
+// GetGuestClusterOwnerReference returns the owner reference pointing to the
+// supervisor object which represents the guest cluster. TanzuKubernetesCluster
+// is assumed unless the guest cluster config specifies a different kind, as
+// is the case for ClusterClass based clusters.
+func GetGuestClusterOwnerReference(gcConfig config.GCConfig) metav1.OwnerReference {
+	apiVersion := cnsoperatortypes.GCAPIVersion
+	kind := cnsoperatortypes.GCKind
+	if gcConfig.ClusterKind != "" {
+		kind = gcConfig.ClusterKind
+		apiVersion = gcConfig.ClusterAPIVersion
+		if apiVersion == "" && kind == cnsoperatortypes.ClusterKind {
+			apiVersion = cnsoperatortypes.ClusterAPIVersion
+		}
+	}
+	return GetCnsVolumeMetadataOwnerReference(apiVersion, kind, gcConfig.TanzuKubernetesClusterName,
+		gcConfig.TanzuKubernetesClusterUID)
+}
+
 // GetCnsVolumeMetadataOwnerReference returns the owner reference object from
 // the input parameters.
 func GetCnsVolumeMetadataOwnerReference(apiVersion string, kind string, clusterName string,
@@ -0,0 +1,64 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
+	cnsoperatortypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/syncer/cnsoperator/types"
+)
+
+func TestGetGuestClusterOwnerReference(t *testing.T) {
+	for _, test := range []struct {
+		name               string
+		clusterAPIVersion  string
+		clusterKind        string
+		expectedAPIVersion string
+		expectedKind       string
+	}{
+		{"TanzuKubernetesCluster by default", "", "", cnsoperatortypes.GCAPIVersion, cnsoperatortypes.GCKind},
+		{"ClusterClass based cluster", "", cnsoperatortypes.ClusterKind, cnsoperatortypes.ClusterAPIVersion,
+			cnsoperatortypes.ClusterKind},
+		{"ClusterClass based cluster with an APIVersion", "cluster.x-k8s.io/v1alpha4", cnsoperatortypes.ClusterKind,
+			"cluster.x-k8s.io/v1alpha4", cnsoperatortypes.ClusterKind},
+		{"APIVersion without a kind", "cluster.x-k8s.io/v1beta1", "", cnsoperatortypes.GCAPIVersion,
+			cnsoperatortypes.GCKind},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			gcConfig := config.GCConfig{
+				TanzuKubernetesClusterName: "gc",
+				TanzuKubernetesClusterUID:  "gc-uid",
+				ClusterAPIVersion:          test.clusterAPIVersion,
+				ClusterKind:                test.clusterKind,
+			}
+			ownerRef := GetGuestClusterOwnerReference(gcConfig)
+			if ownerRef.APIVersion != test.expectedAPIVersion || ownerRef.Kind != test.expectedKind {
+				t.Errorf("expected owner %s %s, got %s %s", test.expectedAPIVersion, test.expectedKind,
+					ownerRef.APIVersion, ownerRef.Kind)
+			}
+			if ownerRef.Name != "gc" || ownerRef.UID != types.UID("gc-uid") {
+				t.Errorf("unexpected owner name %q and UID %q", ownerRef.Name, ownerRef.UID)
+			}
+			if ownerRef.Controller == nil || !*ownerRef.Controller {
+				t.Errorf("expected the guest cluster to be the controller of the instance")
+			}
+		})
+	}
+}
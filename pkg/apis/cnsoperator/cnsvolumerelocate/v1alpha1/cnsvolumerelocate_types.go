@@ -0,0 +1,73 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CnsVolumeRelocateSpec defines the desired state of CnsVolumeRelocate
+// +k8s:openapi-gen=true
+type CnsVolumeRelocateSpec struct {
+	// Name of the PVC bound to the volume to be relocated. The PVC must be in
+	// the namespace of the CnsVolumeRelocate instance.
+	PvcName string `json:"pvcName"`
+
+	// DatastoreURL is the URL of the datastore the volume is relocated to.
+	DatastoreURL string `json:"datastoreURL"`
+}
+
+// CnsVolumeRelocateStatus defines the observed state of CnsVolumeRelocate
+// +k8s:openapi-gen=true
+type CnsVolumeRelocateStatus struct {
+	// Indicates the volume is successfully relocated to the datastore in the
+	// spec.
+	// This field must only be set by the entity completing the relocate
+	// operation, i.e. the CNS Operator.
+	Relocated bool `json:"relocated"`
+
+	// SourceDatastoreURL is the URL of the datastore the volume was on before
+	// it got relocated.
+	SourceDatastoreURL string `json:"sourceDatastoreURL,omitempty"`
+
+	// The last error encountered during relocate operation, if any.
+	// This field must only be set by the entity completing the relocate
+	// operation, i.e. the CNS Operator.
+	Error string `json:"error,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CnsVolumeRelocate is the Schema for the cnsvolumerelocates API
+// +k8s:openapi-gen=true
+// +kubebuilder:subresource:status
+type CnsVolumeRelocate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CnsVolumeRelocateSpec   `json:"spec,omitempty"`
+	Status CnsVolumeRelocateStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CnsVolumeRelocateList contains a list of CnsVolumeRelocate
+type CnsVolumeRelocateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CnsVolumeRelocate `json:"items"`
+}
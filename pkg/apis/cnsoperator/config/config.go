@@ -21,3 +21,13 @@ const EmbedCnsFileAccessConfigCRFileName = "cnsfileaccessconfig_crd.yaml"
 var EmbedCnsRegisterVolumeCRFile embed.FS
 
 const EmbedCnsRegisterVolumeCRFileName = "cnsregistervolume_crd.yaml"
+
+//go:embed cnsvolumerelocate_crd.yaml
+var EmbedCnsVolumeRelocateCRFile embed.FS
+
+const EmbedCnsVolumeRelocateCRFileName = "cnsvolumerelocate_crd.yaml"
+
+//go:embed cnsephemeralvolume_crd.yaml
+var EmbedCnsEphemeralVolumeCRFile embed.FS
+
+const EmbedCnsEphemeralVolumeCRFileName = "cnsephemeralvolume_crd.yaml"
@@ -25,10 +25,12 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	cnsephemeralvolumev1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsephemeralvolume/v1alpha1"
 	cnsfileaccessconfigv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsfileaccessconfig/v1alpha1"
 	cnsnodevmattachmentv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsnodevmattachment/v1alpha1"
 	cnsregistervolumev1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsregistervolume/v1alpha1"
 	cnsvolumemetadatav1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsvolumemetadata/v1alpha1"
+	cnsvolumerelocatev1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsvolumerelocate/v1alpha1"
 )
 
 // GroupName represents the group for cns operator apis
@@ -52,6 +54,10 @@ var (
 	CnsRegisterVolumePlural = "cnsregistervolumes"
 	// CnsFileAccessConfigPlural is plural of CnsFileAccessConfig
 	CnsFileAccessConfigPlural = "cnsfileaccessconfigs"
+	// CnsVolumeRelocatePlural is plural of CnsVolumeRelocate
+	CnsVolumeRelocatePlural = "cnsvolumerelocates"
+	// CnsEphemeralVolumePlural is plural of CnsEphemeralVolume
+	CnsEphemeralVolumePlural = "cnsephemeralvolumes"
 )
 
 var (
@@ -98,6 +104,18 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&cnsnodevmattachmentv1alpha1.CnsNodeVmAttachmentList{},
 	)
 
+	scheme.AddKnownTypes(
+		SchemeGroupVersion,
+		&cnsvolumerelocatev1alpha1.CnsVolumeRelocate{},
+		&cnsvolumerelocatev1alpha1.CnsVolumeRelocateList{},
+	)
+
+	scheme.AddKnownTypes(
+		SchemeGroupVersion,
+		&cnsephemeralvolumev1alpha1.CnsEphemeralVolume{},
+		&cnsephemeralvolumev1alpha1.CnsEphemeralVolumeList{},
+	)
+
 	scheme.AddKnownTypes(
 		SchemeGroupVersion,
 		&metav1.Status{},
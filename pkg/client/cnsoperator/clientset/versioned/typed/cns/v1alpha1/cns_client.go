@@ -0,0 +1,98 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	rest "k8s.io/client-go/rest"
+
+	cnsoperatorv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/client/cnsoperator/clientset/versioned/scheme"
+)
+
+// CnsV1alpha1Interface is the interface of the client of the
+// cns.vmware.com/v1alpha1 API group version.
+type CnsV1alpha1Interface interface {
+	RESTClient() rest.Interface
+	CnsNodeVmAttachmentsGetter
+	CnsVolumeMetadatasGetter
+}
+
+// CnsV1alpha1Client is used to interact with features provided by the
+// cns.vmware.com group.
+type CnsV1alpha1Client struct {
+	restClient rest.Interface
+}
+
+// CnsNodeVmAttachments returns the CnsNodeVmAttachmentInterface of the given namespace.
+func (c *CnsV1alpha1Client) CnsNodeVmAttachments(namespace string) CnsNodeVmAttachmentInterface {
+	return newCnsNodeVmAttachments(c, namespace)
+}
+
+// CnsVolumeMetadatas returns the CnsVolumeMetadataInterface of the given namespace.
+func (c *CnsV1alpha1Client) CnsVolumeMetadatas(namespace string) CnsVolumeMetadataInterface {
+	return newCnsVolumeMetadatas(c, namespace)
+}
+
+// NewForConfig creates a new CnsV1alpha1Client for the given config.
+func NewForConfig(c *rest.Config) (*CnsV1alpha1Client, error) {
+	config := *c
+	if err := setConfigDefaults(&config); err != nil {
+		return nil, err
+	}
+	client, err := rest.RESTClientFor(&config)
+	if err != nil {
+		return nil, err
+	}
+	return &CnsV1alpha1Client{client}, nil
+}
+
+// NewForConfigOrDie creates a new CnsV1alpha1Client for the given config and
+// panics if there is an error in the config.
+func NewForConfigOrDie(c *rest.Config) *CnsV1alpha1Client {
+	client, err := NewForConfig(c)
+	if err != nil {
+		panic(err)
+	}
+	return client
+}
+
+// New creates a new CnsV1alpha1Client for the given RESTClient.
+func New(c rest.Interface) *CnsV1alpha1Client {
+	return &CnsV1alpha1Client{c}
+}
+
+func setConfigDefaults(config *rest.Config) error {
+	gv := cnsoperatorv1alpha1.SchemeGroupVersion
+	config.GroupVersion = &gv
+	config.APIPath = "/apis"
+	config.NegotiatedSerializer = scheme.Codecs.WithoutConversion()
+
+	if config.UserAgent == "" {
+		config.UserAgent = rest.DefaultKubernetesUserAgent()
+	}
+
+	return nil
+}
+
+// RESTClient returns a RESTClient that is used to communicate
+// with API server by this client implementation.
+func (c *CnsV1alpha1Client) RESTClient() rest.Interface {
+	if c == nil {
+		return nil
+	}
+	return c.restClient
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"time"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+
+	attachmentv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsnodevmattachment/v1alpha1"
+	scheme "sigs.k8s.io/vsphere-csi-driver/v2/pkg/client/cnsoperator/clientset/versioned/scheme"
+)
+
+// CnsNodeVmAttachmentsGetter has a method to return a CnsNodeVmAttachmentInterface.
+// A group's client should implement this interface.
+type CnsNodeVmAttachmentsGetter interface {
+	CnsNodeVmAttachments(namespace string) CnsNodeVmAttachmentInterface
+}
+
+// CnsNodeVmAttachmentInterface has methods to work with CnsNodeVmAttachment resources.
+type CnsNodeVmAttachmentInterface interface {
+	Create(ctx context.Context, cnsNodeVmAttachment *attachmentv1alpha1.CnsNodeVmAttachment,
+		opts v1.CreateOptions) (*attachmentv1alpha1.CnsNodeVmAttachment, error)
+	Update(ctx context.Context, cnsNodeVmAttachment *attachmentv1alpha1.CnsNodeVmAttachment,
+		opts v1.UpdateOptions) (*attachmentv1alpha1.CnsNodeVmAttachment, error)
+	UpdateStatus(ctx context.Context, cnsNodeVmAttachment *attachmentv1alpha1.CnsNodeVmAttachment,
+		opts v1.UpdateOptions) (*attachmentv1alpha1.CnsNodeVmAttachment, error)
+	Delete(ctx context.Context, name string, opts v1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error
+	Get(ctx context.Context, name string, opts v1.GetOptions) (*attachmentv1alpha1.CnsNodeVmAttachment, error)
+	List(ctx context.Context, opts v1.ListOptions) (*attachmentv1alpha1.CnsNodeVmAttachmentList, error)
+	Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions,
+		subresources ...string) (result *attachmentv1alpha1.CnsNodeVmAttachment, err error)
+	CnsNodeVmAttachmentExpansion
+}
+
+// cnsNodeVmAttachments implements CnsNodeVmAttachmentInterface
+type cnsNodeVmAttachments struct {
+	client rest.Interface
+	ns     string
+}
+
+// newCnsNodeVmAttachments returns a CnsNodeVmAttachments
+func newCnsNodeVmAttachments(c *CnsV1alpha1Client, namespace string) *cnsNodeVmAttachments {
+	return &cnsNodeVmAttachments{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Get takes name of the cnsNodeVmAttachment, and returns the corresponding cnsNodeVmAttachment
+// object, and an error if there is any.
+func (c *cnsNodeVmAttachments) Get(ctx context.Context, name string,
+	options v1.GetOptions) (result *attachmentv1alpha1.CnsNodeVmAttachment, err error) {
+	result = &attachmentv1alpha1.CnsNodeVmAttachment{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("cnsnodevmattachments").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of CnsNodeVmAttachments
+// that match those selectors.
+func (c *cnsNodeVmAttachments) List(ctx context.Context,
+	opts v1.ListOptions) (result *attachmentv1alpha1.CnsNodeVmAttachmentList, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &attachmentv1alpha1.CnsNodeVmAttachmentList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("cnsnodevmattachments").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested cnsNodeVmAttachments.
+func (c *cnsNodeVmAttachments) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("cnsnodevmattachments").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch(ctx)
+}
+
+// Create takes the representation of a cnsNodeVmAttachment and creates it. Returns the
+// server's representation of the cnsNodeVmAttachment, and an error, if there is any.
+func (c *cnsNodeVmAttachments) Create(ctx context.Context,
+	cnsNodeVmAttachment *attachmentv1alpha1.CnsNodeVmAttachment,
+	opts v1.CreateOptions) (result *attachmentv1alpha1.CnsNodeVmAttachment, err error) {
+	result = &attachmentv1alpha1.CnsNodeVmAttachment{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("cnsnodevmattachments").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(cnsNodeVmAttachment).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Update takes the representation of a cnsNodeVmAttachment and updates it. Returns the
+// server's representation of the cnsNodeVmAttachment, and an error, if there is any.
+func (c *cnsNodeVmAttachments) Update(ctx context.Context,
+	cnsNodeVmAttachment *attachmentv1alpha1.CnsNodeVmAttachment,
+	opts v1.UpdateOptions) (result *attachmentv1alpha1.CnsNodeVmAttachment, err error) {
+	result = &attachmentv1alpha1.CnsNodeVmAttachment{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("cnsnodevmattachments").
+		Name(cnsNodeVmAttachment.Name).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(cnsNodeVmAttachment).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// UpdateStatus updates the status subresource of a cnsNodeVmAttachment. Returns the
+// server's representation of the cnsNodeVmAttachment, and an error, if there is any.
+func (c *cnsNodeVmAttachments) UpdateStatus(ctx context.Context,
+	cnsNodeVmAttachment *attachmentv1alpha1.CnsNodeVmAttachment,
+	opts v1.UpdateOptions) (result *attachmentv1alpha1.CnsNodeVmAttachment, err error) {
+	result = &attachmentv1alpha1.CnsNodeVmAttachment{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("cnsnodevmattachments").
+		Name(cnsNodeVmAttachment.Name).
+		SubResource("status").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(cnsNodeVmAttachment).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Delete takes name of the cnsNodeVmAttachment and deletes it. Returns an error if one
+// occurs.
+func (c *cnsNodeVmAttachments) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("cnsnodevmattachments").
+		Name(name).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *cnsNodeVmAttachments) DeleteCollection(ctx context.Context, opts v1.DeleteOptions,
+	listOpts v1.ListOptions) error {
+	var timeout time.Duration
+	if listOpts.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOpts.TimeoutSeconds) * time.Second
+	}
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("cnsnodevmattachments").
+		VersionedParams(&listOpts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// Patch applies the patch and returns the patched cnsNodeVmAttachment.
+func (c *cnsNodeVmAttachments) Patch(ctx context.Context, name string, pt types.PatchType, data []byte,
+	opts v1.PatchOptions, subresources ...string) (result *attachmentv1alpha1.CnsNodeVmAttachment, err error) {
+	result = &attachmentv1alpha1.CnsNodeVmAttachment{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("cnsnodevmattachments").
+		Name(name).
+		SubResource(subresources...).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(data).
+		Do(ctx).
+		Into(result)
+	return
+}
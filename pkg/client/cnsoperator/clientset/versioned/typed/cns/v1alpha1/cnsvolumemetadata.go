@@ -0,0 +1,213 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"time"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+
+	metadatav1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsvolumemetadata/v1alpha1"
+	scheme "sigs.k8s.io/vsphere-csi-driver/v2/pkg/client/cnsoperator/clientset/versioned/scheme"
+)
+
+// CnsVolumeMetadatasGetter has a method to return a CnsVolumeMetadataInterface.
+// A group's client should implement this interface.
+type CnsVolumeMetadatasGetter interface {
+	CnsVolumeMetadatas(namespace string) CnsVolumeMetadataInterface
+}
+
+// CnsVolumeMetadataInterface has methods to work with CnsVolumeMetadata resources.
+type CnsVolumeMetadataInterface interface {
+	Create(ctx context.Context, cnsVolumeMetadata *metadatav1alpha1.CnsVolumeMetadata,
+		opts v1.CreateOptions) (*metadatav1alpha1.CnsVolumeMetadata, error)
+	Update(ctx context.Context, cnsVolumeMetadata *metadatav1alpha1.CnsVolumeMetadata,
+		opts v1.UpdateOptions) (*metadatav1alpha1.CnsVolumeMetadata, error)
+	UpdateStatus(ctx context.Context, cnsVolumeMetadata *metadatav1alpha1.CnsVolumeMetadata,
+		opts v1.UpdateOptions) (*metadatav1alpha1.CnsVolumeMetadata, error)
+	Delete(ctx context.Context, name string, opts v1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error
+	Get(ctx context.Context, name string, opts v1.GetOptions) (*metadatav1alpha1.CnsVolumeMetadata, error)
+	List(ctx context.Context, opts v1.ListOptions) (*metadatav1alpha1.CnsVolumeMetadataList, error)
+	Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions,
+		subresources ...string) (result *metadatav1alpha1.CnsVolumeMetadata, err error)
+	CnsVolumeMetadataExpansion
+}
+
+// cnsVolumeMetadatas implements CnsVolumeMetadataInterface
+type cnsVolumeMetadatas struct {
+	client rest.Interface
+	ns     string
+}
+
+// newCnsVolumeMetadatas returns a CnsVolumeMetadatas
+func newCnsVolumeMetadatas(c *CnsV1alpha1Client, namespace string) *cnsVolumeMetadatas {
+	return &cnsVolumeMetadatas{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Get takes name of the cnsVolumeMetadata, and returns the corresponding cnsVolumeMetadata
+// object, and an error if there is any.
+func (c *cnsVolumeMetadatas) Get(ctx context.Context, name string,
+	options v1.GetOptions) (result *metadatav1alpha1.CnsVolumeMetadata, err error) {
+	result = &metadatav1alpha1.CnsVolumeMetadata{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("cnsvolumemetadatas").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of CnsVolumeMetadatas
+// that match those selectors.
+func (c *cnsVolumeMetadatas) List(ctx context.Context,
+	opts v1.ListOptions) (result *metadatav1alpha1.CnsVolumeMetadataList, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &metadatav1alpha1.CnsVolumeMetadataList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("cnsvolumemetadatas").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested cnsVolumeMetadatas.
+func (c *cnsVolumeMetadatas) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("cnsvolumemetadatas").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch(ctx)
+}
+
+// Create takes the representation of a cnsVolumeMetadata and creates it. Returns the
+// server's representation of the cnsVolumeMetadata, and an error, if there is any.
+func (c *cnsVolumeMetadatas) Create(ctx context.Context,
+	cnsVolumeMetadata *metadatav1alpha1.CnsVolumeMetadata,
+	opts v1.CreateOptions) (result *metadatav1alpha1.CnsVolumeMetadata, err error) {
+	result = &metadatav1alpha1.CnsVolumeMetadata{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("cnsvolumemetadatas").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(cnsVolumeMetadata).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Update takes the representation of a cnsVolumeMetadata and updates it. Returns the
+// server's representation of the cnsVolumeMetadata, and an error, if there is any.
+func (c *cnsVolumeMetadatas) Update(ctx context.Context,
+	cnsVolumeMetadata *metadatav1alpha1.CnsVolumeMetadata,
+	opts v1.UpdateOptions) (result *metadatav1alpha1.CnsVolumeMetadata, err error) {
+	result = &metadatav1alpha1.CnsVolumeMetadata{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("cnsvolumemetadatas").
+		Name(cnsVolumeMetadata.Name).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(cnsVolumeMetadata).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// UpdateStatus updates the status subresource of a cnsVolumeMetadata. Returns the
+// server's representation of the cnsVolumeMetadata, and an error, if there is any.
+func (c *cnsVolumeMetadatas) UpdateStatus(ctx context.Context,
+	cnsVolumeMetadata *metadatav1alpha1.CnsVolumeMetadata,
+	opts v1.UpdateOptions) (result *metadatav1alpha1.CnsVolumeMetadata, err error) {
+	result = &metadatav1alpha1.CnsVolumeMetadata{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("cnsvolumemetadatas").
+		Name(cnsVolumeMetadata.Name).
+		SubResource("status").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(cnsVolumeMetadata).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Delete takes name of the cnsVolumeMetadata and deletes it. Returns an error if one
+// occurs.
+func (c *cnsVolumeMetadatas) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("cnsvolumemetadatas").
+		Name(name).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *cnsVolumeMetadatas) DeleteCollection(ctx context.Context, opts v1.DeleteOptions,
+	listOpts v1.ListOptions) error {
+	var timeout time.Duration
+	if listOpts.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOpts.TimeoutSeconds) * time.Second
+	}
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("cnsvolumemetadatas").
+		VersionedParams(&listOpts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// Patch applies the patch and returns the patched cnsVolumeMetadata.
+func (c *cnsVolumeMetadatas) Patch(ctx context.Context, name string, pt types.PatchType, data []byte,
+	opts v1.PatchOptions, subresources ...string) (result *metadatav1alpha1.CnsVolumeMetadata, err error) {
+	result = &metadatav1alpha1.CnsVolumeMetadata{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("cnsvolumemetadatas").
+		Name(name).
+		SubResource(subresources...).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(data).
+		Do(ctx).
+		Into(result)
+	return
+}
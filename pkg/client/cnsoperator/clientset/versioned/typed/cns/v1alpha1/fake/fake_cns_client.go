@@ -0,0 +1,47 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	rest "k8s.io/client-go/rest"
+	testing "k8s.io/client-go/testing"
+
+	v1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/client/cnsoperator/clientset/versioned/typed/cns/v1alpha1"
+)
+
+// FakeCnsV1alpha1 is the fake client of the cns.vmware.com/v1alpha1 API group
+// version.
+type FakeCnsV1alpha1 struct {
+	*testing.Fake
+}
+
+// CnsNodeVmAttachments returns the fake CnsNodeVmAttachmentInterface of the given namespace.
+func (c *FakeCnsV1alpha1) CnsNodeVmAttachments(namespace string) v1alpha1.CnsNodeVmAttachmentInterface {
+	return &FakeCnsNodeVmAttachments{c, namespace}
+}
+
+// CnsVolumeMetadatas returns the fake CnsVolumeMetadataInterface of the given namespace.
+func (c *FakeCnsV1alpha1) CnsVolumeMetadatas(namespace string) v1alpha1.CnsVolumeMetadataInterface {
+	return &FakeCnsVolumeMetadatas{c, namespace}
+}
+
+// RESTClient returns a RESTClient that is used to communicate
+// with API server by this client implementation.
+func (c *FakeCnsV1alpha1) RESTClient() rest.Interface {
+	var ret *rest.RESTClient
+	return ret
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+
+	cnsoperatorv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator"
+	attachmentv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsnodevmattachment/v1alpha1"
+)
+
+// FakeCnsNodeVmAttachments implements CnsNodeVmAttachmentInterface
+type FakeCnsNodeVmAttachments struct {
+	Fake *FakeCnsV1alpha1
+	ns   string
+}
+
+var cnsNodeVmAttachmentsResource = cnsoperatorv1alpha1.SchemeGroupVersion.WithResource("cnsnodevmattachments")
+
+var cnsNodeVmAttachmentsKind = cnsoperatorv1alpha1.SchemeGroupVersion.WithKind("CnsNodeVmAttachment")
+
+// Get takes name of the cnsNodeVmAttachment, and returns the corresponding cnsNodeVmAttachment
+// object, and an error if there is any.
+func (c *FakeCnsNodeVmAttachments) Get(ctx context.Context, name string,
+	options v1.GetOptions) (result *attachmentv1alpha1.CnsNodeVmAttachment, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(cnsNodeVmAttachmentsResource, c.ns, name),
+			&attachmentv1alpha1.CnsNodeVmAttachment{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*attachmentv1alpha1.CnsNodeVmAttachment), err
+}
+
+// List takes label and field selectors, and returns the list of CnsNodeVmAttachments
+// that match those selectors.
+func (c *FakeCnsNodeVmAttachments) List(ctx context.Context,
+	opts v1.ListOptions) (result *attachmentv1alpha1.CnsNodeVmAttachmentList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(cnsNodeVmAttachmentsResource, cnsNodeVmAttachmentsKind, c.ns, opts),
+			&attachmentv1alpha1.CnsNodeVmAttachmentList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	objList := obj.(*attachmentv1alpha1.CnsNodeVmAttachmentList)
+	list := &attachmentv1alpha1.CnsNodeVmAttachmentList{ListMeta: objList.ListMeta}
+	for _, item := range objList.Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested cnsNodeVmAttachments.
+func (c *FakeCnsNodeVmAttachments) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(cnsNodeVmAttachmentsResource, c.ns, opts))
+}
+
+// Create takes the representation of a cnsNodeVmAttachment and creates it. Returns the
+// server's representation of the cnsNodeVmAttachment, and an error, if there is any.
+func (c *FakeCnsNodeVmAttachments) Create(ctx context.Context,
+	cnsNodeVmAttachment *attachmentv1alpha1.CnsNodeVmAttachment,
+	opts v1.CreateOptions) (result *attachmentv1alpha1.CnsNodeVmAttachment, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(cnsNodeVmAttachmentsResource, c.ns, cnsNodeVmAttachment),
+			&attachmentv1alpha1.CnsNodeVmAttachment{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*attachmentv1alpha1.CnsNodeVmAttachment), err
+}
+
+// Update takes the representation of a cnsNodeVmAttachment and updates it. Returns the
+// server's representation of the cnsNodeVmAttachment, and an error, if there is any.
+func (c *FakeCnsNodeVmAttachments) Update(ctx context.Context,
+	cnsNodeVmAttachment *attachmentv1alpha1.CnsNodeVmAttachment,
+	opts v1.UpdateOptions) (result *attachmentv1alpha1.CnsNodeVmAttachment, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(cnsNodeVmAttachmentsResource, c.ns, cnsNodeVmAttachment),
+			&attachmentv1alpha1.CnsNodeVmAttachment{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*attachmentv1alpha1.CnsNodeVmAttachment), err
+}
+
+// UpdateStatus updates the status subresource of a cnsNodeVmAttachment. Returns the
+// server's representation of the cnsNodeVmAttachment, and an error, if there is any.
+func (c *FakeCnsNodeVmAttachments) UpdateStatus(ctx context.Context,
+	cnsNodeVmAttachment *attachmentv1alpha1.CnsNodeVmAttachment,
+	opts v1.UpdateOptions) (*attachmentv1alpha1.CnsNodeVmAttachment, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(cnsNodeVmAttachmentsResource, "status", c.ns, cnsNodeVmAttachment),
+			&attachmentv1alpha1.CnsNodeVmAttachment{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*attachmentv1alpha1.CnsNodeVmAttachment), err
+}
+
+// Delete takes name of the cnsNodeVmAttachment and deletes it. Returns an error if one
+// occurs.
+func (c *FakeCnsNodeVmAttachments) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteAction(cnsNodeVmAttachmentsResource, c.ns, name),
+			&attachmentv1alpha1.CnsNodeVmAttachment{})
+
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeCnsNodeVmAttachments) DeleteCollection(ctx context.Context, opts v1.DeleteOptions,
+	listOpts v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(cnsNodeVmAttachmentsResource, c.ns, listOpts)
+
+	_, err := c.Fake.Invokes(action, &attachmentv1alpha1.CnsNodeVmAttachmentList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched cnsNodeVmAttachment.
+func (c *FakeCnsNodeVmAttachments) Patch(ctx context.Context, name string, pt types.PatchType, data []byte,
+	opts v1.PatchOptions, subresources ...string) (result *attachmentv1alpha1.CnsNodeVmAttachment, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(cnsNodeVmAttachmentsResource, c.ns, name, pt, data,
+			subresources...), &attachmentv1alpha1.CnsNodeVmAttachment{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*attachmentv1alpha1.CnsNodeVmAttachment), err
+}
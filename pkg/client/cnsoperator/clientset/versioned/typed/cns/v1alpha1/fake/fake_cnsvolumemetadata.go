@@ -0,0 +1,163 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+
+	cnsoperatorv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator"
+	metadatav1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsvolumemetadata/v1alpha1"
+)
+
+// FakeCnsVolumeMetadatas implements CnsVolumeMetadataInterface
+type FakeCnsVolumeMetadatas struct {
+	Fake *FakeCnsV1alpha1
+	ns   string
+}
+
+var cnsVolumeMetadatasResource = cnsoperatorv1alpha1.SchemeGroupVersion.WithResource("cnsvolumemetadatas")
+
+var cnsVolumeMetadatasKind = cnsoperatorv1alpha1.SchemeGroupVersion.WithKind("CnsVolumeMetadata")
+
+// Get takes name of the cnsVolumeMetadata, and returns the corresponding cnsVolumeMetadata
+// object, and an error if there is any.
+func (c *FakeCnsVolumeMetadatas) Get(ctx context.Context, name string,
+	options v1.GetOptions) (result *metadatav1alpha1.CnsVolumeMetadata, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(cnsVolumeMetadatasResource, c.ns, name),
+			&metadatav1alpha1.CnsVolumeMetadata{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*metadatav1alpha1.CnsVolumeMetadata), err
+}
+
+// List takes label and field selectors, and returns the list of CnsVolumeMetadatas
+// that match those selectors.
+func (c *FakeCnsVolumeMetadatas) List(ctx context.Context,
+	opts v1.ListOptions) (result *metadatav1alpha1.CnsVolumeMetadataList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(cnsVolumeMetadatasResource, cnsVolumeMetadatasKind, c.ns, opts),
+			&metadatav1alpha1.CnsVolumeMetadataList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	objList := obj.(*metadatav1alpha1.CnsVolumeMetadataList)
+	list := &metadatav1alpha1.CnsVolumeMetadataList{ListMeta: objList.ListMeta}
+	for _, item := range objList.Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested cnsVolumeMetadatas.
+func (c *FakeCnsVolumeMetadatas) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(cnsVolumeMetadatasResource, c.ns, opts))
+}
+
+// Create takes the representation of a cnsVolumeMetadata and creates it. Returns the
+// server's representation of the cnsVolumeMetadata, and an error, if there is any.
+func (c *FakeCnsVolumeMetadatas) Create(ctx context.Context,
+	cnsVolumeMetadata *metadatav1alpha1.CnsVolumeMetadata,
+	opts v1.CreateOptions) (result *metadatav1alpha1.CnsVolumeMetadata, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(cnsVolumeMetadatasResource, c.ns, cnsVolumeMetadata),
+			&metadatav1alpha1.CnsVolumeMetadata{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*metadatav1alpha1.CnsVolumeMetadata), err
+}
+
+// Update takes the representation of a cnsVolumeMetadata and updates it. Returns the
+// server's representation of the cnsVolumeMetadata, and an error, if there is any.
+func (c *FakeCnsVolumeMetadatas) Update(ctx context.Context,
+	cnsVolumeMetadata *metadatav1alpha1.CnsVolumeMetadata,
+	opts v1.UpdateOptions) (result *metadatav1alpha1.CnsVolumeMetadata, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(cnsVolumeMetadatasResource, c.ns, cnsVolumeMetadata),
+			&metadatav1alpha1.CnsVolumeMetadata{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*metadatav1alpha1.CnsVolumeMetadata), err
+}
+
+// UpdateStatus updates the status subresource of a cnsVolumeMetadata. Returns the
+// server's representation of the cnsVolumeMetadata, and an error, if there is any.
+func (c *FakeCnsVolumeMetadatas) UpdateStatus(ctx context.Context,
+	cnsVolumeMetadata *metadatav1alpha1.CnsVolumeMetadata,
+	opts v1.UpdateOptions) (*metadatav1alpha1.CnsVolumeMetadata, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(cnsVolumeMetadatasResource, "status", c.ns, cnsVolumeMetadata),
+			&metadatav1alpha1.CnsVolumeMetadata{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*metadatav1alpha1.CnsVolumeMetadata), err
+}
+
+// Delete takes name of the cnsVolumeMetadata and deletes it. Returns an error if one
+// occurs.
+func (c *FakeCnsVolumeMetadatas) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteAction(cnsVolumeMetadatasResource, c.ns, name),
+			&metadatav1alpha1.CnsVolumeMetadata{})
+
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeCnsVolumeMetadatas) DeleteCollection(ctx context.Context, opts v1.DeleteOptions,
+	listOpts v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(cnsVolumeMetadatasResource, c.ns, listOpts)
+
+	_, err := c.Fake.Invokes(action, &metadatav1alpha1.CnsVolumeMetadataList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched cnsVolumeMetadata.
+func (c *FakeCnsVolumeMetadatas) Patch(ctx context.Context, name string, pt types.PatchType, data []byte,
+	opts v1.PatchOptions, subresources ...string) (result *metadatav1alpha1.CnsVolumeMetadata, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(cnsVolumeMetadatasResource, c.ns, name, pt, data,
+			subresources...), &metadatav1alpha1.CnsVolumeMetadata{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*metadatav1alpha1.CnsVolumeMetadata), err
+}
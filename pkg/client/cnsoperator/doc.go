@@ -0,0 +1,27 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cnsoperator holds the typed clientset, listers and informers of the
+// CnsNodeVmAttachment and CnsVolumeMetadata custom resources of the
+// cns.vmware.com/v1alpha1 API group, for the controllers of the driver and
+// third-party integrations to access them with compile-time type safety and
+// shared caches. They follow the layout and conventions of the code
+// generated by client-gen, lister-gen and informer-gen:
+//
+//   - clientset/versioned holds the clientset and its fake for unit tests
+//   - listers/cns/v1alpha1 holds the listers
+//   - informers/externalversions holds the shared informer factory
+package cnsoperator
@@ -0,0 +1,97 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	time "time"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	watch "k8s.io/apimachinery/pkg/watch"
+	cache "k8s.io/client-go/tools/cache"
+
+	attachmentv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsnodevmattachment/v1alpha1"
+	versioned "sigs.k8s.io/vsphere-csi-driver/v2/pkg/client/cnsoperator/clientset/versioned"
+	internalinterfaces "sigs.k8s.io/vsphere-csi-driver/v2/pkg/client/cnsoperator/informers/externalversions/internalinterfaces"
+	listers "sigs.k8s.io/vsphere-csi-driver/v2/pkg/client/cnsoperator/listers/cns/v1alpha1"
+)
+
+// CnsNodeVmAttachmentInformer provides access to a shared informer and lister for
+// CnsNodeVmAttachments.
+type CnsNodeVmAttachmentInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() listers.CnsNodeVmAttachmentLister
+}
+
+type cnsNodeVmAttachmentInformer struct {
+	factory          internalinterfaces.SharedInformerFactory
+	tweakListOptions internalinterfaces.TweakListOptionsFunc
+	namespace        string
+}
+
+// NewCnsNodeVmAttachmentInformer constructs a new informer for CnsNodeVmAttachment type.
+// Always prefer using an informer factory to get a shared informer instead
+// of getting an independent one. This reduces memory footprint and number of
+// connections to the server.
+func NewCnsNodeVmAttachmentInformer(client versioned.Interface, namespace string, resyncPeriod time.Duration,
+	indexers cache.Indexers) cache.SharedIndexInformer {
+	return NewFilteredCnsNodeVmAttachmentInformer(client, namespace, resyncPeriod, indexers, nil)
+}
+
+// NewFilteredCnsNodeVmAttachmentInformer constructs a new informer for CnsNodeVmAttachment type.
+// Always prefer using an informer factory to get a shared informer instead
+// of getting an independent one. This reduces memory footprint and number of
+// connections to the server.
+func NewFilteredCnsNodeVmAttachmentInformer(client versioned.Interface, namespace string, resyncPeriod time.Duration,
+	indexers cache.Indexers, tweakListOptions internalinterfaces.TweakListOptionsFunc) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options v1.ListOptions) (runtime.Object, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.CnsV1alpha1().CnsNodeVmAttachments(namespace).List(context.TODO(), options)
+			},
+			WatchFunc: func(options v1.ListOptions) (watch.Interface, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.CnsV1alpha1().CnsNodeVmAttachments(namespace).Watch(context.TODO(), options)
+			},
+		},
+		&attachmentv1alpha1.CnsNodeVmAttachment{},
+		resyncPeriod,
+		indexers,
+	)
+}
+
+func (f *cnsNodeVmAttachmentInformer) defaultInformer(client versioned.Interface,
+	resyncPeriod time.Duration) cache.SharedIndexInformer {
+	return NewFilteredCnsNodeVmAttachmentInformer(client, f.namespace, resyncPeriod,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}, f.tweakListOptions)
+}
+
+// Informer returns the shared informer of CnsNodeVmAttachments.
+func (f *cnsNodeVmAttachmentInformer) Informer() cache.SharedIndexInformer {
+	return f.factory.InformerFor(&attachmentv1alpha1.CnsNodeVmAttachment{}, f.defaultInformer)
+}
+
+// Lister returns the lister of CnsNodeVmAttachments backed by the shared informer.
+func (f *cnsNodeVmAttachmentInformer) Lister() listers.CnsNodeVmAttachmentLister {
+	return listers.NewCnsNodeVmAttachmentLister(f.Informer().GetIndexer())
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	time "time"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	watch "k8s.io/apimachinery/pkg/watch"
+	cache "k8s.io/client-go/tools/cache"
+
+	metadatav1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsvolumemetadata/v1alpha1"
+	versioned "sigs.k8s.io/vsphere-csi-driver/v2/pkg/client/cnsoperator/clientset/versioned"
+	internalinterfaces "sigs.k8s.io/vsphere-csi-driver/v2/pkg/client/cnsoperator/informers/externalversions/internalinterfaces"
+	listers "sigs.k8s.io/vsphere-csi-driver/v2/pkg/client/cnsoperator/listers/cns/v1alpha1"
+)
+
+// CnsVolumeMetadataInformer provides access to a shared informer and lister for
+// CnsVolumeMetadatas.
+type CnsVolumeMetadataInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() listers.CnsVolumeMetadataLister
+}
+
+type cnsVolumeMetadataInformer struct {
+	factory          internalinterfaces.SharedInformerFactory
+	tweakListOptions internalinterfaces.TweakListOptionsFunc
+	namespace        string
+}
+
+// NewCnsVolumeMetadataInformer constructs a new informer for CnsVolumeMetadata type.
+// Always prefer using an informer factory to get a shared informer instead
+// of getting an independent one. This reduces memory footprint and number of
+// connections to the server.
+func NewCnsVolumeMetadataInformer(client versioned.Interface, namespace string, resyncPeriod time.Duration,
+	indexers cache.Indexers) cache.SharedIndexInformer {
+	return NewFilteredCnsVolumeMetadataInformer(client, namespace, resyncPeriod, indexers, nil)
+}
+
+// NewFilteredCnsVolumeMetadataInformer constructs a new informer for CnsVolumeMetadata type.
+// Always prefer using an informer factory to get a shared informer instead
+// of getting an independent one. This reduces memory footprint and number of
+// connections to the server.
+func NewFilteredCnsVolumeMetadataInformer(client versioned.Interface, namespace string, resyncPeriod time.Duration,
+	indexers cache.Indexers, tweakListOptions internalinterfaces.TweakListOptionsFunc) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options v1.ListOptions) (runtime.Object, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.CnsV1alpha1().CnsVolumeMetadatas(namespace).List(context.TODO(), options)
+			},
+			WatchFunc: func(options v1.ListOptions) (watch.Interface, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.CnsV1alpha1().CnsVolumeMetadatas(namespace).Watch(context.TODO(), options)
+			},
+		},
+		&metadatav1alpha1.CnsVolumeMetadata{},
+		resyncPeriod,
+		indexers,
+	)
+}
+
+func (f *cnsVolumeMetadataInformer) defaultInformer(client versioned.Interface,
+	resyncPeriod time.Duration) cache.SharedIndexInformer {
+	return NewFilteredCnsVolumeMetadataInformer(client, f.namespace, resyncPeriod,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}, f.tweakListOptions)
+}
+
+// Informer returns the shared informer of CnsVolumeMetadatas.
+func (f *cnsVolumeMetadataInformer) Informer() cache.SharedIndexInformer {
+	return f.factory.InformerFor(&metadatav1alpha1.CnsVolumeMetadata{}, f.defaultInformer)
+}
+
+// Lister returns the lister of CnsVolumeMetadatas backed by the shared informer.
+func (f *cnsVolumeMetadataInformer) Lister() listers.CnsVolumeMetadataLister {
+	return listers.NewCnsVolumeMetadataLister(f.Informer().GetIndexer())
+}
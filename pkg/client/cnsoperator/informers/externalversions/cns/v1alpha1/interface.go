@@ -0,0 +1,53 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package v1alpha1 holds the informers of the cns.vmware.com/v1alpha1 API
+// group version.
+package v1alpha1
+
+import (
+	internalinterfaces "sigs.k8s.io/vsphere-csi-driver/v2/pkg/client/cnsoperator/informers/externalversions/internalinterfaces"
+)
+
+// Interface provides access to all the informers in this group version.
+type Interface interface {
+	// CnsNodeVmAttachments returns a CnsNodeVmAttachmentInformer.
+	CnsNodeVmAttachments() CnsNodeVmAttachmentInformer
+	// CnsVolumeMetadatas returns a CnsVolumeMetadataInformer.
+	CnsVolumeMetadatas() CnsVolumeMetadataInformer
+}
+
+type version struct {
+	factory          internalinterfaces.SharedInformerFactory
+	namespace        string
+	tweakListOptions internalinterfaces.TweakListOptionsFunc
+}
+
+// New returns a new Interface.
+func New(f internalinterfaces.SharedInformerFactory, namespace string,
+	tweakListOptions internalinterfaces.TweakListOptionsFunc) Interface {
+	return &version{factory: f, namespace: namespace, tweakListOptions: tweakListOptions}
+}
+
+// CnsNodeVmAttachments returns a CnsNodeVmAttachmentInformer.
+func (v *version) CnsNodeVmAttachments() CnsNodeVmAttachmentInformer {
+	return &cnsNodeVmAttachmentInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
+// CnsVolumeMetadatas returns a CnsVolumeMetadataInformer.
+func (v *version) CnsVolumeMetadatas() CnsVolumeMetadataInformer {
+	return &cnsVolumeMetadataInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
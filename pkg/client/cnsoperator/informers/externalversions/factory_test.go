@@ -0,0 +1,97 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externalversions
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	cnsoperatorv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator"
+	attachmentv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsnodevmattachment/v1alpha1"
+	metadatav1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsvolumemetadata/v1alpha1"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/client/cnsoperator/clientset/versioned/fake"
+)
+
+func TestSharedInformerFactory(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	attachment := &attachmentv1alpha1.CnsNodeVmAttachment{
+		ObjectMeta: metav1.ObjectMeta{Name: "attachment-1", Namespace: "ns-1"},
+		Spec:       attachmentv1alpha1.CnsNodeVmAttachmentSpec{NodeUUID: "node-1", VolumeName: "pvc-1"},
+	}
+	metadata := &metadatav1alpha1.CnsVolumeMetadata{
+		ObjectMeta: metav1.ObjectMeta{Name: "metadata-1", Namespace: "ns-2"},
+	}
+	client := fake.NewSimpleClientset(attachment, metadata)
+	factory := NewSharedInformerFactory(client, 0)
+	attachmentInformer := factory.Cns().V1alpha1().CnsNodeVmAttachments()
+	metadataInformer := factory.Cns().V1alpha1().CnsVolumeMetadatas()
+	attachmentInformer.Informer()
+	metadataInformer.Informer()
+	factory.Start(ctx.Done())
+	for informerType, synced := range factory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			t.Fatalf("informer of %v failed to sync", informerType)
+		}
+	}
+
+	got, err := attachmentInformer.Lister().CnsNodeVmAttachments("ns-1").Get("attachment-1")
+	if err != nil {
+		t.Fatalf("failed to get CnsNodeVmAttachment from lister. Err: %v", err)
+	}
+	if got.Spec.NodeUUID != "node-1" || got.Spec.VolumeName != "pvc-1" {
+		t.Errorf("unexpected CnsNodeVmAttachment spec %+v", got.Spec)
+	}
+	_, err = attachmentInformer.Lister().CnsNodeVmAttachments("ns-2").Get("attachment-1")
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected NotFound error for CnsNodeVmAttachment in other namespace, got %v", err)
+	}
+	metadatas, err := metadataInformer.Lister().List(labels.Everything())
+	if err != nil || len(metadatas) != 1 {
+		t.Fatalf("expected 1 CnsVolumeMetadata from lister, got %d. Err: %v", len(metadatas), err)
+	}
+
+	// Objects created through the clientset reach the listers.
+	created := attachment.DeepCopy()
+	created.Name = "attachment-2"
+	if _, err := client.CnsV1alpha1().CnsNodeVmAttachments("ns-1").Create(ctx, created,
+		metav1.CreateOptions{}); err != nil {
+		t.Fatalf("failed to create CnsNodeVmAttachment. Err: %v", err)
+	}
+	err = wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		_, err := attachmentInformer.Lister().CnsNodeVmAttachments("ns-1").Get("attachment-2")
+		return err == nil, nil
+	})
+	if err != nil {
+		t.Errorf("created CnsNodeVmAttachment did not reach the lister. Err: %v", err)
+	}
+
+	generic, err := factory.ForResource(cnsoperatorv1alpha1.SchemeGroupVersion.WithResource("cnsvolumemetadatas"))
+	if err != nil {
+		t.Fatalf("failed to get generic informer. Err: %v", err)
+	}
+	if _, err := generic.Lister().ByNamespace("ns-2").Get("metadata-1"); err != nil {
+		t.Errorf("failed to get CnsVolumeMetadata from generic lister. Err: %v", err)
+	}
+}
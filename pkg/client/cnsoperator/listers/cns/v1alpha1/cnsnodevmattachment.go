@@ -0,0 +1,101 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+
+	cnsoperatorv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator"
+	attachmentv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsnodevmattachment/v1alpha1"
+)
+
+// CnsNodeVmAttachmentLister helps list CnsNodeVmAttachments.
+// All objects returned here must be treated as read-only.
+type CnsNodeVmAttachmentLister interface {
+	// List lists all CnsNodeVmAttachments in the indexer.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*attachmentv1alpha1.CnsNodeVmAttachment, err error)
+	// CnsNodeVmAttachments returns an object that can list and get CnsNodeVmAttachments.
+	CnsNodeVmAttachments(namespace string) CnsNodeVmAttachmentNamespaceLister
+	CnsNodeVmAttachmentListerExpansion
+}
+
+// cnsNodeVmAttachmentLister implements the CnsNodeVmAttachmentLister interface.
+type cnsNodeVmAttachmentLister struct {
+	indexer cache.Indexer
+}
+
+// NewCnsNodeVmAttachmentLister returns a new CnsNodeVmAttachmentLister.
+func NewCnsNodeVmAttachmentLister(indexer cache.Indexer) CnsNodeVmAttachmentLister {
+	return &cnsNodeVmAttachmentLister{indexer: indexer}
+}
+
+// List lists all CnsNodeVmAttachments in the indexer.
+func (s *cnsNodeVmAttachmentLister) List(selector labels.Selector) (ret []*attachmentv1alpha1.CnsNodeVmAttachment,
+	err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*attachmentv1alpha1.CnsNodeVmAttachment))
+	})
+	return ret, err
+}
+
+// CnsNodeVmAttachments returns an object that can list and get CnsNodeVmAttachments.
+func (s *cnsNodeVmAttachmentLister) CnsNodeVmAttachments(namespace string) CnsNodeVmAttachmentNamespaceLister {
+	return cnsNodeVmAttachmentNamespaceLister{indexer: s.indexer, namespace: namespace}
+}
+
+// CnsNodeVmAttachmentNamespaceLister helps list and get CnsNodeVmAttachments.
+// All objects returned here must be treated as read-only.
+type CnsNodeVmAttachmentNamespaceLister interface {
+	// List lists all CnsNodeVmAttachments in the indexer for a given namespace.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*attachmentv1alpha1.CnsNodeVmAttachment, err error)
+	// Get retrieves the CnsNodeVmAttachment from the indexer for a given namespace and name.
+	// Objects returned here must be treated as read-only.
+	Get(name string) (*attachmentv1alpha1.CnsNodeVmAttachment, error)
+	CnsNodeVmAttachmentNamespaceListerExpansion
+}
+
+// cnsNodeVmAttachmentNamespaceLister implements the CnsNodeVmAttachmentNamespaceLister
+// interface.
+type cnsNodeVmAttachmentNamespaceLister struct {
+	indexer   cache.Indexer
+	namespace string
+}
+
+// List lists all CnsNodeVmAttachments in the indexer for a given namespace.
+func (s cnsNodeVmAttachmentNamespaceLister) List(
+	selector labels.Selector) (ret []*attachmentv1alpha1.CnsNodeVmAttachment, err error) {
+	err = cache.ListAllByNamespace(s.indexer, s.namespace, selector, func(m interface{}) {
+		ret = append(ret, m.(*attachmentv1alpha1.CnsNodeVmAttachment))
+	})
+	return ret, err
+}
+
+// Get retrieves the CnsNodeVmAttachment from the indexer for a given namespace and name.
+func (s cnsNodeVmAttachmentNamespaceLister) Get(name string) (*attachmentv1alpha1.CnsNodeVmAttachment, error) {
+	obj, exists, err := s.indexer.GetByKey(s.namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(cnsoperatorv1alpha1.Resource("cnsnodevmattachment"), name)
+	}
+	return obj.(*attachmentv1alpha1.CnsNodeVmAttachment), nil
+}
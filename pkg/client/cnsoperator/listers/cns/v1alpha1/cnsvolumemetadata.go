@@ -0,0 +1,101 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+
+	cnsoperatorv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator"
+	metadatav1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsvolumemetadata/v1alpha1"
+)
+
+// CnsVolumeMetadataLister helps list CnsVolumeMetadatas.
+// All objects returned here must be treated as read-only.
+type CnsVolumeMetadataLister interface {
+	// List lists all CnsVolumeMetadatas in the indexer.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*metadatav1alpha1.CnsVolumeMetadata, err error)
+	// CnsVolumeMetadatas returns an object that can list and get CnsVolumeMetadatas.
+	CnsVolumeMetadatas(namespace string) CnsVolumeMetadataNamespaceLister
+	CnsVolumeMetadataListerExpansion
+}
+
+// cnsVolumeMetadataLister implements the CnsVolumeMetadataLister interface.
+type cnsVolumeMetadataLister struct {
+	indexer cache.Indexer
+}
+
+// NewCnsVolumeMetadataLister returns a new CnsVolumeMetadataLister.
+func NewCnsVolumeMetadataLister(indexer cache.Indexer) CnsVolumeMetadataLister {
+	return &cnsVolumeMetadataLister{indexer: indexer}
+}
+
+// List lists all CnsVolumeMetadatas in the indexer.
+func (s *cnsVolumeMetadataLister) List(selector labels.Selector) (ret []*metadatav1alpha1.CnsVolumeMetadata,
+	err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*metadatav1alpha1.CnsVolumeMetadata))
+	})
+	return ret, err
+}
+
+// CnsVolumeMetadatas returns an object that can list and get CnsVolumeMetadatas.
+func (s *cnsVolumeMetadataLister) CnsVolumeMetadatas(namespace string) CnsVolumeMetadataNamespaceLister {
+	return cnsVolumeMetadataNamespaceLister{indexer: s.indexer, namespace: namespace}
+}
+
+// CnsVolumeMetadataNamespaceLister helps list and get CnsVolumeMetadatas.
+// All objects returned here must be treated as read-only.
+type CnsVolumeMetadataNamespaceLister interface {
+	// List lists all CnsVolumeMetadatas in the indexer for a given namespace.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*metadatav1alpha1.CnsVolumeMetadata, err error)
+	// Get retrieves the CnsVolumeMetadata from the indexer for a given namespace and name.
+	// Objects returned here must be treated as read-only.
+	Get(name string) (*metadatav1alpha1.CnsVolumeMetadata, error)
+	CnsVolumeMetadataNamespaceListerExpansion
+}
+
+// cnsVolumeMetadataNamespaceLister implements the CnsVolumeMetadataNamespaceLister
+// interface.
+type cnsVolumeMetadataNamespaceLister struct {
+	indexer   cache.Indexer
+	namespace string
+}
+
+// List lists all CnsVolumeMetadatas in the indexer for a given namespace.
+func (s cnsVolumeMetadataNamespaceLister) List(
+	selector labels.Selector) (ret []*metadatav1alpha1.CnsVolumeMetadata, err error) {
+	err = cache.ListAllByNamespace(s.indexer, s.namespace, selector, func(m interface{}) {
+		ret = append(ret, m.(*metadatav1alpha1.CnsVolumeMetadata))
+	})
+	return ret, err
+}
+
+// Get retrieves the CnsVolumeMetadata from the indexer for a given namespace and name.
+func (s cnsVolumeMetadataNamespaceLister) Get(name string) (*metadatav1alpha1.CnsVolumeMetadata, error) {
+	obj, exists, err := s.indexer.GetByKey(s.namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(cnsoperatorv1alpha1.Resource("cnsvolumemetadata"), name)
+	}
+	return obj.(*metadatav1alpha1.CnsVolumeMetadata), nil
+}
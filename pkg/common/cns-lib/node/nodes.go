@@ -19,13 +19,16 @@ package node
 import (
 	"context"
 	"fmt"
+	"reflect"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/vmware/govmomi/vapi/tags"
 	v1 "k8s.io/api/core/v1"
 	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/client-go/tools/cache"
 
 	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	cnsconfig "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
 	k8s "sigs.k8s.io/vsphere-csi-driver/v2/pkg/kubernetes"
 )
@@ -101,6 +104,9 @@ func (nodes *Nodes) nodeUpdate(oldObj interface{}, newObj interface{}) {
 
 func (nodes *Nodes) nodeDelete(obj interface{}) {
 	ctx, log := logger.GetNewContextWithLogger()
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
 	node, ok := obj.(*v1.Node)
 	if node == nil || !ok {
 		log.Warnf("nodeDelete: unrecognized object %+v", obj)
@@ -161,6 +167,9 @@ func (nodes *Nodes) csiNodeUpdate(oldObj interface{}, newObj interface{}) {
 
 func (nodes *Nodes) csiNodeDelete(obj interface{}) {
 	ctx, log := logger.GetNewContextWithLogger()
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
 	csiNode, ok := obj.(*storagev1.CSINode)
 	if csiNode == nil || !ok {
 		log.Warnf("csiNodeDelete: unrecognized object %+v", obj)
@@ -248,21 +257,37 @@ func (nodes *Nodes) GetSharedDatastoresInTopology(
 		log.Errorf(errMsg)
 		return nil, nil, fmt.Errorf(errMsg)
 	}
-	// getNodesInZoneRegion takes zone and region as parameter and returns list
-	// of node VMs which belongs to specified zone and region.
-	getNodesInZoneRegion := func(zoneValue string, regionValue string) (
+	// getNodesInZoneRegion takes zone, region and topology hierarchy as
+	// parameter and returns list of node VMs which belongs to specified zone,
+	// region and topology hierarchy. The zone and region are not checked if
+	// only the topology hierarchy is specified.
+	getNodesInZoneRegion := func(zoneValue string, regionValue string, hierarchy map[string]string) (
 		[]*cnsvsphere.VirtualMachine, error) {
-		log.Debugf("Get nodes in zone: %s, region: %s", zoneValue, regionValue)
+		log.Debugf("Get nodes in zone: %s, region: %s, hierarchy: %+v", zoneValue, regionValue, hierarchy)
+		var hierarchyLevels []string
+		for level := range hierarchy {
+			hierarchyLevels = append(hierarchyLevels, level)
+		}
 		var nodeVMsInZoneAndRegion []*cnsvsphere.VirtualMachine
 		for _, nodeVM := range allNodes {
-			isNodeInZoneRegion, err := nodeVM.IsInZoneRegion(ctx, zoneCategoryName,
-				regionCategoryName, zoneValue, regionValue, tagManager)
+			if zoneValue != "" || regionValue != "" || len(hierarchy) == 0 {
+				isNodeInZoneRegion, err := nodeVM.IsInZoneRegion(ctx, zoneCategoryName,
+					regionCategoryName, zoneValue, regionValue, tagManager)
+				if err != nil {
+					log.Errorf("Error checking if node VM %v belongs to zone [%s] and region [%s]. err: %+v",
+						nodeVM, zoneValue, regionValue, err)
+					return nil, err
+				}
+				if !isNodeInZoneRegion {
+					continue
+				}
+			}
+			nodeHierarchy, err := nodeVM.GetTopologyHierarchy(ctx, hierarchyLevels)
 			if err != nil {
-				log.Errorf("Error checking if node VM %v belongs to zone [%s] and region [%s]. err: %+v",
-					nodeVM, zoneValue, regionValue, err)
+				log.Errorf("Error getting the topology hierarchy of node VM %v. err: %+v", nodeVM, err)
 				return nil, err
 			}
-			if isNodeInZoneRegion {
+			if reflect.DeepEqual(nodeHierarchy, hierarchy) {
 				nodeVMsInZoneAndRegion = append(nodeVMsInZoneAndRegion, nodeVM)
 			}
 		}
@@ -281,8 +306,10 @@ func (nodes *Nodes) GetSharedDatastoresInTopology(
 			segments := topology.GetSegments()
 			zone := segments[v1.LabelZoneFailureDomain]
 			region := segments[v1.LabelZoneRegion]
-			log.Debugf("Getting list of nodeVMs for zone [%s] and region [%s]", zone, region)
-			nodeVMsInZoneRegion, err := getNodesInZoneRegion(zone, region)
+			hierarchy := cnsconfig.GetTopologyHierarchySegments(segments)
+			log.Debugf("Getting list of nodeVMs for zone [%s], region [%s] and hierarchy [%+v]",
+				zone, region, hierarchy)
+			nodeVMsInZoneRegion, err := getNodesInZoneRegion(zone, region, hierarchy)
 			if err != nil {
 				log.Errorf("Failed to find nodes in zone [%s] and region [%s]. Error: %+v",
 					zone, region, err)
@@ -307,6 +334,9 @@ func (nodes *Nodes) GetSharedDatastoresInTopology(
 				if region != "" {
 					accessibleTopology[v1.LabelZoneRegion] = region
 				}
+				for level, value := range hierarchy {
+					accessibleTopology[cnsconfig.GetTopologyHierarchyKey(level)] = value
+				}
 				datastoreTopologyMap[datastore.Info.Url] =
 					append(datastoreTopologyMap[datastore.Info.Url], accessibleTopology)
 			}
@@ -318,13 +348,20 @@ func (nodes *Nodes) GetSharedDatastoresInTopology(
 	var sharedDatastores []*cnsvsphere.DatastoreInfo
 	var datastoreTopologyMap = make(map[string][]map[string]string)
 	if topologyRequirement != nil && topologyRequirement.GetPreferred() != nil {
+		// Preferred topologies are ordered by the CO. With delayed binding the
+		// first entry is the topology of the node selected by the scheduler, so
+		// the topologies are tried one at a time in the given order.
 		log.Debugf("Using preferred topology")
-		sharedDatastores, datastoreTopologyMap, err =
-			getSharedDatastoresInTopology(topologyRequirement.GetPreferred())
-		if err != nil {
-			log.Errorf("Error finding shared datastores from preferred topology: %+v",
-				topologyRequirement.GetPreferred())
-			return nil, nil, err
+		for _, topology := range topologyRequirement.GetPreferred() {
+			sharedDatastores, datastoreTopologyMap, err =
+				getSharedDatastoresInTopology([]*csi.Topology{topology})
+			if err != nil {
+				log.Errorf("Error finding shared datastores from preferred topology: %+v", topology)
+				return nil, nil, err
+			}
+			if len(sharedDatastores) != 0 {
+				break
+			}
 		}
 	}
 	if len(sharedDatastores) == 0 && topologyRequirement != nil &&
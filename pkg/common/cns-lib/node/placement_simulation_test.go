@@ -0,0 +1,281 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vapi/rest"
+	_ "github.com/vmware/govmomi/vapi/simulator"
+	"github.com/vmware/govmomi/vapi/tags"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+
+	cnsvolume "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/volume"
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+)
+
+// placementInventory is a vCenter inventory recorded for placement
+// simulations. Fixtures live in testdata/placement and can be captured from a
+// real vCenter: host tags with `govc tags.attached.ls -r <host>`, datastore
+// URLs, free space and mounting hosts with `govc datastore.info -json` and
+// volume counts per datastore from `govc volume.ls -json`. Anonymize names
+// before adding a customer inventory.
+type placementInventory struct {
+	Name string `json:"name"`
+	// ZoneCategory and RegionCategory are the tag categories of the zones
+	// and regions, as in the Labels section of the vSphere config.
+	ZoneCategory   string               `json:"zoneCategory"`
+	RegionCategory string               `json:"regionCategory"`
+	Hosts          []inventoryHost      `json:"hosts"`
+	Datastores     []inventoryDatastore `json:"datastores"`
+	Scenarios      []placementScenario  `json:"scenarios"`
+}
+
+// inventoryHost is a host of a recorded inventory along with its tags, keyed
+// by tag category.
+type inventoryHost struct {
+	Name string            `json:"name"`
+	Tags map[string]string `json:"tags"`
+}
+
+// inventoryDatastore is a datastore of a recorded inventory.
+type inventoryDatastore struct {
+	Name        string   `json:"name"`
+	URL         string   `json:"url"`
+	FreeSpace   int64    `json:"freeSpace"`
+	VolumeCount int      `json:"volumeCount"`
+	Hosts       []string `json:"hosts"`
+}
+
+// placementScenario is a volume placement request replayed against a
+// recorded inventory. Preferred and requisite hold topology segments as in
+// the CSI TopologyRequirement. ExpectedRanking holds the names of the
+// candidate datastores, best candidate first.
+type placementScenario struct {
+	Name            string              `json:"name"`
+	Preferred       []map[string]string `json:"preferred"`
+	Requisite       []map[string]string `json:"requisite"`
+	ExpectedRanking []string            `json:"expectedRanking"`
+}
+
+// simulatedNodeManager is a Manager returning the node VMs of a simulated
+// inventory.
+type simulatedNodeManager struct {
+	Manager
+	nodeVMs []*cnsvsphere.VirtualMachine
+}
+
+func (m *simulatedNodeManager) GetAllNodes(ctx context.Context) ([]*cnsvsphere.VirtualMachine, error) {
+	return m.nodeVMs, nil
+}
+
+// simulatedVolumeManager is a volume Manager returning the recorded number of
+// volumes of each datastore.
+type simulatedVolumeManager struct {
+	cnsvolume.Manager
+	volumeCounts map[string]int
+}
+
+func (m *simulatedVolumeManager) QueryAllVolume(ctx context.Context, queryFilter cnstypes.CnsQueryFilter,
+	querySelection cnstypes.CnsQuerySelection) (*cnstypes.CnsQueryResult, error) {
+	result := &cnstypes.CnsQueryResult{}
+	for url, count := range m.volumeCounts {
+		for i := 0; i < count; i++ {
+			result.Volumes = append(result.Volumes, cnstypes.CnsVolume{DatastoreUrl: url})
+		}
+	}
+	return result, nil
+}
+
+// simulate creates the recorded inventory on the given vCenter simulator,
+// made of one standalone host with a single node VM per recorded host, and
+// returns the node VMs along with a tag manager.
+func (inv *placementInventory) simulate(ctx context.Context, t *testing.T, client *vim25.Client) (
+	[]*cnsvsphere.VirtualMachine, *tags.Manager) {
+	finder := find.NewFinder(client, true)
+	datacenter, err := finder.DefaultDatacenter(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	finder.SetDatacenter(datacenter)
+	hostObjects, err := finder.HostSystemList(ctx, "*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hosts []mo.HostSystem
+	hostRefs := make([]types.ManagedObjectReference, 0, len(hostObjects))
+	for _, host := range hostObjects {
+		hostRefs = append(hostRefs, host.Reference())
+	}
+	if err := property.DefaultCollector(client).Retrieve(ctx, hostRefs, []string{"vm"}, &hosts); err != nil {
+		t.Fatal(err)
+	}
+	if len(hosts) != len(inv.Hosts) {
+		t.Fatalf("expected %d simulated hosts, got %d", len(inv.Hosts), len(hosts))
+	}
+
+	// Tag the hosts.
+	restClient := rest.NewClient(client)
+	if err := restClient.Login(ctx, simulator.DefaultLogin); err != nil {
+		t.Fatal(err)
+	}
+	tagManager := tags.NewManager(restClient)
+	categoryIDs := make(map[string]string)
+	tagIDs := make(map[string]string)
+	hostRefsByName := make(map[string]types.ManagedObjectReference)
+	var nodeVMs []*cnsvsphere.VirtualMachine
+	for i, host := range inv.Hosts {
+		hostRef := hosts[i].Self
+		hostRefsByName[host.Name] = hostRef
+		for categoryName, tagName := range host.Tags {
+			if _, ok := categoryIDs[categoryName]; !ok {
+				if categoryIDs[categoryName], err = tagManager.CreateCategory(ctx,
+					&tags.Category{Name: categoryName, Cardinality: "SINGLE"}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			tagKey := categoryName + "/" + tagName
+			if _, ok := tagIDs[tagKey]; !ok {
+				if tagIDs[tagKey], err = tagManager.CreateTag(ctx,
+					&tags.Tag{Name: tagName, CategoryID: categoryIDs[categoryName]}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := tagManager.AttachTag(ctx, tagIDs[tagKey], hostRef); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// Only the recorded datastores are mounted on the host.
+		simulatedHost := simulator.Map.Get(hostRef).(*simulator.HostSystem)
+		simulatedHost.Datastore = nil
+		simulator.Map.Get(*simulatedHost.ConfigManager.DatastoreSystem).(*simulator.HostDatastoreSystem).Datastore = nil
+		nodeVMs = append(nodeVMs, &cnsvsphere.VirtualMachine{
+			VirtualMachine: object.NewVirtualMachine(client, hosts[i].Vm[0]),
+			Datacenter:     &cnsvsphere.Datacenter{Datacenter: datacenter},
+		})
+	}
+
+	// Mount the datastores on their hosts.
+	for _, datastore := range inv.Datastores {
+		path := t.TempDir()
+		var datastoreRef types.ManagedObjectReference
+		for _, hostName := range datastore.Hosts {
+			hostRef, ok := hostRefsByName[hostName]
+			if !ok {
+				t.Fatalf("datastore %s is mounted on unknown host %s", datastore.Name, hostName)
+			}
+			datastoreSystem, err := object.NewHostSystem(client, hostRef).ConfigManager().DatastoreSystem(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ds, err := datastoreSystem.CreateLocalDatastore(ctx, datastore.Name, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			datastoreRef = ds.Reference()
+		}
+		info := simulator.Map.Get(datastoreRef).(*simulator.Datastore).Info.GetDatastoreInfo()
+		info.Url = datastore.URL
+		info.FreeSpace = datastore.FreeSpace
+	}
+	return nodeVMs, tagManager
+}
+
+// replay returns the names of the datastores selected and ranked by the
+// placement logic for the given scenario.
+func (inv *placementInventory) replay(ctx context.Context, t *testing.T, nodes *Nodes, tagManager *tags.Manager,
+	scenario placementScenario) []string {
+	topologyRequirement := &csi.TopologyRequirement{}
+	for _, segments := range scenario.Preferred {
+		topologyRequirement.Preferred = append(topologyRequirement.Preferred, &csi.Topology{Segments: segments})
+	}
+	for _, segments := range scenario.Requisite {
+		topologyRequirement.Requisite = append(topologyRequirement.Requisite, &csi.Topology{Segments: segments})
+	}
+	datastores, _, err := nodes.GetSharedDatastoresInTopology(ctx, topologyRequirement, tagManager,
+		inv.ZoneCategory, inv.RegionCategory)
+	if err != nil {
+		t.Fatalf("%s: %s: failed to get the shared datastores: %v", inv.Name, scenario.Name, err)
+	}
+	volumeManager := &simulatedVolumeManager{volumeCounts: make(map[string]int)}
+	namesByURL := make(map[string]string)
+	for _, datastore := range inv.Datastores {
+		volumeManager.volumeCounts[datastore.URL] = datastore.VolumeCount
+		namesByURL[datastore.URL] = datastore.Name
+	}
+	ranked, err := common.RankDatastoresForPlacement(ctx, volumeManager, datastores)
+	if err != nil {
+		t.Fatalf("%s: %s: failed to rank the datastores: %v", inv.Name, scenario.Name, err)
+	}
+	ranking := make([]string, 0, len(ranked))
+	for _, datastore := range ranked {
+		ranking = append(ranking, namesByURL[datastore.Info.Url])
+	}
+	return ranking
+}
+
+// TestPlacementSimulation replays the scenarios of the recorded inventories
+// in testdata/placement against the datastore selection and ranking, on a
+// vCenter simulator holding the inventory.
+func TestPlacementSimulation(t *testing.T) {
+	fixtures, err := filepath.Glob(filepath.Join("testdata", "placement", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixtures) == 0 {
+		t.Fatal("no placement inventories found")
+	}
+	for _, fixture := range fixtures {
+		data, err := ioutil.ReadFile(fixture)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", fixture, err)
+		}
+		var inventory placementInventory
+		if err := json.Unmarshal(data, &inventory); err != nil {
+			t.Fatalf("failed to parse %s: %v", fixture, err)
+		}
+		model := simulator.VPX()
+		model.Cluster = 0
+		model.Host = len(inventory.Hosts)
+		model.Machine = 1
+		simulator.Test(func(ctx context.Context, client *vim25.Client) {
+			nodeVMs, tagManager := inventory.simulate(ctx, t, client)
+			nodes := &Nodes{cnsNodeManager: &simulatedNodeManager{nodeVMs: nodeVMs}}
+			for _, scenario := range inventory.Scenarios {
+				ranking := inventory.replay(ctx, t, nodes, tagManager, scenario)
+				if !reflect.DeepEqual(ranking, scenario.ExpectedRanking) {
+					t.Errorf("%s: %s: expected ranking %v, got %v", inventory.Name, scenario.Name,
+						scenario.ExpectedRanking, ranking)
+				}
+			}
+		}, model)
+	}
+}
@@ -0,0 +1,519 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package volume
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	"github.com/vmware/govmomi/object"
+
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	csifault "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/fault"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+)
+
+const (
+	// circuitBreakerFailureThreshold is the number of consecutive CNS calls
+	// failing because CNS is unavailable after which the circuit opens.
+	circuitBreakerFailureThreshold = 5
+	// circuitBreakerCooldown is the duration for which CNS calls are rejected
+	// once the circuit opens. It doubles every time the trial call after a
+	// cooldown fails, up to circuitBreakerMaxCooldown.
+	circuitBreakerCooldown = 30 * time.Second
+	// circuitBreakerMaxCooldown is the maximum duration for which CNS calls
+	// are rejected.
+	circuitBreakerMaxCooldown = 5 * time.Minute
+)
+
+// cnsUnavailableErrorMessages are the error messages showing CNS could not be
+// reached. The volume manager often re-formats errors, so these are matched
+// on the error message.
+var cnsUnavailableErrorMessages = []string{
+	"503 Service Unavailable",
+	"connection refused",
+	"connection reset by peer",
+	"no such host",
+	"i/o timeout",
+	clientTimeoutErrorMessage,
+}
+
+// clientTimeoutErrorMessage is the error message of the requests exceeding
+// the timeout of the SOAP client.
+const clientTimeoutErrorMessage = "Client.Timeout exceeded"
+
+// CnsUnavailableError is returned by the volume manager, without calling CNS,
+// while its circuit breaker is open, i.e. after several consecutive calls
+// failed because CNS was unavailable.
+type CnsUnavailableError struct {
+	// RetryAfter is the remaining time after which CNS is called again.
+	RetryAfter time.Duration
+}
+
+func (e *CnsUnavailableError) Error() string {
+	return fmt.Sprintf("CNS is unavailable, calls are rejected for the next %v", e.RetryAfter.Round(time.Second))
+}
+
+// IsCnsUnavailableError checks if err is a CnsUnavailableError.
+func IsCnsUnavailableError(err error) bool {
+	var cnsUnavailableError *CnsUnavailableError
+	return errors.As(err, &cnsUnavailableError)
+}
+
+// isCnsUnreachableError checks if err shows CNS could not be reached, as
+// opposed to CNS failing the call. The context of the caller being cancelled
+// or exceeding its deadline, e.g. on a timeout of a CSI sidecar, doesn't show
+// CNS is unreachable.
+func isCnsUnreachableError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return strings.Contains(err.Error(), clientTimeoutErrorMessage)
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	for _, message := range cnsUnavailableErrorMessages {
+		if strings.Contains(err.Error(), message) {
+			return true
+		}
+	}
+	return false
+}
+
+// circuitBreaker tracks consecutive CNS calls failing because CNS is
+// unavailable. Once the threshold is reached, the circuit opens and calls are
+// rejected until the cooldown elapses. A single trial call is then let
+// through, closing the circuit if CNS is reachable again, or re-opening it for
+// twice the previous cooldown otherwise.
+type circuitBreaker struct {
+	lock             sync.Mutex
+	failureThreshold int
+	// baseCooldown and maxCooldown bound the cooldown.
+	baseCooldown time.Duration
+	maxCooldown  time.Duration
+	// cooldown is the cooldown of the circuit while it is open.
+	cooldown            time.Duration
+	consecutiveFailures int
+	openUntil           time.Time
+	// trialInFlight is set while the trial call after a cooldown is running.
+	trialInFlight bool
+	// now returns the current time, it is overridden in unit tests.
+	now func() time.Time
+}
+
+func newCircuitBreaker(failureThreshold int, baseCooldown time.Duration,
+	maxCooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		failureThreshold: failureThreshold,
+		baseCooldown:     baseCooldown,
+		maxCooldown:      maxCooldown,
+		now:              time.Now,
+	}
+}
+
+// allow returns a CnsUnavailableError if the call must be rejected.
+// Otherwise, it returns whether the call is the trial call after a cooldown,
+// which must be passed to record or abandon.
+func (cb *circuitBreaker) allow() (bool, error) {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+	if cb.consecutiveFailures < cb.failureThreshold {
+		return false, nil
+	}
+	now := cb.now()
+	if now.Before(cb.openUntil) {
+		return false, &CnsUnavailableError{RetryAfter: cb.openUntil.Sub(now)}
+	}
+	if cb.trialInFlight {
+		// The circuit stays open for another cooldown if the trial call fails.
+		return false, &CnsUnavailableError{RetryAfter: cb.cooldown}
+	}
+	cb.trialInFlight = true
+	return true, nil
+}
+
+// record records the outcome of a call which was allowed, trial being whether
+// it was the trial call. It returns whether the circuit state changed, and
+// whether it is now open.
+func (cb *circuitBreaker) record(trial bool, err error) (bool, bool) {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+	if trial {
+		cb.trialInFlight = false
+	}
+	wasOpen := cb.consecutiveFailures >= cb.failureThreshold
+	if !isCnsUnreachableError(err) {
+		cb.consecutiveFailures = 0
+		cb.cooldown = 0
+		return wasOpen, false
+	}
+	cb.consecutiveFailures++
+	if cb.consecutiveFailures < cb.failureThreshold {
+		return false, false
+	}
+	if wasOpen {
+		cb.cooldown *= 2
+		if cb.cooldown > cb.maxCooldown {
+			cb.cooldown = cb.maxCooldown
+		}
+	} else {
+		cb.cooldown = cb.baseCooldown
+	}
+	cb.openUntil = cb.now().Add(cb.cooldown)
+	return !wasOpen, true
+}
+
+// abandon records that the outcome of a call which was allowed doesn't show
+// whether CNS is available, e.g. as the context of the caller was done.
+func (cb *circuitBreaker) abandon(trial bool) {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+	if trial {
+		cb.trialInFlight = false
+	}
+}
+
+// getCooldown returns the cooldown of the circuit.
+func (cb *circuitBreaker) getCooldown() time.Duration {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+	return cb.cooldown
+}
+
+// reset closes the circuit.
+func (cb *circuitBreaker) reset() {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+	cb.consecutiveFailures = 0
+	cb.cooldown = 0
+	cb.trialInFlight = false
+}
+
+// circuitBreakerManager is a Manager failing CNS calls fast with a
+// CnsUnavailableError while CNS is unavailable, instead of having every
+// caller block for the full SOAP timeout.
+type circuitBreakerManager struct {
+	Manager
+	breaker *circuitBreaker
+}
+
+func newCircuitBreakerManager(manager Manager) *circuitBreakerManager {
+	return &circuitBreakerManager{
+		Manager: manager,
+		breaker: newCircuitBreaker(circuitBreakerFailureThreshold, circuitBreakerCooldown, circuitBreakerMaxCooldown),
+	}
+}
+
+// allow returns a CnsUnavailableError if the call must be rejected.
+// Otherwise, it returns whether the call is the trial call after a cooldown.
+func (m *circuitBreakerManager) allow(ctx context.Context) (bool, error) {
+	trial, err := m.breaker.allow()
+	if err != nil {
+		log := logger.GetLogger(ctx)
+		log.Debugf("rejecting CNS call: %v", err)
+	}
+	return trial, err
+}
+
+// record records the outcome of a call which was allowed. Failures of calls
+// whose context is done are not recorded, as they don't show CNS is
+// unavailable.
+func (m *circuitBreakerManager) record(ctx context.Context, trial bool, err error) {
+	if err != nil && ctx.Err() != nil {
+		m.breaker.abandon(trial)
+		return
+	}
+	changed, open := m.breaker.record(trial, err)
+	if !changed {
+		return
+	}
+	log := logger.GetLogger(ctx)
+	if open {
+		log.Errorf("CNS is unavailable, rejecting CNS calls for %v. Last error: %v", m.breaker.getCooldown(), err)
+	} else {
+		log.Infof("CNS is available again, no longer rejecting CNS calls")
+	}
+}
+
+// CreateVolume creates a new volume given its spec.
+func (m *circuitBreakerManager) CreateVolume(ctx context.Context,
+	spec *cnstypes.CnsVolumeCreateSpec) (*CnsVolumeInfo, string, error) {
+	trial, err := m.allow(ctx)
+	if err != nil {
+		return nil, csifault.CSICnsUnavailableFault, err
+	}
+	volumeInfo, faultType, err := m.Manager.CreateVolume(ctx, spec)
+	m.record(ctx, trial, err)
+	return volumeInfo, faultType, err
+}
+
+// AttachVolume attaches a volume to a virtual machine given the spec.
+func (m *circuitBreakerManager) AttachVolume(ctx context.Context, vm *cnsvsphere.VirtualMachine,
+	volumeID string, checkNVMeController bool) (string, string, error) {
+	trial, err := m.allow(ctx)
+	if err != nil {
+		return "", csifault.CSICnsUnavailableFault, err
+	}
+	diskUUID, faultType, err := m.Manager.AttachVolume(ctx, vm, volumeID, checkNVMeController)
+	m.record(ctx, trial, err)
+	return diskUUID, faultType, err
+}
+
+// DetachVolume detaches a volume from the virtual machine given the spec.
+func (m *circuitBreakerManager) DetachVolume(ctx context.Context, vm *cnsvsphere.VirtualMachine,
+	volumeID string) (string, error) {
+	trial, err := m.allow(ctx)
+	if err != nil {
+		return csifault.CSICnsUnavailableFault, err
+	}
+	faultType, err := m.Manager.DetachVolume(ctx, vm, volumeID)
+	m.record(ctx, trial, err)
+	return faultType, err
+}
+
+// BatchAttachVolumes attaches the given volumes to a virtual machine using a
+// single CNS task.
+func (m *circuitBreakerManager) BatchAttachVolumes(ctx context.Context, vm *cnsvsphere.VirtualMachine,
+	volumeIDs []string, checkNVMeController bool) map[string]*BatchAttachDetachResult {
+	trial, err := m.allow(ctx)
+	if err != nil {
+		return newBatchAttachDetachResults(volumeIDs, csifault.CSICnsUnavailableFault, err)
+	}
+	results := m.Manager.BatchAttachVolumes(ctx, vm, volumeIDs, checkNVMeController)
+	m.record(ctx, trial, getBatchAttachDetachError(results))
+	return results
+}
+
+// BatchDetachVolumes detaches the given volumes from a virtual machine using
+// a single CNS task.
+func (m *circuitBreakerManager) BatchDetachVolumes(ctx context.Context, vm *cnsvsphere.VirtualMachine,
+	volumeIDs []string) map[string]*BatchAttachDetachResult {
+	trial, err := m.allow(ctx)
+	if err != nil {
+		return newBatchAttachDetachResults(volumeIDs, csifault.CSICnsUnavailableFault, err)
+	}
+	results := m.Manager.BatchDetachVolumes(ctx, vm, volumeIDs)
+	m.record(ctx, trial, getBatchAttachDetachError(results))
+	return results
+}
+
+// DeleteVolume deletes a volume given its spec.
+func (m *circuitBreakerManager) DeleteVolume(ctx context.Context, volumeID string, deleteDisk bool) (string, error) {
+	trial, err := m.allow(ctx)
+	if err != nil {
+		return csifault.CSICnsUnavailableFault, err
+	}
+	faultType, err := m.Manager.DeleteVolume(ctx, volumeID, deleteDisk)
+	m.record(ctx, trial, err)
+	return faultType, err
+}
+
+// UpdateVolumeMetadata updates a volume metadata given its spec.
+func (m *circuitBreakerManager) UpdateVolumeMetadata(ctx context.Context,
+	spec *cnstypes.CnsVolumeMetadataUpdateSpec) error {
+	trial, err := m.allow(ctx)
+	if err != nil {
+		return err
+	}
+	err = m.Manager.UpdateVolumeMetadata(ctx, spec)
+	m.record(ctx, trial, err)
+	return err
+}
+
+// BatchUpdateVolumeMetadata updates the metadata of the given volumes using
+// a single CNS task.
+func (m *circuitBreakerManager) BatchUpdateVolumeMetadata(ctx context.Context,
+	specs []cnstypes.CnsVolumeMetadataUpdateSpec) []error {
+	trial, err := m.allow(ctx)
+	if err != nil {
+		errs := make([]error, len(specs))
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
+	errs := m.Manager.BatchUpdateVolumeMetadata(ctx, specs)
+	m.record(ctx, trial, getBatchUpdateVolumeMetadataError(errs))
+	return errs
+}
+
+// QueryVolumeInfo calls the CNS QueryVolumeInfo API.
+func (m *circuitBreakerManager) QueryVolumeInfo(ctx context.Context,
+	volumeIDList []cnstypes.CnsVolumeId) (*cnstypes.CnsQueryVolumeInfoResult, error) {
+	trial, err := m.allow(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result, err := m.Manager.QueryVolumeInfo(ctx, volumeIDList)
+	m.record(ctx, trial, err)
+	return result, err
+}
+
+// QueryAllVolume returns all volumes matching the given filter and selection.
+func (m *circuitBreakerManager) QueryAllVolume(ctx context.Context, queryFilter cnstypes.CnsQueryFilter,
+	querySelection cnstypes.CnsQuerySelection) (*cnstypes.CnsQueryResult, error) {
+	trial, err := m.allow(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result, err := m.Manager.QueryAllVolume(ctx, queryFilter, querySelection)
+	m.record(ctx, trial, err)
+	return result, err
+}
+
+// QueryVolumeAsync returns CnsQueryResult matching the given filter by using
+// CnsQueryAsync API.
+func (m *circuitBreakerManager) QueryVolumeAsync(ctx context.Context, queryFilter cnstypes.CnsQueryFilter,
+	querySelection *cnstypes.CnsQuerySelection) (*cnstypes.CnsQueryResult, error) {
+	trial, err := m.allow(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result, err := m.Manager.QueryVolumeAsync(ctx, queryFilter, querySelection)
+	m.record(ctx, trial, err)
+	return result, err
+}
+
+// QueryVolume returns volumes matching the given filter.
+func (m *circuitBreakerManager) QueryVolume(ctx context.Context,
+	queryFilter cnstypes.CnsQueryFilter) (*cnstypes.CnsQueryResult, error) {
+	trial, err := m.allow(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result, err := m.Manager.QueryVolume(ctx, queryFilter)
+	m.record(ctx, trial, err)
+	return result, err
+}
+
+// RelocateVolume migrates volumes to their target datastore as specified in
+// relocateSpecList.
+func (m *circuitBreakerManager) RelocateVolume(ctx context.Context,
+	relocateSpecList ...cnstypes.BaseCnsVolumeRelocateSpec) (*object.Task, error) {
+	trial, err := m.allow(ctx)
+	if err != nil {
+		return nil, err
+	}
+	task, err := m.Manager.RelocateVolume(ctx, relocateSpecList...)
+	m.record(ctx, trial, err)
+	return task, err
+}
+
+// ExpandVolume expands a volume to a new size.
+func (m *circuitBreakerManager) ExpandVolume(ctx context.Context, volumeID string, size int64) (string, error) {
+	trial, err := m.allow(ctx)
+	if err != nil {
+		return csifault.CSICnsUnavailableFault, err
+	}
+	faultType, err := m.Manager.ExpandVolume(ctx, volumeID, size)
+	m.record(ctx, trial, err)
+	return faultType, err
+}
+
+// ResetManager helps set new manager instance and VC configuration. The
+// circuit is closed as the new VC has not failed any call yet.
+func (m *circuitBreakerManager) ResetManager(ctx context.Context, vcenter *cnsvsphere.VirtualCenter) {
+	m.Manager.ResetManager(ctx, vcenter)
+	m.breaker.reset()
+}
+
+// ConfigureVolumeACLs configures net permissions for a given
+// CnsVolumeACLConfigureSpec.
+func (m *circuitBreakerManager) ConfigureVolumeACLs(ctx context.Context,
+	spec cnstypes.CnsVolumeACLConfigureSpec) error {
+	trial, err := m.allow(ctx)
+	if err != nil {
+		return err
+	}
+	err = m.Manager.ConfigureVolumeACLs(ctx, spec)
+	m.record(ctx, trial, err)
+	return err
+}
+
+// CreateSnapshot helps create a snapshot for a block volume.
+func (m *circuitBreakerManager) CreateSnapshot(ctx context.Context, volumeID string,
+	desc string) (*CnsSnapshotInfo, error) {
+	trial, err := m.allow(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snapshotInfo, err := m.Manager.CreateSnapshot(ctx, volumeID, desc)
+	m.record(ctx, trial, err)
+	return snapshotInfo, err
+}
+
+// DeleteSnapshot helps delete a snapshot for a block volume.
+func (m *circuitBreakerManager) DeleteSnapshot(ctx context.Context, volumeID string, snapshotID string) error {
+	trial, err := m.allow(ctx)
+	if err != nil {
+		return err
+	}
+	err = m.Manager.DeleteSnapshot(ctx, volumeID, snapshotID)
+	m.record(ctx, trial, err)
+	return err
+}
+
+// QuerySnapshots retrieves the list of snapshots based on the query filter.
+func (m *circuitBreakerManager) QuerySnapshots(ctx context.Context,
+	snapshotQueryFilter cnstypes.CnsSnapshotQueryFilter) (*cnstypes.CnsSnapshotQueryResult, error) {
+	trial, err := m.allow(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result, err := m.Manager.QuerySnapshots(ctx, snapshotQueryFilter)
+	m.record(ctx, trial, err)
+	return result, err
+}
+
+// getBatchUpdateVolumeMetadataError returns an error of the batch showing CNS
+// could not be reached, or any other error of the batch otherwise.
+func getBatchUpdateVolumeMetadataError(errs []error) error {
+	var batchErr error
+	for _, err := range errs {
+		if isCnsUnreachableError(err) {
+			return err
+		}
+		if err != nil {
+			batchErr = err
+		}
+	}
+	return batchErr
+}
+
+// getBatchAttachDetachError returns an error of the batch showing CNS could
+// not be reached, or any other error of the batch otherwise.
+func getBatchAttachDetachError(results map[string]*BatchAttachDetachResult) error {
+	var batchErr error
+	for _, result := range results {
+		if isCnsUnreachableError(result.Err) {
+			return result.Err
+		}
+		if result.Err != nil {
+			batchErr = result.Err
+		}
+	}
+	return batchErr
+}
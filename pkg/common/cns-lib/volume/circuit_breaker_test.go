@@ -0,0 +1,142 @@
+package volume
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	breaker := newCircuitBreaker(2, time.Minute, 3*time.Minute)
+	breaker.now = func() time.Time { return now }
+	unreachableErr := fmt.Errorf("failed to query volumes. err: %v", errors.New("dial tcp: connection refused"))
+
+	// Errors returned by CNS itself do not open the circuit.
+	for i := 0; i < 3; i++ {
+		if _, err := breaker.allow(); err != nil {
+			t.Fatalf("expected call to be allowed, got %v", err)
+		}
+		breaker.record(false, errors.New("CNS fault: NotFound"))
+	}
+
+	// Consecutive unreachable errors open the circuit.
+	for i := 0; i < 2; i++ {
+		if _, err := breaker.allow(); err != nil {
+			t.Fatalf("expected call to be allowed, got %v", err)
+		}
+		changed, open := breaker.record(false, unreachableErr)
+		if expectOpen := i == 1; changed != expectOpen || open != expectOpen {
+			t.Fatalf("call %d: expected circuit changed=%t open=%t, got changed=%t open=%t",
+				i, expectOpen, expectOpen, changed, open)
+		}
+	}
+	_, err := breaker.allow()
+	if !IsCnsUnavailableError(err) {
+		t.Fatalf("expected CnsUnavailableError while circuit is open, got %v", err)
+	}
+
+	// After the cooldown, a single trial call is allowed and re-opens the
+	// circuit on failure.
+	now = now.Add(time.Minute)
+	if trial, err := breaker.allow(); err != nil || !trial {
+		t.Fatalf("expected trial call to be allowed, got trial=%t err=%v", trial, err)
+	}
+	var unavailableErr *CnsUnavailableError
+	if _, err := breaker.allow(); !errors.As(err, &unavailableErr) || unavailableErr.RetryAfter != time.Minute {
+		t.Fatalf("expected CnsUnavailableError retrying after the cooldown while trial call is in flight, got %v",
+			err)
+	}
+
+	// Failed trial calls re-open the circuit for twice the previous cooldown,
+	// up to the maximum cooldown.
+	for _, cooldown := range []time.Duration{2 * time.Minute, 3 * time.Minute, 3 * time.Minute} {
+		if changed, open := breaker.record(true, unreachableErr); changed || !open {
+			t.Fatalf("expected circuit to remain open, got changed=%t open=%t", changed, open)
+		}
+		if _, err := breaker.allow(); !errors.As(err, &unavailableErr) || unavailableErr.RetryAfter != cooldown {
+			t.Fatalf("expected CnsUnavailableError retrying after %v after failed trial call, got %v", cooldown, err)
+		}
+		now = now.Add(cooldown)
+		if trial, err := breaker.allow(); err != nil || !trial {
+			t.Fatalf("expected trial call to be allowed, got trial=%t err=%v", trial, err)
+		}
+	}
+
+	// A successful trial call closes the circuit.
+	if changed, open := breaker.record(true, nil); !changed || open {
+		t.Fatalf("expected circuit to close, got changed=%t open=%t", changed, open)
+	}
+	if _, err := breaker.allow(); err != nil {
+		t.Fatalf("expected call to be allowed once circuit is closed, got %v", err)
+	}
+}
+
+func TestCircuitBreakerSingleTrialCall(t *testing.T) {
+	now := time.Now()
+	breaker := newCircuitBreaker(1, time.Minute, time.Minute)
+	breaker.now = func() time.Time { return now }
+	unreachableErr := errors.New("dial tcp: connection refused")
+
+	// A call started before the circuit opened is still in flight.
+	if _, err := breaker.allow(); err != nil {
+		t.Fatalf("expected call to be allowed, got %v", err)
+	}
+	if _, err := breaker.allow(); err != nil {
+		t.Fatalf("expected call to be allowed, got %v", err)
+	}
+	breaker.record(false, unreachableErr)
+	now = now.Add(time.Minute)
+	if trial, err := breaker.allow(); err != nil || !trial {
+		t.Fatalf("expected trial call to be allowed, got trial=%t err=%v", trial, err)
+	}
+
+	// The call started before the circuit opened completes while the trial
+	// call is in flight, which must not let another trial call through.
+	breaker.record(false, unreachableErr)
+	if _, err := breaker.allow(); !IsCnsUnavailableError(err) {
+		t.Fatalf("expected CnsUnavailableError while the trial call is in flight, got %v", err)
+	}
+	breaker.abandon(false)
+	if _, err := breaker.allow(); !IsCnsUnavailableError(err) {
+		t.Fatalf("expected CnsUnavailableError while the trial call is in flight, got %v", err)
+	}
+}
+
+func TestIsCnsUnreachableError(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+	for _, test := range []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"connection refused", errors.New("dial tcp 10.0.0.1:443: connect: connection refused"), true},
+		{"service unavailable", errors.New("503 Service Unavailable"), true},
+		{"SOAP client timeout", fmt.Errorf("Post \"https://vc/sdk\": %w (Client.Timeout exceeded while awaiting "+
+			"headers)", context.DeadlineExceeded), true},
+		{"network error", &net.OpError{Op: "dial", Err: errors.New("no route to host")}, true},
+		{"deadline of the caller", &url.Error{Op: "Post", URL: "https://vc/sdk", Err: ctx.Err()}, false},
+		{"caller cancelled", fmt.Errorf("failed to query volumes: %w", context.Canceled), false},
+		{"CNS fault", errors.New("CNS fault: NotFound"), false},
+	} {
+		if isCnsUnreachableError(test.err) != test.expected {
+			t.Errorf("%s: expected isCnsUnreachableError %t for %v", test.name, test.expected, test.err)
+		}
+	}
+}
+
+func TestCircuitBreakerManagerIgnoresCallerDeadline(t *testing.T) {
+	m := &circuitBreakerManager{breaker: newCircuitBreaker(1, time.Minute, time.Minute)}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	m.record(ctx, false, errors.New("dial tcp: connection refused"))
+	if _, err := m.allow(context.Background()); err != nil {
+		t.Errorf("expected failures of calls whose context is done not to open the circuit, got %v", err)
+	}
+}
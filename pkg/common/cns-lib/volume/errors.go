@@ -0,0 +1,166 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package volume
+
+import (
+	"errors"
+
+	csifault "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/fault"
+)
+
+// ErrorKind classifies the errors of CNS operations by how callers should
+// react to them.
+type ErrorKind int
+
+const (
+	// ErrorKindUnknown is the kind of errors which could not be classified.
+	ErrorKindUnknown ErrorKind = iota
+	// ErrorKindNotFound is the kind of errors caused by a missing object,
+	// e.g. the volume or the node VM.
+	ErrorKindNotFound
+	// ErrorKindResourceExhausted is the kind of errors caused by a lack of
+	// capacity or a quota or limit being reached.
+	ErrorKindResourceExhausted
+	// ErrorKindInvalidArgument is the kind of errors caused by a request CNS
+	// rejected as invalid. Retrying the same request fails again.
+	ErrorKindInvalidArgument
+	// ErrorKindUnavailable is the kind of errors caused by CNS or vCenter
+	// being temporarily unavailable.
+	ErrorKindUnavailable
+)
+
+// String returns the name of the ErrorKind.
+func (k ErrorKind) String() string {
+	switch k {
+	case ErrorKindNotFound:
+		return "NotFound"
+	case ErrorKindResourceExhausted:
+		return "ResourceExhausted"
+	case ErrorKindInvalidArgument:
+		return "InvalidArgument"
+	case ErrorKindUnavailable:
+		return "Unavailable"
+	}
+	return "Unknown"
+}
+
+// faultErrorKinds maps the fault types returned along with the errors of the
+// volume manager to their ErrorKind.
+var faultErrorKinds = map[string]ErrorKind{
+	vimFaultPrefix + "NotFound":                   ErrorKindNotFound,
+	vimFaultPrefix + "ManagedObjectNotFound":      ErrorKindNotFound,
+	vimFaultPrefix + "FileNotFound":               ErrorKindNotFound,
+	csifault.CSINotFoundFault:                     ErrorKindNotFound,
+	vimFaultPrefix + "InsufficientResourcesFault": ErrorKindResourceExhausted,
+	vimFaultPrefix + "InsufficientStorageSpace":   ErrorKindResourceExhausted,
+	vimFaultPrefix + "NoDiskSpace":                ErrorKindResourceExhausted,
+	csifault.CSIResourceExhaustedFault:            ErrorKindResourceExhausted,
+	vimFaultPrefix + "InvalidArgument":            ErrorKindInvalidArgument,
+	vimFaultPrefix + "InvalidDatastore":           ErrorKindInvalidArgument,
+	vimFaultPrefix + "NotSupported":               ErrorKindInvalidArgument,
+	csifault.CSIInvalidArgumentFault:              ErrorKindInvalidArgument,
+	vimFaultPrefix + "HostNotConnected":           ErrorKindUnavailable,
+	vimFaultPrefix + "HostCommunication":          ErrorKindUnavailable,
+	vimFaultPrefix + "NotAuthenticated":           ErrorKindUnavailable,
+	vimFaultPrefix + "InaccessibleDatastore":      ErrorKindUnavailable,
+	csifault.CSICnsUnavailableFault:               ErrorKindUnavailable,
+}
+
+// CnsError is an error of a CNS operation along with its fault type and
+// ErrorKind, and the ID of the volume the operation was called for, if any.
+// Use errors.As to retrieve it from wrapped errors.
+type CnsError struct {
+	Kind      ErrorKind
+	FaultType string
+	VolumeID  string
+	Err       error
+}
+
+// Error implements the error interface.
+func (e *CnsError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *CnsError) Unwrap() error {
+	return e.Err
+}
+
+// NewCnsError returns a CnsError classifying err from the fault type returned
+// along with it by the volume manager.
+func NewCnsError(faultType string, err error) *CnsError {
+	return &CnsError{
+		Kind:      getErrorKind(faultType, err),
+		FaultType: faultType,
+		Err:       err,
+	}
+}
+
+// NewCnsVolumeError returns a CnsError classifying err, returned by the volume
+// manager along with the given fault type for the volume with the given ID.
+// It returns nil if err is nil.
+func NewCnsVolumeError(faultType string, volumeID string, err error) error {
+	if err == nil {
+		return nil
+	}
+	cnsErr := NewCnsError(faultType, err)
+	cnsErr.VolumeID = volumeID
+	return cnsErr
+}
+
+// IsErrorKind returns whether err is classified, by a CnsError it wraps, as
+// the given ErrorKind.
+func IsErrorKind(err error, kind ErrorKind) bool {
+	var cnsErr *CnsError
+	return errors.As(err, &cnsErr) && cnsErr.Kind == kind
+}
+
+// GetErrorKind returns the ErrorKind of err, classified from the given fault
+// type returned along with err by the volume manager. Errors already
+// classified as a CnsError keep their kind.
+func GetErrorKind(faultType string, err error) ErrorKind {
+	var cnsErr *CnsError
+	if errors.As(err, &cnsErr) {
+		return cnsErr.Kind
+	}
+	return getErrorKind(faultType, err)
+}
+
+// IsRetryableError returns whether the operation which failed with err, along
+// with the given fault type, is worth retrying right away. Errors rejected by
+// an open circuit breaker are not, as CNS is not called until it closes.
+func IsRetryableError(faultType string, err error) bool {
+	if err == nil || IsCnsUnavailableError(err) {
+		return false
+	}
+	return GetErrorKind(faultType, err) == ErrorKindUnavailable
+}
+
+// IsNotFoundError returns whether the operation which failed with err, along
+// with the given fault type, failed as the volume or node VM doesn't exist.
+// DeleteVolume and ControllerUnpublishVolume then succeed, as the CSI spec
+// requires, since there is nothing left to delete or detach.
+func IsNotFoundError(faultType string, err error) bool {
+	return err != nil && GetErrorKind(faultType, err) == ErrorKindNotFound
+}
+
+func getErrorKind(faultType string, err error) ErrorKind {
+	if IsCnsUnavailableError(err) || isCnsUnreachableError(err) {
+		return ErrorKindUnavailable
+	}
+	return faultErrorKinds[faultType]
+}
@@ -0,0 +1,103 @@
+package volume
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	csifault "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/fault"
+)
+
+func TestGetErrorKind(t *testing.T) {
+	tests := []struct {
+		faultType    string
+		err          error
+		expectedKind ErrorKind
+	}{
+		{"vim.fault.NotFound", errors.New("failed to attach disk"), ErrorKindNotFound},
+		{"vim.fault.InsufficientStorageSpace", errors.New("failed to create volume"), ErrorKindResourceExhausted},
+		{csifault.CSIResourceExhaustedFault, errors.New("limit reached"), ErrorKindResourceExhausted},
+		{"vim.fault.InvalidArgument", errors.New("failed to create volume"), ErrorKindInvalidArgument},
+		{csifault.CSICnsUnavailableFault, &CnsUnavailableError{}, ErrorKindUnavailable},
+		{csifault.CSIInternalFault, errors.New("dial tcp: connection refused"), ErrorKindUnavailable},
+		{"vim.fault.CnsFault", errors.New("failed to create volume"), ErrorKindUnknown},
+		{csifault.CSIInternalFault, fmt.Errorf("wrapped: %w",
+			NewCnsError("vim.fault.NotFound", errors.New("not found"))), ErrorKindNotFound},
+	}
+	for _, test := range tests {
+		if kind := GetErrorKind(test.faultType, test.err); kind != test.expectedKind {
+			t.Errorf("fault %q with error %q: expected kind %v, got %v", test.faultType, test.err,
+				test.expectedKind, kind)
+		}
+	}
+}
+
+func TestIsRetryableError(t *testing.T) {
+	tests := []struct {
+		faultType string
+		err       error
+		expected  bool
+	}{
+		{"", nil, false},
+		{"vim.fault.HostCommunication", errors.New("failed to attach disk"), true},
+		{csifault.CSIInternalFault, errors.New("dial tcp: connection refused"), true},
+		{csifault.CSICnsUnavailableFault, &CnsUnavailableError{}, false},
+		{"vim.fault.NotFound", errors.New("failed to attach disk"), false},
+	}
+	for _, test := range tests {
+		if retryable := IsRetryableError(test.faultType, test.err); retryable != test.expected {
+			t.Errorf("fault %q with error %v: expected retryable %v, got %v", test.faultType, test.err,
+				test.expected, retryable)
+		}
+	}
+}
+
+func TestIsNotFoundError(t *testing.T) {
+	tests := []struct {
+		faultType string
+		err       error
+		expected  bool
+	}{
+		{"vim.fault.NotFound", nil, false},
+		{"vim.fault.NotFound", errors.New("failed to detach disk"), true},
+		{csifault.CSINotFoundFault, errors.New("volume not found"), true},
+		{csifault.CSIInternalFault, fmt.Errorf("wrapped: %w",
+			NewCnsError("vim.fault.FileNotFound", errors.New("not found"))), true},
+		{"vim.fault.HostCommunication", errors.New("failed to delete volume"), false},
+	}
+	for _, test := range tests {
+		if notFound := IsNotFoundError(test.faultType, test.err); notFound != test.expected {
+			t.Errorf("fault %q with error %v: expected not found %v, got %v", test.faultType, test.err,
+				test.expected, notFound)
+		}
+	}
+}
+
+func TestNewCnsVolumeError(t *testing.T) {
+	if err := NewCnsVolumeError("vim.fault.NotFound", "vol-1", nil); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	cause := errors.New("volume not found")
+	err := fmt.Errorf("failed to delete volume: %w",
+		NewCnsVolumeError("vim.fault.NotFound", "vol-1", cause))
+	var cnsErr *CnsError
+	if !errors.As(err, &cnsErr) {
+		t.Fatalf("expected a CnsError in %v", err)
+	}
+	if cnsErr.VolumeID != "vol-1" || cnsErr.FaultType != "vim.fault.NotFound" || cnsErr.Kind != ErrorKindNotFound {
+		t.Errorf("unexpected CnsError %+v", cnsErr)
+	}
+	if !errors.Is(err, cause) {
+		t.Errorf("expected %v to wrap %v", err, cause)
+	}
+	if !IsErrorKind(err, ErrorKindNotFound) || IsErrorKind(err, ErrorKindUnavailable) {
+		t.Errorf("unexpected kind of %v", err)
+	}
+	if IsErrorKind(cause, ErrorKindUnknown) {
+		t.Errorf("expected %v not to be classified", cause)
+	}
+	if faultType := ExtractFaultTypeFromErr(context.Background(), err); faultType != "vim.fault.NotFound" {
+		t.Errorf("expected fault type vim.fault.NotFound, got %q", faultType)
+	}
+}
@@ -36,6 +36,7 @@ import (
 	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
 	csifault "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/fault"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/prometheus"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/tracing"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/internalapis/cnsvolumeoperationrequest"
 )
@@ -74,12 +75,25 @@ type Manager interface {
 	// When DetachVolume failed, the first return value (faultType) and second return value(error) need to be set, and
 	// should not be nil.
 	DetachVolume(ctx context.Context, vm *cnsvsphere.VirtualMachine, volumeID string) (string, error)
+	// BatchAttachVolumes attaches the given volumes to a virtual machine using a
+	// single CNS task. The results are keyed by volume ID.
+	BatchAttachVolumes(ctx context.Context, vm *cnsvsphere.VirtualMachine,
+		volumeIDs []string, checkNVMeController bool) map[string]*BatchAttachDetachResult
+	// BatchDetachVolumes detaches the given volumes from a virtual machine using
+	// a single CNS task. The results are keyed by volume ID.
+	BatchDetachVolumes(ctx context.Context, vm *cnsvsphere.VirtualMachine,
+		volumeIDs []string) map[string]*BatchAttachDetachResult
 	// DeleteVolume deletes a volume given its spec.
 	// When DeleteVolume failed, the first return value (faultType) and second return value(error) need to be set, and
-	// should not be nil.
+	// should not be nil. The error wraps a CnsError.
 	DeleteVolume(ctx context.Context, volumeID string, deleteDisk bool) (string, error)
 	// UpdateVolumeMetadata updates a volume metadata given its spec.
+	// Errors wrap a CnsError carrying the fault type of the failure.
 	UpdateVolumeMetadata(ctx context.Context, spec *cnstypes.CnsVolumeMetadataUpdateSpec) error
+	// BatchUpdateVolumeMetadata updates the metadata of the given volumes using
+	// a single CNS task. Each volume must be given at most once. The errors
+	// are returned in the order of the specs, and wrap a CnsError.
+	BatchUpdateVolumeMetadata(ctx context.Context, specs []cnstypes.CnsVolumeMetadataUpdateSpec) []error
 	// QueryVolumeInfo calls the CNS QueryVolumeInfo API and return a task, from
 	// which CnsQueryVolumeInfoResult is extracted.
 	QueryVolumeInfo(ctx context.Context, volumeIDList []cnstypes.CnsVolumeId) (*cnstypes.CnsQueryVolumeInfoResult, error)
@@ -99,7 +113,7 @@ type Manager interface {
 	RelocateVolume(ctx context.Context, relocateSpecList ...cnstypes.BaseCnsVolumeRelocateSpec) (*object.Task, error)
 	// ExpandVolume expands a volume to a new size.
 	// When ExpandVolume failed, the first return value (faultType) and second return value(error) need to be set, and
-	// should not be nil.
+	// should not be nil. The error wraps a CnsError.
 	ExpandVolume(ctx context.Context, volumeID string, size int64) (string, error)
 	// ResetManager helps set new manager instance and VC configuration.
 	ResetManager(ctx context.Context, vcenter *cnsvsphere.VirtualCenter)
@@ -124,6 +138,15 @@ type CnsVolumeInfo struct {
 	VolumeID     cnstypes.CnsVolumeId
 }
 
+// BatchAttachDetachResult holds the outcome of attaching or detaching a single
+// volume as part of a batched CNS task.
+type BatchAttachDetachResult struct {
+	// DiskUUID is the UUID of the attached disk. It is only set for attach.
+	DiskUUID  string
+	FaultType string
+	Err       error
+}
+
 type CnsSnapshotInfo struct {
 	SnapshotID                string
 	SourceVolumeID            string
@@ -134,6 +157,9 @@ type CnsSnapshotInfo struct {
 var (
 	// managerInstance is a Manager singleton.
 	managerInstance *defaultManager
+	// managerInstanceWithCircuitBreaker wraps managerInstance with a circuit
+	// breaker failing CNS calls fast while CNS is unavailable.
+	managerInstanceWithCircuitBreaker *circuitBreakerManager
 	// managerInstanceLock is used for mitigating race condition during
 	// read/write on manager instance.
 	managerInstanceLock sync.Mutex
@@ -168,15 +194,21 @@ func GetManager(ctx context.Context, vc *cnsvsphere.VirtualCenter,
 	defer managerInstanceLock.Unlock()
 	if managerInstance != nil {
 		log.Infof("Retrieving existing defaultManager...")
-		return managerInstance
+		return managerInstanceWithCircuitBreaker
 	}
 	log.Infof("Initializing new defaultManager...")
+	var vcConfig *cnsvsphere.VirtualCenterConfig
+	if vc != nil {
+		vcConfig = vc.Config
+	}
 	managerInstance = &defaultManager{
 		virtualCenter:              vc,
 		operationStore:             operationStore,
 		idempotencyHandlingEnabled: idempotencyHandlingEnabled,
+		queryCache:                 newVolumeQueryCache(vcConfig),
 	}
-	return managerInstance
+	managerInstanceWithCircuitBreaker = newCircuitBreakerManager(managerInstance)
+	return managerInstanceWithCircuitBreaker
 }
 
 // DefaultManager provides functionality to manage volumes.
@@ -184,6 +216,8 @@ type defaultManager struct {
 	virtualCenter              *cnsvsphere.VirtualCenter
 	operationStore             cnsvolumeoperationrequest.VolumeOperationRequest
 	idempotencyHandlingEnabled bool
+	// queryCache caches the results of the queries of volumes by ID.
+	queryCache *volumeQueryCache
 }
 
 // ClearTaskInfoObjects is a go routine which runs in the background to clean
@@ -241,6 +275,7 @@ func (m *defaultManager) ResetManager(ctx context.Context, vcenter *cnsvsphere.V
 	defer managerInstanceLock.Unlock()
 	log.Infof("Re-initializing defaultManager.virtualCenter")
 	managerInstance.virtualCenter = vcenter
+	managerInstance.queryCache.reset(vcenter.Config)
 	if m.virtualCenter.Client != nil {
 		m.virtualCenter.Client.Timeout = time.Duration(vcenter.Config.VCClientTimeout) * time.Minute
 		log.Infof("VC client timeout is set to %v", m.virtualCenter.Client.Timeout)
@@ -349,7 +384,7 @@ func (m *defaultManager) createVolumeWithImprovedIdempotency(ctx context.Context
 		}
 	}
 
-	taskInfo, err := task.WaitForResult(ctx, nil)
+	taskInfo, err := m.waitForTaskResult(ctx, task)
 	if err != nil {
 		if cnsvsphere.IsManagedObjectNotFound(err, task.Reference()) {
 			log.Debugf("CreateVolume task %s not found in vCenter. Querying CNS "+
@@ -491,7 +526,7 @@ func (m *defaultManager) createVolume(ctx context.Context, spec *cnstypes.CnsVol
 	}
 
 	// Get the taskInfo.
-	taskInfo, err := cns.GetTaskInfo(ctx, task)
+	taskInfo, err := m.getTaskInfo(ctx, task)
 	if err != nil || taskInfo == nil {
 		log.Errorf("failed to get taskInfo for CreateVolume task with err: %v", err)
 		if err != nil {
@@ -548,6 +583,12 @@ func (m *defaultManager) createVolume(ctx context.Context, spec *cnstypes.CnsVol
 // CreateVolume creates a new volume given its spec.
 func (m *defaultManager) CreateVolume(ctx context.Context, spec *cnstypes.CnsVolumeCreateSpec) (*CnsVolumeInfo,
 	string, error) {
+	var datastores []string
+	for _, datastore := range spec.Datastores {
+		datastores = append(datastores, datastore.Value)
+	}
+	ctx, span := tracing.StartSpan(ctx, "CreateVolume", m.getVCHostAttribute(),
+		tracing.AttributeDatastore.String(strings.Join(datastores, ",")))
 	internalCreateVolume := func() (*CnsVolumeInfo, string, error) {
 		log := logger.GetLogger(ctx)
 		var faultType string
@@ -571,6 +612,10 @@ func (m *defaultManager) CreateVolume(ctx context.Context, spec *cnstypes.CnsVol
 	}
 	start := time.Now()
 	resp, faultType, err := internalCreateVolume()
+	if resp != nil {
+		span.SetAttributes(tracing.AttributeVolumeID.String(resp.VolumeID.Id))
+	}
+	tracing.EndSpan(span, faultType, err)
 	log := logger.GetLogger(ctx)
 	log.Debugf("internalCreateVolume: returns fault %q", faultType)
 	if err != nil {
@@ -579,6 +624,9 @@ func (m *defaultManager) CreateVolume(ctx context.Context, spec *cnstypes.CnsVol
 	} else {
 		prometheus.CnsControlOpsHistVec.WithLabelValues(prometheus.PrometheusCnsCreateVolumeOpType,
 			prometheus.PrometheusPassStatus).Observe(time.Since(start).Seconds())
+		if resp != nil {
+			m.queryCache.invalidate(resp.VolumeID.Id)
+		}
 	}
 
 	return resp, faultType, err
@@ -587,6 +635,8 @@ func (m *defaultManager) CreateVolume(ctx context.Context, spec *cnstypes.CnsVol
 // AttachVolume attaches a volume to a virtual machine given the spec.
 func (m *defaultManager) AttachVolume(ctx context.Context,
 	vm *cnsvsphere.VirtualMachine, volumeID string, checkNVMeController bool) (string, string, error) {
+	ctx, span := tracing.StartSpan(ctx, "AttachVolume", m.getVCHostAttribute(),
+		tracing.AttributeVolumeID.String(volumeID), tracing.AttributeVM.String(vm.UUID))
 	internalAttachVolume := func() (string, string, error) {
 		log := logger.GetLogger(ctx)
 		var faultType string
@@ -619,7 +669,7 @@ func (m *defaultManager) AttachVolume(ctx context.Context,
 			return "", faultType, err
 		}
 		// Get the taskInfo.
-		taskInfo, err := cns.GetTaskInfo(ctx, task)
+		taskInfo, err := m.getTaskInfo(ctx, task)
 		if err != nil || taskInfo == nil {
 			log.Errorf("failed to get taskInfo for AttachVolume task from vCenter %q with err: %v",
 				m.virtualCenter.Config.Host, err)
@@ -671,6 +721,7 @@ func (m *defaultManager) AttachVolume(ctx context.Context,
 	}
 	start := time.Now()
 	resp, faultType, err := internalAttachVolume()
+	tracing.EndSpan(span, faultType, err)
 	log := logger.GetLogger(ctx)
 	log.Debugf("internalAttachVolume: returns fault %q for volume %q", faultType, volumeID)
 	if err != nil {
@@ -740,7 +791,7 @@ func (m *defaultManager) DetachVolume(ctx context.Context, vm *cnsvsphere.Virtua
 				volumeID, vm, err)
 		}
 		// Get the taskInfo.
-		taskInfo, err := cns.GetTaskInfo(ctx, task)
+		taskInfo, err := m.getTaskInfo(ctx, task)
 		if err != nil || taskInfo == nil {
 			log.Errorf("failed to get taskInfo for DetachVolume task from vCenter %q with err: %v",
 				m.virtualCenter.Config.Host, err)
@@ -804,8 +855,272 @@ func (m *defaultManager) DetachVolume(ctx context.Context, vm *cnsvsphere.Virtua
 	return faultType, err
 }
 
+// BatchAttachVolumes attaches the given volumes to a virtual machine using a
+// single CNS task, so that vCenter reconfigures the VM only once.
+func (m *defaultManager) BatchAttachVolumes(ctx context.Context, vm *cnsvsphere.VirtualMachine,
+	volumeIDs []string, checkNVMeController bool) map[string]*BatchAttachDetachResult {
+	internalBatchAttachVolumes := func() map[string]*BatchAttachDetachResult {
+		log := logger.GetLogger(ctx)
+		err := validateManager(ctx, m)
+		if err != nil {
+			return newBatchAttachDetachResults(volumeIDs, ExtractFaultTypeFromErr(ctx, err), err)
+		}
+		// Set up the VC connection.
+		err = m.virtualCenter.ConnectCns(ctx)
+		if err != nil {
+			log.Errorf("ConnectCns failed with err: %+v", err)
+			return newBatchAttachDetachResults(volumeIDs, ExtractFaultTypeFromErr(ctx, err), err)
+		}
+		// Construct the CNS AttachSpec list.
+		var cnsAttachSpecList []cnstypes.CnsVolumeAttachDetachSpec
+		for _, volumeID := range volumeIDs {
+			cnsAttachSpecList = append(cnsAttachSpecList, cnstypes.CnsVolumeAttachDetachSpec{
+				VolumeId: cnstypes.CnsVolumeId{
+					Id: volumeID,
+				},
+				Vm: vm.Reference(),
+			})
+		}
+		// Call the CNS AttachVolume.
+		task, err := m.virtualCenter.CnsClient.AttachVolume(ctx, cnsAttachSpecList)
+		if err != nil {
+			log.Errorf("CNS AttachVolume failed from vCenter %q with err: %v", m.virtualCenter.Config.Host, err)
+			return newBatchAttachDetachResults(volumeIDs, ExtractFaultTypeFromErr(ctx, err), err)
+		}
+		// Get the taskInfo.
+		taskInfo, err := m.getTaskInfo(ctx, task)
+		if err != nil || taskInfo == nil {
+			log.Errorf("failed to get taskInfo for AttachVolume task from vCenter %q with err: %v",
+				m.virtualCenter.Config.Host, err)
+			if err != nil {
+				return newBatchAttachDetachResults(volumeIDs, ExtractFaultTypeFromErr(ctx, err), err)
+			}
+			return newBatchAttachDetachResults(volumeIDs, csifault.CSITaskInfoEmptyFault,
+				fmt.Errorf("taskInfo is empty for AttachVolume task"))
+		}
+		log.Infof("BatchAttachVolumes: volumeIDs: %v, vm: %q, opId: %q", volumeIDs, vm.String(), taskInfo.ActivationId)
+		// Get the task results for the given task.
+		taskResults, err := cns.GetTaskResultArray(ctx, taskInfo)
+		if err != nil {
+			log.Errorf("unable to find AttachVolume results from vCenter %q with taskID %s and attachResults %v",
+				m.virtualCenter.Config.Host, taskInfo.Task.Value, taskResults)
+			return newBatchAttachDetachResults(volumeIDs, ExtractFaultTypeFromErr(ctx, err), err)
+		}
+		results := make(map[string]*BatchAttachDetachResult)
+		for index, taskResult := range taskResults {
+			if taskResult == nil {
+				continue
+			}
+			volumeOperationRes := taskResult.GetCnsVolumeOperationResult()
+			volumeID := volumeOperationRes.VolumeId.Id
+			if volumeID == "" && index < len(volumeIDs) {
+				// Results are returned in the order of the specs.
+				volumeID = volumeIDs[index]
+			}
+			if volumeOperationRes.Fault != nil {
+				faultType := ExtractFaultTypeFromVolumeResponseResult(ctx, volumeOperationRes)
+				_, isResourceInUseFault := volumeOperationRes.Fault.Fault.(*vim25types.ResourceInUse)
+				if isResourceInUseFault {
+					log.Infof("observed ResourceInUse fault while attaching volume: %q with vm: %q", volumeID, vm.String())
+					// Check if volume is already attached to the requested node.
+					diskUUID, err := IsDiskAttached(ctx, vm, volumeID, checkNVMeController)
+					if err != nil {
+						results[volumeID] = &BatchAttachDetachResult{FaultType: faultType, Err: err}
+						continue
+					}
+					if diskUUID != "" {
+						results[volumeID] = &BatchAttachDetachResult{DiskUUID: diskUUID}
+						continue
+					}
+				}
+				results[volumeID] = &BatchAttachDetachResult{
+					FaultType: faultType,
+					Err: logger.LogNewErrorf(log, "failed to attach cns volume: %q to node vm: %q. fault: %q. opId: %q",
+						volumeID, vm.String(), spew.Sdump(volumeOperationRes.Fault), taskInfo.ActivationId),
+				}
+				continue
+			}
+			attachResult, ok := taskResult.(*cnstypes.CnsVolumeAttachResult)
+			if !ok {
+				continue
+			}
+			log.Infof("BatchAttachVolumes: Volume attached successfully. volumeID: %q, opId: %q, vm: %q, diskUUID: %q",
+				volumeID, taskInfo.ActivationId, vm.String(), attachResult.DiskUUID)
+			results[volumeID] = &BatchAttachDetachResult{DiskUUID: attachResult.DiskUUID}
+		}
+		for _, volumeID := range volumeIDs {
+			if _, ok := results[volumeID]; !ok {
+				results[volumeID] = &BatchAttachDetachResult{
+					FaultType: csifault.CSITaskResultEmptyFault,
+					Err: logger.LogNewErrorf(log, "taskResult is empty for volume %q in AttachVolume task: %q, opId: %q",
+						volumeID, taskInfo.Task.Value, taskInfo.ActivationId),
+				}
+			}
+		}
+		return results
+	}
+	start := time.Now()
+	results := internalBatchAttachVolumes()
+	observeBatchAttachDetachResults(prometheus.PrometheusCnsAttachVolumeOpType, results, start)
+	return results
+}
+
+// BatchDetachVolumes detaches the given volumes from a virtual machine using
+// a single CNS task, so that vCenter reconfigures the VM only once.
+func (m *defaultManager) BatchDetachVolumes(ctx context.Context, vm *cnsvsphere.VirtualMachine,
+	volumeIDs []string) map[string]*BatchAttachDetachResult {
+	internalBatchDetachVolumes := func() map[string]*BatchAttachDetachResult {
+		log := logger.GetLogger(ctx)
+		err := validateManager(ctx, m)
+		if err != nil {
+			return newBatchAttachDetachResults(volumeIDs, ExtractFaultTypeFromErr(ctx, err), err)
+		}
+		// Set up the VC connection.
+		err = m.virtualCenter.ConnectCns(ctx)
+		if err != nil {
+			log.Errorf("ConnectCns failed with err: %+v", err)
+			return newBatchAttachDetachResults(volumeIDs, ExtractFaultTypeFromErr(ctx, err), err)
+		}
+		// Construct the CNS DetachSpec list.
+		var cnsDetachSpecList []cnstypes.CnsVolumeAttachDetachSpec
+		for _, volumeID := range volumeIDs {
+			cnsDetachSpecList = append(cnsDetachSpecList, cnstypes.CnsVolumeAttachDetachSpec{
+				VolumeId: cnstypes.CnsVolumeId{
+					Id: volumeID,
+				},
+				Vm: vm.Reference(),
+			})
+		}
+		// Call the CNS DetachVolume.
+		task, err := m.virtualCenter.CnsClient.DetachVolume(ctx, cnsDetachSpecList)
+		if err != nil {
+			if cnsvsphere.IsManagedObjectNotFound(err, vm.Reference()) {
+				// Node VM is deleted and not present in the vCenter inventory,
+				// marking detach of all the volumes as successful.
+				log.Infof("Node VM: %v not found on vCenter. Marking Detach for volumes: %v successful. err: %v",
+					vm, volumeIDs, err)
+				return newBatchAttachDetachResults(volumeIDs, "", nil)
+			}
+			if cnsvsphere.IsNotFoundError(err) {
+				// One of the volumes is not found, fall back to detaching the
+				// volumes one by one to find out which ones are already detached.
+				log.Infof("VolumeIDs: %v, not found. Detaching the volumes individually", volumeIDs)
+				results := make(map[string]*BatchAttachDetachResult)
+				for _, volumeID := range volumeIDs {
+					faultType, err := m.DetachVolume(ctx, vm, volumeID)
+					results[volumeID] = &BatchAttachDetachResult{FaultType: faultType, Err: err}
+				}
+				return results
+			}
+			return newBatchAttachDetachResults(volumeIDs, ExtractFaultTypeFromErr(ctx, err),
+				logger.LogNewErrorf(log, "failed to detach cns volumes: %v from node vm: %+v. err: %v",
+					volumeIDs, vm, err))
+		}
+		// Get the taskInfo.
+		taskInfo, err := m.getTaskInfo(ctx, task)
+		if err != nil || taskInfo == nil {
+			log.Errorf("failed to get taskInfo for DetachVolume task from vCenter %q with err: %v",
+				m.virtualCenter.Config.Host, err)
+			if err != nil {
+				return newBatchAttachDetachResults(volumeIDs, ExtractFaultTypeFromErr(ctx, err), err)
+			}
+			return newBatchAttachDetachResults(volumeIDs, csifault.CSITaskInfoEmptyFault,
+				fmt.Errorf("taskInfo is empty for DetachVolume task"))
+		}
+		log.Infof("BatchDetachVolumes: volumeIDs: %v, vm: %q, opId: %q", volumeIDs, vm.String(), taskInfo.ActivationId)
+		// Get the task results for the given task.
+		taskResults, err := cns.GetTaskResultArray(ctx, taskInfo)
+		if err != nil {
+			log.Errorf("unable to find DetachVolume task results from vCenter %q with taskID %s and detachResults %v",
+				m.virtualCenter.Config.Host, taskInfo.Task.Value, taskResults)
+			return newBatchAttachDetachResults(volumeIDs, ExtractFaultTypeFromErr(ctx, err), err)
+		}
+		results := make(map[string]*BatchAttachDetachResult)
+		for index, taskResult := range taskResults {
+			if taskResult == nil {
+				continue
+			}
+			volumeOperationRes := taskResult.GetCnsVolumeOperationResult()
+			volumeID := volumeOperationRes.VolumeId.Id
+			if volumeID == "" && index < len(volumeIDs) {
+				// Results are returned in the order of the specs.
+				volumeID = volumeIDs[index]
+			}
+			if volumeOperationRes.Fault != nil {
+				faultType := ExtractFaultTypeFromVolumeResponseResult(ctx, volumeOperationRes)
+				_, isNotFoundFault := volumeOperationRes.Fault.Fault.(*vim25types.NotFound)
+				if isNotFoundFault {
+					// Check if volume is already detached from the VM.
+					diskUUID, err := IsDiskAttached(ctx, vm, volumeID, false)
+					if err != nil {
+						log.Errorf("DetachVolume fault: %+v. Unable to check if volume: %q is already detached from vm: %+v",
+							spew.Sdump(volumeOperationRes.Fault), volumeID, vm)
+						results[volumeID] = &BatchAttachDetachResult{FaultType: faultType, Err: err}
+						continue
+					}
+					if diskUUID == "" {
+						log.Infof("BatchDetachVolumes: volumeID: %q not found on vm: %+v. Assuming it is already detached",
+							volumeID, vm)
+						results[volumeID] = &BatchAttachDetachResult{}
+						continue
+					}
+				}
+				results[volumeID] = &BatchAttachDetachResult{
+					FaultType: faultType,
+					Err: logger.LogNewErrorf(log, "failed to detach cns volume: %q from node vm: %+v. fault: %+v, opId: %q",
+						volumeID, vm, spew.Sdump(volumeOperationRes.Fault), taskInfo.ActivationId),
+				}
+				continue
+			}
+			log.Infof("BatchDetachVolumes: Volume detached successfully. volumeID: %q, vm: %q, opId: %q",
+				volumeID, vm.String(), taskInfo.ActivationId)
+			results[volumeID] = &BatchAttachDetachResult{}
+		}
+		for _, volumeID := range volumeIDs {
+			if _, ok := results[volumeID]; !ok {
+				results[volumeID] = &BatchAttachDetachResult{
+					FaultType: csifault.CSITaskResultEmptyFault,
+					Err: logger.LogNewErrorf(log, "taskResult is empty for volume %q in DetachVolume task: %q, opId: %q",
+						volumeID, taskInfo.Task.Value, taskInfo.ActivationId),
+				}
+			}
+		}
+		return results
+	}
+	start := time.Now()
+	results := internalBatchDetachVolumes()
+	observeBatchAttachDetachResults(prometheus.PrometheusCnsDetachVolumeOpType, results, start)
+	return results
+}
+
+// newBatchAttachDetachResults returns the same result for all the given
+// volumes, for outcomes affecting the whole batch.
+func newBatchAttachDetachResults(volumeIDs []string, faultType string,
+	err error) map[string]*BatchAttachDetachResult {
+	results := make(map[string]*BatchAttachDetachResult)
+	for _, volumeID := range volumeIDs {
+		results[volumeID] = &BatchAttachDetachResult{FaultType: faultType, Err: err}
+	}
+	return results
+}
+
+// observeBatchAttachDetachResults records the duration of a batched CNS
+// operation for each volume of the batch.
+func observeBatchAttachDetachResults(opType string, results map[string]*BatchAttachDetachResult, start time.Time) {
+	for _, result := range results {
+		status := prometheus.PrometheusPassStatus
+		if result.Err != nil {
+			status = prometheus.PrometheusFailStatus
+		}
+		prometheus.CnsControlOpsHistVec.WithLabelValues(opType, status).Observe(time.Since(start).Seconds())
+	}
+}
+
 // DeleteVolume deletes a volume given its spec.
 func (m *defaultManager) DeleteVolume(ctx context.Context, volumeID string, deleteDisk bool) (string, error) {
+	ctx, span := tracing.StartSpan(ctx, "DeleteVolume", m.getVCHostAttribute(),
+		tracing.AttributeVolumeID.String(volumeID))
+	defer m.queryCache.invalidate(volumeID)
 	internalDeleteVolume := func() (string, error) {
 		log := logger.GetLogger(ctx)
 		var faultType string
@@ -829,6 +1144,7 @@ func (m *defaultManager) DeleteVolume(ctx context.Context, volumeID string, dele
 	}
 	start := time.Now()
 	faultType, err := internalDeleteVolume()
+	tracing.EndSpan(span, faultType, err)
 	log := logger.GetLogger(ctx)
 	log.Debugf("internalDeleteVolume: returns fault %q for volume %q", faultType, volumeID)
 	if err != nil {
@@ -838,7 +1154,7 @@ func (m *defaultManager) DeleteVolume(ctx context.Context, volumeID string, dele
 		prometheus.CnsControlOpsHistVec.WithLabelValues(prometheus.PrometheusCnsDeleteVolumeOpType,
 			prometheus.PrometheusPassStatus).Observe(time.Since(start).Seconds())
 	}
-	return faultType, err
+	return faultType, NewCnsVolumeError(faultType, volumeID, err)
 }
 
 // deleteVolume attempts to delete the volume on CNS. CNS task information
@@ -864,7 +1180,7 @@ func (m *defaultManager) deleteVolume(ctx context.Context, volumeID string, dele
 		return faultType, err
 	}
 	// Get the taskInfo.
-	taskInfo, err := cns.GetTaskInfo(ctx, task)
+	taskInfo, err := m.getTaskInfo(ctx, task)
 	if err != nil || taskInfo == nil {
 		log.Errorf("failed to get DeleteVolume taskInfo from vCenter %q with err: %v",
 			m.virtualCenter.Config.Host, err)
@@ -997,7 +1313,7 @@ func (m *defaultManager) deleteVolumeWithImprovedIdempotency(ctx context.Context
 	}
 
 	// Get the taskInfo.
-	taskInfo, err := cns.GetTaskInfo(ctx, task)
+	taskInfo, err := m.getTaskInfo(ctx, task)
 	if err != nil || taskInfo == nil {
 		log.Errorf("failed to get taskInfo for DeleteVolume task from vCenter %q with err: %v",
 			m.virtualCenter.Config.Host, err)
@@ -1044,24 +1360,27 @@ func (m *defaultManager) deleteVolumeWithImprovedIdempotency(ctx context.Context
 
 // UpdateVolume updates a volume given its spec.
 func (m *defaultManager) UpdateVolumeMetadata(ctx context.Context, spec *cnstypes.CnsVolumeMetadataUpdateSpec) error {
-	internalUpdateVolumeMetadata := func() error {
+	ctx, span := tracing.StartSpan(ctx, "UpdateVolumeMetadata", m.getVCHostAttribute(),
+		tracing.AttributeVolumeID.String(spec.VolumeId.Id))
+	defer m.queryCache.invalidate(spec.VolumeId.Id)
+	internalUpdateVolumeMetadata := func() (string, error) {
 		log := logger.GetLogger(ctx)
 		err := validateManager(ctx, m)
 		if err != nil {
-			return err
+			return ExtractFaultTypeFromErr(ctx, err), err
 		}
 		// Set up the VC connection.
 		err = m.virtualCenter.ConnectCns(ctx)
 		if err != nil {
 			log.Errorf("ConnectCns failed with err: %+v", err)
-			return err
+			return ExtractFaultTypeFromErr(ctx, err), err
 		}
 		// If the VSphereUser in the VolumeMetadataUpdateSpec is different from
 		// session user, update the VolumeMetadataUpdateSpec.
 		s, err := m.virtualCenter.Client.SessionManager.UserSession(ctx)
 		if err != nil {
 			log.Errorf("failed to get usersession with err: %v", err)
-			return err
+			return ExtractFaultTypeFromErr(ctx, err), err
 		}
 		if s.UserName != spec.Metadata.ContainerCluster.VSphereUser {
 			log.Debugf("Update VSphereUser from %s to %s", spec.Metadata.ContainerCluster.VSphereUser, s.UserName)
@@ -1078,14 +1397,14 @@ func (m *defaultManager) UpdateVolumeMetadata(ctx context.Context, spec *cnstype
 		task, err := m.virtualCenter.CnsClient.UpdateVolumeMetadata(ctx, cnsUpdateSpecList)
 		if err != nil {
 			log.Errorf("CNS UpdateVolume failed from vCenter %q with err: %v", m.virtualCenter.Config.Host, err)
-			return err
+			return ExtractFaultTypeFromErr(ctx, err), err
 		}
 		// Get the taskInfo.
-		taskInfo, err := cns.GetTaskInfo(ctx, task)
+		taskInfo, err := m.getTaskInfo(ctx, task)
 		if err != nil || taskInfo == nil {
 			log.Errorf("failed to get UpdateVolume taskInfo from vCenter %q with err: %v",
 				m.virtualCenter.Config.Host, err)
-			return err
+			return ExtractFaultTypeFromErr(ctx, err), err
 		}
 		log.Infof("UpdateVolumeMetadata: volumeID: %q, opId: %q", spec.VolumeId.Id, taskInfo.ActivationId)
 		// Get the task results for the given task.
@@ -1093,23 +1412,25 @@ func (m *defaultManager) UpdateVolumeMetadata(ctx context.Context, spec *cnstype
 		if err != nil {
 			log.Errorf("unable to find UpdateVolume result from vCenter %q: taskID %q, opId %q and updateResults %+v",
 				m.virtualCenter.Config.Host, taskInfo.Task.Value, taskInfo.ActivationId, taskResult)
-			return err
+			return ExtractFaultTypeFromErr(ctx, err), err
 		}
 		if taskResult == nil {
-			return logger.LogNewErrorf(log, "taskResult is empty for UpdateVolume task: %q, opId: %q",
-				taskInfo.Task.Value, taskInfo.ActivationId)
+			return csifault.CSITaskResultEmptyFault, logger.LogNewErrorf(log,
+				"taskResult is empty for UpdateVolume task: %q, opId: %q", taskInfo.Task.Value, taskInfo.ActivationId)
 		}
 		volumeOperationRes := taskResult.GetCnsVolumeOperationResult()
 		if volumeOperationRes.Fault != nil {
-			return logger.LogNewErrorf(log, "failed to update volume. updateSpec: %q, fault: %q, opID: %q",
+			return ExtractFaultTypeFromVolumeResponseResult(ctx, volumeOperationRes), logger.LogNewErrorf(log,
+				"failed to update volume. updateSpec: %q, fault: %q, opID: %q",
 				spew.Sdump(spec), spew.Sdump(volumeOperationRes.Fault), taskInfo.ActivationId)
 		}
 		log.Infof("UpdateVolumeMetadata: Volume metadata updated successfully. volumeID: %q, opId: %q",
 			spec.VolumeId.Id, taskInfo.ActivationId)
-		return nil
+		return "", nil
 	}
 	start := time.Now()
-	err := internalUpdateVolumeMetadata()
+	faultType, err := internalUpdateVolumeMetadata()
+	tracing.EndSpan(span, faultType, err)
 	if err != nil {
 		prometheus.CnsControlOpsHistVec.WithLabelValues(prometheus.PrometheusCnsUpdateVolumeMetadataOpType,
 			prometheus.PrometheusFailStatus).Observe(time.Since(start).Seconds())
@@ -1117,11 +1438,129 @@ func (m *defaultManager) UpdateVolumeMetadata(ctx context.Context, spec *cnstype
 		prometheus.CnsControlOpsHistVec.WithLabelValues(prometheus.PrometheusCnsUpdateVolumeMetadataOpType,
 			prometheus.PrometheusPassStatus).Observe(time.Since(start).Seconds())
 	}
-	return err
+	return NewCnsVolumeError(faultType, spec.VolumeId.Id, err)
+}
+
+// BatchUpdateVolumeMetadata updates the metadata of the given volumes using
+// a single CNS task.
+func (m *defaultManager) BatchUpdateVolumeMetadata(ctx context.Context,
+	specs []cnstypes.CnsVolumeMetadataUpdateSpec) []error {
+	volumeIDs := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		volumeIDs = append(volumeIDs, spec.VolumeId.Id)
+	}
+	defer m.queryCache.invalidate(volumeIDs...)
+	internalBatchUpdateVolumeMetadata := func() []error {
+		log := logger.GetLogger(ctx)
+		err := validateManager(ctx, m)
+		if err != nil {
+			return newBatchUpdateVolumeMetadataErrors(ctx, volumeIDs, err)
+		}
+		// Set up the VC connection.
+		err = m.virtualCenter.ConnectCns(ctx)
+		if err != nil {
+			log.Errorf("ConnectCns failed with err: %+v", err)
+			return newBatchUpdateVolumeMetadataErrors(ctx, volumeIDs, err)
+		}
+		// If the VSphereUser in the VolumeMetadataUpdateSpecs is different from
+		// session user, update the VolumeMetadataUpdateSpecs.
+		s, err := m.virtualCenter.Client.SessionManager.UserSession(ctx)
+		if err != nil {
+			log.Errorf("failed to get usersession with err: %v", err)
+			return newBatchUpdateVolumeMetadataErrors(ctx, volumeIDs, err)
+		}
+		cnsUpdateSpecList := make([]cnstypes.CnsVolumeMetadataUpdateSpec, 0, len(specs))
+		for _, spec := range specs {
+			spec.Metadata.ContainerCluster.VSphereUser = s.UserName
+			cnsUpdateSpecList = append(cnsUpdateSpecList, cnstypes.CnsVolumeMetadataUpdateSpec{
+				VolumeId: cnstypes.CnsVolumeId{
+					Id: spec.VolumeId.Id,
+				},
+				Metadata: spec.Metadata,
+			})
+		}
+		task, err := m.virtualCenter.CnsClient.UpdateVolumeMetadata(ctx, cnsUpdateSpecList)
+		if err != nil {
+			log.Errorf("CNS UpdateVolume failed from vCenter %q with err: %v", m.virtualCenter.Config.Host, err)
+			return newBatchUpdateVolumeMetadataErrors(ctx, volumeIDs, err)
+		}
+		// Get the taskInfo.
+		taskInfo, err := m.getTaskInfo(ctx, task)
+		if err != nil || taskInfo == nil {
+			log.Errorf("failed to get UpdateVolume taskInfo from vCenter %q with err: %v",
+				m.virtualCenter.Config.Host, err)
+			if err == nil {
+				err = fmt.Errorf("taskInfo is empty for UpdateVolume task")
+			}
+			return newBatchUpdateVolumeMetadataErrors(ctx, volumeIDs, err)
+		}
+		log.Infof("BatchUpdateVolumeMetadata: volumeIDs: %v, opId: %q", volumeIDs, taskInfo.ActivationId)
+		// Get the task results for the given task.
+		taskResults, err := cns.GetTaskResultArray(ctx, taskInfo)
+		if err != nil {
+			log.Errorf("unable to find UpdateVolume results from vCenter %q: taskID %q, opId %q and updateResults %+v",
+				m.virtualCenter.Config.Host, taskInfo.Task.Value, taskInfo.ActivationId, taskResults)
+			return newBatchUpdateVolumeMetadataErrors(ctx, volumeIDs, err)
+		}
+		resultByVolume := make(map[string]*cnstypes.CnsVolumeOperationResult)
+		for index, taskResult := range taskResults {
+			if taskResult == nil {
+				continue
+			}
+			volumeOperationRes := taskResult.GetCnsVolumeOperationResult()
+			volumeID := volumeOperationRes.VolumeId.Id
+			if volumeID == "" && index < len(volumeIDs) {
+				// Results are returned in the order of the specs.
+				volumeID = volumeIDs[index]
+			}
+			resultByVolume[volumeID] = volumeOperationRes
+		}
+		errs := make([]error, len(specs))
+		for index, volumeID := range volumeIDs {
+			volumeOperationRes, ok := resultByVolume[volumeID]
+			if !ok {
+				errs[index] = NewCnsVolumeError(csifault.CSITaskResultEmptyFault, volumeID, logger.LogNewErrorf(log,
+					"taskResult is empty for volume %q in UpdateVolume task: %q, opId: %q",
+					volumeID, taskInfo.Task.Value, taskInfo.ActivationId))
+				continue
+			}
+			if volumeOperationRes.Fault != nil {
+				errs[index] = NewCnsVolumeError(ExtractFaultTypeFromVolumeResponseResult(ctx, volumeOperationRes),
+					volumeID, logger.LogNewErrorf(log, "failed to update volume. updateSpec: %q, fault: %q, opID: %q",
+						spew.Sdump(specs[index]), spew.Sdump(volumeOperationRes.Fault), taskInfo.ActivationId))
+			}
+		}
+		log.Infof("BatchUpdateVolumeMetadata: Volume metadata update completed. volumeIDs: %v, opId: %q",
+			volumeIDs, taskInfo.ActivationId)
+		return errs
+	}
+	start := time.Now()
+	errs := internalBatchUpdateVolumeMetadata()
+	for _, err := range errs {
+		status := prometheus.PrometheusPassStatus
+		if err != nil {
+			status = prometheus.PrometheusFailStatus
+		}
+		prometheus.CnsControlOpsHistVec.WithLabelValues(prometheus.PrometheusCnsUpdateVolumeMetadataOpType,
+			status).Observe(time.Since(start).Seconds())
+	}
+	return errs
+}
+
+// newBatchUpdateVolumeMetadataErrors returns the given error, wrapped in a
+// CnsError, for each of the given volumes.
+func newBatchUpdateVolumeMetadataErrors(ctx context.Context, volumeIDs []string, err error) []error {
+	faultType := ExtractFaultTypeFromErr(ctx, err)
+	errs := make([]error, 0, len(volumeIDs))
+	for _, volumeID := range volumeIDs {
+		errs = append(errs, NewCnsVolumeError(faultType, volumeID, err))
+	}
+	return errs
 }
 
 // ExpandVolume expands a volume given its spec.
 func (m *defaultManager) ExpandVolume(ctx context.Context, volumeID string, size int64) (string, error) {
+	defer m.queryCache.invalidate(volumeID)
 	internalExpandVolume := func() (string, error) {
 		log := logger.GetLogger(ctx)
 		var faultType string
@@ -1155,7 +1594,7 @@ func (m *defaultManager) ExpandVolume(ctx context.Context, volumeID string, size
 		prometheus.CnsControlOpsHistVec.WithLabelValues(prometheus.PrometheusCnsExpandVolumeOpType,
 			prometheus.PrometheusPassStatus).Observe(time.Since(start).Seconds())
 	}
-	return faultType, err
+	return faultType, NewCnsVolumeError(faultType, volumeID, err)
 }
 
 // expandVolume invokes CNS ExpandVolume.
@@ -1184,7 +1623,7 @@ func (m *defaultManager) expandVolume(ctx context.Context, volumeID string, size
 		return faultType, err
 	}
 	// Get the taskInfo.
-	taskInfo, err := cns.GetTaskInfo(ctx, task)
+	taskInfo, err := m.getTaskInfo(ctx, task)
 	if err != nil || taskInfo == nil {
 		log.Errorf("failed to get taskInfo for ExtendVolume task from vCenter %q with err: %v",
 			m.virtualCenter.Config.Host, err)
@@ -1314,7 +1753,7 @@ func (m *defaultManager) expandVolumeWithImprovedIdempotency(ctx context.Context
 		}
 	}
 
-	taskInfo, err := task.WaitForResult(ctx, nil)
+	taskInfo, err := m.waitForTaskResult(ctx, task)
 	if err != nil {
 		if cnsvsphere.IsManagedObjectNotFound(err, task.Reference()) {
 			log.Debugf("ExtendVolume task %s not found in vCenter. Querying CNS "+
@@ -1396,6 +1835,19 @@ func (m *defaultManager) expandVolumeWithImprovedIdempotency(ctx context.Context
 // QueryVolume returns volumes matching the given filter.
 func (m *defaultManager) QueryVolume(ctx context.Context,
 	queryFilter cnstypes.CnsQueryFilter) (*cnstypes.CnsQueryResult, error) {
+	volumeIDs, cacheable := isCacheableQuery(queryFilter)
+	if cacheable {
+		if res, ok := m.queryCache.getVolumes(volumeIDs); ok {
+			logger.GetLogger(ctx).Debugf("QueryVolume: returning the cached volumes %v", volumeIDs)
+			return res, nil
+		}
+	}
+	var queriedVolumeIDs []string
+	for _, volumeID := range queryFilter.VolumeIds {
+		queriedVolumeIDs = append(queriedVolumeIDs, volumeID.Id)
+	}
+	ctx, span := tracing.StartSpan(ctx, "QueryVolume", m.getVCHostAttribute(),
+		tracing.AttributeVolumeID.String(strings.Join(queriedVolumeIDs, ",")))
 	internalQueryVolume := func() (*cnstypes.CnsQueryResult, error) {
 		log := logger.GetLogger(ctx)
 		err := validateManager(ctx, m)
@@ -1415,10 +1867,14 @@ func (m *defaultManager) QueryVolume(ctx context.Context,
 			return nil, err
 		}
 		res = updateQueryResult(ctx, m, res)
+		if cacheable {
+			m.queryCache.setVolumes(res)
+		}
 		return res, err
 	}
 	start := time.Now()
 	resp, err := internalQueryVolume()
+	tracing.EndSpan(span, "", err)
 	if err != nil {
 		prometheus.CnsControlOpsHistVec.WithLabelValues(prometheus.PrometheusCnsQueryVolumeOpType,
 			prometheus.PrometheusFailStatus).Observe(time.Since(start).Seconds())
@@ -1469,6 +1925,16 @@ func (m *defaultManager) QueryAllVolume(ctx context.Context, queryFilter cnstype
 // which CnsQueryVolumeInfoResult is extracted.
 func (m *defaultManager) QueryVolumeInfo(ctx context.Context,
 	volumeIDList []cnstypes.CnsVolumeId) (*cnstypes.CnsQueryVolumeInfoResult, error) {
+	// The task returns the result of the first volume only, so only the
+	// queries of a single volume are cached.
+	cacheable := len(volumeIDList) == 1
+	if cacheable {
+		if res, ok := m.queryCache.getVolumeInfo(volumeIDList[0].Id); ok {
+			logger.GetLogger(ctx).Debugf("QueryVolumeInfo: returning the cached volumeInfo of volume %q",
+				volumeIDList[0].Id)
+			return res, nil
+		}
+	}
 	internalQueryVolumeInfo := func() (*cnstypes.CnsQueryVolumeInfoResult, error) {
 		log := logger.GetLogger(ctx)
 		err := validateManager(ctx, m)
@@ -1489,7 +1955,7 @@ func (m *defaultManager) QueryVolumeInfo(ctx context.Context,
 		}
 
 		// Get the taskInfo.
-		taskInfo, err := cns.GetTaskInfo(ctx, queryVolumeInfoTask)
+		taskInfo, err := m.getTaskInfo(ctx, queryVolumeInfoTask)
 		if err != nil || taskInfo == nil {
 			log.Errorf("failed to get QueryVolumeInfo taskInfo from vCenter %q with err: %v",
 				m.virtualCenter.Config.Host, err)
@@ -1515,6 +1981,9 @@ func (m *defaultManager) QueryVolumeInfo(ctx context.Context,
 		volumeInfoResult := interface{}(taskResult).(*cnstypes.CnsQueryVolumeInfoResult)
 		log.Infof("QueryVolumeInfo successfully returned volumeInfo volumeIDList %v:, opId: %q",
 			volumeIDList, taskInfo.ActivationId)
+		if cacheable {
+			m.queryCache.setVolumeInfo(volumeIDList[0].Id, volumeInfoResult)
+		}
 		return volumeInfoResult, nil
 	}
 	start := time.Now()
@@ -1531,6 +2000,11 @@ func (m *defaultManager) QueryVolumeInfo(ctx context.Context,
 
 func (m *defaultManager) RelocateVolume(ctx context.Context,
 	relocateSpecList ...cnstypes.BaseCnsVolumeRelocateSpec) (*object.Task, error) {
+	var volumeIDs []string
+	for _, relocateSpec := range relocateSpecList {
+		volumeIDs = append(volumeIDs, relocateSpec.GetCnsVolumeRelocateSpec().VolumeId.Id)
+	}
+	defer m.queryCache.invalidate(volumeIDs...)
 	internalRelocateVolume := func() (*object.Task, error) {
 		log := logger.GetLogger(ctx)
 		err := validateManager(ctx, m)
@@ -1566,6 +2040,7 @@ func (m *defaultManager) RelocateVolume(ctx context.Context,
 
 // ConfigureVolumeACLs configures net permissions for a given CnsVolumeACLConfigureSpec.
 func (m *defaultManager) ConfigureVolumeACLs(ctx context.Context, spec cnstypes.CnsVolumeACLConfigureSpec) error {
+	defer m.queryCache.invalidate(spec.VolumeId.Id)
 	internalConfigureVolumeACLs := func() error {
 		log := logger.GetLogger(ctx)
 		err := validateManager(ctx, m)
@@ -1589,7 +2064,7 @@ func (m *defaultManager) ConfigureVolumeACLs(ctx context.Context, spec cnstypes.
 		}
 
 		// Get the taskInfo.
-		taskInfo, err = cns.GetTaskInfo(ctx, task)
+		taskInfo, err = m.getTaskInfo(ctx, task)
 		if err != nil {
 			log.Errorf("failed to get ConfigureVolumeACLs taskInfo from vCenter %q with err: %v",
 				m.virtualCenter.Config.Host, err)
@@ -1729,7 +2204,7 @@ func (m *defaultManager) QueryVolumeAsync(ctx context.Context, queryFilter cnsty
 		log.Errorf("CNS QueryVolumeAsync failed from vCenter %q with err: %v", m.virtualCenter.Config.Host, err)
 		return nil, err
 	}
-	queryVolumeAsyncTaskInfo, err := cns.GetTaskInfo(ctx, queryVolumeAsyncTask)
+	queryVolumeAsyncTaskInfo, err := m.getTaskInfo(ctx, queryVolumeAsyncTask)
 	if err != nil {
 		log.Errorf("CNS QueryVolumeAsync failed to get TaskInfo with err: %v", err)
 		return nil, err
@@ -1775,7 +2250,7 @@ func (m *defaultManager) QuerySnapshots(ctx context.Context, snapshotQueryFilter
 			log.Errorf("Failed to get the task of CNS QuerySnapshots with err: %v", err)
 			return nil, err
 		}
-		querySnapshotsTaskInfo, err := cns.GetTaskInfo(ctx, querySnapshotsTask)
+		querySnapshotsTaskInfo, err := m.getTaskInfo(ctx, querySnapshotsTask)
 		if err != nil {
 			log.Errorf("failed to get taskInfo for QuerySnapshots task from vCenter %q with err: %v",
 				m.virtualCenter.Config.Host, err)
@@ -1914,7 +2389,7 @@ func (m *defaultManager) createSnapshotWithImprovedIdempotencyCheck(ctx context.
 	}
 
 	// Get the taskInfo and more!
-	createSnapshotsTaskInfo, err := cns.GetTaskInfo(ctx, createSnapshotsTask)
+	createSnapshotsTaskInfo, err := m.getTaskInfo(ctx, createSnapshotsTask)
 	if err != nil || createSnapshotsTaskInfo == nil {
 		return nil, logger.LogNewErrorf(log, "Failed to get taskInfo for CreateSnapshots task "+
 			"from vCenter %q with err: %v", m.virtualCenter.Config.Host, err)
@@ -2110,7 +2585,7 @@ func (m *defaultManager) deleteSnapshotWithImprovedIdempotencyCheck(
 	}
 
 	// Get the taskInfo
-	deleteSnapshotsTaskInfo, err := deleteSnapshotTask.WaitForResult(ctx, nil)
+	deleteSnapshotsTaskInfo, err := m.waitForTaskResult(ctx, deleteSnapshotTask)
 	if err != nil {
 		if cnsvsphere.IsManagedObjectNotFound(err, deleteSnapshotTask.Reference()) {
 			log.Infof("Snapshot %q on volume %q might have already been deleted "+
@@ -0,0 +1,165 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package volume
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+)
+
+// volumeQueryCache caches the results of the CNS queries of volumes by ID
+// for a short time, so that the same volumes queried repeatedly, e.g. during
+// attach storms, are only queried once from vCenter. Volume operations
+// invalidate the cached results of the volumes they change. Nothing is
+// cached if the TTL is 0.
+type volumeQueryCache struct {
+	lock sync.Mutex
+	ttl  time.Duration
+	// volumes holds the results of QueryVolume by volume ID.
+	volumes map[string]volumeQueryCacheEntry
+	// volumeInfos holds the results of QueryVolumeInfo by volume ID.
+	volumeInfos map[string]volumeInfoQueryCacheEntry
+	// now returns the current time. It is replaced in tests.
+	now func() time.Time
+}
+
+// volumeQueryCacheEntry is a volume returned by QueryVolume.
+type volumeQueryCacheEntry struct {
+	volume     cnstypes.CnsVolume
+	expiration time.Time
+}
+
+// volumeInfoQueryCacheEntry is a result returned by QueryVolumeInfo.
+type volumeInfoQueryCacheEntry struct {
+	result     *cnstypes.CnsQueryVolumeInfoResult
+	expiration time.Time
+}
+
+// newVolumeQueryCache returns a volumeQueryCache caching the query results
+// for the TTL configured for the given vCenter.
+func newVolumeQueryCache(vcConfig *cnsvsphere.VirtualCenterConfig) *volumeQueryCache {
+	c := &volumeQueryCache{now: time.Now}
+	c.reset(vcConfig)
+	return c
+}
+
+// reset drops the cached query results, and caches the next ones for the TTL
+// configured for the given vCenter.
+func (c *volumeQueryCache) reset(vcConfig *cnsvsphere.VirtualCenterConfig) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.ttl = 0
+	if vcConfig != nil && vcConfig.VolumeQueryCacheTTL > 0 {
+		c.ttl = vcConfig.VolumeQueryCacheTTL
+	}
+	c.volumes = make(map[string]volumeQueryCacheEntry)
+	c.volumeInfos = make(map[string]volumeInfoQueryCacheEntry)
+}
+
+// isCacheableQuery returns the IDs of the volumes queried by the given
+// filter, if it only filters volumes by ID, and whether it does.
+func isCacheableQuery(queryFilter cnstypes.CnsQueryFilter) ([]cnstypes.CnsVolumeId, bool) {
+	volumeIDs := queryFilter.VolumeIds
+	queryFilter.VolumeIds = nil
+	if len(volumeIDs) == 0 || !reflect.DeepEqual(queryFilter, cnstypes.CnsQueryFilter{}) {
+		return nil, false
+	}
+	return volumeIDs, true
+}
+
+// getVolumes returns the cached result of the query of the given volumes,
+// or false if any of them isn't cached.
+func (c *volumeQueryCache) getVolumes(volumeIDs []cnstypes.CnsVolumeId) (*cnstypes.CnsQueryResult, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.ttl == 0 {
+		return nil, false
+	}
+	now := c.now()
+	result := &cnstypes.CnsQueryResult{}
+	for _, volumeID := range volumeIDs {
+		entry, ok := c.volumes[volumeID.Id]
+		if !ok {
+			return nil, false
+		}
+		if now.After(entry.expiration) {
+			delete(c.volumes, volumeID.Id)
+			return nil, false
+		}
+		result.Volumes = append(result.Volumes, entry.volume)
+	}
+	total := int64(len(result.Volumes))
+	result.Cursor = cnstypes.CnsCursor{Offset: total, Limit: total, TotalRecords: total}
+	return result, true
+}
+
+// setVolumes caches the volumes of the given query result.
+func (c *volumeQueryCache) setVolumes(result *cnstypes.CnsQueryResult) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.ttl == 0 || result == nil {
+		return
+	}
+	expiration := c.now().Add(c.ttl)
+	for _, volume := range result.Volumes {
+		c.volumes[volume.VolumeId.Id] = volumeQueryCacheEntry{volume: volume, expiration: expiration}
+	}
+}
+
+// getVolumeInfo returns the cached result of the QueryVolumeInfo of the
+// given volume, or false if it isn't cached.
+func (c *volumeQueryCache) getVolumeInfo(volumeID string) (*cnstypes.CnsQueryVolumeInfoResult, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.ttl == 0 {
+		return nil, false
+	}
+	entry, ok := c.volumeInfos[volumeID]
+	if !ok {
+		return nil, false
+	}
+	if c.now().After(entry.expiration) {
+		delete(c.volumeInfos, volumeID)
+		return nil, false
+	}
+	return entry.result, true
+}
+
+// setVolumeInfo caches the result of the QueryVolumeInfo of the given volume.
+func (c *volumeQueryCache) setVolumeInfo(volumeID string, result *cnstypes.CnsQueryVolumeInfoResult) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.ttl == 0 || result == nil {
+		return
+	}
+	c.volumeInfos[volumeID] = volumeInfoQueryCacheEntry{result: result, expiration: c.now().Add(c.ttl)}
+}
+
+// invalidate drops the cached query results of the given volumes.
+func (c *volumeQueryCache) invalidate(volumeIDs ...string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, volumeID := range volumeIDs {
+		delete(c.volumes, volumeID)
+		delete(c.volumeInfos, volumeID)
+	}
+}
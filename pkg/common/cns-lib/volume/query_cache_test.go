@@ -0,0 +1,84 @@
+package volume
+
+import (
+	"testing"
+	"time"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+)
+
+func TestIsCacheableQuery(t *testing.T) {
+	volumeIDs := []cnstypes.CnsVolumeId{{Id: "vol-1"}, {Id: "vol-2"}}
+	if ids, ok := isCacheableQuery(cnstypes.CnsQueryFilter{VolumeIds: volumeIDs}); !ok || len(ids) != 2 {
+		t.Errorf("expected query of volumes by ID to be cacheable, got %v, %t", ids, ok)
+	}
+	for _, queryFilter := range []cnstypes.CnsQueryFilter{
+		{},
+		{VolumeIds: volumeIDs, ContainerClusterIds: []string{"cluster-1"}},
+		{VolumeIds: volumeIDs, Cursor: &cnstypes.CnsCursor{Limit: 1}},
+		{Names: []string{"pvc-1"}},
+	} {
+		if _, ok := isCacheableQuery(queryFilter); ok {
+			t.Errorf("expected query %+v not to be cacheable", queryFilter)
+		}
+	}
+}
+
+func TestVolumeQueryCache(t *testing.T) {
+	now := time.Now()
+	cache := newVolumeQueryCache(&cnsvsphere.VirtualCenterConfig{VolumeQueryCacheTTL: 10 * time.Second})
+	cache.now = func() time.Time { return now }
+	volumeIDs := []cnstypes.CnsVolumeId{{Id: "vol-1"}, {Id: "vol-2"}}
+
+	cache.setVolumes(&cnstypes.CnsQueryResult{Volumes: []cnstypes.CnsVolume{{VolumeId: volumeIDs[0]}}})
+	if _, ok := cache.getVolumes(volumeIDs); ok {
+		t.Error("expected no cached result while a volume isn't cached")
+	}
+	cache.setVolumes(&cnstypes.CnsQueryResult{Volumes: []cnstypes.CnsVolume{{VolumeId: volumeIDs[1]}}})
+	result, ok := cache.getVolumes(volumeIDs)
+	if !ok || len(result.Volumes) != 2 || result.Volumes[1].VolumeId.Id != "vol-2" ||
+		result.Cursor.TotalRecords != 2 {
+		t.Errorf("expected the cached volumes, got %+v, %t", result, ok)
+	}
+
+	volumeInfo := &cnstypes.CnsQueryVolumeInfoResult{}
+	cache.setVolumeInfo("vol-1", volumeInfo)
+	if result, ok := cache.getVolumeInfo("vol-1"); !ok || result != volumeInfo {
+		t.Errorf("expected the cached volumeInfo, got %+v, %t", result, ok)
+	}
+
+	cache.invalidate("vol-1")
+	if _, ok := cache.getVolumes(volumeIDs[:1]); ok {
+		t.Error("expected the volume to be invalidated")
+	}
+	if _, ok := cache.getVolumeInfo("vol-1"); ok {
+		t.Error("expected the volumeInfo to be invalidated")
+	}
+	if _, ok := cache.getVolumes(volumeIDs[1:]); !ok {
+		t.Error("expected the other volume to stay cached")
+	}
+
+	now = now.Add(11 * time.Second)
+	if _, ok := cache.getVolumes(volumeIDs[1:]); ok {
+		t.Error("expected the volume to expire")
+	}
+	if len(cache.volumes) != 0 {
+		t.Errorf("expected the expired volume to be dropped, got %v", cache.volumes)
+	}
+}
+
+func TestVolumeQueryCacheDisabled(t *testing.T) {
+	cache := newVolumeQueryCache(nil)
+	cache.setVolumes(&cnstypes.CnsQueryResult{
+		Volumes: []cnstypes.CnsVolume{{VolumeId: cnstypes.CnsVolumeId{Id: "vol-1"}}},
+	})
+	if _, ok := cache.getVolumes([]cnstypes.CnsVolumeId{{Id: "vol-1"}}); ok {
+		t.Error("expected nothing to be cached without a TTL")
+	}
+	cache.setVolumeInfo("vol-1", &cnstypes.CnsQueryVolumeInfoResult{})
+	if _, ok := cache.getVolumeInfo("vol-1"); ok {
+		t.Error("expected nothing to be cached without a TTL")
+	}
+}
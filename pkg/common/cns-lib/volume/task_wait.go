@@ -0,0 +1,185 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package volume
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/task"
+	"github.com/vmware/govmomi/vim25/mo"
+	vim25types "github.com/vmware/govmomi/vim25/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	cnsconfig "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
+)
+
+const (
+	// taskPollBackoffFactor is the factor by which the CNS task polling
+	// interval grows after every poll.
+	taskPollBackoffFactor = 2
+	// taskPollJitterFactor is the maximum fraction of the CNS task polling
+	// interval added to it as jitter.
+	taskPollJitterFactor = 0.2
+)
+
+// taskWaitKey is the context key of the taskWait of a context.
+type taskWaitKey struct{}
+
+// taskWait accumulates the time spent waiting on CNS tasks.
+type taskWait struct {
+	nanoseconds int64
+}
+
+// WithTaskWaitTracking returns a context in which the time spent waiting on
+// CNS tasks by the volume operations invoked with it, or with contexts
+// derived from it, is accumulated. The accumulated time is returned by
+// GetTaskWaitTime.
+func WithTaskWaitTracking(ctx context.Context) context.Context {
+	return context.WithValue(ctx, taskWaitKey{}, &taskWait{})
+}
+
+// GetTaskWaitTime returns the time spent waiting on CNS tasks with the given
+// context, or 0 if it was not returned by WithTaskWaitTracking.
+func GetTaskWaitTime(ctx context.Context) time.Duration {
+	wait, ok := ctx.Value(taskWaitKey{}).(*taskWait)
+	if !ok {
+		return 0
+	}
+	return time.Duration(atomic.LoadInt64(&wait.nanoseconds))
+}
+
+// recordTaskWait adds the time elapsed since start to the time spent waiting
+// on CNS tasks with the given context.
+func recordTaskWait(ctx context.Context, start time.Time) {
+	if wait, ok := ctx.Value(taskWaitKey{}).(*taskWait); ok {
+		atomic.AddInt64(&wait.nanoseconds, int64(time.Since(start)))
+	}
+}
+
+// taskPoller polls CNS tasks until they complete. The polling interval
+// starts at interval and doubles after every poll, up to maxInterval, with
+// jitter so that concurrent waits don't poll vCenter in lockstep.
+type taskPoller struct {
+	interval    time.Duration
+	maxInterval time.Duration
+	// timeout bounds the wait for a task, in addition to the context. It
+	// isn't bounded if timeout is 0.
+	timeout time.Duration
+}
+
+// newTaskPoller returns a taskPoller configured with the task polling
+// settings of the given vCenter, or their defaults if unset.
+func newTaskPoller(vcConfig *cnsvsphere.VirtualCenterConfig) taskPoller {
+	poller := taskPoller{
+		interval:    time.Duration(cnsconfig.DefaultTaskPollIntervalInMs) * time.Millisecond,
+		maxInterval: time.Duration(cnsconfig.DefaultTaskPollMaxIntervalInSec) * time.Second,
+	}
+	if vcConfig == nil {
+		return poller
+	}
+	if vcConfig.TaskPollInterval > 0 {
+		poller.interval = vcConfig.TaskPollInterval
+	}
+	if vcConfig.TaskPollMaxInterval > 0 {
+		poller.maxInterval = vcConfig.TaskPollMaxInterval
+	}
+	if poller.maxInterval < poller.interval {
+		poller.maxInterval = poller.interval
+	}
+	poller.timeout = vcConfig.TaskTimeout
+	return poller
+}
+
+// nextInterval returns the polling interval following the given one.
+func (p taskPoller) nextInterval(interval time.Duration) time.Duration {
+	interval *= taskPollBackoffFactor
+	if interval > p.maxInterval {
+		return p.maxInterval
+	}
+	return interval
+}
+
+// wait polls the given task until it completes, and returns its info. If the
+// task failed, its info is returned along with a task.Error.
+func (p taskPoller) wait(ctx context.Context, t *object.Task) (*vim25types.TaskInfo, error) {
+	waitCtx := ctx
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+	interval := p.interval
+	for {
+		var taskMo mo.Task
+		err := t.Properties(waitCtx, t.Reference(), []string{"info"}, &taskMo)
+		if err != nil {
+			if waitCtx.Err() != nil {
+				return nil, p.waitError(ctx, t)
+			}
+			return nil, err
+		}
+		switch taskMo.Info.State {
+		case vim25types.TaskInfoStateSuccess:
+			return &taskMo.Info, nil
+		case vim25types.TaskInfoStateError:
+			return &taskMo.Info, task.Error{
+				LocalizedMethodFault: taskMo.Info.Error,
+				Description:          taskMo.Info.Description,
+			}
+		}
+		timer := time.NewTimer(wait.Jitter(interval, taskPollJitterFactor))
+		select {
+		case <-waitCtx.Done():
+			timer.Stop()
+			return nil, p.waitError(ctx, t)
+		case <-timer.C:
+		}
+		interval = p.nextInterval(interval)
+	}
+}
+
+// waitError returns the error of a wait for the given task which was
+// interrupted, either by ctx or by the timeout of the poller.
+func (p taskPoller) waitError(ctx context.Context, t *object.Task) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("timed out after %v waiting for task %s", p.timeout, t.Reference().Value)
+}
+
+// getTaskInfo waits for the given CNS task and returns its info, recording
+// the time spent waiting on it. No info is returned if the task failed.
+func (m *defaultManager) getTaskInfo(ctx context.Context, t *object.Task) (*vim25types.TaskInfo, error) {
+	defer recordTaskWait(ctx, time.Now())
+	taskInfo, err := newTaskPoller(m.virtualCenter.Config).wait(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+	return taskInfo, nil
+}
+
+// waitForTaskResult waits for the given CNS task and returns its info,
+// recording the time spent waiting on it.
+func (m *defaultManager) waitForTaskResult(ctx context.Context, t *object.Task) (*vim25types.TaskInfo, error) {
+	defer recordTaskWait(ctx, time.Now())
+	return newTaskPoller(m.virtualCenter.Config).wait(ctx, t)
+}
@@ -0,0 +1,99 @@
+package volume
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/task"
+	"github.com/vmware/govmomi/vim25"
+	vim25types "github.com/vmware/govmomi/vim25/types"
+
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+)
+
+func TestTaskWaitTracking(t *testing.T) {
+	start := time.Now().Add(-time.Second)
+	recordTaskWait(context.Background(), start)
+	if wait := GetTaskWaitTime(context.Background()); wait != 0 {
+		t.Errorf("expected no task wait without tracking, got %v", wait)
+	}
+
+	ctx := WithTaskWaitTracking(context.Background())
+	childCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	recordTaskWait(childCtx, start)
+	recordTaskWait(ctx, start)
+	if wait := GetTaskWaitTime(ctx); wait < 2*time.Second {
+		t.Errorf("expected at least 2s of task wait, got %v", wait)
+	}
+}
+
+func TestNewTaskPoller(t *testing.T) {
+	poller := newTaskPoller(nil)
+	if poller.interval != 500*time.Millisecond || poller.maxInterval != 5*time.Second || poller.timeout != 0 {
+		t.Errorf("unexpected default task poller %+v", poller)
+	}
+
+	poller = newTaskPoller(&cnsvsphere.VirtualCenterConfig{
+		TaskPollInterval:    2 * time.Second,
+		TaskPollMaxInterval: time.Second,
+		TaskTimeout:         time.Minute,
+	})
+	if poller.interval != 2*time.Second || poller.maxInterval != 2*time.Second || poller.timeout != time.Minute {
+		t.Errorf("unexpected task poller %+v", poller)
+	}
+}
+
+func TestTaskPollerNextInterval(t *testing.T) {
+	poller := taskPoller{interval: 500 * time.Millisecond, maxInterval: 5 * time.Second}
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	interval := poller.interval
+	for _, expectedInterval := range expected {
+		interval = poller.nextInterval(interval)
+		if interval != expectedInterval {
+			t.Fatalf("expected interval %v, got %v", expectedInterval, interval)
+		}
+	}
+}
+
+func TestTaskPollerWait(t *testing.T) {
+	simulator.Test(func(ctx context.Context, c *vim25.Client) {
+		poller := taskPoller{interval: time.Millisecond, maxInterval: 10 * time.Millisecond}
+		vm := object.NewVirtualMachine(c, simulator.Map.Any("VirtualMachine").Reference())
+
+		powerOffTask, err := vm.PowerOff(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		taskInfo, err := poller.wait(ctx, powerOffTask)
+		if err != nil {
+			t.Fatalf("expected task to succeed, got %v", err)
+		}
+		if taskInfo.State != vim25types.TaskInfoStateSuccess {
+			t.Errorf("expected task state %q, got %q", vim25types.TaskInfoStateSuccess, taskInfo.State)
+		}
+
+		// Powering off a powered off VM fails.
+		powerOffTask, err = vm.PowerOff(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		taskInfo, err = poller.wait(ctx, powerOffTask)
+		if !errors.As(err, &task.Error{}) {
+			t.Fatalf("expected task error, got %v", err)
+		}
+		if taskInfo == nil || taskInfo.State != vim25types.TaskInfoStateError {
+			t.Errorf("expected task info in state %q, got %+v", vim25types.TaskInfoStateError, taskInfo)
+		}
+
+		canceledCtx, cancel := context.WithCancel(ctx)
+		cancel()
+		if _, err = poller.wait(canceledCtx, powerOffTask); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected %v, got %v", context.Canceled, err)
+		}
+	})
+}
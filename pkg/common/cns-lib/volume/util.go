@@ -18,6 +18,7 @@ package volume
 
 import (
 	"context"
+	"errors"
 	"reflect"
 	"strings"
 
@@ -30,9 +31,11 @@ import (
 	"github.com/vmware/govmomi/vim25/mo"
 	"github.com/vmware/govmomi/vim25/soap"
 	"github.com/vmware/govmomi/vim25/types"
+	"go.opentelemetry.io/otel/attribute"
 
 	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
 	csifault "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/fault"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/tracing"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
 )
 
@@ -48,6 +51,14 @@ func validateManager(ctx context.Context, m *defaultManager) error {
 	return nil
 }
 
+// getVCHostAttribute returns the span attribute of the vCenter of the manager.
+func (m *defaultManager) getVCHostAttribute() attribute.KeyValue {
+	if m.virtualCenter == nil || m.virtualCenter.Config == nil {
+		return tracing.AttributeVCHost.String("")
+	}
+	return tracing.AttributeVCHost.String(m.virtualCenter.Config.Host)
+}
+
 // IsDiskAttached checks if the volume is attached to the VM.
 // If the volume is attached to the VM, return disk uuid of the volume,
 // else return empty string.
@@ -330,11 +341,16 @@ func getCnsVolumeInfoFromTaskResult(ctx context.Context, virtualCenter *cnsvsphe
 }
 
 // ExtractFaultTypeFromErr extracts the fault type from err.
+// Return the fault type of the CnsError wrapped by err, if any.
 // Return the vim fault type if the input err is a SoapFault, and can exract the fault type of VimFault.
 // Otherwise, it returns fault type as "csi.fault.Internal".
 func ExtractFaultTypeFromErr(ctx context.Context, err error) string {
 	log := logger.GetLogger(ctx)
 	var faultType string
+	var cnsErr *CnsError
+	if errors.As(err, &cnsErr) && cnsErr.FaultType != "" {
+		return cnsErr.FaultType
+	}
 	if soap.IsSoapFault(err) {
 		soapFault := soap.ToSoapFault(err)
 		// faultType has the format like "type.XXX", XXX is the specific VimFault type.
@@ -93,6 +93,18 @@ func (dc *Datacenter) GetVirtualMachineByUUID(ctx context.Context,
 	uuid string, instanceUUID bool) (*VirtualMachine, error) {
 	log := logger.GetLogger(ctx)
 	uuid = strings.ToLower(strings.TrimSpace(uuid))
+	cache := getVirtualMachineCache(ctx, dc)
+	if cache != nil {
+		if ref, ok := cache.get(uuid, instanceUUID); ok {
+			log.Debugf("Found VM %v given uuid %s in the VM discovery cache", ref, uuid)
+			return &VirtualMachine{
+				VirtualCenterHost: dc.VirtualCenterHost,
+				UUID:              uuid,
+				VirtualMachine:    object.NewVirtualMachine(dc.Datacenter.Client(), ref),
+				Datacenter:        dc,
+			}, nil
+		}
+	}
 	searchIndex := object.NewSearchIndex(dc.Datacenter.Client())
 	svm, err := searchIndex.FindByUuid(ctx, dc.Datacenter, uuid, true, &instanceUUID)
 	if err != nil {
@@ -108,6 +120,9 @@ func (dc *Datacenter) GetVirtualMachineByUUID(ctx context.Context,
 		VirtualMachine:    object.NewVirtualMachine(dc.Datacenter.Client(), svm.Reference()),
 		Datacenter:        dc,
 	}
+	if cache != nil {
+		cache.addVirtualMachine(ctx, vm)
+	}
 	return vm, nil
 }
 
@@ -231,3 +246,49 @@ func (dc *Datacenter) GetAllDatastores(ctx context.Context) (map[string]*Datasto
 	}
 	return dsURLInfoMap, nil
 }
+
+// GetAllDatastoreTypes gets the datastore URL to datastore type map, e.g.
+// "vsan" or "VMFS", for all the datastores in the datacenter.
+func (dc *Datacenter) GetAllDatastoreTypes(ctx context.Context) (map[string]string, error) {
+	dsURLSummaryMap, err := dc.GetAllDatastoreSummaries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dsURLTypeMap := make(map[string]string)
+	for url, summary := range dsURLSummaryMap {
+		dsURLTypeMap[url] = summary.Type
+	}
+	return dsURLTypeMap, nil
+}
+
+// GetAllDatastoreSummaries gets the datastore URL to datastore summary map,
+// holding the name, type, capacity and free space of the datastores, for all
+// the datastores in the datacenter.
+func (dc *Datacenter) GetAllDatastoreSummaries(ctx context.Context) (map[string]types.DatastoreSummary, error) {
+	log := logger.GetLogger(ctx)
+	finder := find.NewFinder(dc.Client(), false)
+	finder.SetDatacenter(dc.Datacenter)
+	datastores, err := finder.DatastoreList(ctx, "*")
+	if err != nil {
+		log.Errorf("failed to get all the datastores in the Datacenter %s with error: %v", dc.Datacenter.String(), err)
+		return nil, err
+	}
+	var dsList []types.ManagedObjectReference
+	for _, ds := range datastores {
+		dsList = append(dsList, ds.Reference())
+	}
+	var dsMoList []mo.Datastore
+	pc := property.DefaultCollector(dc.Client())
+	properties := []string{"summary"}
+	err = pc.Retrieve(ctx, dsList, properties, &dsMoList)
+	if err != nil {
+		log.Errorf("failed to get datastore managed objects from datastore objects %v with properties %v: %v",
+			dsList, properties, err)
+		return nil, err
+	}
+	dsURLSummaryMap := make(map[string]types.DatastoreSummary)
+	for _, dsMo := range dsMoList {
+		dsURLSummaryMap[dsMo.Summary.Url] = dsMo.Summary
+	}
+	return dsURLSummaryMap, nil
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vsphere
+
+import (
+	"context"
+
+	"github.com/vmware/govmomi/event"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// datastoreAccessibilityEventPageSize is the number of latest events replayed
+// when the watch of the datastore accessibility events starts.
+const datastoreAccessibilityEventPageSize = 10
+
+// datastoreAccessibilityEventTypes are the types of the events posted by
+// vCenter when a datastore is connected to or disconnected from a host,
+// including the all paths down (APD) and VMFS heartbeat events of the hosts.
+var datastoreAccessibilityEventTypes = []string{
+	"DatastoreDiscoveredEvent",
+	"DatastoreRemovedOnHostEvent",
+	"esx.problem.storage.apd.start",
+	"esx.problem.storage.apd.timeout",
+	"esx.clear.storage.apd.exit",
+	"esx.problem.storage.connectivity.lost",
+	"esx.clear.storage.connectivity.restored",
+	"esx.problem.vmfs.heartbeat.timedout",
+	"esx.problem.vmfs.heartbeat.recovered",
+}
+
+// WatchDatastoreAccessibilityEvents calls handler with the events of the
+// given vCenter posted as datastores get connected to or disconnected from
+// hosts, until the given context is canceled or the watch fails. The latest
+// events are replayed first.
+func WatchDatastoreAccessibilityEvents(ctx context.Context, vc *VirtualCenter,
+	handler func(e types.BaseEvent)) error {
+	pool := vc.SessionPool()
+	pooled, err := pool.Get(ctx)
+	if err != nil {
+		return err
+	}
+	defer pool.Put(pooled)
+	client := pooled.Client
+	return event.NewManager(client).Events(ctx, []types.ManagedObjectReference{client.ServiceContent.RootFolder},
+		datastoreAccessibilityEventPageSize, true, false,
+		func(_ types.ManagedObjectReference, events []types.BaseEvent) error {
+			for _, e := range events {
+				handler(e)
+			}
+			return nil
+		}, datastoreAccessibilityEventTypes...)
+}
@@ -18,15 +18,34 @@ package vsphere
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 
+	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/pbm"
 	pbmmethods "github.com/vmware/govmomi/pbm/methods"
 	pbmtypes "github.com/vmware/govmomi/pbm/types"
+	"github.com/vmware/govmomi/vim25/methods"
 	vimtypes "github.com/vmware/govmomi/vim25/types"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
 )
 
+const (
+	// dataServicePolicyNamespace is the namespace of the rules of storage
+	// policies referring to data service policies, e.g. encryption policies.
+	// The capability ID of these rules is the ID of the data service policy.
+	dataServicePolicyNamespace = "com.vmware.storageprofile.dataservice"
+	// vmCryptNamespacePrefix is the prefix of the namespace of the rules of
+	// the VM Encryption I/O filter.
+	vmCryptNamespacePrefix = "vmwarevmcrypt"
+)
+
+// ErrStoragePolicyNotFound is returned, wrapped, when no storage policy has
+// the requested name.
+var ErrStoragePolicyNotFound = errors.New("storage policy not found")
+
 // SpbmPolicyRule is an individual policy rule.
 // Not all providers use Ns, CapID, PropID in the same way,
 // so one needs to look at each one individually.
@@ -80,20 +99,62 @@ func (vc *VirtualCenter) DisconnectPbm(ctx context.Context) error {
 	return nil
 }
 
-// GetStoragePolicyIDByName gets storage policy ID by name.
+// GetStoragePolicyIDByName gets storage policy ID by name. If no storage
+// policy has the given name, the returned error wraps
+// ErrStoragePolicyNotFound and lists the names of the storage policies.
 func (vc *VirtualCenter) GetStoragePolicyIDByName(ctx context.Context, storagePolicyName string) (string, error) {
+	log := logger.GetLogger(ctx)
+	storagePolicyIDs, err := vc.GetStoragePolicyIDsByName(ctx)
+	if err != nil {
+		log.Errorf("failed to get StoragePolicyID from StoragePolicyName %s with err: %v", storagePolicyName, err)
+		return "", err
+	}
+	storagePolicyID, ok := storagePolicyIDs[storagePolicyName]
+	if !ok {
+		return "", fmt.Errorf("%w: %q does not exist in vCenter %q. Available storage policies: %s",
+			ErrStoragePolicyNotFound, storagePolicyName, vc.Config.Host,
+			strings.Join(GetStoragePolicyNames(storagePolicyIDs), ", "))
+	}
+	return storagePolicyID, nil
+}
+
+// GetStoragePolicyIDsByName returns the IDs of the storage policies of the
+// virtual center, by name.
+func (vc *VirtualCenter) GetStoragePolicyIDsByName(ctx context.Context) (map[string]string, error) {
 	log := logger.GetLogger(ctx)
 	err := vc.ConnectPbm(ctx)
 	if err != nil {
 		log.Errorf("Error occurred while connecting to PBM, err: %+v", err)
-		return "", err
+		return nil, err
+	}
+	resourceType := pbmtypes.PbmProfileResourceType{
+		ResourceType: string(pbmtypes.PbmProfileResourceTypeEnumSTORAGE),
 	}
-	storagePolicyID, err := vc.PbmClient.ProfileIDByName(ctx, storagePolicyName)
+	ids, err := vc.PbmClient.QueryProfile(ctx, resourceType, string(pbmtypes.PbmProfileCategoryEnumREQUIREMENT))
 	if err != nil {
-		log.Errorf("failed to get StoragePolicyID from StoragePolicyName %s with err: %v", storagePolicyName, err)
-		return "", err
+		return nil, err
 	}
-	return storagePolicyID, nil
+	profiles, err := vc.PbmClient.RetrieveContent(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	storagePolicyIDs := make(map[string]string, len(profiles))
+	for _, profile := range profiles {
+		pbmProfile := profile.GetPbmProfile()
+		storagePolicyIDs[pbmProfile.Name] = pbmProfile.ProfileId.UniqueId
+	}
+	return storagePolicyIDs, nil
+}
+
+// GetStoragePolicyNames returns the sorted names of the given storage
+// policies, by name.
+func GetStoragePolicyNames(storagePolicyIDs map[string]string) []string {
+	names := make([]string, 0, len(storagePolicyIDs))
+	for name := range storagePolicyIDs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
 // PbmCheckCompatibility performs a compatibility check for the given profileID
@@ -136,6 +197,97 @@ func (vc *VirtualCenter) PbmRetrieveContent(ctx context.Context, policyIds []str
 	return simplifyProfileStructs(ctx, profiles), err
 }
 
+// PbmFetchComplianceResult fetches the last storage policy compliance result
+// of the given FCDs from SPBM.
+func (vc *VirtualCenter) PbmFetchComplianceResult(ctx context.Context,
+	volumeIDs []string) ([]pbmtypes.PbmComplianceResult, error) {
+	entities := make([]pbmtypes.PbmServerObjectRef, 0, len(volumeIDs))
+	for _, volumeID := range volumeIDs {
+		entities = append(entities, pbmtypes.PbmServerObjectRef{
+			ObjectType: string(pbmtypes.PbmObjectTypeVirtualDiskUUID),
+			Key:        volumeID,
+		})
+	}
+	return vc.PbmClient.FetchComplianceResult(ctx, entities)
+}
+
+// UpdateVolumePolicy applies the storage policy with the given profileID to
+// the FCD with the given volumeID on the given datastore.
+func (vc *VirtualCenter) UpdateVolumePolicy(ctx context.Context, volumeID string,
+	datastore vimtypes.ManagedObjectReference, profileID string) (*object.Task, error) {
+	if vc.Client.ServiceContent.VStorageObjectManager == nil {
+		return nil, fmt.Errorf("VStorageObjectManager is not available on vCenter %q", vc.Config.Host)
+	}
+	req := vimtypes.UpdateVStorageObjectPolicy_Task{
+		This:      *vc.Client.ServiceContent.VStorageObjectManager,
+		Id:        vimtypes.ID{Id: volumeID},
+		Datastore: datastore,
+		Profile: []vimtypes.BaseVirtualMachineProfileSpec{
+			&vimtypes.VirtualMachineDefinedProfileSpec{ProfileId: profileID},
+		},
+	}
+	res, err := methods.UpdateVStorageObjectPolicy_Task(ctx, vc.Client, &req)
+	if err != nil {
+		return nil, err
+	}
+	return object.NewTask(vc.Client.Client, res.Returnval), nil
+}
+
+// IsEncryptionStoragePolicy returns whether the storage policy with the given
+// policyID encrypts the volumes it is applied to, i.e. whether it contains VM
+// Encryption rules or refers to an encryption data service policy.
+func (vc *VirtualCenter) IsEncryptionStoragePolicy(ctx context.Context, policyID string) (bool, error) {
+	log := logger.GetLogger(ctx)
+	if err := vc.ConnectPbm(ctx); err != nil {
+		return false, err
+	}
+	policies, err := vc.PbmRetrieveContent(ctx, []string{policyID})
+	if err != nil {
+		log.Errorf("failed to retrieve content of storage policy %q with err: %v", policyID, err)
+		return false, err
+	}
+	encrypted, dataServicePolicyIDs := getEncryptionRules(policies)
+	if encrypted || len(dataServicePolicyIDs) == 0 {
+		return encrypted, nil
+	}
+	ids := make([]pbmtypes.PbmProfileId, 0, len(dataServicePolicyIDs))
+	for _, id := range dataServicePolicyIDs {
+		ids = append(ids, pbmtypes.PbmProfileId{UniqueId: id})
+	}
+	dataServicePolicies, err := vc.PbmClient.RetrieveContent(ctx, ids)
+	if err != nil {
+		log.Errorf("failed to retrieve content of data service policies %v with err: %v",
+			dataServicePolicyIDs, err)
+		return false, err
+	}
+	for _, policy := range dataServicePolicies {
+		if p, ok := policy.(*pbmtypes.PbmCapabilityProfile); ok &&
+			p.LineOfService == string(pbmtypes.PbmLineOfServiceInfoLineOfServiceEnumENCRYPTION) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// getEncryptionRules returns whether the given storage policies contain VM
+// Encryption rules, and the IDs of the data service policies they refer to.
+func getEncryptionRules(policies []SpbmPolicyContent) (bool, []string) {
+	var dataServicePolicyIDs []string
+	for _, policy := range policies {
+		for _, profile := range policy.Profiles {
+			for _, rule := range profile.Rules {
+				if strings.HasPrefix(rule.Ns, vmCryptNamespacePrefix) {
+					return true, nil
+				}
+				if rule.Ns == dataServicePolicyNamespace && rule.CapID != "" {
+					dataServicePolicyIDs = append(dataServicePolicyIDs, rule.CapID)
+				}
+			}
+		}
+	}
+	return false, dataServicePolicyIDs
+}
+
 func simplifyProfileStructs(ctx context.Context, profiles []pbmtypes.BasePbmProfile) []SpbmPolicyContent {
 	log := logger.GetLogger(ctx)
 	out := make([]SpbmPolicyContent, 0)
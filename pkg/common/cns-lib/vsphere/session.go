@@ -0,0 +1,264 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vsphere
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/session/keepalive"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/prometheus"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+)
+
+const (
+	// DefaultSessionKeepAliveInterval is the interval at which VC sessions are
+	// kept alive. VC sessions time out after 30 minutes of inactivity by
+	// default.
+	DefaultSessionKeepAliveInterval = 5 * time.Minute
+	// DefaultSessionPoolSize is the default maximum number of sessions held by
+	// the SessionPool of a VirtualCenter.
+	DefaultSessionPoolSize = 8
+)
+
+// sessionKeepAliveInterval is the interval at which VC sessions are kept
+// alive. It is only changed by tests.
+var sessionKeepAliveInterval = DefaultSessionKeepAliveInterval
+
+// IsNotAuthenticatedError checks if err is the NotAuthenticated fault, which
+// is returned by VC once the session has expired.
+func IsNotAuthenticatedError(err error) bool {
+	isNotAuthenticatedError := false
+	if soap.IsSoapFault(err) {
+		_, isNotAuthenticatedError = soap.ToSoapFault(err).VimFault().(types.NotAuthenticated)
+	}
+	return isNotAuthenticatedError
+}
+
+// reloginRoundTripper is a soap.RoundTripper logging in again and retrying
+// the request once when VC reports the session as not authenticated, so that
+// session timeouts are transparent to the callers.
+type reloginRoundTripper struct {
+	vc           *VirtualCenter
+	roundTripper soap.RoundTripper
+	// client is the client whose session is re-established. It is set once
+	// the client is created.
+	client *govmomi.Client
+	// reloginLock serializes re-logins, so that a burst of NotAuthenticated
+	// errors results in a single new session.
+	reloginLock sync.Mutex
+	// loggedOutLock protects loggedOut. It is distinct from reloginLock as
+	// the login issued by relogin goes through this round tripper.
+	loggedOutLock sync.Mutex
+	// loggedOut is set once the client is explicitly logged out, after which
+	// the session must not be re-established.
+	loggedOut bool
+}
+
+// RoundTrip implements soap.RoundTripper.
+func (rt *reloginRoundTripper) RoundTrip(ctx context.Context, req, res soap.HasFault) error {
+	switch req.(type) {
+	case *methods.LoginBody, *methods.LoginByTokenBody:
+		rt.setLoggedOut(false)
+		return rt.roundTripper.RoundTrip(ctx, req, res)
+	case *methods.LogoutBody:
+		rt.setLoggedOut(true)
+		return rt.roundTripper.RoundTrip(ctx, req, res)
+	}
+	err := rt.roundTripper.RoundTrip(ctx, req, res)
+	if err == nil || !IsNotAuthenticatedError(err) || rt.client == nil {
+		return err
+	}
+	if reloginErr := rt.relogin(ctx); reloginErr != nil {
+		return err
+	}
+	// Clear the fault of the failed attempt before retrying.
+	resValue := reflect.ValueOf(res).Elem()
+	resValue.Set(reflect.Zero(resValue.Type()))
+	return rt.roundTripper.RoundTrip(ctx, req, res)
+}
+
+func (rt *reloginRoundTripper) setLoggedOut(loggedOut bool) {
+	rt.loggedOutLock.Lock()
+	defer rt.loggedOutLock.Unlock()
+	rt.loggedOut = loggedOut
+}
+
+func (rt *reloginRoundTripper) isLoggedOut() bool {
+	rt.loggedOutLock.Lock()
+	defer rt.loggedOutLock.Unlock()
+	return rt.loggedOut
+}
+
+// relogin establishes a new session for the client, unless another request
+// already did so or the client was logged out.
+func (rt *reloginRoundTripper) relogin(ctx context.Context) error {
+	log := logger.GetLogger(ctx)
+	rt.reloginLock.Lock()
+	defer rt.reloginLock.Unlock()
+	if rt.isLoggedOut() {
+		return fmt.Errorf("VC session on %q was logged out", rt.vc.Config.Host)
+	}
+	userSession, err := rt.client.SessionManager.UserSession(ctx)
+	if err == nil && userSession != nil {
+		return nil
+	}
+	log.Infof("VC session on %q is not authenticated. Logging in again.", rt.vc.Config.Host)
+	if err := rt.vc.login(ctx, rt.client); err != nil {
+		log.Errorf("failed to log in again to VC %q. err: %v", rt.vc.Config.Host, err)
+		prometheus.VCSessionOpsCounterVec.WithLabelValues(prometheus.PrometheusVCSessionReloginOpType,
+			prometheus.PrometheusFailStatus).Inc()
+		return err
+	}
+	prometheus.VCSessionOpsCounterVec.WithLabelValues(prometheus.PrometheusVCSessionReloginOpType,
+		prometheus.PrometheusPassStatus).Inc()
+	return nil
+}
+
+// newSessionRoundTripper wraps the given soap.RoundTripper with a keepalive
+// handler, which keeps the session alive once logged in, and with the
+// re-login round tripper. The client of the returned reloginRoundTripper must
+// be set once created.
+func (vc *VirtualCenter) newSessionRoundTripper(roundTripper soap.RoundTripper) (soap.RoundTripper,
+	*reloginRoundTripper) {
+	relogin := &reloginRoundTripper{
+		vc:           vc,
+		roundTripper: roundTripper,
+	}
+	keepAlive := keepalive.NewHandlerSOAP(relogin, sessionKeepAliveInterval, func() error {
+		ctx, log := logger.GetNewContextWithLogger()
+		// An expired session is re-established by the re-login round tripper.
+		if _, err := methods.GetCurrentTime(ctx, relogin); err != nil {
+			log.Warnf("failed to keep VC session on %q alive. err: %v", vc.Config.Host, err)
+			prometheus.VCSessionOpsCounterVec.WithLabelValues(prometheus.PrometheusVCSessionKeepAliveOpType,
+				prometheus.PrometheusFailStatus).Inc()
+			// Returning an error stops the keepalive, keep trying instead.
+			return nil
+		}
+		prometheus.VCSessionOpsCounterVec.WithLabelValues(prometheus.PrometheusVCSessionKeepAliveOpType,
+			prometheus.PrometheusPassStatus).Inc()
+		return nil
+	})
+	return keepAlive, relogin
+}
+
+// SessionPool is a bounded pool of VC sessions, for callers issuing long
+// running or concurrent calls which should not contend on the session of the
+// VirtualCenter client. Pooled sessions are kept alive and logged in again
+// transparently like the VirtualCenter session.
+type SessionPool struct {
+	vc *VirtualCenter
+	// sessions holds a token for each session in use, bounding the number of
+	// sessions of the pool.
+	sessions chan struct{}
+	// lock protects idle.
+	lock sync.Mutex
+	idle []*govmomi.Client
+}
+
+// NewSessionPool returns a SessionPool holding at most size sessions on the
+// given VirtualCenter. DefaultSessionPoolSize is used if size is not
+// positive.
+func NewSessionPool(vc *VirtualCenter, size int) *SessionPool {
+	if size <= 0 {
+		size = DefaultSessionPoolSize
+	}
+	return &SessionPool{
+		vc:       vc,
+		sessions: make(chan struct{}, size),
+	}
+}
+
+// Get returns a client with an authenticated session from the pool, creating
+// a new session if no idle one is available. It blocks while all the
+// sessions of the pool are in use. The client must be returned with Put.
+func (p *SessionPool) Get(ctx context.Context) (*govmomi.Client, error) {
+	select {
+	case p.sessions <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	p.lock.Lock()
+	if len(p.idle) > 0 {
+		client := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		p.lock.Unlock()
+		return client, nil
+	}
+	p.lock.Unlock()
+	client, err := p.vc.newClient(ctx)
+	if err != nil {
+		<-p.sessions
+		return nil, err
+	}
+	return client, nil
+}
+
+// Put returns a client obtained with Get to the pool.
+func (p *SessionPool) Put(client *govmomi.Client) {
+	p.lock.Lock()
+	p.idle = append(p.idle, client)
+	p.lock.Unlock()
+	<-p.sessions
+}
+
+// Close logs out the idle sessions of the pool.
+func (p *SessionPool) Close(ctx context.Context) {
+	log := logger.GetLogger(ctx)
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for _, client := range p.idle {
+		status := prometheus.PrometheusPassStatus
+		if err := client.Logout(ctx); err != nil {
+			log.Warnf("failed to log out pooled session of VC %q. err: %v", p.vc.Config.Host, err)
+			status = prometheus.PrometheusFailStatus
+		}
+		prometheus.VCSessionOpsCounterVec.WithLabelValues(prometheus.PrometheusVCSessionLogoutOpType, status).Inc()
+	}
+	p.idle = nil
+}
+
+// SessionPool returns the pool of sessions of the VirtualCenter, used by the
+// long running watches of vCenter so that they don't hold the session of the
+// VirtualCenter client.
+func (vc *VirtualCenter) SessionPool() *SessionPool {
+	vc.sessionPoolLock.Lock()
+	defer vc.sessionPoolLock.Unlock()
+	if vc.sessionPool == nil {
+		vc.sessionPool = NewSessionPool(vc, vc.Config.SessionPoolSize)
+	}
+	return vc.sessionPool
+}
+
+// closeSessionPool logs out the idle sessions of the pool of the
+// VirtualCenter, if any.
+func (vc *VirtualCenter) closeSessionPool(ctx context.Context) {
+	vc.sessionPoolLock.Lock()
+	pool := vc.sessionPool
+	vc.sessionPoolLock.Unlock()
+	if pool != nil {
+		pool.Close(ctx)
+	}
+}
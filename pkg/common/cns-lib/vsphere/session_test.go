@@ -0,0 +1,199 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vsphere
+
+import (
+	"context"
+	"crypto/tls"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// newTestVirtualCenter returns a VirtualCenter on a new vCenter simulator,
+// along with a function stopping the simulator.
+func newTestVirtualCenter(t *testing.T) (*VirtualCenter, *simulator.Server, func()) {
+	model := simulator.VPX()
+	if err := model.Create(); err != nil {
+		t.Fatal(err)
+	}
+	model.Service.TLS = new(tls.Config)
+	server := model.Service.NewServer()
+	port, err := strconv.Atoi(server.URL.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+	password, _ := simulator.DefaultLogin.Password()
+	vc := &VirtualCenter{Config: &VirtualCenterConfig{
+		Host:     server.URL.Hostname(),
+		Port:     port,
+		Username: simulator.DefaultLogin.Username(),
+		Password: password,
+		Insecure: true,
+	}}
+	return vc, server, func() {
+		server.Close()
+		model.Remove()
+	}
+}
+
+// getSessionKey returns the key of the current session of the given client.
+func getSessionKey(ctx context.Context, t *testing.T, client *govmomi.Client) string {
+	userSession, err := client.SessionManager.UserSession(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userSession == nil {
+		t.Fatal("expected an authenticated session")
+	}
+	return userSession.Key
+}
+
+func TestSessionRelogin(t *testing.T) {
+	ctx := context.Background()
+	vc, server, stop := newTestVirtualCenter(t)
+	defer stop()
+	client, err := vc.newClient(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := getSessionKey(ctx, t, client)
+
+	// Terminate the session from another session, as VC does once it expires.
+	other, err := govmomi.NewClient(ctx, server.URL, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := other.SessionManager.TerminateSession(ctx, []string{key}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := methods.GetCurrentTime(ctx, client); err != nil {
+		t.Fatalf("expected the request to succeed after logging in again, got %v", err)
+	}
+	if newKey := getSessionKey(ctx, t, client); newKey == key {
+		t.Errorf("expected a new session")
+	}
+
+	// Logged out sessions are not re-established.
+	if err := client.Logout(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := methods.GetCurrentTime(ctx, client); err == nil || !IsNotAuthenticatedError(err) {
+		t.Errorf("expected the NotAuthenticated fault once logged out, got %v", err)
+	}
+}
+
+// countingRoundTripper is a soap.RoundTripper counting the GetCurrentTime
+// requests.
+type countingRoundTripper struct {
+	lock            sync.Mutex
+	getCurrentTimes int
+}
+
+func (rt *countingRoundTripper) RoundTrip(ctx context.Context, req, res soap.HasFault) error {
+	if body, ok := res.(*methods.CurrentTimeBody); ok {
+		body.Res = &types.CurrentTimeResponse{}
+		rt.lock.Lock()
+		rt.getCurrentTimes++
+		rt.lock.Unlock()
+	}
+	return nil
+}
+
+func (rt *countingRoundTripper) count() int {
+	rt.lock.Lock()
+	defer rt.lock.Unlock()
+	return rt.getCurrentTimes
+}
+
+func TestSessionKeepAlive(t *testing.T) {
+	defer func(interval time.Duration) {
+		sessionKeepAliveInterval = interval
+	}(sessionKeepAliveInterval)
+	sessionKeepAliveInterval = 50 * time.Millisecond
+	ctx := context.Background()
+	vc := &VirtualCenter{Config: &VirtualCenterConfig{Host: "127.0.0.1"}}
+	counter := &countingRoundTripper{}
+	roundTripper, _ := vc.newSessionRoundTripper(counter)
+
+	time.Sleep(4 * sessionKeepAliveInterval)
+	if count := counter.count(); count != 0 {
+		t.Fatalf("expected no keepalive before login, got %d requests", count)
+	}
+	if _, err := methods.Login(ctx, roundTripper, &types.Login{}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * sessionKeepAliveInterval)
+	if count := counter.count(); count < 2 {
+		t.Errorf("expected the session to be kept alive, got %d requests", count)
+	}
+	if _, err := methods.Logout(ctx, roundTripper, &types.Logout{}); err != nil {
+		t.Fatal(err)
+	}
+	count := counter.count()
+	time.Sleep(4 * sessionKeepAliveInterval)
+	if newCount := counter.count(); newCount != count {
+		t.Errorf("expected the keepalive to stop on logout, got %d more requests", newCount-count)
+	}
+}
+
+func TestSessionPool(t *testing.T) {
+	ctx := context.Background()
+	vc, _, stop := newTestVirtualCenter(t)
+	defer stop()
+	vc.Config.SessionPoolSize = 1
+	pool := vc.SessionPool()
+	if vc.SessionPool() != pool {
+		t.Fatal("expected the VirtualCenter to hold a single session pool")
+	}
+	client, err := pool.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := getSessionKey(ctx, t, client)
+
+	// The pool is bounded, Get blocks until the session is returned.
+	timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Get(timeoutCtx); err != context.DeadlineExceeded {
+		t.Fatalf("expected Get to block while all the sessions are in use, got %v", err)
+	}
+	pool.Put(client)
+	reused, err := pool.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if getSessionKey(ctx, t, reused) != key {
+		t.Errorf("expected the idle session to be reused")
+	}
+	pool.Put(reused)
+
+	// Disconnecting the VirtualCenter logs out the idle sessions.
+	if err := vc.Disconnect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if userSession, err := reused.SessionManager.UserSession(ctx); err != nil || userSession != nil {
+		t.Errorf("expected the pooled session to be logged out, got %v, %v", userSession, err)
+	}
+}
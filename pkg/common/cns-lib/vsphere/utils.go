@@ -10,6 +10,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/vmware/govmomi/cns"
@@ -211,6 +212,12 @@ func GetVirtualCenterConfig(ctx context.Context, cfg *config.Config) (*VirtualCe
 		VCClientTimeout:                  vcClientTimeout,
 		QueryLimit:                       cfg.Global.QueryLimit,
 		ListVolumeThreshold:              cfg.Global.ListVolumeThreshold,
+		TaskPollInterval:                 time.Duration(cfg.Global.TaskPollIntervalInMs) * time.Millisecond,
+		TaskPollMaxInterval:              time.Duration(cfg.Global.TaskPollMaxIntervalInSec) * time.Second,
+		TaskTimeout:                      time.Duration(cfg.Global.TaskTimeoutInSec) * time.Second,
+		VolumeQueryCacheTTL:              time.Duration(cfg.Global.VolumeQueryCacheTTLInSec) * time.Second,
+		VMDiscoveryCache:                 cfg.Global.EnableVMDiscoveryCache,
+		SessionPoolSize:                  cfg.Global.SessionPoolSize,
 	}
 
 	log.Debugf("Setting the queryLimit = %v, ListVolumeThreshold = %v", vcConfig.QueryLimit, vcConfig.ListVolumeThreshold)
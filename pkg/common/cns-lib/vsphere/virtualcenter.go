@@ -33,6 +33,7 @@ import (
 	"github.com/vmware/govmomi/vslm"
 
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/prometheus"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
 
 	"github.com/vmware/govmomi"
@@ -68,6 +69,11 @@ type VirtualCenter struct {
 	VsanClient *vsan.Client
 	// VslmClient represents the Vslm client instance.
 	VslmClient *vslm.Client
+	// sessionPoolLock protects sessionPool.
+	sessionPoolLock sync.Mutex
+	// sessionPool is the pool of sessions of the long running watches. It is
+	// created on first use.
+	sessionPool *SessionPool
 }
 
 var (
@@ -125,6 +131,25 @@ type VirtualCenterConfig struct {
 	// ListVolumeThreshold specifies the maximum number of differences in volume that
 	// can exist between CNS and kubernetes
 	ListVolumeThreshold int
+	// TaskPollInterval is the initial interval at which CNS tasks are polled
+	// for completion.
+	TaskPollInterval time.Duration
+	// TaskPollMaxInterval is the maximum interval at which CNS tasks are
+	// polled for completion.
+	TaskPollMaxInterval time.Duration
+	// TaskTimeout is the time after which waiting for a CNS task to complete
+	// fails. Waiting isn't bounded if it is 0.
+	TaskTimeout time.Duration
+	// VolumeQueryCacheTTL is the time for which the results of the CNS
+	// queries of volumes by ID are cached. They aren't cached if it is 0.
+	VolumeQueryCacheTTL time.Duration
+	// VMDiscoveryCache specifies whether the VMs are looked up by UUID from a
+	// cache kept up to date through property collector subscriptions.
+	VMDiscoveryCache bool
+	// SessionPoolSize is the maximum number of sessions held by the pool of
+	// sessions of the long running watches. DefaultSessionPoolSize is used if
+	// it is 0.
+	SessionPoolSize int
 }
 
 // clientMutex is used for exclusive connection creation.
@@ -168,15 +193,23 @@ func (vc *VirtualCenter) newClient(ctx context.Context) (*govmomi.Client, error)
 		return nil, err
 	}
 	vimClient.UserAgent = "k8s-csi-useragent"
+	// Keep the session alive and log in again transparently once it expires.
+	roundTripper, relogin := vc.newSessionRoundTripper(vimClient.RoundTripper)
+	vimClient.RoundTripper = roundTripper
 	client := &govmomi.Client{
 		Client:         vimClient,
 		SessionManager: session.NewManager(vimClient),
 	}
+	relogin.client = client
 
 	err = vc.login(ctx, client)
 	if err != nil {
+		prometheus.VCSessionOpsCounterVec.WithLabelValues(prometheus.PrometheusVCSessionLoginOpType,
+			prometheus.PrometheusFailStatus).Inc()
 		return nil, err
 	}
+	prometheus.VCSessionOpsCounterVec.WithLabelValues(prometheus.PrometheusVCSessionLoginOpType,
+		prometheus.PrometheusPassStatus).Inc()
 
 	s, err := client.SessionManager.UserSession(ctx)
 	if err == nil {
@@ -280,6 +313,11 @@ func (vc *VirtualCenter) connect(ctx context.Context, requestNewSession bool) er
 	}
 	// If session has expired, create a new instance.
 	log.Warnf("Creating a new client session as the existing one isn't valid or not authenticated")
+	// Log out the existing session, which also stops its keepalive. This is
+	// best effort as the session is likely expired already.
+	if logoutErr := vc.Client.Logout(ctx); logoutErr != nil {
+		log.Debugf("failed to logout of the existing VC session. err: %v", logoutErr)
+	}
 	if vc.Client, err = vc.newClient(ctx); err != nil {
 		log.Errorf("failed to create govmomi client with err: %v", err)
 		return err
@@ -317,6 +355,48 @@ func (vc *VirtualCenter) connect(ctx context.Context, requestNewSession bool) er
 	return nil
 }
 
+// SessionStatus is the status of the session of a VirtualCenter.
+type SessionStatus struct {
+	// Host is the host of the VirtualCenter.
+	Host string `json:"host"`
+	// Authenticated is true if the session is authenticated and not expired.
+	Authenticated bool `json:"authenticated"`
+	// UserName is the user of the session, if authenticated.
+	UserName string `json:"userName,omitempty"`
+	// LoginTime is the time of the login of the session, if authenticated.
+	LoginTime time.Time `json:"loginTime,omitempty"`
+	// LastActiveTime is the time of the last activity of the session, if
+	// authenticated.
+	LastActiveTime time.Time `json:"lastActiveTime,omitempty"`
+	// Error is the error getting the session, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// GetSessionStatus returns the status of the current session of the
+// VirtualCenter. Unlike Connect, it doesn't renew an expired session.
+func (vc *VirtualCenter) GetSessionStatus(ctx context.Context) SessionStatus {
+	status := SessionStatus{Host: vc.Config.Host}
+	clientMutex.Lock()
+	client := vc.Client
+	clientMutex.Unlock()
+	if client == nil {
+		status.Error = "not connected"
+		return status
+	}
+	userSession, err := session.NewManager(client.Client).UserSession(ctx)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	if userSession != nil {
+		status.Authenticated = true
+		status.UserName = userSession.UserName
+		status.LoginTime = userSession.LoginTime
+		status.LastActiveTime = userSession.LastActiveTime
+	}
+	return status
+}
+
 // ListDatacenters returns all Datacenters.
 func (vc *VirtualCenter) ListDatacenters(ctx context.Context) (
 	[]*Datacenter, error) {
@@ -377,6 +457,7 @@ func (vc *VirtualCenter) GetDatacenters(ctx context.Context) ([]*Datacenter, err
 // Disconnect disconnects the virtual center host connection if connected.
 func (vc *VirtualCenter) Disconnect(ctx context.Context) error {
 	log := logger.GetLogger(ctx)
+	vc.closeSessionPool(ctx)
 	if vc.Client == nil {
 		log.Info("Client wasn't connected, ignoring")
 		return nil
@@ -389,6 +470,23 @@ func (vc *VirtualCenter) Disconnect(ctx context.Context) error {
 	return nil
 }
 
+// LogoutAllVirtualCenters logs out of the sessions of all the registered
+// virtual centers, e.g. on termination, so that sessions don't accumulate on
+// the virtual centers until they expire.
+func LogoutAllVirtualCenters(ctx context.Context) {
+	log := logger.GetLogger(ctx)
+	for _, vc := range GetVirtualCenterManager(ctx).GetAllVirtualCenters() {
+		clientMutex.Lock()
+		err := vc.Disconnect(ctx)
+		clientMutex.Unlock()
+		if err != nil {
+			log.Warnf("failed to log out of vCenter %q. Err: %v", vc.Config.Host, err)
+			continue
+		}
+		log.Infof("Logged out of vCenter %q", vc.Config.Host)
+	}
+}
+
 // GetHostsByCluster return hosts inside the cluster using cluster moref.
 func (vc *VirtualCenter) GetHostsByCluster(ctx context.Context,
 	clusterMorefValue string) ([]*HostSystem, error) {
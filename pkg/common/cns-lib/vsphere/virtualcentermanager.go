@@ -137,6 +137,7 @@ func (m *defaultVirtualCenterManager) UnregisterVirtualCenter(ctx context.Contex
 		log.Warnf("failed to disconnect VC %s, couldn't unregister", host)
 	}
 	vc.DisconnectCns(ctx)
+	stopVirtualMachineCaches(host)
 	m.virtualCenters.Delete(host)
 	log.Infof("Successfully unregistered VC %s", host)
 	return nil
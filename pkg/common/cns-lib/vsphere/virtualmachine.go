@@ -25,6 +25,7 @@ import (
 
 	"github.com/vmware/govmomi/vapi/tags"
 	"github.com/vmware/govmomi/vim25/mo"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
 
 	"github.com/vmware/govmomi/object"
@@ -466,3 +467,68 @@ func findMissingCategories(topologyCategories map[string]string) []string {
 	}
 	return missing
 }
+
+// topologyHierarchyLevelTypes maps the inventory levels of the topology
+// hierarchy to the type of their managed objects.
+var topologyHierarchyLevelTypes = map[string]string{
+	config.TopologyHierarchyDatacenter: "Datacenter",
+	config.TopologyHierarchyCluster:    "ClusterComputeResource",
+	config.TopologyHierarchyHost:       "HostSystem",
+}
+
+// GetTopologyHierarchy returns the managed object IDs of the ancestors of the
+// nodeVM at the given inventory levels of the topology hierarchy, by level.
+func (vm *VirtualMachine) GetTopologyHierarchy(ctx context.Context, levels []string) (map[string]string, error) {
+	log := logger.GetLogger(ctx)
+	if len(levels) == 0 {
+		return map[string]string{}, nil
+	}
+	objects, err := vm.GetAncestors(ctx)
+	if err != nil {
+		log.Errorf("GetAncestors failed for %v with err %v", vm.Reference(), err)
+		return nil, err
+	}
+	hierarchy := make(map[string]string)
+	for _, level := range levels {
+		for _, obj := range objects {
+			if obj.Self.Type == topologyHierarchyLevelTypes[level] {
+				hierarchy[level] = obj.Self.Value
+				break
+			}
+		}
+		if _, ok := hierarchy[level]; !ok {
+			return nil, logger.LogNewErrorf(log, "nodeVM %s has no ancestor at topology hierarchy level %q",
+				vm.Reference(), level)
+		}
+	}
+	log.Debugf("NodeVM %v belongs to topology hierarchy: %+v", vm.Reference(), hierarchy)
+	return hierarchy, nil
+}
+
+// GetHardwareVersion returns the hardware version of the virtual machine,
+// e.g. "vmx-14".
+func (vm *VirtualMachine) GetHardwareVersion(ctx context.Context) (string, error) {
+	log := logger.GetLogger(ctx)
+	var o mo.VirtualMachine
+	err := vm.Properties(ctx, vm.Reference(), []string{"config.version"}, &o)
+	if err != nil {
+		log.Errorf("failed to get the hardware version of VM %v with err: %v", vm, err)
+		return "", err
+	}
+	if o.Config == nil {
+		return "", fmt.Errorf("config of VM %v is not available", vm)
+	}
+	return o.Config.Version, nil
+}
+
+// GetPowerState returns the power state of the virtual machine.
+func (vm *VirtualMachine) GetPowerState(ctx context.Context) (types.VirtualMachinePowerState, error) {
+	log := logger.GetLogger(ctx)
+	var o mo.VirtualMachine
+	err := vm.Properties(ctx, vm.Reference(), []string{"runtime.powerState"}, &o)
+	if err != nil {
+		log.Errorf("failed to get the power state of VM %v with err: %v", vm, err)
+		return "", err
+	}
+	return o.Runtime.PowerState, nil
+}
@@ -0,0 +1,303 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vsphere
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+)
+
+const (
+	// vmCacheUUIDProperty is the property holding the BIOS UUID of a VM.
+	vmCacheUUIDProperty = "config.uuid"
+	// vmCacheInstanceUUIDProperty is the property holding the instance UUID
+	// of a VM.
+	vmCacheInstanceUUIDProperty = "config.instanceUuid"
+	// vmCacheHostProperty is the property holding the host of a VM, which
+	// changes when the VM is migrated with vMotion.
+	vmCacheHostProperty = "runtime.host"
+	// vmCacheRetryInterval is the interval at which the subscription of a
+	// virtualMachineCache is retried after it failed.
+	vmCacheRetryInterval = time.Minute
+)
+
+// vmCaches holds the running virtualMachineCaches, by vCenter host and
+// datacenter.
+var vmCaches sync.Map
+
+// virtualMachineCache keeps the managed object references of the VMs of a
+// datacenter, by BIOS and instance UUID, up to date through a property
+// collector subscription, so that VMs are looked up by UUID without
+// searching the inventory of vCenter. VMs migrated with vMotion are dropped
+// from the cache, so that their next lookup searches them again.
+type virtualMachineCache struct {
+	lock sync.RWMutex
+	// synced is set once the VMs of the datacenter were received from the
+	// subscription, and reset when the subscription fails.
+	synced bool
+	// uuids holds the references of the VMs by lower case BIOS UUID.
+	uuids map[string]types.ManagedObjectReference
+	// instanceUUIDs holds the references of the VMs by lower case instance
+	// UUID.
+	instanceUUIDs map[string]types.ManagedObjectReference
+	// vms holds the cached VMs by reference.
+	vms map[types.ManagedObjectReference]*vmCacheEntry
+	// cancel stops the subscription.
+	cancel context.CancelFunc
+}
+
+// vmCacheEntry holds the properties of a cached VM.
+type vmCacheEntry struct {
+	uuid         string
+	instanceUUID string
+	host         types.ManagedObjectReference
+}
+
+// newVirtualMachineCache returns an empty virtualMachineCache.
+func newVirtualMachineCache() *virtualMachineCache {
+	return &virtualMachineCache{
+		uuids:         make(map[string]types.ManagedObjectReference),
+		instanceUUIDs: make(map[string]types.ManagedObjectReference),
+		vms:           make(map[types.ManagedObjectReference]*vmCacheEntry),
+	}
+}
+
+// getVirtualMachineCache returns the virtualMachineCache of the given
+// datacenter, starting it if needed, or nil if the cache isn't enabled for
+// the vCenter of the datacenter.
+func getVirtualMachineCache(ctx context.Context, dc *Datacenter) *virtualMachineCache {
+	log := logger.GetLogger(ctx)
+	key := dc.VirtualCenterHost + "/" + dc.Reference().Value
+	vc, err := GetVirtualCenterManager(ctx).GetVirtualCenter(ctx, dc.VirtualCenterHost)
+	if err != nil || vc.Config == nil || !vc.Config.VMDiscoveryCache {
+		if cache, loaded := vmCaches.LoadAndDelete(key); loaded {
+			log.Infof("Stopping VM discovery cache of datacenter %v", dc)
+			cache.(*virtualMachineCache).cancel()
+		}
+		return nil
+	}
+	if cache, ok := vmCaches.Load(key); ok {
+		return cache.(*virtualMachineCache)
+	}
+	cache := newVirtualMachineCache()
+	runCtx, cancel := context.WithCancel(logger.NewContextWithLogger(context.Background()))
+	cache.cancel = cancel
+	if existing, loaded := vmCaches.LoadOrStore(key, cache); loaded {
+		cancel()
+		return existing.(*virtualMachineCache)
+	}
+	log.Infof("Starting VM discovery cache of datacenter %v", dc)
+	go cache.run(runCtx, dc.VirtualCenterHost, dc.Reference())
+	return cache
+}
+
+// stopVirtualMachineCaches stops the virtualMachineCaches of the
+// datacenters of the given vCenter.
+func stopVirtualMachineCaches(host string) {
+	vmCaches.Range(func(key, cache interface{}) bool {
+		if strings.HasPrefix(key.(string), host+"/") {
+			vmCaches.Delete(key)
+			cache.(*virtualMachineCache).cancel()
+		}
+		return true
+	})
+}
+
+// run subscribes to the VMs of the given datacenter of the given vCenter
+// until the given context is canceled, retrying when the subscription fails,
+// e.g. while vCenter is unreachable.
+func (c *virtualMachineCache) run(ctx context.Context, host string, dcRef types.ManagedObjectReference) {
+	log := logger.GetLogger(ctx)
+	for {
+		err := c.subscribe(ctx, host, dcRef)
+		c.reset()
+		if ctx.Err() != nil {
+			log.Infof("VM discovery cache of datacenter %v on vCenter %q stopped", dcRef, host)
+			return
+		}
+		log.Warnf("VM discovery cache of datacenter %v on vCenter %q failed, retrying in %v. Err: %v",
+			dcRef, host, vmCacheRetryInterval, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(vmCacheRetryInterval):
+		}
+	}
+}
+
+// subscribe creates a property collector subscription to the UUIDs and hosts
+// of the VMs of the given datacenter, and applies the updates to the cache
+// until the subscription fails or the given context is canceled.
+func (c *virtualMachineCache) subscribe(ctx context.Context, host string, dcRef types.ManagedObjectReference) error {
+	vc, err := GetVirtualCenterManager(ctx).GetVirtualCenter(ctx, host)
+	if err != nil {
+		return err
+	}
+	pool := vc.SessionPool()
+	pooled, err := pool.Get(ctx)
+	if err != nil {
+		return err
+	}
+	defer pool.Put(pooled)
+	client := pooled.Client
+	containerView, err := view.NewManager(client).CreateContainerView(ctx,
+		object.NewDatacenter(client, dcRef).Reference(), []string{"VirtualMachine"}, true)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = containerView.Destroy(context.Background())
+	}()
+	filter := new(property.WaitFilter).Add(containerView.Reference(), "VirtualMachine",
+		[]string{vmCacheUUIDProperty, vmCacheInstanceUUIDProperty, vmCacheHostProperty},
+		&types.TraversalSpec{Type: containerView.Reference().Type, Path: "view"})
+	filter.Spec.ObjectSet[0].Skip = types.NewBool(true)
+	return property.WaitForUpdates(ctx, property.DefaultCollector(client), filter,
+		func(updates []types.ObjectUpdate) bool {
+			c.applyUpdates(ctx, updates)
+			return false
+		})
+}
+
+// applyUpdates applies the given updates of the subscription to the cache,
+// and marks the cache as synced.
+func (c *virtualMachineCache) applyUpdates(ctx context.Context, updates []types.ObjectUpdate) {
+	log := logger.GetLogger(ctx)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, update := range updates {
+		entry, cached := c.vms[update.Obj]
+		if update.Kind == types.ObjectUpdateKindLeave {
+			if cached {
+				c.remove(update.Obj, entry)
+			}
+			continue
+		}
+		if !cached {
+			if update.Kind == types.ObjectUpdateKindModify {
+				// The VM was dropped after a vMotion. Its next lookup adds it
+				// back.
+				continue
+			}
+			entry = &vmCacheEntry{}
+		} else {
+			// The VM is added back below with its new UUIDs.
+			c.remove(update.Obj, entry)
+		}
+		migrated := false
+		for _, change := range update.ChangeSet {
+			switch change.Name {
+			case vmCacheUUIDProperty:
+				entry.uuid, _ = change.Val.(string)
+			case vmCacheInstanceUUIDProperty:
+				entry.instanceUUID, _ = change.Val.(string)
+			case vmCacheHostProperty:
+				host, _ := change.Val.(types.ManagedObjectReference)
+				migrated = cached && host != entry.host
+				entry.host = host
+			}
+		}
+		if migrated {
+			log.Infof("VM %v was migrated to host %v. Dropping it from the VM discovery cache.",
+				update.Obj, entry.host)
+			continue
+		}
+		c.add(update.Obj, entry)
+	}
+	c.synced = true
+}
+
+// add caches the given VM. The caller must hold the lock.
+func (c *virtualMachineCache) add(ref types.ManagedObjectReference, entry *vmCacheEntry) {
+	c.vms[ref] = entry
+	if entry.uuid != "" {
+		c.uuids[strings.ToLower(entry.uuid)] = ref
+	}
+	if entry.instanceUUID != "" {
+		c.instanceUUIDs[strings.ToLower(entry.instanceUUID)] = ref
+	}
+}
+
+// remove drops the given VM from the cache. The caller must hold the lock.
+func (c *virtualMachineCache) remove(ref types.ManagedObjectReference, entry *vmCacheEntry) {
+	delete(c.vms, ref)
+	if existing, ok := c.uuids[strings.ToLower(entry.uuid)]; ok && existing == ref {
+		delete(c.uuids, strings.ToLower(entry.uuid))
+	}
+	if existing, ok := c.instanceUUIDs[strings.ToLower(entry.instanceUUID)]; ok && existing == ref {
+		delete(c.instanceUUIDs, strings.ToLower(entry.instanceUUID))
+	}
+}
+
+// reset drops the cached VMs, and marks the cache as not synced.
+func (c *virtualMachineCache) reset() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.synced = false
+	c.uuids = make(map[string]types.ManagedObjectReference)
+	c.instanceUUIDs = make(map[string]types.ManagedObjectReference)
+	c.vms = make(map[types.ManagedObjectReference]*vmCacheEntry)
+}
+
+// get returns the reference of the VM with the given lower case BIOS UUID,
+// or instance UUID if instanceUUID is set, and whether it is cached.
+func (c *virtualMachineCache) get(uuid string, instanceUUID bool) (types.ManagedObjectReference, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if !c.synced {
+		return types.ManagedObjectReference{}, false
+	}
+	uuids := c.uuids
+	if instanceUUID {
+		uuids = c.instanceUUIDs
+	}
+	ref, ok := uuids[uuid]
+	return ref, ok
+}
+
+// addVirtualMachine caches the given VM, e.g. found by searching the
+// inventory after it was dropped from the cache.
+func (c *virtualMachineCache) addVirtualMachine(ctx context.Context, vm *VirtualMachine) {
+	log := logger.GetLogger(ctx)
+	var vmMo mo.VirtualMachine
+	err := vm.Properties(ctx, vm.Reference(), []string{vmCacheUUIDProperty, vmCacheInstanceUUIDProperty,
+		vmCacheHostProperty}, &vmMo)
+	if err != nil || vmMo.Config == nil || vmMo.Runtime.Host == nil {
+		log.Debugf("failed to get the properties of VM %v to cache it. Err: %v", vm, err)
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if !c.synced {
+		return
+	}
+	c.add(vm.Reference(), &vmCacheEntry{
+		uuid:         vmMo.Config.Uuid,
+		instanceUUID: vmMo.Config.InstanceUuid,
+		host:         *vmMo.Runtime.Host,
+	})
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vsphere
+
+import (
+	"context"
+	"testing"
+
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func newVMObjectUpdate(kind types.ObjectUpdateKind, ref types.ManagedObjectReference,
+	changes map[string]types.AnyType) types.ObjectUpdate {
+	update := types.ObjectUpdate{Kind: kind, Obj: ref}
+	for name, val := range changes {
+		update.ChangeSet = append(update.ChangeSet, types.PropertyChange{Name: name, Op: types.PropertyChangeOpAssign,
+			Val: val})
+	}
+	return update
+}
+
+func TestVirtualMachineCacheApplyUpdates(t *testing.T) {
+	ctx := context.Background()
+	cache := newVirtualMachineCache()
+	vm1 := types.ManagedObjectReference{Type: "VirtualMachine", Value: "vm-1"}
+	vm2 := types.ManagedObjectReference{Type: "VirtualMachine", Value: "vm-2"}
+	host1 := types.ManagedObjectReference{Type: "HostSystem", Value: "host-1"}
+	host2 := types.ManagedObjectReference{Type: "HostSystem", Value: "host-2"}
+
+	// VMs aren't returned until the cache is synced.
+	if _, ok := cache.get("uuid-1", false); ok {
+		t.Fatalf("expected no VM before the cache is synced")
+	}
+	cache.applyUpdates(ctx, []types.ObjectUpdate{
+		newVMObjectUpdate(types.ObjectUpdateKindEnter, vm1, map[string]types.AnyType{
+			vmCacheUUIDProperty: "UUID-1", vmCacheInstanceUUIDProperty: "instance-1", vmCacheHostProperty: host1,
+		}),
+		newVMObjectUpdate(types.ObjectUpdateKindEnter, vm2, map[string]types.AnyType{
+			vmCacheUUIDProperty: "uuid-2", vmCacheInstanceUUIDProperty: "instance-2", vmCacheHostProperty: host1,
+		}),
+	})
+	if ref, ok := cache.get("uuid-1", false); !ok || ref != vm1 {
+		t.Errorf("expected VM %v given uuid-1, got %v, %t", vm1, ref, ok)
+	}
+	if ref, ok := cache.get("instance-2", true); !ok || ref != vm2 {
+		t.Errorf("expected VM %v given instance-2, got %v, %t", vm2, ref, ok)
+	}
+	if _, ok := cache.get("instance-2", false); ok {
+		t.Errorf("expected no VM given instance-2 as BIOS UUID")
+	}
+
+	// A VM migrated with vMotion is dropped, and a VM leaving the datacenter
+	// is removed.
+	cache.applyUpdates(ctx, []types.ObjectUpdate{
+		newVMObjectUpdate(types.ObjectUpdateKindModify, vm1, map[string]types.AnyType{vmCacheHostProperty: host2}),
+		{Kind: types.ObjectUpdateKindLeave, Obj: vm2},
+	})
+	for _, uuid := range []string{"uuid-1", "uuid-2"} {
+		if ref, ok := cache.get(uuid, false); ok {
+			t.Errorf("expected no VM given %s, got %v", uuid, ref)
+		}
+	}
+	if len(cache.vms) != 0 || len(cache.instanceUUIDs) != 0 {
+		t.Errorf("expected no cached VM, got %v, %v", cache.vms, cache.instanceUUIDs)
+	}
+
+	// The UUID of a VM changing, e.g. when it's cloned, updates the cache.
+	cache.applyUpdates(ctx, []types.ObjectUpdate{
+		newVMObjectUpdate(types.ObjectUpdateKindEnter, vm1, map[string]types.AnyType{
+			vmCacheUUIDProperty: "uuid-1", vmCacheHostProperty: host2,
+		}),
+	})
+	cache.applyUpdates(ctx, []types.ObjectUpdate{
+		newVMObjectUpdate(types.ObjectUpdateKindModify, vm1, map[string]types.AnyType{vmCacheUUIDProperty: "uuid-3"}),
+	})
+	if ref, ok := cache.get("uuid-3", false); !ok || ref != vm1 {
+		t.Errorf("expected VM %v given uuid-3, got %v, %t", vm1, ref, ok)
+	}
+	if ref, ok := cache.get("uuid-1", false); ok {
+		t.Errorf("expected no VM given the previous uuid-1, got %v", ref)
+	}
+
+	cache.reset()
+	if _, ok := cache.get("uuid-3", false); ok {
+		t.Errorf("expected no VM after the cache is reset")
+	}
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vsphere
+
+import (
+	"context"
+
+	"github.com/vmware/govmomi/event"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// vmMigrationEventPageSize is the number of latest events replayed when the
+// watch of the VM migration events starts.
+const vmMigrationEventPageSize = 10
+
+// vmMigrationEventTypes are the types of the events posted by vCenter when a
+// VM was migrated to another host or datastore, with vMotion, storage vMotion
+// or a cold relocation.
+var vmMigrationEventTypes = []string{"VmMigratedEvent", "DrsVmMigratedEvent", "VmRelocatedEvent"}
+
+// WatchVMMigrationEvents calls handler with the reference of the VMs of the
+// given vCenter as they get migrated to another host or datastore, until the
+// given context is canceled or the watch fails. The latest migration events
+// are replayed first.
+func WatchVMMigrationEvents(ctx context.Context, vc *VirtualCenter,
+	handler func(vmRef types.ManagedObjectReference)) error {
+	pool := vc.SessionPool()
+	pooled, err := pool.Get(ctx)
+	if err != nil {
+		return err
+	}
+	defer pool.Put(pooled)
+	client := pooled.Client
+	return event.NewManager(client).Events(ctx, []types.ManagedObjectReference{client.ServiceContent.RootFolder},
+		vmMigrationEventPageSize, true, false,
+		func(_ types.ManagedObjectReference, events []types.BaseEvent) error {
+			for _, e := range events {
+				if vm := e.GetEvent().Vm; vm != nil {
+					handler(vm.Vm)
+				}
+			}
+			return nil
+		}, vmMigrationEventTypes...)
+}
@@ -84,12 +84,28 @@ const (
 	// TopologyLabelsDomain is the domain name used to identify user-defined
 	// topology labels applied on the node by vSphere CSI driver.
 	TopologyLabelsDomain = "topology.csi.vmware.com"
+	// TopologyHierarchyDatacenter is the datacenter level of the topology
+	// hierarchy.
+	TopologyHierarchyDatacenter = "datacenter"
+	// TopologyHierarchyCluster is the cluster level of the topology hierarchy.
+	TopologyHierarchyCluster = "cluster"
+	// TopologyHierarchyHost is the host level of the topology hierarchy.
+	TopologyHierarchyHost = "host"
 	// DefaultQueryLimit is the default number of volumes to be fetched from CNS QueryAll API
 	// Current default value is set to 10000
 	DefaultQueryLimit = 10000
 	// DefaultListVolumeThreshold specifies the default maximum number of differences in volumes between CNS
 	// and kubernetes
 	DefaultListVolumeThreshold = 50
+	// DefaultAttachDetachBatchWindowInMs is the default time in milliseconds
+	// for which attach and detach requests for a node VM are batched.
+	DefaultAttachDetachBatchWindowInMs = 200
+	// DefaultTaskPollIntervalInMs is the default initial interval in
+	// milliseconds at which CNS tasks are polled.
+	DefaultTaskPollIntervalInMs = 500
+	// DefaultTaskPollMaxIntervalInSec is the default maximum interval in
+	// seconds at which CNS tasks are polled.
+	DefaultTaskPollMaxIntervalInSec = 5
 )
 
 // Errors
@@ -409,6 +425,25 @@ func validateConfig(ctx context.Context, cfg *Config) error {
 		}
 	}
 
+	// Validate the inventory levels of topologyHierarchy in Labels section.
+	hierarchyLevels := make(map[string]bool)
+	for _, level := range GetTopologyHierarchyLevels(cfg) {
+		if level != TopologyHierarchyDatacenter && level != TopologyHierarchyCluster && level != TopologyHierarchyHost {
+			return logger.LogNewErrorf(log, "unrecognised topology hierarchy level %q, expected one of "+
+				"%q, %q and %q", level, TopologyHierarchyDatacenter, TopologyHierarchyCluster, TopologyHierarchyHost)
+		}
+		if hierarchyLevels[level] {
+			return logger.LogNewErrorf(log, "duplicate topology hierarchy level %q", level)
+		}
+		hierarchyLevels[level] = true
+	}
+	for _, category := range strings.Split(cfg.Labels.TopologyCategories, ",") {
+		if hierarchyLevels[strings.TrimSpace(category)] {
+			return logger.LogNewErrorf(log, "topology category %q conflicts with the topology hierarchy level "+
+				"of the same name", strings.TrimSpace(category))
+		}
+	}
+
 	// Validate topology labels specified in TopologyCategory section.
 	betaDomain := strings.Split(corev1.LabelFailureDomainBetaZone, "/")[0]
 	gaDomain := strings.Split(corev1.LabelTopologyZone, "/")[0]
@@ -420,6 +455,13 @@ func validateConfig(ctx context.Context, cfg *Config) error {
 		}
 	}
 
+	for datastoreURL, limit := range cfg.DatastoreProvisioningLimit {
+		if limit.MaxConcurrentOperations <= 0 {
+			return logger.LogNewErrorf(log, "invalid max-concurrent-operations %d for datastore %q, "+
+				"expected a positive number", limit.MaxConcurrentOperations, datastoreURL)
+		}
+	}
+
 	if cfg.Global.QueryLimit == 0 {
 		cfg.Global.QueryLimit = DefaultQueryLimit
 		log.Debugf("Setting default queryLimit to %v", cfg.Global.QueryLimit)
@@ -429,6 +471,35 @@ func validateConfig(ctx context.Context, cfg *Config) error {
 		cfg.Global.ListVolumeThreshold = DefaultListVolumeThreshold
 		log.Debugf("Setting default list volume threshold to %v", cfg.Global.ListVolumeThreshold)
 	}
+
+	if cfg.Global.AttachDetachBatchWindowInMs <= 0 {
+		cfg.Global.AttachDetachBatchWindowInMs = DefaultAttachDetachBatchWindowInMs
+		log.Debugf("Setting default attach/detach batch window to %vms", cfg.Global.AttachDetachBatchWindowInMs)
+	}
+
+	if cfg.Global.TaskPollIntervalInMs <= 0 {
+		cfg.Global.TaskPollIntervalInMs = DefaultTaskPollIntervalInMs
+		log.Debugf("Setting default task poll interval to %vms", cfg.Global.TaskPollIntervalInMs)
+	}
+	if cfg.Global.TaskPollMaxIntervalInSec <= 0 {
+		cfg.Global.TaskPollMaxIntervalInSec = DefaultTaskPollMaxIntervalInSec
+		log.Debugf("Setting default task poll max interval to %vs", cfg.Global.TaskPollMaxIntervalInSec)
+	}
+	if cfg.Global.TaskTimeoutInSec < 0 {
+		log.Warnf("Invalid value %d for task-timeout-insec, CNS task waits are only bounded by the request "+
+			"timeout", cfg.Global.TaskTimeoutInSec)
+		cfg.Global.TaskTimeoutInSec = 0
+	}
+	if cfg.Global.VolumeQueryCacheTTLInSec < 0 {
+		log.Warnf("Invalid value %d for volume-query-cache-ttl-insec, the CNS query results are not cached",
+			cfg.Global.VolumeQueryCacheTTLInSec)
+		cfg.Global.VolumeQueryCacheTTLInSec = 0
+	}
+	if cfg.Global.SessionPoolSize < 0 {
+		log.Warnf("Invalid value %d for session-pool-size, using the default pool size",
+			cfg.Global.SessionPoolSize)
+		cfg.Global.SessionPoolSize = 0
+	}
 	return nil
 }
 
@@ -506,6 +577,12 @@ func FromEnvToGC(ctx context.Context, cfg *Config) error {
 	if v := os.Getenv("WCP_TanzuKubernetesClusterUID"); v != "" {
 		cfg.GC.TanzuKubernetesClusterUID = v
 	}
+	if v := os.Getenv("WCP_ClusterAPIVersion"); v != "" {
+		cfg.GC.ClusterAPIVersion = v
+	}
+	if v := os.Getenv("WCP_ClusterKind"); v != "" {
+		cfg.GC.ClusterKind = v
+	}
 
 	err := validateGCConfig(ctx, cfg)
 	if err != nil {
@@ -592,15 +669,25 @@ func GetSupervisorNamespace(ctx context.Context) (string, error) {
 	return string(namespace), nil
 }
 
-// GetClusterFlavor returns the cluster flavor based on the env variable set in
-// the driver deployment file.
+// GetClusterFlavor returns the cluster flavor. The CLUSTER_FLAVOR env
+// variable set in the driver deployment file takes precedence. If it is not
+// set, the cluster flavor is detected from the environment the driver runs in.
 func GetClusterFlavor(ctx context.Context) (cnstypes.CnsClusterFlavor, error) {
 	log := logger.GetLogger(ctx)
-	// CLUSTER_FLAVOR is defined only in Supervisor and Guest cluster deployments.
-	// If it is empty, it is implied that cluster flavor is Vanilla K8S.
 	clusterFlavor := cnstypes.CnsClusterFlavor(os.Getenv("CLUSTER_FLAVOR"))
 	if strings.TrimSpace(string(clusterFlavor)) == "" {
-		return cnstypes.CnsClusterFlavorVanilla, nil
+		detectClusterFlavorLock.Lock()
+		defer detectClusterFlavorLock.Unlock()
+		if detectedClusterFlavor == "" {
+			flavor, err := detectClusterFlavor(ctx)
+			if err != nil {
+				return "", logger.LogNewErrorf(log, "CLUSTER_FLAVOR is not set and the cluster flavor "+
+					"can't be detected. Error: %v", err)
+			}
+			detectedClusterFlavor = flavor
+			log.Infof("CLUSTER_FLAVOR is not set. Detected cluster flavor %q", detectedClusterFlavor)
+		}
+		return detectedClusterFlavor, nil
 	} else if clusterFlavor == cnstypes.CnsClusterFlavorGuest ||
 		clusterFlavor == cnstypes.CnsClusterFlavorWorkload ||
 		clusterFlavor == cnstypes.CnsClusterFlavorVanilla {
@@ -610,3 +697,33 @@ func GetClusterFlavor(ctx context.Context) (cnstypes.CnsClusterFlavor, error) {
 	log.Error(errMsg)
 	return "", fmt.Errorf(errMsg)
 }
+
+// GetTopologyHierarchyLevels returns the inventory levels listed in the
+// topology-hierarchy parameter of the Labels section of the given config.
+func GetTopologyHierarchyLevels(cfg *Config) []string {
+	var levels []string
+	for _, level := range strings.Split(cfg.Labels.TopologyHierarchy, ",") {
+		if level = strings.TrimSpace(level); level != "" {
+			levels = append(levels, level)
+		}
+	}
+	return levels
+}
+
+// GetTopologyHierarchyKey returns the topology key of the given inventory
+// level of the topology hierarchy.
+func GetTopologyHierarchyKey(level string) string {
+	return TopologyLabelsDomain + "/" + level
+}
+
+// GetTopologyHierarchySegments returns the values of the topology hierarchy
+// keys among the given topology segments, by inventory level.
+func GetTopologyHierarchySegments(segments map[string]string) map[string]string {
+	hierarchy := make(map[string]string)
+	for _, level := range []string{TopologyHierarchyDatacenter, TopologyHierarchyCluster, TopologyHierarchyHost} {
+		if value, ok := segments[GetTopologyHierarchyKey(level)]; ok {
+			hierarchy[level] = value
+		}
+	}
+	return hierarchy
+}
@@ -18,9 +18,12 @@ package config
 
 import (
 	"context"
+	"errors"
 	"os"
 	"reflect"
 	"testing"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
 )
 
 var (
@@ -201,3 +204,126 @@ func isConfigEqual(actual *Config, expected *Config) bool {
 	}
 	return true
 }
+
+func TestValidateConfigWithInvalidDatastoreProvisioningLimit(t *testing.T) {
+	cfg := &Config{
+		VirtualCenter: idealVCConfig,
+		DatastoreProvisioningLimit: map[string]*DatastoreProvisioningLimitConfig{
+			"ds:///vmfs/volumes/small/": {MaxConcurrentOperations: 0},
+		},
+	}
+
+	err := validateConfig(ctx, cfg)
+	if err == nil {
+		t.Errorf("Expected error due to invalid datastore provisioning limit. Config given - %+v", *cfg)
+	}
+}
+
+func TestDetectClusterFlavor(t *testing.T) {
+	origFileExists, origIsSupervisorAPIServed := fileExists, isSupervisorAPIServed
+	defer func() {
+		fileExists, isSupervisorAPIServed = origFileExists, origIsSupervisorAPIServed
+		os.Unsetenv(envCSIMode)
+	}()
+	tests := []struct {
+		name                string
+		existingFiles       map[string]bool
+		csiMode             string
+		supervisorAPIServed bool
+		discoveryErr        error
+		expectedFlavor      cnstypes.CnsClusterFlavor
+	}{
+		{
+			name:           "GuestClusterConfigPresent",
+			existingFiles:  map[string]bool{DefaultGCConfigPath: true},
+			expectedFlavor: cnstypes.CnsClusterFlavorGuest,
+		},
+		{
+			name:           "SupervisorCAPresent",
+			existingFiles:  map[string]bool{SupervisorCAFilePath: true},
+			expectedFlavor: cnstypes.CnsClusterFlavorWorkload,
+		},
+		{
+			name:                "SupervisorAPIServed",
+			supervisorAPIServed: true,
+			expectedFlavor:      cnstypes.CnsClusterFlavorWorkload,
+		},
+		{
+			name:           "Vanilla",
+			expectedFlavor: cnstypes.CnsClusterFlavorVanilla,
+		},
+		{
+			name:                "NodePlugin",
+			csiMode:             csiModeNode,
+			existingFiles:       map[string]bool{DefaultGCConfigPath: true},
+			supervisorAPIServed: true,
+			expectedFlavor:      cnstypes.CnsClusterFlavorVanilla,
+		},
+		{
+			name:         "DiscoveryFailed",
+			discoveryErr: errors.New("connection refused"),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			os.Setenv(envCSIMode, test.csiMode)
+			fileExists = func(path string) bool { return test.existingFiles[path] }
+			isSupervisorAPIServed = func(ctx context.Context) (bool, error) {
+				return test.supervisorAPIServed, test.discoveryErr
+			}
+			flavor, err := detectClusterFlavor(ctx)
+			if (err != nil) != (test.discoveryErr != nil) {
+				t.Fatalf("expected error %v, got %v", test.discoveryErr, err)
+			}
+			if flavor != test.expectedFlavor {
+				t.Errorf("expected cluster flavor %q, got %q", test.expectedFlavor, flavor)
+			}
+		})
+	}
+}
+
+func TestValidateConfigWithTopologyHierarchy(t *testing.T) {
+	tests := []struct {
+		name               string
+		topologyHierarchy  string
+		topologyCategories string
+		expectErr          bool
+	}{
+		{name: "ValidLevels", topologyHierarchy: "datacenter, cluster,host"},
+		{name: "ValidLevelsWithCategories", topologyHierarchy: "cluster", topologyCategories: "k8s-zone"},
+		{name: "UnknownLevel", topologyHierarchy: "cluster,rack", expectErr: true},
+		{name: "DuplicateLevel", topologyHierarchy: "cluster,cluster", expectErr: true},
+		{name: "ConflictingCategory", topologyHierarchy: "host", topologyCategories: "k8s-zone,host", expectErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := &Config{VirtualCenter: idealVCConfig}
+			cfg.Labels.TopologyHierarchy = test.topologyHierarchy
+			cfg.Labels.TopologyCategories = test.topologyCategories
+			err := validateConfig(ctx, cfg)
+			if (err != nil) != test.expectErr {
+				t.Errorf("expected error: %t, got %v. Config given - %+v", test.expectErr, err, *cfg)
+			}
+		})
+	}
+}
+
+func TestGetTopologyHierarchySegments(t *testing.T) {
+	cfg := &Config{}
+	cfg.Labels.TopologyHierarchy = " datacenter,cluster, "
+	expectedLevels := []string{TopologyHierarchyDatacenter, TopologyHierarchyCluster}
+	if levels := GetTopologyHierarchyLevels(cfg); !reflect.DeepEqual(levels, expectedLevels) {
+		t.Errorf("expected levels %v, got %v", expectedLevels, levels)
+	}
+
+	segments := map[string]string{
+		"topology.kubernetes.io/zone":        "zone-a",
+		"topology.csi.vmware.com/k8s-region": "region-1",
+		"topology.csi.vmware.com/cluster":    "domain-c8",
+		"topology.csi.vmware.com/host":       "host-21",
+	}
+	expected := map[string]string{TopologyHierarchyCluster: "domain-c8", TopologyHierarchyHost: "host-21"}
+	if hierarchy := GetTopologyHierarchySegments(segments); !reflect.DeepEqual(hierarchy, expected) {
+		t.Errorf("expected hierarchy %v, got %v", expected, hierarchy)
+	}
+}
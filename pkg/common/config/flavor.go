@@ -0,0 +1,115 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+)
+
+// supervisorAPIGroups are API groups only served by Supervisor Clusters.
+var supervisorAPIGroups = []string{
+	"netoperator.vmware.com",
+	"vmoperator.vmware.com",
+	"topology.tanzu.vmware.com",
+}
+
+const (
+	// envCSIMode is the X_CSI_MODE env variable the driver runs in, i.e.
+	// controller or node.
+	envCSIMode = "X_CSI_MODE"
+	// csiModeNode is the X_CSI_MODE of the node plugin.
+	csiModeNode = "node"
+)
+
+var (
+	// detectedClusterFlavor is the cluster flavor detected when CLUSTER_FLAVOR
+	// is not set. It is detected once as it cannot change while running, and
+	// detected again on the next call if the detection failed.
+	detectedClusterFlavor   cnstypes.CnsClusterFlavor
+	detectClusterFlavorLock sync.Mutex
+
+	// fileExists returns whether the file at the given path exists. It is a
+	// variable so that it can be replaced in tests.
+	fileExists = func(path string) bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}
+	// isSupervisorAPIServed returns whether the API server serves any of the
+	// Supervisor Cluster API groups. It is a variable so that it can be
+	// replaced in tests.
+	isSupervisorAPIServed = func(ctx context.Context) (bool, error) {
+		restConfig, err := rest.InClusterConfig()
+		if err != nil {
+			return false, err
+		}
+		discoveryClient, err := discovery.NewDiscoveryClientForConfig(restConfig)
+		if err != nil {
+			return false, err
+		}
+		groups, err := discoveryClient.ServerGroups()
+		if err != nil {
+			return false, err
+		}
+		for _, group := range groups.Groups {
+			for _, supervisorAPIGroup := range supervisorAPIGroups {
+				if group.Name == supervisorAPIGroup {
+					return true, nil
+				}
+			}
+		}
+		return false, nil
+	}
+)
+
+// detectClusterFlavor detects the cluster flavor from the environment the
+// driver runs in. Guest Clusters are detected by the presence of the
+// Supervisor Cluster connection config, and Supervisor Clusters by the
+// presence of the Supervisor CA certificate or of the Supervisor Cluster APIs.
+// Vanilla is returned otherwise. An error is returned if the API groups
+// served by the API server can't be discovered, as the cluster flavor can't
+// be told then.
+//
+// Only the controller and the syncer detect the cluster flavor, as node
+// plugin pods neither mount the Supervisor Cluster config nor may discover
+// the API server. Node plugins are Vanilla unless CLUSTER_FLAVOR is set,
+// which the Supervisor and Guest Cluster deployments do.
+func detectClusterFlavor(ctx context.Context) (cnstypes.CnsClusterFlavor, error) {
+	if os.Getenv(envCSIMode) == csiModeNode {
+		return cnstypes.CnsClusterFlavorVanilla, nil
+	}
+	if os.Getenv(EnvGCConfig) != "" || fileExists(DefaultGCConfigPath) {
+		return cnstypes.CnsClusterFlavorGuest, nil
+	}
+	if fileExists(SupervisorCAFilePath) {
+		return cnstypes.CnsClusterFlavorWorkload, nil
+	}
+	served, err := isSupervisorAPIServed(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to discover the API groups served by the API server. Err: %v", err)
+	}
+	if served {
+		return cnstypes.CnsClusterFlavorWorkload, nil
+	}
+	return cnstypes.CnsClusterFlavorVanilla, nil
+}
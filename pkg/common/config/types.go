@@ -69,6 +69,51 @@ type Config struct {
 		// ListVolumeThreshold specifies the maximum number of differences in volume that can exist between CNS
 		// and kubernetes
 		ListVolumeThreshold int `gcfg:"list-volume-threshold"`
+		// DatastorePlacementStrategy specifies how the datastore is selected among
+		// the candidate shared datastores while provisioning block volumes.
+		// Supported values are "" (let CNS choose) and "balanced".
+		DatastorePlacementStrategy string `gcfg:"datastore-placement-strategy"`
+		// AttachDetachBatchWindowInMs specifies the time in milliseconds for
+		// which attach and detach requests for a node VM are collected before
+		// being issued as a single batch. Only used when attach/detach batching
+		// is enabled.
+		AttachDetachBatchWindowInMs int `gcfg:"attach-detach-batch-window-inms"`
+		// VolumeTagCategories is a comma separated list of vSphere tag
+		// categories. Tags of these categories attached to the FCD backing a
+		// PV are reflected as labels on the PV by full sync.
+		VolumeTagCategories string `gcfg:"volume-tag-categories"`
+		// DetachQuiesceDelayInSec specifies the minimum time in seconds for
+		// which detach of a volume from a powered on node VM is delayed, for
+		// volumes whose StorageClass does not set detachquiescedelay. Only
+		// used when detach quiesce is enabled.
+		DetachQuiesceDelayInSec int `gcfg:"detach-quiesce-delay-insec"`
+		// ForbidDiskDeletionBySyncer prevents the syncer from deleting the
+		// backing disks of the volumes it removes from CNS.
+		ForbidDiskDeletionBySyncer bool `gcfg:"forbid-disk-deletion-by-syncer"`
+		// TaskPollIntervalInMs specifies the initial interval in milliseconds
+		// at which CNS tasks are polled for completion. The interval doubles
+		// after every poll, up to TaskPollMaxIntervalInSec.
+		TaskPollIntervalInMs int `gcfg:"task-poll-interval-inms"`
+		// TaskPollMaxIntervalInSec specifies the maximum interval in seconds
+		// at which CNS tasks are polled for completion.
+		TaskPollMaxIntervalInSec int `gcfg:"task-poll-max-interval-insec"`
+		// TaskTimeoutInSec specifies the time in seconds after which the
+		// driver stops waiting for a CNS task to complete. If unset, waiting
+		// is only bounded by the timeout of the request.
+		TaskTimeoutInSec int `gcfg:"task-timeout-insec"`
+		// VolumeQueryCacheTTLInSec specifies the time in seconds for which the
+		// results of the CNS queries of volumes by ID are cached. If unset, the
+		// results are not cached.
+		VolumeQueryCacheTTLInSec int `gcfg:"volume-query-cache-ttl-insec"`
+		// EnableVMDiscoveryCache specifies whether the VMs are looked up by
+		// UUID from a cache kept up to date through property collector
+		// subscriptions, instead of searching the inventory of vCenter.
+		EnableVMDiscoveryCache bool `gcfg:"enable-vm-discovery-cache"`
+		// SessionPoolSize specifies the maximum number of vCenter sessions
+		// used by the long running watches of vCenter, like the VM discovery
+		// cache subscriptions and the event watches. If unset, at most 8
+		// sessions are used.
+		SessionPoolSize int `gcfg:"session-pool-size"`
 	}
 
 	// Multiple sets of Net Permissions applied to all file shares
@@ -96,9 +141,18 @@ type Config struct {
 		// create in the inventory using the UI.
 		// Maximum number of categories allowed is 5.
 		TopologyCategories string `gcfg:"topology-categories"`
+		// TopologyHierarchy is a comma separated string of the inventory
+		// levels of the node VMs, among "datacenter", "cluster" and "host",
+		// used as topology domains under the topology.csi.vmware.com/<level>
+		// keys, with the managed object IDs of the node VM ancestors as values.
+		TopologyHierarchy string `gcfg:"topology-hierarchy"`
 	}
 
 	TopologyCategory map[string]*TopologyCategoryInfo
+
+	// DatastoreProvisioningLimit caps the number of concurrent CNS create
+	// and clone operations per datastore, keyed by datastore URL.
+	DatastoreProvisioningLimit map[string]*DatastoreProvisioningLimitConfig
 }
 
 // ConfigurationInfo is a struct that used to capture config param details
@@ -117,6 +171,14 @@ type TopologyCategoryInfo struct {
 	Label string `gcfg:"label"`
 }
 
+// DatastoreProvisioningLimitConfig consists of the provisioning limits of a
+// datastore.
+type DatastoreProvisioningLimitConfig struct {
+	// MaxConcurrentOperations is the maximum number of CNS create and clone
+	// operations in flight on the datastore.
+	MaxConcurrentOperations int `gcfg:"max-concurrent-operations"`
+}
+
 // NetPermissionConfig consists of information used to restrict the
 // network permissions set on file share volumes
 type NetPermissionConfig struct {
@@ -160,6 +222,14 @@ type GCConfig struct {
 	TanzuKubernetesClusterName string `gcfg:"tanzukubernetescluster-name"`
 	// Cluster Distribution Name
 	ClusterDistribution string `gcfg:"cluster-distribution"`
+	// APIVersion of the supervisor object representing the Guest Cluster.
+	// Defaults to the TanzuKubernetesCluster APIVersion when not set.
+	// ClusterClass based clusters are represented by the Cluster API
+	// "cluster.x-k8s.io" group instead.
+	ClusterAPIVersion string `gcfg:"cluster-api-version"`
+	// Kind of the supervisor object representing the Guest Cluster.
+	// Defaults to TanzuKubernetesCluster when not set.
+	ClusterKind string `gcfg:"cluster-kind"`
 }
 
 // SnapshotConfig contains snapshot configuration.
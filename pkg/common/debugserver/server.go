@@ -0,0 +1,102 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package debugserver serves the debug endpoints of the controller and the
+// syncer, to troubleshoot them in production without a custom build.
+package debugserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"time"
+
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+const (
+	// VCSessionsPath is the path serving the status of the vCenter sessions.
+	VCSessionsPath = "/debug/vcsessions"
+
+	// vcSessionTimeout is the timeout of getting the status of a vCenter
+	// session.
+	vcSessionTimeout = 30 * time.Second
+)
+
+// Serve starts an http server exposing pprof, the status of the vCenter
+// sessions and the given handlers, by path, on the address set in
+// X_CSI_DEBUG_ADDRESS, if any.
+func Serve(ctx context.Context, handlers map[string]http.Handler) {
+	log := logger.GetLogger(ctx)
+	address := os.Getenv(csitypes.EnvVarDebugAddress)
+	if address == "" {
+		return
+	}
+	mux := newServeMux(handlers)
+	go func() {
+		for {
+			log.Infof("Starting the http server to expose debug endpoints on %s..", address)
+			err := http.ListenAndServe(address, mux)
+			if err != nil {
+				log.Warnf("Http server that exposes debug endpoints exited with err: %+v", err)
+			}
+			log.Info("Restarting http server to expose debug endpoints..")
+			time.Sleep(time.Second)
+		}
+	}()
+}
+
+// newServeMux returns the mux serving the debug endpoints.
+func newServeMux(handlers map[string]http.Handler) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc(VCSessionsPath, vcSessionsHandler)
+	for path, handler := range handlers {
+		mux.Handle(path, handler)
+	}
+	return mux
+}
+
+// vcSessionsHandler serves the status of the sessions of the registered
+// vCenters.
+func vcSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(logger.NewContextWithLogger(r.Context()), vcSessionTimeout)
+	defer cancel()
+	statuses := make([]cnsvsphere.SessionStatus, 0)
+	for _, vc := range cnsvsphere.GetVirtualCenterManager(ctx).GetAllVirtualCenters() {
+		statuses = append(statuses, vc.GetSessionStatus(ctx))
+	}
+	WriteJSON(ctx, w, statuses)
+}
+
+// WriteJSON writes the given value as JSON in the response.
+func WriteJSON(ctx context.Context, w http.ResponseWriter, v interface{}) {
+	log := logger.GetLogger(ctx)
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		log.Errorf("failed to write debug response. Err: %v", err)
+	}
+}
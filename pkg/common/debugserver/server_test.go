@@ -0,0 +1,60 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debugserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+)
+
+func TestServeMux(t *testing.T) {
+	mux := newServeMux(map[string]http.Handler{
+		"/debug/test": http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			WriteJSON(r.Context(), w, map[string]string{"key": "value"})
+		}),
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	for _, path := range []string{"/debug/pprof/", "/debug/pprof/cmdline", VCSessionsPath, "/debug/test"} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("failed to get %s. Err: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("expected status %d for %s, got %d", http.StatusOK, path, resp.StatusCode)
+		}
+	}
+
+	resp, err := http.Get(server.URL + VCSessionsPath)
+	if err != nil {
+		t.Fatalf("failed to get %s. Err: %v", VCSessionsPath, err)
+	}
+	defer resp.Body.Close()
+	var statuses []cnsvsphere.SessionStatus
+	if err := json.NewDecoder(resp.Body).Decode(&statuses); err != nil {
+		t.Fatalf("failed to decode vCenter session statuses. Err: %v", err)
+	}
+	if len(statuses) != 0 {
+		t.Errorf("expected no vCenter session status without registered vCenter, got %v", statuses)
+	}
+}
@@ -30,4 +30,10 @@ const (
 	CSIInvalidArgumentFault = "csi.fault.InvalidArgument"
 	// CSIUnimplementedFault is the fault type returned when the function is unimplemented.
 	CSIUnimplementedFault = "csi.fault.Unimplemented"
+	// CSICnsUnavailableFault is the fault type returned when CNS calls are
+	// rejected as CNS is unavailable.
+	CSICnsUnavailableFault = "csi.fault.CnsUnavailable"
+	// CSIResourceExhaustedFault is the fault type returned when an operation
+	// is rejected as a configured limit is reached.
+	CSIResourceExhaustedFault = "csi.fault.ResourceExhausted"
 )
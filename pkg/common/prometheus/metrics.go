@@ -85,11 +85,55 @@ const (
 	PrometheusAccessibleVolumes = "accessible-volumes"
 	// PrometheusInaccessibleVolumes represents inaccessible volumes.
 	PrometheusInaccessibleVolumes = "inaccessible-volumes"
+	// PrometheusCompliantVolumes represents volumes compliant with their storage policy.
+	PrometheusCompliantVolumes = "compliant-volumes"
+	// PrometheusNonCompliantVolumes represents volumes not compliant with their storage policy.
+	PrometheusNonCompliantVolumes = "non-compliant-volumes"
+	// PrometheusUnknownComplianceVolumes represents volumes whose storage policy
+	// compliance is unknown, out of date or not applicable.
+	PrometheusUnknownComplianceVolumes = "unknown-compliance-volumes"
+
+	// Consistency audit mismatch types
+
+	// PrometheusPVMissingInCNS represents CSI PVs whose volume isn't known to CNS.
+	PrometheusPVMissingInCNS = "pv-missing-in-cns"
+	// PrometheusCNSVolumeMissingPV represents CNS volumes of the cluster without a PV.
+	PrometheusCNSVolumeMissingPV = "cns-volume-missing-pv"
+	// PrometheusVolumeAttachmentMissingPV represents VolumeAttachments whose PV doesn't exist.
+	PrometheusVolumeAttachmentMissingPV = "volumeattachment-missing-pv"
+	// PrometheusVolumeAttachmentMissingInCNS represents attached VolumeAttachments
+	// whose volume isn't known to CNS.
+	PrometheusVolumeAttachmentMissingInCNS = "volumeattachment-missing-in-cns"
+
+	// VC session operation types
+
+	// PrometheusVCSessionLoginOpType represents a login creating a new VC session.
+	PrometheusVCSessionLoginOpType = "login"
+	// PrometheusVCSessionReloginOpType represents a login replacing an expired VC session.
+	PrometheusVCSessionReloginOpType = "relogin"
+	// PrometheusVCSessionKeepAliveOpType represents a keepalive of an idle VC session.
+	PrometheusVCSessionKeepAliveOpType = "keepalive"
+	// PrometheusVCSessionLogoutOpType represents a logout of a VC session.
+	PrometheusVCSessionLogoutOpType = "logout"
+
+	// Features whose privileges are checked by the permissions monitor
+
+	// PrometheusCnsFeature represents the CNS queries, checked on the root folder.
+	PrometheusCnsFeature = "cns"
+	// PrometheusBlockVolumeFeature represents the block volume provisioning,
+	// checked on the shared datastores.
+	PrometheusBlockVolumeFeature = "block-volume"
+	// PrometheusAttachFeature represents the volume attachments, checked on the
+	// node VMs.
+	PrometheusAttachFeature = "attach"
 
 	// PrometheusPassStatus represents a successful API run.
 	PrometheusPassStatus = "pass"
 	// PrometheusFailStatus represents an unsuccessful API run.
 	PrometheusFailStatus = "fail"
+	// PrometheusRetryStatus represents a failed attempt of an operation which
+	// is retried.
+	PrometheusRetryStatus = "retry"
 )
 
 var (
@@ -135,6 +179,42 @@ var (
 		// Possible status - "pass", "fail"
 		[]string{"optype", "status"})
 
+	// GRPCRequestsHistVec is a histogram vector metric to observe the latency
+	// of the requests served on the CSI endpoint, by gRPC method and status
+	// code.
+	GRPCRequestsHistVec = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "vsphere_csi_grpc_request_duration_seconds",
+		Help:    "Histogram vector for the latency of the requests served on the CSI endpoint.",
+		Buckets: []float64{0.1, 0.5, 1, 2, 3, 5, 7, 10, 15, 20, 30, 60, 120, 180, 300},
+	},
+		// Possible method - "CreateVolume", "NodeStageVolume", "Probe", etc
+		// Possible code - "OK", "Internal", "DeadlineExceeded", etc
+		[]string{"method", "code"})
+
+	// GRPCRequestsCounterVec is a counter vector metric to observe the
+	// requests served on the CSI endpoint, by gRPC method and status code.
+	GRPCRequestsCounterVec = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "vsphere_csi_grpc_requests_total",
+		Help: "Counter vector for the requests served on the CSI endpoint.",
+	}, []string{"method", "code"})
+
+	// GRPCRequestsInFlightGaugeVec is a gauge metric to observe the requests
+	// being served on the CSI endpoint, by gRPC method.
+	GRPCRequestsInFlightGaugeVec = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "vsphere_csi_grpc_requests_in_flight",
+		Help: "Number of requests being served on the CSI endpoint.",
+	}, []string{"method"})
+
+	// GRPCCnsTaskWaitHistVec is a histogram vector metric to observe the part
+	// of the latency of the requests served on the CSI endpoint spent waiting
+	// on CNS tasks, by gRPC method. Only requests waiting on CNS tasks are
+	// observed.
+	GRPCCnsTaskWaitHistVec = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "vsphere_csi_grpc_request_cns_task_wait_seconds",
+		Help:    "Histogram vector for the time requests served on the CSI endpoint spent waiting on CNS tasks.",
+		Buckets: []float64{0.1, 0.5, 1, 2, 3, 5, 7, 10, 15, 20, 30, 60, 120, 180, 300},
+	}, []string{"method"})
+
 	// VolumeHealthGaugeVec is a gauge metric to observe the number of accessible and inaccessible volumes.
 	VolumeHealthGaugeVec = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Name: "vsphere_volume_health_gauge",
@@ -143,6 +223,52 @@ var (
 		// Possible volume_health_type - "accessible-volumes", "inaccessible-volumes"
 		[]string{"volume_health_type"})
 
+	// VolumeComplianceGaugeVec is a gauge metric to observe the number of volumes
+	// compliant and not compliant with their storage policy.
+	VolumeComplianceGaugeVec = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "vsphere_volume_compliance_gauge",
+		Help: "Gauge for total number of volumes compliant and not compliant with their storage policy",
+	},
+		// Possible volume_compliance_type - "compliant-volumes", "non-compliant-volumes",
+		// "unknown-compliance-volumes"
+		[]string{"volume_compliance_type"})
+
+	// VolumeCountGaugeVec is a gauge metric to observe the number of volumes
+	// managed by CNS for the cluster, by volume type, storage policy,
+	// datastore type and cluster flavor.
+	VolumeCountGaugeVec = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "vsphere_volume_count",
+		Help: "Number of volumes by volume type, storage policy, datastore type and cluster flavor",
+	},
+		// Possible voltype - "block", "file", "unknown"
+		// Possible cluster_flavor - "VANILLA", "WORKLOAD"
+		[]string{"voltype", "storage_policy", "datastore_type", "cluster_flavor"})
+
+	// DatastoreCapacityGaugeVec is a gauge metric to observe the capacity of
+	// the datastores holding the volumes of the cluster.
+	DatastoreCapacityGaugeVec = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "vsphere_datastore_capacity_bytes",
+		Help: "Capacity in bytes of the datastores holding the volumes of the cluster",
+	}, []string{"datastore_url", "datastore_name"})
+
+	// DatastoreFreeSpaceGaugeVec is a gauge metric to observe the free space
+	// of the datastores holding the volumes of the cluster.
+	DatastoreFreeSpaceGaugeVec = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "vsphere_datastore_free_space_bytes",
+		Help: "Free space in bytes of the datastores holding the volumes of the cluster",
+	}, []string{"datastore_url", "datastore_name"})
+
+	// ConsistencyAuditMismatchGaugeVec is a gauge metric to observe the number
+	// of mismatches between PVs, VolumeAttachments and CNS volumes found by the
+	// consistency audit run on syncer startup.
+	ConsistencyAuditMismatchGaugeVec = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "vsphere_consistency_audit_mismatches",
+		Help: "Number of mismatches between PVs, VolumeAttachments and CNS volumes found on syncer startup",
+	},
+		// Possible type - "pv-missing-in-cns", "cns-volume-missing-pv",
+		// "volumeattachment-missing-pv", "volumeattachment-missing-in-cns"
+		[]string{"type"})
+
 	// FullSyncOpsHistVec is a histogram vector metric to observe CSI Full Sync.
 	FullSyncOpsHistVec = promauto.NewHistogramVec(prometheus.HistogramOpts{
 		Name: "vsphere_full_sync_ops_histogram",
@@ -154,4 +280,82 @@ var (
 	},
 		// Possible status - "pass", "fail"
 		[]string{"status"})
+
+	// FullSyncInitialCompletedGauge is a gauge metric set to 1 once the first
+	// full sync since the syncer started has succeeded, so that a syncer which
+	// never synced can be told apart from one whose last sync is stale.
+	FullSyncInitialCompletedGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "vsphere_full_sync_initial_completed",
+		Help: "Whether the first full sync since the syncer started has succeeded.",
+	})
+
+	// FullSyncLastSuccessTimestampGauge is a gauge metric to observe the
+	// completion time of the last successful full sync.
+	FullSyncLastSuccessTimestampGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "vsphere_full_sync_last_success_timestamp_seconds",
+		Help: "Unix time at which the last successful full sync completed.",
+	})
+
+	// VCSessionOpsCounterVec is a counter vector metric to observe the churn of
+	// vCenter sessions.
+	VCSessionOpsCounterVec = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "vsphere_vc_session_ops_total",
+		Help: "Counter vector for vCenter session operations.",
+	},
+		// Possible optype - "login", "relogin", "keepalive", "logout"
+		// Possible status - "pass", "fail"
+		[]string{"optype", "status"})
+
+	// RetryOpsCounterVec is a counter vector metric to observe the attempts of
+	// the operations retried with a backoff.
+	RetryOpsCounterVec = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "vsphere_retry_ops_total",
+		Help: "Counter vector for the attempts of retried operations.",
+	},
+		// Possible status - "pass", "fail", "retry"
+		[]string{"operation", "status"})
+
+	// MissingPrivilegesGaugeVec is a gauge metric to observe the number of
+	// entities on which a privilege required by a feature is not granted to
+	// the VC user.
+	MissingPrivilegesGaugeVec = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "vsphere_missing_privileges",
+		Help: "Number of entities on which a privilege required by a feature is not granted to the VC user",
+	},
+		// Possible feature - "cns", "block-volume", "attach"
+		[]string{"feature", "privilege"})
+
+	// RevokedPrivilegesCounterVec is a counter vector metric to observe the
+	// privileges of the VC user revoked after they were granted.
+	RevokedPrivilegesCounterVec = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "vsphere_revoked_privileges_total",
+		Help: "Counter vector for the privileges of the VC user revoked after they were granted.",
+	},
+		// Possible feature - "cns", "block-volume", "attach"
+		[]string{"feature", "privilege"})
+
+	// StorageClassesMissingPolicyGauge is a gauge metric to observe the
+	// number of StorageClasses whose storage policy doesn't exist in vCenter.
+	StorageClassesMissingPolicyGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "vsphere_storage_classes_missing_storage_policy",
+		Help: "Number of StorageClasses whose storage policy doesn't exist in vCenter",
+	})
+
+	// UnsupportedVersionSkewGauge is a gauge metric to observe the number of
+	// node plugins and cns.vmware.com CRDs whose version isn't supported by
+	// the controller.
+	UnsupportedVersionSkewGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "vsphere_csi_unsupported_version_skew",
+		Help: "Number of node plugins and CRDs whose version isn't supported by the controller",
+	},
+		// Possible kind - "node", "crd"
+		[]string{"kind"})
+
+	// StaleVolumeTopologyGauge is a gauge metric to observe the number of
+	// PVs whose node affinity doesn't match the accessibility of the
+	// datastore of their volume.
+	StaleVolumeTopologyGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "vsphere_csi_stale_volume_topology",
+		Help: "Number of PVs whose node affinity doesn't match the accessibility of the datastore of their volume",
+	})
 )
@@ -0,0 +1,150 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package retry provides a jittered exponential backoff used to retry
+// operations across the driver and the syncer.
+package retry
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/clock"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/prometheus"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+)
+
+// Backoff describes how an operation is retried.
+type Backoff struct {
+	// Initial is the delay before the first retry.
+	Initial time.Duration
+	// Max caps the delay between two attempts, before jitter is applied.
+	Max time.Duration
+	// Factor multiplies the delay after each attempt.
+	Factor float64
+	// Jitter is the fraction of the delay randomly added to it, which
+	// spreads the retries of concurrent callers.
+	Jitter float64
+	// Steps is the maximum number of attempts.
+	Steps int
+	// Clock is the time source of the delays between two attempts. The real
+	// clock is used if it is nil. Tests set it to a clock.FakeClock.
+	Clock clock.Clock
+}
+
+// Retry policies of the operations adopting this package. Tune the retry
+// behavior of these operations here.
+var (
+	// NodeDeviceDiscovery is used to wait for the device of an attached
+	// volume to show up on the node, and for udev to update its links.
+	NodeDeviceDiscovery = Backoff{Initial: 100 * time.Millisecond, Max: 2 * time.Second, Factor: 2,
+		Jitter: 0.2, Steps: 8}
+	// AttachVolume is used to retry attaching a volume when CNS is
+	// temporarily unavailable.
+	AttachVolume = Backoff{Initial: time.Second, Max: 10 * time.Second, Factor: 2, Jitter: 0.2, Steps: 3}
+	// SyncerCnsCall is used to retry the CNS calls of the syncer when CNS is
+	// temporarily unavailable.
+	SyncerCnsCall = Backoff{Initial: time.Second, Max: 30 * time.Second, Factor: 2, Jitter: 0.2, Steps: 4}
+	// SyncerVolumeLookup is used by the syncer to wait for a volume to be
+	// registered in CNS.
+	SyncerVolumeLookup = Backoff{Initial: time.Second, Max: 10 * time.Second, Factor: 2, Jitter: 0.2, Steps: 8}
+	// SyncerPVRecreate is used by the syncer to create a PV again after
+	// deleting it to replace its volume handle.
+	SyncerPVRecreate = Backoff{Initial: time.Second, Max: 30 * time.Second, Factor: 2, Jitter: 0.2, Steps: 6}
+)
+
+// Delay returns the delay before the attempt following the given number of
+// failed attempts.
+func (b Backoff) Delay(failedAttempts int) time.Duration {
+	delay := float64(b.Initial) * math.Pow(b.Factor, float64(failedAttempts-1))
+	if b.Max > 0 && delay > float64(b.Max) {
+		delay = float64(b.Max)
+	}
+	if b.Jitter > 0 {
+		delay += delay * b.Jitter * rand.Float64()
+	}
+	return time.Duration(delay)
+}
+
+// clock returns the time source of the delays of b.
+func (b Backoff) clock() clock.Clock {
+	if b.Clock == nil {
+		return clock.RealClock{}
+	}
+	return b.Clock
+}
+
+// permanentError marks an error as not worth retrying.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent wraps err to stop Do from retrying the operation which returned
+// it.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// Do calls fn until it succeeds, returns an error wrapped with Permanent, the
+// attempts of backoff are exhausted or ctx is done. The error returned is the
+// last error of fn, unwrapped if permanent, or the error of ctx. Attempts are
+// counted per operation in the vsphere_retry_ops_total metric.
+func Do(ctx context.Context, operation string, backoff Backoff, fn func() error) error {
+	log := logger.GetLogger(ctx)
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			prometheus.RetryOpsCounterVec.WithLabelValues(operation, prometheus.PrometheusPassStatus).Inc()
+			return nil
+		}
+		var permanentErr *permanentError
+		if errors.As(err, &permanentErr) {
+			prometheus.RetryOpsCounterVec.WithLabelValues(operation, prometheus.PrometheusFailStatus).Inc()
+			return permanentErr.err
+		}
+		if attempt >= backoff.Steps {
+			log.Debugf("%s: giving up after %d attempts. Last error: %v", operation, attempt, err)
+			prometheus.RetryOpsCounterVec.WithLabelValues(operation, prometheus.PrometheusFailStatus).Inc()
+			return err
+		}
+		delay := backoff.Delay(attempt)
+		log.Debugf("%s: attempt %d failed, retrying in %v. Err: %v", operation, attempt, delay, err)
+		prometheus.RetryOpsCounterVec.WithLabelValues(operation, prometheus.PrometheusRetryStatus).Inc()
+		timer := backoff.clock().NewTimer(delay)
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			prometheus.RetryOpsCounterVec.WithLabelValues(operation, prometheus.PrometheusFailStatus).Inc()
+			return ctx.Err()
+		}
+	}
+}
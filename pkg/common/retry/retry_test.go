@@ -0,0 +1,136 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/clock"
+)
+
+var testBackoff = Backoff{Initial: time.Millisecond, Max: 4 * time.Millisecond, Factor: 2, Steps: 4}
+
+func TestBackoffDelay(t *testing.T) {
+	expected := []time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond, 4 * time.Millisecond}
+	for i, delay := range expected {
+		if actual := testBackoff.Delay(i + 1); actual != delay {
+			t.Errorf("expected delay %v after %d failed attempts, got %v", delay, i+1, actual)
+		}
+	}
+	jittered := testBackoff
+	jittered.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if delay := jittered.Delay(1); delay < time.Millisecond || delay > 1500*time.Microsecond {
+			t.Fatalf("jittered delay %v out of bounds", delay)
+		}
+	}
+}
+
+func TestDo(t *testing.T) {
+	ctx := context.Background()
+	errTransient := errors.New("transient")
+	errFatal := errors.New("fatal")
+
+	attempts := 0
+	err := Do(ctx, "test", testBackoff, func() error {
+		attempts++
+		if attempts < 3 {
+			return errTransient
+		}
+		return nil
+	})
+	if err != nil || attempts != 3 {
+		t.Errorf("expected success after 3 attempts, got err %v after %d attempts", err, attempts)
+	}
+
+	attempts = 0
+	err = Do(ctx, "test", testBackoff, func() error {
+		attempts++
+		return errTransient
+	})
+	if err != errTransient || attempts != testBackoff.Steps {
+		t.Errorf("expected %v after %d attempts, got %v after %d attempts", errTransient, testBackoff.Steps,
+			err, attempts)
+	}
+
+	attempts = 0
+	err = Do(ctx, "test", testBackoff, func() error {
+		attempts++
+		return Permanent(errFatal)
+	})
+	if err != errFatal || attempts != 1 {
+		t.Errorf("expected %v after 1 attempt, got %v after %d attempts", errFatal, err, attempts)
+	}
+
+	cancelledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	attempts = 0
+	err = Do(cancelledCtx, "test", Backoff{Initial: time.Hour, Factor: 1, Steps: 2}, func() error {
+		attempts++
+		return errTransient
+	})
+	if err != context.Canceled || attempts != 1 {
+		t.Errorf("expected %v after 1 attempt, got %v after %d attempts", context.Canceled, err, attempts)
+	}
+}
+
+func TestDoWithFakeClock(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Now())
+	backoff := Backoff{Initial: time.Second, Max: 10 * time.Second, Factor: 2, Steps: 3, Clock: fakeClock}
+	attempts := make(chan int, backoff.Steps)
+	done := make(chan error)
+	go func() {
+		attempt := 0
+		done <- Do(context.Background(), "test", backoff, func() error {
+			attempt++
+			attempts <- attempt
+			return errors.New("transient")
+		})
+	}()
+
+	// waitForDelay waits for the attempt to fail and Do to wait for the next
+	// one.
+	waitForDelay := func(attempt int) {
+		if actual := <-attempts; actual != attempt {
+			t.Fatalf("expected attempt %d, got %d", attempt, actual)
+		}
+		for !fakeClock.HasWaiters() {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	waitForDelay(1)
+	fakeClock.Step(999 * time.Millisecond)
+	if !fakeClock.HasWaiters() {
+		t.Fatal("expected Do to wait 1s after the first attempt")
+	}
+	fakeClock.Step(time.Millisecond)
+	waitForDelay(2)
+	fakeClock.Step(time.Second)
+	if !fakeClock.HasWaiters() {
+		t.Fatal("expected Do to wait 2s after the second attempt")
+	}
+	fakeClock.Step(time.Second)
+	if actual := <-attempts; actual != 3 {
+		t.Fatalf("expected attempt 3, got %d", actual)
+	}
+	if err := <-done; err == nil {
+		t.Error("expected Do to fail after the last attempt")
+	}
+}
@@ -0,0 +1,258 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// otlpExportTimeout is the timeout of exporting a batch of spans.
+const otlpExportTimeout = 10 * time.Second
+
+// otlpExporter exports spans to an OTLP collector with the JSON encoding of
+// OTLP/HTTP. The OTLP exporters of OpenTelemetry depend on a more recent gRPC
+// than the one the etcd client of gocsi builds with, hence the JSON encoding
+// of the spans here.
+type otlpExporter struct {
+	endpoint string
+	headers  map[string]string
+	client   *http.Client
+}
+
+// newOTLPExporter returns an otlpExporter of the given OTLP/HTTP traces
+// endpoint, sending the given headers.
+func newOTLPExporter(endpoint string, headers map[string]string) *otlpExporter {
+	return &otlpExporter{
+		endpoint: endpoint,
+		headers:  headers,
+		client:   &http.Client{Timeout: otlpExportTimeout},
+	}
+}
+
+// ExportSpans posts the given spans to the OTLP collector.
+func (e *otlpExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(newOTLPTracesRequest(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to export %d spans to %s: %s", len(spans), e.endpoint, resp.Status)
+	}
+	return nil
+}
+
+// Shutdown does nothing, as spans are exported synchronously.
+func (e *otlpExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// The JSON encoding of the OTLP ExportTraceServiceRequest, as documented in
+// https://github.com/open-telemetry/opentelemetry-proto. 64-bit integers are
+// encoded as strings, and IDs as hex strings.
+type otlpTracesRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Events            []otlpEvent    `json:"events,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string         `json:"timeUnixNano"`
+	Name         string         `json:"name"`
+	Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+// OTLP status codes.
+const (
+	otlpStatusCodeOk    = 1
+	otlpStatusCodeError = 2
+)
+
+// newOTLPTracesRequest returns the OTLP request exporting the given spans,
+// grouped by resource and instrumentation library.
+func newOTLPTracesRequest(spans []sdktrace.ReadOnlySpan) *otlpTracesRequest {
+	req := &otlpTracesRequest{}
+	resourceSpans := make(map[string]*otlpResourceSpans)
+	var resources []string
+	scopeSpans := make(map[string]map[otlpScope]*otlpScopeSpans)
+	scopes := make(map[string][]otlpScope)
+	for _, span := range spans {
+		var resourceKey string
+		var resourceAttributes []attribute.KeyValue
+		if span.Resource() != nil {
+			resourceKey = span.Resource().Encoded(attribute.DefaultEncoder())
+			resourceAttributes = span.Resource().Attributes()
+		}
+		if _, ok := resourceSpans[resourceKey]; !ok {
+			resourceSpans[resourceKey] = &otlpResourceSpans{
+				Resource: otlpResource{Attributes: newOTLPKeyValues(resourceAttributes)},
+			}
+			resources = append(resources, resourceKey)
+			scopeSpans[resourceKey] = make(map[otlpScope]*otlpScopeSpans)
+		}
+		scope := otlpScope{Name: span.InstrumentationLibrary().Name, Version: span.InstrumentationLibrary().Version}
+		if _, ok := scopeSpans[resourceKey][scope]; !ok {
+			scopeSpans[resourceKey][scope] = &otlpScopeSpans{Scope: scope}
+			scopes[resourceKey] = append(scopes[resourceKey], scope)
+		}
+		scopeSpans[resourceKey][scope].Spans = append(scopeSpans[resourceKey][scope].Spans, newOTLPSpan(span))
+	}
+	for _, resourceKey := range resources {
+		for _, scope := range scopes[resourceKey] {
+			resourceSpans[resourceKey].ScopeSpans = append(resourceSpans[resourceKey].ScopeSpans,
+				*scopeSpans[resourceKey][scope])
+		}
+		req.ResourceSpans = append(req.ResourceSpans, *resourceSpans[resourceKey])
+	}
+	return req
+}
+
+// newOTLPSpan returns the OTLP encoding of the given span.
+func newOTLPSpan(span sdktrace.ReadOnlySpan) otlpSpan {
+	otlpSpan := otlpSpan{
+		TraceID:           span.SpanContext().TraceID().String(),
+		SpanID:            span.SpanContext().SpanID().String(),
+		Name:              span.Name(),
+		Kind:              int(span.SpanKind()),
+		StartTimeUnixNano: formatUnixNano(span.StartTime()),
+		EndTimeUnixNano:   formatUnixNano(span.EndTime()),
+		Attributes:        newOTLPKeyValues(span.Attributes()),
+		Status:            otlpStatus{Message: span.Status().Description},
+	}
+	if span.Parent().HasSpanID() {
+		otlpSpan.ParentSpanID = span.Parent().SpanID().String()
+	}
+	switch span.Status().Code {
+	case codes.Ok:
+		otlpSpan.Status.Code = otlpStatusCodeOk
+	case codes.Error:
+		otlpSpan.Status.Code = otlpStatusCodeError
+	}
+	for _, event := range span.Events() {
+		otlpSpan.Events = append(otlpSpan.Events, otlpEvent{
+			TimeUnixNano: formatUnixNano(event.Time),
+			Name:         event.Name,
+			Attributes:   newOTLPKeyValues(event.Attributes),
+		})
+	}
+	return otlpSpan
+}
+
+// newOTLPKeyValues returns the OTLP encoding of the given attributes. Slices
+// are encoded as strings.
+func newOTLPKeyValues(attributes []attribute.KeyValue) []otlpKeyValue {
+	var keyValues []otlpKeyValue
+	for _, kv := range attributes {
+		var value otlpAnyValue
+		switch kv.Value.Type() {
+		case attribute.BOOL:
+			b := kv.Value.AsBool()
+			value.BoolValue = &b
+		case attribute.INT64:
+			i := strconv.FormatInt(kv.Value.AsInt64(), 10)
+			value.IntValue = &i
+		case attribute.FLOAT64:
+			f := kv.Value.AsFloat64()
+			value.DoubleValue = &f
+		default:
+			s := kv.Value.Emit()
+			value.StringValue = &s
+		}
+		keyValues = append(keyValues, otlpKeyValue{Key: string(kv.Key), Value: value})
+	}
+	return keyValues
+}
+
+// formatUnixNano returns the given time in nanoseconds since the epoch.
+func formatUnixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
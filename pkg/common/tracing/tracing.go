@@ -0,0 +1,135 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tracing traces the CNS operations of the driver with OpenTelemetry,
+// and exports the spans to an OTLP collector.
+package tracing
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+)
+
+const (
+	// EnvOTLPEndpoint is the base URL of the OTLP collector, to which
+	// /v1/traces is appended.
+	EnvOTLPEndpoint = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	// EnvOTLPTracesEndpoint is the URL of the OTLP collector to export the
+	// spans to. It takes precedence over EnvOTLPEndpoint.
+	EnvOTLPTracesEndpoint = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
+	// EnvOTLPHeaders are the comma separated key=value headers to send to the
+	// OTLP collector, e.g. to authenticate.
+	EnvOTLPHeaders = "OTEL_EXPORTER_OTLP_HEADERS"
+	// EnvServiceName overrides the name of the service of the spans.
+	EnvServiceName = "OTEL_SERVICE_NAME"
+
+	// tracerName is the name of the tracer of the driver.
+	tracerName = "sigs.k8s.io/vsphere-csi-driver"
+	// otlpTracesPath is the path of the OTLP/HTTP traces endpoint.
+	otlpTracesPath = "/v1/traces"
+)
+
+// Attributes of the spans of the CNS operations.
+const (
+	// AttributeVolumeID is the ID of the CNS volume(s) of the operation.
+	AttributeVolumeID = attribute.Key("cns.volume_id")
+	// AttributeDatastore is the datastore(s) of the operation.
+	AttributeDatastore = attribute.Key("cns.datastore")
+	// AttributeVM is the VM a volume is attached to.
+	AttributeVM = attribute.Key("cns.vm")
+	// AttributeFault is the CSI fault of a failed operation.
+	AttributeFault = attribute.Key("cns.fault")
+	// AttributeVCHost is the vCenter serving the operation.
+	AttributeVCHost = attribute.Key("vc.host")
+)
+
+// Init exports the spans of the driver to the OTLP collector set in
+// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT or OTEL_EXPORTER_OTLP_ENDPOINT, if any,
+// as the given service unless OTEL_SERVICE_NAME is set. Spans are dropped
+// when no collector is set. The returned function flushes the pending spans
+// and stops exporting them.
+func Init(ctx context.Context, serviceName string) func(context.Context) error {
+	log := logger.GetLogger(ctx)
+	endpoint := getOTLPTracesEndpoint()
+	if endpoint == "" {
+		return func(context.Context) error { return nil }
+	}
+	if name := os.Getenv(EnvServiceName); name != "" {
+		serviceName = name
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(newOTLPExporter(endpoint, parseOTLPHeaders(os.Getenv(EnvOTLPHeaders)))),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	log.Infof("Exporting the spans of %s to %s", serviceName, endpoint)
+	return provider.Shutdown
+}
+
+// StartSpan starts a span of the given CNS operation, with the given
+// attributes, and returns the context of the span.
+func StartSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attributes...))
+}
+
+// EndSpan ends the given span, recording the given CSI fault and error of
+// the operation, if any.
+func EndSpan(span trace.Span, faultType string, err error) {
+	if faultType != "" {
+		span.SetAttributes(AttributeFault.String(faultType))
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// getOTLPTracesEndpoint returns the URL to export the spans to, if any.
+func getOTLPTracesEndpoint() string {
+	if endpoint := os.Getenv(EnvOTLPTracesEndpoint); endpoint != "" {
+		return endpoint
+	}
+	if endpoint := os.Getenv(EnvOTLPEndpoint); endpoint != "" {
+		return strings.TrimSuffix(endpoint, "/") + otlpTracesPath
+	}
+	return ""
+}
+
+// parseOTLPHeaders parses the comma separated key=value headers of
+// OTEL_EXPORTER_OTLP_HEADERS, ignoring malformed ones.
+func parseOTLPHeaders(value string) map[string]string {
+	headers := make(map[string]string)
+	for _, header := range strings.Split(value, ",") {
+		parts := strings.SplitN(header, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			continue
+		}
+		headers[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return headers
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestParseOTLPHeaders(t *testing.T) {
+	headers := parseOTLPHeaders("Authorization=Bearer token, x-tenant = cns ,malformed,=empty")
+	expected := map[string]string{"Authorization": "Bearer token", "x-tenant": "cns"}
+	if !reflect.DeepEqual(headers, expected) {
+		t.Errorf("expected headers %v, got %v", expected, headers)
+	}
+}
+
+func TestGetOTLPTracesEndpoint(t *testing.T) {
+	defer os.Unsetenv(EnvOTLPEndpoint)
+	defer os.Unsetenv(EnvOTLPTracesEndpoint)
+	for _, test := range []struct {
+		endpoint       string
+		tracesEndpoint string
+		expected       string
+	}{
+		{"", "", ""},
+		{"http://collector:4318/", "", "http://collector:4318/v1/traces"},
+		{"http://collector:4318", "http://traces:4318/otlp", "http://traces:4318/otlp"},
+	} {
+		os.Setenv(EnvOTLPEndpoint, test.endpoint)
+		os.Setenv(EnvOTLPTracesEndpoint, test.tracesEndpoint)
+		if endpoint := getOTLPTracesEndpoint(); endpoint != test.expected {
+			t.Errorf("expected endpoint %q for %q and %q, got %q", test.expected, test.endpoint,
+				test.tracesEndpoint, endpoint)
+		}
+	}
+}
+
+func TestExportSpans(t *testing.T) {
+	var lock sync.Mutex
+	var requests []otlpTracesRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != otlpTracesPath || r.Header.Get("Content-Type") != "application/json" ||
+			r.Header.Get("x-tenant") != "cns" {
+			t.Errorf("unexpected request %s %v", r.URL.Path, r.Header)
+		}
+		var req otlpTracesRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		lock.Lock()
+		requests = append(requests, req)
+		lock.Unlock()
+	}))
+	defer server.Close()
+	os.Setenv(EnvOTLPEndpoint, server.URL)
+	defer os.Unsetenv(EnvOTLPEndpoint)
+	os.Setenv(EnvOTLPHeaders, "x-tenant=cns")
+	defer os.Unsetenv(EnvOTLPHeaders)
+
+	ctx := context.Background()
+	shutdown := Init(ctx, "vsphere-csi-controller")
+	spanCtx, span := StartSpan(ctx, "CreateVolume", AttributeDatastore.String("datastore-1"))
+	_, child := StartSpan(spanCtx, "QueryVolume", AttributeVolumeID.String("volume-1"))
+	EndSpan(child, "", nil)
+	EndSpan(span, "csi.fault.Internal", errors.New("failed"))
+	if err := shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	var spans []otlpSpan
+	for _, req := range requests {
+		for _, resourceSpans := range req.ResourceSpans {
+			if attributes := resourceSpans.Resource.Attributes; len(attributes) != 1 ||
+				*attributes[0].Value.StringValue != "vsphere-csi-controller" {
+				t.Errorf("unexpected resource attributes %+v", attributes)
+			}
+			for _, scopeSpans := range resourceSpans.ScopeSpans {
+				spans = append(spans, scopeSpans.Spans...)
+			}
+		}
+	}
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans to be exported, got %+v", spans)
+	}
+	if spans[0].Name != "QueryVolume" || spans[0].ParentSpanID != spans[1].SpanID ||
+		spans[0].TraceID != spans[1].TraceID || spans[0].Status.Code != 0 {
+		t.Errorf("unexpected child span %+v", spans[0])
+	}
+	if spans[1].Name != "CreateVolume" || spans[1].ParentSpanID != "" ||
+		spans[1].Status.Code != otlpStatusCodeError || spans[1].Status.Message != "failed" ||
+		len(spans[1].Events) != 1 {
+		t.Errorf("unexpected span %+v", spans[1])
+	}
+	attributes := make(map[string]string)
+	for _, kv := range spans[1].Attributes {
+		attributes[kv.Key] = *kv.Value.StringValue
+	}
+	expected := map[string]string{string(AttributeDatastore): "datastore-1", string(AttributeFault): "csi.fault.Internal"}
+	if !reflect.DeepEqual(attributes, expected) {
+		t.Errorf("expected attributes %v, got %v", expected, attributes)
+	}
+}
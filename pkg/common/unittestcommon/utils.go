@@ -44,12 +44,14 @@ func GetFakeContainerOrchestratorInterface(orchestratorType int) (commonco.COCom
 	if orchestratorType == common.Kubernetes {
 		fakeCO := &FakeK8SOrchestrator{
 			featureStates: map[string]string{
-				"volume-extend":         "true",
-				"volume-health":         "true",
-				"csi-migration":         "true",
-				"file-volume":           "true",
-				"block-volume-snapshot": "true",
-				"tkgs-ha":               "true",
+				"volume-extend":               "true",
+				"volume-health":               "true",
+				"csi-migration":               "true",
+				"file-volume":                 "true",
+				"block-volume-snapshot":       "true",
+				"tkgs-ha":                     "true",
+				"list-volumes":                "true",
+				"controller-volume-condition": "true",
 			},
 		}
 		return fakeCO, nil
@@ -100,6 +102,16 @@ func (c *FakeK8SOrchestrator) ClearFakeAttached(ctx context.Context, volumeID st
 		"ClearFakeAttached for FakeK8SOrchestrator is not yet implemented.")
 }
 
+// IsNodeOutOfService checks if the node with the given name has the out-of-service taint.
+func (c *FakeK8SOrchestrator) IsNodeOutOfService(ctx context.Context, nodeName string) (bool, error) {
+	return false, nil
+}
+
+// GetVolumeAttributes returns the volume attributes of the PV of the given volume.
+func (c *FakeK8SOrchestrator) GetVolumeAttributes(ctx context.Context, volumeID string) (map[string]string, error) {
+	return nil, common.ErrNotFound
+}
+
 // GetNodeTopologyLabels fetches the topology information of a node from the CSINodeTopology CR.
 func (nodeTopology *mockNodeVolumeTopology) GetNodeTopologyLabels(ctx context.Context, info *commoncotypes.NodeInfo) (
 	map[string]string, error) {
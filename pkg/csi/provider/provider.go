@@ -17,15 +17,19 @@ limitations under the License.
 package provider
 
 import (
+	"context"
+
 	"github.com/rexray/gocsi"
 
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
 )
 
 // New returns a new CSI Storage Plug-in Provider.
 func New() gocsi.StoragePluginProvider {
 	svc := service.NewDriver()
 	ctrl := svc.GetController()
+	ctx := logger.NewContextWithLogger(context.Background())
 
 	return &gocsi.StoragePlugin{
 		Controller:  ctrl,
@@ -33,6 +37,10 @@ func New() gocsi.StoragePluginProvider {
 		Node:        svc,
 		BeforeServe: svc.BeforeServe,
 
+		// Protect the driver from misconfigured sidecars.
+		ServerOpts:   service.GRPCServerOptions(ctx),
+		Interceptors: service.GRPCServerInterceptors(ctx),
+
 		EnvVars: []string{
 			// Enable request validation.
 			gocsi.EnvVarSpecReqValidation + "=true",
@@ -0,0 +1,238 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	cnsvolume "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/volume"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	csifault "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/fault"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/retry"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+)
+
+const (
+	batchOperationAttach = "attach"
+	batchOperationDetach = "detach"
+)
+
+// batchVolumesFunc issues a batched attach or detach of the given volumes on
+// the vm.
+type batchVolumesFunc func(ctx context.Context, vm *vsphere.VirtualMachine, volumeIDs []string,
+	checkNVMeController bool) map[string]*cnsvolume.BatchAttachDetachResult
+
+// VolumeAttachBatcher coalesces concurrent attach or detach requests for the
+// same node VM into a single CNS task, so that vCenter reconfigures the VM
+// once per batch instead of once per volume.
+type VolumeAttachBatcher struct {
+	manager *Manager
+	// window is the duration for which requests for a node VM are collected
+	// before the batch is issued.
+	window time.Duration
+	// lock protects batches.
+	lock    sync.Mutex
+	batches map[string]*volumeBatch
+	// attachVolumes and detachVolumes issue the batched CNS operations.
+	attachVolumes batchVolumesFunc
+	detachVolumes batchVolumesFunc
+}
+
+// volumeBatch holds the pending requests of a batch.
+type volumeBatch struct {
+	operation           string
+	vm                  *vsphere.VirtualMachine
+	checkNVMeController bool
+	// waiters holds, per volume ID, the channels of the requests waiting for
+	// the result of the batch.
+	waiters map[string][]chan *cnsvolume.BatchAttachDetachResult
+}
+
+// NewVolumeAttachBatcher returns a VolumeAttachBatcher collecting requests
+// for the given window before issuing them as a single CNS task.
+func NewVolumeAttachBatcher(manager *Manager, window time.Duration) *VolumeAttachBatcher {
+	batcher := &VolumeAttachBatcher{
+		manager: manager,
+		window:  window,
+		batches: make(map[string]*volumeBatch),
+	}
+	batcher.attachVolumes = batcher.batchAttachVolumes
+	batcher.detachVolumes = batcher.batchDetachVolumes
+	return batcher
+}
+
+// AttachVolume attaches the volume to the vm along with the other volumes
+// requested to be attached to the same vm within the batch window. It returns
+// the disk UUID and, on failure, the fault type and error.
+func (b *VolumeAttachBatcher) AttachVolume(ctx context.Context, vm *vsphere.VirtualMachine,
+	volumeID string, checkNVMeController bool) (string, string, error) {
+	log := logger.GetLogger(ctx)
+	log.Debugf("vSphere CSI driver is attaching volume: %q to vm: %q in a batch", volumeID, vm.String())
+	result := b.submit(ctx, batchOperationAttach, vm, volumeID, checkNVMeController)
+	if result.Err != nil {
+		log.Errorf("failed to attach disk %q with VM: %q. err: %+v faultType %q", volumeID, vm.String(),
+			result.Err, result.FaultType)
+		return "", result.FaultType, result.Err
+	}
+	log.Debugf("Successfully attached disk %s to VM %v. Disk UUID is %s", volumeID, vm, result.DiskUUID)
+	return result.DiskUUID, "", nil
+}
+
+// DetachVolume detaches the volume from the vm along with the other volumes
+// requested to be detached from the same vm within the batch window. It
+// returns the fault type and error on failure.
+func (b *VolumeAttachBatcher) DetachVolume(ctx context.Context, vm *vsphere.VirtualMachine,
+	volumeID string) (string, error) {
+	log := logger.GetLogger(ctx)
+	log.Debugf("vSphere CSI driver is detaching volume: %s from node vm: %s in a batch", volumeID, vm.InventoryPath)
+	result := b.submit(ctx, batchOperationDetach, vm, volumeID, false)
+	if result.Err != nil {
+		log.Errorf("failed to detach disk %s with err %+v", volumeID, result.Err)
+		return result.FaultType, result.Err
+	}
+	log.Debugf("Successfully detached disk %s from VM %v.", volumeID, vm)
+	return "", nil
+}
+
+// submit adds the volume to the pending batch of the operation for the vm,
+// starting a new batch if there is none, and waits for the batch result.
+func (b *VolumeAttachBatcher) submit(ctx context.Context, operation string, vm *vsphere.VirtualMachine,
+	volumeID string, checkNVMeController bool) *cnsvolume.BatchAttachDetachResult {
+	resultCh := make(chan *cnsvolume.BatchAttachDetachResult, 1)
+	key := fmt.Sprintf("%s/%s/%t", operation, vm.Reference().Value, checkNVMeController)
+	b.lock.Lock()
+	batch, ok := b.batches[key]
+	if !ok {
+		batch = &volumeBatch{
+			operation:           operation,
+			vm:                  vm,
+			checkNVMeController: checkNVMeController,
+			waiters:             make(map[string][]chan *cnsvolume.BatchAttachDetachResult),
+		}
+		b.batches[key] = batch
+		time.AfterFunc(b.window, func() { b.flush(key) })
+	}
+	batch.waiters[volumeID] = append(batch.waiters[volumeID], resultCh)
+	b.lock.Unlock()
+
+	select {
+	case result := <-resultCh:
+		return result
+	case <-ctx.Done():
+		// The batch still completes, the request is retried by the CO.
+		return &cnsvolume.BatchAttachDetachResult{
+			FaultType: csifault.CSIInternalFault,
+			Err:       ctx.Err(),
+		}
+	}
+}
+
+// flush issues the pending batch with the given key and hands the result
+// of each volume to the requests waiting for it.
+func (b *VolumeAttachBatcher) flush(key string) {
+	// The batch serves several requests, so it does not use the context of
+	// any of them.
+	ctx, log := logger.GetNewContextWithLogger()
+	b.lock.Lock()
+	batch := b.batches[key]
+	delete(b.batches, key)
+	b.lock.Unlock()
+	if batch == nil {
+		return
+	}
+	volumeIDs := make([]string, 0, len(batch.waiters))
+	for volumeID := range batch.waiters {
+		volumeIDs = append(volumeIDs, volumeID)
+	}
+	sort.Strings(volumeIDs)
+	log.Infof("Issuing %s of volumes %v on vm %q as a single batch", batch.operation, volumeIDs, batch.vm.String())
+	var results map[string]*cnsvolume.BatchAttachDetachResult
+	if batch.operation == batchOperationAttach {
+		results = b.attachVolumesWithRetry(ctx, batch.vm, volumeIDs, batch.checkNVMeController)
+	} else {
+		results = b.detachVolumes(ctx, batch.vm, volumeIDs, batch.checkNVMeController)
+	}
+	for volumeID, waiters := range batch.waiters {
+		result, ok := results[volumeID]
+		if !ok || result == nil {
+			result = &cnsvolume.BatchAttachDetachResult{
+				FaultType: csifault.CSIInternalFault,
+				Err: logger.LogNewErrorf(log, "no %s result returned for volume %q on vm %q",
+					batch.operation, volumeID, batch.vm.String()),
+			}
+		}
+		for _, waiter := range waiters {
+			waiter <- result
+		}
+	}
+}
+
+// attachVolumesWithRetry attaches the volumes of a batch, retrying the
+// volumes whose attach failed as CNS was temporarily unavailable with the
+// backoff of AttachVolumeUtil.
+func (b *VolumeAttachBatcher) attachVolumesWithRetry(ctx context.Context, vm *vsphere.VirtualMachine,
+	volumeIDs []string, checkNVMeController bool) map[string]*cnsvolume.BatchAttachDetachResult {
+	results := make(map[string]*cnsvolume.BatchAttachDetachResult)
+	pending := volumeIDs
+	// The errors are returned per volume in the results.
+	_ = retry.Do(ctx, "AttachVolume", retry.AttachVolume, func() error {
+		attempt := b.attachVolumes(ctx, vm, pending, checkNVMeController)
+		var retryable []string
+		var err error
+		for _, volumeID := range pending {
+			result := attempt[volumeID]
+			results[volumeID] = result
+			if result != nil && cnsvolume.IsRetryableError(result.FaultType, result.Err) {
+				retryable = append(retryable, volumeID)
+				err = result.Err
+			}
+		}
+		pending = retryable
+		return err
+	})
+	return results
+}
+
+// batchAttachVolumes attaches the volumes using the volume manager. A single
+// volume goes through the regular attach path.
+func (b *VolumeAttachBatcher) batchAttachVolumes(ctx context.Context, vm *vsphere.VirtualMachine,
+	volumeIDs []string, checkNVMeController bool) map[string]*cnsvolume.BatchAttachDetachResult {
+	if len(volumeIDs) == 1 {
+		diskUUID, faultType, err := b.manager.VolumeManager.AttachVolume(ctx, vm, volumeIDs[0], checkNVMeController)
+		return map[string]*cnsvolume.BatchAttachDetachResult{
+			volumeIDs[0]: {DiskUUID: diskUUID, FaultType: faultType, Err: err},
+		}
+	}
+	return b.manager.VolumeManager.BatchAttachVolumes(ctx, vm, volumeIDs, checkNVMeController)
+}
+
+// batchDetachVolumes detaches the volumes using the volume manager. A single
+// volume goes through the regular detach path.
+func (b *VolumeAttachBatcher) batchDetachVolumes(ctx context.Context, vm *vsphere.VirtualMachine,
+	volumeIDs []string, _ bool) map[string]*cnsvolume.BatchAttachDetachResult {
+	if len(volumeIDs) == 1 {
+		faultType, err := b.manager.VolumeManager.DetachVolume(ctx, vm, volumeIDs[0])
+		return map[string]*cnsvolume.BatchAttachDetachResult{
+			volumeIDs[0]: {FaultType: faultType, Err: err},
+		}
+	}
+	return b.manager.VolumeManager.BatchDetachVolumes(ctx, vm, volumeIDs)
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vmware/govmomi/object"
+	vim25types "github.com/vmware/govmomi/vim25/types"
+
+	cnsvolume "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/volume"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/retry"
+)
+
+func newAttachBatcherTestVM(moID string) *vsphere.VirtualMachine {
+	return &vsphere.VirtualMachine{
+		VirtualMachine: object.NewVirtualMachine(nil,
+			vim25types.ManagedObjectReference{Type: "VirtualMachine", Value: moID}),
+	}
+}
+
+func TestVolumeAttachBatcherCoalescesRequestsPerNode(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var lock sync.Mutex
+	attachBatches := make(map[string][]string)
+	detachBatches := make(map[string][]string)
+	batcher := NewVolumeAttachBatcher(nil, 100*time.Millisecond)
+	batcher.attachVolumes = func(ctx context.Context, vm *vsphere.VirtualMachine, volumeIDs []string,
+		checkNVMeController bool) map[string]*cnsvolume.BatchAttachDetachResult {
+		lock.Lock()
+		defer lock.Unlock()
+		attachBatches[vm.Reference().Value] = volumeIDs
+		results := make(map[string]*cnsvolume.BatchAttachDetachResult)
+		for _, volumeID := range volumeIDs {
+			if volumeID == "vol-failed" {
+				results[volumeID] = &cnsvolume.BatchAttachDetachResult{
+					FaultType: "vim.fault.NotFound",
+					Err:       errors.New("volume not found"),
+				}
+				continue
+			}
+			results[volumeID] = &cnsvolume.BatchAttachDetachResult{DiskUUID: "disk-" + volumeID}
+		}
+		return results
+	}
+	batcher.detachVolumes = func(ctx context.Context, vm *vsphere.VirtualMachine, volumeIDs []string,
+		checkNVMeController bool) map[string]*cnsvolume.BatchAttachDetachResult {
+		lock.Lock()
+		defer lock.Unlock()
+		detachBatches[vm.Reference().Value] = volumeIDs
+		results := make(map[string]*cnsvolume.BatchAttachDetachResult)
+		for _, volumeID := range volumeIDs {
+			results[volumeID] = &cnsvolume.BatchAttachDetachResult{}
+		}
+		return results
+	}
+
+	vm1 := newAttachBatcherTestVM("vm-1")
+	vm2 := newAttachBatcherTestVM("vm-2")
+	attachRequests := []struct {
+		vm               *vsphere.VirtualMachine
+		volumeID         string
+		expectedDiskUUID string
+		expectErr        bool
+	}{
+		{vm1, "vol-1", "disk-vol-1", false},
+		{vm1, "vol-2", "disk-vol-2", false},
+		{vm1, "vol-failed", "", true},
+		{vm2, "vol-3", "disk-vol-3", false},
+	}
+	var wg sync.WaitGroup
+	for _, request := range attachRequests {
+		wg.Add(1)
+		go func(vm *vsphere.VirtualMachine, volumeID string, expectedDiskUUID string, expectErr bool) {
+			defer wg.Done()
+			diskUUID, faultType, err := batcher.AttachVolume(ctx, vm, volumeID, false)
+			if expectErr {
+				if err == nil || faultType == "" {
+					t.Errorf("expected attach of volume %q to fail with a fault type", volumeID)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("failed to attach volume %q. Err: %v", volumeID, err)
+			}
+			if diskUUID != expectedDiskUUID {
+				t.Errorf("volume %q: expected disk UUID %q, got %q", volumeID, expectedDiskUUID, diskUUID)
+			}
+		}(request.vm, request.volumeID, request.expectedDiskUUID, request.expectErr)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if _, err := batcher.DetachVolume(ctx, vm1, "vol-4"); err != nil {
+			t.Errorf("failed to detach volume vol-4. Err: %v", err)
+		}
+	}()
+	wg.Wait()
+
+	expectedAttachBatches := map[string][]string{
+		"vm-1": {"vol-1", "vol-2", "vol-failed"},
+		"vm-2": {"vol-3"},
+	}
+	if !reflect.DeepEqual(attachBatches, expectedAttachBatches) {
+		t.Errorf("expected attach batches %v, got %v", expectedAttachBatches, attachBatches)
+	}
+	expectedDetachBatches := map[string][]string{
+		"vm-1": {"vol-4"},
+	}
+	if !reflect.DeepEqual(detachBatches, expectedDetachBatches) {
+		t.Errorf("expected detach batches %v, got %v", expectedDetachBatches, detachBatches)
+	}
+}
+
+func TestVolumeAttachBatcherRetriesUnavailableAttaches(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	defer func(backoff retry.Backoff) {
+		retry.AttachVolume = backoff
+	}(retry.AttachVolume)
+	retry.AttachVolume = retry.Backoff{Initial: time.Millisecond, Factor: 1, Steps: 3}
+
+	var attempts [][]string
+	batcher := NewVolumeAttachBatcher(nil, time.Millisecond)
+	batcher.attachVolumes = func(ctx context.Context, vm *vsphere.VirtualMachine, volumeIDs []string,
+		checkNVMeController bool) map[string]*cnsvolume.BatchAttachDetachResult {
+		attempts = append(attempts, volumeIDs)
+		results := make(map[string]*cnsvolume.BatchAttachDetachResult)
+		for _, volumeID := range volumeIDs {
+			switch {
+			case volumeID == "vol-failed":
+				results[volumeID] = &cnsvolume.BatchAttachDetachResult{
+					FaultType: "vim.fault.NotFound",
+					Err:       errors.New("volume not found"),
+				}
+			case volumeID == "vol-unavailable" && len(attempts) == 1:
+				results[volumeID] = &cnsvolume.BatchAttachDetachResult{
+					FaultType: "vim.fault.HostCommunication",
+					Err:       errors.New("host not reachable"),
+				}
+			default:
+				results[volumeID] = &cnsvolume.BatchAttachDetachResult{DiskUUID: "disk-" + volumeID}
+			}
+		}
+		return results
+	}
+
+	results := batcher.attachVolumesWithRetry(ctx, newAttachBatcherTestVM("vm-1"),
+		[]string{"vol-1", "vol-failed", "vol-unavailable"}, false)
+	expectedAttempts := [][]string{{"vol-1", "vol-failed", "vol-unavailable"}, {"vol-unavailable"}}
+	if !reflect.DeepEqual(attempts, expectedAttempts) {
+		t.Errorf("expected attach attempts %v, got %v", expectedAttempts, attempts)
+	}
+	if results["vol-unavailable"].Err != nil || results["vol-unavailable"].DiskUUID != "disk-vol-unavailable" {
+		t.Errorf("expected the attach of vol-unavailable to succeed on retry, got %+v", results["vol-unavailable"])
+	}
+	if results["vol-failed"].Err == nil {
+		t.Errorf("expected the attach of vol-failed to fail without retry")
+	}
+	if results["vol-1"].Err != nil {
+		t.Errorf("expected the attach of vol-1 to succeed, got %v", results["vol-1"].Err)
+	}
+}
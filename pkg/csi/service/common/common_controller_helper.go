@@ -121,6 +121,32 @@ func CheckSnapshotSupport(ctx context.Context, manager *Manager) bool {
 	return true
 }
 
+// GetControllerServiceCapabilities builds the controller service capabilities
+// for the given RPC types. RPC types present in featureGates are reported only
+// if the corresponding feature state is enabled, so that the CSI sidecars do
+// not attempt operations which cannot be served in the current deployment.
+func GetControllerServiceCapabilities(ctx context.Context, rpcTypes []csi.ControllerServiceCapability_RPC_Type,
+	featureGates map[csi.ControllerServiceCapability_RPC_Type]string,
+	isFSSEnabled func(ctx context.Context, featureName string) bool) []*csi.ControllerServiceCapability {
+	log := logger.GetLogger(ctx)
+	var caps []*csi.ControllerServiceCapability
+	for _, rpcType := range rpcTypes {
+		if featureName, ok := featureGates[rpcType]; ok && !isFSSEnabled(ctx, featureName) {
+			log.Infof("Not reporting controller capability %q as %q feature is disabled",
+				rpcType.String(), featureName)
+			continue
+		}
+		caps = append(caps, &csi.ControllerServiceCapability{
+			Type: &csi.ControllerServiceCapability_Rpc{
+				Rpc: &csi.ControllerServiceCapability_RPC{
+					Type: rpcType,
+				},
+			},
+		})
+	}
+	return caps
+}
+
 // CheckAPI checks if specified version against the specified minimum support version.
 func CheckAPI(versionToCheck string,
 	minSupportedVCenterMajor int,
@@ -168,19 +194,19 @@ func UseVslmAPIs(ctx context.Context, aboutInfo vim25types.AboutInfo) (bool, err
 	vSphereVersionInt, err := strconv.Atoi(apiVersion[0:3])
 	if err != nil {
 		return false, logger.LogNewErrorf(log,
-			"Error while converting ApiVersion %q to integer, err %+v", apiVersion, err)
+			"Error while converting ApiVersion %q to integer, err %w", apiVersion, err)
 	}
 	vSphere67u3VersionStr := strings.Join(strings.Split(VSphere67u3Version, "."), "")
 	vSphere67u3VersionInt, err := strconv.Atoi(vSphere67u3VersionStr[0:3])
 	if err != nil {
 		return false, logger.LogNewErrorf(log,
-			"Error while converting VSphere67u3Version %q to integer, err %+v", VSphere67u3Version, err)
+			"Error while converting VSphere67u3Version %q to integer, err %w", VSphere67u3Version, err)
 	}
 	vSphere7VersionStr := strings.Join(strings.Split(VSphere7Version, "."), "")
 	vSphere7VersionInt, err := strconv.Atoi(vSphere7VersionStr[0:3])
 	if err != nil {
 		return false, logger.LogNewErrorf(log,
-			"Error while converting VSphere7Version %q to integer, err %+v", VSphere7Version, err)
+			"Error while converting VSphere7Version %q to integer, err %w", VSphere7Version, err)
 	}
 	// Check if the current vSphere version is between 6.7.3 and 7.0.0.
 	if vSphereVersionInt > vSphere67u3VersionInt && vSphereVersionInt <= vSphere7VersionInt {
@@ -212,7 +238,7 @@ func UseVslmAPIs(ctx context.Context, aboutInfo vim25types.AboutInfo) (bool, err
 		}
 		if err != nil {
 			return false, logger.LogNewErrorf(log,
-				"Error while converting VC Build info %q to integer, err %+v", aboutInfo.Build, err)
+				"Error while converting VC Build info %q to integer, err %w", aboutInfo.Build, err)
 		}
 	}
 	// For all other versions.
@@ -287,7 +313,7 @@ func IsvSphere8AndAbove(ctx context.Context, aboutInfo vim25types.AboutInfo) (bo
 	vSphereMajorVersionInt, err := strconv.Atoi(string(apiVersion[0]))
 	if err != nil {
 		return false, logger.LogNewErrorf(log,
-			"Error while converting ApiVersion %q to integer, err %+v", apiVersion, err)
+			"Error while converting ApiVersion %q to integer, err %w", apiVersion, err)
 	}
 
 	// Check if the current vSphere version is greater 8
@@ -314,3 +340,23 @@ func CheckPVtoBackingDiskObjectIdSupport(ctx context.Context, vc *cnsvsphere.Vir
 	log.Infof("vCenter API version: %s supports CNS PV to BackingDiskObjectId mapping.", currentVcVersion)
 	return true
 }
+
+// GetCnsErrorCode returns the gRPC status code for err, returned by a CNS
+// operation along with the given fault type. Only errors which may succeed on
+// retry are mapped to codes the CSI sidecars retry right away, so that e.g.
+// requests rejected as invalid are not retried endlessly. DeleteVolume and
+// ControllerUnpublishVolume must succeed instead of returning NotFound, see
+// cnsvolume.IsNotFoundError.
+func GetCnsErrorCode(faultType string, err error) codes.Code {
+	switch cnsvolume.GetErrorKind(faultType, err) {
+	case cnsvolume.ErrorKindNotFound:
+		return codes.NotFound
+	case cnsvolume.ErrorKindResourceExhausted:
+		return codes.ResourceExhausted
+	case cnsvolume.ErrorKindInvalidArgument:
+		return codes.InvalidArgument
+	case cnsvolume.ErrorKindUnavailable:
+		return codes.Unavailable
+	}
+	return codes.Internal
+}
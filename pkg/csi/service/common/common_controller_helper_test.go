@@ -20,6 +20,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/container-storage-interface/spec/lib/go/csi"
 	vim25types "github.com/vmware/govmomi/vim25/types"
 )
 
@@ -174,3 +175,33 @@ func TestUseVslmAPIsFuncForVC70u1(t *testing.T) {
 		t.Fatal("Received error from UseVslmAPIs method")
 	}
 }
+
+// TestGetControllerServiceCapabilities tests that feature gated controller
+// capabilities are only reported when the feature is enabled.
+func TestGetControllerServiceCapabilities(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rpcTypes := []csi.ControllerServiceCapability_RPC_Type{
+		csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME,
+		csi.ControllerServiceCapability_RPC_EXPAND_VOLUME,
+	}
+	featureGates := map[csi.ControllerServiceCapability_RPC_Type]string{
+		csi.ControllerServiceCapability_RPC_EXPAND_VOLUME: VolumeExtend,
+	}
+	fssDisabled := func(ctx context.Context, featureName string) bool { return false }
+	fssEnabled := func(ctx context.Context, featureName string) bool { return true }
+
+	caps := GetControllerServiceCapabilities(ctx, rpcTypes, featureGates, fssDisabled)
+	if len(caps) != 1 || caps[0].GetRpc().GetType() != csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME {
+		t.Fatalf("expected only CREATE_DELETE_VOLUME capability, got %+v", caps)
+	}
+	caps = GetControllerServiceCapabilities(ctx, rpcTypes, featureGates, fssEnabled)
+	if len(caps) != 2 {
+		t.Fatalf("expected 2 capabilities, got %+v", caps)
+	}
+	caps = GetControllerServiceCapabilities(ctx, rpcTypes, nil, fssDisabled)
+	if len(caps) != 2 {
+		t.Fatalf("expected 2 capabilities without feature gates, got %+v", caps)
+	}
+}
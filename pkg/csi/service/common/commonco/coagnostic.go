@@ -45,6 +45,10 @@ type COCommonInterface interface {
 	MarkFakeAttached(ctx context.Context, volumeID string) error
 	// Check if the volume was fake attached, and unmark it as not fake attached.
 	ClearFakeAttached(ctx context.Context, volumeID string) error
+	// Check if the node with the given name has the out-of-service taint.
+	IsNodeOutOfService(ctx context.Context, nodeName string) (bool, error)
+	// Get the volume attributes of the PV of the given volume.
+	GetVolumeAttributes(ctx context.Context, volumeID string) (map[string]string, error)
 	// InitTopologyServiceInController initializes the necessary resources
 	// required for topology related functionality in the controller.
 	InitTopologyServiceInController(ctx context.Context) (types.ControllerTopologyService, error)
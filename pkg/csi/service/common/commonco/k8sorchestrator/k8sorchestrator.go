@@ -29,9 +29,11 @@ import (
 	cnstypes "github.com/vmware/govmomi/cns/types"
 	pbmtypes "github.com/vmware/govmomi/pbm/types"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	apiMeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/informers"
 	clientset "k8s.io/client-go/kubernetes"
@@ -173,6 +175,11 @@ func Newk8sOrchestrator(ctx context.Context, controllerClusterFlavor cnstypes.Cn
 
 				initVolumeHandleToPvcMap(ctx)
 			}
+			if controllerClusterFlavor == cnstypes.CnsClusterFlavorVanilla &&
+				k8sOrchestratorInstance.IsFSSEnabled(ctx, common.DetachQuiesce) {
+				// Start the PV informer, whose lister backs GetVolumeAttributes.
+				k8sOrchestratorInstance.informerManager.AddPVListener(nil, nil, nil)
+			}
 			k8sOrchestratorInstance.informerManager.Listen()
 			atomic.StoreUint32(&k8sOrchestratorInstanceInitialized, 1)
 			log.Info("k8sOrchestratorInstance initialized")
@@ -942,3 +949,42 @@ func (c *K8sOrchestrator) ClearFakeAttached(ctx context.Context, volumeID string
 	}
 	return nil
 }
+
+// IsNodeOutOfService checks if the node with the given name has the
+// out-of-service taint, set on nodes shut down non-gracefully so that their
+// pods and volumes are failed over without waiting for the node to return.
+func (c *K8sOrchestrator) IsNodeOutOfService(ctx context.Context, nodeName string) (bool, error) {
+	log := logger.GetLogger(ctx)
+	node, err := c.k8sClient.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		log.Errorf("failed to get node %q. Error: %+v", nodeName, err)
+		return false, err
+	}
+	for _, taint := range node.Spec.Taints {
+		if taint.Key == common.TaintNodeOutOfService {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetVolumeAttributes returns the volume attributes of the PV of the volume
+// with the given ID, i.e. the volume context returned by CreateVolume.
+func (c *K8sOrchestrator) GetVolumeAttributes(ctx context.Context, volumeID string) (map[string]string, error) {
+	log := logger.GetLogger(ctx)
+	pvs, err := c.informerManager.GetPVLister().List(labels.Everything())
+	if err != nil {
+		log.Errorf("failed to list PVs. Error: %+v", err)
+		return nil, err
+	}
+	for _, pv := range pvs {
+		if pv.Spec.CSI != nil && pv.Spec.CSI.Driver == csitypes.Name && pv.Spec.CSI.VolumeHandle == volumeID {
+			return pv.Spec.CSI.VolumeAttributes, nil
+		}
+	}
+	log.Debugf("could not find PV for volumeID: %s", volumeID)
+	return nil, common.ErrNotFound
+}
@@ -19,10 +19,18 @@ package k8sorchestrator
 import (
 	"context"
 	"testing"
+	"time"
 
 	cnstypes "github.com/vmware/govmomi/cns/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
 
 	cnsconfig "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+	k8s "sigs.k8s.io/vsphere-csi-driver/v2/pkg/kubernetes"
 )
 
 var (
@@ -232,3 +240,83 @@ func TestIsFSSEnabledWithWrongClusterFlavor(t *testing.T) {
 		t.Errorf("volume-extend feature state enabled even when cluster flavor is wrong")
 	}
 }
+
+// TestIsNodeOutOfService tests IsNodeOutOfService with nodes with and without
+// the out-of-service taint, and a missing node.
+func TestIsNodeOutOfService(t *testing.T) {
+	k8sOrchestrator := K8sOrchestrator{
+		k8sClient: k8sfake.NewSimpleClientset(
+			&v1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "node-out-of-service"},
+				Spec: v1.NodeSpec{Taints: []v1.Taint{{
+					Key:    common.TaintNodeOutOfService,
+					Value:  "nodeshutdown",
+					Effect: v1.TaintEffectNoExecute,
+				}}},
+			},
+			&v1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "node-in-service"},
+				Spec: v1.NodeSpec{Taints: []v1.Taint{{
+					Key:    v1.TaintNodeUnreachable,
+					Effect: v1.TaintEffectNoExecute,
+				}}},
+			},
+		),
+	}
+	tests := map[string]bool{
+		"node-out-of-service": true,
+		"node-in-service":     false,
+		"node-missing":        false,
+	}
+	for nodeName, expected := range tests {
+		outOfService, err := k8sOrchestrator.IsNodeOutOfService(ctx, nodeName)
+		if err != nil {
+			t.Fatalf("IsNodeOutOfService(%q) failed: %v", nodeName, err)
+		}
+		if outOfService != expected {
+			t.Errorf("IsNodeOutOfService(%q) = %t, expected %t", nodeName, outOfService, expected)
+		}
+	}
+}
+
+// TestGetVolumeAttributes tests GetVolumeAttributes with a volume of this
+// driver, a volume of another driver and a missing volume.
+func TestGetVolumeAttributes(t *testing.T) {
+	newPV := func(name string, driver string, volumeHandle string) *v1.PersistentVolume {
+		return &v1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: v1.PersistentVolumeSpec{PersistentVolumeSource: v1.PersistentVolumeSource{
+				CSI: &v1.CSIPersistentVolumeSource{
+					Driver:           driver,
+					VolumeHandle:     volumeHandle,
+					VolumeAttributes: map[string]string{common.AttributeDetachQuiesceDelay: name},
+				},
+			}},
+		}
+	}
+	k8sClient := k8sfake.NewSimpleClientset(
+		newPV("pv-1", csitypes.Name, "volume-1"),
+		newPV("pv-2", "other.csi.driver", "volume-2"),
+	)
+	k8sOrchestrator := K8sOrchestrator{k8sClient: k8sClient, informerManager: k8s.NewInformer(k8sClient)}
+	k8sOrchestrator.informerManager.AddPVListener(nil, nil, nil)
+	k8sOrchestrator.informerManager.Listen()
+
+	var attributes map[string]string
+	err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		var err error
+		attributes, err = k8sOrchestrator.GetVolumeAttributes(ctx, "volume-1")
+		return err == nil, nil
+	})
+	if err != nil {
+		t.Fatalf("GetVolumeAttributes(%q) failed: %v", "volume-1", err)
+	}
+	if attributes[common.AttributeDetachQuiesceDelay] != "pv-1" {
+		t.Errorf("GetVolumeAttributes(%q) = %v, expected the attributes of pv-1", "volume-1", attributes)
+	}
+	for _, volumeID := range []string{"volume-2", "volume-missing"} {
+		if _, err := k8sOrchestrator.GetVolumeAttributes(ctx, volumeID); err != common.ErrNotFound {
+			t.Errorf("GetVolumeAttributes(%q) returned %v, expected %v", volumeID, err, common.ErrNotFound)
+		}
+	}
+}
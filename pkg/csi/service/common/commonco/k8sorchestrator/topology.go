@@ -239,7 +239,7 @@ func startAvailabilityZoneInformer(ctx context.Context, cfg *restclient.Config)
 	// Create a new AvailabilityZone client.
 	azClient, err := dynamic.NewForConfig(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create AvailabilityZone client using config. Err: %+v", err)
+		return nil, fmt.Errorf("failed to create AvailabilityZone client using config. Err: %w", err)
 	}
 	// Get AvailabilityZone list
 	azResource := schema.GroupVersionResource{
@@ -618,6 +618,10 @@ func (volTopology *nodeVolumeTopology) GetNodeTopologyLabels(ctx context.Context
 	defer watchCSINodeTopology.Stop()
 
 	// Check if status gets updated in the instance within the given timeout seconds.
+	// The controller retries failed topology lookups, e.g. while vCenter is
+	// slow or unreachable, so an error status doesn't fail the registration of
+	// the node until the timeout.
+	var lastErrorMessage string
 	for event := range watchCSINodeTopology.ResultChan() {
 		csiNodeTopologyInstance, ok := event.Object.(*csinodetopologyv1alpha1.CSINodeTopology)
 		if !ok {
@@ -637,12 +641,18 @@ func (volTopology *nodeVolumeTopology) GetNodeTopologyLabels(ctx context.Context
 			return accessibleTopology, nil
 		case csinodetopologyv1alpha1.CSINodeTopologyError:
 			// There was an error collecting topology information from nodes.
-			return nil, logger.LogNewErrorCodef(log, codes.Internal,
-				"failed to retrieve topology information for Node: %q. Error: %q", nodeInfo.NodeName,
-				csiNodeTopologyInstance.Status.ErrorMessage)
+			// Wait for the controller to retry.
+			lastErrorMessage = csiNodeTopologyInstance.Status.ErrorMessage
+			log.Warnf("failed to retrieve topology information for Node: %q, waiting for a retry. Error: %q",
+				nodeInfo.NodeName, lastErrorMessage)
 		}
 	}
 	// Timed out waiting for topology labels to be updated.
+	if lastErrorMessage != "" {
+		return nil, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to retrieve topology information for Node: %q. Error: %q", nodeInfo.NodeName,
+			lastErrorMessage)
+	}
 	return nil, logger.LogNewErrorCodef(log, codes.Internal,
 		"timed out while waiting for topology labels to be updated in %q CSINodeTopology instance.",
 		nodeInfo.NodeName)
@@ -757,17 +767,24 @@ func (volTopology *controllerVolumeTopology) GetSharedDatastoresInTopology(ctx c
 	)
 
 	// Fetch shared datastores for the preferred topology requirement.
+	// Preferred topologies are tried one at a time in the order given by the
+	// CO, so that volumes with delayed binding land on datastores accessible
+	// to the zone of the node selected by the scheduler.
 	if params.TopologyRequirement.GetPreferred() != nil {
 		log.Debugf("Using preferred topology")
-		sharedDatastores, err = volTopology.getSharedDatastoresInTopology(ctx,
-			params.TopologyRequirement.GetPreferred())
-		if err != nil {
-			log.Errorf("Error finding shared datastores using preferred topology: %+v",
-				params.TopologyRequirement.GetPreferred())
-			return nil, err
+		for _, topology := range params.TopologyRequirement.GetPreferred() {
+			sharedDatastores, err = volTopology.getSharedDatastoresInTopology(ctx,
+				[]*csi.Topology{topology})
+			if err != nil {
+				log.Errorf("Error finding shared datastores using preferred topology: %+v", topology)
+				return nil, err
+			}
+			if len(sharedDatastores) != 0 {
+				break
+			}
 		}
 	}
-	// If there are no shared datastores for the preferred topology requirement, fetch shared
+	// If there are no shared datastores for any of the preferred topologies, fetch shared
 	// datastores for the requisite topology requirement instead.
 	if len(sharedDatastores) == 0 && params.TopologyRequirement.GetRequisite() != nil {
 		log.Debugf("Using requisite topology")
@@ -837,7 +854,7 @@ func (volTopology *controllerVolumeTopology) getNodesMatchingTopologySegment(ctx
 			&nodeTopologyInstance)
 		if err != nil {
 			return nil, logger.LogNewErrorf(log, "failed to convert unstructured object %+v to "+
-				"CSINodeTopology instance. Error: %+v", val, err)
+				"CSINodeTopology instance. Error: %w", val, err)
 		}
 
 		// Check CSINodeTopology instance `Status` field for success.
@@ -896,7 +913,7 @@ func (volTopology *controllerVolumeTopology) GetTopologyInfoFromNodes(ctx contex
 		item, exists, err := nodeTopologyStore.GetByKey(nodeName)
 		if err != nil || !exists {
 			return nil, logger.LogNewErrorf(log, "failed to find a CSINodeTopology instance with name: %q. "+
-				"Error: %+v", nodeName, err)
+				"Error: %w", nodeName, err)
 		}
 
 		// Validate the object received.
@@ -905,7 +922,7 @@ func (volTopology *controllerVolumeTopology) GetTopologyInfoFromNodes(ctx contex
 			&nodeTopologyInstance)
 		if err != nil {
 			return nil, logger.LogNewErrorf(log, "failed to convert unstructured object %+v to "+
-				"CSINodeTopology instance. Error: %+v", item, err)
+				"CSINodeTopology instance. Error: %w", item, err)
 		}
 		// Check the status of CSINodeTopology instance.
 		if nodeTopologyInstance.Status.Status != csinodetopologyv1alpha1.CSINodeTopologySuccess {
@@ -946,7 +963,7 @@ func (volTopology *controllerVolumeTopology) GetTopologyInfoFromNodes(ctx contex
 		params.DatastoreURL, topologySegments)
 	if err != nil {
 		return nil, logger.LogNewErrorf(log, "failed to verify if all nodes in the topology segments "+
-			"retrieved are accessible to datastore %q. Error: %+v", params.DatastoreURL, err)
+			"retrieved are accessible to datastore %q. Error: %w", params.DatastoreURL, err)
 	}
 	log.Infof("Accessible topology calculated for datastore %q is %+v",
 		params.DatastoreURL, accessibleTopology)
@@ -1029,7 +1046,7 @@ func (volTopology *wcpControllerVolumeTopology) GetSharedDatastoresInTopology(ct
 		clusterMorefs, err := volTopology.getClustersMatchingTopologySegment(ctx, segments)
 		if err != nil {
 			return nil, logger.LogNewErrorf(log,
-				"failed to fetch clusters matching topology requirement. Error: %v", err)
+				"failed to fetch clusters matching topology requirement. Error: %w", err)
 		}
 		if len(clusterMorefs) == 0 {
 			log.Warnf("No clusters matched the topology requirement provided: %+v",
@@ -1042,7 +1059,7 @@ func (volTopology *wcpControllerVolumeTopology) GetSharedDatastoresInTopology(ct
 			accessibleDs, _, err := cnsvsphere.GetCandidateDatastoresInCluster(ctx, params.Vc, clusterMoref)
 			if err != nil {
 				return nil, logger.LogNewErrorf(log,
-					"failed to find candidate datastores to place volume in cluster %q. Error: %v",
+					"failed to find candidate datastores to place volume in cluster %q. Error: %w",
 					clusterMoref, err)
 			}
 			sharedDatastores = append(sharedDatastores, accessibleDs...)
@@ -0,0 +1,181 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+)
+
+const (
+	// EnvCompatibilityReport is the env variable holding the path of the
+	// compatibility report consumed by the controller.
+	EnvCompatibilityReport = "VSPHERE_CSI_COMPATIBILITY_REPORT"
+
+	// CompatibilityFeatureCreateDeleteVolume is the compatibility report
+	// feature for provisioning and deleting block volumes.
+	CompatibilityFeatureCreateDeleteVolume = "create-delete-volume"
+	// CompatibilityFeatureAttachDetachVolume is the compatibility report
+	// feature for attaching block volumes to, and detaching them from, nodes.
+	CompatibilityFeatureAttachDetachVolume = "attach-detach-volume"
+	// CompatibilityFeatureExpandVolume is the compatibility report feature for
+	// offline expansion of block volumes.
+	CompatibilityFeatureExpandVolume = "expand-volume"
+	// CompatibilityFeatureVolumeSnapshot is the compatibility report feature
+	// for creating and deleting snapshots of block volumes.
+	CompatibilityFeatureVolumeSnapshot = "volume-snapshot"
+)
+
+// compatibilityGatedCapabilities maps the compatibility report features to
+// the controller capabilities which are not reported if the feature is
+// failing on the vCenter version of the deployment. Features every deployment
+// relies on, like provisioning volumes, are reported but never gated.
+var compatibilityGatedCapabilities = map[string][]csi.ControllerServiceCapability_RPC_Type{
+	CompatibilityFeatureExpandVolume: {
+		csi.ControllerServiceCapability_RPC_EXPAND_VOLUME,
+	},
+	CompatibilityFeatureVolumeSnapshot: {
+		csi.ControllerServiceCapability_RPC_CREATE_DELETE_SNAPSHOT,
+		csi.ControllerServiceCapability_RPC_LIST_SNAPSHOTS,
+	},
+}
+
+// CompatibilityReport is the machine-readable report of the features found
+// working or failing on a matrix of vCenter and ESX versions.
+type CompatibilityReport struct {
+	Results []CompatibilityResult `json:"results"`
+}
+
+// CompatibilityResult holds the features found working or failing on a
+// vCenter version and the ESX versions of its hosts.
+type CompatibilityResult struct {
+	VCenterVersion string                          `json:"vcenterVersion"`
+	VCenterBuild   string                          `json:"vcenterBuild,omitempty"`
+	ESXVersions    []string                        `json:"esxVersions,omitempty"`
+	Features       map[string]FeatureCompatibility `json:"features"`
+}
+
+// FeatureCompatibility is the outcome of the compatibility check of a feature.
+type FeatureCompatibility struct {
+	Working bool `json:"working"`
+	// Error is the failure of the check, if the feature is not working.
+	Error string `json:"error,omitempty"`
+}
+
+// LoadCompatibilityReport reads the compatibility report at the given path.
+// An empty report is returned if the file does not exist.
+func LoadCompatibilityReport(path string) (*CompatibilityReport, error) {
+	report := &CompatibilityReport{}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return report, nil
+		}
+		return nil, fmt.Errorf("failed to read compatibility report %q: %w", path, err)
+	}
+	if err := json.Unmarshal(data, report); err != nil {
+		return nil, fmt.Errorf("failed to parse compatibility report %q: %w", path, err)
+	}
+	return report, nil
+}
+
+// Write writes the compatibility report to the given path.
+func (r *CompatibilityReport) Write(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode compatibility report: %w", err)
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write compatibility report %q: %w", path, err)
+	}
+	return nil
+}
+
+// SetResult adds the given result to the report, replacing the result of the
+// same vCenter version and build, if any. Results are kept sorted by vCenter
+// version and build so that reports of a matrix are stable.
+func (r *CompatibilityReport) SetResult(result CompatibilityResult) {
+	for i := range r.Results {
+		if r.Results[i].VCenterVersion == result.VCenterVersion && r.Results[i].VCenterBuild == result.VCenterBuild {
+			r.Results[i] = result
+			return
+		}
+	}
+	r.Results = append(r.Results, result)
+	sort.SliceStable(r.Results, func(i, j int) bool {
+		if r.Results[i].VCenterVersion != r.Results[j].VCenterVersion {
+			return r.Results[i].VCenterVersion < r.Results[j].VCenterVersion
+		}
+		return r.Results[i].VCenterBuild < r.Results[j].VCenterBuild
+	})
+}
+
+// IsFeatureFailing returns true if the feature was found failing on the given
+// vCenter version. Features which were not checked on the version are not
+// considered failing.
+func (r *CompatibilityReport) IsFeatureFailing(vcVersion string, feature string) bool {
+	if r == nil {
+		return false
+	}
+	for _, result := range r.Results {
+		if result.VCenterVersion != vcVersion {
+			continue
+		}
+		if compatibility, ok := result.Features[feature]; ok && !compatibility.Working {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterCompatibleControllerCapabilities returns the given controller
+// capabilities without the ones of features the compatibility report found
+// failing on the given vCenter version.
+func FilterCompatibleControllerCapabilities(ctx context.Context, report *CompatibilityReport, vcVersion string,
+	rpcTypes []csi.ControllerServiceCapability_RPC_Type) []csi.ControllerServiceCapability_RPC_Type {
+	log := logger.GetLogger(ctx)
+	if report == nil {
+		return rpcTypes
+	}
+	failing := make(map[csi.ControllerServiceCapability_RPC_Type]string)
+	for feature, gatedTypes := range compatibilityGatedCapabilities {
+		if !report.IsFeatureFailing(vcVersion, feature) {
+			continue
+		}
+		for _, rpcType := range gatedTypes {
+			failing[rpcType] = feature
+		}
+	}
+	var compatible []csi.ControllerServiceCapability_RPC_Type
+	for _, rpcType := range rpcTypes {
+		if feature, ok := failing[rpcType]; ok {
+			log.Warnf("Not reporting controller capability %q as %q feature is failing on vCenter version %q "+
+				"according to the compatibility report", rpcType.String(), feature, vcVersion)
+			continue
+		}
+		compatible = append(compatible, rpcType)
+	}
+	return compatible
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+)
+
+func TestCompatibilityReportRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "compatibility")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "report.json")
+
+	report, err := LoadCompatibilityReport(path)
+	if err != nil {
+		t.Fatalf("expected an empty report for a missing file, got %v", err)
+	}
+	report.SetResult(CompatibilityResult{
+		VCenterVersion: "7.0.3",
+		Features:       map[string]FeatureCompatibility{CompatibilityFeatureExpandVolume: {Working: true}},
+	})
+	report.SetResult(CompatibilityResult{
+		VCenterVersion: "6.7.0",
+		Features:       map[string]FeatureCompatibility{CompatibilityFeatureExpandVolume: {Error: "failed"}},
+	})
+	// The result of a version is replaced when the version is probed again.
+	report.SetResult(CompatibilityResult{
+		VCenterVersion: "7.0.3",
+		ESXVersions:    []string{"7.0.3"},
+		Features:       map[string]FeatureCompatibility{CompatibilityFeatureExpandVolume: {Working: true}},
+	})
+	if err := report.Write(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadCompatibilityReport(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(report, loaded) {
+		t.Fatalf("expected %+v, got %+v", report, loaded)
+	}
+	if len(loaded.Results) != 2 || loaded.Results[0].VCenterVersion != "6.7.0" {
+		t.Fatalf("expected 2 results sorted by version, got %+v", loaded.Results)
+	}
+}
+
+func TestFilterCompatibleControllerCapabilities(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	report := &CompatibilityReport{Results: []CompatibilityResult{{
+		VCenterVersion: "6.7.0",
+		Features: map[string]FeatureCompatibility{
+			CompatibilityFeatureCreateDeleteVolume: {Error: "failed"},
+			CompatibilityFeatureExpandVolume:       {Error: "failed"},
+			CompatibilityFeatureVolumeSnapshot:     {Working: true},
+		},
+	}}}
+	rpcTypes := []csi.ControllerServiceCapability_RPC_Type{
+		csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME,
+		csi.ControllerServiceCapability_RPC_EXPAND_VOLUME,
+		csi.ControllerServiceCapability_RPC_CREATE_DELETE_SNAPSHOT,
+	}
+	compatible := FilterCompatibleControllerCapabilities(ctx, report, "6.7.0", rpcTypes)
+	expected := []csi.ControllerServiceCapability_RPC_Type{
+		csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME,
+		csi.ControllerServiceCapability_RPC_CREATE_DELETE_SNAPSHOT,
+	}
+	if !reflect.DeepEqual(compatible, expected) {
+		t.Fatalf("expected %v, got %v", expected, compatible)
+	}
+	if compatible := FilterCompatibleControllerCapabilities(ctx, report, "7.0.3", rpcTypes); len(compatible) != 3 {
+		t.Fatalf("expected all capabilities on a version missing from the report, got %v", compatible)
+	}
+	if compatible := FilterCompatibleControllerCapabilities(ctx, nil, "6.7.0", rpcTypes); len(compatible) != 3 {
+		t.Fatalf("expected all capabilities without a report, got %v", compatible)
+	}
+}
@@ -29,6 +29,10 @@ const (
 	// TODO: will make the DefaultGbDiskSize configurable in the future.
 	DefaultGbDiskSize = int64(10)
 
+	// EphemeralVolumeNamePrefix is the prefix of the CNS volume names of CSI
+	// ephemeral inline volumes, which have no PV.
+	EphemeralVolumeNamePrefix = "ephemeral-"
+
 	// DiskTypeBlockVolume is the value for PersistentVolume's attribute "type".
 	DiskTypeBlockVolume = "vSphere CNS Block Volume"
 
@@ -79,6 +83,12 @@ const (
 	// Ext4FsType represents the default filesystem type for block volume.
 	Ext4FsType = "ext4"
 
+	// Ext3FsType represents ext3 filesystem type for block volume.
+	Ext3FsType = "ext3"
+
+	// XfsFsType represents xfs filesystem type for block volume.
+	XfsFsType = "xfs"
+
 	// NfsV4FsType represents nfs4 mount type.
 	NfsV4FsType = "nfs4"
 
@@ -111,6 +121,30 @@ const (
 	// Nfsv4AccessPoint is the access point of file volume.
 	Nfsv4AccessPoint = "Nfsv4AccessPoint"
 
+	// Nfsv3AccessPointKey is the key for NFSv3 access point.
+	Nfsv3AccessPointKey = "NFSv3"
+
+	// Nfsv3AccessPoint is the NFSv3 access point of file volume.
+	Nfsv3AccessPoint = "Nfsv3AccessPoint"
+
+	// NfsVersion41 represents the NFS v4.1 protocol version of file volumes.
+	NfsVersion41 = "4.1"
+
+	// NfsVersion3 represents the NFS v3 protocol version of file volumes.
+	NfsVersion3 = "3"
+
+	// NfsVersionAuto represents the negotiation of the NFS protocol version
+	// of file volumes, i.e. NFS v4.1 with a fall back to NFS v3.
+	NfsVersionAuto = "auto"
+
+	// DiskControllerPVSCSI represents the PVSCSI controllers block volumes
+	// are attached to by default.
+	DiskControllerPVSCSI = "pvscsi"
+
+	// DiskControllerNVMe represents the NVMe controllers block volumes can
+	// be attached to.
+	DiskControllerNVMe = "nvme"
+
 	// MinSupportedVCenterMajor is the minimum, major version of vCenter
 	// on which CNS is supported.
 	MinSupportedVCenterMajor int = 6
@@ -173,6 +207,23 @@ const (
 	// is created.
 	AttributeInitialVolumeFilepath = "initialvolumefilepath"
 
+	// AttributeDetachQuiesceDelay represents the minimum time in seconds for
+	// which detach of a volume is delayed, to let the node flush outstanding
+	// IO. It is only enforced while the node VM is powered on.
+	AttributeDetachQuiesceDelay = "detachquiescedelay"
+
+	// AttributeMountOptions represents the comma separated mount options the
+	// volume was created with.
+	AttributeMountOptions = "mountoptions"
+
+	// AttributeNfsVersion represents the NFS protocol version file volumes
+	// are mounted with: "4.1" (default), "3" or "auto".
+	AttributeNfsVersion = "nfsversion"
+
+	// AttributeDiskController represents the type of controller block volumes
+	// are attached to: "pvscsi" (default) or "nvme".
+	AttributeDiskController = "diskcontroller"
+
 	// DatastoreMigrationParam is used to supply datastore name for Volume
 	// provisioning.
 	DatastoreMigrationParam = "datastore-migrationparam"
@@ -213,9 +264,6 @@ const (
 	// supposed to provision a volume for this PVC.
 	AnnStorageProvisioner = "volume.kubernetes.io/storage-provisioner"
 
-	// vSphereCSIDriverName vSphere CSI driver name
-	VSphereCSIDriverName = "csi.vsphere.vmware.com"
-
 	// AnnDynamicallyProvisioned annotation is added to a PV that has been
 	// dynamically provisioned by Kubernetes. Its value is name of volume plugin
 	// that created the volume. It serves both user (to show where a PV comes
@@ -235,12 +283,38 @@ const (
 	// HostConfigStoragePriv is the privilege for file volumes.
 	HostConfigStoragePriv = "Host.Config.Storage"
 
+	// CnsSearchablePriv is the privilege to search the CNS volumes.
+	CnsSearchablePriv = "Cns.Searchable"
+
+	// StorageProfileViewPriv is the privilege to view the storage policies.
+	StorageProfileViewPriv = "StorageProfile.View"
+
+	// VMAddExistingDiskPriv is the privilege to attach volumes to a node VM.
+	VMAddExistingDiskPriv = "VirtualMachine.Config.AddExistingDisk"
+
+	// VMAddRemoveDevicePriv is the privilege to add and remove the devices of
+	// a node VM.
+	VMAddRemoveDevicePriv = "VirtualMachine.Config.AddRemoveDevice"
+
 	// AnnVolumeHealth is the key for HealthStatus annotation on volume claim.
 	AnnVolumeHealth = "volumehealth.storage.kubernetes.io/health"
 
 	// AnnFakeAttached is the key for fake attach annotation on volume claim.
 	AnnFakeAttached = "csi.vmware.com/fake-attached"
 
+	// TaintNodeOutOfService is the key of the taint marking a node as out of
+	// service after a non-graceful node shutdown.
+	TaintNodeOutOfService = "node.kubernetes.io/out-of-service"
+
+	// AnnVolumeDatastoreURL is the key for the annotation on volume holding
+	// the URL of the datastore the volume was relocated to.
+	AnnVolumeDatastoreURL = "cns.vmware.com/datastore-url"
+
+	// AnnRediscoverNodeTopology is the key for the annotation on a
+	// CSINodeTopology instance requesting the topology of its node VM to be
+	// discovered again, e.g. after the VM was migrated.
+	AnnRediscoverNodeTopology = "cns.vmware.com/rediscover-topology"
+
 	// VolHealthStatusAccessible is volume health status for accessible volume.
 	VolHealthStatusAccessible = "accessible"
 
@@ -259,6 +333,10 @@ const (
 	// The 128 size limit is specified by CNS QuerySnapshot API.
 	QuerySnapshotLimit = int64(128)
 
+	// QueryVolumeLimit is the maximum number of volumes that can be retrieved per QueryVolume call
+	// made by ListVolumes.
+	QueryVolumeLimit = int64(1000)
+
 	// VSphereCSISnapshotIdDelimiter is the delimiter for concatenating CNS VolumeID and CNS SnapshotID
 	VSphereCSISnapshotIdDelimiter = "+"
 
@@ -266,6 +344,16 @@ const (
 	// topology labels applied on the node by vSphere CSI driver.
 	TopologyLabelsDomain = "topology.csi.vmware.com"
 
+	// ValidatingWebhookConfigName is the name of the
+	// ValidatingWebhookConfiguration of the vSphere CSI webhook. The CRDs
+	// converted by the webhook trust its CA bundle.
+	ValidatingWebhookConfigName = "validation.csi.vsphere.vmware.com"
+
+	// NodeConditionNodePluginVersion is the Node condition type set by the
+	// node plugin to publish its version, as the condition message, for the
+	// version skew check of the syncer.
+	NodeConditionNodePluginVersion = "VSphereCSINodePluginVersion"
+
 	//AnnGuestClusterRequestedTopology is the key for guest cluster requested topology
 	AnnGuestClusterRequestedTopology = "csi.vsphere.volume-requested-topology"
 
@@ -283,6 +371,11 @@ const (
 	// PVtoBackingDiskObjectIdSupportedVCenterPatch is the minimum patch version of vCenter
 	// on which PV to BackingDiskObjectId mapping feature is supported.
 	PVtoBackingDiskObjectIdSupportedVCenterPatch int = 2
+
+	// MaxDetachQuiesceDelay is the maximum detach quiesce delay. It is kept
+	// well below the 300s timeout of the csi-attacher, so that detach
+	// completes before the attacher gives up and retries.
+	MaxDetachQuiesceDelay = 120 * time.Second
 )
 
 // Supported container orchestrators.
@@ -346,4 +439,149 @@ const (
 	ListVolumes = "list-volumes"
 	// PVtoBackingDiskObjectIdMapping is the feature to support pv to backingDiskObjectId mapping on vSphere CSI driver.
 	PVtoBackingDiskObjectIdMapping = "pv-to-backingdiskobjectid-mapping"
+	// StorageQuotaValidation is the feature to validate PVC requests against
+	// the CnsStorageQuota defined in their namespace.
+	StorageQuotaValidation = "storage-quota-validation"
+	// FailedVolumeRecovery is the feature to recover PVs marked Failed during
+	// a temporary VC outage once their backing volume is healthy again.
+	FailedVolumeRecovery = "failed-volume-recovery"
+	// StorageClassParamValidation is the feature to reject StorageClasses
+	// with unknown or conflicting vSphere CSI parameters in the webhook.
+	StorageClassParamValidation = "storageclass-param-validation"
+	// StorageClassDefaults is the feature to inject cluster default fstype
+	// and storage policy into vSphere CSI StorageClasses omitting them.
+	StorageClassDefaults = "storageclass-defaults"
+	// VolumeOwnerLookup is the feature to serve the Kubernetes objects using
+	// a volume handle from the syncer.
+	VolumeOwnerLookup = "volume-owner-lookup"
+	// AttachDetachBatching is the feature to coalesce concurrent attach and
+	// detach requests for a node VM into a single VM reconfigure.
+	AttachDetachBatching = "attach-detach-batching"
+	// VolumeTagSync is the feature to reflect vSphere tags attached to FCDs
+	// as labels on the corresponding PVs.
+	VolumeTagSync = "volume-tag-sync"
+	// DetachQuiesce is the feature to delay the detach of a volume from a
+	// powered on node VM by a quiesce delay configurable per StorageClass.
+	DetachQuiesce = "detach-quiesce"
+	// VanillaVolumeRegistration is the feature to import existing FCDs and
+	// vSAN file shares into Vanilla clusters using CnsRegisterVolume.
+	VanillaVolumeRegistration = "vanilla-volume-registration"
+	// VolumeRelocation is the feature to relocate the volumes of Vanilla
+	// clusters to another datastore using CnsVolumeRelocate.
+	VolumeRelocation = "volume-relocation"
+	// StoragePolicyCompliance is the feature to periodically check the storage
+	// policy compliance of volumes and report non-compliant volumes.
+	StoragePolicyCompliance = "storage-policy-compliance"
+	// VolumePolicyUpdate is the feature to change the storage policy of
+	// existing volumes through a PVC annotation.
+	VolumePolicyUpdate = "volume-policy-update"
+	// VolumeEncryption is the feature to validate VM Encryption storage
+	// policies on provisioning and annotate PVs with their encryption status.
+	VolumeEncryption = "volume-encryption"
+	// VolumeCountMetrics is the feature to publish the number of volumes by
+	// volume type, storage policy, datastore type and cluster flavor.
+	VolumeCountMetrics = "volume-count-metrics"
+	// EphemeralInlineVolume is the feature to support CSI ephemeral inline
+	// volumes, provisioned by the node plugin for the lifetime of a pod.
+	EphemeralInlineVolume = "ephemeral-inline-volume"
+	// StartupConsistencyAudit is the feature to audit the consistency of PVs,
+	// VolumeAttachments and CNS volumes on syncer startup.
+	StartupConsistencyAudit = "startup-consistency-audit"
+	// GenericEphemeralVolumeMetadata is the feature to record the pod owning
+	// the PVC of a generic ephemeral volume in the CNS volume metadata.
+	GenericEphemeralVolumeMetadata = "generic-ephemeral-volume-metadata"
+	// NodeVolumeCondition is the feature to report the condition of volumes,
+	// based on the state of their device, in NodeGetVolumeStats.
+	NodeVolumeCondition = "node-volume-condition"
+	// FilesystemHealthMonitor is the feature to monitor the file systems of
+	// staged volumes for read-only remounts and I/O errors.
+	FilesystemHealthMonitor = "filesystem-health-monitor"
+	// StaleReleasedVolumeCleaner is the feature to report, or clean up the CNS
+	// metadata of, PVs staying in Released phase for too long.
+	StaleReleasedVolumeCleaner = "stale-released-volume-cleaner"
+	// NodeVolumeRecovery is the feature to remount the staged volumes which
+	// went stale once their device is accessible again after an outage.
+	NodeVolumeRecovery = "node-volume-recovery"
+	// PodVolumeUsageAnnotations is the feature to annotate pods with the
+	// storage provisioned and consumed by the vSphere volumes they reference.
+	PodVolumeUsageAnnotations = "pod-volume-usage-annotations"
+	// FileAccessConfigAutomation is the feature to create and delete the
+	// CnsFileAccessConfig instances of guest clusters following the placement
+	// of the pods using file volumes.
+	FileAccessConfigAutomation = "file-access-config-automation"
+	// PermissionsMonitor is the feature to periodically check the privileges
+	// of the VC user and report the privileges revoked since they were granted.
+	PermissionsMonitor = "permissions-monitor"
+	// DatastoreCapacityMetrics is the feature to export the capacity and free
+	// space of the datastores holding the volumes of the cluster as metrics.
+	DatastoreCapacityMetrics = "datastore-capacity-metrics"
+	// StoragePolicyDeletionCheck is the feature to periodically check that
+	// the storage policies of the StorageClasses exist, and report the
+	// StorageClasses whose storage policy was deleted.
+	StoragePolicyDeletionCheck = "storage-policy-deletion-check"
+	// NodeDrainDetach is the feature to detach the volumes of the pods
+	// evicted from cordoned nodes in parallel batches, ahead of the
+	// attach/detach controller.
+	NodeDrainDetach = "node-drain-detach"
+	// CRDConversionWebhook is the feature to serve the v1beta1 version of the
+	// cns.vmware.com CRDs, converted from and to their v1alpha1 storage
+	// version by the conversion webhook of the vSphere CSI webhook.
+	CRDConversionWebhook = "crd-conversion-webhook"
+	// VersionSkewCheck is the feature to periodically check the version skew
+	// between the controller and the node plugins, and the versions served
+	// by the cns.vmware.com CRDs, and report the unsupported ones.
+	VersionSkewCheck = "version-skew-check"
+	// TopologyFullSyncValidation is the feature to check during full sync
+	// that the node affinity of the PVs still matches the accessibility of
+	// the datastores of their volumes, and report the stale ones.
+	TopologyFullSyncValidation = "topology-full-sync-validation"
+	// StorageCapacityTracking is the feature to periodically publish the
+	// capacity available to the StorageClasses of the driver in each topology
+	// segment as CSIStorageCapacity objects.
+	StorageCapacityTracking = "storage-capacity-tracking"
+	// ControllerVolumeCondition is the feature to report the condition of
+	// volumes, based on their CNS health status, in ControllerGetVolume and
+	// ListVolumes.
+	ControllerVolumeCondition = "controller-volume-condition"
+	// NodeAttachLimit is the feature to report the maximum number of volumes
+	// which can be attached to the node VMs, based on their hardware version
+	// and PVSCSI controllers, in NodeGetInfo.
+	NodeAttachLimit = "node-attach-limit"
+	// PVSCSIControllerHotAdd is the feature to hot-add a PVSCSI controller to
+	// the node VMs whose PVSCSI controllers are full when attaching a volume.
+	PVSCSIControllerHotAdd = "pvscsi-controller-hot-add"
+	// NVMeDiskController is the feature to attach block volumes to NVMe
+	// controllers of the node VMs, with the diskcontroller StorageClass
+	// parameter.
+	NVMeDiskController = "nvme-disk-controller"
+	// NodeRegistrySync is the feature to register the Nodes missing from the
+	// node manager of the syncer, and delete the CSINodeTopology instances of
+	// the deleted Nodes.
+	NodeRegistrySync = "node-registry-sync"
+	// NodeFailureDetach is the feature to detach the volumes of the nodes
+	// NotReady for longer than a timeout whose VM is powered off.
+	NodeFailureDetach = "node-failure-detach"
+	// NonGracefulNodeShutdown is the feature to detach the volumes of the
+	// nodes with the out-of-service taint right away, without the detach
+	// quiesce delay and batching.
+	NonGracefulNodeShutdown = "non-graceful-node-shutdown"
+	// VMMigrationEventWatcher is the feature to update the datastore of the
+	// volumes and the topology of the node VMs on the VM migration events of
+	// vCenter.
+	VMMigrationEventWatcher = "vm-migration-event-watcher"
+	// DatastoreAccessibilityWatcher is the feature to refresh the volume
+	// health and the storage capacity on the datastore accessibility events
+	// of vCenter.
+	DatastoreAccessibilityWatcher = "datastore-accessibility-watcher"
+	// SupervisorQuotaValidation is the feature to validate the storage quota
+	// of the supervisor namespace in the CreateVolume calls of guest clusters,
+	// before the supervisor PVC is created.
+	SupervisorQuotaValidation = "supervisor-quota-validation"
+	// GuestPVProtection is the feature to protect the supervisor PVCs backing
+	// the PVs of guest clusters from deletion while the guest PVs exist.
+	GuestPVProtection = "guest-pv-protection"
+	// CnsVolumeMetadataGC is the feature to garbage collect the
+	// CnsVolumeMetadata instances of the deleted guest clusters in the
+	// supervisor cluster.
+	CnsVolumeMetadataGC = "cnsvolumemetadata-gc"
 )
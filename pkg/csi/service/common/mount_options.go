@@ -0,0 +1,179 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/golang/protobuf/proto"
+)
+
+// extMountOptions are the mount options, or prefixes of mount options taking
+// a value, only supported by ext3 and ext4 file systems.
+var extMountOptions = []string{"data=", "errors=", "commit=", "journal_checksum", "journal_async_commit",
+	"barrier=", "nodelalloc", "delalloc", "stripe=", "resuid=", "resgid=", "user_xattr", "nouser_xattr"}
+
+// xfsMountOptions are the mount options, or prefixes of mount options taking
+// a value, only supported by xfs file systems.
+var xfsMountOptions = []string{"nouuid", "inode32", "inode64", "allocsize=", "logbufs=", "logbsize=",
+	"logdev=", "rtdev=", "largeio", "nolargeio", "swalloc", "noswalloc", "ikeep", "noikeep",
+	"pquota", "prjquota", "uquota", "gquota", "swidth=", "sunit=", "wsync"}
+
+// hasMountOption returns true if the given mount flags include the given
+// mount option, or a value of it if the option ends with "=".
+func hasMountOption(mntFlags []string, option string) bool {
+	for _, flag := range mntFlags {
+		if flag == option || (strings.HasSuffix(option, "=") && strings.HasPrefix(flag, option)) {
+			return true
+		}
+	}
+	return false
+}
+
+// getNfsVersMountOption returns the value of the NFS version mount option of
+// the given mount flags, or an empty string if they have none.
+func getNfsVersMountOption(mntFlags []string) string {
+	for _, flag := range mntFlags {
+		if strings.HasPrefix(flag, "vers=") || strings.HasPrefix(flag, "nfsvers=") {
+			return flag[strings.Index(flag, "=")+1:]
+		}
+	}
+	return ""
+}
+
+// ValidateFsMountFlags checks that the given mount flags are supported by the
+// file system fsType: block volume file systems don't support the mount
+// options of the other ones, and file volumes are only mounted with NFS v3 or
+// NFS v4.
+func ValidateFsMountFlags(fsType string, mntFlags []string) error {
+	var unsupported []string
+	switch fsType {
+	case XfsFsType:
+		unsupported = extMountOptions
+	case Ext3FsType, Ext4FsType:
+		unsupported = xfsMountOptions
+	case NfsV4FsType, NfsFsType:
+		if vers := getNfsVersMountOption(mntFlags); vers != "" && vers != NfsVersion3 &&
+			!strings.HasPrefix(vers, "4") {
+			return fmt.Errorf("NFS version %q is not supported, file volumes are mounted with NFS v3 or NFS v4",
+				vers)
+		}
+		if hasMountOption(mntFlags, "hard") && hasMountOption(mntFlags, "soft") {
+			return fmt.Errorf("mount options \"hard\" and \"soft\" are mutually exclusive")
+		}
+		return nil
+	default:
+		return nil
+	}
+	for _, flag := range mntFlags {
+		for _, option := range unsupported {
+			if flag == option || (strings.HasSuffix(option, "=") && strings.HasPrefix(flag, option)) {
+				return fmt.Errorf("mount option %q is not supported by %s file systems", flag, fsType)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateMountFlags checks that the mount flags of the given volume
+// capability make sense for the volume: "ro" and "rw" must agree with the
+// access mode of the capability, and the other mount options must be
+// supported by its file system.
+func ValidateMountFlags(ctx context.Context, volCap *csi.VolumeCapability) error {
+	mntFlags := volCap.GetMount().GetMountFlags()
+	if len(mntFlags) == 0 {
+		return nil
+	}
+	ro := hasMountOption(mntFlags, "ro")
+	rw := hasMountOption(mntFlags, "rw")
+	if ro && rw {
+		return fmt.Errorf("mount options \"ro\" and \"rw\" are mutually exclusive")
+	}
+	accessMode := volCap.GetAccessMode().GetMode()
+	if ro && !IsVolumeReadOnly(volCap) {
+		return fmt.Errorf("mount option \"ro\" conflicts with access mode %s", accessMode)
+	}
+	if rw && IsVolumeReadOnly(volCap) {
+		return fmt.Errorf("mount option \"rw\" conflicts with access mode %s", accessMode)
+	}
+	return ValidateFsMountFlags(GetVolumeCapabilityFsType(ctx, volCap), mntFlags)
+}
+
+// GetMountFlags returns the mount flags of the first mount volume capability
+// of the given capabilities.
+func GetMountFlags(volCaps []*csi.VolumeCapability) []string {
+	for _, volCap := range volCaps {
+		if mountVol := volCap.GetMount(); mountVol != nil {
+			return mountVol.GetMountFlags()
+		}
+	}
+	return nil
+}
+
+// SetMountOptionsAttribute records the mount flags of the given capabilities
+// in the given volume context, so that they are applied on the nodes even if
+// the PersistentVolume doesn't carry them.
+func SetMountOptionsAttribute(volCaps []*csi.VolumeCapability, attributes map[string]string) {
+	if mntFlags := GetMountFlags(volCaps); len(mntFlags) > 0 {
+		attributes[AttributeMountOptions] = strings.Join(mntFlags, ",")
+	}
+}
+
+// ApplyMountOptionsAttribute returns the given mount volume capability with
+// the mount flags recorded in the volume context if it has none. Mount flags
+// of the capability, i.e. the mount options of the PersistentVolume, take
+// precedence. The given capability is not modified.
+func ApplyMountOptionsAttribute(volCap *csi.VolumeCapability, volumeContext map[string]string) *csi.VolumeCapability {
+	mountOptions := volumeContext[AttributeMountOptions]
+	if volCap.GetMount() == nil || len(volCap.GetMount().GetMountFlags()) > 0 || mountOptions == "" {
+		return volCap
+	}
+	volCap = proto.Clone(volCap).(*csi.VolumeCapability)
+	volCap.GetMount().MountFlags = strings.Split(mountOptions, ",")
+	return volCap
+}
+
+// ParseNfsVersion parses the value of the nfsversion StorageClass parameter.
+func ParseNfsVersion(value string) (string, error) {
+	nfsVersion := strings.ToLower(strings.TrimSpace(value))
+	switch nfsVersion {
+	case NfsVersion41, NfsVersion3, NfsVersionAuto:
+		return nfsVersion, nil
+	}
+	return "", fmt.Errorf("invalid value %q for param %q, expected %q, %q or %q", value, AttributeNfsVersion,
+		NfsVersion41, NfsVersion3, NfsVersionAuto)
+}
+
+// GetNfsVersion returns the NFS protocol version a file volume with the given
+// volume context and mount flags is mounted with. The version mount option,
+// i.e. the mount options of the PersistentVolume, takes precedence over the
+// version recorded in the volume context, and NFS v4.1 is used by default.
+func GetNfsVersion(volumeContext map[string]string, mntFlags []string) string {
+	if vers := getNfsVersMountOption(mntFlags); vers != "" {
+		if vers == NfsVersion3 {
+			return NfsVersion3
+		}
+		return NfsVersion41
+	}
+	if nfsVersion, err := ParseNfsVersion(volumeContext[AttributeNfsVersion]); err == nil {
+		return nfsVersion
+	}
+	return NfsVersion41
+}
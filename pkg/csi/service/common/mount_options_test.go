@@ -0,0 +1,135 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+)
+
+func TestValidateFsMountFlags(t *testing.T) {
+	tests := []struct {
+		fsType   string
+		mntFlags []string
+		valid    bool
+	}{
+		{"xfs", []string{"noatime", "inode64", "logbufs=8"}, true},
+		{"xfs", []string{"data=ordered"}, false},
+		{"ext4", []string{"noatime", "data=ordered", "errors=remount-ro"}, true},
+		{"ext4", []string{"nouuid"}, false},
+		{"ext3", []string{"allocsize=64k"}, false},
+		{"nfs4", []string{"nouuid"}, true},
+		{"nfs4", []string{"vers=4.2", "soft"}, true},
+		{"nfs4", []string{"vers=3"}, true},
+		{"nfs", []string{"nfsvers=3", "hard"}, true},
+		{"nfs4", []string{"vers=2"}, false},
+		{"nfs4", []string{"hard", "soft"}, false},
+	}
+	for _, test := range tests {
+		err := ValidateFsMountFlags(test.fsType, test.mntFlags)
+		if (err == nil) != test.valid {
+			t.Errorf("%s with mount flags %v: expected valid %v, got error %v", test.fsType, test.mntFlags,
+				test.valid, err)
+		}
+	}
+}
+
+func newMountVolumeCapability(mode csi.VolumeCapability_AccessMode_Mode, fsType string,
+	mntFlags ...string) *csi.VolumeCapability {
+	return &csi.VolumeCapability{
+		AccessType: &csi.VolumeCapability_Mount{
+			Mount: &csi.VolumeCapability_MountVolume{FsType: fsType, MountFlags: mntFlags},
+		},
+		AccessMode: &csi.VolumeCapability_AccessMode{Mode: mode},
+	}
+}
+
+func TestValidateMountFlags(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tests := []struct {
+		volCap *csi.VolumeCapability
+		valid  bool
+	}{
+		{newMountVolumeCapability(csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER, "ext4", "noatime"), true},
+		{newMountVolumeCapability(csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER, "ext4", "rw"), true},
+		{newMountVolumeCapability(csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER, "ext4", "ro"), false},
+		{newMountVolumeCapability(csi.VolumeCapability_AccessMode_SINGLE_NODE_READER_ONLY, "ext4", "ro"), true},
+		{newMountVolumeCapability(csi.VolumeCapability_AccessMode_SINGLE_NODE_READER_ONLY, "ext4", "rw"), false},
+		{newMountVolumeCapability(csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY, "", "ro", "rw"), false},
+		{newMountVolumeCapability(csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER, "", "vers=3"), true},
+		{newMountVolumeCapability(csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER, "", "vers=2"), false},
+		{newMountVolumeCapability(csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER, "", "inode64"), false},
+	}
+	for _, test := range tests {
+		err := ValidateMountFlags(ctx, test.volCap)
+		if (err == nil) != test.valid {
+			t.Errorf("%v: expected valid %v, got error %v", test.volCap, test.valid, err)
+		}
+	}
+}
+
+func TestMountOptionsAttribute(t *testing.T) {
+	volCaps := []*csi.VolumeCapability{
+		newMountVolumeCapability(csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER, "ext4", "noatime", "discard"),
+	}
+	attributes := make(map[string]string)
+	SetMountOptionsAttribute(volCaps, attributes)
+	if attributes[AttributeMountOptions] != "noatime,discard" {
+		t.Fatalf("unexpected mount options attribute %q", attributes[AttributeMountOptions])
+	}
+
+	// The mount options of the volume context apply to a capability without
+	// mount flags, without modifying it.
+	volCap := newMountVolumeCapability(csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER, "ext4")
+	applied := ApplyMountOptionsAttribute(volCap, attributes)
+	if !reflect.DeepEqual(applied.GetMount().GetMountFlags(), []string{"noatime", "discard"}) {
+		t.Errorf("expected the mount options of the volume context, got %v", applied.GetMount().GetMountFlags())
+	}
+	if len(volCap.GetMount().GetMountFlags()) != 0 {
+		t.Errorf("expected the capability not to be modified, got %v", volCap.GetMount().GetMountFlags())
+	}
+	// The mount flags of the capability take precedence.
+	applied = ApplyMountOptionsAttribute(volCaps[0], map[string]string{AttributeMountOptions: "sync"})
+	if !reflect.DeepEqual(applied.GetMount().GetMountFlags(), []string{"noatime", "discard"}) {
+		t.Errorf("expected the mount flags of the capability, got %v", applied.GetMount().GetMountFlags())
+	}
+}
+
+func TestGetNfsVersion(t *testing.T) {
+	tests := []struct {
+		volumeContext map[string]string
+		mntFlags      []string
+		expected      string
+	}{
+		{nil, nil, NfsVersion41},
+		{map[string]string{AttributeNfsVersion: "3"}, nil, NfsVersion3},
+		{map[string]string{AttributeNfsVersion: "auto"}, []string{"hard"}, NfsVersionAuto},
+		{map[string]string{AttributeNfsVersion: "invalid"}, nil, NfsVersion41},
+		{map[string]string{AttributeNfsVersion: "auto"}, []string{"nfsvers=3"}, NfsVersion3},
+		{map[string]string{AttributeNfsVersion: "3"}, []string{"vers=4.1"}, NfsVersion41},
+	}
+	for _, test := range tests {
+		if nfsVersion := GetNfsVersion(test.volumeContext, test.mntFlags); nfsVersion != test.expected {
+			t.Errorf("volume context %v and mount flags %v: expected NFS version %q, got %q",
+				test.volumeContext, test.mntFlags, test.expected, nfsVersion)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
+
+	cnsvolume "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/volume"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	csifault "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/fault"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+)
+
+const (
+	// nvmeControllerHardwareVersion is the first hardware version supporting
+	// NVMe controllers.
+	nvmeControllerHardwareVersion = 13
+	// nvmeNamespacesPerController is the number of namespaces, i.e. disks,
+	// of an NVMe controller.
+	nvmeNamespacesPerController = 15
+	// maxNVMeControllersPerVM is the maximum number of NVMe controllers of a
+	// VM.
+	maxNVMeControllersPerVM = 4
+)
+
+// nvmeControllerAttachLock serializes the attach of volumes to NVMe
+// controllers, so that concurrent attaches don't pick the same unit number.
+var nvmeControllerAttachLock sync.Mutex
+
+// ParseDiskController parses the value of the diskcontroller StorageClass
+// parameter.
+func ParseDiskController(value string) (string, error) {
+	diskController := strings.ToLower(strings.TrimSpace(value))
+	switch diskController {
+	case DiskControllerPVSCSI, DiskControllerNVMe:
+		return diskController, nil
+	}
+	return "", fmt.Errorf("invalid value %q for param %q, expected %q or %q", value, AttributeDiskController,
+		DiskControllerPVSCSI, DiskControllerNVMe)
+}
+
+// getFreeNVMeUnit returns the key of an NVMe controller among the given
+// devices of a VM with a free unit, and the free unit number. found is false
+// if all the NVMe controllers are full.
+func getFreeNVMeUnit(devices object.VirtualDeviceList) (controllerKey int32, unitNumber int32, found bool) {
+	for _, controller := range devices.SelectByType((*types.VirtualNVMEController)(nil)) {
+		key := controller.GetVirtualDevice().Key
+		usedUnits := make(map[int32]bool)
+		for _, device := range devices {
+			virtualDevice := device.GetVirtualDevice()
+			if virtualDevice.ControllerKey == key && virtualDevice.UnitNumber != nil {
+				usedUnits[*virtualDevice.UnitNumber] = true
+			}
+		}
+		for unit := int32(0); unit < nvmeNamespacesPerController; unit++ {
+			if !usedUnits[unit] {
+				return key, unit, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// AttachVolumeToNVMeController attaches the given volume to an NVMe
+// controller of the given VM, hot-adding an NVMe controller if the VM has
+// none with a free unit. It returns the NVMe format UUID of the disk, and the
+// fault type on failure.
+func AttachVolumeToNVMeController(ctx context.Context, manager *Manager, vm *vsphere.VirtualMachine,
+	volumeID string) (string, string, error) {
+	log := logger.GetLogger(ctx)
+	nvmeControllerAttachLock.Lock()
+	defer nvmeControllerAttachLock.Unlock()
+
+	diskUUID, err := cnsvolume.IsDiskAttached(ctx, vm, volumeID, true)
+	if err != nil {
+		return "", csifault.CSIInternalFault, err
+	}
+	if diskUUID != "" {
+		log.Infof("Volume %q is already attached to VM %v with diskUUID %q", volumeID, vm, diskUUID)
+		return diskUUID, "", nil
+	}
+	hardwareVersion, err := vm.GetHardwareVersion(ctx)
+	if err != nil {
+		return "", csifault.CSIInternalFault, err
+	}
+	if !isHardwareVersionAtLeast(hardwareVersion, nvmeControllerHardwareVersion) {
+		return "", csifault.CSIInvalidArgumentFault, fmt.Errorf("hardware version %q of VM %v doesn't support "+
+			"NVMe controllers, vmx-%d or later is required", hardwareVersion, vm, nvmeControllerHardwareVersion)
+	}
+	devices, err := vm.Device(ctx)
+	if err != nil {
+		log.Errorf("failed to get the devices of VM %v. err: %v", vm, err)
+		return "", csifault.CSIInternalFault, err
+	}
+	controllerKey, unitNumber, found := getFreeNVMeUnit(devices)
+	if !found {
+		nvmeControllers := devices.SelectByType((*types.VirtualNVMEController)(nil))
+		if len(nvmeControllers) >= maxNVMeControllersPerVM {
+			return "", csifault.CSIResourceExhaustedFault, fmt.Errorf("the %d NVMe controllers of VM %v are full",
+				len(nvmeControllers), vm)
+		}
+		controller, err := devices.CreateNVMEController()
+		if err != nil {
+			log.Errorf("failed to create NVMe controller spec for VM %v. err: %v", vm, err)
+			return "", csifault.CSIInternalFault, err
+		}
+		log.Infof("VM %v has no NVMe controller with a free unit left. Hot-adding an NVMe controller.", vm)
+		if err := vm.AddDevice(ctx, controller); err != nil {
+			log.Errorf("failed to hot-add NVMe controller to VM %v. err: %v", vm, err)
+			return "", csifault.CSIInternalFault, err
+		}
+		if devices, err = vm.Device(ctx); err != nil {
+			log.Errorf("failed to get the devices of VM %v. err: %v", vm, err)
+			return "", csifault.CSIInternalFault, err
+		}
+		if controllerKey, unitNumber, found = getFreeNVMeUnit(devices); !found {
+			return "", csifault.CSIInternalFault, fmt.Errorf("failed to find the NVMe controller hot-added "+
+				"to VM %v", vm)
+		}
+	}
+
+	volume, err := QueryVolumeByID(ctx, manager.VolumeManager, volumeID)
+	if err != nil {
+		if err == ErrNotFound {
+			return "", csifault.CSINotFoundFault, fmt.Errorf("volume %q not found", volumeID)
+		}
+		return "", csifault.CSIInternalFault, err
+	}
+	datastore, err := vm.Datacenter.GetDatastoreByURL(ctx, volume.DatastoreUrl)
+	if err != nil {
+		log.Errorf("failed to get datastore %q of volume %q. err: %v", volume.DatastoreUrl, volumeID, err)
+		return "", csifault.CSIInternalFault, err
+	}
+	log.Infof("Attaching volume %q to unit %d of NVMe controller %d of VM %v", volumeID, unitNumber,
+		controllerKey, vm)
+	if err := vm.AttachDisk(ctx, volumeID, datastore.Datastore, controllerKey, unitNumber); err != nil {
+		log.Errorf("failed to attach volume %q to NVMe controller of VM %v. err: %v", volumeID, vm, err)
+		return "", csifault.CSIInternalFault, err
+	}
+	diskUUID, err = cnsvolume.IsDiskAttached(ctx, vm, volumeID, true)
+	if err != nil {
+		return "", csifault.CSIInternalFault, err
+	}
+	if diskUUID == "" {
+		return "", csifault.CSIInternalFault, fmt.Errorf("volume %q isn't attached to VM %v after the attach",
+			volumeID, vm)
+	}
+	log.Infof("Attached volume %q to NVMe controller of VM %v with diskUUID %q", volumeID, vm, diskUUID)
+	return diskUUID, "", nil
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestGetFreeNVMeUnit(t *testing.T) {
+	newDisk := func(controllerKey int32, unitNumber int32) *types.VirtualDisk {
+		disk := &types.VirtualDisk{}
+		disk.ControllerKey = controllerKey
+		disk.UnitNumber = &unitNumber
+		return disk
+	}
+	pvscsiController := &types.ParaVirtualSCSIController{}
+	pvscsiController.Key = 1000
+	nvmeController0 := &types.VirtualNVMEController{}
+	nvmeController0.Key = 31000
+	nvmeController1 := &types.VirtualNVMEController{}
+	nvmeController1.Key = 31001
+
+	devices := object.VirtualDeviceList{pvscsiController, newDisk(1000, 0)}
+	if _, _, found := getFreeNVMeUnit(devices); found {
+		t.Errorf("expected no free unit without NVMe controller")
+	}
+	devices = append(devices, nvmeController0, newDisk(31000, 0), newDisk(31000, 2))
+	if key, unit, found := getFreeNVMeUnit(devices); !found || key != 31000 || unit != 1 {
+		t.Errorf("expected unit 1 of controller 31000, got unit %d of controller %d, found %t", unit, key, found)
+	}
+	for unit := int32(1); unit < nvmeNamespacesPerController; unit++ {
+		if unit != 2 {
+			devices = append(devices, newDisk(31000, unit))
+		}
+	}
+	if _, _, found := getFreeNVMeUnit(devices); found {
+		t.Errorf("expected no free unit on a full NVMe controller")
+	}
+	devices = append(devices, nvmeController1)
+	if key, unit, found := getFreeNVMeUnit(devices); !found || key != 31001 || unit != 0 {
+		t.Errorf("expected unit 0 of controller 31001, got unit %d of controller %d, found %t", unit, key, found)
+	}
+}
+
+func TestParseDiskController(t *testing.T) {
+	tests := map[string]string{
+		"pvscsi":   DiskControllerPVSCSI,
+		" NVMe ":   DiskControllerNVMe,
+		"nvme":     DiskControllerNVMe,
+		"lsilogic": "",
+		"":         "",
+	}
+	for value, expected := range tests {
+		diskController, err := ParseDiskController(value)
+		if expected == "" && err == nil {
+			t.Errorf("expected error for value %q", value)
+		}
+		if diskController != expected {
+			t.Errorf("expected disk controller %q for value %q, got %q", expected, value, diskController)
+		}
+	}
+}
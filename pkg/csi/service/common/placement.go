@@ -0,0 +1,141 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"sort"
+	"strings"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	"golang.org/x/net/context"
+
+	cnsvolume "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/volume"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+)
+
+const (
+	// DatastorePlacementStrategyBalanced spreads volumes across the candidate
+	// shared datastores based on their free capacity and the number of
+	// volumes already provisioned on them.
+	DatastorePlacementStrategyBalanced = "balanced"
+
+	// freeSpaceWeight is the weight given to the free capacity of a datastore
+	// while computing its placement score.
+	freeSpaceWeight = 0.5
+	// volumeCountWeight is the weight given to the number of volumes
+	// provisioned on a datastore while computing its placement score.
+	volumeCountWeight = 0.5
+)
+
+// datastorePlacementScore holds the placement score of a candidate datastore.
+type datastorePlacementScore struct {
+	datastore   *vsphere.DatastoreInfo
+	freeSpace   int64
+	volumeCount int
+	score       float64
+}
+
+// RankDatastoresForPlacement returns the given datastores ordered by their
+// placement score, best candidate first. Datastores with more free capacity
+// and fewer provisioned CNS volumes score higher.
+func RankDatastoresForPlacement(ctx context.Context, volumeManager cnsvolume.Manager,
+	datastores []*vsphere.DatastoreInfo) ([]*vsphere.DatastoreInfo, error) {
+	log := logger.GetLogger(ctx)
+	if len(datastores) < 2 {
+		return datastores, nil
+	}
+	volumeCounts, err := getVolumeCountPerDatastore(ctx, volumeManager, datastores)
+	if err != nil {
+		log.Errorf("failed to get volume count for datastores %v. Err: %v", datastores, err)
+		return nil, err
+	}
+	ranked := scoreDatastores(datastores, volumeCounts)
+	for _, candidate := range ranked {
+		log.Debugf("Datastore %q placement score: %f, free space: %d, volume count: %d",
+			candidate.datastore.Info.Url, candidate.score, candidate.freeSpace, candidate.volumeCount)
+	}
+	rankedDatastores := make([]*vsphere.DatastoreInfo, 0, len(ranked))
+	for _, candidate := range ranked {
+		rankedDatastores = append(rankedDatastores, candidate.datastore)
+	}
+	return rankedDatastores, nil
+}
+
+// getVolumeCountPerDatastore queries CNS for the volumes provisioned on the
+// given datastores and returns the number of volumes per datastore URL.
+func getVolumeCountPerDatastore(ctx context.Context, volumeManager cnsvolume.Manager,
+	datastores []*vsphere.DatastoreInfo) (map[string]int, error) {
+	queryFilter := cnstypes.CnsQueryFilter{
+		Datastores: getDatastoreMoRefs(datastores),
+	}
+	// Select only the volume type to keep the query lightweight. The
+	// datastore URL is always returned.
+	querySelection := cnstypes.CnsQuerySelection{
+		Names: []string{
+			string(cnstypes.QuerySelectionNameTypeVolumeType),
+		},
+	}
+	queryResult, err := volumeManager.QueryAllVolume(ctx, queryFilter, querySelection)
+	if err != nil {
+		return nil, err
+	}
+	volumeCounts := make(map[string]int)
+	for _, volume := range queryResult.Volumes {
+		volumeCounts[strings.TrimSpace(volume.DatastoreUrl)]++
+	}
+	return volumeCounts, nil
+}
+
+// scoreDatastores computes the placement score of each datastore and returns
+// the datastores sorted by descending score. Free capacity and volume count
+// are normalized against the best and worst candidates respectively.
+func scoreDatastores(datastores []*vsphere.DatastoreInfo,
+	volumeCounts map[string]int) []datastorePlacementScore {
+	var maxFreeSpace int64
+	var maxVolumeCount int
+	scores := make([]datastorePlacementScore, 0, len(datastores))
+	for _, datastore := range datastores {
+		candidate := datastorePlacementScore{
+			datastore:   datastore,
+			freeSpace:   datastore.Info.FreeSpace,
+			volumeCount: volumeCounts[strings.TrimSpace(datastore.Info.Url)],
+		}
+		if candidate.freeSpace > maxFreeSpace {
+			maxFreeSpace = candidate.freeSpace
+		}
+		if candidate.volumeCount > maxVolumeCount {
+			maxVolumeCount = candidate.volumeCount
+		}
+		scores = append(scores, candidate)
+	}
+	for i := range scores {
+		freeSpaceScore := 1.0
+		if maxFreeSpace > 0 {
+			freeSpaceScore = float64(scores[i].freeSpace) / float64(maxFreeSpace)
+		}
+		volumeCountScore := 1.0
+		if maxVolumeCount > 0 {
+			volumeCountScore = 1 - float64(scores[i].volumeCount)/float64(maxVolumeCount)
+		}
+		scores[i].score = freeSpaceWeight*freeSpaceScore + volumeCountWeight*volumeCountScore
+	}
+	sort.SliceStable(scores, func(i, j int) bool {
+		return scores[i].score > scores[j].score
+	})
+	return scores
+}
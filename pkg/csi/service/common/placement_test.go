@@ -0,0 +1,68 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+
+	"github.com/vmware/govmomi/vim25/types"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+)
+
+func newTestDatastoreInfo(url string, freeSpace int64) *vsphere.DatastoreInfo {
+	return &vsphere.DatastoreInfo{
+		Info: &types.DatastoreInfo{
+			Url:       url,
+			FreeSpace: freeSpace,
+		},
+	}
+}
+
+// TestScoreDatastores tests that datastores with more free space and fewer
+// provisioned volumes are ranked first.
+func TestScoreDatastores(t *testing.T) {
+	datastores := []*vsphere.DatastoreInfo{
+		newTestDatastoreInfo("ds:///vmfs/volumes/ds1/", 100),
+		newTestDatastoreInfo("ds:///vmfs/volumes/ds2/", 100),
+		newTestDatastoreInfo("ds:///vmfs/volumes/ds3/", 50),
+	}
+	volumeCounts := map[string]int{
+		"ds:///vmfs/volumes/ds1/": 10,
+		"ds:///vmfs/volumes/ds2/": 2,
+	}
+	scores := scoreDatastores(datastores, volumeCounts)
+	expectedOrder := []string{"ds:///vmfs/volumes/ds2/", "ds:///vmfs/volumes/ds3/", "ds:///vmfs/volumes/ds1/"}
+	for i, url := range expectedOrder {
+		if scores[i].datastore.Info.Url != url {
+			t.Fatalf("expected datastore %q at position %d, got %q", url, i, scores[i].datastore.Info.Url)
+		}
+	}
+}
+
+// TestScoreDatastoresWithoutVolumes tests that free space decides the ranking
+// when no volumes are provisioned on the candidate datastores.
+func TestScoreDatastoresWithoutVolumes(t *testing.T) {
+	datastores := []*vsphere.DatastoreInfo{
+		newTestDatastoreInfo("ds:///vmfs/volumes/ds1/", 10),
+		newTestDatastoreInfo("ds:///vmfs/volumes/ds2/", 20),
+	}
+	scores := scoreDatastores(datastores, map[string]int{})
+	if scores[0].datastore.Info.Url != "ds:///vmfs/volumes/ds2/" {
+		t.Fatalf("expected datastore ds2 to be ranked first, got %q", scores[0].datastore.Info.Url)
+	}
+}
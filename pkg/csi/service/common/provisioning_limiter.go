@@ -0,0 +1,86 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	cnsconfig "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
+)
+
+// datastoreProvisioningLimiter tracks the CNS create and clone operations in
+// flight per datastore URL, to enforce the provisioning limits configured per
+// datastore.
+type datastoreProvisioningLimiter struct {
+	lock     sync.Mutex
+	inFlight map[string]int
+}
+
+// provisioningLimiter is the limiter shared by all the volume creations of
+// the controller.
+var provisioningLimiter = newDatastoreProvisioningLimiter()
+
+func newDatastoreProvisioningLimiter() *datastoreProvisioningLimiter {
+	return &datastoreProvisioningLimiter{
+		inFlight: make(map[string]int),
+	}
+}
+
+// acquire reserves a slot on each of the given candidate datastores having a
+// provisioning limit, and returns the indexes of the candidates admitted for
+// the operation. Candidates at their limit are left out. As CNS picks any of
+// the candidates, a slot is held on every admitted candidate with a limit
+// until the returned release function is called. An error is returned if all
+// the candidates are at their limit.
+func (l *datastoreProvisioningLimiter) acquire(limits map[string]*cnsconfig.DatastoreProvisioningLimitConfig,
+	datastoreURLs []string) ([]int, func(), error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	var admitted []int
+	var reserved []string
+	for i, datastoreURL := range datastoreURLs {
+		datastoreURL = strings.TrimSpace(datastoreURL)
+		limit, ok := limits[datastoreURL]
+		if !ok {
+			admitted = append(admitted, i)
+			continue
+		}
+		if l.inFlight[datastoreURL] >= limit.MaxConcurrentOperations {
+			continue
+		}
+		l.inFlight[datastoreURL]++
+		reserved = append(reserved, datastoreURL)
+		admitted = append(admitted, i)
+	}
+	if len(admitted) == 0 {
+		return nil, nil, fmt.Errorf("all candidate datastores %v are at their limit of concurrent "+
+			"provisioning operations", datastoreURLs)
+	}
+	release := func() {
+		l.lock.Lock()
+		defer l.lock.Unlock()
+		for _, datastoreURL := range reserved {
+			l.inFlight[datastoreURL]--
+			if l.inFlight[datastoreURL] == 0 {
+				delete(l.inFlight, datastoreURL)
+			}
+		}
+	}
+	return admitted, release, nil
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"reflect"
+	"testing"
+
+	cnsconfig "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
+)
+
+func TestDatastoreProvisioningLimiter(t *testing.T) {
+	limiter := newDatastoreProvisioningLimiter()
+	limits := map[string]*cnsconfig.DatastoreProvisioningLimitConfig{
+		"ds:///vmfs/volumes/small/": {MaxConcurrentOperations: 1},
+	}
+	candidates := []string{"ds:///vmfs/volumes/small/", "ds:///vmfs/volumes/large/"}
+
+	admitted, releaseFirst, err := limiter.acquire(limits, candidates)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(admitted, []int{0, 1}) {
+		t.Fatalf("expected all candidates to be admitted, got %v", admitted)
+	}
+
+	// The limited datastore is excluded while at its limit.
+	admitted, releaseSecond, err := limiter.acquire(limits, candidates)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(admitted, []int{1}) {
+		t.Fatalf("expected only the unlimited candidate to be admitted, got %v", admitted)
+	}
+	if _, _, err := limiter.acquire(limits, candidates[:1]); err == nil {
+		t.Fatal("expected error when all candidates are at their limit")
+	}
+
+	// Releasing the slot admits the limited datastore again.
+	releaseFirst()
+	releaseSecond()
+	admitted, release, err := limiter.acquire(limits, candidates[:1])
+	if err != nil {
+		t.Fatalf("unexpected error after release: %v", err)
+	}
+	if !reflect.DeepEqual(admitted, []int{0}) {
+		t.Fatalf("expected limited candidate to be admitted after release, got %v", admitted)
+	}
+	release()
+	if len(limiter.inFlight) != 0 {
+		t.Fatalf("expected no operation in flight, got %v", limiter.inFlight)
+	}
+}
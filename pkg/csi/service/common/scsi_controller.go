@@ -0,0 +1,141 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+)
+
+const (
+	// pvscsiTargetsPerController is the number of targets of a PVSCSI
+	// controller on VMs with hardware versions older than
+	// pvscsiExtendedTargetsHardwareVersion.
+	pvscsiTargetsPerController = 16
+	// pvscsiExtendedTargetsPerController is the number of targets of a
+	// PVSCSI controller on VMs with hardware version
+	// pvscsiExtendedTargetsHardwareVersion or later.
+	pvscsiExtendedTargetsPerController = 64
+	// pvscsiExtendedTargetsHardwareVersion is the first hardware version
+	// supporting pvscsiExtendedTargetsPerController targets per PVSCSI
+	// controller.
+	pvscsiExtendedTargetsHardwareVersion = 14
+	// scsiControllerReservedTargets is the number of targets of a SCSI
+	// controller reserved for the controller itself, i.e. unit 7.
+	scsiControllerReservedTargets = 1
+	// maxSCSIControllersPerVM is the maximum number of SCSI controllers of
+	// any type of a VM.
+	maxSCSIControllersPerVM = 4
+)
+
+// scsiControllerHotAddLock serializes the hot-add of SCSI controllers, so that
+// concurrent attaches to a full VM add a single controller.
+var scsiControllerHotAddLock sync.Mutex
+
+// GetPVSCSIUsableTargetsPerController returns the number of targets of a
+// PVSCSI controller which can hold disks on a VM with the given hardware
+// version, e.g. "vmx-14".
+func GetPVSCSIUsableTargetsPerController(hardwareVersion string) int {
+	targets := pvscsiTargetsPerController
+	if isHardwareVersionAtLeast(hardwareVersion, pvscsiExtendedTargetsHardwareVersion) {
+		targets = pvscsiExtendedTargetsPerController
+	}
+	return targets - scsiControllerReservedTargets
+}
+
+// isHardwareVersionAtLeast returns whether the given hardware version of a
+// VM, e.g. "vmx-14", is the given version or later. Hardware versions which
+// can't be parsed are considered older.
+func isHardwareVersionAtLeast(hardwareVersion string, version int) bool {
+	number, err := strconv.Atoi(strings.TrimPrefix(hardwareVersion, "vmx-"))
+	return err == nil && number >= version
+}
+
+// getFreePVSCSITargets returns the number of targets of the PVSCSI
+// controllers of a VM with the given hardware version and devices which
+// don't hold a device yet.
+func getFreePVSCSITargets(hardwareVersion string, devices object.VirtualDeviceList) int {
+	usedTargets := make(map[int32]int)
+	for _, device := range devices {
+		if _, ok := device.(*types.ParaVirtualSCSIController); ok {
+			usedTargets[device.GetVirtualDevice().Key] = 0
+		}
+	}
+	for _, device := range devices {
+		controllerKey := device.GetVirtualDevice().ControllerKey
+		if _, ok := usedTargets[controllerKey]; ok {
+			usedTargets[controllerKey]++
+		}
+	}
+	freeTargets := 0
+	for _, used := range usedTargets {
+		if free := GetPVSCSIUsableTargetsPerController(hardwareVersion) - used; free > 0 {
+			freeTargets += free
+		}
+	}
+	return freeTargets
+}
+
+// AddPVSCSIControllerIfFull hot-adds a PVSCSI controller to the given VM if
+// none of its PVSCSI controllers has a free target left, and the VM has less
+// than the maximum number of SCSI controllers. It returns whether a
+// controller was added.
+func AddPVSCSIControllerIfFull(ctx context.Context, vm *vsphere.VirtualMachine) (bool, error) {
+	log := logger.GetLogger(ctx)
+	scsiControllerHotAddLock.Lock()
+	defer scsiControllerHotAddLock.Unlock()
+
+	hardwareVersion, err := vm.GetHardwareVersion(ctx)
+	if err != nil {
+		return false, err
+	}
+	devices, err := vm.Device(ctx)
+	if err != nil {
+		log.Errorf("failed to get the devices of VM %v. err: %v", vm, err)
+		return false, err
+	}
+	if freeTargets := getFreePVSCSITargets(hardwareVersion, devices); freeTargets > 0 {
+		log.Debugf("VM %v has %d free PVSCSI targets left. Not adding any PVSCSI controller.", vm, freeTargets)
+		return false, nil
+	}
+	scsiControllers := devices.SelectByType((*types.VirtualSCSIController)(nil))
+	if len(scsiControllers) >= maxSCSIControllersPerVM {
+		log.Infof("VM %v has no free PVSCSI target left, and already has %d SCSI controllers.",
+			vm, len(scsiControllers))
+		return false, nil
+	}
+	controller, err := devices.CreateSCSIController("pvscsi")
+	if err != nil {
+		log.Errorf("failed to create PVSCSI controller spec for VM %v. err: %v", vm, err)
+		return false, err
+	}
+	log.Infof("VM %v has no free PVSCSI target left. Hot-adding a PVSCSI controller.", vm)
+	if err := vm.AddDevice(ctx, controller); err != nil {
+		log.Errorf("failed to hot-add PVSCSI controller to VM %v. err: %v", vm, err)
+		return false, err
+	}
+	log.Infof("Hot-added PVSCSI controller to VM %v.", vm)
+	return true, nil
+}
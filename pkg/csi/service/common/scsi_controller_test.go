@@ -0,0 +1,69 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestGetPVSCSIUsableTargetsPerController(t *testing.T) {
+	tests := map[string]int{
+		"vmx-13": 15,
+		"vmx-14": 63,
+		"vmx-19": 63,
+		"":       15,
+	}
+	for hardwareVersion, expected := range tests {
+		if targets := GetPVSCSIUsableTargetsPerController(hardwareVersion); targets != expected {
+			t.Errorf("expected %d targets for hardware version %q, got %d", expected, hardwareVersion, targets)
+		}
+	}
+}
+
+func TestGetFreePVSCSITargets(t *testing.T) {
+	newDevices := func(controllerKey int32, count int) object.VirtualDeviceList {
+		var devices object.VirtualDeviceList
+		for i := 0; i < count; i++ {
+			disk := &types.VirtualDisk{}
+			disk.ControllerKey = controllerKey
+			devices = append(devices, disk)
+		}
+		return devices
+	}
+	pvscsiController := &types.ParaVirtualSCSIController{}
+	pvscsiController.Key = 1000
+	lsiLogicController := &types.VirtualLsiLogicController{}
+	lsiLogicController.Key = 1001
+
+	devices := append(object.VirtualDeviceList{pvscsiController, lsiLogicController}, newDevices(1001, 3)...)
+	if free := getFreePVSCSITargets("vmx-13", devices); free != 15 {
+		t.Errorf("expected 15 free targets, got %d", free)
+	}
+	devices = append(devices, newDevices(1000, 15)...)
+	if free := getFreePVSCSITargets("vmx-13", devices); free != 0 {
+		t.Errorf("expected no free target, got %d", free)
+	}
+	if free := getFreePVSCSITargets("vmx-14", devices); free != 48 {
+		t.Errorf("expected 48 free targets, got %d", free)
+	}
+	if free := getFreePVSCSITargets("vmx-14", object.VirtualDeviceList{lsiLogicController}); free != 0 {
+		t.Errorf("expected no free target without PVSCSI controller, got %d", free)
+	}
+}
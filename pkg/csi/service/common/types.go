@@ -18,6 +18,7 @@ package common
 
 import (
 	"errors"
+	"time"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	cnsvolume "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/volume"
@@ -82,4 +83,11 @@ type StorageClassParams struct {
 	StoragePolicyName string
 	CSIMigration      string
 	Datastore         string
+	// DetachQuiesceDelay is the minimum time for which detach of the volume
+	// is delayed.
+	DetachQuiesceDelay time.Duration
+	// NfsVersion is the NFS protocol version file volumes are mounted with.
+	NfsVersion string
+	// DiskController is the type of controller block volumes are attached to.
+	DiskController string
 }
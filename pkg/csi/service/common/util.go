@@ -22,6 +22,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	cnstypes "github.com/vmware/govmomi/cns/types"
@@ -43,6 +44,14 @@ import (
 
 const (
 	defaultK8sCloudOperatorServicePort = 10000
+
+	// DefaultShutdownTimeout is the default time given to drain the work in
+	// progress on termination, below the default termination grace period of
+	// pods of 30 seconds.
+	DefaultShutdownTimeout = 25 * time.Second
+	// VCLogoutTimeout is the time given to log out of vCenter on termination,
+	// once the work in progress is drained.
+	VCLogoutTimeout = 5 * time.Second
 )
 
 var ErrAvailabilityZoneCRNotRegistered = errors.New("AvailabilityZone custom resource not registered")
@@ -170,12 +179,23 @@ func validateVolumeCapabilities(volCaps []*csi.VolumeCapability,
 }
 
 // IsValidVolumeCapabilities helps validate the given volume capabilities
-// based on volume type.
+// based on volume type, and their mount flags.
 func IsValidVolumeCapabilities(ctx context.Context, volCaps []*csi.VolumeCapability) error {
+	var err error
 	if IsFileVolumeRequest(ctx, volCaps) {
-		return validateVolumeCapabilities(volCaps, FileVolumeCaps, FileVolumeType)
+		err = validateVolumeCapabilities(volCaps, FileVolumeCaps, FileVolumeType)
+	} else {
+		err = validateVolumeCapabilities(volCaps, BlockVolumeCaps, BlockVolumeType)
 	}
-	return validateVolumeCapabilities(volCaps, BlockVolumeCaps, BlockVolumeType)
+	if err != nil {
+		return err
+	}
+	for _, volCap := range volCaps {
+		if err := ValidateMountFlags(ctx, volCap); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // ParseStorageClassParams parses the params in the CSI CreateVolumeRequest API
@@ -196,6 +216,24 @@ func ParseStorageClassParams(ctx context.Context, params map[string]string,
 				scParams.StoragePolicyName = value
 			} else if param == AttributeFsType {
 				log.Warnf("param 'fstype' is deprecated, please use 'csi.storage.k8s.io/fstype' instead")
+			} else if param == AttributeDetachQuiesceDelay {
+				delay, err := ParseDetachQuiesceDelay(value)
+				if err != nil {
+					return nil, err
+				}
+				scParams.DetachQuiesceDelay = delay
+			} else if param == AttributeNfsVersion {
+				nfsVersion, err := ParseNfsVersion(value)
+				if err != nil {
+					return nil, err
+				}
+				scParams.NfsVersion = nfsVersion
+			} else if param == AttributeDiskController {
+				diskController, err := ParseDiskController(value)
+				if err != nil {
+					return nil, err
+				}
+				scParams.DiskController = diskController
 			} else {
 				return nil, fmt.Errorf("invalid param: %q and value: %q", param, value)
 			}
@@ -212,6 +250,24 @@ func ParseStorageClassParams(ctx context.Context, params map[string]string,
 				log.Warnf("param 'fstype' is deprecated, please use 'csi.storage.k8s.io/fstype' instead")
 			} else if param == CSIMigrationParams {
 				scParams.CSIMigration = value
+			} else if param == AttributeDetachQuiesceDelay {
+				delay, err := ParseDetachQuiesceDelay(value)
+				if err != nil {
+					return nil, err
+				}
+				scParams.DetachQuiesceDelay = delay
+			} else if param == AttributeNfsVersion {
+				nfsVersion, err := ParseNfsVersion(value)
+				if err != nil {
+					return nil, err
+				}
+				scParams.NfsVersion = nfsVersion
+			} else if param == AttributeDiskController {
+				diskController, err := ParseDiskController(value)
+				if err != nil {
+					return nil, err
+				}
+				scParams.DiskController = diskController
 			} else {
 				otherParams[param] = value
 			}
@@ -243,12 +299,29 @@ func ParseStorageClassParams(ctx context.Context, params map[string]string,
 	return scParams, nil
 }
 
+// ParseDetachQuiesceDelay parses the value of the detachquiescedelay
+// StorageClass parameter, a non-negative number of seconds.
+func ParseDetachQuiesceDelay(value string) (time.Duration, error) {
+	seconds, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || seconds < 0 {
+		return 0, fmt.Errorf("invalid value %q for param %q, expected a non-negative number of seconds",
+			value, AttributeDetachQuiesceDelay)
+	}
+	if delay := time.Duration(seconds) * time.Second; delay > MaxDetachQuiesceDelay {
+		return 0, fmt.Errorf("invalid value %q for param %q, expected at most %d seconds",
+			value, AttributeDetachQuiesceDelay, int(MaxDetachQuiesceDelay.Seconds()))
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
 // GetConfigPath returns ConfigPath depending on the environment variable
 // specified and the cluster flavor set.
 func GetConfigPath(ctx context.Context) string {
+	log := logger.GetLogger(ctx)
 	var cfgPath string
-	clusterFlavor := cnstypes.CnsClusterFlavor(os.Getenv(csitypes.EnvClusterFlavor))
-	if strings.TrimSpace(string(clusterFlavor)) == "" {
+	clusterFlavor, err := cnsconfig.GetClusterFlavor(ctx)
+	if err != nil {
+		log.Warnf("failed to get cluster flavor, assuming %q. Err: %v", cnstypes.CnsClusterFlavorVanilla, err)
 		clusterFlavor = cnstypes.CnsClusterFlavorVanilla
 	}
 	if clusterFlavor == cnstypes.CnsClusterFlavorGuest {
@@ -327,6 +400,24 @@ func GetK8sCloudOperatorServicePort(ctx context.Context) int {
 	return k8sCloudOperatorServicePort
 }
 
+// GetShutdownTimeout returns the time given to drain the work in progress on
+// termination, set in X_CSI_SHUTDOWN_TIMEOUT, or DefaultShutdownTimeout if it
+// isn't set or invalid.
+func GetShutdownTimeout(ctx context.Context) time.Duration {
+	log := logger.GetLogger(ctx)
+	v := os.Getenv(csitypes.EnvVarShutdownTimeout)
+	if v == "" {
+		return DefaultShutdownTimeout
+	}
+	timeout, err := time.ParseDuration(v)
+	if err != nil || timeout < 0 {
+		log.Warnf("%s %q is invalid, will use the default value %v",
+			csitypes.EnvVarShutdownTimeout, v, DefaultShutdownTimeout)
+		return DefaultShutdownTimeout
+	}
+	return timeout
+}
+
 // ConvertVolumeHealthStatus convert the volume health status into
 // accessible/inaccessible status.
 func ConvertVolumeHealthStatus(ctx context.Context, volID string, volHealthStatus string) (string, error) {
@@ -389,13 +480,13 @@ func GetClusterComputeResourceMoIds(ctx context.Context) ([]string, error) {
 	clusterComputeResourceMoIds := make([]string, 0)
 	cfg, err := config.GetConfig()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get Kubernetes config. Err: %+v", err)
+		return nil, fmt.Errorf("failed to get Kubernetes config. Err: %w", err)
 	}
 
 	// Create a new AvailabilityZone client.
 	azClient, err := dynamic.NewForConfig(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create AvailabilityZone client using config. Err: %+v", err)
+		return nil, fmt.Errorf("failed to create AvailabilityZone client using config. Err: %w", err)
 	}
 	azResource := schema.GroupVersionResource{
 		Group: "topology.tanzu.vmware.com", Version: "v1alpha1", Resource: "availabilityzones"}
@@ -412,7 +503,7 @@ func GetClusterComputeResourceMoIds(ctx context.Context) ([]string, error) {
 			log.Infof("AvailabilityZone CR is not registered on the cluster")
 			return nil, nil
 		}
-		return nil, fmt.Errorf("failed to get AvailabilityZone lists. err: %+v", err)
+		return nil, fmt.Errorf("failed to get AvailabilityZone lists. err: %w", err)
 	}
 	if len(azList.Items) == 0 {
 		return nil, fmt.Errorf("could not find any AvailabilityZone")
@@ -423,7 +514,7 @@ func GetClusterComputeResourceMoIds(ctx context.Context) ([]string, error) {
 		clusterComputeResourceMoId, found, err := unstructured.NestedString(az.Object, "spec", "clusterComputeResourceMoId")
 		if !found || err != nil {
 			return nil, fmt.Errorf("failed to get clusterComputeResourceMoId "+
-				"from AvailabilityZone instance: %+v, err:%+v", az.Object, err)
+				"from AvailabilityZone instance: %+v, err:%w", az.Object, err)
 		}
 		clusterComputeResourceMoIds = append(clusterComputeResourceMoIds, clusterComputeResourceMoId)
 	}
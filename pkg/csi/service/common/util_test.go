@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -272,6 +273,12 @@ func isStorageClassParamsEqual(expected *StorageClassParams, actual *StorageClas
 	if expected.StoragePolicyName != actual.StoragePolicyName {
 		return false
 	}
+	if expected.DetachQuiesceDelay != actual.DetachQuiesceDelay {
+		return false
+	}
+	if expected.DiskController != actual.DiskController {
+		return false
+	}
 	return true
 }
 
@@ -309,6 +316,56 @@ func TestParseStorageClassParamsWithValidParams(t *testing.T) {
 	}
 }
 
+func TestParseStorageClassParamsWithDetachQuiesceDelay(t *testing.T) {
+	params := map[string]string{
+		AttributeStoragePolicyName:  "policy1",
+		AttributeDetachQuiesceDelay: "30",
+	}
+	expectedScParams := &StorageClassParams{
+		StoragePolicyName:  "policy1",
+		DetachQuiesceDelay: 30 * time.Second,
+	}
+	for _, csiMigrationFeatureState := range []bool{false, true} {
+		actualScParams, err := ParseStorageClassParams(ctx, params, csiMigrationFeatureState)
+		if err != nil {
+			t.Fatalf("failed to parse params: %+v. err: %v", params, err)
+		}
+		if !isStorageClassParamsEqual(expectedScParams, actualScParams) {
+			t.Errorf("Expected: %+v\n Actual: %+v", expectedScParams, actualScParams)
+		}
+	}
+	for _, delay := range []string{"soon", "301"} {
+		params[AttributeDetachQuiesceDelay] = delay
+		if scParams, err := ParseStorageClassParams(ctx, params, false); err == nil {
+			t.Errorf("error expected but not received. scParam received from ParseStorageClassParams: %v", scParams)
+		}
+	}
+}
+
+func TestParseStorageClassParamsWithDiskController(t *testing.T) {
+	params := map[string]string{
+		AttributeStoragePolicyName: "policy1",
+		"diskController":           "NVMe",
+	}
+	expectedScParams := &StorageClassParams{
+		StoragePolicyName: "policy1",
+		DiskController:    DiskControllerNVMe,
+	}
+	for _, csiMigrationFeatureState := range []bool{false, true} {
+		actualScParams, err := ParseStorageClassParams(ctx, params, csiMigrationFeatureState)
+		if err != nil {
+			t.Fatalf("failed to parse params: %+v. err: %v", params, err)
+		}
+		if !isStorageClassParamsEqual(expectedScParams, actualScParams) {
+			t.Errorf("Expected: %+v\n Actual: %+v", expectedScParams, actualScParams)
+		}
+	}
+	params["diskController"] = "lsilogic"
+	if scParams, err := ParseStorageClassParams(ctx, params, false); err == nil {
+		t.Errorf("error expected but not received. scParam received from ParseStorageClassParams: %v", scParams)
+	}
+}
+
 func TestParseStorageClassParamsWithMigrationEnabledNagative(t *testing.T) {
 	csiMigrationFeatureState := true
 	params := map[string]string{
@@ -17,6 +17,7 @@ limitations under the License.
 package common
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -36,6 +37,7 @@ import (
 	cnsvolume "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/volume"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
 	csifault "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/fault"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/retry"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/utils"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
 )
@@ -57,12 +59,17 @@ func CreateBlockVolumeUtil(ctx context.Context, clusterFlavor cnstypes.CnsCluste
 		if err != nil {
 			log.Errorf("Error occurred while getting Profile Id from Profile Name: %s, err: %+v",
 				spec.ScParams.StoragePolicyName, err)
+			if errors.Is(err, vsphere.ErrStoragePolicyNotFound) {
+				return nil, csifault.CSIInvalidArgumentFault, err
+			}
 			// TODO: need to extract fault from err returned by GetStoragePolicyIDByName.
 			// Currently, just return csi.fault.Internal.
 			return nil, csifault.CSIInternalFault, err
 		}
 	}
 	var datastores []vim25types.ManagedObjectReference
+	// datastoreURLs holds the URLs of the datastores, in the same order.
+	var datastoreURLs []string
 	if spec.ScParams.DatastoreURL == "" {
 		// Check if datastore URL is specified by the storage pool parameter.
 		if spec.VsanDirectDatastoreURL != "" {
@@ -92,6 +99,7 @@ func CreateBlockVolumeUtil(ctx context.Context, clusterFlavor cnstypes.CnsCluste
 				log.Debugf("Successfully fetched the datastore %v from the URL: %v",
 					datastoreObj.Reference(), spec.VsanDirectDatastoreURL)
 				datastores = append(datastores, datastoreObj.Reference())
+				datastoreURLs = append(datastoreURLs, spec.VsanDirectDatastoreURL)
 				break
 			}
 			if datastores == nil {
@@ -104,7 +112,26 @@ func CreateBlockVolumeUtil(ctx context.Context, clusterFlavor cnstypes.CnsCluste
 		} else {
 			// If DatastoreURL is not specified in StorageClass, get all shared
 			// datastores.
-			datastores = getDatastoreMoRefs(sharedDatastores)
+			candidateDatastores := sharedDatastores
+			if manager.CnsConfig.Global.DatastorePlacementStrategy == DatastorePlacementStrategyBalanced {
+				candidateDatastores, err = RankDatastoresForPlacement(ctx, manager.VolumeManager, sharedDatastores)
+				if err != nil {
+					return nil, csifault.CSIInternalFault, logger.LogNewErrorf(log,
+						"failed to rank shared datastores for volume %q. Error: %w", spec.Name, err)
+				}
+				// Without a storage policy every shared datastore is compatible,
+				// so the best scoring datastore is picked. Otherwise, CNS needs
+				// the complete list to find a compatible datastore.
+				if spec.StoragePolicyID == "" && len(candidateDatastores) > 0 {
+					log.Infof("Selected datastore %q for volume %q using %q placement strategy",
+						candidateDatastores[0].Info.Url, spec.Name, DatastorePlacementStrategyBalanced)
+					candidateDatastores = candidateDatastores[:1]
+				}
+			}
+			datastores = getDatastoreMoRefs(candidateDatastores)
+			for _, candidateDatastore := range candidateDatastores {
+				datastoreURLs = append(datastoreURLs, candidateDatastore.Info.Url)
+			}
 		}
 	} else {
 		// vc.GetDatacenters returns datacenters found on the VirtualCenter.
@@ -148,6 +175,7 @@ func CreateBlockVolumeUtil(ctx context.Context, clusterFlavor cnstypes.CnsCluste
 		}
 		if isSharedDatastoreURL {
 			datastores = append(datastores, datastoreObj.Reference())
+			datastoreURLs = append(datastoreURLs, spec.ScParams.DatastoreURL)
 		} else {
 			// TODO: Need to figure out which fault need to return when datastore is not accessible to all nodes.
 			// Currently, just return csi.fault.Internal.
@@ -219,7 +247,7 @@ func CreateBlockVolumeUtil(ctx context.Context, clusterFlavor cnstypes.CnsCluste
 		cnsVolume, err := QueryVolumeByID(ctx, manager.VolumeManager, cnsVolumeID)
 		if err != nil {
 			return nil, csifault.CSIInternalFault, logger.LogNewErrorf(log,
-				"failed to query datastore for the snapshot %s with error %+v",
+				"failed to query datastore for the snapshot %s with error %w",
 				spec.ContentSourceSnapshotID, err)
 		}
 
@@ -228,7 +256,7 @@ func CreateBlockVolumeUtil(ctx context.Context, clusterFlavor cnstypes.CnsCluste
 			ctx, vc, createSpec.Datastores, cnsVolume.DatastoreUrl)
 		if err != nil {
 			return nil, csifault.CSIInternalFault, logger.LogNewErrorf(log,
-				"failed to get the compatible datastore for create volume from snapshot %s with error: %+v",
+				"failed to get the compatible datastore for create volume from snapshot %s with error: %w",
 				spec.ContentSourceSnapshotID, err)
 		}
 		// overwrite the datatstores field in create spec with the compatible datastore
@@ -236,6 +264,26 @@ func CreateBlockVolumeUtil(ctx context.Context, clusterFlavor cnstypes.CnsCluste
 			"when create volume from snapshot %s", createSpec.Datastores, *compatibleDatastore,
 			spec.ContentSourceSnapshotID)
 		createSpec.Datastores = []vim25types.ManagedObjectReference{*compatibleDatastore}
+		datastoreURLs = []string{cnsVolume.DatastoreUrl}
+	}
+
+	if len(manager.CnsConfig.DatastoreProvisioningLimit) > 0 && len(datastoreURLs) > 0 {
+		admitted, release, err := provisioningLimiter.acquire(manager.CnsConfig.DatastoreProvisioningLimit,
+			datastoreURLs)
+		if err != nil {
+			return nil, csifault.CSIResourceExhaustedFault, logger.LogNewErrorf(log,
+				"failed to create volume %s. Error: %w", spec.Name, err)
+		}
+		defer release()
+		if len(admitted) < len(createSpec.Datastores) {
+			var admittedDatastores []vim25types.ManagedObjectReference
+			for _, i := range admitted {
+				admittedDatastores = append(admittedDatastores, createSpec.Datastores[i])
+			}
+			log.Infof("Excluding datastores at their provisioning limit from the candidates of volume %s. "+
+				"Remaining candidates: %v", spec.Name, admittedDatastores)
+			createSpec.Datastores = admittedDatastores
+		}
 	}
 
 	log.Debugf("vSphere CSI driver creating volume %s with create spec %+v", spec.Name, spew.Sdump(createSpec))
@@ -265,6 +313,9 @@ func CreateFileVolumeUtil(ctx context.Context, clusterFlavor cnstypes.CnsCluster
 		if err != nil {
 			log.Errorf("Error occurred while getting Profile Id from Profile Name: %q, err: %+v",
 				spec.ScParams.StoragePolicyName, err)
+			if errors.Is(err, vsphere.ErrStoragePolicyNotFound) {
+				return "", csifault.CSIInvalidArgumentFault, err
+			}
 			// TODO: need to extract fault from err returned by GetStoragePolicyIDByName.
 			// Currently, just return csi.fault.Internal.
 			return "", csifault.CSIInternalFault, err
@@ -364,6 +415,9 @@ func CreateFileVolumeUtilOld(ctx context.Context, clusterFlavor cnstypes.CnsClus
 		if err != nil {
 			log.Errorf("Error occurred while getting Profile Id from Profile Name: %q, err: %+v",
 				spec.ScParams.StoragePolicyName, err)
+			if errors.Is(err, vsphere.ErrStoragePolicyNotFound) {
+				return "", csifault.CSIInvalidArgumentFault, err
+			}
 			// TODO: need to extract fault from err returned by GetStoragePolicyIDByName.
 			// Currently, just return csi.fault.Internal.
 			return "", csifault.CSIInternalFault, err
@@ -545,7 +599,15 @@ func AttachVolumeUtil(ctx context.Context, manager *Manager,
 	volumeID string, checkNVMeController bool) (string, string, error) {
 	log := logger.GetLogger(ctx)
 	log.Debugf("vSphere CSI driver is attaching volume: %q to vm: %q", volumeID, vm.String())
-	diskUUID, faultType, err := manager.VolumeManager.AttachVolume(ctx, vm, volumeID, checkNVMeController)
+	var diskUUID, faultType string
+	err := retry.Do(ctx, "AttachVolume", retry.AttachVolume, func() error {
+		var attachErr error
+		diskUUID, faultType, attachErr = manager.VolumeManager.AttachVolume(ctx, vm, volumeID, checkNVMeController)
+		if attachErr != nil && !cnsvolume.IsRetryableError(faultType, attachErr) {
+			return retry.Permanent(attachErr)
+		}
+		return attachErr
+	})
 	if err != nil {
 		log.Errorf("failed to attach disk %q with VM: %q. err: %+v faultType %q", volumeID, vm.String(), err, faultType)
 		return "", faultType, err
@@ -606,7 +668,7 @@ func ExpandVolumeUtil(ctx context.Context, manager *Manager, volumeID string, ca
 	isvSphere8AndAbove, err = IsvSphere8AndAbove(ctx, vc.Client.ServiceContent.About)
 	if err != nil {
 		return "", logger.LogNewErrorf(log,
-			"Error while determining whether vSphere version is 8 and above %q. Error= %+v",
+			"Error while determining whether vSphere version is 8 and above %q. Error= %w",
 			vc.Client.ServiceContent.About.ApiVersion, err)
 	}
 
@@ -1018,7 +1080,7 @@ func DeleteSnapshotUtil(ctx context.Context, manager *Manager, csiSnapshotID str
 	log.Debugf("vSphere CSI driver is deleting snapshot %q on volume: %q", cnsSnapshotID, cnsVolumeID)
 	err = manager.VolumeManager.DeleteSnapshot(ctx, cnsVolumeID, cnsSnapshotID)
 	if err != nil {
-		return logger.LogNewErrorf(log, "failed to delete snapshot %q on volume %q with error %+v",
+		return logger.LogNewErrorf(log, "failed to delete snapshot %q on volume %q with error %w",
 			cnsSnapshotID, cnsVolumeID, err)
 	}
 	log.Debugf("Successfully deleted snapshot %q on volume %q", cnsSnapshotID, cnsVolumeID)
@@ -1045,13 +1107,13 @@ func GetNodeVMsWithAccessToDatastore(ctx context.Context, vc *vsphere.VirtualCen
 	dsObj, err := getDatastoreObj(ctx, vc, dsURL)
 	if err != nil {
 		return nil, logger.LogNewErrorf(log, "failed to retrieve datastore object using datastore "+
-			"URL %q. Error: %+v", dsURL, err)
+			"URL %q. Error: %w", dsURL, err)
 	}
 	// Get datastore host mounts.
 	var ds mo.Datastore
 	err = dsObj.Properties(ctx, dsObj.Reference(), []string{"host"}, &ds)
 	if err != nil {
-		return nil, logger.LogNewErrorf(log, "failed to get host mounts from datastore %q. Error: %+v",
+		return nil, logger.LogNewErrorf(log, "failed to get host mounts from datastore %q. Error: %w",
 			dsURL, err)
 	}
 
@@ -1063,7 +1125,7 @@ func GetNodeVMsWithAccessToDatastore(ctx context.Context, vc *vsphere.VirtualCen
 		var hs mo.HostSystem
 		err = hostObj.Properties(ctx, hostObj.Reference(), []string{"vm"}, &hs)
 		if err != nil {
-			return nil, logger.LogNewErrorf(log, "failed to retrieve virtual machines from host %q. Error: %+v",
+			return nil, logger.LogNewErrorf(log, "failed to retrieve virtual machines from host %q. Error: %w",
 				hostObj.String(), err)
 		}
 		// For each VM on the host, check if the VM is a NodeVM participating in the k8s cluster.
@@ -1083,3 +1145,60 @@ func GetNodeVMsWithAccessToDatastore(ctx context.Context, vc *vsphere.VirtualCen
 	log.Infof("Nodes that have access to datastore %q are %+v", dsURL, accessibleNodes)
 	return accessibleNodes, nil
 }
+
+// FilterDatastoresByStorageClassParams returns the given datastores which
+// volumes of the given StorageClass parameters can be provisioned on, i.e.
+// the datastore of the datastore URL parameter and the datastores compatible
+// with the storage policy of the storage policy name parameter.
+func FilterDatastoresByStorageClassParams(ctx context.Context, vc *vsphere.VirtualCenter,
+	scParams *StorageClassParams, datastores []*vsphere.DatastoreInfo) ([]*vsphere.DatastoreInfo, error) {
+	if scParams.DatastoreURL != "" {
+		var filtered []*vsphere.DatastoreInfo
+		for _, datastore := range datastores {
+			if datastore.Info.Url == scParams.DatastoreURL {
+				filtered = append(filtered, datastore)
+			}
+		}
+		datastores = filtered
+	}
+	if scParams.StoragePolicyName == "" || len(datastores) == 0 {
+		return datastores, nil
+	}
+	policyID, err := vc.GetStoragePolicyIDByName(ctx, scParams.StoragePolicyName)
+	if err != nil {
+		return nil, err
+	}
+	datastoreMorList := make([]vim25types.ManagedObjectReference, 0, len(datastores))
+	for _, datastore := range datastores {
+		datastoreMorList = append(datastoreMorList, datastore.Datastore.Reference())
+	}
+	compat, err := vc.PbmCheckCompatibility(ctx, datastoreMorList, policyID)
+	if err != nil {
+		return nil, err
+	}
+	compatibleDatastores := make(map[string]bool)
+	for _, hub := range compat.CompatibleDatastores() {
+		compatibleDatastores[hub.HubId] = true
+	}
+	var filtered []*vsphere.DatastoreInfo
+	for _, datastore := range datastores {
+		if compatibleDatastores[datastore.Datastore.Reference().Value] {
+			filtered = append(filtered, datastore)
+		}
+	}
+	return filtered, nil
+}
+
+// GetDatastoresCapacity returns the total free space of the given
+// datastores, and the free space of the datastore with the most free space,
+// i.e. the size of the largest volume which can be provisioned on them.
+func GetDatastoresCapacity(datastores []*vsphere.DatastoreInfo) (int64, int64) {
+	var capacity, maximumVolumeSize int64
+	for _, datastore := range datastores {
+		capacity += datastore.Info.FreeSpace
+		if datastore.Info.FreeSpace > maximumVolumeSize {
+			maximumVolumeSize = datastore.Info.FreeSpace
+		}
+	}
+	return capacity, maximumVolumeSize
+}
@@ -9,6 +9,7 @@ import (
 	cnstypes "github.com/vmware/govmomi/cns/types"
 	"github.com/vmware/govmomi/vim25/types"
 	cnsvolume "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/volume"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/utils"
 )
 
@@ -151,3 +152,17 @@ func TestQueryAllVolumeSnapshotsWithQuerySnapshotsUnexpectedFault(t *testing.T)
 	_, _, err := QueryAllVolumeSnapshots(context.TODO(), nil, "", 100)
 	assert.Error(t, err)
 }
+
+func TestGetDatastoresCapacity(t *testing.T) {
+	datastores := []*vsphere.DatastoreInfo{
+		{Info: &types.DatastoreInfo{Url: "ds:///vmfs/volumes/ds-1/", FreeSpace: 10 * GbInBytes}},
+		{Info: &types.DatastoreInfo{Url: "ds:///vmfs/volumes/ds-2/", FreeSpace: 30 * GbInBytes}},
+	}
+	capacity, maximumVolumeSize := GetDatastoresCapacity(datastores)
+	assert.Equal(t, int64(40*GbInBytes), capacity)
+	assert.Equal(t, int64(30*GbInBytes), maximumVolumeSize)
+
+	capacity, maximumVolumeSize = GetDatastoresCapacity(nil)
+	assert.Equal(t, int64(0), capacity)
+	assert.Equal(t, int64(0), maximumVolumeSize)
+}
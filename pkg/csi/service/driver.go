@@ -21,12 +21,15 @@ import (
 	"net"
 	"os"
 	"strings"
+	"sync"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/rexray/gocsi"
 	cnstypes "github.com/vmware/govmomi/cns/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	cnsconfig "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/debugserver"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common/commonco"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
@@ -47,9 +50,12 @@ const (
 var (
 	// COInitParams stores the input params required for initiating the
 	// CO agnostic orchestrator for the controller as well as node containers.
-	COInitParams  interface{}
-	clusterFlavor = defaultClusterFlavor
-	cfgPath       = cnsconfig.DefaultCloudConfigPath
+	COInitParams interface{}
+	// KubeletRootDir is the root directory of kubelet on the nodes. If empty,
+	// the node plugin discovers it from the paths passed by kubelet.
+	KubeletRootDir string
+	clusterFlavor  = defaultClusterFlavor
+	cfgPath        = cnsconfig.DefaultCloudConfigPath
 )
 
 // Driver is a CSI SP and idempotency.Provider.
@@ -65,6 +71,21 @@ type vsphereCSIDriver struct {
 	mode    string
 	cnscs   csitypes.CnsController
 	osUtils *osutils.OsUtils
+	// kubeletRootDir is the validated KubeletRootDir, if set.
+	kubeletRootDir string
+	// fsHealthMonitor checks the file systems of the staged volumes, if the
+	// feature is enabled.
+	fsHealthMonitor *filesystemHealthMonitor
+	// volumeRecovery remounts the staged volumes which went stale, if the
+	// feature is enabled.
+	volumeRecovery *volumeRecoveryReconciler
+
+	// ephemeralLock guards ephemeralClient, which is initialized by the
+	// node plugin on the first ephemeral inline volume request.
+	ephemeralLock sync.Mutex
+	// ephemeralClient manages the CnsEphemeralVolume instances requesting
+	// the volumes of the ephemeral inline volumes of this node.
+	ephemeralClient client.Client
 }
 
 // If k8s node died unexpectedly in an earlier run, the unix socket is left
@@ -86,7 +107,12 @@ func NewDriver() Driver {
 
 func (driver *vsphereCSIDriver) GetController() csi.ControllerServer {
 	// Check which controller type to use.
-	clusterFlavor = cnstypes.CnsClusterFlavor(os.Getenv(csitypes.EnvClusterFlavor))
+	ctx, log := logger.GetNewContextWithLogger()
+	var err error
+	clusterFlavor, err = cnsconfig.GetClusterFlavor(ctx)
+	if err != nil {
+		log.Warnf("failed to get cluster flavor, using %q. Err: %v", defaultClusterFlavor, err)
+	}
 	switch clusterFlavor {
 	case cnstypes.CnsClusterFlavorWorkload:
 		driver.cnscs = wcp.New()
@@ -132,8 +158,33 @@ func (driver *vsphereCSIDriver) BeforeServe(
 		return err
 	}
 
+	if !strings.EqualFold(driver.mode, "controller") {
+		driver.publishHostUtilitiesCondition(ctx)
+		if commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.VersionSkewCheck) {
+			driver.publishNodePluginVersionCondition(ctx)
+		}
+		serveNodeMetrics(ctx)
+		if KubeletRootDir != "" {
+			if err := validateKubeletRootDir(KubeletRootDir); err != nil {
+				log.Errorf("Invalid kubelet root directory. Error: %v", err)
+				return err
+			}
+			driver.kubeletRootDir = KubeletRootDir
+			log.Infof("Using kubelet root directory %q", driver.kubeletRootDir)
+		}
+		if commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.FilesystemHealthMonitor) {
+			driver.fsHealthMonitor = newFilesystemHealthMonitor(ctx, driver.osUtils)
+			go driver.fsHealthMonitor.run(ctx, getFilesystemHealthCheckInterval(ctx))
+		}
+		if commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.NodeVolumeRecovery) {
+			driver.volumeRecovery = newVolumeRecoveryReconciler(ctx, driver.osUtils)
+			go driver.volumeRecovery.run(ctx, getVolumeRecoveryInterval(ctx))
+		}
+	}
+
 	if !strings.EqualFold(driver.mode, "node") {
 		// Controller service is needed.
+		debugserver.Serve(ctx, nil)
 		cfg, err = common.GetConfig(ctx)
 		if err != nil {
 			log.Errorf("failed to read config. Error: %+v", err)
@@ -160,5 +211,13 @@ func (driver *vsphereCSIDriver) Run(ctx context.Context, endpoint string) {
 
 	//Start the nonblocking GRPC
 	grpc := NewNonBlockingGRPCServer()
+	terminating, terminated := driver.handleTermination(ctx, grpc)
 	grpc.Start(endpoint, driver, controllerServer, driver)
+	// The server stops serving when it is stopped on termination, wait for
+	// the termination to complete.
+	select {
+	case <-terminating:
+		<-terminated
+	default:
+	}
 }
@@ -0,0 +1,362 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cnsoperatorv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator"
+	cnsephemeralvolumev1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsephemeralvolume/v1alpha1"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common/commonco"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	k8s "sigs.k8s.io/vsphere-csi-driver/v2/pkg/kubernetes"
+)
+
+const (
+	// ephemeralVolumeContextKey is the volume context key set to "true" by
+	// kubelet for CSI ephemeral inline volumes.
+	ephemeralVolumeContextKey = "csi.storage.k8s.io/ephemeral"
+	// ephemeralVolumeSizeAttribute is the volume attribute of ephemeral
+	// inline volumes holding their size, e.g. "5Gi". The default volume size
+	// is used if it isn't set.
+	ephemeralVolumeSizeAttribute = "size"
+	// ephemeralVolumePollInterval is the interval at which the node plugin
+	// checks whether the syncer attached the volume of a CnsEphemeralVolume.
+	ephemeralVolumePollInterval = 2 * time.Second
+	// ephemeralVolumeAttachTimeout is how long NodePublishVolume waits for
+	// the syncer to attach the volume of a CnsEphemeralVolume. kubelet
+	// retries NodePublishVolume afterwards.
+	ephemeralVolumeAttachTimeout = time.Minute
+)
+
+// getEphemeralVolumesDir returns the directory holding the staging paths and
+// the records of the ephemeral inline volumes provisioned by the node plugin,
+// under the given kubelet root directory. The records persist across restarts
+// of the node plugin, so that the volumes still get deleted when their pod
+// goes away.
+func getEphemeralVolumesDir(kubeletRootDir string) string {
+	return filepath.Join(getPluginDir(kubeletRootDir), "ephemeral")
+}
+
+// ephemeralVolume records an ephemeral inline volume provisioned by the node
+// plugin, keyed by the volume ID assigned by kubelet.
+type ephemeralVolume struct {
+	// VolumeID is the ID of the CNS volume, once it is attached.
+	VolumeID string `json:"volumeID,omitempty"`
+	// StagingPath is the path the volume is staged at.
+	StagingPath string `json:"stagingPath"`
+}
+
+// isEphemeralVolumeRequest returns whether the given volume context is the
+// one of a CSI ephemeral inline volume.
+func isEphemeralVolumeRequest(volumeContext map[string]string) bool {
+	return volumeContext[ephemeralVolumeContextKey] == "true"
+}
+
+// getEphemeralVolumeInstance returns the CnsEphemeralVolume instance
+// requesting the CNS volume of the ephemeral inline volume published by the
+// given request on the given node. The volume attributes other than the size
+// are passed as StorageClass parameters, e.g. storagepolicyname.
+func getEphemeralVolumeInstance(req *csi.NodePublishVolumeRequest,
+	nodeName string) (*cnsephemeralvolumev1alpha1.CnsEphemeralVolume, error) {
+	instance := &cnsephemeralvolumev1alpha1.CnsEphemeralVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: req.GetVolumeId()},
+		Spec: cnsephemeralvolumev1alpha1.CnsEphemeralVolumeSpec{
+			NodeName:   nodeName,
+			Parameters: make(map[string]string),
+		},
+	}
+	for key, value := range req.GetVolumeContext() {
+		switch {
+		case key == ephemeralVolumeSizeAttribute:
+			size, err := resource.ParseQuantity(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s attribute %q: %w", ephemeralVolumeSizeAttribute, value, err)
+			}
+			instance.Spec.CapacityInBytes = size.Value()
+		case strings.HasPrefix(key, "csi.storage.k8s.io/"):
+			// Pod info and ephemeral flag set by kubelet.
+		default:
+			instance.Spec.Parameters[key] = value
+		}
+	}
+	return instance, nil
+}
+
+// getEphemeralVolumeRecordPath returns the path of the record of the
+// ephemeral inline volume with the given kubelet volume ID.
+func getEphemeralVolumeRecordPath(dir, volumeID string) (string, error) {
+	if volumeID == "" || filepath.Base(volumeID) != volumeID {
+		return "", fmt.Errorf("invalid volume ID %q", volumeID)
+	}
+	return filepath.Join(dir, volumeID+".json"), nil
+}
+
+// loadEphemeralVolume returns the record of the ephemeral inline volume with
+// the given kubelet volume ID, or nil if there is none.
+func loadEphemeralVolume(dir, volumeID string) (*ephemeralVolume, error) {
+	path, err := getEphemeralVolumeRecordPath(dir, volumeID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	volume := &ephemeralVolume{}
+	if err := json.Unmarshal(data, volume); err != nil {
+		return nil, err
+	}
+	return volume, nil
+}
+
+// saveEphemeralVolume records the given ephemeral inline volume under the
+// given kubelet volume ID.
+func saveEphemeralVolume(dir, volumeID string, volume *ephemeralVolume) error {
+	path, err := getEphemeralVolumeRecordPath(dir, volumeID)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return err
+	}
+	data, err := json.Marshal(volume)
+	if err != nil {
+		return err
+	}
+	// Write the record atomically, so that a restart of the node plugin
+	// never leaves a partial record behind.
+	tmpPath := path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// removeEphemeralVolume removes the record of the ephemeral inline volume
+// with the given kubelet volume ID.
+func removeEphemeralVolume(dir, volumeID string) error {
+	path, err := getEphemeralVolumeRecordPath(dir, volumeID)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// getEphemeralVolumeClient returns the client managing the
+// CnsEphemeralVolume instances of this node, initializing it on first use.
+func (driver *vsphereCSIDriver) getEphemeralVolumeClient(ctx context.Context) (client.Client, error) {
+	driver.ephemeralLock.Lock()
+	defer driver.ephemeralLock.Unlock()
+	if driver.ephemeralClient != nil {
+		return driver.ephemeralClient, nil
+	}
+	if clusterFlavor != cnstypes.CnsClusterFlavorVanilla {
+		return nil, fmt.Errorf("ephemeral inline volumes are not supported in %s clusters", clusterFlavor)
+	}
+	config, err := k8s.GetKubeConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kubeconfig: %w", err)
+	}
+	crClient, err := k8s.NewClientForGroup(ctx, config, cnsoperatorv1alpha1.GroupName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for group %s: %w", cnsoperatorv1alpha1.GroupName, err)
+	}
+	driver.ephemeralClient = crClient
+	return driver.ephemeralClient, nil
+}
+
+// waitForEphemeralVolumeAttached waits for the syncer to attach the volume of
+// the CnsEphemeralVolume instance with the given name to this node, and
+// returns the attached instance.
+func waitForEphemeralVolumeAttached(ctx context.Context, crClient client.Client,
+	name string) (*cnsephemeralvolumev1alpha1.CnsEphemeralVolume, error) {
+	instance := &cnsephemeralvolumev1alpha1.CnsEphemeralVolume{}
+	err := wait.PollImmediate(ephemeralVolumePollInterval, ephemeralVolumeAttachTimeout, func() (bool, error) {
+		if err := crClient.Get(ctx, client.ObjectKey{Name: name}, instance); err != nil {
+			return false, err
+		}
+		return instance.Status.Attached, nil
+	})
+	if err == wait.ErrWaitTimeout && instance.Status.Error != "" {
+		return nil, fmt.Errorf("%w: %s", err, instance.Status.Error)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return instance, nil
+}
+
+// publishEphemeralVolume requests the syncer to create the CNS volume of the
+// ephemeral inline volume published by the given request and to attach it
+// to this node through a CnsEphemeralVolume instance, then stages and
+// publishes it. The node plugin doesn't need any vCenter credentials.
+func (driver *vsphereCSIDriver) publishEphemeralVolume(ctx context.Context,
+	req *csi.NodePublishVolumeRequest) (*csi.NodePublishVolumeResponse, error) {
+	log := logger.GetLogger(ctx)
+	if !commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.EphemeralInlineVolume) {
+		return nil, logger.LogNewErrorCode(log, codes.InvalidArgument,
+			"ephemeral inline volumes are not enabled")
+	}
+	if req.GetTargetPath() == "" {
+		return nil, logger.LogNewErrorCodef(log, codes.FailedPrecondition,
+			"target path %q not set", req.GetTargetPath())
+	}
+	volCap := req.GetVolumeCapability()
+	if volCap == nil || volCap.GetMount() == nil {
+		return nil, logger.LogNewErrorCode(log, codes.InvalidArgument,
+			"ephemeral inline volumes must have a mount volume capability")
+	}
+	nodeName := os.Getenv("NODE_NAME")
+	if nodeName == "" {
+		return nil, logger.LogNewErrorCode(log, codes.FailedPrecondition,
+			"ENV NODE_NAME is not set")
+	}
+	instance, err := getEphemeralVolumeInstance(req, nodeName)
+	if err != nil {
+		return nil, logger.LogNewErrorCode(log, codes.InvalidArgument, err.Error())
+	}
+	ephemeralVolumesDir := getEphemeralVolumesDir(driver.getKubeletRootDir(req.GetTargetPath()))
+	stagingPath, err := getEphemeralVolumeRecordPath(ephemeralVolumesDir, req.GetVolumeId())
+	if err != nil {
+		return nil, logger.LogNewErrorCode(log, codes.InvalidArgument, err.Error())
+	}
+	stagingPath = strings.TrimSuffix(stagingPath, ".json")
+	crClient, err := driver.getEphemeralVolumeClient(ctx)
+	if err != nil {
+		return nil, logger.LogNewErrorCodef(log, codes.FailedPrecondition,
+			"failed to provision ephemeral inline volume %q. Error: %v", req.GetVolumeId(), err)
+	}
+
+	// Record the volume before requesting it, so that NodeUnpublishVolume
+	// cleans it up even if publishing it fails.
+	volume := &ephemeralVolume{StagingPath: stagingPath}
+	if err := saveEphemeralVolume(ephemeralVolumesDir, req.GetVolumeId(), volume); err != nil {
+		return nil, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to record ephemeral inline volume %q. Error: %v", req.GetVolumeId(), err)
+	}
+	// The instance is named after the volume ID assigned by kubelet, so
+	// retries of NodePublishVolume reuse the instance of a previous attempt.
+	if err := crClient.Create(ctx, instance); err != nil && !apierrors.IsAlreadyExists(err) {
+		return nil, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to create CnsEphemeralVolume %q. Error: %v", instance.Name, err)
+	}
+	instance, err = waitForEphemeralVolumeAttached(ctx, crClient, instance.Name)
+	if err != nil {
+		return nil, logger.LogNewErrorCodef(log, codes.Unavailable,
+			"volume of ephemeral inline volume %q is not attached yet. Error: %v", req.GetVolumeId(), err)
+	}
+	volume.VolumeID = instance.Status.VolumeID
+	if err := saveEphemeralVolume(ephemeralVolumesDir, req.GetVolumeId(), volume); err != nil {
+		return nil, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to record ephemeral inline volume %q. Error: %v", req.GetVolumeId(), err)
+	}
+	log.Infof("Volume %q of ephemeral inline volume %q is attached", volume.VolumeID, req.GetVolumeId())
+
+	publishContext := map[string]string{
+		common.AttributeDiskType:           common.DiskTypeBlockVolume,
+		common.AttributeFirstClassDiskUUID: instance.Status.DiskUUID,
+	}
+	if err := os.MkdirAll(stagingPath, 0750); err != nil {
+		return nil, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to create staging path %q. Error: %v", stagingPath, err)
+	}
+	_, err = driver.NodeStageVolume(ctx, &csi.NodeStageVolumeRequest{
+		VolumeId:          volume.VolumeID,
+		PublishContext:    publishContext,
+		StagingTargetPath: stagingPath,
+		VolumeCapability:  volCap,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return driver.NodePublishVolume(ctx, &csi.NodePublishVolumeRequest{
+		VolumeId:          volume.VolumeID,
+		PublishContext:    publishContext,
+		StagingTargetPath: stagingPath,
+		TargetPath:        req.GetTargetPath(),
+		VolumeCapability:  volCap,
+		Readonly:          req.GetReadonly(),
+	})
+}
+
+// unpublishEphemeralVolume unpublishes and unstages the given ephemeral
+// inline volume, then deletes its CnsEphemeralVolume instance so that the
+// syncer detaches and deletes its CNS volume.
+func (driver *vsphereCSIDriver) unpublishEphemeralVolume(ctx context.Context,
+	req *csi.NodeUnpublishVolumeRequest, volume *ephemeralVolume) (*csi.NodeUnpublishVolumeResponse, error) {
+	log := logger.GetLogger(ctx)
+	crClient, err := driver.getEphemeralVolumeClient(ctx)
+	if err != nil {
+		return nil, logger.LogNewErrorCodef(log, codes.FailedPrecondition,
+			"failed to delete ephemeral inline volume %q. Error: %v", req.GetVolumeId(), err)
+	}
+	if err := driver.osUtils.CleanupPublishPath(ctx, req.GetTargetPath(), volume.VolumeID); err != nil {
+		return nil, logger.LogNewErrorCodef(log, codes.Internal,
+			"Unmount failed: %v\nUnmounting arguments: %s\n", err, req.GetTargetPath())
+	}
+	if _, err := os.Stat(volume.StagingPath); err == nil {
+		_, err = driver.NodeUnstageVolume(ctx, &csi.NodeUnstageVolumeRequest{
+			VolumeId:          volume.VolumeID,
+			StagingTargetPath: volume.StagingPath,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := os.Remove(volume.StagingPath); err != nil && !os.IsNotExist(err) {
+			return nil, logger.LogNewErrorCodef(log, codes.Internal,
+				"failed to remove staging path %q. Error: %v", volume.StagingPath, err)
+		}
+	}
+	instance := &cnsephemeralvolumev1alpha1.CnsEphemeralVolume{}
+	instance.Name = req.GetVolumeId()
+	if err := crClient.Delete(ctx, instance); err != nil && !apierrors.IsNotFound(err) {
+		return nil, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to delete CnsEphemeralVolume %q. Error: %v", instance.Name, err)
+	}
+	ephemeralVolumesDir := getEphemeralVolumesDir(driver.getKubeletRootDir(req.GetTargetPath()))
+	if err := removeEphemeralVolume(ephemeralVolumesDir, req.GetVolumeId()); err != nil {
+		return nil, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to remove record of ephemeral inline volume %q. Error: %v", req.GetVolumeId(), err)
+	}
+	log.Infof("Requested deletion of volume %q of ephemeral inline volume %q", volume.VolumeID,
+		req.GetVolumeId())
+	return &csi.NodeUnpublishVolumeResponse{}, nil
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+)
+
+func TestIsEphemeralVolumeRequest(t *testing.T) {
+	if isEphemeralVolumeRequest(nil) {
+		t.Errorf("expected a request without volume context not to be ephemeral")
+	}
+	if isEphemeralVolumeRequest(map[string]string{ephemeralVolumeContextKey: "false"}) {
+		t.Errorf("expected a request with %s=false not to be ephemeral", ephemeralVolumeContextKey)
+	}
+	if !isEphemeralVolumeRequest(map[string]string{ephemeralVolumeContextKey: "true"}) {
+		t.Errorf("expected a request with %s=true to be ephemeral", ephemeralVolumeContextKey)
+	}
+}
+
+func TestGetEphemeralVolumeInstance(t *testing.T) {
+	req := &csi.NodePublishVolumeRequest{
+		VolumeId: "csi-1234",
+		VolumeContext: map[string]string{
+			ephemeralVolumeContextKey:     "true",
+			"csi.storage.k8s.io/pod.name": "web",
+			ephemeralVolumeSizeAttribute:  "2Gi",
+			"storagepolicyname":           "gold",
+		},
+	}
+	instance, err := getEphemeralVolumeInstance(req, "node-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if instance.Name != "csi-1234" {
+		t.Errorf("unexpected instance name %q", instance.Name)
+	}
+	if instance.Spec.NodeName != "node-1" {
+		t.Errorf("unexpected node name %q", instance.Spec.NodeName)
+	}
+	if instance.Spec.CapacityInBytes != 2*1024*1024*1024 {
+		t.Errorf("unexpected capacity %d", instance.Spec.CapacityInBytes)
+	}
+	if !reflect.DeepEqual(instance.Spec.Parameters, map[string]string{"storagepolicyname": "gold"}) {
+		t.Errorf("unexpected parameters %v", instance.Spec.Parameters)
+	}
+
+	req.VolumeContext[ephemeralVolumeSizeAttribute] = "large"
+	if _, err := getEphemeralVolumeInstance(req, "node-1"); err == nil {
+		t.Errorf("expected an error for an invalid size")
+	}
+}
+
+func TestEphemeralVolumeRecords(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ephemeral")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	volume, err := loadEphemeralVolume(dir, "csi-1234")
+	if err != nil || volume != nil {
+		t.Fatalf("expected no record, got %v, %v", volume, err)
+	}
+	saved := &ephemeralVolume{VolumeID: "vol-1", StagingPath: dir + "/csi-1234"}
+	if err := saveEphemeralVolume(dir, "csi-1234", saved); err != nil {
+		t.Fatal(err)
+	}
+	volume, err = loadEphemeralVolume(dir, "csi-1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(volume, saved) {
+		t.Errorf("expected record %v, got %v", saved, volume)
+	}
+	if err := removeEphemeralVolume(dir, "csi-1234"); err != nil {
+		t.Fatal(err)
+	}
+	if volume, err = loadEphemeralVolume(dir, "csi-1234"); err != nil || volume != nil {
+		t.Errorf("expected no record after removal, got %v, %v", volume, err)
+	}
+	if err := removeEphemeralVolume(dir, "csi-1234"); err != nil {
+		t.Errorf("expected removing a missing record to succeed, got %v", err)
+	}
+	if _, err := loadEphemeralVolume(dir, "../csi-1234"); err == nil {
+		t.Errorf("expected an error for a volume ID with a path separator")
+	}
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/osutils"
+)
+
+const (
+	// envFilesystemHealthCheckInterval is the env variable holding the
+	// interval, in seconds, between checks of the file systems of the staged
+	// volumes.
+	envFilesystemHealthCheckInterval = "FILESYSTEM_HEALTH_CHECK_INTERVAL_SECONDS"
+	// defaultFilesystemHealthCheckInterval is the default interval between
+	// checks of the file systems of the staged volumes.
+	defaultFilesystemHealthCheckInterval = time.Minute
+	// event reason for pods using a volume whose file system has a problem
+	reasonFilesystemError = "VolumeFilesystemError"
+)
+
+// filesystemHealthMonitor periodically checks the file systems of the block
+// volumes staged on the node with pluggable checkers, e.g. for read-only
+// remounts and kernel I/O errors. The problems found mark the condition of
+// the volumes abnormal in NodeGetVolumeStats, and are reported as events on
+// the pods using the volumes.
+type filesystemHealthMonitor struct {
+	osUtils  *osutils.OsUtils
+	checkers []osutils.FilesystemChecker
+	events   *nodePodEventRecorder
+
+	// lock guards problems.
+	lock sync.RWMutex
+	// problems holds the problems found by the last check, keyed by device.
+	problems map[string]string
+}
+
+// newFilesystemHealthMonitor returns a filesystemHealthMonitor using the
+// file system checkers of the given OsUtils.
+func newFilesystemHealthMonitor(ctx context.Context, osUtils *osutils.OsUtils) *filesystemHealthMonitor {
+	return &filesystemHealthMonitor{
+		osUtils:  osUtils,
+		checkers: osUtils.GetFilesystemCheckers(),
+		events:   newNodePodEventRecorder(ctx, "FilesystemHealthMonitor"),
+		problems: make(map[string]string),
+	}
+}
+
+// getFilesystemHealthCheckInterval returns the interval between checks of
+// the file systems of the staged volumes.
+func getFilesystemHealthCheckInterval(ctx context.Context) time.Duration {
+	log := logger.GetLogger(ctx)
+	if v := os.Getenv(envFilesystemHealthCheckInterval); v != "" {
+		if value, err := strconv.Atoi(v); err == nil && value > 0 {
+			return time.Duration(value) * time.Second
+		}
+		log.Warnf("%s set in env variable %q is not a positive number of seconds. Using default %v",
+			envFilesystemHealthCheckInterval, v, defaultFilesystemHealthCheckInterval)
+	}
+	return defaultFilesystemHealthCheckInterval
+}
+
+// run checks the file systems of the staged volumes at the given interval
+// until the context is done.
+func (m *filesystemHealthMonitor) run(ctx context.Context, interval time.Duration) {
+	log := logger.GetLogger(ctx)
+	log.Infof("FilesystemHealthMonitor: checking file systems of staged volumes every %v", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		m.check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check runs the checkers on the staged volumes and reports the new
+// problems found.
+func (m *filesystemHealthMonitor) check(ctx context.Context) {
+	log := logger.GetLogger(ctx)
+	mounts, err := m.osUtils.GetStagedVolumeMounts(ctx)
+	if err != nil {
+		log.Errorf("FilesystemHealthMonitor: failed to get staged volumes. Err: %v", err)
+		return
+	}
+	problems := make(map[string]string)
+	for _, checker := range m.checkers {
+		checkerProblems, err := checker.Check(ctx, mounts)
+		if err != nil {
+			log.Errorf("FilesystemHealthMonitor: %s check failed. Err: %v", checker.Name(), err)
+			continue
+		}
+		for device, message := range checkerProblems {
+			if _, ok := problems[device]; !ok {
+				problems[device] = message
+			}
+		}
+	}
+
+	m.lock.Lock()
+	previousProblems := m.problems
+	m.problems = problems
+	m.lock.Unlock()
+
+	var events []podEvent
+	for _, mount := range mounts {
+		message, ok := problems[mount.Device]
+		if !ok {
+			continue
+		}
+		if previousProblems[mount.Device] != message {
+			log.Warnf("FilesystemHealthMonitor: volume staged at %q is abnormal: %s", mount.StagingPath, message)
+			events = append(events, podEvent{
+				podUIDs:   mount.PodUIDs,
+				eventType: v1.EventTypeWarning,
+				reason:    reasonFilesystemError,
+				message:   fmt.Sprintf("Volume staged at %s is abnormal: %s", mount.StagingPath, message),
+			})
+		}
+	}
+	m.events.emit(ctx, events)
+}
+
+// getProblem returns the problem found on the file system of the given
+// device, or an empty string if none was found.
+func (m *filesystemHealthMonitor) getProblem(device string) string {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.problems[device]
+}
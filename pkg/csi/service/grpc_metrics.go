@@ -0,0 +1,85 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	cnsvolume "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/volume"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/prometheus"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+// newMetricsInterceptor returns an interceptor observing the latency, status
+// code and concurrency of the requests, and the part of their latency spent
+// waiting on CNS tasks.
+func newMetricsInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		method := path.Base(info.FullMethod)
+		inFlight := prometheus.GRPCRequestsInFlightGaugeVec.WithLabelValues(method)
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		ctx = cnsvolume.WithTaskWaitTracking(ctx)
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		duration := time.Since(start)
+		taskWait := cnsvolume.GetTaskWaitTime(ctx)
+		code := status.Code(err).String()
+		prometheus.GRPCRequestsHistVec.WithLabelValues(method, code).Observe(duration.Seconds())
+		prometheus.GRPCRequestsCounterVec.WithLabelValues(method, code).Inc()
+		if taskWait > 0 {
+			prometheus.GRPCCnsTaskWaitHistVec.WithLabelValues(method).Observe(taskWait.Seconds())
+		}
+		logger.GetLogger(ctx).Debugf("%s completed with code %s in %v, of which %v waiting on CNS tasks",
+			method, code, duration, taskWait)
+		return resp, err
+	}
+}
+
+// serveNodeMetrics exposes the Prometheus metrics of the node plugin on the
+// address set in X_CSI_NODE_METRICS_ADDRESS, if any.
+func serveNodeMetrics(ctx context.Context) {
+	log := logger.GetLogger(ctx)
+	address := os.Getenv(csitypes.EnvVarNodeMetricsAddress)
+	if address == "" {
+		return
+	}
+	go func() {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.Handler())
+		for {
+			log.Infof("Starting the http server to expose Prometheus metrics on %s..", address)
+			err := http.ListenAndServe(address, mux)
+			if err != nil {
+				log.Warnf("Http server that exposes the Prometheus exited with err: %+v", err)
+			}
+			log.Info("Restarting http server to expose Prometheus metrics..")
+			time.Sleep(time.Second)
+		}
+	}()
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/prometheus"
+)
+
+// TestMetricsInterceptor tests that requests are counted by method and
+// status code, and are no longer in flight once served.
+func TestMetricsInterceptor(t *testing.T) {
+	interceptor := newMetricsInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/csi.v1.Controller/CreateVolume"}
+	var inFlight float64
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		inFlight = testutil.ToFloat64(prometheus.GRPCRequestsInFlightGaugeVec.WithLabelValues("CreateVolume"))
+		return nil, status.Error(codes.Internal, "failed")
+	}
+
+	if _, err := interceptor(context.Background(), nil, info, handler); status.Code(err) != codes.Internal {
+		t.Fatalf("expected the error of the handler, got %v", err)
+	}
+	if inFlight != 1 {
+		t.Errorf("expected 1 request in flight while served, got %v", inFlight)
+	}
+	if value := testutil.ToFloat64(prometheus.GRPCRequestsInFlightGaugeVec.WithLabelValues("CreateVolume")); value != 0 {
+		t.Errorf("expected no request in flight once served, got %v", value)
+	}
+	if value := testutil.ToFloat64(prometheus.GRPCRequestsCounterVec.WithLabelValues("CreateVolume",
+		codes.Internal.String())); value != 1 {
+		t.Errorf("expected 1 failed CreateVolume request, got %v", value)
+	}
+}
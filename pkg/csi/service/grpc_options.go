@@ -0,0 +1,106 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+// defaultGRPCDeadline is the deadline of the requests received without a
+// deadline, unless overridden by X_CSI_GRPC_DEFAULT_DEADLINE.
+const defaultGRPCDeadline = 10 * time.Minute
+
+// GRPCServerOptions returns the gRPC server options of the CSI endpoint
+// configured through the X_CSI_GRPC_* environment variables. Interceptors
+// are returned separately by GRPCServerInterceptors, as gocsi chains them
+// with its own.
+func GRPCServerOptions(ctx context.Context) []grpc.ServerOption {
+	var opts []grpc.ServerOption
+	if maxStreams := getPositiveIntEnv(ctx, csitypes.EnvVarGRPCMaxConcurrentStreams); maxStreams > 0 {
+		opts = append(opts, grpc.MaxConcurrentStreams(uint32(maxStreams)))
+	}
+	if maxRecvMsgSize := getPositiveIntEnv(ctx, csitypes.EnvVarGRPCMaxRecvMsgSize); maxRecvMsgSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(maxRecvMsgSize))
+	}
+	return opts
+}
+
+// GRPCServerInterceptors returns the unary interceptors of the CSI endpoint.
+func GRPCServerInterceptors(ctx context.Context) []grpc.UnaryServerInterceptor {
+	interceptors := []grpc.UnaryServerInterceptor{newMetricsInterceptor()}
+	if deadline := getDefaultGRPCDeadline(ctx); deadline > 0 {
+		interceptors = append(interceptors, newDefaultDeadlineInterceptor(deadline))
+	}
+	return interceptors
+}
+
+// newDefaultDeadlineInterceptor returns an interceptor setting the given
+// deadline on the requests received without one, so that requests of a
+// misconfigured sidecar can't hold the driver indefinitely.
+func newDefaultDeadlineInterceptor(deadline time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, deadline)
+			defer cancel()
+		}
+		return handler(ctx, req)
+	}
+}
+
+// getDefaultGRPCDeadline returns the deadline set in
+// X_CSI_GRPC_DEFAULT_DEADLINE, or defaultGRPCDeadline if it isn't set or
+// invalid.
+func getDefaultGRPCDeadline(ctx context.Context) time.Duration {
+	log := logger.GetLogger(ctx)
+	v := os.Getenv(csitypes.EnvVarGRPCDefaultDeadline)
+	if v == "" {
+		return defaultGRPCDeadline
+	}
+	deadline, err := time.ParseDuration(v)
+	if err != nil || deadline < 0 {
+		log.Warnf("%s %q is invalid, will use the default value %v",
+			csitypes.EnvVarGRPCDefaultDeadline, v, defaultGRPCDeadline)
+		return defaultGRPCDeadline
+	}
+	return deadline
+}
+
+// getPositiveIntEnv returns the value of the given environment variable, or
+// 0 if it isn't set or isn't a positive integer.
+func getPositiveIntEnv(ctx context.Context, name string) int {
+	log := logger.GetLogger(ctx)
+	v := os.Getenv(name)
+	if v == "" {
+		return 0
+	}
+	value, err := strconv.Atoi(v)
+	if err != nil || value <= 0 {
+		log.Warnf("%s %q is not a positive integer, ignoring it", name, v)
+		return 0
+	}
+	return value
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+// TestDefaultDeadlineInterceptor tests that the default deadline is only set
+// on requests received without a deadline.
+func TestDefaultDeadlineInterceptor(t *testing.T) {
+	interceptor := newDefaultDeadlineInterceptor(time.Minute)
+	var handlerDeadline time.Time
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		handlerDeadline, _ = ctx.Deadline()
+		return nil, nil
+	}
+
+	start := time.Now()
+	if _, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, handler); err != nil {
+		t.Fatal(err)
+	}
+	if handlerDeadline.Before(start.Add(time.Minute)) || handlerDeadline.After(time.Now().Add(time.Minute)) {
+		t.Errorf("expected a deadline in a minute, got %v", handlerDeadline)
+	}
+
+	deadline := time.Now().Add(time.Hour)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	if _, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler); err != nil {
+		t.Fatal(err)
+	}
+	if !handlerDeadline.Equal(deadline) {
+		t.Errorf("expected the deadline of the request %v, got %v", deadline, handlerDeadline)
+	}
+}
+
+func TestGetDefaultGRPCDeadline(t *testing.T) {
+	ctx := context.Background()
+	defer os.Unsetenv(csitypes.EnvVarGRPCDefaultDeadline)
+	tests := map[string]time.Duration{
+		"":        defaultGRPCDeadline,
+		"2m":      2 * time.Minute,
+		"0":       0,
+		"invalid": defaultGRPCDeadline,
+		"-1s":     defaultGRPCDeadline,
+	}
+	for value, expected := range tests {
+		os.Setenv(csitypes.EnvVarGRPCDefaultDeadline, value)
+		if deadline := getDefaultGRPCDeadline(ctx); deadline != expected {
+			t.Errorf("%q: expected %v, got %v", value, expected, deadline)
+		}
+	}
+}
@@ -20,6 +20,11 @@ import (
 	"context"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
+	cnstypes "github.com/vmware/govmomi/cns/types"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common/commonco"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
 	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
 )
 
@@ -50,6 +55,7 @@ func (driver *vsphereCSIDriver) GetPluginCapabilities(
 	req *csi.GetPluginCapabilitiesRequest) (
 	*csi.GetPluginCapabilitiesResponse, error) {
 
+	ctx = logger.NewContextWithLogger(ctx)
 	rep := &csi.GetPluginCapabilitiesResponse{
 		Capabilities: []*csi.PluginCapability{
 			{
@@ -68,5 +74,35 @@ func (driver *vsphereCSIDriver) GetPluginCapabilities(
 			},
 		},
 	}
+	if expansionType, ok := getVolumeExpansionType(ctx); ok {
+		rep.Capabilities = append(rep.Capabilities, &csi.PluginCapability{
+			Type: &csi.PluginCapability_VolumeExpansion_{
+				VolumeExpansion: &csi.PluginCapability_VolumeExpansion{
+					Type: expansionType,
+				},
+			},
+		})
+	}
 	return rep, nil
 }
+
+// getVolumeExpansionType returns the type of volume expansion supported by
+// the driver for the current cluster flavor and feature states. The second
+// return value is false if volume expansion is not supported at all.
+func getVolumeExpansionType(ctx context.Context) (csi.PluginCapability_VolumeExpansion_Type, bool) {
+	log := logger.GetLogger(ctx)
+	if commonco.ContainerOrchestratorUtility == nil {
+		log.Debugf("Container orchestrator is not initialized. Not reporting volume expansion capability.")
+		return csi.PluginCapability_VolumeExpansion_UNKNOWN, false
+	}
+	if clusterFlavor != cnstypes.CnsClusterFlavorVanilla &&
+		!commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.VolumeExtend) {
+		log.Infof("Not reporting volume expansion capability as %q feature is disabled "+
+			"for cluster flavor %q", common.VolumeExtend, clusterFlavor)
+		return csi.PluginCapability_VolumeExpansion_UNKNOWN, false
+	}
+	if commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.OnlineVolumeExtend) {
+		return csi.PluginCapability_VolumeExpansion_ONLINE, true
+	}
+	return csi.PluginCapability_VolumeExpansion_OFFLINE, true
+}
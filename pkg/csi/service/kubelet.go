@@ -0,0 +1,117 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+const (
+	// defaultKubeletRootDir is the default root directory of kubelet, used
+	// when it is neither configured nor discovered from request paths.
+	defaultKubeletRootDir = "/var/lib/kubelet"
+)
+
+// kubeletPathMarkers are the path segments following the kubelet root
+// directory in the staging and target paths kubelet passes to the node
+// plugin, e.g.
+//
+//	<root>/plugins/kubernetes.io/csi/pv/<pv>/globalmount
+//	<root>/pods/<pod uid>/volumes/kubernetes.io~csi/<pv>/mount
+//	<root>/plugins/kubernetes.io/csi/volumeDevices/publish/<pv>/<pod uid>
+var kubeletPathMarkers = []string{
+	"/plugins/kubernetes.io/csi/",
+	"/pods/",
+}
+
+// validateKubeletRootDir returns an error if the given directory can't be
+// the kubelet root directory, i.e. if it isn't an absolute, clean path to an
+// existing directory.
+func validateKubeletRootDir(dir string) error {
+	if !filepath.IsAbs(dir) {
+		return fmt.Errorf("kubelet root directory %q is not an absolute path", dir)
+	}
+	if filepath.Clean(dir) != dir {
+		return fmt.Errorf("kubelet root directory %q is not a clean path, expected %q", dir, filepath.Clean(dir))
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("failed to stat kubelet root directory %q: %w", dir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("kubelet root directory %q is not a directory", dir)
+	}
+	return nil
+}
+
+// discoverKubeletRootDir returns the kubelet root directory the given
+// staging or target path lives in, or false if it isn't a path created by
+// kubelet.
+func discoverKubeletRootDir(path string) (string, bool) {
+	path = filepath.ToSlash(filepath.Clean(path))
+	for _, marker := range kubeletPathMarkers {
+		index := strings.LastIndex(path, marker)
+		if index <= 0 {
+			continue
+		}
+		// Pod directories of kubelet hold the volumes of the pod.
+		if marker == "/pods/" && !strings.Contains(path[index:], "/volumes/") {
+			continue
+		}
+		return filepath.FromSlash(path[:index]), true
+	}
+	return "", false
+}
+
+// getKubeletRootDir returns the kubelet root directory. The directory
+// configured with the --kubelet-root-dir flag takes precedence, then the one
+// discovered from the given staging or target path, and finally the default
+// one.
+func (driver *vsphereCSIDriver) getKubeletRootDir(path string) string {
+	if driver.kubeletRootDir != "" {
+		return driver.kubeletRootDir
+	}
+	if dir, ok := discoverKubeletRootDir(path); ok {
+		return dir
+	}
+	return defaultKubeletRootDir
+}
+
+// getPluginDir returns the directory of the node plugin under the given
+// kubelet root directory, which holds its socket and its state.
+func getPluginDir(kubeletRootDir string) string {
+	return filepath.Join(kubeletRootDir, "plugins", csitypes.Name)
+}
+
+// validateKubeletPath returns an error if the given staging or target path
+// isn't under the configured kubelet root directory, which points out a
+// --kubelet-root-dir flag not matching the --root-dir flag of kubelet.
+func (driver *vsphereCSIDriver) validateKubeletPath(path string) error {
+	if driver.kubeletRootDir == "" || path == "" {
+		return nil
+	}
+	rel, err := filepath.Rel(driver.kubeletRootDir, filepath.Clean(path))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("path %q is not under the kubelet root directory %q", path, driver.kubeletRootDir)
+	}
+	return nil
+}
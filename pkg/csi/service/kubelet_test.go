@@ -0,0 +1,90 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiscoverKubeletRootDir(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+		ok       bool
+	}{
+		{"/var/lib/kubelet/plugins/kubernetes.io/csi/pv/pvc-1/globalmount", "/var/lib/kubelet", true},
+		{"/var/lib/kubelet/pods/1234/volumes/kubernetes.io~csi/pvc-1/mount", "/var/lib/kubelet", true},
+		{"/var/lib/kubelet/plugins/kubernetes.io/csi/volumeDevices/publish/pvc-1/1234", "/var/lib/kubelet", true},
+		{"/var/snap/microk8s/common/var/lib/kubelet/pods/1234/volumes/kubernetes.io~csi/pvc-1/mount",
+			"/var/snap/microk8s/common/var/lib/kubelet", true},
+		{"/data/pods/kubelet/pods/1234/volumes/kubernetes.io~csi/pvc-1/mount", "/data/pods/kubelet", true},
+		{"/mnt/pods/target", "", false},
+		{"/tmp/target", "", false},
+	}
+	for _, test := range tests {
+		dir, ok := discoverKubeletRootDir(test.path)
+		if dir != test.expected || ok != test.ok {
+			t.Errorf("discoverKubeletRootDir(%q): expected %q, %v, got %q, %v", test.path, test.expected, test.ok,
+				dir, ok)
+		}
+	}
+}
+
+func TestValidateKubeletRootDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubelet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(file, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := validateKubeletRootDir(dir); err != nil {
+		t.Errorf("expected %q to be valid, got %v", dir, err)
+	}
+	for _, invalid := range []string{"var/lib/kubelet", dir + "/", dir + "/../kubelet", file,
+		filepath.Join(dir, "missing")} {
+		if err := validateKubeletRootDir(invalid); err == nil {
+			t.Errorf("expected %q to be invalid", invalid)
+		}
+	}
+}
+
+func TestValidateKubeletPath(t *testing.T) {
+	driver := &vsphereCSIDriver{}
+	if err := driver.validateKubeletPath("/tmp/target"); err != nil {
+		t.Errorf("expected any path to be valid without kubelet root directory, got %v", err)
+	}
+	driver.kubeletRootDir = "/var/lib/kubelet"
+	if err := driver.validateKubeletPath("/var/lib/kubelet/pods/1234/volumes/kubernetes.io~csi/pvc-1/mount"); err != nil {
+		t.Errorf("expected path under the kubelet root directory to be valid, got %v", err)
+	}
+	for _, invalid := range []string{"/tmp/target", "/var/lib/kubelet-other/pods", "/var/lib/kubelet/../target"} {
+		if err := driver.validateKubeletPath(invalid); err == nil {
+			t.Errorf("expected %q to be invalid", invalid)
+		}
+	}
+	if dir := driver.getKubeletRootDir("/data/kubelet/pods/1234/volumes/kubernetes.io~csi/pvc-1/mount"); dir !=
+		"/var/lib/kubelet" {
+		t.Errorf("expected the configured kubelet root directory, got %q", dir)
+	}
+}
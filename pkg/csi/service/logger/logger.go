@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/google/uuid"
+	vim25types "github.com/vmware/govmomi/vim25/types"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"google.golang.org/grpc/codes"
@@ -24,6 +25,12 @@ const (
 	EnvLoggerLevel = "LOGGER_LEVEL"
 	// LogCtxIDKey holds the TraceId for log.
 	LogCtxIDKey = "TraceId"
+	// OperationIDPrefix is the prefix of the vSphere operation IDs derived
+	// from trace IDs.
+	OperationIDPrefix = "csi-"
+	// operationIDTraceIDLength is the number of leading characters of the
+	// trace ID used in operation IDs.
+	operationIDTraceIDLength = 8
 )
 
 var defaultLogLevel LogLevel
@@ -58,9 +65,30 @@ func GetLogger(ctx context.Context) *zap.SugaredLogger {
 
 // NewContextWithLogger returns a new child context with context UUID set
 // using key CtxId.
+// A short operation ID derived from the context UUID is set as the operation
+// ID of the vSphere API requests made with the context. vCenter records it on
+// the tasks and in the logs of these requests, so that a VC administrator can
+// find the matching driver logs by searching the operation ID without its
+// prefix.
 func NewContextWithLogger(ctx context.Context) context.Context {
-	newCtx := withFields(ctx, zap.String(LogCtxIDKey, uuid.New().String()))
-	return newCtx
+	traceID := uuid.New().String()
+	newCtx := withFields(ctx, zap.String(LogCtxIDKey, traceID))
+	return context.WithValue(newCtx, vim25types.ID{}, getOperationID(traceID))
+}
+
+// GetOperationID returns the vSphere operation ID set on the given context,
+// or an empty string if there is none.
+func GetOperationID(ctx context.Context) string {
+	operationID, _ := ctx.Value(vim25types.ID{}).(string)
+	return operationID
+}
+
+// getOperationID returns the vSphere operation ID for the given trace ID.
+func getOperationID(traceID string) string {
+	if len(traceID) > operationIDTraceIDLength {
+		traceID = traceID[:operationIDTraceIDLength]
+	}
+	return OperationIDPrefix + traceID
 }
 
 // GetNewContextWithLogger creates a new context with context UUID and logger
@@ -102,11 +130,12 @@ func LogNewError(log *zap.SugaredLogger, msg string) error {
 	return errors.New(msg)
 }
 
-// LogNewErrorf logs a formated msg, and returns error with msg.
+// LogNewErrorf logs a formated msg, and returns error with msg. As with
+// fmt.Errorf, the returned error wraps the operand of a %w verb.
 func LogNewErrorf(log *zap.SugaredLogger, format string, a ...interface{}) error {
-	msg := fmt.Sprintf(format, a...)
-	log.Desugar().WithOptions(zap.AddCallerSkip(1)).Sugar().Error(msg)
-	return errors.New(msg)
+	err := fmt.Errorf(format, a...)
+	log.Desugar().WithOptions(zap.AddCallerSkip(1)).Sugar().Error(err.Error())
+	return err
 }
 
 // LogNewErrorCode logs an error msg, and returns error with code and msg.
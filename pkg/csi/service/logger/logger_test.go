@@ -17,11 +17,29 @@ limitations under the License.
 package logger
 
 import (
+	"context"
+	"errors"
+	"strings"
 	"testing"
 
 	"google.golang.org/grpc/codes"
 )
 
+func TestNewContextWithLoggerSetsOperationID(t *testing.T) {
+	ctx := NewContextWithLogger(context.Background())
+	operationID := GetOperationID(ctx)
+	if !strings.HasPrefix(operationID, OperationIDPrefix) ||
+		len(operationID) != len(OperationIDPrefix)+operationIDTraceIDLength {
+		t.Errorf("unexpected operation ID %q", operationID)
+	}
+	if GetOperationID(NewContextWithLogger(ctx)) == operationID {
+		t.Errorf("expected a new operation ID for a new trace ID")
+	}
+	if GetOperationID(context.Background()) != "" {
+		t.Errorf("expected no operation ID on a context without logger")
+	}
+}
+
 func TestLogNewError(t *testing.T) {
 	log := GetLoggerWithNoContext()
 	e := LogNewError(log, "Error Test")
@@ -36,6 +54,11 @@ func TestLogNewErrorf(t *testing.T) {
 	if e == nil {
 		t.Error("Failed to create an error")
 	}
+	cause := errors.New("cause")
+	e = LogNewErrorf(log, "wrapped: %w", cause)
+	if !errors.Is(e, cause) || e.Error() != "wrapped: cause" {
+		t.Errorf("expected error wrapping %v, got %v", cause, e)
+	}
 }
 
 func TestLogNewErrorCode(t *testing.T) {
@@ -0,0 +1,46 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mounter
+
+import (
+	"strings"
+)
+
+// normalizeDiskID returns the hex digits of the given disk identifier in
+// lower case, dropping the "eui." prefix and the separators which NVMe
+// namespace identifiers are reported with.
+func normalizeDiskID(id string) string {
+	id = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(id)), "eui.")
+	return strings.Map(func(r rune) rune {
+		if (r >= '0' && r <= '9') || (r >= 'a' && r <= 'f') {
+			return r
+		}
+		return -1
+	}, id)
+}
+
+// matchDiskID returns true if the given disk UUID of a volume identifies the
+// disk with the given SCSI page 83 identifier and serial number. SCSI disks
+// are identified by their page 83 identifier, and NVMe disks, which may not
+// report one, by the EUI of their namespace reported as serial number.
+func matchDiskID(diskUUID, page83, serialNumber string) bool {
+	id := normalizeDiskID(diskUUID)
+	if id == "" {
+		return false
+	}
+	if page83 != "" && normalizeDiskID(page83) == id {
+		return true
+	}
+	return serialNumber != "" && normalizeDiskID(serialNumber) == id
+}
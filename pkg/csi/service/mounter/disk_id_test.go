@@ -0,0 +1,42 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mounter
+
+import (
+	"testing"
+)
+
+func TestMatchDiskID(t *testing.T) {
+	tests := []struct {
+		name         string
+		diskUUID     string
+		page83       string
+		serialNumber string
+		expected     bool
+	}{
+		{"scsi", "6000c2912f0e9a3e1b4c2d5f6a7b8c9d", "6000c2912f0e9a3e1b4c2d5f6a7b8c9d", "", true},
+		{"scsi upper case", "6000c2912f0e9a3e1b4c2d5f6a7b8c9d", "6000C2912F0E9A3E1B4C2D5F6A7B8C9D", "", true},
+		{"scsi other disk", "6000c2912f0e9a3e1b4c2d5f6a7b8c9d", "6000c2912f0e9a3e1b4c2d5f6a7b8c9e", "", false},
+		{"nvme eui", "6000c2912f0e9a3e1b4c2d5f6a7b8c9d", "", "6000_C291_2F0E_9A3E_1B4C_2D5F_6A7B_8C9D.", true},
+		{"nvme eui prefix", "6000c2912f0e9a3e1b4c2d5f6a7b8c9d", "", "eui.6000c2912f0e9a3e1b4c2d5f6a7b8c9d", true},
+		{"nvme other disk", "6000c2912f0e9a3e1b4c2d5f6a7b8c9d", "", "0000_0000_0000_0001.", false},
+		{"no identifiers", "6000c2912f0e9a3e1b4c2d5f6a7b8c9d", "", "", false},
+		{"empty disk uuid", "", "", "", false},
+	}
+	for _, test := range tests {
+		if matched := matchDiskID(test.diskUUID, test.page83, test.serialNumber); matched != test.expected {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, matched)
+		}
+	}
+}
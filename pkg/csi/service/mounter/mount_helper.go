@@ -0,0 +1,244 @@
+//go:build darwin || linux
+// +build darwin linux
+
+/*
+Copyright 2022 The Kubernetes Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mounter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/akutz/gofsutil"
+	"k8s.io/mount-utils"
+	utilexec "k8s.io/utils/exec"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+)
+
+// Operations supported by the mount helper daemon.
+const (
+	mountHelperOpFormatAndMount = "FormatAndMount"
+	mountHelperOpMount          = "Mount"
+	mountHelperOpBindMount      = "BindMount"
+	mountHelperOpUnmount        = "Unmount"
+	mountHelperOpResize         = "Resize"
+)
+
+// FsMounter performs mount operations on the host. The signatures follow
+// the gofsutil package which is used by default.
+type FsMounter interface {
+	// FormatAndMount formats the source device with mkfs if needed and mounts
+	// it to target.
+	FormatAndMount(ctx context.Context, source, target, fsType string, opts ...string) error
+	// Mount mounts source to target as fsType with the given options.
+	Mount(ctx context.Context, source, target, fsType string, opts ...string) error
+	// BindMount bind mounts source to target with the given options.
+	BindMount(ctx context.Context, source, target string, opts ...string) error
+	// Unmount unmounts the target.
+	Unmount(ctx context.Context, target string) error
+	// Resize grows the file system of the source device mounted at target to
+	// the size of the device.
+	Resize(ctx context.Context, source, target string) error
+}
+
+// NewFsMounter returns an FsMounter which performs mount operations through
+// the mount helper daemon listening on helperSocket. If helperSocket is
+// empty, mount operations are performed from within the node plugin
+// container.
+func NewFsMounter(ctx context.Context, helperSocket string) FsMounter {
+	log := logger.GetLogger(ctx)
+	if helperSocket == "" {
+		return &gofsutilMounter{}
+	}
+	log.Infof("Mount operations will be performed through the mount helper at %q", helperSocket)
+	return &helperMounter{socket: helperSocket}
+}
+
+// gofsutilMounter performs mount operations using gofsutil.
+type gofsutilMounter struct{}
+
+func (m *gofsutilMounter) FormatAndMount(ctx context.Context, source, target, fsType string, opts ...string) error {
+	return gofsutil.FormatAndMount(ctx, source, target, fsType, opts...)
+}
+
+func (m *gofsutilMounter) Mount(ctx context.Context, source, target, fsType string, opts ...string) error {
+	return gofsutil.Mount(ctx, source, target, fsType, opts...)
+}
+
+func (m *gofsutilMounter) BindMount(ctx context.Context, source, target string, opts ...string) error {
+	return gofsutil.BindMount(ctx, source, target, opts...)
+}
+
+func (m *gofsutilMounter) Unmount(ctx context.Context, target string) error {
+	return gofsutil.Unmount(ctx, target)
+}
+
+func (m *gofsutilMounter) Resize(ctx context.Context, source, target string) error {
+	_, err := mount.NewResizeFs(utilexec.New()).Resize(source, target)
+	return err
+}
+
+// mountHelperRequest is the request sent to the mount helper daemon.
+type mountHelperRequest struct {
+	Op      string   `json:"op"`
+	Source  string   `json:"source,omitempty"`
+	Target  string   `json:"target"`
+	FsType  string   `json:"fsType,omitempty"`
+	Options []string `json:"options,omitempty"`
+}
+
+// mountHelperResponse is the response returned by the mount helper daemon.
+type mountHelperResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+// helperMounter forwards mount operations to the mount helper daemon.
+type helperMounter struct {
+	socket string
+}
+
+func (m *helperMounter) FormatAndMount(ctx context.Context, source, target, fsType string, opts ...string) error {
+	return m.call(ctx, &mountHelperRequest{Op: mountHelperOpFormatAndMount, Source: source, Target: target,
+		FsType: fsType, Options: opts})
+}
+
+func (m *helperMounter) Mount(ctx context.Context, source, target, fsType string, opts ...string) error {
+	return m.call(ctx, &mountHelperRequest{Op: mountHelperOpMount, Source: source, Target: target,
+		FsType: fsType, Options: opts})
+}
+
+func (m *helperMounter) BindMount(ctx context.Context, source, target string, opts ...string) error {
+	return m.call(ctx, &mountHelperRequest{Op: mountHelperOpBindMount, Source: source, Target: target,
+		Options: opts})
+}
+
+func (m *helperMounter) Unmount(ctx context.Context, target string) error {
+	return m.call(ctx, &mountHelperRequest{Op: mountHelperOpUnmount, Target: target})
+}
+
+func (m *helperMounter) Resize(ctx context.Context, source, target string) error {
+	return m.call(ctx, &mountHelperRequest{Op: mountHelperOpResize, Source: source, Target: target})
+}
+
+// call sends a single request to the mount helper daemon and waits for the
+// response.
+func (m *helperMounter) call(ctx context.Context, req *mountHelperRequest) error {
+	log := logger.GetLogger(ctx)
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", m.socket)
+	if err != nil {
+		return fmt.Errorf("failed to connect to mount helper at %q. Err: %w", m.socket, err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return err
+		}
+	}
+	log.Debugf("Sending %s request for target %q to mount helper", req.Op, req.Target)
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return fmt.Errorf("failed to send %s request to mount helper. Err: %w", req.Op, err)
+	}
+	var resp mountHelperResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return fmt.Errorf("failed to read %s response from mount helper. Err: %w", req.Op, err)
+	}
+	if resp.Error != "" {
+		return errors.New(resp.Error)
+	}
+	return nil
+}
+
+// ServeMountHelper listens on the given unix socket and performs the
+// requested mount operations on the host until ctx is cancelled. It is meant
+// to run in a host level daemon on distributions where mounting from within
+// the node plugin container is not possible.
+func ServeMountHelper(ctx context.Context, socket string) error {
+	return serveMountHelper(ctx, socket, &gofsutilMounter{})
+}
+
+// serveMountHelper listens on the given unix socket and performs the
+// requested mount operations with fsMounter until ctx is cancelled.
+func serveMountHelper(ctx context.Context, socket string, fsMounter FsMounter) error {
+	log := logger.GetLogger(ctx)
+	if err := os.Remove(socket); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove stale socket %q. Err: %w", socket, err)
+	}
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %q. Err: %w", socket, err)
+	}
+	// Only the node plugin, running as root, is expected to connect.
+	if err := os.Chmod(socket, 0600); err != nil {
+		listener.Close()
+		return fmt.Errorf("failed to set permissions on %q. Err: %w", socket, err)
+	}
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+	log.Infof("Mount helper listening on %q", socket)
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			log.Errorf("failed to accept connection on %q. Err: %v", socket, err)
+			continue
+		}
+		go handleMountHelperConn(ctx, fsMounter, conn)
+	}
+}
+
+// handleMountHelperConn serves a single mount helper request.
+func handleMountHelperConn(ctx context.Context, fsMounter FsMounter, conn net.Conn) {
+	log := logger.GetLogger(ctx)
+	defer conn.Close()
+	var req mountHelperRequest
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		log.Errorf("failed to decode mount helper request. Err: %v", err)
+		return
+	}
+	log.Infof("Received %s request with source %q, target %q, fsType %q and options %v",
+		req.Op, req.Source, req.Target, req.FsType, req.Options)
+	var err error
+	switch req.Op {
+	case mountHelperOpFormatAndMount:
+		err = fsMounter.FormatAndMount(ctx, req.Source, req.Target, req.FsType, req.Options...)
+	case mountHelperOpMount:
+		err = fsMounter.Mount(ctx, req.Source, req.Target, req.FsType, req.Options...)
+	case mountHelperOpBindMount:
+		err = fsMounter.BindMount(ctx, req.Source, req.Target, req.Options...)
+	case mountHelperOpUnmount:
+		err = fsMounter.Unmount(ctx, req.Target)
+	case mountHelperOpResize:
+		err = fsMounter.Resize(ctx, req.Source, req.Target)
+	default:
+		err = fmt.Errorf("unsupported mount helper operation %q", req.Op)
+	}
+	var resp mountHelperResponse
+	if err != nil {
+		log.Errorf("%s request for target %q failed. Err: %v", req.Op, req.Target, err)
+		resp.Error = err.Error()
+	}
+	if err := json.NewEncoder(conn).Encode(&resp); err != nil {
+		log.Errorf("failed to send mount helper response. Err: %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+//go:build darwin || linux
+// +build darwin linux
+
+/*
+Copyright 2022 The Kubernetes Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mounter
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeFsMounter records the mount operations performed by the mount helper.
+type fakeFsMounter struct {
+	lock     sync.Mutex
+	requests []mountHelperRequest
+	err      error
+}
+
+func (m *fakeFsMounter) record(req mountHelperRequest) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.requests = append(m.requests, req)
+	return m.err
+}
+
+func (m *fakeFsMounter) FormatAndMount(ctx context.Context, source, target, fsType string, opts ...string) error {
+	return m.record(mountHelperRequest{Op: mountHelperOpFormatAndMount, Source: source, Target: target,
+		FsType: fsType, Options: opts})
+}
+
+func (m *fakeFsMounter) Mount(ctx context.Context, source, target, fsType string, opts ...string) error {
+	return m.record(mountHelperRequest{Op: mountHelperOpMount, Source: source, Target: target, FsType: fsType,
+		Options: opts})
+}
+
+func (m *fakeFsMounter) BindMount(ctx context.Context, source, target string, opts ...string) error {
+	return m.record(mountHelperRequest{Op: mountHelperOpBindMount, Source: source, Target: target, Options: opts})
+}
+
+func (m *fakeFsMounter) Unmount(ctx context.Context, target string) error {
+	return m.record(mountHelperRequest{Op: mountHelperOpUnmount, Target: target})
+}
+
+func (m *fakeFsMounter) Resize(ctx context.Context, source, target string) error {
+	return m.record(mountHelperRequest{Op: mountHelperOpResize, Source: source, Target: target})
+}
+
+// startTestMountHelper serves the mount helper with fsMounter on a temporary
+// socket and returns a mounter forwarding to it.
+func startTestMountHelper(t *testing.T, fsMounter FsMounter) FsMounter {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	socket := filepath.Join(t.TempDir(), "mount-helper.sock")
+	go func() {
+		if err := serveMountHelper(ctx, socket, fsMounter); err != nil {
+			t.Errorf("mount helper failed: %v", err)
+		}
+	}()
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		if info, err := os.Stat(socket); err == nil && info.Mode().Perm() == 0600 {
+			break
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("mount helper didn't listen on %q", socket)
+		}
+	}
+	return NewFsMounter(ctx, socket)
+}
+
+func TestMountHelper(t *testing.T) {
+	ctx := context.Background()
+	fsMounter := &fakeFsMounter{}
+	helper := startTestMountHelper(t, fsMounter)
+	if _, ok := helper.(*helperMounter); !ok {
+		t.Fatalf("expected the mount operations to be forwarded to the mount helper, got %T", helper)
+	}
+
+	for _, call := range []func() error{
+		func() error { return helper.FormatAndMount(ctx, "/dev/sdb", "/staging", "xfs", "noatime") },
+		func() error { return helper.Mount(ctx, "nfs:/share", "/target", "nfs4", "hard") },
+		func() error { return helper.BindMount(ctx, "/staging", "/target", "ro") },
+		func() error { return helper.Resize(ctx, "/dev/sdb", "/staging") },
+		func() error { return helper.Unmount(ctx, "/target") },
+	} {
+		if err := call(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := []mountHelperRequest{
+		{Op: mountHelperOpFormatAndMount, Source: "/dev/sdb", Target: "/staging", FsType: "xfs",
+			Options: []string{"noatime"}},
+		{Op: mountHelperOpMount, Source: "nfs:/share", Target: "/target", FsType: "nfs4", Options: []string{"hard"}},
+		{Op: mountHelperOpBindMount, Source: "/staging", Target: "/target", Options: []string{"ro"}},
+		{Op: mountHelperOpResize, Source: "/dev/sdb", Target: "/staging"},
+		{Op: mountHelperOpUnmount, Target: "/target"},
+	}
+	if !reflect.DeepEqual(fsMounter.requests, expected) {
+		t.Errorf("expected requests %+v, got %+v", expected, fsMounter.requests)
+	}
+}
+
+func TestMountHelperErrors(t *testing.T) {
+	ctx := context.Background()
+	helper := startTestMountHelper(t, &fakeFsMounter{err: errors.New("mkfs.xfs: not found")})
+	if err := helper.FormatAndMount(ctx, "/dev/sdb", "/staging", "xfs"); err == nil ||
+		err.Error() != "mkfs.xfs: not found" {
+		t.Errorf("expected the error of the mount helper, got %v", err)
+	}
+	if err := helper.(*helperMounter).call(ctx, &mountHelperRequest{Op: "Format", Target: "/dev/sdb"}); err == nil {
+		t.Errorf("expected unsupported operations to fail")
+	}
+	unreachable := NewFsMounter(ctx, filepath.Join(t.TempDir(), "missing.sock"))
+	if err := unreachable.Unmount(ctx, "/target"); err == nil {
+		t.Errorf("expected an error when the mount helper isn't running")
+	}
+}
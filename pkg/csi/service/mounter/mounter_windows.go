@@ -24,7 +24,6 @@ import (
 	"strconv"
 	"strings"
 
-	"github.com/davecgh/go-spew/spew"
 	disk "github.com/kubernetes-csi/csi-proxy/client/api/disk/v1"
 	diskclient "github.com/kubernetes-csi/csi-proxy/client/groups/disk/v1"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
@@ -190,27 +189,24 @@ func (mounter *csiProxyMounter) MakeDir(pathname string) error {
 	return nil
 }
 
-// Gets windows specific disk number from diskId
+// Gets windows specific disk number from diskId. Disks are discovered through
+// the disk IDs listed by CSI proxy, matching SCSI disks by their page 83
+// identifier and NVMe disks by their serial number.
 func (mounter *csiProxyMounter) GetDiskNumber(diskID string) (string, error) {
 	ctx := mounter.Ctx
 	log := logger.GetLogger(ctx)
 	// Check device is attached
-	log.Debug("GetDiskNumber called with diskID: %q", diskID)
+	log.Debugf("GetDiskNumber called with diskID: %q", diskID)
 
 	listRequest := &disk.ListDiskIDsRequest{}
 	diskIDsResponse, err := mounter.DiskClient.ListDiskIDs(context.Background(), listRequest)
 	if err != nil {
-		log.Debug("Could not get diskids %s", err)
+		log.Debugf("Could not get diskids %s", err)
 		return "", err
 	}
-	spew.Dump("disIDs: ", diskIDsResponse)
 	for diskNum, diskInfo := range diskIDsResponse.GetDiskIDs() {
-		log.Infof("found disk number %d, disk info %v", diskNum, diskInfo)
-		ID := diskInfo.Page83
-		if ID == "" {
-			continue
-		}
-		if ID == diskID {
+		log.Debugf("found disk number %d, disk info %v", diskNum, diskInfo)
+		if matchDiskID(diskID, diskInfo.GetPage83(), diskInfo.GetSerialNumber()) {
 			log.Infof("Found disk number: %d with diskID: %s", diskNum, diskID)
 			return strconv.FormatUint(uint64(diskNum), 10), nil
 		}
@@ -248,7 +244,7 @@ func (mounter *csiProxyMounter) FormatAndMount(source string, target string, fst
 	log := logger.GetLogger(ctx)
 	diskNum, err := strconv.Atoi(source)
 	if err != nil {
-		return fmt.Errorf("parse %s failed with error: %v", source, err)
+		return fmt.Errorf("parse %s failed with error: %w", source, err)
 	}
 	log.Infof("Disk Number: %d", diskNum)
 	// Call PartitionDisk CSI proxy call to partition the disk and return the volume id
@@ -312,7 +308,7 @@ func (mounter *csiProxyMounter) FormatAndMount(source string, target string, fst
 		TargetPath: normalizeWindowsPath(target),
 	}
 	_, err = mounter.VolumeClient.MountVolume(context.Background(), mountVolumeRequest)
-	log.Debug("Volume mounted")
+	log.Debugf("Volume mounted")
 	if err != nil {
 		return err
 	}
@@ -445,7 +441,10 @@ func (mounter *csiProxyMounter) GetDiskTotalBytes(volumeId string) (int64, error
 		&disk.GetDiskStatsRequest{
 			DiskNumber: diskNumber,
 		})
-	return DiskStatsResponse.TotalBytes, err
+	if err != nil {
+		return -1, err
+	}
+	return DiskStatsResponse.TotalBytes, nil
 }
 
 // StatFS returns info about volume
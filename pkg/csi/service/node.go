@@ -17,8 +17,10 @@ limitations under the License.
 package service
 
 import (
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	cnstypes "github.com/vmware/govmomi/cns/types"
@@ -34,10 +36,15 @@ import (
 	commoncotypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common/commonco/types"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/osutils"
+	k8s "sigs.k8s.io/vsphere-csi-driver/v2/pkg/kubernetes"
 )
 
 const (
 	maxAllowedBlockVolumesPerNode = 59
+
+	// nodeConditionHostUtilitiesMissing is the Node condition type set by
+	// the node plugin to report host utilities which could not be found.
+	nodeConditionHostUtilitiesMissing v1.NodeConditionType = "VSphereCSIHostUtilitiesMissing"
 )
 
 var topologyService commoncotypes.NodeTopologyService
@@ -49,11 +56,21 @@ func (driver *vsphereCSIDriver) NodeStageVolume(
 	ctx = logger.NewContextWithLogger(ctx)
 	log := logger.GetLogger(ctx)
 	log.Infof("NodeStageVolume: called with args %+v", *req)
+	if err := driver.validateKubeletPath(req.GetStagingTargetPath()); err != nil {
+		return nil, logger.LogNewErrorCode(log, codes.InvalidArgument, err.Error())
+	}
 
 	volumeID := req.GetVolumeId()
+	// Apply the mount options the volume was created with if the PV has none.
+	req.VolumeCapability = common.ApplyMountOptionsAttribute(req.GetVolumeCapability(), req.GetVolumeContext())
 	volCap := req.GetVolumeCapability()
 	// Check for block volume or file share.
 	if common.IsFileVolumeRequest(ctx, []*csi.VolumeCapability{volCap}) {
+		// Mount options of file volumes are applied when they are published.
+		if err := common.ValidateMountFlags(ctx, volCap); err != nil {
+			return nil, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+				"invalid mount options for volume %q. Err: %v", volumeID, err)
+		}
 		log.Infof("NodeStageVolume: Volume %q detected as a file share volume. Ignoring staging for file volumes.",
 			volumeID)
 		return &csi.NodeStageVolumeResponse{}, nil
@@ -147,6 +164,12 @@ func (driver *vsphereCSIDriver) NodePublishVolume(
 	ctx = logger.NewContextWithLogger(ctx)
 	log := logger.GetLogger(ctx)
 	log.Infof("NodePublishVolume: called with args %+v", *req)
+	if err := driver.validateKubeletPath(req.GetTargetPath()); err != nil {
+		return nil, logger.LogNewErrorCode(log, codes.InvalidArgument, err.Error())
+	}
+	if isEphemeralVolumeRequest(req.GetVolumeContext()) {
+		return driver.publishEphemeralVolume(ctx, req)
+	}
 	var err error
 	params := osutils.NodePublishParams{
 		VolID:  req.GetVolumeId(),
@@ -166,6 +189,8 @@ func (driver *vsphereCSIDriver) NodePublishVolume(
 			"target path %q not set", params.Target)
 	}
 
+	// Apply the mount options the volume was created with if the PV has none.
+	req.VolumeCapability = common.ApplyMountOptionsAttribute(req.GetVolumeCapability(), req.GetVolumeContext())
 	volCap := req.GetVolumeCapability()
 	if volCap == nil {
 		return nil, logger.LogNewErrorCode(log, codes.InvalidArgument,
@@ -214,6 +239,16 @@ func (driver *vsphereCSIDriver) NodeUnpublishVolume(
 			"target path %q not set", target)
 	}
 
+	ephemeralVolume, err := loadEphemeralVolume(
+		getEphemeralVolumesDir(driver.getKubeletRootDir(target)), volID)
+	if err != nil {
+		return nil, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to load ephemeral volume %q. Error: %v", volID, err)
+	}
+	if ephemeralVolume != nil {
+		return driver.unpublishEphemeralVolume(ctx, req, ephemeralVolume)
+	}
+
 	if err := driver.osUtils.CleanupPublishPath(ctx, target, volID); err != nil {
 		return nil, logger.LogNewErrorCodef(log, codes.Internal,
 			"Unmount failed: %v\nUnmounting arguments: %s\n", err, target)
@@ -238,6 +273,60 @@ func (driver *vsphereCSIDriver) NodeGetVolumeStats(
 			"received empty targetpath %q", targetPath)
 	}
 
+	targetInfo, err := os.Stat(targetPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, logger.LogNewErrorCodef(log, codes.NotFound,
+				"volume path %q does not exist", targetPath)
+		}
+		return nil, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to stat volume path %q. Error: %v", targetPath, err)
+	}
+
+	var volumeCondition *csi.VolumeCondition
+	if commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.NodeVolumeCondition) {
+		abnormalMessage, err := driver.osUtils.GetVolumeCondition(ctx, targetPath)
+		if err != nil {
+			return nil, logger.LogNewErrorCodef(log, codes.Internal,
+				"failed to get condition of volume at path %q. Error: %v", targetPath, err)
+		}
+		if abnormalMessage == "" && driver.fsHealthMonitor != nil && targetInfo.IsDir() {
+			// File volumes have no device, so only look up the problems of
+			// block volumes found by the file system health monitor.
+			dev, err := driver.osUtils.GetDevFromMount(ctx, targetPath)
+			if err == nil && dev != nil {
+				abnormalMessage = driver.fsHealthMonitor.getProblem(dev.RealDev)
+			}
+		}
+		if abnormalMessage != "" {
+			// The usage of an abnormal volume can't be trusted, so only its
+			// condition is reported.
+			log.Warnf("NodeGetVolumeStats: volume %q is abnormal: %s", req.GetVolumeId(), abnormalMessage)
+			return &csi.NodeGetVolumeStatsResponse{
+				VolumeCondition: &csi.VolumeCondition{Abnormal: true, Message: abnormalMessage},
+			}, nil
+		}
+		volumeCondition = &csi.VolumeCondition{Abnormal: false, Message: "volume is healthy"}
+	}
+
+	if targetInfo.Mode()&os.ModeDevice != 0 {
+		// Raw block volumes have no file system, so only their size is
+		// reported.
+		capacity, err := driver.osUtils.GetBlockSizeBytes(ctx, targetPath)
+		if err != nil {
+			return nil, logger.LogNewErrorCode(log, codes.Internal, err.Error())
+		}
+		return &csi.NodeGetVolumeStatsResponse{
+			Usage: []*csi.VolumeUsage{
+				{
+					Total: capacity,
+					Unit:  csi.VolumeUsage_BYTES,
+				},
+			},
+			VolumeCondition: volumeCondition,
+		}, nil
+	}
+
 	volMetrics, err := driver.osUtils.GetMetrics(ctx, targetPath)
 	if err != nil {
 		return nil, logger.LogNewErrorCode(log, codes.Internal, err.Error())
@@ -282,6 +371,7 @@ func (driver *vsphereCSIDriver) NodeGetVolumeStats(
 				Unit:      csi.VolumeUsage_INODES,
 			},
 		},
+		VolumeCondition: volumeCondition,
 	}, nil
 }
 
@@ -289,32 +379,41 @@ func (driver *vsphereCSIDriver) NodeGetCapabilities(
 	ctx context.Context,
 	req *csi.NodeGetCapabilitiesRequest) (
 	*csi.NodeGetCapabilitiesResponse, error) {
+	ctx = logger.NewContextWithLogger(ctx)
 
-	return &csi.NodeGetCapabilitiesResponse{
-		Capabilities: []*csi.NodeServiceCapability{
-			{
-				Type: &csi.NodeServiceCapability_Rpc{
-					Rpc: &csi.NodeServiceCapability_RPC{
-						Type: csi.NodeServiceCapability_RPC_STAGE_UNSTAGE_VOLUME,
-					},
+	capabilities := []*csi.NodeServiceCapability{
+		{
+			Type: &csi.NodeServiceCapability_Rpc{
+				Rpc: &csi.NodeServiceCapability_RPC{
+					Type: csi.NodeServiceCapability_RPC_STAGE_UNSTAGE_VOLUME,
 				},
 			},
-			{
-				Type: &csi.NodeServiceCapability_Rpc{
-					Rpc: &csi.NodeServiceCapability_RPC{
-						Type: csi.NodeServiceCapability_RPC_EXPAND_VOLUME,
-					},
+		},
+		{
+			Type: &csi.NodeServiceCapability_Rpc{
+				Rpc: &csi.NodeServiceCapability_RPC{
+					Type: csi.NodeServiceCapability_RPC_EXPAND_VOLUME,
 				},
 			},
-			{
-				Type: &csi.NodeServiceCapability_Rpc{
-					Rpc: &csi.NodeServiceCapability_RPC{
-						Type: csi.NodeServiceCapability_RPC_GET_VOLUME_STATS,
-					},
+		},
+		{
+			Type: &csi.NodeServiceCapability_Rpc{
+				Rpc: &csi.NodeServiceCapability_RPC{
+					Type: csi.NodeServiceCapability_RPC_GET_VOLUME_STATS,
 				},
 			},
 		},
-	}, nil
+	}
+	if commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.NodeVolumeCondition) {
+		capabilities = append(capabilities, &csi.NodeServiceCapability{
+			Type: &csi.NodeServiceCapability_Rpc{
+				Rpc: &csi.NodeServiceCapability_RPC{
+					Type: csi.NodeServiceCapability_RPC_VOLUME_CONDITION,
+				},
+			},
+		})
+	}
+	return &csi.NodeGetCapabilitiesResponse{Capabilities: capabilities}, nil
 }
 
 // NodeGetInfo RPC returns the NodeGetInfoResponse with mandatory fields
@@ -324,7 +423,9 @@ func (driver *vsphereCSIDriver) NodeGetCapabilities(
 // attached is deterministic by inspecting SCSI controllers of the VM, but for
 // file volume, this is not deterministic. We can not set this limit on
 // MaxVolumesPerNode, since single driver is used for both block and file
-// volumes.
+// volumes. The limit is only reported if set with MAX_VOLUMES_PER_NODE, or
+// if the NodeAttachLimit feature is enabled, in which case file volumes also
+// count against the limit of block volumes.
 func (driver *vsphereCSIDriver) NodeGetInfo(
 	ctx context.Context,
 	req *csi.NodeGetInfoRequest) (
@@ -379,6 +480,9 @@ func (driver *vsphereCSIDriver) NodeGetInfo(
 			return nil, logger.LogNewErrorCodef(log, codes.Internal,
 				"NodeGetInfo: MAX_VOLUMES_PER_NODE set in env variable %v is invalid", v)
 		}
+	} else if commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.NodeAttachLimit) {
+		// MAX_VOLUMES_PER_NODE overrides the attach limit of the node VM.
+		maxVolumesPerNode = driver.getMaxVolumesPerNode(ctx, nodeID)
 	}
 
 	var (
@@ -493,60 +597,31 @@ func (driver *vsphereCSIDriver) fetchTopologyLabelsUsingVCCreds(
 	map[string]string, error) {
 	log := logger.GetLogger(ctx)
 
-	// If zone or region are empty, return.
-	if cfg.Labels.Zone == "" || cfg.Labels.Region == "" {
+	// If neither user-defined topology categories, zone and region nor
+	// topology hierarchy are given, return.
+	isUserDefinedTopology := strings.TrimSpace(cfg.Labels.TopologyCategories) != ""
+	isZoneRegionTopology := !isUserDefinedTopology && cfg.Labels.Zone != "" && cfg.Labels.Region != ""
+	hierarchyLevels := cnsconfig.GetTopologyHierarchyLevels(cfg)
+	if !isUserDefinedTopology && !isZoneRegionTopology && len(hierarchyLevels) == 0 {
 		return nil, nil
 	}
 
-	log.Infof("Config file provided to node daemonset contains zone and region info. " +
-		"Assuming topology aware cluster.")
-	vcenterconfig, err := cnsvsphere.GetVirtualCenterConfig(ctx, cfg)
-	if err != nil {
-		return nil, logger.LogNewErrorCodef(log, codes.Internal,
-			"failed to get VirtualCenterConfig from cns config. err: %v", err)
+	if isUserDefinedTopology {
+		log.Infof("Config file provided to node daemonset contains topology categories %q. "+
+			"Assuming topology aware cluster.", cfg.Labels.TopologyCategories)
+	} else if isZoneRegionTopology {
+		log.Infof("Config file provided to node daemonset contains zone and region info. " +
+			"Assuming topology aware cluster.")
 	}
-	vcManager := cnsvsphere.GetVirtualCenterManager(ctx)
-	vcenter, err := vcManager.RegisterVirtualCenter(ctx, vcenterconfig)
-	if err != nil {
-		return nil, logger.LogNewErrorCodef(log, codes.Internal,
-			"failed to register vcenter with virtualCenterManager. err: %v", err)
-	}
-	defer func() {
-		if vcManager != nil {
-			err = vcManager.UnregisterAllVirtualCenters(ctx)
-			if err != nil {
-				log.Errorf("UnregisterAllVirtualCenters failed. err: %v", err)
-			}
-		}
-	}()
-
-	// Connect to vCenter.
-	err = vcenter.Connect(ctx)
-	if err != nil {
-		return nil, logger.LogNewErrorCodef(log, codes.Internal,
-			"failed to connect to vcenter host: %s. err: %v", vcenter.Config.Host, err)
+	if len(hierarchyLevels) > 0 {
+		log.Infof("Config file provided to node daemonset contains topology hierarchy %v. "+
+			"Assuming topology aware cluster.", hierarchyLevels)
 	}
-	// Get VM UUID.
-	uuid, err := driver.osUtils.GetSystemUUID(ctx)
+	vcenter, nodeVM, disconnect, err := driver.getNodeVMUsingVCCreds(ctx, nodeID, cfg)
 	if err != nil {
-		return nil, logger.LogNewErrorCodef(log, codes.Internal,
-			"failed to get system uuid for node VM. err: %v", err)
-	}
-	log.Debugf("Successfully retrieved uuid:%s  from the node: %s", uuid, nodeID)
-	nodeVM, err := cnsvsphere.GetVirtualMachineByUUID(ctx, uuid, false)
-	if err != nil || nodeVM == nil {
-		log.Errorf("failed to get nodeVM for uuid: %s. err: %+v", uuid, err)
-		uuid, err = driver.osUtils.ConvertUUID(uuid)
-		if err != nil {
-			return nil, logger.LogNewErrorCodef(log, codes.Internal,
-				"convertUUID failed with error: %v", err)
-		}
-		nodeVM, err = cnsvsphere.GetVirtualMachineByUUID(ctx, uuid, false)
-		if err != nil || nodeVM == nil {
-			return nil, logger.LogNewErrorCodef(log, codes.Internal,
-				"failed to get nodeVM for uuid: %s. err: %+v", uuid, err)
-		}
+		return nil, err
 	}
+	defer disconnect()
 	// Get a tag manager instance.
 	tagManager, err := cnsvsphere.GetTagManager(ctx, vcenter)
 	if err != nil {
@@ -560,21 +635,51 @@ func (driver *vsphereCSIDriver) fetchTopologyLabelsUsingVCCreds(
 		}
 	}()
 
-	// Fetch zone and region for given node.
-	zone, region, err := nodeVM.GetZoneRegion(ctx, cfg.Labels.Zone, cfg.Labels.Region, tagManager)
-	if err != nil {
-		return nil, logger.LogNewErrorCodef(log, codes.Internal,
-			"failed to get accessibleTopology for vm: %v, err: %v", nodeVM.Reference(), err)
+	accessibleTopology := make(map[string]string)
+	if isUserDefinedTopology {
+		// Fetch the tags of the given node for each user-defined category, from
+		// the host, cluster and datacenter hierarchy of the node VM.
+		topologyCategories := getUserDefinedTopologyCategories(cfg)
+		err = nodeVM.GetTopologyLabels(ctx, tagManager, topologyCategories)
+		if err != nil {
+			return nil, logger.LogNewErrorCodef(log, codes.Internal,
+				"failed to get accessibleTopology for vm: %v, err: %v", nodeVM.Reference(), err)
+		}
+		log.Debugf("topology: %+v, Node VM: [%s]", topologyCategories, nodeID)
+		for key, value := range getUserDefinedTopologySegments(cfg, topologyCategories) {
+			accessibleTopology[key] = value
+		}
+	} else if isZoneRegionTopology {
+		// Fetch zone and region for given node.
+		zone, region, err := nodeVM.GetZoneRegion(ctx, cfg.Labels.Zone, cfg.Labels.Region, tagManager)
+		if err != nil {
+			return nil, logger.LogNewErrorCodef(log, codes.Internal,
+				"failed to get accessibleTopology for vm: %v, err: %v", nodeVM.Reference(), err)
+		}
+		log.Debugf("zone: [%s], region: [%s], Node VM: [%s]", zone, region, nodeID)
+		if zone != "" && region != "" {
+			accessibleTopology[v1.LabelZoneRegion] = region
+			accessibleTopology[v1.LabelZoneFailureDomain] = zone
+		}
+	}
+
+	if len(hierarchyLevels) > 0 {
+		// Fetch the datacenter, cluster and host of the given node.
+		hierarchy, err := nodeVM.GetTopologyHierarchy(ctx, hierarchyLevels)
+		if err != nil {
+			return nil, logger.LogNewErrorCodef(log, codes.Internal,
+				"failed to get topology hierarchy for vm: %v, err: %v", nodeVM.Reference(), err)
+		}
+		log.Debugf("hierarchy: %+v, Node VM: [%s]", hierarchy, nodeID)
+		for level, value := range hierarchy {
+			accessibleTopology[cnsconfig.GetTopologyHierarchyKey(level)] = value
+		}
 	}
-	log.Debugf("zone: [%s], region: [%s], Node VM: [%s]", zone, region, nodeID)
 
-	if zone != "" && region != "" {
-		accessibleTopology := make(map[string]string)
-		accessibleTopology[v1.LabelZoneRegion] = region
-		accessibleTopology[v1.LabelZoneFailureDomain] = zone
-		return accessibleTopology, nil
+	if len(accessibleTopology) == 0 {
+		return nil, nil
 	}
-	return nil, nil
+	return accessibleTopology, nil
 }
 
 func (driver *vsphereCSIDriver) NodeExpandVolume(
@@ -654,3 +759,121 @@ func (driver *vsphereCSIDriver) NodeExpandVolume(
 		CapacityBytes: int64(units.FileSize(reqVolSizeMB * common.MbInBytes)),
 	}, nil
 }
+
+// publishHostUtilitiesCondition probes the node for the host utilities
+// required by the node plugin and publishes the result as a condition on the
+// Kubernetes Node API object, so that missing utilities are surfaced before
+// the first NodeStageVolume call fails with an exec error.
+func (driver *vsphereCSIDriver) publishHostUtilitiesCondition(ctx context.Context) {
+	log := logger.GetLogger(ctx)
+	nodeName := os.Getenv("NODE_NAME")
+	if nodeName == "" {
+		log.Warnf("ENV NODE_NAME is not set. Skipping host utilities check.")
+		return
+	}
+	condition := v1.NodeCondition{
+		Type:    nodeConditionHostUtilitiesMissing,
+		Status:  v1.ConditionFalse,
+		Reason:  "HostUtilitiesFound",
+		Message: "All host utilities required by the vSphere CSI node plugin were found",
+	}
+	missing := driver.osUtils.GetMissingHostUtilities(ctx)
+	if len(missing) != 0 {
+		log.Warnf("Host utilities %v required by the node plugin were not found on node %q", missing, nodeName)
+		condition.Status = v1.ConditionTrue
+		condition.Reason = "HostUtilitiesNotFound"
+		condition.Message = fmt.Sprintf("Host utilities required by the vSphere CSI node plugin "+
+			"were not found: %s", strings.Join(missing, ", "))
+	}
+	k8sClient, err := k8s.NewClient(ctx)
+	if err != nil {
+		log.Errorf("failed to create kubernetes client. Err: %v", err)
+		return
+	}
+	// Failing to publish the condition should not prevent the node plugin
+	// from serving requests.
+	_ = k8s.SetNodeCondition(ctx, k8sClient, nodeName, condition)
+}
+
+// publishNodePluginVersionCondition sets the Node condition publishing the
+// version of the node plugin, for the syncer to check the version skew
+// between the controller and the node plugins.
+func (driver *vsphereCSIDriver) publishNodePluginVersionCondition(ctx context.Context) {
+	log := logger.GetLogger(ctx)
+	nodeName := os.Getenv("NODE_NAME")
+	if nodeName == "" {
+		log.Warnf("ENV NODE_NAME is not set. Skipping publishing the node plugin version.")
+		return
+	}
+	condition := v1.NodeCondition{
+		Type:    common.NodeConditionNodePluginVersion,
+		Status:  v1.ConditionTrue,
+		Reason:  "NodePluginStarted",
+		Message: Version,
+	}
+	k8sClient, err := k8s.NewClient(ctx)
+	if err != nil {
+		log.Errorf("failed to create kubernetes client. Err: %v", err)
+		return
+	}
+	// Failing to publish the condition should not prevent the node plugin
+	// from serving requests.
+	_ = k8s.SetNodeCondition(ctx, k8sClient, nodeName, condition)
+}
+
+// getNodeVMUsingVCCreds connects to vCenter with the VC credentials of the
+// given config, and returns the vCenter and the VM of the node, along with a
+// function to disconnect from vCenter once done.
+func (driver *vsphereCSIDriver) getNodeVMUsingVCCreds(ctx context.Context, nodeID string,
+	cfg *cnsconfig.Config) (*cnsvsphere.VirtualCenter, *cnsvsphere.VirtualMachine, func(), error) {
+	log := logger.GetLogger(ctx)
+	vcenterconfig, err := cnsvsphere.GetVirtualCenterConfig(ctx, cfg)
+	if err != nil {
+		return nil, nil, nil, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to get VirtualCenterConfig from cns config. err: %v", err)
+	}
+	vcManager := cnsvsphere.GetVirtualCenterManager(ctx)
+	vcenter, err := vcManager.RegisterVirtualCenter(ctx, vcenterconfig)
+	if err != nil {
+		return nil, nil, nil, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to register vcenter with virtualCenterManager. err: %v", err)
+	}
+	disconnect := func() {
+		if err := vcManager.UnregisterAllVirtualCenters(ctx); err != nil {
+			log.Errorf("UnregisterAllVirtualCenters failed. err: %v", err)
+		}
+	}
+
+	// Connect to vCenter.
+	err = vcenter.Connect(ctx)
+	if err != nil {
+		disconnect()
+		return nil, nil, nil, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to connect to vcenter host: %s. err: %v", vcenter.Config.Host, err)
+	}
+	// Get VM UUID.
+	uuid, err := driver.osUtils.GetSystemUUID(ctx)
+	if err != nil {
+		disconnect()
+		return nil, nil, nil, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to get system uuid for node VM. err: %v", err)
+	}
+	log.Debugf("Successfully retrieved uuid:%s  from the node: %s", uuid, nodeID)
+	nodeVM, err := cnsvsphere.GetVirtualMachineByUUID(ctx, uuid, false)
+	if err != nil || nodeVM == nil {
+		log.Errorf("failed to get nodeVM for uuid: %s. err: %+v", uuid, err)
+		uuid, err = driver.osUtils.ConvertUUID(uuid)
+		if err != nil {
+			disconnect()
+			return nil, nil, nil, logger.LogNewErrorCodef(log, codes.Internal,
+				"convertUUID failed with error: %v", err)
+		}
+		nodeVM, err = cnsvsphere.GetVirtualMachineByUUID(ctx, uuid, false)
+		if err != nil || nodeVM == nil {
+			disconnect()
+			return nil, nil, nil, logger.LogNewErrorCodef(log, codes.Internal,
+				"failed to get nodeVM for uuid: %s. err: %+v", uuid, err)
+		}
+	}
+	return vcenter, nodeVM, disconnect, nil
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"os"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
+
+	cnsconfig "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+)
+
+// getMaxVolumesPerNode returns the maximum number of block volumes which can
+// be attached to the node VM, found with the VC credentials of the config
+// file provided to the node daemonset. It returns 0, i.e. no limit, if the
+// config file isn't provided or the limit can't be found, so that the node
+// still registers.
+func (driver *vsphereCSIDriver) getMaxVolumesPerNode(ctx context.Context, nodeID string) int64 {
+	log := logger.GetLogger(ctx)
+	clusterFlavor, err := cnsconfig.GetClusterFlavor(ctx)
+	if err != nil || clusterFlavor != cnstypes.CnsClusterFlavorVanilla {
+		return 0
+	}
+	cfgPath := os.Getenv(cnsconfig.EnvVSphereCSIConfig)
+	if cfgPath == "" {
+		cfgPath = cnsconfig.DefaultCloudConfigPath
+	}
+	cfg, err := cnsconfig.GetCnsconfig(ctx, cfgPath)
+	if err != nil {
+		log.Warnf("NodeGetInfo: failed to read CNS config to find the attach limit of the node. "+
+			"Not reporting any limit. Err: %v", err)
+		return 0
+	}
+	_, nodeVM, disconnect, err := driver.getNodeVMUsingVCCreds(ctx, nodeID, cfg)
+	if err != nil {
+		log.Warnf("NodeGetInfo: failed to get the node VM to find the attach limit of the node. "+
+			"Not reporting any limit. Err: %v", err)
+		return 0
+	}
+	defer disconnect()
+	hardwareVersion, err := nodeVM.GetHardwareVersion(ctx)
+	if err != nil {
+		log.Warnf("NodeGetInfo: failed to get the hardware version of node VM %v. Not reporting any limit. "+
+			"Err: %v", nodeVM, err)
+		return 0
+	}
+	devices, err := nodeVM.Device(ctx)
+	if err != nil {
+		log.Warnf("NodeGetInfo: failed to get the devices of node VM %v. Not reporting any limit. Err: %v",
+			nodeVM, err)
+		return 0
+	}
+	maxVolumesPerNode := getMaxAttachableBlockVolumes(hardwareVersion, devices)
+	log.Infof("NodeGetInfo: found attach limit %d for node VM %v with hardware version %s",
+		maxVolumesPerNode, nodeVM, hardwareVersion)
+	return maxVolumesPerNode
+}
+
+// getMaxAttachableBlockVolumes returns the maximum number of block volumes
+// which can be attached to a VM with the given hardware version and devices,
+// i.e. the targets of its PVSCSI controllers which aren't reserved nor used
+// by disks other than volumes, such as the boot disk.
+func getMaxAttachableBlockVolumes(hardwareVersion string, devices object.VirtualDeviceList) int64 {
+	targetsPerController := common.GetPVSCSIUsableTargetsPerController(hardwareVersion)
+	controllers := make(map[int32]bool)
+	for _, device := range devices {
+		if _, ok := device.(*types.ParaVirtualSCSIController); ok {
+			controllers[device.GetVirtualDevice().Key] = true
+		}
+	}
+	maxVolumes := int64(len(controllers) * targetsPerController)
+	for _, device := range devices {
+		// Volumes are first class disks, disks without a VDiskId aren't
+		// volumes.
+		if disk, ok := device.(*types.VirtualDisk); ok && disk.VDiskId == nil &&
+			controllers[disk.ControllerKey] {
+			maxVolumes--
+		}
+	}
+	if maxVolumes < 0 {
+		return 0
+	}
+	return maxVolumes
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"testing"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func newPVSCSIController(key int32) types.BaseVirtualDevice {
+	controller := &types.ParaVirtualSCSIController{}
+	controller.Key = key
+	return controller
+}
+
+func newVirtualDisk(controllerKey int32, volumeID string) types.BaseVirtualDevice {
+	disk := &types.VirtualDisk{}
+	disk.ControllerKey = controllerKey
+	if volumeID != "" {
+		disk.VDiskId = &types.ID{Id: volumeID}
+	}
+	return disk
+}
+
+func TestGetMaxAttachableBlockVolumes(t *testing.T) {
+	lsiLogicController := &types.VirtualLsiLogicController{}
+	lsiLogicController.Key = 1000
+	tests := []struct {
+		name            string
+		hardwareVersion string
+		devices         object.VirtualDeviceList
+		expected        int64
+	}{
+		{
+			name:            "NoPVSCSIController",
+			hardwareVersion: "vmx-15",
+			devices:         object.VirtualDeviceList{lsiLogicController, newVirtualDisk(1000, "")},
+		},
+		{
+			name:            "BootDiskOnPVSCSIController",
+			hardwareVersion: "vmx-13",
+			devices:         object.VirtualDeviceList{newPVSCSIController(1000), newVirtualDisk(1000, "")},
+			expected:        14,
+		},
+		{
+			name:            "BootDiskOnOtherController",
+			hardwareVersion: "vmx-13",
+			devices: object.VirtualDeviceList{lsiLogicController, newVirtualDisk(1000, ""),
+				newPVSCSIController(1001)},
+			expected: 15,
+		},
+		{
+			name:            "ExtendedTargets",
+			hardwareVersion: "vmx-14",
+			devices: object.VirtualDeviceList{newPVSCSIController(1000), newVirtualDisk(1000, ""),
+				newPVSCSIController(1001), newVirtualDisk(1001, "volume-1")},
+			expected: 125,
+		},
+		{
+			name:            "UnknownHardwareVersion",
+			hardwareVersion: "",
+			devices:         object.VirtualDeviceList{newPVSCSIController(1000), newPVSCSIController(1001)},
+			expected:        30,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if maxVolumes := getMaxAttachableBlockVolumes(test.hardwareVersion, test.devices); maxVolumes != test.expected {
+				t.Errorf("expected %d attachable volumes, got %d", test.expected, maxVolumes)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"os"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+	k8s "sigs.k8s.io/vsphere-csi-driver/v2/pkg/kubernetes"
+)
+
+// podEvent is an event to emit on the pods using a staged volume.
+type podEvent struct {
+	podUIDs   []string
+	eventType string
+	reason    string
+	message   string
+}
+
+// nodePodEventRecorder emits events on the pods running on the node of the
+// node plugin.
+type nodePodEventRecorder struct {
+	// k8sClient and recorder are nil if events can't be emitted.
+	k8sClient clientset.Interface
+	recorder  record.EventRecorder
+	nodeName  string
+}
+
+// newNodePodEventRecorder returns a nodePodEventRecorder for the node named
+// by the NODE_NAME env variable. The returned recorder drops the events if
+// the node name or a kubernetes client is not available, so that node
+// plugin features reporting events keep working without them.
+func newNodePodEventRecorder(ctx context.Context, component string) *nodePodEventRecorder {
+	log := logger.GetLogger(ctx)
+	r := &nodePodEventRecorder{nodeName: os.Getenv("NODE_NAME")}
+	if r.nodeName == "" {
+		log.Warnf("ENV NODE_NAME is not set. %s won't report pod events.", component)
+		return r
+	}
+	k8sClient, err := k8s.NewClient(ctx)
+	if err != nil {
+		log.Errorf("failed to create kubernetes client. %s won't report pod events. Err: %v", component, err)
+		return r
+	}
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(
+		&typedcorev1.EventSinkImpl{
+			Interface: k8sClient.CoreV1().Events(""),
+		},
+	)
+	r.k8sClient = k8sClient
+	r.recorder = eventBroadcaster.NewRecorder(scheme.Scheme,
+		v1.EventSource{Component: csitypes.Name, Host: r.nodeName})
+	return r
+}
+
+// emit emits the given events on the pods of the node they are for.
+func (r *nodePodEventRecorder) emit(ctx context.Context, events []podEvent) {
+	log := logger.GetLogger(ctx)
+	if r.recorder == nil || len(events) == 0 {
+		return
+	}
+	pods, err := r.k8sClient.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", r.nodeName).String(),
+	})
+	if err != nil {
+		log.Errorf("failed to list pods of node %q. Err: %v", r.nodeName, err)
+		return
+	}
+	podsByUID := make(map[string]*v1.Pod)
+	for i := range pods.Items {
+		podsByUID[string(pods.Items[i].UID)] = &pods.Items[i]
+	}
+	for _, event := range events {
+		for _, podUID := range event.podUIDs {
+			if pod, ok := podsByUID[podUID]; ok {
+				r.recorder.Event(pod, event.eventType, event.reason, event.message)
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"strings"
+
+	cnsconfig "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+)
+
+// getUserDefinedTopologyCategories returns a map with the vSphere categories
+// listed in the topology-categories parameter of the Labels section as keys,
+// and empty strings as values, to be populated with the tags of the node VM.
+func getUserDefinedTopologyCategories(cfg *cnsconfig.Config) map[string]string {
+	topologyCategories := make(map[string]string)
+	for _, category := range strings.Split(cfg.Labels.TopologyCategories, ",") {
+		if category = strings.TrimSpace(category); category != "" {
+			topologyCategories[category] = ""
+		}
+	}
+	return topologyCategories
+}
+
+// getUserDefinedTopologySegments returns the accessible topology of a node
+// given the tags found for each of the user-defined topology categories.
+// A category is reported under the label configured for it in its
+// TopologyCategory section, if any, or else under the TopologyLabelsDomain
+// prefixed category name.
+func getUserDefinedTopologySegments(cfg *cnsconfig.Config,
+	topologyCategories map[string]string) map[string]string {
+	accessibleTopology := make(map[string]string)
+	for category, tag := range topologyCategories {
+		key := common.TopologyLabelsDomain + "/" + category
+		if categoryInfo, exists := cfg.TopologyCategory[category]; exists && categoryInfo.Label != "" {
+			key = categoryInfo.Label
+		}
+		accessibleTopology[key] = tag
+	}
+	return accessibleTopology
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	cnsconfig "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
+)
+
+func TestGetUserDefinedTopologyCategories(t *testing.T) {
+	cfg := &cnsconfig.Config{}
+	cfg.Labels.TopologyCategories = "k8s-region, k8s-zone,,rack "
+	expected := map[string]string{"k8s-region": "", "k8s-zone": "", "rack": ""}
+	if categories := getUserDefinedTopologyCategories(cfg); !reflect.DeepEqual(categories, expected) {
+		t.Errorf("expected categories %v, got %v", expected, categories)
+	}
+}
+
+func TestGetUserDefinedTopologySegments(t *testing.T) {
+	cfg := &cnsconfig.Config{
+		TopologyCategory: map[string]*cnsconfig.TopologyCategoryInfo{
+			"k8s-zone": {Label: "topology.kubernetes.io/zone"},
+		},
+	}
+	segments := getUserDefinedTopologySegments(cfg, map[string]string{"k8s-zone": "zone-a", "rack": "rack-1"})
+	expected := map[string]string{
+		"topology.kubernetes.io/zone":  "zone-a",
+		"topology.csi.vmware.com/rack": "rack-1",
+	}
+	if !reflect.DeepEqual(segments, expected) {
+		t.Errorf("expected segments %v, got %v", expected, segments)
+	}
+}
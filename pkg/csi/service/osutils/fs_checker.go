@@ -0,0 +1,181 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package osutils
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	utilexec "k8s.io/utils/exec"
+)
+
+// StagedVolumeMount is the mount of the file system of a block volume at its
+// staging path.
+type StagedVolumeMount struct {
+	// StagingPath is the path the volume is staged at.
+	StagingPath string
+	// Device is the path of the device of the volume, e.g. /dev/sdb.
+	Device string
+	// ReadOnly is set if the file system is mounted read-only.
+	ReadOnly bool
+	// PodUIDs are the UIDs of the pods the volume is published to.
+	PodUIDs []string
+}
+
+// FilesystemChecker detects problems on the file systems of staged volumes.
+type FilesystemChecker interface {
+	// Name returns the name of the checker.
+	Name() string
+	// Check returns the problems found on the file systems of the given
+	// staged volumes, keyed by device. Checkers are called periodically with
+	// the volumes staged at the time, and may keep state between calls.
+	Check(ctx context.Context, mounts []StagedVolumeMount) (map[string]string, error)
+}
+
+// readOnlyRemountChecker detects the file systems remounted read-only since
+// they were first seen read-write, which file systems do on errors.
+type readOnlyRemountChecker struct {
+	// readWrite holds the staging paths seen mounted read-write.
+	readWrite map[string]bool
+}
+
+// NewReadOnlyRemountChecker returns a FilesystemChecker detecting the file
+// systems remounted read-only since they were first seen read-write.
+func NewReadOnlyRemountChecker() FilesystemChecker {
+	return &readOnlyRemountChecker{readWrite: make(map[string]bool)}
+}
+
+func (c *readOnlyRemountChecker) Name() string {
+	return "read-only-remount"
+}
+
+func (c *readOnlyRemountChecker) Check(ctx context.Context, mounts []StagedVolumeMount) (map[string]string, error) {
+	problems := make(map[string]string)
+	staged := make(map[string]bool)
+	for _, mount := range mounts {
+		staged[mount.StagingPath] = true
+		if !mount.ReadOnly {
+			c.readWrite[mount.StagingPath] = true
+			continue
+		}
+		if c.readWrite[mount.StagingPath] {
+			problems[mount.Device] = fmt.Sprintf("file system on %s was remounted read-only", mount.Device)
+		}
+	}
+	// Forget the volumes unstaged since the last check.
+	for stagingPath := range c.readWrite {
+		if !staged[stagingPath] {
+			delete(c.readWrite, stagingPath)
+		}
+	}
+	return problems, nil
+}
+
+var (
+	// kernelLogLineRegexp matches the lines of the kernel log, capturing the
+	// timestamp and the message.
+	kernelLogLineRegexp = regexp.MustCompile(`^\[\s*(\d+\.\d+)\]\s*(.*)$`)
+	// kernelLogErrorRegexps match the kernel log messages about I/O errors and
+	// file system errors, capturing the name of the device.
+	kernelLogErrorRegexps = []*regexp.Regexp{
+		regexp.MustCompile(`I/O error, dev ([\w-]+),`),
+		regexp.MustCompile(`EXT4-fs error \(device ([\w-]+)\)`),
+		regexp.MustCompile(`EXT4-fs \(([\w-]+)\): Remounting filesystem read-only`),
+		regexp.MustCompile(`XFS \(([\w-]+)\): .*(?:Corruption|I/O error|Filesystem has been shut down)`),
+	}
+)
+
+// kernelLogChecker detects the I/O errors and the file system errors logged
+// by the kernel for the devices of staged volumes.
+type kernelLogChecker struct {
+	exec utilexec.Interface
+	// started is set once the kernel log has been read once.
+	started bool
+	// lastTimestamp is the timestamp of the last kernel log line read.
+	lastTimestamp float64
+	// errors holds the last error logged by device name.
+	errors map[string]string
+}
+
+// NewKernelLogChecker returns a FilesystemChecker detecting the I/O errors and
+// the file system errors logged by the kernel, read with dmesg. The errors
+// logged before the first check are ignored, since the names of their devices
+// may have been reused by other volumes since.
+func NewKernelLogChecker(exec utilexec.Interface) FilesystemChecker {
+	return &kernelLogChecker{exec: exec, errors: make(map[string]string)}
+}
+
+func (c *kernelLogChecker) Name() string {
+	return "kernel-log"
+}
+
+func (c *kernelLogChecker) Check(ctx context.Context, mounts []StagedVolumeMount) (map[string]string, error) {
+	output, err := c.exec.CommandContext(ctx, "dmesg").CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kernel log: output: %s, err: %w", string(output), err)
+	}
+	c.parseKernelLog(string(output))
+
+	problems := make(map[string]string)
+	staged := make(map[string]bool)
+	for _, mount := range mounts {
+		devName := filepath.Base(mount.Device)
+		staged[devName] = true
+		if message, ok := c.errors[devName]; ok {
+			problems[mount.Device] = message
+		}
+	}
+	// Forget the errors of the devices not staged anymore, since their names
+	// may be reused by other volumes.
+	for devName := range c.errors {
+		if !staged[devName] {
+			delete(c.errors, devName)
+		}
+	}
+	return problems, nil
+}
+
+// parseKernelLog records the errors of the lines of the given kernel log
+// logged since the last call.
+func (c *kernelLogChecker) parseKernelLog(kernelLog string) {
+	scanner := bufio.NewScanner(strings.NewReader(kernelLog))
+	lastTimestamp := c.lastTimestamp
+	for scanner.Scan() {
+		match := kernelLogLineRegexp.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			continue
+		}
+		timestamp, err := strconv.ParseFloat(match[1], 64)
+		if err != nil || timestamp <= c.lastTimestamp {
+			continue
+		}
+		lastTimestamp = timestamp
+		if !c.started {
+			continue
+		}
+		for _, errorRegexp := range kernelLogErrorRegexps {
+			if errorMatch := errorRegexp.FindStringSubmatch(match[2]); errorMatch != nil {
+				c.errors[errorMatch[1]] = fmt.Sprintf("kernel logged an error for %s: %s", errorMatch[1], match[2])
+				break
+			}
+		}
+	}
+	c.lastTimestamp = lastTimestamp
+	c.started = true
+}
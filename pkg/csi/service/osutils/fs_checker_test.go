@@ -0,0 +1,105 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package osutils
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"k8s.io/utils/exec"
+	testingexec "k8s.io/utils/exec/testing"
+)
+
+func TestReadOnlyRemountChecker(t *testing.T) {
+	ctx := context.Background()
+	checker := NewReadOnlyRemountChecker()
+	rwMount := StagedVolumeMount{StagingPath: "/stage/pv-1/globalmount", Device: "/dev/sdb"}
+	roMount := StagedVolumeMount{StagingPath: "/stage/pv-1/globalmount", Device: "/dev/sdb", ReadOnly: true}
+	// Volumes staged read-only are not reported.
+	stagedReadOnly := StagedVolumeMount{StagingPath: "/stage/pv-2/globalmount", Device: "/dev/sdc", ReadOnly: true}
+
+	problems, err := checker.Check(ctx, []StagedVolumeMount{rwMount, stagedReadOnly})
+	if err != nil || len(problems) != 0 {
+		t.Fatalf("Expected no problem, got %v, %v", problems, err)
+	}
+	problems, err = checker.Check(ctx, []StagedVolumeMount{roMount, stagedReadOnly})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 1 || problems["/dev/sdb"] == "" {
+		t.Errorf("Expected a problem for /dev/sdb, got %v", problems)
+	}
+	// A volume staged read-only again after being unstaged is not reported.
+	if _, err = checker.Check(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	problems, err = checker.Check(ctx, []StagedVolumeMount{roMount})
+	if err != nil || len(problems) != 0 {
+		t.Errorf("Expected no problem after the volume was unstaged, got %v, %v", problems, err)
+	}
+}
+
+func newFakeDmesgExec(outputs ...string) *testingexec.FakeExec {
+	fakeExec := &testingexec.FakeExec{}
+	for _, output := range outputs {
+		output := output
+		fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+			return &testingexec.FakeCmd{
+				CombinedOutputScript: []testingexec.FakeAction{
+					func() ([]byte, []byte, error) { return []byte(output), nil, nil },
+				},
+			}
+		})
+	}
+	return fakeExec
+}
+
+func TestKernelLogChecker(t *testing.T) {
+	ctx := context.Background()
+	bootLog := "[    1.000000] Linux version 5.10.0\n" +
+		"[    2.000000] blk_update_request: I/O error, dev sdb, sector 2048 op 0x1:(WRITE)\n"
+	errorLog := bootLog +
+		"[  100.000000] EXT4-fs error (device sdb): ext4_find_entry:1455: inode #2: comm ls: reading directory\n" +
+		"[  101.000000] XFS (sdc): Corruption detected. Unmount and run xfs_repair\n" +
+		"[  102.000000] sd 0:0:1:0: [sdd] Attached SCSI disk\n"
+	checker := NewKernelLogChecker(newFakeDmesgExec(bootLog, errorLog, errorLog))
+	mounts := []StagedVolumeMount{
+		{StagingPath: "/stage/pv-1/globalmount", Device: "/dev/sdb"},
+		{StagingPath: "/stage/pv-2/globalmount", Device: "/dev/sdc"},
+		{StagingPath: "/stage/pv-3/globalmount", Device: "/dev/sdd"},
+	}
+
+	// The errors logged before the first check are ignored.
+	problems, err := checker.Check(ctx, mounts)
+	if err != nil || len(problems) != 0 {
+		t.Fatalf("Expected no problem on first check, got %v, %v", problems, err)
+	}
+	problems, err = checker.Check(ctx, mounts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 2 || !strings.Contains(problems["/dev/sdb"], "EXT4-fs error") ||
+		!strings.Contains(problems["/dev/sdc"], "Corruption") {
+		t.Errorf("Expected problems for /dev/sdb and /dev/sdc, got %v", problems)
+	}
+	// The errors of unstaged devices are forgotten.
+	problems, err = checker.Check(ctx, mounts[2:])
+	if err != nil || len(problems) != 0 {
+		t.Errorf("Expected no problem for /dev/sdd, got %v, %v", problems, err)
+	}
+}
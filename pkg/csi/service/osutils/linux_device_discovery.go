@@ -0,0 +1,196 @@
+//go:build darwin || linux
+// +build darwin linux
+
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package osutils
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// multipathBlockPrefix is the prefix of the by-id links of the multipath
+	// devices, followed by the SCSI identifier of the disk, i.e. "3" and the
+	// disk UUID.
+	multipathBlockPrefix = "dm-uuid-mpath-3"
+	// multipathUUIDPrefix is the prefix of the device mapper UUID of the
+	// multipath devices.
+	multipathUUIDPrefix = "mpath-"
+)
+
+// errDeviceMismatch is returned when the device found for a disk reports the
+// identifier of another disk.
+var errDeviceMismatch = errors.New("device identifier mismatch")
+
+// deviceDiscovery finds the block device of the disks attached to the node.
+// Disks are looked up through the by-id links created by udev, and through
+// the block devices listed in sysfs while udev has not processed the events
+// of a newly attached disk yet. Paths of a multipath device are resolved to
+// the multipath device, and the identifier reported by the device found is
+// validated against the disk, so that a stale link can't lead to mounting
+// another disk.
+type deviceDiscovery struct {
+	devDiskIDDir string
+	sysBlockDir  string
+	devDir       string
+}
+
+// nodeDeviceDiscovery discovers the devices of the disks attached to the
+// node.
+var nodeDeviceDiscovery = &deviceDiscovery{
+	devDiskIDDir: devDiskID,
+	sysBlockDir:  sysBlockDir,
+	devDir:       "/dev",
+}
+
+// findDiskLink returns the name of the by-id link of the given disk among the
+// given files, preferring the link of the multipath device of the disk, or an
+// empty string if the disk has no link.
+func findDiskLink(id string, files []os.FileInfo) string {
+	var link string
+	for _, f := range files {
+		switch f.Name() {
+		case multipathBlockPrefix + id:
+			return f.Name()
+		case blockPrefix + id, nvmeBlockPrefix + id:
+			link = f.Name()
+		default:
+			if strings.HasPrefix(f.Name(), nvmeUUIDBlockPrefix) &&
+				strings.ReplaceAll(strings.TrimPrefix(f.Name(), nvmeUUIDBlockPrefix), "-", "") == id {
+				link = f.Name()
+			}
+		}
+	}
+	return link
+}
+
+// findDiskPath returns the path of the device of the given disk, or an empty
+// string if the disk was not found.
+func (d *deviceDiscovery) findDiskPath(id string) (string, error) {
+	files, err := ioutil.ReadDir(d.devDiskIDDir)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	if link := findDiskLink(id, files); link != "" {
+		path := filepath.Join(d.devDiskIDDir, link)
+		if strings.HasPrefix(link, multipathBlockPrefix) {
+			return path, nil
+		}
+		// udev rules may point the links of a multipath disk at one of its
+		// paths, which must not be mounted directly.
+		realDev, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			return "", err
+		}
+		if holder := d.multipathHolder(filepath.Base(realDev)); holder != "" {
+			return filepath.Join(d.devDir, holder), nil
+		}
+		return path, nil
+	}
+	// Fall back to the block devices known to the kernel, whose links may
+	// not have been created by udev yet.
+	devs, err := ioutil.ReadDir(d.sysBlockDir)
+	if err != nil {
+		return "", err
+	}
+	for _, dev := range devs {
+		wwid, err := d.deviceWWID(dev.Name())
+		if err != nil || wwid != strings.ToLower(id) {
+			continue
+		}
+		if holder := d.multipathHolder(dev.Name()); holder != "" {
+			return filepath.Join(d.devDir, holder), nil
+		}
+		return filepath.Join(d.devDir, dev.Name()), nil
+	}
+	return "", nil
+}
+
+// multipathHolder returns the name of the multipath device holding the given
+// block device, or an empty string if the device is not a path of a
+// multipath device.
+func (d *deviceDiscovery) multipathHolder(devName string) string {
+	holders, err := ioutil.ReadDir(filepath.Join(d.sysBlockDir, devName, "holders"))
+	if err != nil {
+		return ""
+	}
+	for _, holder := range holders {
+		uuid, err := ioutil.ReadFile(filepath.Join(d.sysBlockDir, holder.Name(), "dm", "uuid"))
+		if err == nil && strings.HasPrefix(strings.TrimSpace(string(uuid)), multipathUUIDPrefix) {
+			return holder.Name()
+		}
+	}
+	return ""
+}
+
+// deviceWWID returns the disk UUID reported by the given block device, in
+// lower case, or an error if the device doesn't report one. SCSI disks report
+// their NAA identifier, NVMe namespaces their EUI or hyphenated UUID, and
+// multipath devices the SCSI identifier of their disk.
+func (d *deviceDiscovery) deviceWWID(devName string) (string, error) {
+	if uuid, err := ioutil.ReadFile(filepath.Join(d.sysBlockDir, devName, "dm", "uuid")); err == nil {
+		id := strings.ToLower(strings.TrimSpace(string(uuid)))
+		if !strings.HasPrefix(id, multipathUUIDPrefix+"3") {
+			return "", fmt.Errorf("device %q is not a multipath device of a SCSI disk", devName)
+		}
+		return strings.TrimPrefix(id, multipathUUIDPrefix+"3"), nil
+	}
+	wwid, err := ioutil.ReadFile(filepath.Join(d.sysBlockDir, devName, "device", "wwid"))
+	if os.IsNotExist(err) {
+		wwid, err = ioutil.ReadFile(filepath.Join(d.sysBlockDir, devName, "wwid"))
+	}
+	if err != nil {
+		return "", err
+	}
+	id := strings.ToLower(strings.TrimSpace(string(wwid)))
+	for _, prefix := range []string{"naa.", "eui."} {
+		if strings.HasPrefix(id, prefix) {
+			return strings.TrimPrefix(id, prefix), nil
+		}
+	}
+	if strings.HasPrefix(id, "uuid.") {
+		return strings.ReplaceAll(strings.TrimPrefix(id, "uuid."), "-", ""), nil
+	}
+	return "", fmt.Errorf("unsupported identifier %q of device %q", id, devName)
+}
+
+// validateDevice checks that the device at the given path is the device of
+// the given disk. Devices which don't report an identifier are not
+// validated.
+func (d *deviceDiscovery) validateDevice(path string, id string) error {
+	realDev, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return err
+	}
+	wwid, err := d.deviceWWID(filepath.Base(realDev))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if wwid != strings.ToLower(id) {
+		return fmt.Errorf("%w: device %q of disk %q reports disk %q", errDeviceMismatch, realDev, id, wwid)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+//go:build darwin || linux
+// +build darwin linux
+
+package osutils
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testDiskID = "6000c2912f0e9a3e1b4c2d5f6a7b8c9d"
+
+// newTestDeviceDiscovery returns a deviceDiscovery rooted in a temporary
+// directory, and a function to add files to it.
+func newTestDeviceDiscovery(t *testing.T) (*deviceDiscovery, func(path, content string)) {
+	root, err := ioutil.TempDir("", "device-discovery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(root) })
+	d := &deviceDiscovery{
+		devDiskIDDir: filepath.Join(root, "dev", "disk", "by-id"),
+		sysBlockDir:  filepath.Join(root, "sys", "block"),
+		devDir:       filepath.Join(root, "dev"),
+	}
+	for _, dir := range []string{d.devDiskIDDir, d.sysBlockDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write := func(path, content string) {
+		path = filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return d, write
+}
+
+func linkDisk(t *testing.T, d *deviceDiscovery, link, devName string) {
+	if err := os.Symlink(filepath.Join(d.devDir, devName), filepath.Join(d.devDiskIDDir, link)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFindDiskPath(t *testing.T) {
+	d, write := newTestDeviceDiscovery(t)
+	write("dev/sdb", "")
+	write("sys/block/sdb/device/wwid", "naa."+testDiskID+"\n")
+
+	// The disk is found from sysfs while udev has not created its link yet.
+	path, err := d.findDiskPath(testDiskID)
+	if err != nil || path != filepath.Join(d.devDir, "sdb") {
+		t.Fatalf("expected the device from sysfs, got %q, %v", path, err)
+	}
+
+	linkDisk(t, d, blockPrefix+testDiskID, "sdb")
+	path, err = d.findDiskPath(testDiskID)
+	if err != nil || path != filepath.Join(d.devDiskIDDir, blockPrefix+testDiskID) {
+		t.Fatalf("expected the by-id link, got %q, %v", path, err)
+	}
+	if err := d.validateDevice(path, testDiskID); err != nil {
+		t.Errorf("unexpected validation error %v", err)
+	}
+
+	// A path of a multipath device resolves to the multipath device.
+	write("dev/dm-0", "")
+	write("sys/block/sdb/holders/dm-0", "")
+	write("sys/block/dm-0/dm/uuid", "mpath-3"+testDiskID+"\n")
+	path, err = d.findDiskPath(testDiskID)
+	if err != nil || path != filepath.Join(d.devDir, "dm-0") {
+		t.Fatalf("expected the multipath device, got %q, %v", path, err)
+	}
+	if err := d.validateDevice(path, testDiskID); err != nil {
+		t.Errorf("unexpected validation error %v", err)
+	}
+
+	// The link of the multipath device is preferred.
+	linkDisk(t, d, multipathBlockPrefix+testDiskID, "dm-0")
+	path, err = d.findDiskPath(testDiskID)
+	if err != nil || path != filepath.Join(d.devDiskIDDir, multipathBlockPrefix+testDiskID) {
+		t.Fatalf("expected the multipath link, got %q, %v", path, err)
+	}
+
+	path, err = d.findDiskPath("6000c2900000000000000000000000ff")
+	if err != nil || path != "" {
+		t.Fatalf("expected no device for an unknown disk, got %q, %v", path, err)
+	}
+}
+
+func TestFindNVMeUUIDDiskPath(t *testing.T) {
+	d, write := newTestDeviceDiscovery(t)
+	uuid := testDiskID[:8] + "-" + testDiskID[8:12] + "-" + testDiskID[12:16] + "-" + testDiskID[16:20] + "-" +
+		testDiskID[20:]
+	write("dev/nvme0n1", "")
+	write("sys/block/nvme0n1/wwid", "uuid."+uuid+"\n")
+
+	// The disk is found from sysfs while udev has not created its link yet.
+	path, err := d.findDiskPath(testDiskID)
+	if err != nil || path != filepath.Join(d.devDir, "nvme0n1") {
+		t.Fatalf("expected the device from sysfs, got %q, %v", path, err)
+	}
+
+	linkDisk(t, d, nvmeUUIDBlockPrefix+uuid, "nvme0n1")
+	path, err = d.findDiskPath(testDiskID)
+	if err != nil || path != filepath.Join(d.devDiskIDDir, nvmeUUIDBlockPrefix+uuid) {
+		t.Fatalf("expected the by-id link, got %q, %v", path, err)
+	}
+	if err := d.validateDevice(path, testDiskID); err != nil {
+		t.Errorf("unexpected validation error %v", err)
+	}
+}
+
+func TestValidateDevice(t *testing.T) {
+	d, write := newTestDeviceDiscovery(t)
+	write("dev/sdb", "")
+	write("dev/nvme0n1", "")
+	write("dev/sdc", "")
+	write("sys/block/sdb/device/wwid", "naa.6000c2900000000000000000000000ff\n")
+	write("sys/block/nvme0n1/wwid", "eui."+testDiskID+"\n")
+
+	// A stale link pointing at another disk is rejected.
+	linkDisk(t, d, blockPrefix+testDiskID, "sdb")
+	err := d.validateDevice(filepath.Join(d.devDiskIDDir, blockPrefix+testDiskID), testDiskID)
+	if !errors.Is(err, errDeviceMismatch) {
+		t.Errorf("expected a mismatch error, got %v", err)
+	}
+	if err := d.validateDevice(filepath.Join(d.devDir, "nvme0n1"), testDiskID); err != nil {
+		t.Errorf("unexpected validation error for an NVMe namespace %v", err)
+	}
+	// Devices which don't report an identifier are not validated.
+	if err := d.validateDevice(filepath.Join(d.devDir, "sdc"), testDiskID); err != nil {
+		t.Errorf("unexpected validation error for a device without identifier %v", err)
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
 
 	"github.com/akutz/gofsutil"
 	"github.com/container-storage-interface/spec/lib/go/csi"
@@ -34,25 +35,119 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8svol "k8s.io/kubernetes/pkg/volume"
 	"k8s.io/kubernetes/pkg/volume/util/fs"
-	"k8s.io/mount-utils"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/mounter"
 
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/retry"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
 )
 
 const (
 	devDiskID   = "/dev/disk/by-id"
 	blockPrefix = "wwn-0x"
-	dmiDir      = "/sys/class/dmi"
+	// nvmeBlockPrefix is the prefix of the by-id links of the disks attached
+	// to a virtual NVMe controller, which is the default disk controller of
+	// arm64 VMs.
+	nvmeBlockPrefix = "nvme-eui."
+	dmiDir          = "/sys/class/dmi"
+	sysBlockDir     = "/sys/block"
+
+	// nvmeUUIDBlockPrefix is the prefix of the by-id links of the disks
+	// attached to a virtual NVMe controller reporting NVMe 1.3 namespace
+	// UUIDs, followed by the hyphenated UUID.
+	nvmeUUIDBlockPrefix = "nvme-uuid."
 )
 
 // defaultFileMountOptions are the mount flag options used by default while publishing a file volume.
 var defaultFileMountOptions = []string{"hard", "sec=sys", "vers=4", "minorversion=1"}
 
+// defaultNfsv3FileMountOptions are the mount flag options used by default
+// while publishing a file volume with NFS v3.
+var defaultNfsv3FileMountOptions = []string{"hard", "sec=sys", "vers=3"}
+
+// fileVolumeMount is the way to mount a file volume with an NFS version.
+type fileVolumeMount struct {
+	nfsVersion string
+	// accessPointKey is the key of the access point of the version in the
+	// publish context.
+	accessPointKey string
+	// fsType overrides the file system type of the volume capability if set.
+	fsType         string
+	defaultOptions []string
+}
+
+// getFileVolumeMounts returns the ways to mount a file volume with the given
+// NFS version, in the order they are to be tried.
+func getFileVolumeMounts(nfsVersion string) []fileVolumeMount {
+	nfsv41Mount := fileVolumeMount{
+		nfsVersion:     common.NfsVersion41,
+		accessPointKey: common.Nfsv4AccessPoint,
+		defaultOptions: defaultFileMountOptions,
+	}
+	// NFS v3 exports are mounted with the nfs type, mount.nfs4 only
+	// supports NFS v4.
+	nfsv3Mount := fileVolumeMount{
+		nfsVersion:     common.NfsVersion3,
+		accessPointKey: common.Nfsv3AccessPoint,
+		fsType:         common.NfsFsType,
+		defaultOptions: defaultNfsv3FileMountOptions,
+	}
+	switch nfsVersion {
+	case common.NfsVersion3:
+		return []fileVolumeMount{nfsv3Mount}
+	case common.NfsVersionAuto:
+		return []fileVolumeMount{nfsv41Mount, nfsv3Mount}
+	}
+	return []fileVolumeMount{nfsv41Mount}
+}
+
+// fileMountOptionKeys returns the keys of the given file volume mount
+// option, i.e. the keys of the default mount options it overrides.
+func fileMountOptionKeys(option string) []string {
+	key := strings.SplitN(option, "=", 2)[0]
+	switch key {
+	case "hard", "soft":
+		return []string{"hard", "soft"}
+	case "vers", "nfsvers":
+		// The minor version of the default version doesn't apply to another
+		// version.
+		return []string{"vers", "minorversion"}
+	}
+	return []string{key}
+}
+
+// mergeFileMountOptions returns the given mount flags with the given default
+// mount options they don't override.
+func mergeFileMountOptions(mntFlags []string, defaults []string) []string {
+	overridden := make(map[string]bool)
+	for _, flag := range mntFlags {
+		for _, key := range fileMountOptionKeys(flag) {
+			overridden[key] = true
+		}
+	}
+	merged := append([]string{}, mntFlags...)
+	for _, option := range defaults {
+		if !overridden[strings.SplitN(option, "=", 2)[0]] {
+			merged = append(merged, option)
+		}
+	}
+	return merged
+}
+
+// fsMounter performs the mount operations on the host. Mount operations are
+// forwarded to the mount helper daemon if one is configured.
+var fsMounter = mounter.NewFsMounter(context.Background(), "")
+
+// requiredHostUtilities are the host utilities invoked by the node plugin
+// while staging, publishing and expanding volumes.
+var requiredHostUtilities = []string{"blkid", "mkfs.ext3", "mkfs.ext4", "mkfs.xfs", "mount.nfs4",
+	"resize2fs", "xfs_growfs", "xfs_info"}
+
 // NewOsUtils creates OsUtils with a linux specific mounter
 func NewOsUtils(ctx context.Context) (*OsUtils, error) {
 	log := logger.GetLogger(ctx)
+	fsMounter = mounter.NewFsMounter(ctx, os.Getenv(csitypes.EnvVarMountHelperSocket))
 	mounter, err := mounter.NewSafeMounter(ctx)
 	if err != nil {
 		log.Debugf("Could not create instance of Mounter %v", err)
@@ -63,6 +158,20 @@ func NewOsUtils(ctx context.Context) (*OsUtils, error) {
 	}, nil
 }
 
+// GetMissingHostUtilities returns the list of host utilities required by the
+// node plugin which could not be found in the PATH.
+func (osUtils *OsUtils) GetMissingHostUtilities(ctx context.Context) []string {
+	log := logger.GetLogger(ctx)
+	var missing []string
+	for _, utility := range requiredHostUtilities {
+		if _, err := osUtils.Mounter.Exec.LookPath(utility); err != nil {
+			log.Debugf("Host utility %q not found. Err: %v", utility, err)
+			missing = append(missing, utility)
+		}
+	}
+	return missing
+}
+
 // NodeStageBlockVolume mounts mount volume or file volume to staging target
 func (osUtils *OsUtils) NodeStageBlockVolume(
 	ctx context.Context,
@@ -120,7 +229,7 @@ func (osUtils *OsUtils) NodeStageBlockVolume(
 			log.Debugf("nodeStageBlockVolume: Mounting %q at %q in read-only mode with mount flags %v",
 				dev.FullPath, params.StagingTarget, params.MntFlags)
 			params.MntFlags = append(params.MntFlags, "ro")
-			err := gofsutil.Mount(ctx, dev.FullPath, params.StagingTarget, params.FsType, params.MntFlags...)
+			err := fsMounter.Mount(ctx, dev.FullPath, params.StagingTarget, params.FsType, params.MntFlags...)
 			if err != nil {
 				return nil, logger.LogNewErrorCodef(log, codes.Internal,
 					"error mounting volume. Parameters: %v err: %v", params, err)
@@ -131,7 +240,7 @@ func (osUtils *OsUtils) NodeStageBlockVolume(
 		// Format and mount the device.
 		log.Debugf("nodeStageBlockVolume: Format and mount the device %q at %q with mount flags %v",
 			dev.FullPath, params.StagingTarget, params.MntFlags)
-		err := gofsutil.FormatAndMount(ctx, dev.FullPath, params.StagingTarget, params.FsType, params.MntFlags...)
+		err := fsMounter.FormatAndMount(ctx, dev.FullPath, params.StagingTarget, params.FsType, params.MntFlags...)
 		if err != nil {
 			return nil, logger.LogNewErrorCodef(log, codes.Internal,
 				"error in formating and mounting volume. Parameters: %v err: %v", params, err)
@@ -178,9 +287,9 @@ func (osUtils *OsUtils) CleanupStagePath(ctx context.Context, stagingTarget stri
 	// Volume is still mounted. Unstage the volume.
 	if isMounted {
 		log.Infof("Attempting to unmount target %q for volume %q", stagingTarget, volID)
-		if err := gofsutil.Unmount(ctx, stagingTarget); err != nil {
+		if err := fsMounter.Unmount(ctx, stagingTarget); err != nil {
 			return fmt.Errorf(
-				"error unmounting stagingTarget: %v", err)
+				"error unmounting stagingTarget: %w", err)
 		}
 	}
 	return nil
@@ -254,7 +363,7 @@ func (osUtils *OsUtils) CleanupPublishPath(ctx context.Context, target string, v
 			return nil
 		}
 		return fmt.Errorf(
-			"failed to stat target %q, err: %v", target, err)
+			"failed to stat target %q, err: %w", target, err)
 	}
 
 	// Fetch all the mount points.
@@ -286,7 +395,7 @@ func (osUtils *OsUtils) CleanupPublishPath(ctx context.Context, target string, v
 
 	if isPublished {
 		log.Infof("NodeUnpublishVolume: Attempting to unmount target %q for volume %q", target, volID)
-		if err := gofsutil.Unmount(ctx, target); err != nil {
+		if err := fsMounter.Unmount(ctx, target); err != nil {
 			return fmt.Errorf(
 				"error unmounting target %q for volume %q. %q", target, volID, err.Error())
 		}
@@ -356,7 +465,7 @@ func (osUtils *OsUtils) GetBlockSizeBytes(ctx context.Context, devicePath string
 	cmd := osUtils.Mounter.Exec.Command("blockdev", cmdArgs...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return -1, fmt.Errorf("error when getting size of block volume at path %s: output: %s, err: %v",
+		return -1, fmt.Errorf("error when getting size of block volume at path %s: output: %s, err: %w",
 			devicePath, string(output), err)
 	}
 	strOut := strings.TrimSpace(string(output))
@@ -439,7 +548,7 @@ func (osUtils *OsUtils) PublishMountVol(
 	}
 	log.Debugf("PublishMountVolume: Attempting to bind mount %q to %q with mount flags %v",
 		params.StagingTarget, params.Target, mntFlags)
-	if err := gofsutil.BindMount(ctx, params.StagingTarget, params.Target, mntFlags...); err != nil {
+	if err := fsMounter.BindMount(ctx, params.StagingTarget, params.Target, mntFlags...); err != nil {
 		return nil, logger.LogNewErrorCodef(log, codes.Internal,
 			"error mounting volume. Parameters: %v err: %v", params, err)
 	}
@@ -490,7 +599,7 @@ func (osUtils *OsUtils) PublishBlockVol(
 		mntFlags := make([]string, 0)
 		log.Debugf("PublishBlockVolume: Attempting to bind mount %q to %q with mount flags %v",
 			dev.FullPath, params.Target, mntFlags)
-		if err := gofsutil.BindMount(ctx, dev.FullPath, params.Target, mntFlags...); err != nil {
+		if err := fsMounter.BindMount(ctx, dev.FullPath, params.Target, mntFlags...); err != nil {
 			return nil, logger.LogNewErrorCodef(log, codes.Internal,
 				"error mounting volume. Parameters: %v err: %v", params, err)
 		}
@@ -566,23 +675,38 @@ func (osUtils *OsUtils) PublishFileVol(
 	if params.Ro {
 		mntFlags = append(mntFlags, "ro")
 	}
-	// Add defaultFileMountOptions to the mntFlags.
-	mntFlags = append(mntFlags, defaultFileMountOptions...)
-	// Retrieve the file share access point from publish context.
-	mntSrc, ok := req.GetPublishContext()[common.Nfsv4AccessPoint]
-	if !ok {
-		return nil, logger.LogNewErrorCode(log, codes.Internal,
-			"nfs v4 accesspoint not set in publish context")
-	}
-	// Directly mount the file share volume to the pod. No bind mount required.
-	log.Debugf("PublishFileVolume: Attempting to mount %q to %q with fstype %q and mountflags %v",
-		mntSrc, params.Target, fsType, mntFlags)
-	if err := gofsutil.Mount(ctx, mntSrc, params.Target, fsType, mntFlags...); err != nil {
-		return nil, logger.LogNewErrorCodef(log, codes.Internal,
-			"error publish volume to target path: %v", err)
+	// Mount with the NFS versions to negotiate, falling back to the next one
+	// if the access point of a version is not published or its mount fails.
+	nfsVersion := common.GetNfsVersion(req.GetVolumeContext(), mntFlags)
+	var mountErr error
+	for _, fileMount := range getFileVolumeMounts(nfsVersion) {
+		// Retrieve the file share access point from publish context. The
+		// export paths of the access points differ between NFS versions.
+		mntSrc, ok := req.GetPublishContext()[fileMount.accessPointKey]
+		if !ok {
+			mountErr = fmt.Errorf("nfs v%s accesspoint not set in publish context", fileMount.nfsVersion)
+			log.Debugf("PublishFileVolume: %v", mountErr)
+			continue
+		}
+		mntFsType := fsType
+		if fileMount.fsType != "" {
+			mntFsType = fileMount.fsType
+		}
+		// Add the default mount options not overridden by the mntFlags.
+		fileMntFlags := mergeFileMountOptions(mntFlags, fileMount.defaultOptions)
+		// Directly mount the file share volume to the pod. No bind mount required.
+		log.Debugf("PublishFileVolume: Attempting to mount %q to %q with fstype %q and mountflags %v",
+			mntSrc, params.Target, mntFsType, fileMntFlags)
+		if mountErr = fsMounter.Mount(ctx, mntSrc, params.Target, mntFsType, fileMntFlags...); mountErr != nil {
+			log.Warnf("PublishFileVolume: Failed to mount %q with NFS v%s. Err: %v", mntSrc,
+				fileMount.nfsVersion, mountErr)
+			continue
+		}
+		log.Infof("NodePublishVolume successful to path %q with NFS v%s", params.Target, fileMount.nfsVersion)
+		return &csi.NodePublishVolumeResponse{}, nil
 	}
-	log.Infof("NodePublishVolume successful to path %q", params.Target)
-	return &csi.NodePublishVolumeResponse{}, nil
+	return nil, logger.LogNewErrorCodef(log, codes.Internal,
+		"error publish volume to target path: %v", mountErr)
 }
 
 // GetDevice returns a Device struct with info about the given device, or
@@ -641,9 +765,15 @@ func (osUtils *OsUtils) GetDeviceRescanPath(dev *Device) (string, error) {
 	// A typical dev.RealDev path looks like `/dev/sda`. To rescan a block
 	// device we need to write into `/sys/block/$DEVICE/device/rescan`
 	// Refer to https://kb.vmware.com/s/article/1006371
+	// NVMe namespaces, e.g. `/dev/nvme0n1`, are rescanned through their
+	// controller with `/sys/block/$DEVICE/device/rescan_controller`.
 	parts := strings.Split(dev.RealDev, "/")
 	if len(parts) == 3 && strings.HasPrefix(parts[1], "dev") {
-		return filepath.EvalSymlinks(filepath.Join("/sys/block", parts[2], "device", "rescan"))
+		rescanFile := "rescan"
+		if strings.HasPrefix(parts[2], "nvme") {
+			rescanFile = "rescan_controller"
+		}
+		return filepath.EvalSymlinks(filepath.Join(sysBlockDir, parts[2], "device", rescanFile))
 	}
 	return "", fmt.Errorf("illegal path for device %q", dev.RealDev)
 }
@@ -664,30 +794,46 @@ func (osUtils *OsUtils) GetDiskPath(id string, files []os.FileInfo) (string, err
 	} else {
 		devs = files
 	}
-	targetDisk := blockPrefix + id
-
-	for _, f := range devs {
-		if f.Name() == targetDisk {
-			return filepath.Join(devDiskID, f.Name()), nil
-		}
+	// Disks attached to a SCSI controller are identified by their WWN, and
+	// the ones attached to an NVMe controller by their EUI, both derived from
+	// the disk UUID.
+	if link := findDiskLink(id, devs); link != "" {
+		return filepath.Join(devDiskID, link), nil
 	}
-
 	return "", nil
 }
 
 // VerifyVolumeAttached verifies if the volume path exist for diskID
 func (osUtils *OsUtils) VerifyVolumeAttached(ctx context.Context, diskID string) (string, error) {
 	log := logger.GetLogger(ctx)
-	// Check that volume is attached.
-	volPath, err := osUtils.GetDiskPath(diskID, nil)
+	// Check that volume is attached. The device of a volume which was just
+	// attached may take a moment to show up on the node, and its by-id link
+	// may still point at a detached disk until udev processed its events.
+	var volPath string
+	errDiskNotFound := errors.New("disk not found")
+	err := retry.Do(ctx, "NodeDeviceDiscovery", retry.NodeDeviceDiscovery, func() error {
+		var err error
+		volPath, err = nodeDeviceDiscovery.findDiskPath(diskID)
+		if err != nil {
+			return retry.Permanent(err)
+		}
+		if volPath == "" {
+			return errDiskNotFound
+		}
+		return nodeDeviceDiscovery.validateDevice(volPath, diskID)
+	})
+	if err == errDiskNotFound {
+		return "", logger.LogNewErrorCodef(log, codes.NotFound,
+			"disk: %s not attached to node", diskID)
+	}
+	if errors.Is(err, errDeviceMismatch) {
+		return "", logger.LogNewErrorCodef(log, codes.Internal,
+			"refusing to use device %q for disk %s: %v", volPath, diskID, err)
+	}
 	if err != nil {
 		return "", logger.LogNewErrorCodef(log, codes.Internal,
 			"error trying to read attached disks: %v", err)
 	}
-	if volPath == "" {
-		return "", logger.LogNewErrorCodef(log, codes.NotFound,
-			"disk: %s not attached to node", diskID)
-	}
 
 	log.Debugf("found disk: disk ID: %q, volume path: %q", diskID, volPath)
 	return volPath, nil
@@ -902,6 +1048,139 @@ func (osUtils *OsUtils) GetDevFromMount(ctx context.Context, target string) (*De
 	return nil, nil
 }
 
+// GetVolumeCondition returns a message describing why the volume published
+// at the given target path is abnormal, or an empty string if it is healthy.
+// A block volume is abnormal if it isn't mounted at the target path anymore,
+// or if its device is missing or not running.
+func (osUtils *OsUtils) GetVolumeCondition(ctx context.Context, target string) (string, error) {
+	mnts, err := gofsutil.GetMounts(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, m := range mnts {
+		if unescape(ctx, m.Path) != target {
+			continue
+		}
+		if m.Type == common.NfsFsType || m.Type == common.NfsV4FsType {
+			// File volumes have no device on the node.
+			return "", nil
+		}
+		d := m.Device
+		if m.Device == "udev" || m.Device == "devtmpfs" {
+			d = m.Source
+		}
+		realDev, err := filepath.EvalSymlinks(d)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return fmt.Sprintf("device %s of the volume is missing", d), nil
+			}
+			return "", err
+		}
+		state, err := getBlockDeviceState(sysBlockDir, filepath.Base(realDev))
+		if err != nil {
+			return "", err
+		}
+		if state != "" && state != "running" && state != "live" {
+			return fmt.Sprintf("device %s of the volume is in state %q", realDev, state), nil
+		}
+		return "", nil
+	}
+	return fmt.Sprintf("volume is not mounted at %s", target), nil
+}
+
+// GetStagedVolumeMounts returns the mounts of the file systems of the block
+// volumes staged on the node.
+func (osUtils *OsUtils) GetStagedVolumeMounts(ctx context.Context) ([]StagedVolumeMount, error) {
+	mnts, err := gofsutil.GetMounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var stagedMounts []*StagedVolumeMount
+	deviceMounts := make(map[string]*StagedVolumeMount)
+	for _, m := range mnts {
+		mountPath := unescape(ctx, m.Path)
+		if !strings.HasPrefix(m.Device, "/dev/") || !strings.Contains(mountPath, "/plugins/kubernetes.io/csi/") ||
+			!strings.HasSuffix(mountPath, "/globalmount") {
+			continue
+		}
+		device, err := filepath.EvalSymlinks(m.Device)
+		if err != nil {
+			device = m.Device
+		}
+		stagedMount := &StagedVolumeMount{
+			StagingPath: mountPath,
+			Device:      device,
+			ReadOnly:    common.Contains(m.Opts, "ro"),
+		}
+		stagedMounts = append(stagedMounts, stagedMount)
+		deviceMounts[m.Device] = stagedMount
+	}
+	// Publish paths look like <kubelet root>/pods/<pod uid>/volumes/kubernetes.io~csi/<pv>/mount.
+	for _, m := range mnts {
+		stagedMount, ok := deviceMounts[m.Device]
+		if !ok {
+			continue
+		}
+		parts := strings.Split(unescape(ctx, m.Path), "/")
+		for i := 0; i+2 < len(parts); i++ {
+			if parts[i] == "pods" && parts[i+2] == "volumes" {
+				stagedMount.PodUIDs = append(stagedMount.PodUIDs, parts[i+1])
+				break
+			}
+		}
+	}
+	result := make([]StagedVolumeMount, 0, len(stagedMounts))
+	for _, stagedMount := range stagedMounts {
+		result = append(result, *stagedMount)
+	}
+	return result, nil
+}
+
+// GetFilesystemCheckers returns the checkers detecting problems on the file
+// systems of staged volumes.
+func (osUtils *OsUtils) GetFilesystemCheckers() []FilesystemChecker {
+	return []FilesystemChecker{
+		NewReadOnlyRemountChecker(),
+		NewKernelLogChecker(osUtils.Mounter.Exec),
+	}
+}
+
+// IsStagedVolumeStale returns whether the file system staged at the given
+// path can't be accessed anymore, e.g. after the device of the volume was
+// lost during an all paths down condition.
+func (osUtils *OsUtils) IsStagedVolumeStale(stagingPath string) bool {
+	_, err := os.Stat(stagingPath)
+	return errors.Is(err, syscall.EIO) || errors.Is(err, syscall.ESTALE) || errors.Is(err, syscall.ENOTCONN)
+}
+
+// RemountStagedVolume remounts the file system staged at the given path
+// read-write, which recovers file systems remounted read-only on I/O errors
+// once the device of the volume is accessible again. The publish paths of the
+// volume share the file system and are recovered along.
+func (osUtils *OsUtils) RemountStagedVolume(ctx context.Context, stagingPath string) error {
+	log := logger.GetLogger(ctx)
+	log.Infof("Remounting file system staged at %q read-write", stagingPath)
+	output, err := osUtils.Mounter.Exec.Command("mount", "-o", "remount,rw", stagingPath).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to remount %q: output: %s, err: %w", stagingPath, string(output), err)
+	}
+	return nil
+}
+
+// getBlockDeviceState returns the state of the given block device, i.e.
+// "running" for a usable SCSI disk and "live" for the controller of a usable
+// NVMe namespace, or an empty string if the device has no state.
+func getBlockDeviceState(sysBlockDir, devName string) (string, error) {
+	state, err := ioutil.ReadFile(filepath.Join(sysBlockDir, devName, "device", "state"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(state)), nil
+}
+
 // IsTargetInMounts checks if a path exists in the mounts
 func (osUtils *OsUtils) IsTargetInMounts(ctx context.Context, path string) (bool, error) {
 	log := logger.GetLogger(ctx)
@@ -935,11 +1214,10 @@ func (osUtils *OsUtils) GetVolumeCapabilityFsType(ctx context.Context, capabilit
 // ResizeVolume resizes the volume
 func (osUtils *OsUtils) ResizeVolume(ctx context.Context, devicePath, volumePath string, reqVolSizeBytes int64) error {
 	log := logger.GetLogger(ctx)
-	resizer := mount.NewResizeFs(osUtils.Mounter.Exec)
-	_, err := resizer.Resize(devicePath, volumePath)
+	err := fsMounter.Resize(ctx, devicePath, volumePath)
 	if err != nil {
 		return fmt.Errorf(
-			"error when resizing filesystem on devicePath %s and volumePath %s, err: %v ", devicePath, volumePath, err)
+			"error when resizing filesystem on devicePath %s and volumePath %s, err: %w ", devicePath, volumePath, err)
 	}
 	// Check the block size.
 	currentBlockSizeBytes, err := osUtils.GetBlockSizeBytes(ctx, devicePath)
@@ -954,10 +1232,70 @@ func (osUtils *OsUtils) ResizeVolume(ctx context.Context, devicePath, volumePath
 		return logger.LogNewErrorCodef(log, codes.Internal,
 			"requested volume size was %d, but got volume with size %d", reqVolSizeBytes, currentBlockSizeBytes)
 	}
+	// xfs_growfs succeeds without growing the file system when it does not
+	// see a larger device yet, so check that xfs file systems span the whole
+	// device.
+	fsType, err := osUtils.Mounter.GetDiskFormat(devicePath)
+	if err != nil {
+		return logger.LogNewErrorCodef(log, codes.Internal,
+			"error when getting the file system type of device %s: %v", devicePath, err)
+	}
+	if fsType == common.XfsFsType {
+		blockSize, dataSizeBytes, err := osUtils.getXfsDataSize(volumePath)
+		if err != nil {
+			return logger.LogNewErrorCodef(log, codes.Internal,
+				"error when getting the size of the xfs file system at %s: %v", volumePath, err)
+		}
+		// Tolerate one block difference for rounding.
+		if dataSizeBytes+blockSize < currentBlockSizeBytes {
+			return logger.LogNewErrorCodef(log, codes.Internal,
+				"xfs file system at %s was not grown: file system size is %d, device size is %d",
+				volumePath, dataSizeBytes, currentBlockSizeBytes)
+		}
+	}
 
 	return nil
 }
 
+// getXfsDataSize returns the block size and the size of the data section of
+// the xfs file system mounted at mountPath, as reported by xfs_info.
+func (osUtils *OsUtils) getXfsDataSize(mountPath string) (int64, int64, error) {
+	output, err := osUtils.Mounter.Exec.Command("xfs_info", mountPath).CombinedOutput()
+	if err != nil {
+		return 0, 0, fmt.Errorf("xfs_info failed: output: %s, err: %w", string(output), err)
+	}
+	return parseXfsInfoDataSize(string(output))
+}
+
+// parseXfsInfoDataSize parses the block size and block count of the data
+// section from the output of xfs_info, e.g.
+// "data     =                       bsize=4096   blocks=262144, imaxpct=25",
+// and returns the block size and the size of the data section in bytes.
+func parseXfsInfoDataSize(output string) (int64, int64, error) {
+	for _, line := range strings.Split(output, "\n") {
+		if !strings.HasPrefix(line, "data") {
+			continue
+		}
+		var blockSize, blocks int64
+		for _, field := range strings.Fields(strings.ReplaceAll(line, ",", " ")) {
+			var err error
+			if strings.HasPrefix(field, "bsize=") {
+				blockSize, err = strconv.ParseInt(strings.TrimPrefix(field, "bsize="), 10, 64)
+			} else if strings.HasPrefix(field, "blocks=") {
+				blocks, err = strconv.ParseInt(strings.TrimPrefix(field, "blocks="), 10, 64)
+			}
+			if err != nil {
+				return 0, 0, fmt.Errorf("failed to parse %q of xfs_info output: %w", field, err)
+			}
+		}
+		if blockSize == 0 || blocks == 0 {
+			return 0, 0, fmt.Errorf("failed to find the data block size and count in %q", line)
+		}
+		return blockSize, blockSize * blocks, nil
+	}
+	return 0, 0, fmt.Errorf("failed to find the data section in xfs_info output %q", output)
+}
+
 func (osUtils *OsUtils) VerifyVolumeAttachedAndFillParams(ctx context.Context,
 	pubCtx map[string]string, params *NodePublishParams, dev **Device) error {
 	log := logger.GetLogger(ctx)
@@ -5,8 +5,17 @@ package osutils
 
 import (
 	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strconv"
 	"testing"
+
+	"k8s.io/mount-utils"
+	testingexec "k8s.io/utils/exec/testing"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
 )
 
 func TestUnescape(t *testing.T) {
@@ -52,3 +61,130 @@ func TestUnescape(t *testing.T) {
 		})
 	}
 }
+
+func TestGetMissingHostUtilities(t *testing.T) {
+	ctx := context.Background()
+	fakeExec := &testingexec.FakeExec{
+		LookPathFunc: func(file string) (string, error) {
+			if file == "xfs_growfs" || file == "mount.nfs4" {
+				return "", fmt.Errorf("executable file not found in $PATH")
+			}
+			return "/usr/sbin/" + file, nil
+		},
+	}
+	osUtils := &OsUtils{
+		Mounter: &mount.SafeFormatAndMount{
+			Interface: mount.NewFakeMounter(nil),
+			Exec:      fakeExec,
+		},
+	}
+	missing := osUtils.GetMissingHostUtilities(ctx)
+	if len(missing) != 2 || missing[0] != "mount.nfs4" || missing[1] != "xfs_growfs" {
+		t.Errorf("Expected missing host utilities [mount.nfs4 xfs_growfs], got %v", missing)
+	}
+}
+
+func TestGetBlockDeviceState(t *testing.T) {
+	sysBlock, err := ioutil.TempDir("", "sys-block")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(sysBlock)
+	for dev, state := range map[string]string{"sdb": "running\n", "sdc": "offline\n", "nvme0n1": "live\n"} {
+		if err := os.MkdirAll(filepath.Join(sysBlock, dev, "device"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(sysBlock, dev, "device", "state"), []byte(state), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		dev   string
+		state string
+	}{
+		{"sdb", "running"},
+		{"sdc", "offline"},
+		{"nvme0n1", "live"},
+		{"sdd", ""},
+	}
+	for _, test := range tests {
+		state, err := getBlockDeviceState(sysBlock, test.dev)
+		if err != nil {
+			t.Errorf("Unexpected error getting state of %s: %v", test.dev, err)
+		}
+		if state != test.state {
+			t.Errorf("Expected state %q of %s, got %q", test.state, test.dev, state)
+		}
+	}
+}
+
+func TestParseXfsInfoDataSize(t *testing.T) {
+	output := `meta-data=/dev/sdb               isize=512    agcount=4, agsize=65536 blks
+         =                       sectsz=512   attr=2, projid32bit=1
+         =                       crc=1        finobt=1, sparse=1, rmapbt=0
+data     =                       bsize=4096   blocks=262144, imaxpct=25
+         =                       sunit=0      swidth=0 blks
+naming   =version 2              bsize=4096   ascii-ci=0, ftype=1
+log      =internal log           bsize=4096   blocks=2560, version=2
+`
+	blockSize, size, err := parseXfsInfoDataSize(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blockSize != 4096 || size != 4096*262144 {
+		t.Errorf("expected block size 4096 and size %d, got %d and %d", 4096*262144, blockSize, size)
+	}
+	if _, _, err := parseXfsInfoDataSize("xfs_info: /mnt is not a mounted XFS filesystem"); err == nil {
+		t.Error("expected an error for output without data section")
+	}
+}
+
+func TestMergeFileMountOptions(t *testing.T) {
+	tests := []struct {
+		mntFlags []string
+		expected []string
+	}{
+		{nil, []string{"hard", "sec=sys", "vers=4", "minorversion=1"}},
+		{[]string{"ro", "noatime"}, []string{"ro", "noatime", "hard", "sec=sys", "vers=4", "minorversion=1"}},
+		{[]string{"soft", "sec=krb5"}, []string{"soft", "sec=krb5", "vers=4", "minorversion=1"}},
+		{[]string{"vers=4.2"}, []string{"vers=4.2", "hard", "sec=sys"}},
+		{[]string{"minorversion=0"}, []string{"minorversion=0", "hard", "sec=sys", "vers=4"}},
+	}
+	for _, test := range tests {
+		merged := mergeFileMountOptions(test.mntFlags, defaultFileMountOptions)
+		if fmt.Sprint(merged) != fmt.Sprint(test.expected) {
+			t.Errorf("mount flags %v: expected %v, got %v", test.mntFlags, test.expected, merged)
+		}
+	}
+}
+
+func TestGetFileVolumeMounts(t *testing.T) {
+	tests := []struct {
+		nfsVersion string
+		expected   []string
+	}{
+		{common.NfsVersion41, []string{common.Nfsv4AccessPoint}},
+		{common.NfsVersion3, []string{common.Nfsv3AccessPoint}},
+		{common.NfsVersionAuto, []string{common.Nfsv4AccessPoint, common.Nfsv3AccessPoint}},
+	}
+	for _, test := range tests {
+		var accessPointKeys []string
+		for _, fileMount := range getFileVolumeMounts(test.nfsVersion) {
+			accessPointKeys = append(accessPointKeys, fileMount.accessPointKey)
+		}
+		if fmt.Sprint(accessPointKeys) != fmt.Sprint(test.expected) {
+			t.Errorf("NFS version %q: expected access points %v, got %v", test.nfsVersion, test.expected,
+				accessPointKeys)
+		}
+	}
+	nfsv3Mount := getFileVolumeMounts(common.NfsVersion3)[0]
+	if nfsv3Mount.fsType != common.NfsFsType {
+		t.Errorf("expected NFS v3 volumes to be mounted with fstype %q, got %q", common.NfsFsType,
+			nfsv3Mount.fsType)
+	}
+	merged := mergeFileMountOptions([]string{"ro"}, nfsv3Mount.defaultOptions)
+	if expected := []string{"ro", "hard", "sec=sys", "vers=3"}; fmt.Sprint(merged) != fmt.Sprint(expected) {
+		t.Errorf("expected NFS v3 mount flags %v, got %v", expected, merged)
+	}
+}
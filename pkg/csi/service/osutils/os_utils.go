@@ -33,7 +33,7 @@ type OsUtils struct {
 type NodeStageParams struct {
 	// volID is the identifier for the underlying volume.
 	VolID string
-	// fsType is the file system type - ext3, ext4, nfs, nfs4.
+	// fsType is the file system type - ext3, ext4, xfs, nfs, nfs4.
 	FsType string
 	// Staging Target path is used to mount the volume to the node.
 	StagingTarget string
@@ -90,10 +90,13 @@ func (osUtils *OsUtils) EnsureMountVol(ctx context.Context, log *zap.SugaredLogg
 	}
 	fs := osUtils.GetVolumeCapabilityFsType(ctx, volCap)
 	mntFlags := mountVol.GetMountFlags()
+	if err := common.ValidateFsMountFlags(fs, mntFlags); err != nil {
+		return "", nil, logger.LogNewErrorCode(log, codes.InvalidArgument, err.Error())
+	}
 
 	// By default, xfs does not allow mounting of two volumes with the same filesystem uuid.
 	// Force ignore this uuid to be able to mount volume + its clone / restored snapshot on the same node.
-	if fs == "xfs" {
+	if fs == common.XfsFsType && !common.Contains(mntFlags, "nouuid") {
 		mntFlags = append(mntFlags, "nouuid")
 	}
 
@@ -30,9 +30,10 @@ import (
 func TestGetDiskPath(t *testing.T) {
 	osUtils, _ := NewOsUtils(context.TODO())
 	tests := []struct {
-		devs  []os.FileInfo
-		volID string
-		match bool
+		devs   []os.FileInfo
+		volID  string
+		match  bool
+		prefix string
 	}{
 		{
 			devs: []os.FileInfo{
@@ -50,6 +51,15 @@ func TestGetDiskPath(t *testing.T) {
 			volID: "702438570234875",
 			match: false,
 		},
+		{
+			devs: []os.FileInfo{
+				&FakeFileInfo{name: "nvme-eui.702438570234875"},
+				&FakeFileInfo{name: "wwn-0x702345804753484"},
+			},
+			volID:  "702438570234875",
+			match:  true,
+			prefix: nvmeBlockPrefix,
+		},
 	}
 
 	for _, tt := range tests {
@@ -61,7 +71,11 @@ func TestGetDiskPath(t *testing.T) {
 				t.Errorf("%v", e)
 			}
 
-			disk := filepath.Join(devDiskID, blockPrefix+tt.volID)
+			prefix := blockPrefix
+			if tt.prefix != "" {
+				prefix = tt.prefix
+			}
+			disk := filepath.Join(devDiskID, prefix+tt.volID)
 			if tt.match {
 				if d != disk {
 					t.Errorf("Expected disk: %s got: %s", disk, d)
@@ -60,6 +60,13 @@ func GetMounter(ctx context.Context, osUtils *OsUtils) (mounter.CSIProxyMounter,
 	}
 }
 
+// GetMissingHostUtilities returns the list of host utilities required by the
+// node plugin which could not be found. Windows nodes perform all operations
+// through csi-proxy, so there are no host utilities to check.
+func (osUtils *OsUtils) GetMissingHostUtilities(ctx context.Context) []string {
+	return nil
+}
+
 // NodeStageBlockVolume mounts mount volume or file volume to staging target
 func (osUtils *OsUtils) NodeStageBlockVolume(
 	ctx context.Context,
@@ -143,7 +150,7 @@ func (osUtils *OsUtils) CleanupStagePath(ctx context.Context, stagingTarget stri
 	err = mounter.Unmount(stagingTarget)
 	if err != nil {
 		return fmt.Errorf(
-			"error unmounting stagingTarget: %v", err)
+			"error unmounting stagingTarget: %w", err)
 	}
 	return nil
 }
@@ -161,7 +168,7 @@ func (osUtils *OsUtils) CleanupPublishPath(ctx context.Context, target string, v
 	err = mounter.Rmdir(target)
 	if err != nil {
 		return fmt.Errorf(
-			"error unmounting publishTarget: %v", err)
+			"error unmounting publishTarget: %w", err)
 	}
 	return nil
 }
@@ -321,6 +328,41 @@ func (osUtils *OsUtils) VerifyTargetDir(ctx context.Context, target string, targ
 	return true, nil
 }
 
+// GetVolumeCondition returns a message describing why the volume published
+// at the given target path is abnormal, or an empty string if it is healthy.
+// The state of disks isn't exposed on Windows nodes, so volumes are always
+// reported healthy.
+func (osUtils *OsUtils) GetVolumeCondition(ctx context.Context, target string) (string, error) {
+	return "", nil
+}
+
+// GetStagedVolumeMounts returns the mounts of the file systems of the block
+// volumes staged on the node.
+// Staged volumes aren't monitored on Windows nodes, so none is returned.
+func (osUtils *OsUtils) GetStagedVolumeMounts(ctx context.Context) ([]StagedVolumeMount, error) {
+	return nil, nil
+}
+
+// GetFilesystemCheckers returns the checkers detecting problems on the file
+// systems of staged volumes.
+// Staged volumes aren't monitored on Windows nodes, so none is returned.
+func (osUtils *OsUtils) GetFilesystemCheckers() []FilesystemChecker {
+	return nil
+}
+
+// IsStagedVolumeStale returns whether the file system staged at the given
+// path can't be accessed anymore.
+// Staged volumes aren't recovered on Windows nodes, so they are never stale.
+func (osUtils *OsUtils) IsStagedVolumeStale(stagingPath string) bool {
+	return false
+}
+
+// RemountStagedVolume remounts the file system staged at the given path
+// read-write.
+func (osUtils *OsUtils) RemountStagedVolume(ctx context.Context, stagingPath string) error {
+	return fmt.Errorf("remounting staged volumes is not supported on windows nodes")
+}
+
 // GetDevFromMount returns device info mounted on the target dir
 func (osUtils *OsUtils) GetDevFromMount(ctx context.Context, target string) (*Device, error) {
 	return osUtils.GetDevice(ctx, target)
@@ -363,7 +405,7 @@ func (osUtils *OsUtils) ResizeVolume(ctx context.Context, devicePath, volumePath
 	err = mounter.ResizeVolume(devicePath, reqVolSizeBytes)
 	if err != nil {
 		return fmt.Errorf(
-			"error when resizing filesystem on devicePath %s and volumePath %s, err: %v ", devicePath, volumePath, err)
+			"error when resizing filesystem on devicePath %s and volumePath %s, err: %w ", devicePath, volumePath, err)
 	}
 	// Check the block size.
 	currentBlockSizeBytes, err := mounter.GetDiskTotalBytes(devicePath)
@@ -17,6 +17,7 @@ limitations under the License.
 package service
 
 import (
+	"context"
 	"net"
 	"net/url"
 	"os"
@@ -24,6 +25,7 @@ import (
 	"sync"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/rexray/gocsi/utils"
 	"google.golang.org/grpc"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
 
@@ -79,14 +81,14 @@ func (s *nonBlockingGRPCServer) GracefulStop() {
 	})
 }
 
+// Stop stops the gRPC server, even if it is being stopped gracefully, so
+// that a graceful stop taking too long can be cut short.
 func (s *nonBlockingGRPCServer) Stop() {
 	log := logger.GetLoggerWithNoContext()
-	stopOnce.Do(func() {
-		if s.server != nil {
-			s.server.Stop()
-		}
-		log.Info("stopped")
-	})
+	if s.server != nil {
+		s.server.Stop()
+	}
+	log.Info("stopped")
 }
 
 func (s *nonBlockingGRPCServer) serve(endpoint string, ids csi.IdentityServer,
@@ -94,7 +96,7 @@ func (s *nonBlockingGRPCServer) serve(endpoint string, ids csi.IdentityServer,
 	log := logger.GetLoggerWithNoContext()
 	u, err := url.Parse(endpoint)
 	if err != nil {
-		return logger.LogNewErrorf(log, "failed to parse the endpoint %s. Err: %v", endpoint, err)
+		return logger.LogNewErrorf(log, "failed to parse the endpoint %s. Err: %w", endpoint, err)
 	}
 
 	// CSI driver currently supports only unix path.
@@ -106,15 +108,20 @@ func (s *nonBlockingGRPCServer) serve(endpoint string, ids csi.IdentityServer,
 
 	// Remove UNIX sock file if present.
 	if err := os.Remove(addr); err != nil && !os.IsNotExist(err) {
-		return logger.LogNewErrorf(log, "failed to remove %s. Err: %v", addr, err)
+		return logger.LogNewErrorf(log, "failed to remove %s. Err: %w", addr, err)
 	}
 
 	listener, err := net.Listen(u.Scheme, addr)
 	if err != nil {
-		return logger.LogNewErrorf(log, "failed to listen: %v", err)
+		return logger.LogNewErrorf(log, "failed to listen: %w", err)
 	}
 
-	server := grpc.NewServer()
+	ctx := logger.NewContextWithLogger(context.Background())
+	opts := GRPCServerOptions(ctx)
+	if interceptors := GRPCServerInterceptors(ctx); len(interceptors) > 0 {
+		opts = append(opts, grpc.UnaryInterceptor(utils.ChainUnaryServer(interceptors...)))
+	}
+	server := grpc.NewServer(opts...)
 	s.server = server
 
 	// Register the CSI services.
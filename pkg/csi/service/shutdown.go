@@ -0,0 +1,77 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+)
+
+// handleTermination shuts the driver down on SIGTERM or SIGINT. The returned
+// terminating channel is closed when a termination signal is received, and
+// the terminated channel once the driver is shut down.
+func (driver *vsphereCSIDriver) handleTermination(ctx context.Context,
+	server NonBlockingGRPCServer) (<-chan struct{}, <-chan struct{}) {
+	terminating := make(chan struct{})
+	terminated := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	go func() {
+		sig := <-signals
+		signal.Stop(signals)
+		close(terminating)
+		defer close(terminated)
+		logger.GetLogger(ctx).Infof("Received signal %v, shutting down", sig)
+		driver.shutdown(ctx, server, common.GetShutdownTimeout(ctx))
+	}()
+	return terminating, terminated
+}
+
+// shutdown stops the given server gracefully, waiting up to timeout for the
+// requests in progress, e.g. waiting on CNS tasks, to complete. The
+// controller then logs out of vCenter.
+func (driver *vsphereCSIDriver) shutdown(ctx context.Context, server NonBlockingGRPCServer,
+	timeout time.Duration) {
+	log := logger.GetLogger(ctx)
+	log.Infof("Waiting up to %v for the requests in progress to complete", timeout)
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		log.Info("All the requests in progress completed")
+	case <-time.After(timeout):
+		log.Warnf("Requests still in progress after %v, stopping", timeout)
+		server.Stop()
+	}
+
+	if !strings.EqualFold(driver.mode, "node") {
+		logoutCtx, cancel := context.WithTimeout(ctx, common.VCLogoutTimeout)
+		defer cancel()
+		cnsvsphere.LogoutAllVirtualCenters(logoutCtx)
+	}
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+)
+
+// fakeGRPCServer is a NonBlockingGRPCServer whose graceful stop completes
+// once inFlight is closed, or the server is stopped.
+type fakeGRPCServer struct {
+	inFlight chan struct{}
+	stopped  chan struct{}
+}
+
+func (s *fakeGRPCServer) Start(string, csi.IdentityServer, csi.ControllerServer, csi.NodeServer) {}
+
+func (s *fakeGRPCServer) Stop() {
+	close(s.stopped)
+}
+
+func (s *fakeGRPCServer) GracefulStop() {
+	select {
+	case <-s.inFlight:
+	case <-s.stopped:
+	}
+}
+
+// TestShutdown tests that shutdown waits for the requests in progress, and
+// stops the server once the timeout expired.
+func TestShutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	driver := &vsphereCSIDriver{mode: "node"}
+
+	server := &fakeGRPCServer{inFlight: make(chan struct{}), stopped: make(chan struct{})}
+	close(server.inFlight)
+	driver.shutdown(ctx, server, time.Minute)
+	select {
+	case <-server.stopped:
+		t.Error("expected the server not to be stopped once the requests in progress completed")
+	default:
+	}
+
+	server = &fakeGRPCServer{inFlight: make(chan struct{}), stopped: make(chan struct{})}
+	driver.shutdown(ctx, server, 10*time.Millisecond)
+	select {
+	case <-server.stopped:
+	default:
+		t.Error("expected the server to be stopped once the timeout expired")
+	}
+}
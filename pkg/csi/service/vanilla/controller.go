@@ -18,13 +18,17 @@ package vanilla
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/fsnotify/fsnotify"
@@ -69,6 +73,21 @@ type controller struct {
 	nodeMgr     NodeManagerInterface
 	authMgr     common.AuthorizationService
 	topologyMgr commoncotypes.ControllerTopologyService
+	// attachBatcher batches attach and detach requests per node VM. It is
+	// nil when attach/detach batching is disabled.
+	attachBatcher *common.VolumeAttachBatcher
+	// compatibilityReport holds the features found working or failing per
+	// vCenter version. It is nil if no compatibility report is configured.
+	compatibilityReport *common.CompatibilityReport
+}
+
+// controllerCapsFeatureGates maps controller capabilities to the feature
+// states which need to be enabled for them to be reported.
+var controllerCapsFeatureGates = map[csi.ControllerServiceCapability_RPC_Type]string{
+	csi.ControllerServiceCapability_RPC_LIST_VOLUMES:                 common.ListVolumes,
+	csi.ControllerServiceCapability_RPC_LIST_VOLUMES_PUBLISHED_NODES: common.ListVolumes,
+	csi.ControllerServiceCapability_RPC_GET_VOLUME:                   common.ControllerVolumeCondition,
+	csi.ControllerServiceCapability_RPC_VOLUME_CONDITION:             common.ControllerVolumeCondition,
 }
 
 // volumeMigrationService holds the pointer to VolumeMigration instance.
@@ -130,7 +149,7 @@ func (c *controller) Init(config *cnsconfig.Config, version string) error {
 	if !isAuthCheckFSSEnabled && len(c.manager.VcenterConfig.TargetvSANFileShareDatastoreURLs) > 0 {
 		datacenters, err := vc.ListDatacenters(ctx)
 		if err != nil {
-			return logger.LogNewErrorf(log, "failed to find datacenters from VC: %q, Error: %+v", vc.Config.Host, err)
+			return logger.LogNewErrorf(log, "failed to find datacenters from VC: %q, Error: %w", vc.Config.Host, err)
 		}
 		// Check if file service is enabled on datastore present in
 		// targetvSANFileShareDatastoreURLs.
@@ -138,7 +157,7 @@ func (c *controller) Init(config *cnsconfig.Config, version string) error {
 			c.manager.VcenterConfig.TargetvSANFileShareDatastoreURLs, vc, datacenters)
 		if err != nil {
 			return logger.LogNewErrorf(log, "file service enablement check failed for datastore specified in "+
-				"TargetvSANFileShareDatastoreURLs. err=%v", err)
+				"TargetvSANFileShareDatastoreURLs. err=%w", err)
 		}
 		for _, targetFSDatastore := range c.manager.VcenterConfig.TargetvSANFileShareDatastoreURLs {
 			isFSEnabled := dsToFileServiceEnabledMap[targetFSDatastore]
@@ -192,6 +211,10 @@ func (c *controller) Init(config *cnsconfig.Config, version string) error {
 				config.Global.CSIAuthCheckIntervalInMin)
 		}
 	}
+	if commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.PermissionsMonitor) {
+		log.Info("PermissionsMonitor feature is enabled, checking the privileges of the VC user periodically")
+		go c.runPermissionsMonitor(config.Global.CSIAuthCheckIntervalInMin)
+	}
 
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
@@ -242,6 +265,20 @@ func (c *controller) Init(config *cnsconfig.Config, version string) error {
 			return err
 		}
 	}
+	if commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.AttachDetachBatching) {
+		window := time.Duration(config.Global.AttachDetachBatchWindowInMs) * time.Millisecond
+		log.Infof("Attach/detach batching is enabled with a batch window of %v", window)
+		c.attachBatcher = common.NewVolumeAttachBatcher(c.manager, window)
+	}
+	if reportPath := os.Getenv(common.EnvCompatibilityReport); reportPath != "" {
+		report, err := common.LoadCompatibilityReport(reportPath)
+		if err != nil {
+			log.Warnf("failed to load compatibility report. Capabilities won't be gated by it. Err: %v", err)
+		} else {
+			log.Infof("Loaded compatibility report %q with %d results", reportPath, len(report.Results))
+			c.compatibilityReport = report
+		}
+	}
 	// Create dynamic informer for CSINodeTopology instance if FSS is enabled.
 	if commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.ImprovedVolumeTopology) {
 		// Initialize volume topology service.
@@ -274,7 +311,7 @@ func (c *controller) ReloadConfiguration() error {
 	log.Info("Reloading Configuration")
 	cfg, err := common.GetConfig(ctx)
 	if err != nil {
-		return logger.LogNewErrorf(log, "failed to read config. Error: %+v", err)
+		return logger.LogNewErrorf(log, "failed to read config. Error: %w", err)
 	}
 	newVCConfig, err := cnsvsphere.GetVirtualCenterConfig(ctx, cfg)
 	if err != nil {
@@ -291,7 +328,7 @@ func (c *controller) ReloadConfiguration() error {
 			// vCenter. Proceed only if the connection succeeds, else return error.
 			newVC := &cnsvsphere.VirtualCenter{Config: newVCConfig}
 			if err = newVC.Connect(ctx); err != nil {
-				return logger.LogNewErrorf(log, "failed to connect to VirtualCenter host: %q, Err: %+v",
+				return logger.LogNewErrorf(log, "failed to connect to VirtualCenter host: %q, Err: %w",
 					newVCConfig.Host, err)
 			}
 
@@ -299,14 +336,14 @@ func (c *controller) ReloadConfiguration() error {
 			log.Info("Obtaining new vCenterInstance using new credentials")
 			vcenter, err = cnsvsphere.GetVirtualCenterInstance(ctx, &cnsconfig.ConfigurationInfo{Cfg: cfg}, true)
 			if err != nil {
-				return logger.LogNewErrorf(log, "failed to get VirtualCenter. err=%v", err)
+				return logger.LogNewErrorf(log, "failed to get VirtualCenter. err=%w", err)
 			}
 		} else {
 			// If it's not a VC host or VC credentials update, same singleton
 			// instance can be used and it's Config field can be updated.
 			vcenter, err = cnsvsphere.GetVirtualCenterInstance(ctx, &cnsconfig.ConfigurationInfo{Cfg: cfg}, false)
 			if err != nil {
-				return logger.LogNewErrorf(log, "failed to get VirtualCenter. err=%v", err)
+				return logger.LogNewErrorf(log, "failed to get VirtualCenter. err=%w", err)
 			}
 			vcenter.Config = newVCConfig
 		}
@@ -490,7 +527,7 @@ func (c *controller) createBlockVolume(ctx context.Context, req *csi.CreateVolum
 			// Check if topology domains have been provided in the vSphere CSI config secret.
 			// NOTE: We do not support kubernetes.io/hostname as a topology label.
 			if c.manager.CnsConfig.Labels.TopologyCategories == "" && c.manager.CnsConfig.Labels.Zone == "" &&
-				c.manager.CnsConfig.Labels.Region == "" {
+				c.manager.CnsConfig.Labels.Region == "" && c.manager.CnsConfig.Labels.TopologyHierarchy == "" {
 				return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCode(log, codes.InvalidArgument,
 					"topology category names not specified in the vsphere config secret")
 			}
@@ -506,9 +543,11 @@ func (c *controller) createBlockVolume(ctx context.Context, req *csi.CreateVolum
 			log.Debugf("Shared datastores [%+v] retrieved for topologyRequirement [%+v]", sharedDatastores,
 				topologyRequirement)
 		} else {
-			if c.manager.CnsConfig.Labels.Zone == "" || c.manager.CnsConfig.Labels.Region == "" {
-				// If zone and region label (vSphere category names) not specified in
-				// the config secret, then return NotFound error.
+			if (c.manager.CnsConfig.Labels.Zone == "" || c.manager.CnsConfig.Labels.Region == "") &&
+				c.manager.CnsConfig.Labels.TopologyHierarchy == "" {
+				// If zone and region label (vSphere category names) nor topology
+				// hierarchy are specified in the config secret, then return NotFound
+				// error.
 				return nil, csifault.CSIInternalFault, logger.LogNewErrorCode(log, codes.Internal,
 					"zone/region vsphere category names not specified in the vsphere config secret")
 			}
@@ -552,12 +591,19 @@ func (c *controller) createBlockVolume(ctx context.Context, req *csi.CreateVolum
 	volumeInfo, faultType, err := common.CreateBlockVolumeUtil(ctx, cnstypes.CnsClusterFlavorVanilla,
 		c.manager, &createVolumeSpec, sharedDatastores)
 	if err != nil {
-		return nil, faultType, logger.LogNewErrorCodef(log, codes.Internal,
+		return nil, faultType, logger.LogNewErrorCodef(log, common.GetCnsErrorCode(faultType, err),
 			"failed to create volume. Error: %+v", err)
 	}
 
 	attributes := make(map[string]string)
 	attributes[common.AttributeDiskType] = common.DiskTypeBlockVolume
+	common.SetMountOptionsAttribute(req.GetVolumeCapabilities(), attributes)
+	if scParams.DetachQuiesceDelay > 0 {
+		attributes[common.AttributeDetachQuiesceDelay] = strconv.Itoa(int(scParams.DetachQuiesceDelay.Seconds()))
+	}
+	if scParams.DiskController != "" {
+		attributes[common.AttributeDiskController] = scParams.DiskController
+	}
 	if csiMigrationFeatureState && scParams.CSIMigration == "true" {
 		// In case if feature state switch is enabled after controller is
 		// deployed, we need to initialize the volumeMigrationService.
@@ -756,6 +802,35 @@ func (c *controller) createFileVolume(ctx context.Context, req *csi.CreateVolume
 			"parsing storage class parameters failed with error: %+v", err)
 	}
 
+	if scParams.StoragePolicyName != "" &&
+		commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.VolumeEncryption) {
+		// vSAN file shares can't be encrypted with VM Encryption.
+		vc, err := common.GetVCenter(ctx, c.manager)
+		if err != nil {
+			return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+				"failed to get vCenter. Error: %+v", err)
+		}
+		policyID, err := vc.GetStoragePolicyIDByName(ctx, scParams.StoragePolicyName)
+		if errors.Is(err, cnsvsphere.ErrStoragePolicyNotFound) {
+			return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+				"failed to get ID of storage policy %q. Error: %+v", scParams.StoragePolicyName, err)
+		}
+		if err != nil {
+			return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+				"failed to get ID of storage policy %q. Error: %+v", scParams.StoragePolicyName, err)
+		}
+		encrypted, err := vc.IsEncryptionStoragePolicy(ctx, policyID)
+		if err != nil {
+			return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+				"failed to check encryption of storage policy %q. Error: %+v", scParams.StoragePolicyName, err)
+		}
+		if encrypted {
+			return nil, csifault.CSIInvalidArgumentFault, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+				"storage policy %q is an encryption policy, which is not supported for file volumes",
+				scParams.StoragePolicyName)
+		}
+	}
+
 	var createVolumeSpec = common.CreateVolumeSpec{
 		CapacityMB: volSizeMB,
 		Name:       req.Name,
@@ -779,20 +854,24 @@ func (c *controller) createFileVolume(ctx context.Context, req *csi.CreateVolume
 		volumeID, faultType, err = common.CreateFileVolumeUtil(ctx, cnstypes.CnsClusterFlavorVanilla,
 			c.manager, &createVolumeSpec, filteredDatastores)
 		if err != nil {
-			return nil, faultType, logger.LogNewErrorCodef(log, codes.Internal,
+			return nil, faultType, logger.LogNewErrorCodef(log, common.GetCnsErrorCode(faultType, err),
 				"failed to create volume. Error: %+v", err)
 		}
 	} else {
 		volumeID, faultType, err = common.CreateFileVolumeUtilOld(ctx, cnstypes.CnsClusterFlavorVanilla,
 			c.manager, &createVolumeSpec)
 		if err != nil {
-			return nil, faultType, logger.LogNewErrorCodef(log, codes.Internal,
+			return nil, faultType, logger.LogNewErrorCodef(log, common.GetCnsErrorCode(faultType, err),
 				"failed to create volume. Error: %+v", err)
 		}
 	}
 
 	attributes := make(map[string]string)
 	attributes[common.AttributeDiskType] = common.DiskTypeFileVolume
+	common.SetMountOptionsAttribute(req.GetVolumeCapabilities(), attributes)
+	if scParams.NfsVersion != "" {
+		attributes[common.AttributeNfsVersion] = scParams.NfsVersion
+	}
 
 	resp := &csi.CreateVolumeResponse{
 		Volume: &csi.Volume{
@@ -923,8 +1002,12 @@ func (c *controller) DeleteVolume(ctx context.Context, req *csi.DeleteVolumeRequ
 		// TODO: Add code to determine the volume type and set volumeType for
 		// Prometheus metric accordingly.
 		faultType, err = common.DeleteVolumeUtil(ctx, c.manager.VolumeManager, req.VolumeId, true)
+		if cnsvolume.IsNotFoundError(faultType, err) {
+			log.Infof("volume: %q not found. Assuming it is already deleted. Error: %+v", req.VolumeId, err)
+			err = nil
+		}
 		if err != nil {
-			return nil, faultType, logger.LogNewErrorCodef(log, codes.Internal,
+			return nil, faultType, logger.LogNewErrorCodef(log, common.GetCnsErrorCode(faultType, err),
 				"failed to delete volume: %q. Error: %+v", req.VolumeId, err)
 		}
 		// Migration feature switch is enabled and volumePath is set.
@@ -1002,18 +1085,26 @@ func (c *controller) ControllerPublishVolume(ctx context.Context, req *csi.Contr
 			vSANFileBackingDetails :=
 				queryResult.Volumes[0].BackingObjectDetails.(*cnstypes.CnsVsanFileShareBackingDetails)
 			publishInfo[common.AttributeDiskType] = common.DiskTypeFileVolume
-			nfsv4AccessPointFound := false
 			for _, kv := range vSANFileBackingDetails.AccessPoints {
-				if kv.Key == common.Nfsv4AccessPointKey {
+				switch kv.Key {
+				case common.Nfsv4AccessPointKey:
 					publishInfo[common.Nfsv4AccessPoint] = kv.Value
-					nfsv4AccessPointFound = true
-					break
+				case common.Nfsv3AccessPointKey:
+					publishInfo[common.Nfsv3AccessPoint] = kv.Value
 				}
 			}
-			if !nfsv4AccessPointFound {
+			// Publish the access points of all the NFS versions, the node
+			// negotiates the version the volume is mounted with.
+			volCap := common.ApplyMountOptionsAttribute(req.GetVolumeCapability(), req.GetVolumeContext())
+			nfsVersion := common.GetNfsVersion(req.GetVolumeContext(), volCap.GetMount().GetMountFlags())
+			_, nfsv4AccessPointFound := publishInfo[common.Nfsv4AccessPoint]
+			_, nfsv3AccessPointFound := publishInfo[common.Nfsv3AccessPoint]
+			if (nfsVersion == common.NfsVersion41 && !nfsv4AccessPointFound) ||
+				(nfsVersion == common.NfsVersion3 && !nfsv3AccessPointFound) ||
+				(!nfsv4AccessPointFound && !nfsv3AccessPointFound) {
 				return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
-					"failed to get NFSv4 access point for volume: %q. Returned vSAN file backing details: %+v",
-					req.VolumeId, vSANFileBackingDetails)
+					"failed to get NFS %s access point for volume: %q. Returned vSAN file backing details: %+v",
+					nfsVersion, req.VolumeId, vSANFileBackingDetails)
 			}
 		} else {
 			// Block Volume.
@@ -1053,9 +1144,37 @@ func (c *controller) ControllerPublishVolume(ctx context.Context, req *csi.Contr
 			}
 			log.Debugf("Found VirtualMachine for node:%q.", req.NodeId)
 			// faultType is returned from manager.AttachVolume.
-			diskUUID, faultType, err := common.AttachVolumeUtil(ctx, c.manager, node, req.VolumeId, false)
+			var diskUUID, faultType string
+			attachVolume := func() (string, string, error) {
+				if c.attachBatcher != nil {
+					return c.attachBatcher.AttachVolume(ctx, node, req.VolumeId, false)
+				}
+				return common.AttachVolumeUtil(ctx, c.manager, node, req.VolumeId, false)
+			}
+			attachToNVMe := req.VolumeContext[common.AttributeDiskController] == common.DiskControllerNVMe &&
+				commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.NVMeDiskController)
+			if attachToNVMe {
+				// NVMe controllers are hot-added as needed by the attach.
+				attachVolume = func() (string, string, error) {
+					return common.AttachVolumeToNVMeController(ctx, c.manager, node, req.VolumeId)
+				}
+			}
+			diskUUID, faultType, err = attachVolume()
+			if err != nil && !attachToNVMe &&
+				commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.PVSCSIControllerHotAdd) {
+				// The attach may have failed as the PVSCSI controllers of the
+				// node VM are full. Retry once after hot-adding a controller.
+				added, hotAddErr := common.AddPVSCSIControllerIfFull(ctx, node)
+				if hotAddErr != nil {
+					log.Errorf("failed to hot-add PVSCSI controller to node %q. Err: %v", req.NodeId, hotAddErr)
+				} else if added {
+					log.Infof("Retrying to attach volume %q to node %q after hot-adding a PVSCSI controller",
+						req.VolumeId, req.NodeId)
+					diskUUID, faultType, err = attachVolume()
+				}
+			}
 			if err != nil {
-				return nil, faultType, logger.LogNewErrorCodef(log, codes.Internal,
+				return nil, faultType, logger.LogNewErrorCodef(log, common.GetCnsErrorCode(faultType, err),
 					"failed to attach disk: %+q with node: %q err %+v", req.VolumeId, req.NodeId, err)
 			}
 			publishInfo[common.AttributeDiskType] = common.DiskTypeBlockVolume
@@ -1165,6 +1284,22 @@ func (c *controller) ControllerUnpublishVolume(ctx context.Context, req *csi.Con
 		}
 		// Block Volume.
 		volumeType = prometheus.PrometheusBlockVolumeType
+		if commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.NonGracefulNodeShutdown) &&
+			c.isNodeOutOfService(ctx, req.NodeId) {
+			faultType, err = c.detachFromOutOfServiceNode(ctx, req.NodeId, req.VolumeId)
+			if cnsvolume.IsNotFoundError(faultType, err) {
+				log.Infof("volume: %q or node: %q not found. Assuming the volume is already detached. "+
+					"Error: %+v", req.VolumeId, req.NodeId, err)
+				err = nil
+			}
+			if err != nil {
+				return nil, faultType, logger.LogNewErrorCodef(log, common.GetCnsErrorCode(faultType, err),
+					"failed to force detach disk: %+q from out-of-service node: %q err %+v", req.VolumeId,
+					req.NodeId, err)
+			}
+			log.Infof("ControllerUnpublishVolume successful for volume ID: %s", req.VolumeId)
+			return &csi.ControllerUnpublishVolumeResponse{}, "", nil
+		}
 		var node *cnsvsphere.VirtualMachine
 		if commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.UseCSINodeId) {
 			node, err = c.nodeMgr.GetNodeByUuid(ctx, req.NodeId)
@@ -1175,9 +1310,24 @@ func (c *controller) ControllerUnpublishVolume(ctx context.Context, req *csi.Con
 			return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
 				"failed to find VirtualMachine for node:%q. Error: %v", req.NodeId, err)
 		}
-		faultType, err = common.DetachVolumeUtil(ctx, c.manager, node, req.VolumeId)
+		if commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.DetachQuiesce) {
+			if err := c.quiesceBeforeDetach(ctx, node, req.VolumeId); err != nil {
+				return nil, csifault.CSIInternalFault, logger.LogNewErrorCodef(log, codes.Internal,
+					"failed to quiesce disk: %+q on node: %q before detach. err %+v", req.VolumeId, req.NodeId, err)
+			}
+		}
+		if c.attachBatcher != nil {
+			faultType, err = c.attachBatcher.DetachVolume(ctx, node, req.VolumeId)
+		} else {
+			faultType, err = common.DetachVolumeUtil(ctx, c.manager, node, req.VolumeId)
+		}
+		if cnsvolume.IsNotFoundError(faultType, err) {
+			log.Infof("volume: %q not found. Assuming it is already detached from node: %q. Error: %+v",
+				req.VolumeId, req.NodeId, err)
+			err = nil
+		}
 		if err != nil {
-			return nil, faultType, logger.LogNewErrorCodef(log, codes.Internal,
+			return nil, faultType, logger.LogNewErrorCodef(log, common.GetCnsErrorCode(faultType, err),
 				"failed to detach disk: %+q from node: %q err %+v", req.VolumeId, req.NodeId, err)
 		}
 		log.Infof("ControllerUnpublishVolume successful for volume ID: %s", req.VolumeId)
@@ -1261,7 +1411,7 @@ func (c *controller) ControllerExpandVolume(ctx context.Context, req *csi.Contro
 		faultType, err = common.ExpandVolumeUtil(ctx, c.manager, volumeID, volSizeMB,
 			commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.AsyncQueryVolume))
 		if err != nil {
-			return nil, faultType, logger.LogNewErrorCodef(log, codes.Internal,
+			return nil, faultType, logger.LogNewErrorCodef(log, common.GetCnsErrorCode(faultType, err),
 				"failed to expand volume: %q to size: %d with error: %+v", volumeID, volSizeMB, err)
 		}
 
@@ -1316,7 +1466,101 @@ func (c *controller) ListVolumes(ctx context.Context, req *csi.ListVolumesReques
 	ctx = logger.NewContextWithLogger(ctx)
 	log := logger.GetLogger(ctx)
 	log.Infof("ListVolumes: called with args %+v", *req)
-	return nil, logger.LogNewErrorCode(log, codes.Unimplemented, "listVolumes")
+	if !commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.ListVolumes) {
+		return nil, logger.LogNewErrorCode(log, codes.Unimplemented, "listVolumes")
+	}
+	offset, err := validateVanillaListVolumesRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	limit := common.QueryVolumeLimit
+	if req.MaxEntries != 0 && int64(req.MaxEntries) < limit {
+		limit = int64(req.MaxEntries)
+	}
+	queryFilter := cnstypes.CnsQueryFilter{
+		ContainerClusterIds: []string{c.manager.CnsConfig.Global.ClusterID},
+		Cursor: &cnstypes.CnsCursor{
+			Offset: offset,
+			Limit:  limit,
+		},
+	}
+	queryResult, err := c.manager.VolumeManager.QueryVolume(ctx, queryFilter)
+	if err != nil {
+		return nil, logger.LogNewErrorCodef(log, codes.Internal,
+			"queryVolume failed for queryFilter: %+v. Err: %+v", queryFilter, err)
+	}
+	volumeIDToNodeIDs, err := c.getBlockVolumeIDToNodeIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	isVolumeConditionEnabled := commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx,
+		common.ControllerVolumeCondition)
+	var entries []*csi.ListVolumesResponse_Entry
+	for _, volume := range queryResult.Volumes {
+		// File volumes aren't attached to the node VMs, so the nodes they're
+		// published to are unknown. They're left out so that the
+		// external-attacher doesn't consider them detached.
+		if volume.VolumeType != common.BlockVolumeType {
+			continue
+		}
+		entry := &csi.ListVolumesResponse_Entry{
+			Volume: getCSIVolume(volume),
+			Status: &csi.ListVolumesResponse_VolumeStatus{
+				PublishedNodeIds: volumeIDToNodeIDs[volume.VolumeId.Id],
+			},
+		}
+		if isVolumeConditionEnabled {
+			entry.Status.VolumeCondition = getVolumeCondition(ctx, volume)
+		}
+		entries = append(entries, entry)
+	}
+	var nextToken string
+	cursor := queryResult.Cursor
+	if cursor.Offset > offset && cursor.Offset < cursor.TotalRecords {
+		nextToken = strconv.FormatInt(cursor.Offset, 10)
+	}
+	log.Infof("ListVolumes: served %d volumes, token for next set: %q", len(entries), nextToken)
+	return &csi.ListVolumesResponse{
+		Entries:   entries,
+		NextToken: nextToken,
+	}, nil
+}
+
+// getBlockVolumeIDToNodeIDs returns the IDs of the nodes the block volumes
+// are attached to, by volume ID. The node IDs are the IDs the nodes are
+// published to in ControllerPublishVolume, i.e. the node UUIDs if the
+// UseCSINodeId feature is enabled, and the node names otherwise.
+func (c *controller) getBlockVolumeIDToNodeIDs(ctx context.Context) (map[string][]string, error) {
+	log := logger.GetLogger(ctx)
+	nodeVMs, err := c.nodeMgr.GetAllNodes(ctx)
+	if err != nil {
+		return nil, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to find VirtualMachines for all registered nodes. Err: %v", err)
+	}
+	useNodeUuid := commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.UseCSINodeId)
+	volumeIDToNodeIDs := make(map[string][]string)
+	for _, nodeVM := range nodeVMs {
+		nodeID := nodeVM.UUID
+		if !useNodeUuid {
+			nodeID, err = c.nodeMgr.GetNodeNameByUUID(ctx, nodeVM.UUID)
+			if err != nil {
+				log.Warnf("ListVolumes: failed to get the node name of VM %v. Err: %v", nodeVM, err)
+				continue
+			}
+		}
+		devices, err := nodeVM.Device(ctx)
+		if err != nil {
+			return nil, logger.LogNewErrorCodef(log, codes.Internal,
+				"failed to get the devices of VM %v. Err: %v", nodeVM, err)
+		}
+		for _, device := range devices {
+			if virtualDisk, ok := device.(*types.VirtualDisk); ok && virtualDisk.VDiskId != nil {
+				volumeIDToNodeIDs[virtualDisk.VDiskId.Id] = append(volumeIDToNodeIDs[virtualDisk.VDiskId.Id], nodeID)
+			}
+		}
+	}
+	return volumeIDToNodeIDs, nil
 }
 
 func (c *controller) GetCapacity(ctx context.Context, req *csi.GetCapacityRequest) (
@@ -1324,7 +1568,103 @@ func (c *controller) GetCapacity(ctx context.Context, req *csi.GetCapacityReques
 	ctx = logger.NewContextWithLogger(ctx)
 	log := logger.GetLogger(ctx)
 	log.Infof("GetCapacity: called with args %+v", *req)
-	return nil, logger.LogNewErrorCode(log, codes.Unimplemented, "getCapacity")
+	volCaps := req.GetVolumeCapabilities()
+	if len(volCaps) > 0 {
+		if err := common.IsValidVolumeCapabilities(ctx, volCaps); err != nil {
+			return nil, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+				"volume capability not supported. Err: %+v", err)
+		}
+		if common.IsFileVolumeRequest(ctx, volCaps) {
+			// File volumes are provisioned by vSAN file service, whose
+			// capacity isn't reported.
+			log.Infof("GetCapacity: capacity of file volumes is not reported")
+			return &csi.GetCapacityResponse{}, nil
+		}
+	}
+	csiMigrationFeatureState := commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.CSIMigration)
+	scParams, err := common.ParseStorageClassParams(ctx, req.GetParameters(), csiMigrationFeatureState)
+	if err != nil {
+		return nil, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+			"parsing storage class parameters failed with error: %+v", err)
+	}
+
+	sharedDatastores, err := c.getSharedDatastoresInTopology(ctx, req.GetAccessibleTopology())
+	if err != nil {
+		// Error is already wrapped in CSI error code.
+		return nil, err
+	}
+	if commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.CSIAuthCheck) {
+		// Filter datastores which in datastoreMap from sharedDatastores.
+		sharedDatastores = c.filterDatastores(ctx, sharedDatastores)
+	}
+	vc, err := common.GetVCenter(ctx, c.manager)
+	if err != nil {
+		return nil, logger.LogNewErrorCodef(log, codes.Internal, "failed to get vCenter. Err: %v", err)
+	}
+	datastores, err := common.FilterDatastoresByStorageClassParams(ctx, vc, scParams, sharedDatastores)
+	if err != nil {
+		return nil, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to get the datastores matching the storage class parameters %+v. Err: %v", *scParams, err)
+	}
+	availableCapacity, maximumVolumeSize := common.GetDatastoresCapacity(datastores)
+	resp := &csi.GetCapacityResponse{
+		AvailableCapacity: availableCapacity,
+		MaximumVolumeSize: wrapperspb.Int64(maximumVolumeSize),
+	}
+	log.Infof("GetCapacity: returning %+v for %d datastores", resp, len(datastores))
+	return resp, nil
+}
+
+// getSharedDatastoresInTopology returns the datastores shared by the nodes of
+// the given topology segment, or by all the nodes of the cluster if no
+// segment is given.
+func (c *controller) getSharedDatastoresInTopology(ctx context.Context, topology *csi.Topology) (
+	[]*cnsvsphere.DatastoreInfo, error) {
+	log := logger.GetLogger(ctx)
+	if topology == nil || len(topology.GetSegments()) == 0 {
+		sharedDatastores, err := c.nodeMgr.GetSharedDatastoresInK8SCluster(ctx)
+		if err != nil {
+			return nil, logger.LogNewErrorCodef(log, codes.Internal,
+				"failed to get shared datastores in kubernetes cluster. Error: %+v", err)
+		}
+		return sharedDatastores, nil
+	}
+	topologyRequirement := &csi.TopologyRequirement{Requisite: []*csi.Topology{topology}}
+	if commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.ImprovedVolumeTopology) {
+		sharedDatastores, err := c.topologyMgr.GetSharedDatastoresInTopology(ctx,
+			commoncotypes.VanillaTopologyFetchDSParams{TopologyRequirement: topologyRequirement})
+		if err != nil {
+			return nil, logger.LogNewErrorCodef(log, codes.Internal,
+				"failed to get shared datastores for topology: %+v. Error: %+v", topology, err)
+		}
+		return sharedDatastores, nil
+	}
+	if (c.manager.CnsConfig.Labels.Zone == "" || c.manager.CnsConfig.Labels.Region == "") &&
+		c.manager.CnsConfig.Labels.TopologyHierarchy == "" {
+		return nil, logger.LogNewErrorCode(log, codes.InvalidArgument,
+			"zone/region vsphere category names not specified in the vsphere config secret")
+	}
+	vcenter, err := c.manager.VcenterManager.GetVirtualCenter(ctx, c.manager.VcenterConfig.Host)
+	if err != nil {
+		return nil, logger.LogNewErrorCodef(log, codes.Internal, "failed to get vCenter. Err: %v", err)
+	}
+	tagManager, err := cnsvsphere.GetTagManager(ctx, vcenter)
+	if err != nil {
+		return nil, logger.LogNewErrorCodef(log, codes.Internal, "failed to get tagManager. Err: %v", err)
+	}
+	defer func() {
+		err := tagManager.Logout(ctx)
+		if err != nil {
+			log.Errorf("failed to logout tagManager. err: %v", err)
+		}
+	}()
+	sharedDatastores, _, err := c.nodeMgr.GetSharedDatastoresInTopology(ctx, topologyRequirement, tagManager,
+		c.manager.CnsConfig.Labels.Zone, c.manager.CnsConfig.Labels.Region)
+	if err != nil {
+		return nil, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to get shared datastores in topology: %+v. Error: %+v", topology, err)
+	}
+	return sharedDatastores, nil
 }
 
 // initVolumeMigrationService is a helper method to initialize
@@ -1357,6 +1697,11 @@ func (c *controller) ControllerGetCapabilities(ctx context.Context, req *csi.Con
 		csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME,
 		csi.ControllerServiceCapability_RPC_PUBLISH_UNPUBLISH_VOLUME,
 		csi.ControllerServiceCapability_RPC_EXPAND_VOLUME,
+		csi.ControllerServiceCapability_RPC_GET_CAPACITY,
+		csi.ControllerServiceCapability_RPC_LIST_VOLUMES,
+		csi.ControllerServiceCapability_RPC_LIST_VOLUMES_PUBLISHED_NODES,
+		csi.ControllerServiceCapability_RPC_GET_VOLUME,
+		csi.ControllerServiceCapability_RPC_VOLUME_CONDITION,
 	}
 	var vcSnapshotSupportCheck, csiSnapshotFSSEnabled bool
 	// Report capability only if CSI Snapshot FSS is enabled and VC supports
@@ -1372,18 +1717,19 @@ func (c *controller) ControllerGetCapabilities(ctx context.Context, req *csi.Con
 		controllerCaps = append(controllerCaps, csi.ControllerServiceCapability_RPC_CREATE_DELETE_SNAPSHOT,
 			csi.ControllerServiceCapability_RPC_LIST_SNAPSHOTS)
 	}
-
-	var caps []*csi.ControllerServiceCapability
-	for _, cap := range controllerCaps {
-		c := &csi.ControllerServiceCapability{
-			Type: &csi.ControllerServiceCapability_Rpc{
-				Rpc: &csi.ControllerServiceCapability_RPC{
-					Type: cap,
-				},
-			},
+	if c.compatibilityReport != nil {
+		vc, err := common.GetVCenter(ctx, c.manager)
+		if err != nil {
+			log.Warnf("ControllerGetCapabilities: failed to get vCenter to check the compatibility report. Err: %v",
+				err)
+		} else {
+			controllerCaps = common.FilterCompatibleControllerCapabilities(ctx, c.compatibilityReport,
+				vc.Client.ServiceContent.About.Version, controllerCaps)
 		}
-		caps = append(caps, c)
 	}
+
+	caps := common.GetControllerServiceCapabilities(ctx, controllerCaps, controllerCapsFeatureGates,
+		commonco.ContainerOrchestratorUtility.IsFSSEnabled)
 	return &csi.ControllerGetCapabilitiesResponse{Capabilities: caps}, nil
 }
 
@@ -1605,5 +1951,38 @@ func (c *controller) ControllerGetVolume(ctx context.Context, req *csi.Controlle
 	ctx = logger.NewContextWithLogger(ctx)
 	log := logger.GetLogger(ctx)
 	log.Infof("ControllerGetVolume: called with args %+v", *req)
-	return nil, logger.LogNewErrorCode(log, codes.Unimplemented, "controllerGetVolume")
+	if !commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.ControllerVolumeCondition) {
+		return nil, logger.LogNewErrorCode(log, codes.Unimplemented, "controllerGetVolume")
+	}
+	volumeID := req.GetVolumeId()
+	if volumeID == "" {
+		return nil, logger.LogNewErrorCode(log, codes.InvalidArgument,
+			"ControllerGetVolume Volume ID must be provided")
+	}
+	volume, err := common.QueryVolumeByID(ctx, c.manager.VolumeManager, volumeID)
+	if err != nil {
+		if err == common.ErrNotFound {
+			return nil, logger.LogNewErrorCodef(log, codes.NotFound, "volume %q not found", volumeID)
+		}
+		return nil, logger.LogNewErrorCodef(log, codes.Internal,
+			"failed to query volume %q. Err: %+v", volumeID, err)
+	}
+	volumeStatus := &csi.ControllerGetVolumeResponse_VolumeStatus{
+		VolumeCondition: getVolumeCondition(ctx, *volume),
+	}
+	// The nodes file volumes are published to are unknown, as they aren't
+	// attached to the node VMs.
+	if volume.VolumeType == common.BlockVolumeType {
+		volumeIDToNodeIDs, err := c.getBlockVolumeIDToNodeIDs(ctx)
+		if err != nil {
+			return nil, err
+		}
+		volumeStatus.PublishedNodeIds = volumeIDToNodeIDs[volumeID]
+	}
+	resp := &csi.ControllerGetVolumeResponse{
+		Volume: getCSIVolume(*volume),
+		Status: volumeStatus,
+	}
+	log.Infof("ControllerGetVolume: returning %+v", resp)
+	return resp, nil
 }
@@ -21,13 +21,18 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
+	cnstypes "github.com/vmware/govmomi/cns/types"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/node"
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	csifault "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/fault"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common/commonco"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
 )
 
@@ -99,6 +104,68 @@ func validateVanillaCreateSnapshotRequestRequest(ctx context.Context, req *csi.C
 	return nil
 }
 
+// validateVanillaListVolumesRequest is the helper function to validate
+// ListVolumesRequest for Vanilla CSI driver. Function returns the offset of
+// the starting token, or an error if validation fails.
+func validateVanillaListVolumesRequest(ctx context.Context, req *csi.ListVolumesRequest) (int64, error) {
+	log := logger.GetLogger(ctx)
+	if req.MaxEntries < 0 {
+		return 0, logger.LogNewErrorCodef(log, codes.InvalidArgument,
+			"ListVolumes MaxEntries: %d cannot be negative", req.MaxEntries)
+	}
+	if req.StartingToken == "" {
+		return 0, nil
+	}
+	offset, err := strconv.ParseInt(req.StartingToken, 10, 64)
+	if err != nil || offset < 0 {
+		return 0, logger.LogNewErrorCodef(log, codes.Aborted,
+			"ListVolumes StartingToken: %s cannot be parsed", req.StartingToken)
+	}
+	return offset, nil
+}
+
+// getCSIVolume returns the CSI volume of the given CNS volume.
+func getCSIVolume(volume cnstypes.CnsVolume) *csi.Volume {
+	var capacityInMb int64
+	if volume.BackingObjectDetails != nil {
+		capacityInMb = volume.BackingObjectDetails.GetCnsBackingObjectDetails().CapacityInMb
+	}
+	return &csi.Volume{
+		VolumeId:      volume.VolumeId.Id,
+		CapacityBytes: capacityInMb * common.MbInBytes,
+	}
+}
+
+// getVolumeCondition returns the condition of the given CNS volume, derived
+// from its health status. The volume is abnormal if it's inaccessible.
+func getVolumeCondition(ctx context.Context, volume cnstypes.CnsVolume) *csi.VolumeCondition {
+	if volume.VolumeType == common.FileVolumeType && volume.HealthStatus == "" {
+		// The health status of file volumes may not be set, which doesn't
+		// mean they're inaccessible.
+		return &csi.VolumeCondition{
+			Message: "health status of the volume is unknown",
+		}
+	}
+	// ConvertVolumeHealthStatus never fails.
+	healthStatus, _ := common.ConvertVolumeHealthStatus(ctx, volume.VolumeId.Id, volume.HealthStatus)
+	switch healthStatus {
+	case common.VolHealthStatusInaccessible:
+		return &csi.VolumeCondition{
+			Abnormal: true,
+			Message: fmt.Sprintf("volume is inaccessible, its health status in CNS is %q",
+				volume.HealthStatus),
+		}
+	case common.VolHealthStatusAccessible:
+		return &csi.VolumeCondition{
+			Message: "volume is accessible",
+		}
+	default:
+		return &csi.VolumeCondition{
+			Message: "health status of the volume is unknown",
+		}
+	}
+}
+
 func validateVanillaListSnapshotRequest(ctx context.Context, req *csi.ListSnapshotsRequest) error {
 	log := logger.GetLogger(ctx)
 	maxEntries := req.MaxEntries
@@ -126,3 +193,113 @@ func validateVanillaListSnapshotRequest(ctx context.Context, req *csi.ListSnapsh
 	}
 	return nil
 }
+
+// getDetachQuiesceDelay returns the detach quiesce delay of the given volume,
+// set in the volume attributes of its PV. Volumes whose StorageClass does not
+// set it fall back to the delay set in the config. The delay is capped at
+// common.MaxDetachQuiesceDelay so that detach completes before the
+// csi-attacher times out.
+func (c *controller) getDetachQuiesceDelay(ctx context.Context, volumeID string) time.Duration {
+	log := logger.GetLogger(ctx)
+	var delay time.Duration
+	attributes, err := commonco.ContainerOrchestratorUtility.GetVolumeAttributes(ctx, volumeID)
+	if err != nil && err != common.ErrNotFound {
+		log.Warnf("failed to get the volume attributes of volume %q. Using the detach quiesce delay of "+
+			"the config. err: %v", volumeID, err)
+	}
+	if value, ok := attributes[common.AttributeDetachQuiesceDelay]; ok {
+		if delay, err = common.ParseDetachQuiesceDelay(value); err != nil {
+			log.Warnf("ignoring detach quiesce delay of volume %q. err: %v", volumeID, err)
+		}
+	} else if c.manager.CnsConfig != nil && c.manager.CnsConfig.Global.DetachQuiesceDelayInSec > 0 {
+		delay = time.Duration(c.manager.CnsConfig.Global.DetachQuiesceDelayInSec) * time.Second
+	}
+	if delay > common.MaxDetachQuiesceDelay {
+		log.Warnf("detach quiesce delay %v of volume %q exceeds the maximum of %v. Using the maximum",
+			delay, volumeID, common.MaxDetachQuiesceDelay)
+		delay = common.MaxDetachQuiesceDelay
+	}
+	return delay
+}
+
+// quiesceBeforeDetach waits for the detach quiesce delay of the given volume,
+// giving the node time to flush outstanding IO before the disk is detached.
+// The delay is skipped if the node VM is not powered on, as no IO can be
+// pending then.
+func (c *controller) quiesceBeforeDetach(ctx context.Context, vm *cnsvsphere.VirtualMachine,
+	volumeID string) error {
+	log := logger.GetLogger(ctx)
+	delay := c.getDetachQuiesceDelay(ctx, volumeID)
+	if delay <= 0 {
+		return nil
+	}
+	active, err := vm.IsActive(ctx)
+	if err != nil {
+		log.Warnf("failed to get power state of VM %v. Enforcing detach quiesce delay. err: %v", vm, err)
+	} else if !active {
+		log.Infof("VM %v is not powered on. Skipping detach quiesce delay for volume %q", vm, volumeID)
+		return nil
+	}
+	log.Infof("Waiting %v for volume %q to quiesce on VM %v before detach", delay, volumeID, vm)
+	select {
+	case <-time.After(delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// isNodeOutOfService returns true if the node with the given node ID has the
+// out-of-service taint of a non-graceful node shutdown.
+func (c *controller) isNodeOutOfService(ctx context.Context, nodeID string) bool {
+	log := logger.GetLogger(ctx)
+	nodeName := nodeID
+	if commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.UseCSINodeId) {
+		var err error
+		if nodeName, err = c.nodeMgr.GetNodeNameByUUID(ctx, nodeID); err != nil {
+			log.Warnf("failed to get the name of node %q. Assuming it is in service. err: %v", nodeID, err)
+			return false
+		}
+	}
+	outOfService, err := commonco.ContainerOrchestratorUtility.IsNodeOutOfService(ctx, nodeName)
+	if err != nil {
+		log.Warnf("failed to check if node %q is out of service. Assuming it is in service. err: %v", nodeName, err)
+		return false
+	}
+	return outOfService
+}
+
+// detachFromOutOfServiceNode detaches the given volume from the VM of the
+// given out-of-service node right away, without waiting for the detach
+// quiesce delay nor batching the detach. The volume is considered detached if
+// the VM was deleted.
+func (c *controller) detachFromOutOfServiceNode(ctx context.Context, nodeID string, volumeID string) (
+	string, error) {
+	log := logger.GetLogger(ctx)
+	var vm *cnsvsphere.VirtualMachine
+	var err error
+	if commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.UseCSINodeId) {
+		vm, err = c.nodeMgr.GetNodeByUuid(ctx, nodeID)
+	} else {
+		vm, err = c.nodeMgr.GetNodeByName(ctx, nodeID)
+	}
+	if err != nil {
+		if err == node.ErrNodeNotFound || err == cnsvsphere.ErrVMNotFound {
+			log.Infof("VM of out-of-service node %q not found. Considering volume %q detached.", nodeID, volumeID)
+			return "", nil
+		}
+		return csifault.CSIInternalFault, fmt.Errorf("failed to find VirtualMachine for node:%q. Error: %v",
+			nodeID, err)
+	}
+	log.Infof("Node %q is out of service. Force detaching volume %q from VM %v.", nodeID, volumeID, vm)
+	faultType, err := common.DetachVolumeUtil(ctx, c.manager, vm, volumeID)
+	if err != nil {
+		if _, stateErr := vm.GetPowerState(ctx); cnsvsphere.IsManagedObjectNotFound(stateErr, vm.Reference()) {
+			log.Infof("VM %v of out-of-service node %q was deleted. Considering volume %q detached.",
+				vm, nodeID, volumeID)
+			return "", nil
+		}
+		return faultType, err
+	}
+	return "", nil
+}
@@ -38,6 +38,7 @@ import (
 	"github.com/vmware/govmomi/find"
 	"github.com/vmware/govmomi/object"
 	pbmsim "github.com/vmware/govmomi/pbm/simulator"
+	pbmtypes "github.com/vmware/govmomi/pbm/types"
 	"github.com/vmware/govmomi/property"
 	"github.com/vmware/govmomi/simulator"
 	"github.com/vmware/govmomi/vapi/tags"
@@ -2142,3 +2143,185 @@ func TestDeleteBlockVolumeSnapshotWithManagedObjectNotFound(t *testing.T) {
 		t.Fatalf("Unexpected error is thrown in DeleteSnapshot with error: %v", err)
 	}
 }
+
+func TestGetCapacity(t *testing.T) {
+	ct := getControllerTest(t)
+	sharedDatastores, err := ct.controller.nodeMgr.GetSharedDatastoresInK8SCluster(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	freeSpace := sharedDatastores[0].Info.FreeSpace
+	blockCapabilities := []*csi.VolumeCapability{
+		{
+			AccessMode: &csi.VolumeCapability_AccessMode{
+				Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+			},
+		},
+	}
+
+	// Capacity of the datastores shared by all the nodes.
+	resp, err := ct.controller.GetCapacity(ctx, &csi.GetCapacityRequest{VolumeCapabilities: blockCapabilities})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.AvailableCapacity != freeSpace || resp.GetMaximumVolumeSize().GetValue() != freeSpace {
+		t.Errorf("expected available capacity and maximum volume size %d, got %+v", freeSpace, resp)
+	}
+
+	// Capacity of a datastore not shared by the nodes.
+	resp, err = ct.controller.GetCapacity(ctx, &csi.GetCapacityRequest{
+		VolumeCapabilities: blockCapabilities,
+		Parameters:         map[string]string{common.AttributeDatastoreURL: "ds:///vmfs/volumes/not-shared/"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.AvailableCapacity != 0 {
+		t.Errorf("expected no available capacity, got %+v", resp)
+	}
+
+	// Invalid storage class parameters.
+	_, err = ct.controller.GetCapacity(ctx, &csi.GetCapacityRequest{
+		Parameters: map[string]string{"unknown": "value"},
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument error, got %v", err)
+	}
+}
+
+func TestListVolumes(t *testing.T) {
+	ct := getControllerTest(t)
+	params := make(map[string]string)
+	if v := os.Getenv("VSPHERE_DATASTORE_URL"); v != "" {
+		params[common.AttributeDatastoreURL] = v
+	}
+	reqCreate := &csi.CreateVolumeRequest{
+		Name: testVolumeName + "-" + uuid.New().String(),
+		CapacityRange: &csi.CapacityRange{
+			RequiredBytes: 1 * common.GbInBytes,
+		},
+		Parameters: params,
+		VolumeCapabilities: []*csi.VolumeCapability{
+			{
+				AccessMode: &csi.VolumeCapability_AccessMode{
+					Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+				},
+			},
+		},
+	}
+	respCreate, err := ct.controller.CreateVolume(ctx, reqCreate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	volID := respCreate.Volume.VolumeId
+	defer func() {
+		if _, err := ct.controller.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: volID}); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	resp, err := ct.controller.ListVolumes(ctx, &csi.ListVolumesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entry *csi.ListVolumesResponse_Entry
+	for _, e := range resp.Entries {
+		if e.Volume.VolumeId == volID {
+			entry = e
+		}
+	}
+	if entry == nil {
+		t.Fatalf("volume %s not found in ListVolumes response %+v", volID, resp)
+	}
+	if entry.Volume.CapacityBytes != 1*common.GbInBytes {
+		t.Errorf("expected capacity %d for volume %s, got %d", 1*common.GbInBytes, volID,
+			entry.Volume.CapacityBytes)
+	}
+	// The volume isn't attached to any node.
+	if len(entry.Status.GetPublishedNodeIds()) != 0 {
+		t.Errorf("expected no published node for volume %s, got %v", volID, entry.Status.GetPublishedNodeIds())
+	}
+
+	// Invalid starting token.
+	_, err = ct.controller.ListVolumes(ctx, &csi.ListVolumesRequest{StartingToken: "invalid"})
+	if status.Code(err) != codes.Aborted {
+		t.Errorf("expected Aborted error, got %v", err)
+	}
+}
+
+func TestControllerGetVolume(t *testing.T) {
+	ct := getControllerTest(t)
+	params := make(map[string]string)
+	if v := os.Getenv("VSPHERE_DATASTORE_URL"); v != "" {
+		params[common.AttributeDatastoreURL] = v
+	}
+	reqCreate := &csi.CreateVolumeRequest{
+		Name: testVolumeName + "-" + uuid.New().String(),
+		CapacityRange: &csi.CapacityRange{
+			RequiredBytes: 1 * common.GbInBytes,
+		},
+		Parameters: params,
+		VolumeCapabilities: []*csi.VolumeCapability{
+			{
+				AccessMode: &csi.VolumeCapability_AccessMode{
+					Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+				},
+			},
+		},
+	}
+	respCreate, err := ct.controller.CreateVolume(ctx, reqCreate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	volID := respCreate.Volume.VolumeId
+	defer func() {
+		if _, err := ct.controller.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: volID}); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	resp, err := ct.controller.ControllerGetVolume(ctx, &csi.ControllerGetVolumeRequest{VolumeId: volID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Volume.VolumeId != volID || resp.Volume.CapacityBytes != 1*common.GbInBytes {
+		t.Errorf("expected volume %s with capacity %d, got %+v", volID, 1*common.GbInBytes, resp.Volume)
+	}
+	if resp.Status.GetVolumeCondition() == nil {
+		t.Errorf("expected a condition for volume %s, got %+v", volID, resp.Status)
+	}
+
+	_, err = ct.controller.ControllerGetVolume(ctx, &csi.ControllerGetVolumeRequest{VolumeId: uuid.New().String()})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound error, got %v", err)
+	}
+}
+
+func TestGetVolumeCondition(t *testing.T) {
+	tests := []struct {
+		volumeType       string
+		healthStatus     string
+		expectedAbnormal bool
+	}{
+		{volumeType: common.BlockVolumeType, healthStatus: string(pbmtypes.PbmHealthStatusForEntityGreen)},
+		{volumeType: common.BlockVolumeType, healthStatus: string(pbmtypes.PbmHealthStatusForEntityYellow)},
+		{volumeType: common.BlockVolumeType, healthStatus: string(pbmtypes.PbmHealthStatusForEntityUnknown)},
+		{volumeType: common.BlockVolumeType, healthStatus: string(pbmtypes.PbmHealthStatusForEntityRed),
+			expectedAbnormal: true},
+		// The health status isn't set for volumes which don't exist anymore.
+		{volumeType: common.BlockVolumeType, healthStatus: "", expectedAbnormal: true},
+		{volumeType: common.FileVolumeType, healthStatus: ""},
+	}
+	for _, test := range tests {
+		volume := cnstypes.CnsVolume{
+			VolumeId:     cnstypes.CnsVolumeId{Id: "volume-1"},
+			VolumeType:   test.volumeType,
+			HealthStatus: test.healthStatus,
+		}
+		condition := getVolumeCondition(ctx, volume)
+		if condition.Abnormal != test.expectedAbnormal {
+			t.Errorf("expected abnormal %t for %s volume with health status %q, got %+v",
+				test.expectedAbnormal, test.volumeType, test.healthStatus, condition)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vanilla
+
+import (
+	"context"
+	"time"
+
+	"github.com/vmware/govmomi/object"
+	vim25types "github.com/vmware/govmomi/vim25/types"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/prometheus"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+)
+
+// privilegeCheck is a set of privileges required by a feature on a set of
+// entities.
+type privilegeCheck struct {
+	feature    string
+	privileges []string
+	entities   []vim25types.ManagedObjectReference
+}
+
+// privilegeKey identifies a privilege required by a feature on an entity.
+type privilegeKey struct {
+	feature   string
+	privilege string
+	entity    string
+}
+
+// permissionsMonitor periodically checks the privileges of the VC user
+// required by the features in use, and reports the privileges revoked since
+// they were granted. Without it, revoked privileges only surface as faults of
+// the operations needing them.
+type permissionsMonitor struct {
+	// granted records whether each privilege was granted at the last check.
+	granted map[privilegeKey]bool
+}
+
+// newPermissionsMonitor returns a permissionsMonitor without any privilege
+// checked yet.
+func newPermissionsMonitor() *permissionsMonitor {
+	return &permissionsMonitor{
+		granted: make(map[privilegeKey]bool),
+	}
+}
+
+// runPermissionsMonitor checks the privileges of the VC user every
+// checkInterval minutes.
+func (c *controller) runPermissionsMonitor(checkInterval int) {
+	log := logger.GetLoggerWithNoContext()
+	log.Info("permissions monitor: runPermissionsMonitor enter")
+	monitor := newPermissionsMonitor()
+	ticker := time.NewTicker(time.Duration(checkInterval) * time.Minute)
+	for ; true; <-ticker.C {
+		ctx, log := logger.GetNewContextWithLogger()
+		granted, err := c.checkPrivileges(ctx)
+		if err != nil {
+			log.Errorf("permissions monitor: failed to check the privileges of the VC user. Err: %+v", err)
+			continue
+		}
+		monitor.update(ctx, granted)
+	}
+}
+
+// checkPrivileges returns whether the privileges required by the CNS queries,
+// the block volume provisioning and the volume attachments are granted to
+// the VC user on the root folder, the shared datastores and the node VMs.
+func (c *controller) checkPrivileges(ctx context.Context) (map[privilegeKey]bool, error) {
+	log := logger.GetLogger(ctx)
+	vc, err := common.GetVCenter(ctx, c.manager)
+	if err != nil {
+		return nil, err
+	}
+	checks := []privilegeCheck{
+		{
+			feature:    prometheus.PrometheusCnsFeature,
+			privileges: []string{common.CnsSearchablePriv, common.StorageProfileViewPriv},
+			entities:   []vim25types.ManagedObjectReference{vc.Client.ServiceContent.RootFolder},
+		},
+	}
+	sharedDatastores, err := c.nodeMgr.GetSharedDatastoresInK8SCluster(ctx)
+	if err != nil {
+		return nil, err
+	}
+	datastoreCheck := privilegeCheck{
+		feature:    prometheus.PrometheusBlockVolumeFeature,
+		privileges: []string{common.DsPriv, common.SysReadPriv},
+	}
+	for _, datastore := range sharedDatastores {
+		datastoreCheck.entities = append(datastoreCheck.entities, datastore.Datastore.Reference())
+	}
+	nodeVMs, err := c.nodeMgr.GetAllNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nodeVMCheck := privilegeCheck{
+		feature:    prometheus.PrometheusAttachFeature,
+		privileges: []string{common.VMAddExistingDiskPriv, common.VMAddRemoveDevicePriv},
+	}
+	for _, nodeVM := range nodeVMs {
+		nodeVMCheck.entities = append(nodeVMCheck.entities, nodeVM.Reference())
+	}
+	checks = append(checks, datastoreCheck, nodeVMCheck)
+
+	authMgr := object.NewAuthorizationManager(vc.Client.Client)
+	userName := vc.Config.Username
+	granted := make(map[privilegeKey]bool)
+	for _, check := range checks {
+		if len(check.entities) == 0 {
+			continue
+		}
+		result, err := authMgr.HasUserPrivilegeOnEntities(ctx, check.entities, userName, check.privileges)
+		if err != nil {
+			log.Errorf("permissions monitor: failed to check privileges %v on entities %v for user %s",
+				check.privileges, check.entities, userName)
+			return nil, err
+		}
+		for _, entityPriv := range result {
+			for _, privAvail := range entityPriv.PrivAvailability {
+				granted[privilegeKey{
+					feature:   check.feature,
+					privilege: privAvail.PrivId,
+					entity:    entityPriv.Entity.Value,
+				}] = privAvail.IsGranted
+			}
+		}
+	}
+	return granted, nil
+}
+
+// update records the privileges granted at the last check, updates the
+// missing privileges metric and returns the privileges revoked since the
+// previous check. Privileges never granted are reported as missing but not
+// as revoked.
+func (m *permissionsMonitor) update(ctx context.Context, granted map[privilegeKey]bool) []privilegeKey {
+	log := logger.GetLogger(ctx)
+	var revoked []privilegeKey
+	missing := make(map[privilegeKey]int)
+	for key, isGranted := range granted {
+		featurePrivilege := privilegeKey{feature: key.feature, privilege: key.privilege}
+		if isGranted {
+			missing[featurePrivilege] += 0
+			continue
+		}
+		missing[featurePrivilege]++
+		if m.granted[key] {
+			revoked = append(revoked, key)
+			prometheus.RevokedPrivilegesCounterVec.WithLabelValues(key.feature, key.privilege).Inc()
+			log.Errorf("permissions monitor: privilege %s required by feature %q was revoked from the VC user on %s",
+				key.privilege, key.feature, key.entity)
+		} else if _, checked := m.granted[key]; !checked {
+			log.Warnf("permissions monitor: privilege %s required by feature %q is not granted to the VC user on %s",
+				key.privilege, key.feature, key.entity)
+		}
+	}
+	for featurePrivilege, count := range missing {
+		prometheus.MissingPrivilegesGaugeVec.WithLabelValues(featurePrivilege.feature,
+			featurePrivilege.privilege).Set(float64(count))
+	}
+	m.granted = granted
+	return revoked
+}
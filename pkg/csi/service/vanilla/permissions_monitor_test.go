@@ -0,0 +1,73 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vanilla
+
+import (
+	"context"
+	"testing"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/prometheus"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+)
+
+func TestPermissionsMonitorUpdate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dsPriv := privilegeKey{feature: prometheus.PrometheusBlockVolumeFeature, privilege: common.DsPriv,
+		entity: "datastore-1"}
+	attachPriv := privilegeKey{feature: prometheus.PrometheusAttachFeature, privilege: common.VMAddExistingDiskPriv,
+		entity: "vm-1"}
+	newAttachPriv := privilegeKey{feature: prometheus.PrometheusAttachFeature,
+		privilege: common.VMAddExistingDiskPriv, entity: "vm-2"}
+
+	tests := []struct {
+		name    string
+		granted map[privilegeKey]bool
+		revoked []privilegeKey
+	}{
+		{
+			name:    "first check",
+			granted: map[privilegeKey]bool{dsPriv: true, attachPriv: false},
+		},
+		{
+			name:    "privilege revoked",
+			granted: map[privilegeKey]bool{dsPriv: false, attachPriv: false},
+			revoked: []privilegeKey{dsPriv},
+		},
+		{
+			name:    "privilege still revoked",
+			granted: map[privilegeKey]bool{dsPriv: false, attachPriv: false},
+		},
+		{
+			name:    "new entity without privilege",
+			granted: map[privilegeKey]bool{dsPriv: true, attachPriv: true, newAttachPriv: false},
+		},
+	}
+	monitor := newPermissionsMonitor()
+	for _, test := range tests {
+		revoked := monitor.update(ctx, test.granted)
+		if len(revoked) != len(test.revoked) {
+			t.Fatalf("%s: expected revoked privileges %v, got %v", test.name, test.revoked, revoked)
+		}
+		for i := range revoked {
+			if revoked[i] != test.revoked[i] {
+				t.Fatalf("%s: expected revoked privileges %v, got %v", test.name, test.revoked, revoked)
+			}
+		}
+	}
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/osutils"
+)
+
+const (
+	// envVolumeRecoveryInterval is the env variable holding the interval, in
+	// seconds, between reconciliations of the staged volumes.
+	envVolumeRecoveryInterval = "NODE_VOLUME_RECOVERY_INTERVAL_SECONDS"
+	// defaultVolumeRecoveryInterval is the default interval between
+	// reconciliations of the staged volumes.
+	defaultVolumeRecoveryInterval = 30 * time.Second
+	// event reason for pods using a volume which was remounted after its
+	// device became accessible again
+	reasonVolumeRecovered = "VolumeRecovered"
+	// event reason for pods using a volume which could not be remounted
+	reasonVolumeRecoveryFailed = "VolumeRecoveryFailed"
+)
+
+// stagedVolumeRecoverer are the node operations used by the
+// volumeRecoveryReconciler, implemented by osutils.OsUtils.
+type stagedVolumeRecoverer interface {
+	GetStagedVolumeMounts(ctx context.Context) ([]osutils.StagedVolumeMount, error)
+	IsStagedVolumeStale(stagingPath string) bool
+	GetVolumeCondition(ctx context.Context, target string) (string, error)
+	RemountStagedVolume(ctx context.Context, stagingPath string) error
+}
+
+// volumeRecoveryReconciler periodically re-validates the block volumes staged
+// on the node, to recover them once their device is accessible again after a
+// storage outage, e.g. an all paths down condition of the ESX host. Staged
+// file systems which went stale, or were remounted read-only on I/O errors
+// since they were first seen read-write, are remounted read-write as soon as
+// their device is reported running again, so that the pods using them don't
+// need to be restarted. Recoveries and failed recoveries are reported as
+// events on the pods using the volumes.
+type volumeRecoveryReconciler struct {
+	recoverer stagedVolumeRecoverer
+	events    *nodePodEventRecorder
+	// readWrite holds the staging paths seen mounted read-write.
+	readWrite map[string]bool
+	// failures holds the last recovery error of the staging paths, so that
+	// failed recoveries are reported once per error.
+	failures map[string]string
+}
+
+// newVolumeRecoveryReconciler returns a volumeRecoveryReconciler recovering
+// the staged volumes through the given OsUtils.
+func newVolumeRecoveryReconciler(ctx context.Context, osUtils *osutils.OsUtils) *volumeRecoveryReconciler {
+	return &volumeRecoveryReconciler{
+		recoverer: osUtils,
+		events:    newNodePodEventRecorder(ctx, "VolumeRecovery"),
+		readWrite: make(map[string]bool),
+		failures:  make(map[string]string),
+	}
+}
+
+// getVolumeRecoveryInterval returns the interval between reconciliations of
+// the staged volumes.
+func getVolumeRecoveryInterval(ctx context.Context) time.Duration {
+	log := logger.GetLogger(ctx)
+	if v := os.Getenv(envVolumeRecoveryInterval); v != "" {
+		if value, err := strconv.Atoi(v); err == nil && value > 0 {
+			return time.Duration(value) * time.Second
+		}
+		log.Warnf("%s set in env variable %q is not a positive number of seconds. Using default %v",
+			envVolumeRecoveryInterval, v, defaultVolumeRecoveryInterval)
+	}
+	return defaultVolumeRecoveryInterval
+}
+
+// run reconciles the staged volumes at the given interval until the context
+// is done.
+func (r *volumeRecoveryReconciler) run(ctx context.Context, interval time.Duration) {
+	log := logger.GetLogger(ctx)
+	log.Infof("VolumeRecovery: reconciling staged volumes every %v", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		r.reconcile(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reconcile remounts the stale staged volumes whose device is accessible
+// again and reports the outcome.
+func (r *volumeRecoveryReconciler) reconcile(ctx context.Context) {
+	log := logger.GetLogger(ctx)
+	mounts, err := r.recoverer.GetStagedVolumeMounts(ctx)
+	if err != nil {
+		log.Errorf("VolumeRecovery: failed to get staged volumes. Err: %v", err)
+		return
+	}
+	var events []podEvent
+	staged := make(map[string]bool)
+	for _, mount := range mounts {
+		staged[mount.StagingPath] = true
+		stale := r.recoverer.IsStagedVolumeStale(mount.StagingPath)
+		if !mount.ReadOnly && !stale {
+			r.readWrite[mount.StagingPath] = true
+			delete(r.failures, mount.StagingPath)
+			continue
+		}
+		// Volumes staged read-only are left alone.
+		if mount.ReadOnly && !r.readWrite[mount.StagingPath] {
+			continue
+		}
+		condition, err := r.recoverer.GetVolumeCondition(ctx, mount.StagingPath)
+		if err != nil {
+			log.Errorf("VolumeRecovery: failed to get the condition of the volume staged at %q. Err: %v",
+				mount.StagingPath, err)
+			continue
+		}
+		if condition != "" {
+			log.Debugf("VolumeRecovery: volume staged at %q is not recoverable yet: %s", mount.StagingPath, condition)
+			continue
+		}
+		if err := r.recoverer.RemountStagedVolume(ctx, mount.StagingPath); err != nil {
+			log.Errorf("VolumeRecovery: failed to recover the volume staged at %q. Err: %v", mount.StagingPath, err)
+			if r.failures[mount.StagingPath] != err.Error() {
+				r.failures[mount.StagingPath] = err.Error()
+				events = append(events, podEvent{
+					podUIDs:   mount.PodUIDs,
+					eventType: v1.EventTypeWarning,
+					reason:    reasonVolumeRecoveryFailed,
+					message: fmt.Sprintf("Failed to remount volume staged at %s after its device "+
+						"recovered, the pod may need to be restarted: %v", mount.StagingPath, err),
+				})
+			}
+			continue
+		}
+		log.Infof("VolumeRecovery: recovered the volume staged at %q", mount.StagingPath)
+		r.readWrite[mount.StagingPath] = true
+		delete(r.failures, mount.StagingPath)
+		events = append(events, podEvent{
+			podUIDs:   mount.PodUIDs,
+			eventType: v1.EventTypeNormal,
+			reason:    reasonVolumeRecovered,
+			message:   fmt.Sprintf("Volume staged at %s was remounted after its device recovered", mount.StagingPath),
+		})
+	}
+	for stagingPath := range r.readWrite {
+		if !staged[stagingPath] {
+			delete(r.readWrite, stagingPath)
+			delete(r.failures, stagingPath)
+		}
+	}
+	r.events.emit(ctx, events)
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/osutils"
+)
+
+type fakeStagedVolumeRecoverer struct {
+	mounts      []osutils.StagedVolumeMount
+	stale       map[string]bool
+	conditions  map[string]string
+	remountErr  error
+	remountedAt []string
+}
+
+func (f *fakeStagedVolumeRecoverer) GetStagedVolumeMounts(ctx context.Context) ([]osutils.StagedVolumeMount,
+	error) {
+	return f.mounts, nil
+}
+
+func (f *fakeStagedVolumeRecoverer) IsStagedVolumeStale(stagingPath string) bool {
+	return f.stale[stagingPath]
+}
+
+func (f *fakeStagedVolumeRecoverer) GetVolumeCondition(ctx context.Context, target string) (string, error) {
+	return f.conditions[target], nil
+}
+
+func (f *fakeStagedVolumeRecoverer) RemountStagedVolume(ctx context.Context, stagingPath string) error {
+	if f.remountErr != nil {
+		return f.remountErr
+	}
+	f.remountedAt = append(f.remountedAt, stagingPath)
+	for i := range f.mounts {
+		if f.mounts[i].StagingPath == stagingPath {
+			f.mounts[i].ReadOnly = false
+		}
+	}
+	delete(f.stale, stagingPath)
+	return nil
+}
+
+func TestVolumeRecoveryReconcile(t *testing.T) {
+	ctx := context.Background()
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Namespace: "default", UID: "uid-1"},
+		Spec:       v1.PodSpec{NodeName: "node-1"},
+	}
+	recorder := record.NewFakeRecorder(10)
+	recoverer := &fakeStagedVolumeRecoverer{
+		mounts: []osutils.StagedVolumeMount{
+			{StagingPath: "/stage/rw", Device: "/dev/sdb", PodUIDs: []string{"uid-1"}},
+			{StagingPath: "/stage/ro", Device: "/dev/sdc", ReadOnly: true, PodUIDs: []string{"uid-1"}},
+		},
+		stale:      make(map[string]bool),
+		conditions: make(map[string]string),
+	}
+	reconciler := &volumeRecoveryReconciler{
+		recoverer: recoverer,
+		events: &nodePodEventRecorder{
+			k8sClient: testclient.NewSimpleClientset(pod),
+			recorder:  recorder,
+			nodeName:  "node-1",
+		},
+		readWrite: make(map[string]bool),
+		failures:  make(map[string]string),
+	}
+
+	// Healthy volumes, and volumes staged read-only, are left alone.
+	reconciler.reconcile(ctx)
+	if len(recoverer.remountedAt) != 0 || len(recorder.Events) != 0 {
+		t.Fatalf("expected no recovery, got remounts %v and %d events", recoverer.remountedAt, len(recorder.Events))
+	}
+
+	// The volume is remounted read-only while its device is lost.
+	recoverer.mounts[0].ReadOnly = true
+	recoverer.conditions["/stage/rw"] = "device /dev/sdb of the volume is in state \"offline\""
+	reconciler.reconcile(ctx)
+	if len(recoverer.remountedAt) != 0 {
+		t.Fatalf("expected no recovery while the device is lost, got remounts %v", recoverer.remountedAt)
+	}
+
+	// A failed recovery is reported once.
+	delete(recoverer.conditions, "/stage/rw")
+	recoverer.remountErr = errors.New("mount failed")
+	reconciler.reconcile(ctx)
+	reconciler.reconcile(ctx)
+	if len(recorder.Events) != 1 {
+		t.Fatalf("expected one event, got %d", len(recorder.Events))
+	}
+	if event := <-recorder.Events; !strings.Contains(event, reasonVolumeRecoveryFailed) {
+		t.Errorf("unexpected event %q", event)
+	}
+
+	// The volume is remounted once the device is back.
+	recoverer.remountErr = nil
+	reconciler.reconcile(ctx)
+	if len(recoverer.remountedAt) != 1 || recoverer.remountedAt[0] != "/stage/rw" {
+		t.Fatalf("expected /stage/rw to be remounted, got remounts %v", recoverer.remountedAt)
+	}
+	if event := <-recorder.Events; !strings.Contains(event, reasonVolumeRecovered) {
+		t.Errorf("unexpected event %q", event)
+	}
+
+	// Stale volumes are remounted too.
+	recoverer.stale["/stage/rw"] = true
+	reconciler.reconcile(ctx)
+	if len(recoverer.remountedAt) != 2 {
+		t.Fatalf("expected the stale volume to be remounted, got remounts %v", recoverer.remountedAt)
+	}
+}
@@ -58,6 +58,11 @@ var (
 		csi.ControllerServiceCapability_RPC_PUBLISH_UNPUBLISH_VOLUME,
 		csi.ControllerServiceCapability_RPC_EXPAND_VOLUME,
 	}
+	// controllerCapsFeatureGates maps controller capabilities to the feature
+	// states which need to be enabled for them to be reported.
+	controllerCapsFeatureGates = map[csi.ControllerServiceCapability_RPC_Type]string{
+		csi.ControllerServiceCapability_RPC_EXPAND_VOLUME: common.VolumeExtend,
+	}
 )
 
 var getCandidateDatastores = cnsvsphere.GetCandidateDatastoresInCluster
@@ -290,7 +295,7 @@ func (c *controller) ReloadConfiguration(reconnectToVCFromNewConfig bool) error
 			// vCenter. Proceed only if the connection succeeds, else return error.
 			newVC := &cnsvsphere.VirtualCenter{Config: newVCConfig}
 			if err = newVC.Connect(ctx); err != nil {
-				return logger.LogNewErrorf(log, "failed to connect to VirtualCenter host: %q, Err: %+v",
+				return logger.LogNewErrorf(log, "failed to connect to VirtualCenter host: %q, Err: %w",
 					newVCConfig.Host, err)
 			}
 
@@ -299,14 +304,14 @@ func (c *controller) ReloadConfiguration(reconnectToVCFromNewConfig bool) error
 			log.Info("Obtaining new vCenterInstance")
 			vcenter, err = cnsvsphere.GetVirtualCenterInstance(ctx, &cnsconfig.ConfigurationInfo{Cfg: cfg}, true)
 			if err != nil {
-				return logger.LogNewErrorf(log, "failed to get VirtualCenter. err=%v", err)
+				return logger.LogNewErrorf(log, "failed to get VirtualCenter. err=%w", err)
 			}
 		} else {
 			// If it's not a VC host or VC credentials update, same singleton
 			// instance can be used and it's Config field can be updated.
 			vcenter, err = cnsvsphere.GetVirtualCenterInstance(ctx, &cnsconfig.ConfigurationInfo{Cfg: cfg}, false)
 			if err != nil {
-				return logger.LogNewErrorf(log, "failed to get VirtualCenter. err=%v", err)
+				return logger.LogNewErrorf(log, "failed to get VirtualCenter. err=%w", err)
 			}
 			vcenter.Config = newVCConfig
 		}
@@ -508,6 +513,7 @@ func (c *controller) createBlockVolume(ctx context.Context, req *csi.CreateVolum
 	// CreateVolume response.
 	attributes := make(map[string]string)
 	attributes[common.AttributeDiskType] = common.DiskTypeBlockVolume
+	common.SetMountOptionsAttribute(req.GetVolumeCapabilities(), attributes)
 	resp := &csi.CreateVolumeResponse{
 		Volume: &csi.Volume{
 			VolumeId:      volumeInfo.VolumeID.Id,
@@ -636,6 +642,7 @@ func (c *controller) createFileVolume(ctx context.Context, req *csi.CreateVolume
 
 	attributes := make(map[string]string)
 	attributes[common.AttributeDiskType] = common.DiskTypeFileVolume
+	common.SetMountOptionsAttribute(req.GetVolumeCapabilities(), attributes)
 
 	resp := &csi.CreateVolumeResponse{
 		Volume: &csi.Volume{
@@ -1001,17 +1008,8 @@ func (c *controller) ControllerGetCapabilities(ctx context.Context, req *csi.Con
 	ctx = logger.NewContextWithLogger(ctx)
 	log := logger.GetLogger(ctx)
 	log.Infof("ControllerGetCapabilities: called with args %+v", *req)
-	var caps []*csi.ControllerServiceCapability
-	for _, cap := range controllerCaps {
-		c := &csi.ControllerServiceCapability{
-			Type: &csi.ControllerServiceCapability_Rpc{
-				Rpc: &csi.ControllerServiceCapability_RPC{
-					Type: cap,
-				},
-			},
-		}
-		caps = append(caps, c)
-	}
+	caps := common.GetControllerServiceCapabilities(ctx, controllerCaps, controllerCapsFeatureGates,
+		commonco.ContainerOrchestratorUtility.IsFSSEnabled)
 	return &csi.ControllerGetCapabilitiesResponse{Capabilities: caps}, nil
 }
 
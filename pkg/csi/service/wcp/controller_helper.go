@@ -340,7 +340,7 @@ func getVMByInstanceUUIDInDatacenter(ctx context.Context,
 	// Get VM by UUID from datacenter.
 	vm, err := dc.GetVirtualMachineByUUID(ctx, vmInstanceUUID, true)
 	if err != nil {
-		return nil, fmt.Errorf("failed to the VM from the VM Instance UUID: %s in datacenter: %+v with err: %+v",
+		return nil, fmt.Errorf("failed to the VM from the VM Instance UUID: %s in datacenter: %+v with err: %w",
 			vmInstanceUUID, dc, err)
 	}
 	return vm, nil
@@ -352,20 +352,20 @@ func getDatastoreURLFromStoragePool(ctx context.Context, spName string) (string,
 	// Get a config to talk to the apiserver.
 	cfg, err := config.GetConfig()
 	if err != nil {
-		return "", fmt.Errorf("failed to get Kubernetes config. Err: %+v", err)
+		return "", fmt.Errorf("failed to get Kubernetes config. Err: %w", err)
 	}
 
 	// create a new StoragePool client.
 	spclient, err := dynamic.NewForConfig(cfg)
 	if err != nil {
-		return "", fmt.Errorf("failed to create StoragePool client using config. Err: %+v", err)
+		return "", fmt.Errorf("failed to create StoragePool client using config. Err: %w", err)
 	}
 	spResource := spv1alpha1.SchemeGroupVersion.WithResource("storagepools")
 
 	// Get StoragePool with spName.
 	sp, err := spclient.Resource(spResource).Get(ctx, spName, metav1.GetOptions{})
 	if err != nil {
-		return "", fmt.Errorf("failed to get StoragePool with name %s: %+v", spName, err)
+		return "", fmt.Errorf("failed to get StoragePool with name %s: %w", spName, err)
 	}
 
 	// extract the datastoreUrl field.
@@ -382,20 +382,20 @@ func getStoragePoolInfo(ctx context.Context, spName string) ([]string, string, e
 	// Get a config to talk to the apiserver.
 	cfg, err := config.GetConfig()
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to get Kubernetes config. Err: %+v", err)
+		return nil, "", fmt.Errorf("failed to get Kubernetes config. Err: %w", err)
 	}
 
 	// Create a new StoragePool client.
 	spClient, err := dynamic.NewForConfig(cfg)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to create StoragePool client using config. Err: %+v", err)
+		return nil, "", fmt.Errorf("failed to create StoragePool client using config. Err: %w", err)
 	}
 	spResource := spv1alpha1.SchemeGroupVersion.WithResource("storagepools")
 
 	// Get StoragePool with spName.
 	sp, err := spClient.Resource(spResource).Get(ctx, spName, metav1.GetOptions{})
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to get StoragePool with name %s: %+v", spName, err)
+		return nil, "", fmt.Errorf("failed to get StoragePool with name %s: %w", spName, err)
 	}
 
 	// Extract the accessibleNodes field.
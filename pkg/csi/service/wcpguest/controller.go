@@ -63,11 +63,19 @@ var (
 		csi.ControllerServiceCapability_RPC_PUBLISH_UNPUBLISH_VOLUME,
 		csi.ControllerServiceCapability_RPC_EXPAND_VOLUME,
 	}
+	// controllerCapsFeatureGates maps controller capabilities to the feature
+	// states which need to be enabled for them to be reported.
+	controllerCapsFeatureGates = map[csi.ControllerServiceCapability_RPC_Type]string{
+		csi.ControllerServiceCapability_RPC_EXPAND_VOLUME: common.VolumeExtend,
+	}
 	// virtualMachineLock is used for handling race conditions during concurrent Attach/Detach calls
 	virtualMachineLock = &sync.Mutex{}
 )
 
 type controller struct {
+	// guestClient is the client of the guest cluster, used to resolve the
+	// VirtualMachines backing the guest nodes.
+	guestClient               clientset.Interface
 	supervisorClient          clientset.Interface
 	restClientConfig          *rest.Config
 	vmOperatorClient          client.Client
@@ -93,6 +101,11 @@ func (c *controller) Init(config *cnsconfig.Config, version string) error {
 		return err
 	}
 	c.tanzukubernetesClusterUID = config.GC.TanzuKubernetesClusterUID
+	c.guestClient, err = k8s.NewClient(ctx)
+	if err != nil {
+		log.Errorf("failed to create guest cluster client. Error: %+v", err)
+		return err
+	}
 	c.restClientConfig = k8s.GetRestClientConfigForSupervisor(ctx, config.GC.Endpoint, config.GC.Port)
 	c.supervisorClient, err = k8s.NewSupervisorClient(ctx, c.restClientConfig)
 	if err != nil {
@@ -292,6 +305,14 @@ func (c *controller) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequ
 					}
 					annotations[common.AnnGuestClusterRequestedTopology] = topologyAnnotation
 				}
+				if supervisorStorageClass != "" &&
+					commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.SupervisorQuotaValidation) {
+					err := validateSupervisorNamespaceQuota(ctx, c.supervisorClient, c.supervisorNamespace,
+						supervisorStorageClass, volSizeMB*common.MbInBytes)
+					if err != nil {
+						return nil, csifault.CSIResourceExhaustedFault, err
+					}
+				}
 				claim := getPersistentVolumeClaimSpecWithStorageClass(supervisorPVCName, c.supervisorNamespace,
 					diskSize, supervisorStorageClass, getAccessMode(accessMode), annotations)
 				log.Debugf("PVC claim spec is %+v", spew.Sdump(claim))
@@ -333,6 +354,7 @@ func (c *controller) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequ
 		} else {
 			attributes[common.AttributeDiskType] = common.DiskTypeBlockVolume
 		}
+		common.SetMountOptionsAttribute(req.GetVolumeCapabilities(), attributes)
 		var accessibleTopology []*csi.Topology
 		if !isFileVolumeRequest && commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.TKGsHA) &&
 			req.AccessibilityRequirements != nil {
@@ -513,14 +535,8 @@ func (c *controller) ControllerPublishVolume(ctx context.Context, req *csi.Contr
 func controllerPublishForBlockVolume(ctx context.Context, req *csi.ControllerPublishVolumeRequest, c *controller) (
 	*csi.ControllerPublishVolumeResponse, string, error) {
 	log := logger.GetLogger(ctx)
-	virtualMachine := &vmoperatortypes.VirtualMachine{}
-	vmKey := types.NamespacedName{
-		Namespace: c.supervisorNamespace,
-		Name:      req.NodeId,
-	}
-
-	var err error
-	if err = c.vmOperatorClient.Get(ctx, vmKey, virtualMachine); err != nil {
+	virtualMachine, err := c.getVirtualMachineForNode(ctx, req.NodeId)
+	if err != nil {
 		msg := fmt.Sprintf("failed to get VirtualMachines for the node: %q. Error: %+v", req.NodeId, err)
 		log.Error(msg)
 		return nil, csifault.CSIInternalFault, status.Errorf(codes.Internal, msg)
@@ -567,7 +583,7 @@ func controllerPublishForBlockVolume(ctx context.Context, req *csi.ControllerPub
 			if err == nil || time.Now().After(timeout) {
 				break
 			}
-			if err := c.vmOperatorClient.Get(ctx, vmKey, virtualMachine); err != nil {
+			if err := c.refreshVirtualMachine(ctx, virtualMachine); err != nil {
 				msg := fmt.Sprintf("failed to get VirtualMachines for the node: %q. Error: %+v", req.NodeId, err)
 				log.Error(msg)
 				return nil, csifault.CSIInternalFault, status.Errorf(codes.Internal, msg)
@@ -611,6 +627,11 @@ func controllerPublishForBlockVolume(ctx context.Context, req *csi.ControllerPub
 					vm.Name, virtualMachine.Name, req.VolumeId)
 				continue
 			}
+			if vm.UID != virtualMachine.UID {
+				log.Debugf("Observed vm UID: %q, expecting vm UID: %q, volumeID: %q",
+					vm.UID, virtualMachine.UID, req.VolumeId)
+				continue
+			}
 			log.Debugf("observed update on virtualmachine: %q. checking if disk UUID is set for volume: %q ",
 				virtualMachine.Name, req.VolumeId)
 			for _, volume := range vm.Status.Volumes {
@@ -704,9 +725,11 @@ func controllerPublishForFileVolume(ctx context.Context, req *csi.ControllerPubl
 	// Verify if the CnsFileAccessConfig instance has status with done set to true and error is empty
 	if cnsFileAccessConfigInstance.Status.Done && cnsFileAccessConfigInstance.Status.Error == "" {
 		for key, value := range cnsFileAccessConfigInstance.Status.AccessPoints {
-			if key == common.Nfsv4AccessPointKey {
+			switch key {
+			case common.Nfsv4AccessPointKey:
 				publishInfo[common.Nfsv4AccessPoint] = value
-				break
+			case common.Nfsv3AccessPointKey:
+				publishInfo[common.Nfsv3AccessPoint] = value
 			}
 		}
 		publishInfo[common.AttributeDiskType] = common.DiskTypeFileVolume
@@ -769,10 +792,12 @@ func controllerPublishForFileVolume(ctx context.Context, req *csi.ControllerPubl
 			cnsfileaccessconfig.DeletionTimestamp == nil {
 			// Check if the updated instance has the AccessPoints
 			for key, value := range cnsfileaccessconfig.Status.AccessPoints {
-				if key == common.Nfsv4AccessPointKey {
+				switch key {
+				case common.Nfsv4AccessPointKey:
 					publishInfo[common.AttributeDiskType] = common.DiskTypeFileVolume
 					publishInfo[common.Nfsv4AccessPoint] = value
-					break
+				case common.Nfsv3AccessPointKey:
+					publishInfo[common.Nfsv3AccessPoint] = value
 				}
 			}
 			if _, ok := publishInfo[common.Nfsv4AccessPoint]; ok {
@@ -862,13 +887,8 @@ func controllerUnpublishForBlockVolume(ctx context.Context, req *csi.ControllerU
 
 	// TODO: Investigate if a race condition can exist here between multiple detach calls to the same volume.
 	// 	If yes, implement some locking mechanism
-	virtualMachine := &vmoperatortypes.VirtualMachine{}
-	vmKey := types.NamespacedName{
-		Namespace: c.supervisorNamespace,
-		Name:      req.NodeId,
-	}
-	var err error
-	if err := c.vmOperatorClient.Get(ctx, vmKey, virtualMachine); err != nil {
+	virtualMachine, err := c.getVirtualMachineForNode(ctx, req.NodeId)
+	if err != nil {
 		if errors.IsNotFound(err) {
 			log.Infof("VirtualMachine %s/%s not found. Assuming volume %s was detached.",
 				c.supervisorNamespace, req.NodeId, req.VolumeId)
@@ -896,8 +916,8 @@ func controllerUnpublishForBlockVolume(ctx context.Context, req *csi.ControllerU
 		if err == nil || time.Now().After(timeout) {
 			break
 		}
-		if err := c.vmOperatorClient.Get(ctx, vmKey, virtualMachine); err != nil {
-			if errors.IsNotFound(err) {
+		if err := c.refreshVirtualMachine(ctx, virtualMachine); err != nil {
+			if errors.IsNotFound(err) || err == errVirtualMachineRecreated {
 				log.Infof("VirtualMachine %s/%s not found. Assuming volume %s was detached.",
 					c.supervisorNamespace, req.NodeId, req.VolumeId)
 				return &csi.ControllerUnpublishVolumeResponse{}, "", nil
@@ -950,6 +970,11 @@ func controllerUnpublishForBlockVolume(ctx context.Context, req *csi.ControllerU
 				vm.Name, virtualMachine.Name, req.VolumeId)
 			continue
 		}
+		if vm.UID != virtualMachine.UID {
+			log.Debugf("Observed vm UID: %q, expecting vm UID: %q, volumeID: %q",
+				vm.UID, virtualMachine.UID, req.VolumeId)
+			continue
+		}
 		switch event.Type {
 		case watch.Added, watch.Modified:
 			isVolumeDetached = true
@@ -1262,17 +1287,8 @@ func (c *controller) ControllerGetCapabilities(ctx context.Context, req *csi.Con
 	ctx = logger.NewContextWithLogger(ctx)
 	log := logger.GetLogger(ctx)
 	log.Infof("ControllerGetCapabilities: called with args %+v", *req)
-	var caps []*csi.ControllerServiceCapability
-	for _, cap := range controllerCaps {
-		c := &csi.ControllerServiceCapability{
-			Type: &csi.ControllerServiceCapability_Rpc{
-				Rpc: &csi.ControllerServiceCapability_RPC{
-					Type: cap,
-				},
-			},
-		}
-		caps = append(caps, c)
-	}
+	caps := common.GetControllerServiceCapabilities(ctx, controllerCaps, controllerCapsFeatureGates,
+		commonco.ContainerOrchestratorUtility.IsFSSEnabled)
 	return &csi.ControllerGetCapabilitiesResponse{Capabilities: caps}, nil
 }
 
@@ -19,6 +19,7 @@ package wcpguest
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"strconv"
@@ -26,13 +27,16 @@ import (
 	"time"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
+	vmoperatortypes "github.com/vmware-tanzu/vm-operator-api/api/v1alpha1"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
 	clientset "k8s.io/client-go/kubernetes"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common/commonco"
@@ -51,8 +55,17 @@ const (
 	// Default timeout for resize, used unless overridden by user in
 	// csi-controller YAML.
 	defaultResizeTimeoutInMin = 4
+
+	// storageClassQuotaSuffix is the suffix of the names of the resources of
+	// the storage quota of a supervisor namespace for a storage class, which
+	// are prefixed with the name of the storage class.
+	storageClassQuotaSuffix = ".storageclass.storage.k8s.io/"
 )
 
+// errVirtualMachineRecreated is returned when the VirtualMachine backing a
+// node was deleted and recreated with the same name while being updated.
+var errVirtualMachineRecreated = errors.New("VirtualMachine was recreated")
+
 // validateGuestClusterCreateVolumeRequest is the helper function to validate
 // CreateVolumeRequest for Guest Cluster CSI driver.
 // Function returns error if validation fails otherwise returns nil.
@@ -131,7 +144,7 @@ func checkForSupervisorPVCCondition(ctx context.Context, client clientset.Interf
 			Watch:          true,
 		})
 	if err != nil {
-		errMsg := fmt.Errorf("failed to watch supervisor PersistentVolumeClaim %s in namespace %s with Error: %+v",
+		errMsg := fmt.Errorf("failed to watch supervisor PersistentVolumeClaim %s in namespace %s with Error: %w",
 			pvcName, ns, err)
 		log.Error(errMsg)
 		return errMsg
@@ -212,7 +225,7 @@ func generateGuestClusterRequestedTopologyJSON(topologies []*csi.Topology) (stri
 	for _, topology := range topologies {
 		jsonSegment, err := json.Marshal(topology.Segments)
 		if err != nil {
-			return "", fmt.Errorf("failed to marshal topology segment: %v to json. Err: %v", topology.Segments, err)
+			return "", fmt.Errorf("failed to marshal topology segment: %v to json. Err: %w", topology.Segments, err)
 		}
 		segmentsArray = append(segmentsArray, string(jsonSegment))
 	}
@@ -232,7 +245,7 @@ func generateVolumeAccessibilityRequirementsFromPVCAnnotation(claim *v1.Persiste
 	err := json.Unmarshal([]byte(volumeAccessibleTopology), &volumeAccessibleTopologyArray)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse annotation: %q value %v from the claim: %q, namespace: %q. "+
-			"err: %v", common.AnnVolumeAccessibleTopology, volumeAccessibleTopology,
+			"err: %w", common.AnnVolumeAccessibleTopology, volumeAccessibleTopology,
 			claim.Name, claim.Namespace, err)
 	}
 	requirement := &csi.TopologyRequirement{}
@@ -266,7 +279,7 @@ func isPVCInSupervisorClusterBound(ctx context.Context, client clientset.Interfa
 			Watch:          true,
 		})
 	if err != nil {
-		errMsg := fmt.Errorf("failed to watch PersistentVolumeClaim %s with Error: %v", pvcName, err)
+		errMsg := fmt.Errorf("failed to watch PersistentVolumeClaim %s with Error: %w", pvcName, err)
 		log.Error(errMsg)
 		return false, errMsg
 	}
@@ -288,6 +301,77 @@ func isPVCInSupervisorClusterBound(ctx context.Context, client clientset.Interfa
 		pvcName, ns, v1.ClaimBound, timeoutSeconds)
 }
 
+// validateSupervisorNamespaceQuota returns a ResourceExhausted error if the
+// given storage class isn't available in the given supervisor namespace, or
+// if the storage quota of the namespace doesn't allow a volume of the given
+// size to be created with it. The validation is skipped if the resource
+// quotas of the namespace can't be listed, leaving it to the supervisor.
+func validateSupervisorNamespaceQuota(ctx context.Context, client clientset.Interface, namespace string,
+	storageClass string, sizeBytes int64) error {
+	log := logger.GetLogger(ctx)
+	quotaList, err := client.CoreV1().ResourceQuotas(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		log.Warnf("failed to list the resource quotas of supervisor namespace %s, not validating the "+
+			"storage quota. Error: %+v", namespace, err)
+		return nil
+	}
+	if err := checkStorageQuotas(quotaList.Items, storageClass, sizeBytes); err != nil {
+		msg := fmt.Sprintf("cannot create volume of %d bytes with storage class %q in supervisor namespace %s: %v",
+			sizeBytes, storageClass, namespace, err)
+		log.Error(msg)
+		return status.Error(codes.ResourceExhausted, msg)
+	}
+	return nil
+}
+
+// checkStorageQuotas returns an error if the given storage class isn't
+// assigned to the namespace of the given resource quotas, or if one of them
+// doesn't allow another volume of the given size to be created with it.
+// Storage classes are assigned to a supervisor namespace with the storage
+// quota of the namespace, e.g.
+// <storage-class-name>.storageclass.storage.k8s.io/requests.storage, so the
+// storage classes of a namespace without storage quota aren't checked.
+func checkStorageQuotas(quotas []v1.ResourceQuota, storageClass string, sizeBytes int64) error {
+	storageClassPrefix := storageClass + storageClassQuotaSuffix
+	size := *resource.NewQuantity(sizeBytes, resource.BinarySI)
+	claims := *resource.NewQuantity(1, resource.DecimalSI)
+	storageClassStorage := v1.ResourceName(storageClassPrefix + string(v1.ResourceRequestsStorage))
+	storageClassClaims := v1.ResourceName(storageClassPrefix + string(v1.ResourcePersistentVolumeClaims))
+	requests := v1.ResourceList{
+		v1.ResourceRequestsStorage:        size,
+		v1.ResourcePersistentVolumeClaims: claims,
+		storageClassStorage:               size,
+		storageClassClaims:                claims,
+	}
+	storageClassQuota := false
+	assigned := false
+	for _, quota := range quotas {
+		for name := range quota.Spec.Hard {
+			if strings.Contains(string(name), storageClassQuotaSuffix) {
+				storageClassQuota = true
+				assigned = assigned || strings.HasPrefix(string(name), storageClassPrefix)
+			}
+		}
+		for name, requested := range requests {
+			hard, ok := quota.Spec.Hard[name]
+			if !ok {
+				continue
+			}
+			used := quota.Status.Used[name]
+			total := used.DeepCopy()
+			total.Add(requested)
+			if total.Cmp(hard) > 0 {
+				return fmt.Errorf("exceeded quota %s, requested %s=%s, used %s=%s, limited %s=%s",
+					quota.Name, name, requested.String(), name, used.String(), name, hard.String())
+			}
+		}
+	}
+	if storageClassQuota && !assigned {
+		return fmt.Errorf("storage class %q is not assigned to the namespace", storageClass)
+	}
+	return nil
+}
+
 // getProvisionTimeoutInMin() return the timeout for volume provision.
 // If environment variable PROVISION_TIMEOUT_MINUTES is set and valid,
 // return the interval value read from environment variable
@@ -359,3 +443,76 @@ func getAttacherTimeoutInMin(ctx context.Context) int {
 	}
 	return attacherTimeoutInMin
 }
+
+// getVirtualMachineForNode returns the VirtualMachine backing the given guest
+// node. The VirtualMachine is looked up by name in the supervisor namespace of
+// the guest cluster, and its BIOS UUID is checked against the provider ID or
+// system UUID of the node, so that volumes are never attached to or detached
+// from a VirtualMachine which happens to have the name of the node but backs
+// another node. The check is skipped when the node is not found, e.g. when
+// detaching volumes from a deleted node.
+func (c *controller) getVirtualMachineForNode(ctx context.Context,
+	nodeName string) (*vmoperatortypes.VirtualMachine, error) {
+	log := logger.GetLogger(ctx)
+	virtualMachine := &vmoperatortypes.VirtualMachine{}
+	vmKey := types.NamespacedName{
+		Namespace: c.supervisorNamespace,
+		Name:      nodeName,
+	}
+	if err := c.vmOperatorClient.Get(ctx, vmKey, virtualMachine); err != nil {
+		return nil, err
+	}
+	node, err := c.guestClient.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Infof("Node %q not found. Skipping the BIOS UUID check of VirtualMachine %s/%s.",
+				nodeName, virtualMachine.Namespace, virtualMachine.Name)
+			return virtualMachine, nil
+		}
+		return nil, fmt.Errorf("failed to get node %q. Error: %+v", nodeName, err)
+	}
+	if !isVirtualMachineOfNode(virtualMachine, node) {
+		return nil, fmt.Errorf("VirtualMachine %s/%s with BIOS UUID %q does not back node %q with "+
+			"provider ID %q and system UUID %q", virtualMachine.Namespace, virtualMachine.Name,
+			virtualMachine.Status.BiosUUID, nodeName, node.Spec.ProviderID, node.Status.NodeInfo.SystemUUID)
+	}
+	return virtualMachine, nil
+}
+
+// refreshVirtualMachine fetches the latest version of the given
+// VirtualMachine. errVirtualMachineRecreated is returned if the
+// VirtualMachine was recreated with the same name in the meantime.
+func (c *controller) refreshVirtualMachine(ctx context.Context,
+	virtualMachine *vmoperatortypes.VirtualMachine) error {
+	latest := &vmoperatortypes.VirtualMachine{}
+	vmKey := types.NamespacedName{
+		Namespace: virtualMachine.Namespace,
+		Name:      virtualMachine.Name,
+	}
+	if err := c.vmOperatorClient.Get(ctx, vmKey, latest); err != nil {
+		return err
+	}
+	if latest.UID != virtualMachine.UID {
+		return errVirtualMachineRecreated
+	}
+	*virtualMachine = *latest
+	return nil
+}
+
+// isVirtualMachineOfNode returns true if the given VirtualMachine backs the
+// given node, by comparing its BIOS UUID with the provider ID of the node, or
+// its system UUID if the provider ID is not set yet. It returns true when
+// either side has no identifier to compare.
+func isVirtualMachineOfNode(virtualMachine *vmoperatortypes.VirtualMachine, node *v1.Node) bool {
+	biosUUID := virtualMachine.Status.BiosUUID
+	if biosUUID == "" {
+		return true
+	}
+	if node.Spec.ProviderID != "" {
+		return strings.EqualFold(strings.TrimPrefix(node.Spec.ProviderID, common.ProviderPrefix), biosUUID)
+	}
+	if node.Status.NodeInfo.SystemUUID != "" {
+		return strings.EqualFold(node.Status.NodeInfo.SystemUUID, biosUUID)
+	}
+	return true
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wcpguest
+
+import (
+	"context"
+	"testing"
+
+	vmoperatortypes "github.com/vmware-tanzu/vm-operator-api/api/v1alpha1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newTestVirtualMachine(namespace, name, uid, biosUUID string) *vmoperatortypes.VirtualMachine {
+	return &vmoperatortypes.VirtualMachine{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, UID: types.UID("vm-uid-" + uid)},
+		Status:     vmoperatortypes.VirtualMachineStatus{BiosUUID: biosUUID},
+	}
+}
+
+func TestIsVirtualMachineOfNode(t *testing.T) {
+	const biosUUID = "42113a3c-ab24-1f5e-8fb5-bb9ac4e55e2a"
+	tests := []struct {
+		name     string
+		biosUUID string
+		node     v1.Node
+		expected bool
+	}{
+		{
+			name:     "MatchingProviderID",
+			biosUUID: biosUUID,
+			node:     v1.Node{Spec: v1.NodeSpec{ProviderID: "vsphere://42113A3C-AB24-1F5E-8FB5-BB9AC4E55E2A"}},
+			expected: true,
+		},
+		{
+			name:     "OtherProviderID",
+			biosUUID: biosUUID,
+			node:     v1.Node{Spec: v1.NodeSpec{ProviderID: "vsphere://4211b6a5-5d05-b8c4-6d3c-4a4ed6d2a60c"}},
+			expected: false,
+		},
+		{
+			name:     "MatchingSystemUUID",
+			biosUUID: biosUUID,
+			node:     v1.Node{Status: v1.NodeStatus{NodeInfo: v1.NodeSystemInfo{SystemUUID: biosUUID}}},
+			expected: true,
+		},
+		{
+			name:     "OtherSystemUUID",
+			biosUUID: biosUUID,
+			node: v1.Node{Status: v1.NodeStatus{NodeInfo: v1.NodeSystemInfo{
+				SystemUUID: "4211b6a5-5d05-b8c4-6d3c-4a4ed6d2a60c"}}},
+			expected: false,
+		},
+		{
+			name:     "NoNodeIdentifier",
+			biosUUID: biosUUID,
+			expected: true,
+		},
+		{
+			name:     "NoBiosUUID",
+			node:     v1.Node{Spec: v1.NodeSpec{ProviderID: "vsphere://" + biosUUID}},
+			expected: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vm := newTestVirtualMachine(testNamespace, "node-1", "1", test.biosUUID)
+			if isVirtualMachineOfNode(vm, &test.node) != test.expected {
+				t.Errorf("expected isVirtualMachineOfNode to return %t", test.expected)
+			}
+		})
+	}
+}
+
+func TestGetVirtualMachineForNode(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := runtime.NewScheme()
+	if err := vmoperatortypes.AddToScheme(s); err != nil {
+		t.Fatalf("failed to add vm-operator types to scheme. Err: %v", err)
+	}
+	// node-1 is backed by its VirtualMachine, while the VirtualMachine named
+	// node-2 backs a node of another guest cluster. The VirtualMachine of the
+	// deleted node-3 remains.
+	vmOperatorClient := fake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(
+		newTestVirtualMachine(testNamespace, "node-1", "1", "42113a3c-ab24-1f5e-8fb5-bb9ac4e55e2a"),
+		newTestVirtualMachine(testNamespace, "node-2", "2", "4211b6a5-5d05-b8c4-6d3c-4a4ed6d2a60c"),
+		newTestVirtualMachine(testNamespace, "node-3", "3", "4211d2f3-3a6b-4bd1-22b5-f4d1b8c8e0a1"),
+		newTestVirtualMachine("other-namespace", "node-4", "4", "4211e6a1-0c93-5ff7-4b0b-1b5a9a2f0d3c"),
+	).Build()
+	guestClient := k8sfake.NewSimpleClientset(
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+			Spec: v1.NodeSpec{ProviderID: "vsphere://42113a3c-ab24-1f5e-8fb5-bb9ac4e55e2a"}},
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-2"},
+			Spec: v1.NodeSpec{ProviderID: "vsphere://4211f1c0-8e7d-2a55-9d7e-0a8c3b5f6e21"}},
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-4"},
+			Spec: v1.NodeSpec{ProviderID: "vsphere://4211e6a1-0c93-5ff7-4b0b-1b5a9a2f0d3c"}},
+	)
+	c := &controller{
+		guestClient:         guestClient,
+		vmOperatorClient:    vmOperatorClient,
+		supervisorNamespace: testNamespace,
+	}
+
+	vm, err := c.getVirtualMachineForNode(ctx, "node-1")
+	if err != nil || vm.UID != "vm-uid-1" {
+		t.Errorf("expected VirtualMachine vm-uid-1 for node-1, got %v. Err: %v", vm, err)
+	}
+	if _, err := c.getVirtualMachineForNode(ctx, "node-2"); err == nil {
+		t.Error("expected error for node-2 backed by another VirtualMachine")
+	}
+	vm, err = c.getVirtualMachineForNode(ctx, "node-3")
+	if err != nil || vm.UID != "vm-uid-3" {
+		t.Errorf("expected VirtualMachine vm-uid-3 for deleted node-3, got %v. Err: %v", vm, err)
+	}
+	if _, err := c.getVirtualMachineForNode(ctx, "node-4"); !apierrors.IsNotFound(err) {
+		t.Errorf("expected NotFound error for node-4 in another namespace, got %v", err)
+	}
+
+	// Recreate the VirtualMachine of node-1 with the same name.
+	vm, _ = c.getVirtualMachineForNode(ctx, "node-1")
+	if err := vmOperatorClient.Delete(ctx, vm.DeepCopy()); err != nil {
+		t.Fatalf("failed to delete VirtualMachine. Err: %v", err)
+	}
+	if err := vmOperatorClient.Create(ctx, newTestVirtualMachine(testNamespace, "node-1", "5",
+		vm.Status.BiosUUID)); err != nil {
+		t.Fatalf("failed to create VirtualMachine. Err: %v", err)
+	}
+	if err := c.refreshVirtualMachine(ctx, vm); err != errVirtualMachineRecreated {
+		t.Errorf("expected errVirtualMachineRecreated, got %v", err)
+	}
+}
+
+func TestValidateSupervisorNamespaceQuota(t *testing.T) {
+	ctx := context.Background()
+	gi := int64(1024 * 1024 * 1024)
+	newQuota := func(name string, hard, used v1.ResourceList) *v1.ResourceQuota {
+		return &v1.ResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "sv-ns", Name: name},
+			Spec:       v1.ResourceQuotaSpec{Hard: hard},
+			Status:     v1.ResourceQuotaStatus{Hard: hard, Used: used},
+		}
+	}
+	storageQuota := newQuota("sv-ns-storagequota", v1.ResourceList{
+		"gold.storageclass.storage.k8s.io/requests.storage":   resource.MustParse("10Gi"),
+		"silver.storageclass.storage.k8s.io/requests.storage": resource.MustParse("10Gi"),
+	}, v1.ResourceList{
+		"gold.storageclass.storage.k8s.io/requests.storage":   resource.MustParse("8Gi"),
+		"silver.storageclass.storage.k8s.io/requests.storage": resource.MustParse("0"),
+	})
+	claimQuota := newQuota("sv-ns-claims", v1.ResourceList{
+		v1.ResourcePersistentVolumeClaims: resource.MustParse("5"),
+	}, v1.ResourceList{
+		v1.ResourcePersistentVolumeClaims: resource.MustParse("4"),
+	})
+	fullClaimQuota := newQuota("sv-ns-claims", v1.ResourceList{
+		v1.ResourcePersistentVolumeClaims: resource.MustParse("5"),
+	}, v1.ResourceList{
+		v1.ResourcePersistentVolumeClaims: resource.MustParse("5"),
+	})
+
+	for _, test := range []struct {
+		name         string
+		quotas       []runtime.Object
+		storageClass string
+		sizeBytes    int64
+		exhausted    bool
+	}{
+		{"no quota", nil, "gold", 100 * gi, false},
+		{"within quota", []runtime.Object{storageQuota, claimQuota}, "gold", 2 * gi, false},
+		{"storage quota exceeded", []runtime.Object{storageQuota, claimQuota}, "gold", 3 * gi, true},
+		{"other storage class within quota", []runtime.Object{storageQuota}, "silver", 10 * gi, false},
+		{"storage class not assigned", []runtime.Object{storageQuota}, "bronze", gi, true},
+		{"claim quota exceeded", []runtime.Object{storageQuota, fullClaimQuota}, "silver", gi, true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			client := k8sfake.NewSimpleClientset(test.quotas...)
+			err := validateSupervisorNamespaceQuota(ctx, client, "sv-ns", test.storageClass, test.sizeBytes)
+			if !test.exhausted {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+			if status.Code(err) != codes.ResourceExhausted {
+				t.Fatalf("expected ResourceExhausted error, got %v", err)
+			}
+		})
+	}
+}
@@ -1,6 +1,24 @@
 package types
 
+import (
+	"os"
+	"strings"
+)
+
 const (
-	// Name is the name of this CSI SP
-	Name = "csi.vsphere.vmware.com"
+	// DefaultName is the default name of this CSI SP
+	DefaultName = "csi.vsphere.vmware.com"
 )
+
+// Name is the name of this CSI SP. It is DefaultName unless overridden with
+// the X_CSI_DRIVER_NAME env variable, so that several instances of the driver
+// can run side-by-side in a cluster.
+var Name = getName()
+
+// getName returns the name of this CSI SP set in the environment.
+func getName() string {
+	if name := strings.TrimSpace(os.Getenv(EnvVarDriverName)); name != "" {
+		return name
+	}
+	return DefaultName
+}
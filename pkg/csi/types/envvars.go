@@ -43,4 +43,64 @@ const (
 	// Depending on the value, either controller and node service will be
 	// activated (The identity service is always activated).
 	EnvVarMode = "X_CSI_MODE"
+
+	// EnvVarMountHelperSocket specifies the unix socket of the host level
+	// mount helper daemon. If set, the node plugin performs mount operations
+	// through the mount helper instead of from within its container.
+	EnvVarMountHelperSocket = "X_CSI_MOUNT_HELPER_SOCKET"
+
+	// EnvVarFullSyncDryRun enables the dry run mode of full sync when set to
+	// true. Full sync then logs the operations it would execute to reconcile
+	// volumes without executing them.
+	EnvVarFullSyncDryRun = "X_CSI_FULL_SYNC_DRY_RUN"
+
+	// EnvVarGRPCMaxConcurrentStreams limits the number of concurrent requests
+	// served per connection to the CSI endpoint. Unlimited if not set.
+	EnvVarGRPCMaxConcurrentStreams = "X_CSI_GRPC_MAX_CONCURRENT_STREAMS"
+
+	// EnvVarGRPCMaxRecvMsgSize is the maximum size in bytes of the requests
+	// accepted on the CSI endpoint. The gRPC default of 4MiB applies if not
+	// set.
+	EnvVarGRPCMaxRecvMsgSize = "X_CSI_GRPC_MAX_RECV_MSG_SIZE"
+
+	// EnvVarGRPCDefaultDeadline is the deadline, as a duration like "10m",
+	// applied to the requests received on the CSI endpoint without a
+	// deadline. Set it to 0 to serve such requests without deadline.
+	EnvVarGRPCDefaultDeadline = "X_CSI_GRPC_DEFAULT_DEADLINE"
+
+	// EnvVarDriverName overrides the name of the driver, to run several
+	// instances of the driver side-by-side in a cluster. It must be set to the
+	// same value in all the containers of an instance.
+	EnvVarDriverName = "X_CSI_DRIVER_NAME"
+
+	// EnvVarNodeMetricsAddress is the address, like ":2113", on which the
+	// node plugin exposes its Prometheus metrics. The node plugin doesn't
+	// expose metrics if not set.
+	EnvVarNodeMetricsAddress = "X_CSI_NODE_METRICS_ADDRESS"
+
+	// EnvVarSyncNamespaces is the comma separated list of the namespaces whose
+	// PVCs and pods are synced to CNS by the metadata syncer and full sync.
+	// All namespaces are synced if not set.
+	EnvVarSyncNamespaces = "X_CSI_SYNC_NAMESPACES"
+
+	// EnvVarSyncExcludedNamespaces is the comma separated list of the
+	// namespaces whose PVCs and pods are not synced to CNS by the metadata
+	// syncer and full sync. It takes precedence over EnvVarSyncNamespaces.
+	EnvVarSyncExcludedNamespaces = "X_CSI_SYNC_EXCLUDED_NAMESPACES"
+
+	// EnvVarDebugAddress is the address, like "localhost:6060", on which the
+	// controller and the syncer serve their debug endpoints, like pprof. The
+	// debug endpoints are not served if not set.
+	EnvVarDebugAddress = "X_CSI_DEBUG_ADDRESS"
+
+	// EnvVarShutdownTimeout is the time, as a duration like "25s", given to
+	// the controller, node plugin and syncer to drain their work in progress
+	// on termination, before logging out of vCenter. It should be lower than
+	// the termination grace period of their pod.
+	EnvVarShutdownTimeout = "X_CSI_SHUTDOWN_TIMEOUT"
+
+	// EnvVarVolumeOwnersAddress is the address, like "localhost:2115", on
+	// which the syncer serves the Kubernetes objects using a volume handle.
+	// They are not served if not set.
+	EnvVarVolumeOwnersAddress = "X_CSI_VOLUME_OWNERS_ADDRESS"
 )
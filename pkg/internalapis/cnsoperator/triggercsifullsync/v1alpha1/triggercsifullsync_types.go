@@ -55,6 +55,10 @@ type TriggerCsiFullSyncStatus struct {
 	// This timestamp can be either the successful or failed full sync end timestamp.
 	LastRunEndTimeStamp *metav1.Time `json:"lastRunEndTimeStamp,omitempty"`
 
+	// InitialFullSyncCompletedTimeStamp indicates when the first successful
+	// full sync since the syncer started completed. It is unset until then.
+	InitialFullSyncCompletedTimeStamp *metav1.Time `json:"initialFullSyncCompletedTimeStamp,omitempty"`
+
 	// The last error encountered during CSI full sync operation, if any.
 	// Previous error will be cleared when a new full sync is in progress.
 	Error string `json:"error,omitempty"`
@@ -28,7 +28,7 @@ func (in *TriggerCsiFullSync) DeepCopyInto(out *TriggerCsiFullSync) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -102,6 +102,26 @@ func (in *TriggerCsiFullSyncSpec) DeepCopy() *TriggerCsiFullSyncSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TriggerCsiFullSyncStatus) DeepCopyInto(out *TriggerCsiFullSyncStatus) {
 	*out = *in
+	if in.LastSuccessfulStartTimeStamp != nil {
+		in, out := &in.LastSuccessfulStartTimeStamp, &out.LastSuccessfulStartTimeStamp
+		*out = (*in).DeepCopy()
+	}
+	if in.LastSuccessfulEndTimeStamp != nil {
+		in, out := &in.LastSuccessfulEndTimeStamp, &out.LastSuccessfulEndTimeStamp
+		*out = (*in).DeepCopy()
+	}
+	if in.LastRunStartTimeStamp != nil {
+		in, out := &in.LastRunStartTimeStamp, &out.LastRunStartTimeStamp
+		*out = (*in).DeepCopy()
+	}
+	if in.LastRunEndTimeStamp != nil {
+		in, out := &in.LastRunEndTimeStamp, &out.LastRunEndTimeStamp
+		*out = (*in).DeepCopy()
+	}
+	if in.InitialFullSyncCompletedTimeStamp != nil {
+		in, out := &in.InitialFullSyncCompletedTimeStamp, &out.InitialFullSyncCompletedTimeStamp
+		*out = (*in).DeepCopy()
+	}
 	return
 }
 
@@ -0,0 +1,54 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CnsStorageQuotaSpec defines the desired state of CnsStorageQuota.
+type CnsStorageQuotaSpec struct {
+	// StorageClassName restricts the quota to PVCs using the given
+	// StorageClass. When empty, the quota applies to all PVCs in the namespace
+	// provisioned by the vSphere CSI driver.
+	//+optional
+	StorageClassName string `json:"storageClassName,omitempty"`
+
+	// Limit is the total storage which can be requested by the PVCs in the
+	// namespace to which this quota applies.
+	Limit resource.Quantity `json:"limit"`
+}
+
+//+kubebuilder:object:root=true
+
+// CnsStorageQuota is the Schema for the cnsstoragequotas API.
+type CnsStorageQuota struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec CnsStorageQuotaSpec `json:"spec"`
+}
+
+//+kubebuilder:object:root=true
+
+// CnsStorageQuotaList contains a list of CnsStorageQuota.
+type CnsStorageQuotaList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CnsStorageQuota `json:"items"`
+}
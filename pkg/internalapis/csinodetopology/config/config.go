@@ -6,3 +6,8 @@ import "embed"
 var EmbedCSINodeTopologyFile embed.FS
 
 const EmbedCSINodeTopologyFileName = "cns.vmware.com_csinodetopologies.yaml"
+
+//go:embed cns.vmware.com_csinodetopologies_v1beta1.yaml
+var EmbedCSINodeTopologyV1beta1File embed.FS
+
+const EmbedCSINodeTopologyV1beta1FileName = "cns.vmware.com_csinodetopologies_v1beta1.yaml"
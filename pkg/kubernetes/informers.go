@@ -79,11 +79,11 @@ func (im *InformerManager) AddNodeListener(
 // AddCSINodeNodeListener hooks up add, update, delete callbacks.
 func (im *InformerManager) AddCSINodeListener(
 	add func(obj interface{}), update func(oldObj, newObj interface{}), remove func(obj interface{})) {
-	if im.nodeInformer == nil {
-		im.nodeInformer = im.informerFactory.Storage().V1().CSINodes().Informer()
+	if im.csiNodeInformer == nil {
+		im.csiNodeInformer = im.informerFactory.Storage().V1().CSINodes().Informer()
 	}
 
-	im.nodeInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+	im.csiNodeInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc:    add,
 		UpdateFunc: update,
 		DeleteFunc: remove,
@@ -191,6 +191,38 @@ func (im *InformerManager) GetPodLister() corelisters.PodLister {
 	return im.informerFactory.Core().V1().Pods().Lister()
 }
 
+// WarmUp starts the PV, PVC and Pod informers, before any listener is added,
+// so that their caches are ready when the listeners are added. It waits
+// until their caches synced or stopCh is closed, and returns whether they
+// synced. Listeners added afterwards are notified of the cached objects.
+func (im *InformerManager) WarmUp(stopCh <-chan struct{}) bool {
+	informersSynced := im.warmUpInformersSynced()
+	go im.informerFactory.Start(im.stopCh)
+	return cache.WaitForCacheSync(stopCh, informersSynced...)
+}
+
+// WarmedUp returns whether the caches of the PV, PVC and Pod informers
+// synced.
+func (im *InformerManager) WarmedUp() bool {
+	for _, informerSynced := range im.warmUpInformersSynced() {
+		if !informerSynced() {
+			return false
+		}
+	}
+	return true
+}
+
+// warmUpInformersSynced returns the functions determining if the PV, PVC and
+// Pod informers synced. The informer factory returns the same informers to
+// the listeners added afterwards.
+func (im *InformerManager) warmUpInformersSynced() []cache.InformerSynced {
+	return []cache.InformerSynced{
+		im.informerFactory.Core().V1().PersistentVolumes().Informer().HasSynced,
+		im.informerFactory.Core().V1().PersistentVolumeClaims().Informer().HasSynced,
+		im.informerFactory.Core().V1().Pods().Informer().HasSynced,
+	}
+}
+
 // Listen starts the Informers.
 func (im *InformerManager) Listen() (stopCh <-chan struct{}) {
 	go im.informerFactory.Start(im.stopCh)
@@ -19,6 +19,7 @@ package kubernetes
 import (
 	"context"
 	"embed"
+	"encoding/json"
 	"flag"
 	"io/ioutil"
 	"net"
@@ -36,6 +37,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
+	k8stypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
 	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
 	clientset "k8s.io/client-go/kubernetes"
@@ -56,6 +58,7 @@ import (
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
 	internalapis "sigs.k8s.io/vsphere-csi-driver/v2/pkg/internalapis"
+	cnsstoragequotav1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/internalapis/cnsstoragequota/v1alpha1"
 	cnsvolumeoprequestv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/internalapis/cnsvolumeoperationrequest/v1alpha1"
 	csinodetopologyv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/internalapis/csinodetopology/v1alpha1"
 )
@@ -116,6 +119,18 @@ func NewSnapshotterClient(ctx context.Context) (snapshotterClientSet.Interface,
 	return snapshotterClientSet.NewForConfig(config)
 }
 
+// NewAPIExtensionsClient creates a new apiextensions client based on a
+// service account.
+func NewAPIExtensionsClient(ctx context.Context) (apiextensionsclientset.Interface, error) {
+	log := logger.GetLogger(ctx)
+	config, err := GetKubeConfig(ctx)
+	if err != nil {
+		log.Errorf("Failed to get KubeConfig. err: %v", err)
+		return nil, err
+	}
+	return apiextensionsclientset.NewForConfig(config)
+}
+
 // GetRestClientConfigForSupervisor returns restclient config for given
 // endpoint, port, certificate and token.
 func GetRestClientConfigForSupervisor(ctx context.Context, endpoint string, port string) *restclient.Config {
@@ -198,6 +213,11 @@ func NewClientForGroup(ctx context.Context, config *restclient.Config, groupName
 			log.Errorf("failed to add CSINodeTopology to scheme with error: %+v", err)
 			return nil, err
 		}
+		err = cnsstoragequotav1alpha1.AddToScheme(scheme)
+		if err != nil {
+			log.Errorf("failed to add CnsStorageQuota to scheme with error: %+v", err)
+			return nil, err
+		}
 	}
 	client, err := client.New(config, client.Options{
 		Scheme: scheme,
@@ -339,6 +359,33 @@ func GetNodeUUID(ctx context.Context,
 	return nodeId, nil
 }
 
+// SetNodeCondition adds or updates the given condition on the status of the
+// Kubernetes Node API object with the given name.
+func SetNodeCondition(ctx context.Context, k8sclient clientset.Interface, nodeName string,
+	condition v1.NodeCondition) error {
+	log := logger.GetLogger(ctx)
+	now := metav1.Now()
+	condition.LastHeartbeatTime = now
+	condition.LastTransitionTime = now
+	patch, err := json.Marshal(map[string]interface{}{
+		"status": map[string]interface{}{
+			"conditions": []v1.NodeCondition{condition},
+		},
+	})
+	if err != nil {
+		log.Errorf("failed to marshal condition %q for node %q. Err: %v", condition.Type, nodeName, err)
+		return err
+	}
+	_, err = k8sclient.CoreV1().Nodes().Patch(ctx, nodeName, k8stypes.StrategicMergePatchType, patch,
+		metav1.PatchOptions{}, "status")
+	if err != nil {
+		log.Errorf("failed to set condition %q on node %q. Err: %v", condition.Type, nodeName, err)
+		return err
+	}
+	log.Infof("Set condition %q with status %q on node %q", condition.Type, condition.Status, nodeName)
+	return nil
+}
+
 // getClientThroughput returns the QPS and Burst for the API server client.
 // QPS and Burst default to 50.
 // The maximum accepted value for QPS or Burst is set to 1000.
@@ -415,6 +462,49 @@ func CreateCustomResourceDefinitionFromManifest(ctx context.Context, embedFiles
 	return createCustomResourceDefinition(ctx, manifestcrd)
 }
 
+// CreateCustomResourceDefinitionFromManifestWithCABundle creates custom
+// resource definition spec from manifest file, with the given CA bundle to
+// verify the certificate of its conversion webhook.
+func CreateCustomResourceDefinitionFromManifestWithCABundle(ctx context.Context, embedFiles embed.FS,
+	fileName string, caBundle []byte) error {
+	log := logger.GetLogger(ctx)
+	manifestcrd, err := getCRDFromManifest(ctx, embedFiles, fileName)
+	if err != nil {
+		log.Errorf("Failed to read the CRD spec from manifest file: %s with err: %+v", fileName, err)
+		return err
+	}
+	conversion := manifestcrd.Spec.Conversion
+	if conversion == nil || conversion.Webhook == nil || conversion.Webhook.ClientConfig == nil {
+		return logger.LogNewErrorf(log, "CRD spec from manifest file: %s has no conversion webhook", fileName)
+	}
+	conversion.Webhook.ClientConfig.CABundle = caBundle
+	return createCustomResourceDefinition(ctx, manifestcrd)
+}
+
+// GetValidatingWebhookCABundle returns the CA bundle used by the API server
+// to verify the certificate of the webhooks of the given
+// ValidatingWebhookConfiguration.
+func GetValidatingWebhookCABundle(ctx context.Context, webhookConfigName string) ([]byte, error) {
+	log := logger.GetLogger(ctx)
+	k8sClient, err := NewClient(ctx)
+	if err != nil {
+		log.Errorf("failed to create Kubernetes client. Err: %+v", err)
+		return nil, err
+	}
+	webhookConfig, err := k8sClient.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(ctx,
+		webhookConfigName, metav1.GetOptions{})
+	if err != nil {
+		log.Errorf("failed to get ValidatingWebhookConfiguration %q. Err: %+v", webhookConfigName, err)
+		return nil, err
+	}
+	for _, webhook := range webhookConfig.Webhooks {
+		if len(webhook.ClientConfig.CABundle) != 0 {
+			return webhook.ClientConfig.CABundle, nil
+		}
+	}
+	return nil, logger.LogNewErrorf(log, "ValidatingWebhookConfiguration %q has no CA bundle", webhookConfigName)
+}
+
 // GetNodeIdFromCSINode gets the UUID from CSINode object
 func GetNodeIdFromCSINode(csiNode *storagev1.CSINode) string {
 	drivers := csiNode.Spec.Drivers
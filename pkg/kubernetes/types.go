@@ -55,6 +55,8 @@ type InformerManager struct {
 
 	// node informer
 	nodeInformer cache.SharedInformer
+	// CSINode informer
+	csiNodeInformer cache.SharedInformer
 
 	// ConfigMap informer
 	configMapInformer cache.SharedInformer
@@ -140,7 +140,11 @@ func StartWebhookServer(ctx context.Context) error {
 		}
 	}
 	if containerOrchestratorUtility.IsFSSEnabled(ctx, common.CSIMigration) ||
-		containerOrchestratorUtility.IsFSSEnabled(ctx, common.BlockVolumeSnapshot) {
+		containerOrchestratorUtility.IsFSSEnabled(ctx, common.BlockVolumeSnapshot) ||
+		containerOrchestratorUtility.IsFSSEnabled(ctx, common.StorageQuotaValidation) ||
+		containerOrchestratorUtility.IsFSSEnabled(ctx, common.StorageClassParamValidation) ||
+		containerOrchestratorUtility.IsFSSEnabled(ctx, common.StorageClassDefaults) ||
+		containerOrchestratorUtility.IsFSSEnabled(ctx, common.CRDConversionWebhook) {
 		certs, err := tls.LoadX509KeyPair(cfg.WebHookConfig.CertFile, cfg.WebHookConfig.KeyFile)
 		if err != nil {
 			log.Errorf("failed to load key pair. certFile: %q, keyFile: %q err: %v",
@@ -157,6 +161,8 @@ func StartWebhookServer(ctx context.Context) error {
 		// Define http server and server handler.
 		mux := http.NewServeMux()
 		mux.HandleFunc("/validate", validationHandler)
+		mux.HandleFunc("/mutate", mutationHandler)
+		mux.HandleFunc("/convert", conversionHandler)
 		server.Handler = mux
 
 		// Start webhook server.
@@ -201,6 +207,48 @@ func restartWebhookServer(ctx context.Context) error {
 // validate resources. Depending on the URL validation of AdmissionReview
 // will be redirected to appropriate validation function.
 func validationHandler(w http.ResponseWriter, r *http.Request) {
+	admissionReviewHandler(w, r, func(ctx context.Context,
+		ar *admissionv1.AdmissionReview) *admissionv1.AdmissionResponse {
+		log := logger.GetLogger(ctx)
+		log.Debugf("request URL path is /validate")
+		switch ar.Request.Kind.Kind {
+		case "StorageClass":
+			return validateStorageClass(ctx, ar)
+		case "PersistentVolumeClaim":
+			return validatePVC(ctx, ar)
+		default:
+			log.Infof("Skipping validation for resource type: %q", ar.Request.Kind.Kind)
+			return &admissionv1.AdmissionResponse{
+				Allowed: true,
+			}
+		}
+	})
+}
+
+// mutationHandler is the handler for webhook http multiplexer to help
+// mutate resources. Depending on the kind of the resource, the
+// AdmissionReview will be redirected to appropriate mutation function.
+func mutationHandler(w http.ResponseWriter, r *http.Request) {
+	admissionReviewHandler(w, r, func(ctx context.Context,
+		ar *admissionv1.AdmissionReview) *admissionv1.AdmissionResponse {
+		log := logger.GetLogger(ctx)
+		log.Debugf("request URL path is /mutate")
+		switch ar.Request.Kind.Kind {
+		case "StorageClass":
+			return mutateStorageClass(ctx, ar)
+		default:
+			log.Infof("Skipping mutation for resource type: %q", ar.Request.Kind.Kind)
+			return &admissionv1.AdmissionResponse{
+				Allowed: true,
+			}
+		}
+	})
+}
+
+// admissionReviewHandler decodes the AdmissionReview from the request, passes
+// it to the given admit function and writes back the AdmissionResponse.
+func admissionReviewHandler(w http.ResponseWriter, r *http.Request,
+	admit func(ctx context.Context, ar *admissionv1.AdmissionReview) *admissionv1.AdmissionResponse) {
 	var body []byte
 	ctx, log := logger.GetNewContextWithLogger()
 	if r.Body != nil {
@@ -234,22 +282,9 @@ func validationHandler(w http.ResponseWriter, r *http.Request) {
 			},
 		}
 	} else {
-		if r.URL.Path == "/validate" {
-			log.Debugf("request URL path is /validate")
-			log.Debugf("admissionReview: %+v", ar)
-			switch ar.Request.Kind.Kind {
-			case "StorageClass":
-				admissionResponse = validateStorageClass(ctx, &ar)
-			case "PersistentVolumeClaim":
-				admissionResponse = validatePVC(ctx, &ar)
-			default:
-				log.Infof("Skipping validation for resource type: %q", ar.Request.Kind.Kind)
-				admissionResponse = &admissionv1.AdmissionResponse{
-					Allowed: true,
-				}
-			}
-			log.Debugf("admissionResponse: %+v", admissionResponse)
-		}
+		log.Debugf("admissionReview: %+v", ar)
+		admissionResponse = admit(ctx, &ar)
+		log.Debugf("admissionResponse: %+v", admissionResponse)
 	}
 	admissionReview := admissionv1.AdmissionReview{}
 	admissionReview.APIVersion = "admission.k8s.io/v1"
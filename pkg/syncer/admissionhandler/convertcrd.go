@@ -0,0 +1,217 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admissionhandler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+)
+
+const (
+	// crdGroup is the API group of the CRDs converted by the webhook.
+	crdGroup = "cns.vmware.com"
+	// preservedFieldsAnnotation holds, as JSON, the fields of a custom
+	// resource which don't exist in the version it was converted to, so that
+	// they are restored when it is converted back to a version having them.
+	preservedFieldsAnnotation = "cns.vmware.com/preserved-fields"
+)
+
+// crdVersions lists the versions of the cns.vmware.com CRDs, from the oldest
+// to the newest.
+var crdVersions = []string{"v1alpha1", "v1beta1"}
+
+// crdAddedFields lists, by kind and version, the paths of the fields added
+// to the cns.vmware.com CRDs served at more than one version, in each
+// version after v1alpha1. Fields are only added by new versions, never
+// renamed or removed, so converting a custom resource to an older version
+// only sets aside the fields the older version doesn't have.
+var crdAddedFields = map[string]map[string][][]string{
+	"CSINodeTopology": {"v1beta1": nil},
+}
+
+// conversionHandler is the handler for webhook http multiplexer to convert
+// the cns.vmware.com custom resources between the versions of their CRD.
+func conversionHandler(w http.ResponseWriter, r *http.Request) {
+	var body []byte
+	ctx, log := logger.GetNewContextWithLogger()
+	if r.Body != nil {
+		if data, err := ioutil.ReadAll(r.Body); err == nil {
+			body = data
+		}
+	}
+	if len(body) == 0 {
+		log.Error("received empty request body")
+		http.Error(w, "received empty request body", http.StatusBadRequest)
+		return
+	}
+	contentType := r.Header.Get("Content-Type")
+	if contentType != "application/json" {
+		log.Errorf("content-Type=%s, expect application/json", contentType)
+		http.Error(w, "invalid Content-Type, expect `application/json`", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	conversionReview := apiextensionsv1.ConversionReview{}
+	if err := json.Unmarshal(body, &conversionReview); err != nil || conversionReview.Request == nil {
+		log.Errorf("Can't decode body: %v", err)
+		http.Error(w, "could not decode ConversionReview", http.StatusBadRequest)
+		return
+	}
+	conversionReview.Response = convertCRDObjects(ctx, conversionReview.Request)
+	conversionReview.Request = nil
+	resp, err := json.Marshal(conversionReview)
+	if err != nil {
+		log.Errorf("Can't encode response: %v", err)
+		http.Error(w, fmt.Sprintf("could not encode response: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if _, err := w.Write(resp); err != nil {
+		log.Errorf("Can't write response: %v", err)
+	}
+}
+
+// convertCRDObjects converts the custom resources of the given
+// ConversionRequest to its desired API version.
+func convertCRDObjects(ctx context.Context,
+	req *apiextensionsv1.ConversionRequest) *apiextensionsv1.ConversionResponse {
+	log := logger.GetLogger(ctx)
+	resp := &apiextensionsv1.ConversionResponse{UID: req.UID}
+	for _, object := range req.Objects {
+		obj := &unstructured.Unstructured{}
+		converted, err := func() ([]byte, error) {
+			if err := obj.UnmarshalJSON(object.Raw); err != nil {
+				return nil, err
+			}
+			convertedObj, err := convertCRDObject(obj, req.DesiredAPIVersion)
+			if err != nil {
+				return nil, err
+			}
+			return convertedObj.MarshalJSON()
+		}()
+		if err != nil {
+			log.Errorf("failed to convert %s %q to %q. Err: %v", obj.GetKind(), obj.GetName(),
+				req.DesiredAPIVersion, err)
+			resp.ConvertedObjects = nil
+			resp.Result = metav1.Status{Status: metav1.StatusFailure, Message: err.Error()}
+			return resp
+		}
+		resp.ConvertedObjects = append(resp.ConvertedObjects, runtime.RawExtension{Raw: converted})
+	}
+	log.Debugf("Converted %d objects to %q", len(resp.ConvertedObjects), req.DesiredAPIVersion)
+	resp.Result = metav1.Status{Status: metav1.StatusSuccess}
+	return resp
+}
+
+// convertCRDObject returns the given cns.vmware.com custom resource converted
+// to the given API version. The fields the version doesn't have are set
+// aside in the preservedFieldsAnnotation, and the fields set aside which the
+// version has are restored, so that converting a custom resource to another
+// version and back doesn't lose any field.
+func convertCRDObject(obj *unstructured.Unstructured,
+	desiredAPIVersion string) (*unstructured.Unstructured, error) {
+	desiredGV, err := schema.ParseGroupVersion(desiredAPIVersion)
+	if err != nil {
+		return nil, err
+	}
+	currentGV, err := schema.ParseGroupVersion(obj.GetAPIVersion())
+	if err != nil {
+		return nil, err
+	}
+	if desiredGV.Group != crdGroup || currentGV.Group != crdGroup {
+		return nil, fmt.Errorf("cannot convert %q to %q, expected group %q",
+			obj.GetAPIVersion(), desiredAPIVersion, crdGroup)
+	}
+	addedFields, ok := crdAddedFields[obj.GetKind()]
+	if !ok {
+		return nil, fmt.Errorf("conversion of kind %q is not supported", obj.GetKind())
+	}
+	desiredIndex := -1
+	for i, version := range crdVersions {
+		if version == desiredGV.Version {
+			desiredIndex = i
+		}
+	}
+	if desiredIndex == -1 {
+		return nil, fmt.Errorf("unknown version %q", desiredGV.Version)
+	}
+
+	converted := obj.DeepCopy()
+	converted.SetAPIVersion(desiredAPIVersion)
+	annotations := converted.GetAnnotations()
+	preserved := make(map[string]interface{})
+	if value := annotations[preservedFieldsAnnotation]; value != "" {
+		decoder := json.NewDecoder(bytes.NewBufferString(value))
+		decoder.UseNumber()
+		if err := decoder.Decode(&preserved); err != nil {
+			return nil, fmt.Errorf("invalid %s annotation: %v", preservedFieldsAnnotation, err)
+		}
+	}
+	for i, version := range crdVersions[1:] {
+		for _, path := range addedFields[version] {
+			key := strings.Join(path, ".")
+			if i+1 <= desiredIndex {
+				// Restore the field set aside.
+				value, ok := preserved[key]
+				if !ok {
+					continue
+				}
+				if err := unstructured.SetNestedField(converted.Object, value, path...); err != nil {
+					return nil, err
+				}
+				delete(preserved, key)
+			} else {
+				// Set aside the field the desired version doesn't have.
+				value, found, err := unstructured.NestedFieldCopy(converted.Object, path...)
+				if err != nil || !found {
+					continue
+				}
+				preserved[key] = value
+				unstructured.RemoveNestedField(converted.Object, path...)
+			}
+		}
+	}
+	if len(preserved) == 0 {
+		delete(annotations, preservedFieldsAnnotation)
+	} else {
+		value, err := json.Marshal(preserved)
+		if err != nil {
+			return nil, err
+		}
+		if annotations == nil {
+			annotations = make(map[string]string)
+		}
+		annotations[preservedFieldsAnnotation] = string(value)
+	}
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+	converted.SetAnnotations(annotations)
+	return converted, nil
+}
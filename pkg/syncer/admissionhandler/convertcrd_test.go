@@ -0,0 +1,190 @@
+package admissionhandler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func newCSINodeTopology(apiVersion string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	_ = obj.UnmarshalJSON([]byte(`{
+		"apiVersion": "` + apiVersion + `",
+		"kind": "CSINodeTopology",
+		"metadata": {"name": "node-1", "annotations": {"owner": "syncer"}},
+		"spec": {"nodeID": "node-1", "nodeuuid": "4237e2b8-0a6b-4a0c-8c8b-3d0e4e0a8b5c"},
+		"status": {
+			"status": "Success",
+			"topologyLabels": [{"key": "topology.csi.vmware.com/k8s-zone", "value": "zone-a"}]
+		}
+	}`))
+	return obj
+}
+
+// TestConvertCRDObjectRoundTrip checks that converting a CSINodeTopology to
+// v1beta1 and back to its v1alpha1 storage version doesn't change it.
+func TestConvertCRDObjectRoundTrip(t *testing.T) {
+	original := newCSINodeTopology("cns.vmware.com/v1alpha1")
+	beta, err := convertCRDObject(original, "cns.vmware.com/v1beta1")
+	if err != nil {
+		t.Fatalf("failed to convert to v1beta1: %v", err)
+	}
+	if beta.GetAPIVersion() != "cns.vmware.com/v1beta1" {
+		t.Errorf("expected apiVersion cns.vmware.com/v1beta1, got %q", beta.GetAPIVersion())
+	}
+	alpha, err := convertCRDObject(beta, "cns.vmware.com/v1alpha1")
+	if err != nil {
+		t.Fatalf("failed to convert back to v1alpha1: %v", err)
+	}
+	if !reflect.DeepEqual(alpha, original) {
+		t.Errorf("expected the round trip to return %v, got %v", original, alpha)
+	}
+}
+
+// TestConvertCRDObjectPreservesAddedFields checks that the fields added in
+// v1beta1 are preserved when a custom resource is converted to v1alpha1,
+// e.g. to be stored or read by a driver rolled back to a version which only
+// knows v1alpha1, and restored when it is converted back to v1beta1.
+func TestConvertCRDObjectPreservesAddedFields(t *testing.T) {
+	addedFields := crdAddedFields["CSINodeTopology"]
+	crdAddedFields["CSINodeTopology"] = map[string][][]string{
+		"v1beta1": {{"status", "topologyDomains"}, {"spec", "maxVolumes"}},
+	}
+	defer func() { crdAddedFields["CSINodeTopology"] = addedFields }()
+
+	original := newCSINodeTopology("cns.vmware.com/v1beta1")
+	_ = unstructured.SetNestedField(original.Object, int64(59), "spec", "maxVolumes")
+	_ = unstructured.SetNestedStringSlice(original.Object, []string{"zone-a", "region-1"},
+		"status", "topologyDomains")
+
+	alpha, err := convertCRDObject(original, "cns.vmware.com/v1alpha1")
+	if err != nil {
+		t.Fatalf("failed to convert to v1alpha1: %v", err)
+	}
+	if _, found, _ := unstructured.NestedFieldNoCopy(alpha.Object, "spec", "maxVolumes"); found {
+		t.Error("expected spec.maxVolumes to be removed from v1alpha1")
+	}
+	if _, found, _ := unstructured.NestedFieldNoCopy(alpha.Object, "status", "topologyDomains"); found {
+		t.Error("expected status.topologyDomains to be removed from v1alpha1")
+	}
+	if alpha.GetAnnotations()[preservedFieldsAnnotation] == "" {
+		t.Errorf("expected the removed fields to be preserved, got annotations %v", alpha.GetAnnotations())
+	}
+
+	// Round trip through JSON, as the API server stores the v1alpha1 object.
+	data, err := alpha.MarshalJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal v1alpha1: %v", err)
+	}
+	stored := &unstructured.Unstructured{}
+	if err := stored.UnmarshalJSON(data); err != nil {
+		t.Fatalf("failed to unmarshal v1alpha1: %v", err)
+	}
+	beta, err := convertCRDObject(stored, "cns.vmware.com/v1beta1")
+	if err != nil {
+		t.Fatalf("failed to convert back to v1beta1: %v", err)
+	}
+	maxVolumes, _, _ := unstructured.NestedFieldNoCopy(beta.Object, "spec", "maxVolumes")
+	if maxVolumes != json.Number("59") {
+		t.Errorf("expected spec.maxVolumes to be restored, got %#v", maxVolumes)
+	}
+	if domains, _, _ := unstructured.NestedStringSlice(beta.Object, "status", "topologyDomains"); !reflect.DeepEqual(
+		domains, []string{"zone-a", "region-1"}) {
+		t.Errorf("expected status.topologyDomains to be restored, got %v", domains)
+	}
+	if !reflect.DeepEqual(beta.GetAnnotations(), map[string]string{"owner": "syncer"}) {
+		t.Errorf("expected the preserved fields annotation to be removed, got %v", beta.GetAnnotations())
+	}
+}
+
+func TestConvertCRDObjectErrors(t *testing.T) {
+	tests := []struct {
+		name              string
+		obj               *unstructured.Unstructured
+		desiredAPIVersion string
+	}{
+		{
+			name:              "UnknownVersion",
+			obj:               newCSINodeTopology("cns.vmware.com/v1alpha1"),
+			desiredAPIVersion: "cns.vmware.com/v2",
+		},
+		{
+			name:              "OtherGroup",
+			obj:               newCSINodeTopology("cns.vmware.com/v1alpha1"),
+			desiredAPIVersion: "storage.k8s.io/v1",
+		},
+		{
+			name: "UnsupportedKind",
+			obj: &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "cns.vmware.com/v1alpha1",
+				"kind":       "CnsVolumeMetadata",
+			}},
+			desiredAPIVersion: "cns.vmware.com/v1beta1",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := convertCRDObject(test.obj, test.desiredAPIVersion); err == nil {
+				t.Error("expected the conversion to fail")
+			}
+		})
+	}
+}
+
+func TestConversionHandler(t *testing.T) {
+	data, err := newCSINodeTopology("cns.vmware.com/v1alpha1").MarshalJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal CSINodeTopology: %v", err)
+	}
+	review := apiextensionsv1.ConversionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "apiextensions.k8s.io/v1", Kind: "ConversionReview"},
+		Request: &apiextensionsv1.ConversionRequest{
+			UID:               "review-1",
+			DesiredAPIVersion: "cns.vmware.com/v1beta1",
+			Objects:           []runtime.RawExtension{{Raw: data}},
+		},
+	}
+	body, err := json.Marshal(review)
+	if err != nil {
+		t.Fatalf("failed to marshal ConversionReview: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/convert", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	conversionHandler(recorder, req)
+
+	response := apiextensionsv1.ConversionReview{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal the response %q: %v", recorder.Body.String(), err)
+	}
+	if response.Response == nil || response.Response.UID != "review-1" ||
+		response.Response.Result.Status != metav1.StatusSuccess || len(response.Response.ConvertedObjects) != 1 {
+		t.Fatalf("expected a successful response with 1 object, got %+v", response.Response)
+	}
+	converted := &unstructured.Unstructured{}
+	if err := converted.UnmarshalJSON(response.Response.ConvertedObjects[0].Raw); err != nil {
+		t.Fatalf("failed to unmarshal the converted object: %v", err)
+	}
+	if converted.GetAPIVersion() != "cns.vmware.com/v1beta1" {
+		t.Errorf("expected apiVersion cns.vmware.com/v1beta1, got %q", converted.GetAPIVersion())
+	}
+
+	// A failed conversion fails the whole request.
+	failed := convertCRDObjects(context.Background(), &apiextensionsv1.ConversionRequest{
+		UID:               "review-2",
+		DesiredAPIVersion: "cns.vmware.com/v2",
+		Objects:           []runtime.RawExtension{{Raw: data}},
+	})
+	if failed.Result.Status != metav1.StatusFailure || len(failed.ConvertedObjects) != 0 {
+		t.Errorf("expected a failed response without objects, got %+v", failed)
+	}
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admissionhandler
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cnsconfig "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+	k8s "sigs.k8s.io/vsphere-csi-driver/v2/pkg/kubernetes"
+)
+
+const (
+	// storageClassDefaultsConfigMapName is the name of the ConfigMap, in the
+	// namespace of the driver, holding the cluster default StorageClass
+	// parameters. Keys of the ConfigMap are the StorageClass parameters to be
+	// defaulted.
+	storageClassDefaultsConfigMapName = "vsphere-csi-storageclass-defaults"
+	// envCSINamespace is the environment variable holding the namespace of
+	// the driver.
+	envCSINamespace = "CSI_NAMESPACE"
+)
+
+// jsonPatchOperation is a single JSON patch operation as defined in RFC 6902.
+type jsonPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// mutateStorageClass helps mutate AdmissionReview requests for StorageClass.
+// On creation of a vSphere CSI StorageClass, the cluster default fstype and
+// storage policy are injected if the StorageClass omits them.
+func mutateStorageClass(ctx context.Context, ar *admissionv1.AdmissionReview) *admissionv1.AdmissionResponse {
+	if containerOrchestratorUtility != nil &&
+		!containerOrchestratorUtility.IsFSSEnabled(ctx, common.StorageClassDefaults) {
+		return &admissionv1.AdmissionResponse{
+			Allowed: true,
+		}
+	}
+	log := logger.GetLogger(ctx)
+	req := ar.Request
+	if req.Operation != admissionv1.Create {
+		return &admissionv1.AdmissionResponse{
+			Allowed: true,
+		}
+	}
+	sc := storagev1.StorageClass{}
+	if err := json.Unmarshal(req.Object.Raw, &sc); err != nil {
+		log.Warnf("error deserializing storage class: %v. skipping mutation.", err)
+		return &admissionv1.AdmissionResponse{
+			Allowed: true,
+		}
+	}
+	if sc.Provisioner != csitypes.Name {
+		return &admissionv1.AdmissionResponse{
+			Allowed: true,
+		}
+	}
+	defaults, err := getStorageClassDefaults(ctx)
+	if err != nil {
+		log.Warnf("failed to get StorageClass defaults: %v. skipping mutation of StorageClass %q.", err, sc.Name)
+		return &admissionv1.AdmissionResponse{
+			Allowed: true,
+		}
+	}
+	patch := getStorageClassDefaultsPatch(sc.Parameters, defaults)
+	if len(patch) == 0 {
+		return &admissionv1.AdmissionResponse{
+			Allowed: true,
+		}
+	}
+	patchBytes, err := json.Marshal(patch)
+	if err != nil {
+		log.Warnf("failed to encode patch %v: %v. skipping mutation of StorageClass %q.", patch, err, sc.Name)
+		return &admissionv1.AdmissionResponse{
+			Allowed: true,
+		}
+	}
+	log.Infof("Injecting default parameters into StorageClass %q. Patch: %s", sc.Name, string(patchBytes))
+	patchType := admissionv1.PatchTypeJSONPatch
+	return &admissionv1.AdmissionResponse{
+		Allowed:   true,
+		Patch:     patchBytes,
+		PatchType: &patchType,
+	}
+}
+
+// getStorageClassDefaults returns the cluster default StorageClass
+// parameters from the storageClassDefaultsConfigMapName ConfigMap. No
+// defaults are returned if the ConfigMap does not exist.
+func getStorageClassDefaults(ctx context.Context) (map[string]string, error) {
+	namespace := os.Getenv(envCSINamespace)
+	if namespace == "" {
+		namespace = cnsconfig.DefaultCSINamespace
+	}
+	kubeClient, err := k8s.NewClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	configMap, err := kubeClient.CoreV1().ConfigMaps(namespace).Get(ctx,
+		storageClassDefaultsConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return configMap.Data, nil
+}
+
+// getStorageClassDefaultsPatch returns the JSON patch adding the default
+// fstype and storage policy to the given StorageClass parameters when they
+// are omitted. The storage policy is not defaulted if a datastore is
+// explicitly requested.
+func getStorageClassDefaultsPatch(params map[string]string, defaults map[string]string) []jsonPatchOperation {
+	var hasFsType, hasPolicy, hasDatastore bool
+	for param := range params {
+		switch strings.ToLower(param) {
+		case csiProvisionerFsTypeParam, common.AttributeFsType:
+			hasFsType = true
+		case common.AttributeStoragePolicyName, common.AttributeStoragePolicyID:
+			hasPolicy = true
+		case common.AttributeDatastoreURL:
+			hasDatastore = true
+		}
+	}
+	injected := make(map[string]string)
+	if fsType := defaults[csiProvisionerFsTypeParam]; fsType != "" && !hasFsType {
+		injected[csiProvisionerFsTypeParam] = fsType
+	}
+	if policy := defaults[common.AttributeStoragePolicyName]; policy != "" && !hasPolicy && !hasDatastore {
+		injected[common.AttributeStoragePolicyName] = policy
+	}
+	if len(injected) == 0 {
+		return nil
+	}
+	if len(params) == 0 {
+		return []jsonPatchOperation{{Op: "add", Path: "/parameters", Value: injected}}
+	}
+	var patch []jsonPatchOperation
+	for _, param := range []string{csiProvisionerFsTypeParam, common.AttributeStoragePolicyName} {
+		if value, ok := injected[param]; ok {
+			patch = append(patch, jsonPatchOperation{
+				Op: "add",
+				// "/" in the key is escaped as "~1" in JSON pointers.
+				Path:  "/parameters/" + strings.ReplaceAll(param, "/", "~1"),
+				Value: value,
+			})
+		}
+	}
+	return patch
+}
@@ -0,0 +1,61 @@
+package admissionhandler
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestGetStorageClassDefaultsPatch is the unit test for computing the patch
+// injecting the default parameters into a vSphere CSI StorageClass.
+func TestGetStorageClassDefaultsPatch(t *testing.T) {
+	defaults := map[string]string{
+		"csi.storage.k8s.io/fstype": "xfs",
+		"storagepolicyname":         "gold",
+	}
+	tests := []struct {
+		name          string
+		params        map[string]string
+		expectedPatch []jsonPatchOperation
+	}{
+		{
+			name:   "NoParams",
+			params: nil,
+			expectedPatch: []jsonPatchOperation{
+				{Op: "add", Path: "/parameters", Value: map[string]string{
+					"csi.storage.k8s.io/fstype": "xfs",
+					"storagepolicyname":         "gold",
+				}},
+			},
+		},
+		{
+			name:   "FsTypeSet",
+			params: map[string]string{"fstype": "ext4"},
+			expectedPatch: []jsonPatchOperation{
+				{Op: "add", Path: "/parameters/storagepolicyname", Value: "gold"},
+			},
+		},
+		{
+			name:   "DatastoreSet",
+			params: map[string]string{"datastoreURL": "ds:///vmfs/volumes/datastore1/"},
+			expectedPatch: []jsonPatchOperation{
+				{Op: "add", Path: "/parameters/csi.storage.k8s.io~1fstype", Value: "xfs"},
+			},
+		},
+		{
+			name: "AllSet",
+			params: map[string]string{
+				"csi.storage.k8s.io/fstype": "ext4",
+				"storagePolicyName":         "silver",
+			},
+			expectedPatch: nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			patch := getStorageClassDefaultsPatch(test.params, defaults)
+			if !reflect.DeepEqual(patch, test.expectedPatch) {
+				t.Fatalf("expected patch %+v, got %+v", test.expectedPatch, patch)
+			}
+		})
+	}
+}
@@ -23,6 +23,22 @@ const (
 
 // validatePVC helps validate AdmissionReview requests for PersistentVolumeClaim.
 func validatePVC(ctx context.Context, ar *admissionv1.AdmissionReview) *admissionv1.AdmissionResponse {
+	if ar.Request.Operation == admissionv1.Create || ar.Request.Operation == admissionv1.Update {
+		if containerOrchestratorUtility != nil &&
+			containerOrchestratorUtility.IsFSSEnabled(ctx, common.StorageQuotaValidation) {
+			// PVC expansions are validated against the quota as well.
+			if resp := validatePVCStorageQuota(ctx, ar.Request); !resp.Allowed ||
+				ar.Request.Operation == admissionv1.Create {
+				return resp
+			}
+		}
+		if ar.Request.Operation == admissionv1.Create {
+			return &admissionv1.AdmissionResponse{
+				Allowed: true,
+			}
+		}
+	}
+
 	if containerOrchestratorUtility != nil && !containerOrchestratorUtility.IsFSSEnabled(ctx, common.BlockVolumeSnapshot) {
 		// If CSI block volume snapshot is disabled and webhook is running,
 		// skip validation for PersistentVolumeClaim.
@@ -19,6 +19,8 @@ package admissionhandler
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"strings"
 
 	admissionv1 "k8s.io/api/admission/v1"
 	stroagev1 "k8s.io/api/storage/v1"
@@ -26,6 +28,7 @@ import (
 
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
 )
 
 var (
@@ -40,19 +43,46 @@ var (
 		common.ObjectspacereservationMigrationParam: struct{}{},
 		common.IopslimitMigrationParam:              struct{}{},
 	}
+	supportedParameters = parameterSet{
+		common.AttributeDatastoreURL:       struct{}{},
+		common.AttributeStoragePolicyName:  struct{}{},
+		common.AttributeFsType:             struct{}{},
+		common.AttributeDetachQuiesceDelay: struct{}{},
+		common.AttributeNfsVersion:         struct{}{},
+		common.AttributeDiskController:     struct{}{},
+	}
+	supportedFsTypes = parameterSet{
+		common.Ext3FsType:  struct{}{},
+		common.Ext4FsType:  struct{}{},
+		common.XfsFsType:   struct{}{},
+		common.NfsFsType:   struct{}{},
+		common.NfsV4FsType: struct{}{},
+		common.NTFSFsType:  struct{}{},
+	}
 )
 
 const (
 	volumeExpansionErrorMessage = "AllowVolumeExpansion can not be set to true on the in-tree vSphere StorageClass"
 	migrationParamErrorMessage  = "Invalid StorageClass Parameters. " +
 		"Migration specific parameters should not be used in the StorageClass"
+	invalidParamErrorMessage = "Invalid StorageClass Parameters."
+
+	// csiProvisionerParamPrefix is the prefix of the parameters consumed by
+	// the external-provisioner which are not passed to the driver.
+	csiProvisionerParamPrefix = "csi.storage.k8s.io/"
+	// csiProvisionerFsTypeParam is the external-provisioner fstype parameter.
+	csiProvisionerFsTypeParam = csiProvisionerParamPrefix + "fstype"
 )
 
 // validateStorageClass helps validate AdmissionReview requests for StroageClass.
 func validateStorageClass(ctx context.Context, ar *admissionv1.AdmissionReview) *admissionv1.AdmissionResponse {
-	if containerOrchestratorUtility != nil && !containerOrchestratorUtility.IsFSSEnabled(ctx, common.CSIMigration) {
-		// If CSI migration is disabled and webhook is running,
-		// skip validation for StorageClass.
+	migrationEnabled := containerOrchestratorUtility == nil ||
+		containerOrchestratorUtility.IsFSSEnabled(ctx, common.CSIMigration)
+	paramValidationEnabled := containerOrchestratorUtility == nil ||
+		containerOrchestratorUtility.IsFSSEnabled(ctx, common.StorageClassParamValidation)
+	if !migrationEnabled && !paramValidationEnabled {
+		// If CSI migration and parameter validation are disabled and webhook
+		// is running, skip validation for StorageClass.
 		return &admissionv1.AdmissionResponse{
 			Allowed: true,
 		}
@@ -76,22 +106,32 @@ func validateStorageClass(ctx context.Context, ar *admissionv1.AdmissionReview)
 		}
 		log.Infof("Validating StorageClass: %q", sc.Name)
 		// AllowVolumeExpansion check for kubernetes.io/vsphere-volume provisioner.
-		if sc.Provisioner == "kubernetes.io/vsphere-volume" {
+		if sc.Provisioner == "kubernetes.io/vsphere-volume" && migrationEnabled {
 			if sc.AllowVolumeExpansion != nil && *sc.AllowVolumeExpansion {
 				allowed = false
 				result = &metav1.Status{
 					Reason: volumeExpansionErrorMessage,
 				}
 			}
-		} else if sc.Provisioner == "csi.vsphere.vmware.com" {
-			// Migration parameters check for csi.vsphere.vmware.com provisioner.
-			for param := range sc.Parameters {
-				if unSupportedParameters.Has(param) {
+		} else if sc.Provisioner == csitypes.Name {
+			if migrationEnabled {
+				// Migration parameters check for the vSphere CSI provisioner.
+				for param := range sc.Parameters {
+					if unSupportedParameters.Has(param) {
+						allowed = false
+						result = &metav1.Status{
+							Reason: migrationParamErrorMessage,
+						}
+						break
+					}
+				}
+			}
+			if allowed && paramValidationEnabled {
+				if err := validateStorageClassParams(sc.Parameters); err != nil {
 					allowed = false
 					result = &metav1.Status{
-						Reason: migrationParamErrorMessage,
+						Reason: metav1.StatusReason(fmt.Sprintf("%s %v", invalidParamErrorMessage, err)),
 					}
-					break
 				}
 			}
 		}
@@ -110,3 +150,54 @@ func validateStorageClass(ctx context.Context, ar *admissionv1.AdmissionReview)
 		Result:  result,
 	}
 }
+
+// validateStorageClassParams validates the parameters of a vSphere CSI
+// StorageClass the same way CreateVolume parses them, so that a misconfigured
+// StorageClass is rejected at creation time instead of at first PVC
+// provisioning. Compatibility of the datastore with the storage policy
+// requires vCenter and is still verified during provisioning.
+func validateStorageClassParams(params map[string]string) error {
+	var fsType, provisionerFsType string
+	for param, value := range params {
+		if strings.HasPrefix(param, csiProvisionerParamPrefix) {
+			if param == csiProvisionerFsTypeParam {
+				provisionerFsType = strings.ToLower(value)
+			}
+			continue
+		}
+		param = strings.ToLower(param)
+		if !supportedParameters.Has(param) {
+			return fmt.Errorf("unknown parameter %q", param)
+		}
+		switch param {
+		case common.AttributeDatastoreURL, common.AttributeStoragePolicyName:
+			if strings.TrimSpace(value) == "" {
+				return fmt.Errorf("parameter %q can not be empty", param)
+			}
+		case common.AttributeFsType:
+			fsType = strings.ToLower(value)
+		case common.AttributeDetachQuiesceDelay:
+			if _, err := common.ParseDetachQuiesceDelay(value); err != nil {
+				return err
+			}
+		case common.AttributeNfsVersion:
+			if _, err := common.ParseNfsVersion(value); err != nil {
+				return err
+			}
+		case common.AttributeDiskController:
+			if _, err := common.ParseDiskController(value); err != nil {
+				return err
+			}
+		}
+	}
+	for _, value := range []string{fsType, provisionerFsType} {
+		if value != "" && !supportedFsTypes.Has(value) {
+			return fmt.Errorf("unsupported fstype %q", value)
+		}
+	}
+	if fsType != "" && provisionerFsType != "" && fsType != provisionerFsType {
+		return fmt.Errorf("conflicting values %q and %q for parameters %q and %q",
+			fsType, provisionerFsType, common.AttributeFsType, csiProvisionerFsTypeParam)
+	}
+	return nil
+}
@@ -116,3 +116,85 @@ func TestValidateStorageClassForValidStorageClass(t *testing.T) {
 	}
 	t.Log("TestValidateStorageClassForValidStorageClass Passed")
 }
+
+// TestValidateStorageClassParams is the unit test for validating parameters
+// of a vSphere CSI StorageClass.
+func TestValidateStorageClassParams(t *testing.T) {
+	tests := []struct {
+		name      string
+		params    map[string]string
+		expectErr bool
+	}{
+		{
+			name: "ValidParams",
+			params: map[string]string{
+				"storagePolicyName":         "vSAN Default Storage Policy",
+				"datastoreURL":              "ds:///vmfs/volumes/vsan:52cdfa80721ff516-ea1e993113acfc77/",
+				"csi.storage.k8s.io/fstype": "xfs",
+			},
+		},
+		{
+			name:      "UnknownParam",
+			params:    map[string]string{"storagepolicy": "vSAN Default Storage Policy"},
+			expectErr: true,
+		},
+		{
+			name:      "EmptyDatastoreURL",
+			params:    map[string]string{"datastoreurl": " "},
+			expectErr: true,
+		},
+		{
+			name:      "InvalidFsType",
+			params:    map[string]string{"csi.storage.k8s.io/fstype": "btrfs"},
+			expectErr: true,
+		},
+		{
+			name:      "ConflictingFsType",
+			params:    map[string]string{"fstype": "ext4", "csi.storage.k8s.io/fstype": "xfs"},
+			expectErr: true,
+		},
+		{
+			name:   "ValidDetachQuiesceDelay",
+			params: map[string]string{"detachQuiesceDelay": "30"},
+		},
+		{
+			name:      "InvalidDetachQuiesceDelay",
+			params:    map[string]string{"detachquiescedelay": "-1"},
+			expectErr: true,
+		},
+		{
+			name:      "TooLongDetachQuiesceDelay",
+			params:    map[string]string{"detachquiescedelay": "300"},
+			expectErr: true,
+		},
+		{
+			name:   "ValidNfsVersion",
+			params: map[string]string{"nfsVersion": "auto"},
+		},
+		{
+			name:      "InvalidNfsVersion",
+			params:    map[string]string{"nfsversion": "4.2"},
+			expectErr: true,
+		},
+		{
+			name:   "ValidDiskController",
+			params: map[string]string{"diskController": "NVMe"},
+		},
+		{
+			name:      "InvalidDiskController",
+			params:    map[string]string{"diskcontroller": "lsilogic"},
+			expectErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateStorageClassParams(test.params)
+			if test.expectErr && err == nil {
+				t.Fatalf("expected error for params %v", test.params)
+			}
+			if !test.expectErr && err != nil {
+				t.Fatalf("unexpected error for params %v: %v", test.params, err)
+			}
+		})
+	}
+}
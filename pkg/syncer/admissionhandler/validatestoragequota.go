@@ -0,0 +1,259 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admissionhandler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+	cnsstoragequotav1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/internalapis/cnsstoragequota/v1alpha1"
+	k8s "sigs.k8s.io/vsphere-csi-driver/v2/pkg/kubernetes"
+)
+
+const (
+	StorageQuotaExceededErrorMessage = "Requested storage exceeds the CnsStorageQuota"
+	// storageQuotaExceededEventReason is the reason of the event recorded on
+	// the CnsStorageQuota when a PVC request is rejected.
+	storageQuotaExceededEventReason = "StorageQuotaExceeded"
+	webhookEventSourceComponent     = "vsphere-csi-webhook"
+	// isDefaultStorageClassAnnotation marks the default StorageClass used by
+	// the PVCs which don't set a StorageClass name.
+	isDefaultStorageClassAnnotation = "storageclass.kubernetes.io/is-default-class"
+)
+
+// validatePVCStorageQuota validates a PVC create or expansion request against
+// the CnsStorageQuota instances defined in the namespace of the PVC, so that
+// requests exceeding the quota fail early instead of during provisioning.
+func validatePVCStorageQuota(ctx context.Context, req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	log := logger.GetLogger(ctx)
+	pvc := corev1.PersistentVolumeClaim{}
+	if err := json.Unmarshal(req.Object.Raw, &pvc); err != nil {
+		log.Warnf("error deserializing pvc: %v. skipping storage quota validation.", err)
+		return &admissionv1.AdmissionResponse{
+			Allowed: true,
+		}
+	}
+	if req.Operation == admissionv1.Update {
+		oldPVC := corev1.PersistentVolumeClaim{}
+		if err := json.Unmarshal(req.OldObject.Raw, &oldPVC); err != nil {
+			log.Warnf("error deserializing old pvc: %v. skipping storage quota validation.", err)
+			return &admissionv1.AdmissionResponse{
+				Allowed: true,
+			}
+		}
+		oldReq := oldPVC.Spec.Resources.Requests[corev1.ResourceStorage]
+		newReq := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+		if newReq.Cmp(oldReq) <= 0 {
+			// Only expansions consume more of the quota.
+			return &admissionv1.AdmissionResponse{
+				Allowed: true,
+			}
+		}
+	}
+	if pvc.Spec.StorageClassName != nil && *pvc.Spec.StorageClassName == "" {
+		log.Debugf("pvc %s/%s has no StorageClass. skipping storage quota validation.", pvc.Namespace, pvc.Name)
+		return &admissionv1.AdmissionResponse{
+			Allowed: true,
+		}
+	}
+	kubeClient, err := k8s.NewClient(ctx)
+	if err != nil {
+		log.Warnf("failed to get kube client: %v. skipping storage quota validation.", err)
+		return &admissionv1.AdmissionResponse{
+			Allowed: true,
+		}
+	}
+	vsphereStorageClasses, defaultStorageClass, err := getVSphereStorageClasses(ctx, kubeClient)
+	if err != nil {
+		log.Warnf("failed to list StorageClasses: %v. skipping storage quota validation.", err)
+		return &admissionv1.AdmissionResponse{
+			Allowed: true,
+		}
+	}
+	if !vsphereStorageClasses.Has(getPVCStorageClassName(&pvc, defaultStorageClass)) {
+		// PVC is not provisioned by vSphere CSI driver.
+		return &admissionv1.AdmissionResponse{
+			Allowed: true,
+		}
+	}
+	quotas, err := getStorageQuotas(ctx, pvc.Namespace)
+	if err != nil {
+		log.Warnf("failed to list CnsStorageQuota in namespace %q: %v. skipping storage quota validation.",
+			pvc.Namespace, err)
+		return &admissionv1.AdmissionResponse{
+			Allowed: true,
+		}
+	}
+	if len(quotas) == 0 {
+		return &admissionv1.AdmissionResponse{
+			Allowed: true,
+		}
+	}
+	pvcList, err := kubeClient.CoreV1().PersistentVolumeClaims(pvc.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		log.Warnf("failed to list PVCs in namespace %q: %v. skipping storage quota validation.",
+			pvc.Namespace, err)
+		return &admissionv1.AdmissionResponse{
+			Allowed: true,
+		}
+	}
+	quota, used := getExceededStorageQuota(quotas, pvcList.Items, vsphereStorageClasses, defaultStorageClass, &pvc)
+	if quota == nil {
+		return &admissionv1.AdmissionResponse{
+			Allowed: true,
+		}
+	}
+	requested := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	message := fmt.Sprintf("%s %s/%s. Requested: %s, used: %s, limit: %s", StorageQuotaExceededErrorMessage,
+		quota.Namespace, quota.Name, requested.String(), used.String(), quota.Spec.Limit.String())
+	log.Infof("Rejecting pvc %s/%s. %s", pvc.Namespace, pvc.Name, message)
+	recordStorageQuotaExceededEvent(ctx, kubeClient, quota, fmt.Sprintf("PVC %s/%s rejected. %s",
+		pvc.Namespace, pvc.Name, message))
+	return &admissionv1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Reason: metav1.StatusReason(message),
+		},
+	}
+}
+
+// getExceededStorageQuota returns the first quota which would be exceeded by
+// the given PVC, along with the storage already used against that quota. The
+// existing PVC of the same name is replaced by the given one, so that an
+// expansion is only charged for the size it adds. PVCs without a
+// StorageClass name use the given default StorageClass.
+func getExceededStorageQuota(quotas []cnsstoragequotav1alpha1.CnsStorageQuota,
+	pvcs []corev1.PersistentVolumeClaim, vsphereStorageClasses parameterSet, defaultStorageClass string,
+	pvc *corev1.PersistentVolumeClaim) (*cnsstoragequotav1alpha1.CnsStorageQuota, resource.Quantity) {
+	requested := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	pvcStorageClass := getPVCStorageClassName(pvc, defaultStorageClass)
+	for i := range quotas {
+		quota := &quotas[i]
+		if quota.Spec.StorageClassName != "" && quota.Spec.StorageClassName != pvcStorageClass {
+			continue
+		}
+		var used resource.Quantity
+		for j := range pvcs {
+			existing := &pvcs[j]
+			if existing.Name == pvc.Name {
+				continue
+			}
+			scName := getPVCStorageClassName(existing, defaultStorageClass)
+			if !vsphereStorageClasses.Has(scName) ||
+				(quota.Spec.StorageClassName != "" && quota.Spec.StorageClassName != scName) {
+				continue
+			}
+			used.Add(existing.Spec.Resources.Requests[corev1.ResourceStorage])
+		}
+		total := used.DeepCopy()
+		total.Add(requested)
+		if total.Cmp(quota.Spec.Limit) > 0 {
+			return quota, used
+		}
+	}
+	return nil, resource.Quantity{}
+}
+
+// getVSphereStorageClasses returns the names of the StorageClasses which use
+// the vSphere CSI driver as the provisioner, and the name of the default
+// StorageClass of the cluster, if any.
+func getVSphereStorageClasses(ctx context.Context, kubeClient clientset.Interface) (parameterSet, string, error) {
+	scList, err := kubeClient.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, "", err
+	}
+	storageClasses := make(parameterSet)
+	var defaultStorageClass string
+	for _, sc := range scList.Items {
+		if sc.Provisioner == csitypes.Name {
+			storageClasses[sc.Name] = struct{}{}
+		}
+		if sc.Annotations[isDefaultStorageClassAnnotation] == "true" {
+			defaultStorageClass = sc.Name
+		}
+	}
+	return storageClasses, defaultStorageClass, nil
+}
+
+// getPVCStorageClassName returns the StorageClass name of the given PVC, or
+// the given default StorageClass if the PVC doesn't set one.
+func getPVCStorageClassName(pvc *corev1.PersistentVolumeClaim, defaultStorageClass string) string {
+	if pvc.Spec.StorageClassName == nil {
+		return defaultStorageClass
+	}
+	return *pvc.Spec.StorageClassName
+}
+
+// getStorageQuotas returns the CnsStorageQuota instances in the given
+// namespace.
+func getStorageQuotas(ctx context.Context, namespace string) ([]cnsstoragequotav1alpha1.CnsStorageQuota, error) {
+	restConfig, err := k8s.GetKubeConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cnsClient, err := k8s.NewClientForGroup(ctx, restConfig, cnsstoragequotav1alpha1.GroupName)
+	if err != nil {
+		return nil, err
+	}
+	quotaList := &cnsstoragequotav1alpha1.CnsStorageQuotaList{}
+	err = cnsClient.List(ctx, quotaList, client.InNamespace(namespace))
+	if err != nil {
+		return nil, err
+	}
+	return quotaList.Items, nil
+}
+
+// recordStorageQuotaExceededEvent records a warning event on the given
+// CnsStorageQuota. Failures are only logged as the event is informational.
+func recordStorageQuotaExceededEvent(ctx context.Context, kubeClient clientset.Interface,
+	quota *cnsstoragequotav1alpha1.CnsStorageQuota, message string) {
+	log := logger.GetLogger(ctx)
+	now := metav1.Now()
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: quota.Name + "-",
+			Namespace:    quota.Namespace,
+		},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion: cnsstoragequotav1alpha1.SchemeGroupVersion.String(),
+			Kind:       "CnsStorageQuota",
+			Name:       quota.Name,
+			Namespace:  quota.Namespace,
+			UID:        quota.UID,
+		},
+		Reason:         storageQuotaExceededEventReason,
+		Message:        message,
+		Type:           corev1.EventTypeWarning,
+		Source:         corev1.EventSource{Component: webhookEventSourceComponent},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+	if _, err := kubeClient.CoreV1().Events(quota.Namespace).Create(ctx, event, metav1.CreateOptions{}); err != nil {
+		log.Warnf("failed to record event on CnsStorageQuota %s/%s: %v", quota.Namespace, quota.Name, err)
+	}
+}
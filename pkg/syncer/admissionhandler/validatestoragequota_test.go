@@ -0,0 +1,129 @@
+package admissionhandler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cnsstoragequotav1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/internalapis/cnsstoragequota/v1alpha1"
+)
+
+func newTestPVC(name string, storageClassName string, size string) corev1.PersistentVolumeClaim {
+	return corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: testNamespace,
+			Name:      name,
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			StorageClassName: &storageClassName,
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: resource.MustParse(size),
+				},
+			},
+		},
+	}
+}
+
+func newTestStorageQuota(storageClassName string, limit string) cnsstoragequotav1alpha1.CnsStorageQuota {
+	return cnsstoragequotav1alpha1.CnsStorageQuota{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: testNamespace,
+			Name:      "test-quota",
+		},
+		Spec: cnsstoragequotav1alpha1.CnsStorageQuotaSpec{
+			StorageClassName: storageClassName,
+			Limit:            resource.MustParse(limit),
+		},
+	}
+}
+
+func TestGetExceededStorageQuota(t *testing.T) {
+	otherStorageClassName := "other-sc"
+	vsphereStorageClasses := parameterSet{testStorageClassName: struct{}{}, otherStorageClassName: struct{}{}}
+	existingPVCs := []corev1.PersistentVolumeClaim{
+		newTestPVC("pvc-1", testStorageClassName, "5Gi"),
+		newTestPVC("pvc-2", otherStorageClassName, "5Gi"),
+		newTestPVC("pvc-3", "non-vsphere-sc", "50Gi"),
+	}
+	defaultSCPVC := newTestPVC(testFirstPVCName, "", "10Gi")
+	defaultSCPVC.Spec.StorageClassName = nil
+	tests := []struct {
+		name                string
+		quotas              []cnsstoragequotav1alpha1.CnsStorageQuota
+		pvc                 corev1.PersistentVolumeClaim
+		defaultStorageClass string
+		expectExceed        bool
+		expectedUsed        string
+	}{
+		{
+			name:   "TestNamespaceQuotaNotExceeded",
+			quotas: []cnsstoragequotav1alpha1.CnsStorageQuota{newTestStorageQuota("", "20Gi")},
+			pvc:    newTestPVC(testFirstPVCName, testStorageClassName, "10Gi"),
+		},
+		{
+			name:         "TestNamespaceQuotaExceeded",
+			quotas:       []cnsstoragequotav1alpha1.CnsStorageQuota{newTestStorageQuota("", "15Gi")},
+			pvc:          newTestPVC(testFirstPVCName, testStorageClassName, "10Gi"),
+			expectExceed: true,
+			expectedUsed: "10Gi",
+		},
+		{
+			name:   "TestStorageClassQuotaNotExceeded",
+			quotas: []cnsstoragequotav1alpha1.CnsStorageQuota{newTestStorageQuota(testStorageClassName, "15Gi")},
+			pvc:    newTestPVC(testFirstPVCName, testStorageClassName, "10Gi"),
+		},
+		{
+			name:         "TestStorageClassQuotaExceeded",
+			quotas:       []cnsstoragequotav1alpha1.CnsStorageQuota{newTestStorageQuota(testStorageClassName, "12Gi")},
+			pvc:          newTestPVC(testFirstPVCName, testStorageClassName, "10Gi"),
+			expectExceed: true,
+			expectedUsed: "5Gi",
+		},
+		{
+			name:   "TestQuotaForOtherStorageClassIgnored",
+			quotas: []cnsstoragequotav1alpha1.CnsStorageQuota{newTestStorageQuota(otherStorageClassName, "1Gi")},
+			pvc:    newTestPVC(testFirstPVCName, testStorageClassName, "10Gi"),
+		},
+		{
+			name:   "TestExpansionChargedForAddedSize",
+			quotas: []cnsstoragequotav1alpha1.CnsStorageQuota{newTestStorageQuota("", "20Gi")},
+			pvc:    newTestPVC("pvc-1", testStorageClassName, "12Gi"),
+		},
+		{
+			name:         "TestExpansionExceeded",
+			quotas:       []cnsstoragequotav1alpha1.CnsStorageQuota{newTestStorageQuota("", "15Gi")},
+			pvc:          newTestPVC("pvc-1", testStorageClassName, "12Gi"),
+			expectExceed: true,
+			expectedUsed: "5Gi",
+		},
+		{
+			name:                "TestDefaultStorageClassQuotaExceeded",
+			quotas:              []cnsstoragequotav1alpha1.CnsStorageQuota{newTestStorageQuota(testStorageClassName, "12Gi")},
+			pvc:                 defaultSCPVC,
+			defaultStorageClass: testStorageClassName,
+			expectExceed:        true,
+			expectedUsed:        "5Gi",
+		},
+		{
+			name:   "TestNoDefaultStorageClass",
+			quotas: []cnsstoragequotav1alpha1.CnsStorageQuota{newTestStorageQuota(testStorageClassName, "12Gi")},
+			pvc:    defaultSCPVC,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			quota, used := getExceededStorageQuota(test.quotas, existingPVCs, vsphereStorageClasses,
+				test.defaultStorageClass, &test.pvc)
+			if !test.expectExceed {
+				assert.Nil(t, quota)
+				return
+			}
+			assert.NotNil(t, quota)
+			assert.Equal(t, 0, used.Cmp(resource.MustParse(test.expectedUsed)))
+		})
+	}
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/clock"
+)
+
+// syncerClock is the time source of the periodic tasks, polls and timestamps
+// of the syncer. Tests replace it with a clock.FakeClock to control time.
+var syncerClock clock.Clock = clock.RealClock{}
+
+// runPeriodically runs task right away, then every interval of the given
+// clock until stopCh is closed.
+func runPeriodically(clk clock.Clock, interval time.Duration, stopCh <-chan struct{}, task func()) {
+	runPeriodicallyUntilDone(clk, interval, stopCh, func() bool {
+		task()
+		return false
+	})
+}
+
+// runPeriodicallyUntilDone runs task right away, then every interval of the
+// given clock until it returns true, or stopCh is closed.
+func runPeriodicallyUntilDone(clk clock.Clock, interval time.Duration, stopCh <-chan struct{}, task func() bool) {
+	ticker := clk.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if task() {
+			return
+		}
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C():
+		}
+	}
+}
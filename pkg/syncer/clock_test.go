@@ -0,0 +1,64 @@
+package syncer
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/clock"
+)
+
+func TestRunPeriodically(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Now())
+	stopCh := make(chan struct{})
+	runs := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runPeriodically(fakeClock, time.Minute, stopCh, func() {
+			runs <- struct{}{}
+		})
+	}()
+
+	// The task runs right away, then once per interval.
+	<-runs
+	for i := 0; i < 3; i++ {
+		fakeClock.Step(time.Minute)
+		select {
+		case <-runs:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("expected the task to run after %d intervals", i+1)
+		}
+	}
+	fakeClock.Step(59 * time.Second)
+	select {
+	case <-runs:
+		t.Fatal("expected the task not to run before the interval elapsed")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(stopCh)
+	<-done
+}
+
+func TestRunPeriodicallyUntilDone(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Now())
+	runs := make(chan int)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		run := 0
+		runPeriodicallyUntilDone(fakeClock, time.Minute, make(chan struct{}), func() bool {
+			run++
+			runs <- run
+			return run == 2
+		})
+	}()
+
+	<-runs
+	fakeClock.Step(time.Minute)
+	if run := <-runs; run != 2 {
+		t.Fatalf("expected run 2, got %d", run)
+	}
+	// The task stops running once it returned true.
+	<-done
+}
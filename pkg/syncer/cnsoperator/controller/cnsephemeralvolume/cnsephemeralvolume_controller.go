@@ -0,0 +1,433 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cnsephemeralvolume
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	apis "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator"
+	cnsephemeralvolumev1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsephemeralvolume/v1alpha1"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/node"
+	volumes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/volume"
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	commonconfig "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common/commonco"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	k8s "sigs.k8s.io/vsphere-csi-driver/v2/pkg/kubernetes"
+	cnsoperatortypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/syncer/cnsoperator/types"
+)
+
+const (
+	defaultMaxWorkerThreadsForEphemeralVolume = 10
+)
+
+// backOffDuration is a map of cnsephemeralvolume name's to the time after
+// which a request for this instance will be requeued.
+// Initialized to 1 second for new instances and for instances whose latest
+// reconcile operation succeeded.
+// If the reconcile fails, backoff is incremented exponentially.
+var (
+	backOffDuration         map[string]time.Duration
+	backOffDurationMapMutex = sync.Mutex{}
+)
+
+// nodeManager looks up the VMs of the nodes ephemeral inline volumes are
+// attached to.
+type nodeManager interface {
+	GetNodeByName(ctx context.Context, nodeName string) (*cnsvsphere.VirtualMachine, error)
+}
+
+// Add creates a new CnsEphemeralVolume Controller and adds it to the Manager,
+// ConfigurationInfo and VirtualCenterTypes. The Manager will set fields on
+// the Controller and Start it when the Manager is Started.
+func Add(mgr manager.Manager, clusterFlavor cnstypes.CnsClusterFlavor,
+	configInfo *commonconfig.ConfigurationInfo, volumeManager volumes.Manager) error {
+	ctx, log := logger.GetNewContextWithLogger()
+	if clusterFlavor != cnstypes.CnsClusterFlavorVanilla {
+		log.Debug("Not initializing the CnsEphemeralVolume Controller as its a non-Vanilla CSI deployment")
+		return nil
+	}
+	if !commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.EphemeralInlineVolume) {
+		log.Debug("Not initializing the CnsEphemeralVolume Controller as ephemeral inline volumes are disabled")
+		return nil
+	}
+	// Initializes kubernetes client.
+	k8sclient, err := k8s.NewClient(ctx)
+	if err != nil {
+		log.Errorf("Creating Kubernetes client failed. Err: %v", err)
+		return err
+	}
+
+	// eventBroadcaster broadcasts events on cnsephemeralvolume instances to
+	// the event sink.
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(
+		&typedcorev1.EventSinkImpl{
+			Interface: k8sclient.CoreV1().Events(""),
+		},
+	)
+	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: apis.GroupName})
+	return add(mgr, &ReconcileCnsEphemeralVolume{client: mgr.GetClient(), scheme: mgr.GetScheme(),
+		configInfo: configInfo, volumeManager: volumeManager, nodeManager: node.GetManager(ctx),
+		recorder: recorder})
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler.
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	_, log := logger.GetNewContextWithLogger()
+
+	// Create a new controller.
+	c, err := controller.New("cnsephemeralvolume-controller", mgr,
+		controller.Options{Reconciler: r, MaxConcurrentReconciles: defaultMaxWorkerThreadsForEphemeralVolume})
+	if err != nil {
+		log.Errorf("Failed to create new CnsEphemeralVolume controller with error: %+v", err)
+		return err
+	}
+
+	backOffDuration = make(map[string]time.Duration)
+
+	// Watch for changes to primary resource CnsEphemeralVolume.
+	err = c.Watch(&source.Kind{Type: &cnsephemeralvolumev1alpha1.CnsEphemeralVolume{}},
+		&handler.EnqueueRequestForObject{})
+	if err != nil {
+		log.Errorf("Failed to watch for changes to CnsEphemeralVolume resource with error: %+v", err)
+		return err
+	}
+	return nil
+}
+
+// blank assignment to verify that ReconcileCnsEphemeralVolume implements
+// reconcile.Reconciler.
+var _ reconcile.Reconciler = &ReconcileCnsEphemeralVolume{}
+
+// ReconcileCnsEphemeralVolume reconciles a CnsEphemeralVolume object.
+type ReconcileCnsEphemeralVolume struct {
+	// This client, initialized using mgr.Client() above, is a split client
+	// that reads objects from the cache and writes to the apiserver.
+	client        client.Client
+	scheme        *runtime.Scheme
+	configInfo    *commonconfig.ConfigurationInfo
+	volumeManager volumes.Manager
+	nodeManager   nodeManager
+	recorder      record.EventRecorder
+}
+
+// Reconcile reads that state of the cluster for a CnsEphemeralVolume object,
+// creates the volume described by CnsEphemeralVolume.Spec and attaches it to
+// the node in CnsEphemeralVolume.Spec. The volume is detached and deleted
+// once the CnsEphemeralVolume instance is deleted.
+// Note:
+// The Controller will requeue the Request to be processed again if the
+// returned error is non-nil or Result.Requeue is true. Otherwise, upon
+// completion it will remove the work from the queue.
+func (r *ReconcileCnsEphemeralVolume) Reconcile(ctx context.Context,
+	request reconcile.Request) (reconcile.Result, error) {
+	log := logger.GetLogger(ctx)
+	// Fetch the CnsEphemeralVolume instance.
+	instance := &cnsephemeralvolumev1alpha1.CnsEphemeralVolume{}
+	err := r.client.Get(ctx, request.NamespacedName, instance)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Infof("CnsEphemeralVolume resource not found. Ignoring since object must be deleted.")
+			return reconcile.Result{}, nil
+		}
+		log.Errorf("Error reading the CnsEphemeralVolume with name: %q. Err: %+v", request.Name, err)
+		// Error reading the object - return with err.
+		return reconcile.Result{}, err
+	}
+	// Initialize backOffDuration for the instance, if required.
+	backOffDurationMapMutex.Lock()
+	var timeout time.Duration
+	if _, exists := backOffDuration[instance.Name]; !exists {
+		backOffDuration[instance.Name] = time.Second
+	}
+	timeout = backOffDuration[instance.Name]
+	backOffDurationMapMutex.Unlock()
+
+	if instance.DeletionTimestamp != nil {
+		if !hasFinalizer(instance) {
+			return reconcile.Result{}, nil
+		}
+		log.Infof("Deleting volume: %q of CnsEphemeralVolume: %q", instance.Status.VolumeID, instance.Name)
+		if err := r.deleteVolume(ctx, instance); err != nil {
+			setInstanceError(ctx, r, instance, err.Error())
+			return reconcile.Result{RequeueAfter: timeout}, nil
+		}
+		removeFinalizer(instance)
+		if err := updateCnsEphemeralVolume(ctx, r.client, instance); err != nil {
+			return reconcile.Result{RequeueAfter: timeout}, nil
+		}
+		backOffDurationMapMutex.Lock()
+		delete(backOffDuration, instance.Name)
+		backOffDurationMapMutex.Unlock()
+		log.Infof("Deleted volume: %q of CnsEphemeralVolume: %q", instance.Status.VolumeID, instance.Name)
+		return reconcile.Result{}, nil
+	}
+
+	// If the volume of the CnsEphemeralVolume instance is already attached,
+	// remove the instance from the queue.
+	if instance.Status.Attached {
+		backOffDurationMapMutex.Lock()
+		delete(backOffDuration, instance.Name)
+		backOffDurationMapMutex.Unlock()
+		return reconcile.Result{}, nil
+	}
+
+	log.Infof("Reconciling CnsEphemeralVolume with instance: %q. timeout %q seconds", instance.Name, timeout)
+	// Add the finalizer before creating the volume, so that the volume gets
+	// deleted along with the instance.
+	if !hasFinalizer(instance) {
+		instance.Finalizers = append(instance.Finalizers, cnsoperatortypes.CNSFinalizer)
+		if err := updateCnsEphemeralVolume(ctx, r.client, instance); err != nil {
+			return reconcile.Result{RequeueAfter: timeout}, nil
+		}
+	}
+	nodeVM, err := r.nodeManager.GetNodeByName(ctx, instance.Spec.NodeName)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to find the VM of node: %q with error: %+v", instance.Spec.NodeName, err)
+		log.Error(msg)
+		setInstanceError(ctx, r, instance, msg)
+		return reconcile.Result{RequeueAfter: timeout}, nil
+	}
+	vc, err := cnsvsphere.GetVirtualCenterInstance(ctx, r.configInfo, false)
+	if err != nil {
+		log.Errorf("Failed to get virtual center instance with error: %+v", err)
+		setInstanceError(ctx, r, instance, "Unable to connect to VC to provision the ephemeral volume")
+		return reconcile.Result{RequeueAfter: timeout}, nil
+	}
+	cnsManager := &common.Manager{
+		VcenterConfig:  vc.Config,
+		CnsConfig:      r.configInfo.Cfg,
+		VolumeManager:  r.volumeManager,
+		VcenterManager: cnsvsphere.GetVirtualCenterManager(ctx),
+	}
+
+	if instance.Status.VolumeID == "" {
+		volumeID, err := r.createVolume(ctx, cnsManager, nodeVM, instance)
+		if err != nil {
+			log.Error(err.Error())
+			setInstanceError(ctx, r, instance, err.Error())
+			return reconcile.Result{RequeueAfter: timeout}, nil
+		}
+		// Record the volume right away, so that it gets deleted along with
+		// the instance even if attaching it fails.
+		instance.Status.VolumeID = volumeID
+		if err := updateCnsEphemeralVolume(ctx, r.client, instance); err != nil {
+			return reconcile.Result{RequeueAfter: timeout}, nil
+		}
+	}
+
+	diskUUID, _, err := common.AttachVolumeUtil(ctx, cnsManager, nodeVM, instance.Status.VolumeID, false)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to attach volume: %q to node: %q with error: %+v", instance.Status.VolumeID,
+			instance.Spec.NodeName, err)
+		log.Error(msg)
+		setInstanceError(ctx, r, instance, msg)
+		return reconcile.Result{RequeueAfter: timeout}, nil
+	}
+
+	// Update the instance to indicate the volume is attached.
+	instance.Status.Attached = true
+	instance.Status.DiskUUID = common.FormatDiskUUID(diskUUID)
+	instance.Status.Error = ""
+	if err := updateCnsEphemeralVolume(ctx, r.client, instance); err != nil {
+		return reconcile.Result{RequeueAfter: timeout}, nil
+	}
+	msg := fmt.Sprintf("Successfully attached volume: %q to node: %q", instance.Status.VolumeID,
+		instance.Spec.NodeName)
+	recordEvent(ctx, r, instance, v1.EventTypeNormal, msg)
+	backOffDurationMapMutex.Lock()
+	delete(backOffDuration, instance.Name)
+	backOffDurationMapMutex.Unlock()
+	log.Info(msg)
+	return reconcile.Result{}, nil
+}
+
+// createVolume creates the CNS volume of the given CnsEphemeralVolume
+// instance on the datastores accessible to its node, and returns its ID. The
+// volume created by a previous reconcile which failed to record it is
+// returned if any.
+func (r *ReconcileCnsEphemeralVolume) createVolume(ctx context.Context, cnsManager *common.Manager,
+	nodeVM *cnsvsphere.VirtualMachine, instance *cnsephemeralvolumev1alpha1.CnsEphemeralVolume) (string, error) {
+	log := logger.GetLogger(ctx)
+	spec, err := getCreateVolumeSpec(ctx, instance)
+	if err != nil {
+		return "", err
+	}
+	queryResult, err := r.volumeManager.QueryAllVolume(ctx, cnstypes.CnsQueryFilter{Names: []string{spec.Name}},
+		cnstypes.CnsQuerySelection{})
+	if err != nil {
+		return "", fmt.Errorf("failed to query volume: %q with error: %w", spec.Name, err)
+	}
+	if len(queryResult.Volumes) > 0 {
+		log.Infof("Found volume: %q created for CnsEphemeralVolume: %q", queryResult.Volumes[0].VolumeId.Id,
+			instance.Name)
+		return queryResult.Volumes[0].VolumeId.Id, nil
+	}
+	datastores, err := nodeVM.GetAllAccessibleDatastores(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the datastores accessible to node: %q with error: %w",
+			instance.Spec.NodeName, err)
+	}
+	volumeInfo, _, err := common.CreateBlockVolumeUtil(ctx, cnstypes.CnsClusterFlavorVanilla, cnsManager, spec,
+		datastores)
+	if err != nil {
+		return "", fmt.Errorf("failed to create volume: %q with error: %w", spec.Name, err)
+	}
+	log.Infof("Created volume: %q for CnsEphemeralVolume: %q", volumeInfo.VolumeID.Id, instance.Name)
+	return volumeInfo.VolumeID.Id, nil
+}
+
+// deleteVolume detaches the volume of the given CnsEphemeralVolume instance
+// from its node, if the node VM still exists, and deletes the volume.
+func (r *ReconcileCnsEphemeralVolume) deleteVolume(ctx context.Context,
+	instance *cnsephemeralvolumev1alpha1.CnsEphemeralVolume) error {
+	volumeID := instance.Status.VolumeID
+	if volumeID == "" {
+		return nil
+	}
+	if _, err := common.QueryVolumeByID(ctx, r.volumeManager, volumeID); err != nil {
+		if errors.Is(err, common.ErrNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to query volume: %q with error: %w", volumeID, err)
+	}
+	nodeVM, err := r.nodeManager.GetNodeByName(ctx, instance.Spec.NodeName)
+	if err == nil {
+		if _, err := r.volumeManager.DetachVolume(ctx, nodeVM, volumeID); err != nil {
+			return fmt.Errorf("failed to detach volume: %q from node: %q with error: %w", volumeID,
+				instance.Spec.NodeName, err)
+		}
+	} else if err != node.ErrNodeNotFound {
+		return fmt.Errorf("failed to find the VM of node: %q with error: %w", instance.Spec.NodeName, err)
+	}
+	if _, err := common.DeleteVolumeUtil(ctx, r.volumeManager, volumeID, true); err != nil {
+		return fmt.Errorf("failed to delete volume: %q with error: %w", volumeID, err)
+	}
+	return nil
+}
+
+// getCreateVolumeSpec returns the spec of the block volume of the given
+// CnsEphemeralVolume instance.
+func getCreateVolumeSpec(ctx context.Context,
+	instance *cnsephemeralvolumev1alpha1.CnsEphemeralVolume) (*common.CreateVolumeSpec, error) {
+	scParams, err := common.ParseStorageClassParams(ctx, instance.Spec.Parameters, false)
+	if err != nil {
+		return nil, fmt.Errorf("invalid parameters of CnsEphemeralVolume: %q with error: %w", instance.Name, err)
+	}
+	if scParams.DatastoreURL != "" {
+		return nil, fmt.Errorf("the datastore of ephemeral volumes can't be set, got datastoreurl: %q",
+			scParams.DatastoreURL)
+	}
+	capacityInBytes := instance.Spec.CapacityInBytes
+	if capacityInBytes == 0 {
+		capacityInBytes = common.DefaultGbDiskSize * common.GbInBytes
+	}
+	return &common.CreateVolumeSpec{
+		Name:       common.EphemeralVolumeNamePrefix + instance.Name,
+		ScParams:   scParams,
+		CapacityMB: common.RoundUpSize(capacityInBytes, common.MbInBytes),
+		VolumeType: common.BlockVolumeType,
+	}, nil
+}
+
+// hasFinalizer returns whether the CNS finalizer is set on the given
+// CnsEphemeralVolume instance.
+func hasFinalizer(instance *cnsephemeralvolumev1alpha1.CnsEphemeralVolume) bool {
+	for _, finalizer := range instance.Finalizers {
+		if finalizer == cnsoperatortypes.CNSFinalizer {
+			return true
+		}
+	}
+	return false
+}
+
+// removeFinalizer removes the CNS finalizer from the given CnsEphemeralVolume
+// instance.
+func removeFinalizer(instance *cnsephemeralvolumev1alpha1.CnsEphemeralVolume) {
+	for i, finalizer := range instance.Finalizers {
+		if finalizer == cnsoperatortypes.CNSFinalizer {
+			instance.Finalizers = append(instance.Finalizers[:i], instance.Finalizers[i+1:]...)
+			return
+		}
+	}
+}
+
+// setInstanceError sets error and records an event on the CnsEphemeralVolume
+// instance.
+func setInstanceError(ctx context.Context, r *ReconcileCnsEphemeralVolume,
+	instance *cnsephemeralvolumev1alpha1.CnsEphemeralVolume, errMsg string) {
+	log := logger.GetLogger(ctx)
+	instance.Status.Error = errMsg
+	err := updateCnsEphemeralVolume(ctx, r.client, instance)
+	if err != nil {
+		log.Errorf("updateCnsEphemeralVolume failed. err: %v", err)
+	}
+	recordEvent(ctx, r, instance, v1.EventTypeWarning, errMsg)
+}
+
+// recordEvent records the event, sets the backOffDuration for the instance
+// appropriately and logs the message.
+// backOffDuration is reset to 1 second on success and doubled on failure.
+func recordEvent(ctx context.Context, r *ReconcileCnsEphemeralVolume,
+	instance *cnsephemeralvolumev1alpha1.CnsEphemeralVolume, eventtype string, msg string) {
+	log := logger.GetLogger(ctx)
+	log.Debugf("Event type is %s", eventtype)
+	switch eventtype {
+	case v1.EventTypeWarning:
+		// Double backOff duration.
+		backOffDurationMapMutex.Lock()
+		backOffDuration[instance.Name] = backOffDuration[instance.Name] * 2
+		r.recorder.Event(instance, v1.EventTypeWarning, "CnsEphemeralVolumeFailed", msg)
+		backOffDurationMapMutex.Unlock()
+	case v1.EventTypeNormal:
+		// Reset backOff duration to one second.
+		backOffDurationMapMutex.Lock()
+		backOffDuration[instance.Name] = time.Second
+		r.recorder.Event(instance, v1.EventTypeNormal, "CnsEphemeralVolumeSucceeded", msg)
+		backOffDurationMapMutex.Unlock()
+	}
+}
+
+// updateCnsEphemeralVolume updates the CnsEphemeralVolume instance in K8S.
+func updateCnsEphemeralVolume(ctx context.Context, client client.Client,
+	instance *cnsephemeralvolumev1alpha1.CnsEphemeralVolume) error {
+	log := logger.GetLogger(ctx)
+	err := client.Update(ctx, instance)
+	if err != nil {
+		log.Errorf("Failed to update CnsEphemeralVolume instance: %q. Error: %+v", instance.Name, err)
+	}
+	return err
+}
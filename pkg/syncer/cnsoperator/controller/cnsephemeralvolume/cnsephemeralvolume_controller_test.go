@@ -0,0 +1,169 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cnsephemeralvolume
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cnsephemeralvolumev1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsephemeralvolume/v1alpha1"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/node"
+	volumes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/volume"
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+)
+
+// fakeVolumeManager is a volume Manager recording the volumes it detaches
+// and deletes.
+type fakeVolumeManager struct {
+	volumes.Manager
+	volumeIDs []string
+	detached  []string
+	deleted   []string
+}
+
+func (m *fakeVolumeManager) QueryVolume(ctx context.Context,
+	queryFilter cnstypes.CnsQueryFilter) (*cnstypes.CnsQueryResult, error) {
+	result := &cnstypes.CnsQueryResult{}
+	for _, volumeID := range m.volumeIDs {
+		if volumeID == queryFilter.VolumeIds[0].Id {
+			result.Volumes = append(result.Volumes, cnstypes.CnsVolume{VolumeId: cnstypes.CnsVolumeId{Id: volumeID}})
+		}
+	}
+	return result, nil
+}
+
+func (m *fakeVolumeManager) DetachVolume(ctx context.Context, vm *cnsvsphere.VirtualMachine,
+	volumeID string) (string, error) {
+	m.detached = append(m.detached, volumeID)
+	return "", nil
+}
+
+func (m *fakeVolumeManager) DeleteVolume(ctx context.Context, volumeID string, deleteDisk bool) (string, error) {
+	m.deleted = append(m.deleted, volumeID)
+	return "", nil
+}
+
+// fakeNodeManager is a nodeManager knowing the VMs of the given nodes.
+type fakeNodeManager map[string]*cnsvsphere.VirtualMachine
+
+func (m fakeNodeManager) GetNodeByName(ctx context.Context, nodeName string) (*cnsvsphere.VirtualMachine, error) {
+	vm, ok := m[nodeName]
+	if !ok {
+		return nil, node.ErrNodeNotFound
+	}
+	return vm, nil
+}
+
+func newInstance(name, nodeName, volumeID string) *cnsephemeralvolumev1alpha1.CnsEphemeralVolume {
+	return &cnsephemeralvolumev1alpha1.CnsEphemeralVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       cnsephemeralvolumev1alpha1.CnsEphemeralVolumeSpec{NodeName: nodeName},
+		Status:     cnsephemeralvolumev1alpha1.CnsEphemeralVolumeStatus{VolumeID: volumeID},
+	}
+}
+
+// TestGetCreateVolumeSpec tests that the volume spec is built from the spec
+// of the instance, with the default size unless one is requested.
+func TestGetCreateVolumeSpec(t *testing.T) {
+	ctx := context.Background()
+	instance := newInstance("csi-1234", "node-1", "")
+	spec, err := getCreateVolumeSpec(ctx, instance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.Name != "ephemeral-csi-1234" {
+		t.Errorf("unexpected volume name %q", spec.Name)
+	}
+	if spec.CapacityMB != common.DefaultGbDiskSize*1024 {
+		t.Errorf("expected the default size, got %d MB", spec.CapacityMB)
+	}
+	if spec.VolumeType != common.BlockVolumeType {
+		t.Errorf("unexpected volume type %q", spec.VolumeType)
+	}
+
+	instance.Spec.CapacityInBytes = 2 * common.GbInBytes
+	instance.Spec.Parameters = map[string]string{"storagepolicyname": "gold"}
+	if spec, err = getCreateVolumeSpec(ctx, instance); err != nil {
+		t.Fatal(err)
+	}
+	if spec.CapacityMB != 2*1024 {
+		t.Errorf("unexpected size %d MB", spec.CapacityMB)
+	}
+	if spec.ScParams.StoragePolicyName != "gold" {
+		t.Errorf("unexpected storage policy %q", spec.ScParams.StoragePolicyName)
+	}
+
+	instance.Spec.Parameters = map[string]string{"datastoreurl": "ds:///vmfs/volumes/ds-1/"}
+	if _, err := getCreateVolumeSpec(ctx, instance); err == nil {
+		t.Errorf("expected an error for a datastore URL")
+	}
+}
+
+// TestDeleteVolume tests that the volume of an instance is detached from its
+// node, unless the node VM is gone, and deleted if it still exists.
+func TestDeleteVolume(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name             string
+		instance         *cnsephemeralvolumev1alpha1.CnsEphemeralVolume
+		expectedDetached []string
+		expectedDeleted  []string
+	}{
+		{
+			name:             "attached volume",
+			instance:         newInstance("csi-1", "node-1", "vol-1"),
+			expectedDetached: []string{"vol-1"},
+			expectedDeleted:  []string{"vol-1"},
+		},
+		{
+			name:            "node VM gone",
+			instance:        newInstance("csi-1", "node-2", "vol-1"),
+			expectedDeleted: []string{"vol-1"},
+		},
+		{
+			name:     "volume already deleted",
+			instance: newInstance("csi-1", "node-1", "vol-2"),
+		},
+		{
+			name:     "volume not created",
+			instance: newInstance("csi-1", "node-1", ""),
+		},
+	}
+	for _, test := range tests {
+		volumeManager := &fakeVolumeManager{volumeIDs: []string{"vol-1"}}
+		r := &ReconcileCnsEphemeralVolume{
+			volumeManager: volumeManager,
+			nodeManager:   fakeNodeManager{"node-1": &cnsvsphere.VirtualMachine{}},
+		}
+		if err := r.deleteVolume(ctx, test.instance); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if !reflect.DeepEqual(volumeManager.detached, test.expectedDetached) {
+			t.Errorf("%s: expected detached volumes %v, got %v", test.name, test.expectedDetached,
+				volumeManager.detached)
+		}
+		if !reflect.DeepEqual(volumeManager.deleted, test.expectedDeleted) {
+			t.Errorf("%s: expected deleted volumes %v, got %v", test.name, test.expectedDeleted,
+				volumeManager.deleted)
+		}
+	}
+}
@@ -408,30 +408,30 @@ func (r *ReconcileCnsFileAccessConfig) configureNetPermissionsForFileVolume(ctx
 	log := logger.GetLogger(ctx)
 	tkgVMIP, err := r.getVMExternalIP(ctx, vm)
 	if err != nil {
-		return logger.LogNewErrorf(log, "Failed to get external facing IP address for VM: %s/%s instance. Error: %+v",
+		return logger.LogNewErrorf(log, "Failed to get external facing IP address for VM: %s/%s instance. Error: %w",
 			vm.Namespace, vm.Name, err)
 	}
 	cnsFileVolumeClientInstance, err := cnsfilevolumeclient.GetFileVolumeClientInstance(ctx)
 	if err != nil {
-		return logger.LogNewErrorf(log, "Failed to get CNSFileVolumeClient instance. Error: %+v", err)
+		return logger.LogNewErrorf(log, "Failed to get CNSFileVolumeClient instance. Error: %w", err)
 	}
 	clientVms, err := cnsFileVolumeClientInstance.GetClientVMsFromIPList(ctx,
 		instance.Namespace+"/"+instance.Spec.PvcName, tkgVMIP)
 	if err != nil {
-		return logger.LogNewErrorf(log, "Failed to get the list of clients VMs for IP %q. Error: %+v", tkgVMIP, err)
+		return logger.LogNewErrorf(log, "Failed to get the list of clients VMs for IP %q. Error: %w", tkgVMIP, err)
 	}
 	if !removePermission {
 		if len(clientVms) == 0 {
 			err = r.configureVolumeACLs(ctx, volumeID, tkgVMIP, false)
 			if err != nil {
-				return logger.LogNewErrorf(log, "Failed to add net permissions for file volume %q. Error: %+v",
+				return logger.LogNewErrorf(log, "Failed to add net permissions for file volume %q. Error: %w",
 					volumeID, err)
 			}
 		}
 		err = cnsFileVolumeClientInstance.AddClientVMToIPList(ctx,
 			instance.Namespace+"/"+instance.Spec.PvcName, instance.Spec.VMName, tkgVMIP)
 		if err != nil {
-			return logger.LogNewErrorf(log, "Failed to add VM %q with IP %q to IPList. Error: %+v",
+			return logger.LogNewErrorf(log, "Failed to add VM %q with IP %q to IPList. Error: %w",
 				vm.Name, tkgVMIP, err)
 		}
 		log.Debugf("Successfully added VM IP %q to IPList for CnsFileAccessConfig request with name: %q on namespace: %q",
@@ -442,14 +442,14 @@ func (r *ReconcileCnsFileAccessConfig) configureNetPermissionsForFileVolume(ctx
 	if len(clientVms) == 1 && clientVms[0] == vm.Name {
 		err = r.configureVolumeACLs(ctx, volumeID, tkgVMIP, true)
 		if err != nil {
-			return logger.LogNewErrorf(log, "Failed to remove net permissions for file volume %q. Error: %+v",
+			return logger.LogNewErrorf(log, "Failed to remove net permissions for file volume %q. Error: %w",
 				volumeID, err)
 		}
 	}
 	err = cnsFileVolumeClientInstance.RemoveClientVMFromIPList(ctx,
 		instance.Namespace+"/"+instance.Spec.PvcName, instance.Spec.VMName, tkgVMIP)
 	if err != nil {
-		return logger.LogNewErrorf(log, "Failed to remove VM %q with IP %q to IPList. Error: %+v", vm.Name, tkgVMIP, err)
+		return logger.LogNewErrorf(log, "Failed to remove VM %q with IP %q to IPList. Error: %w", vm.Name, tkgVMIP, err)
 	}
 	log.Debugf("Successfully removed VM IP %q to IPList for CnsFileAccessConfig request with name: %q on namespace: %q",
 		tkgVMIP, instance.Name, instance.Namespace)
@@ -485,7 +485,7 @@ func (r *ReconcileCnsFileAccessConfig) configureVolumeACLs(ctx context.Context,
 	log.Debugf("CnsVolumeACLConfigSpec : %v", cnsVolumeACLConfigSpec)
 	err := r.volumeManager.ConfigureVolumeACLs(ctx, cnsVolumeACLConfigSpec)
 	if err != nil {
-		return logger.LogNewErrorf(log, "Failed to configure ACLs for volume: %q. Error: %+v", volumeID, err)
+		return logger.LogNewErrorf(log, "Failed to configure ACLs for volume: %q. Error: %w", volumeID, err)
 	}
 	log.Debugf("Successfully configured ACLs for volume %q", volumeID)
 	return nil
@@ -497,7 +497,7 @@ func (r *ReconcileCnsFileAccessConfig) getVMExternalIP(ctx context.Context,
 	log := logger.GetLogger(ctx)
 	networkProvider, err := cnsoperatorutil.GetNetworkProvider(ctx)
 	if err != nil {
-		return "", logger.LogNewErrorf(log, "Failed to identify the network provider. Error: %+v", err)
+		return "", logger.LogNewErrorf(log, "Failed to identify the network provider. Error: %w", err)
 	}
 	var nsxConfiguration bool
 	if networkProvider == "" {
@@ -508,13 +508,13 @@ func (r *ReconcileCnsFileAccessConfig) getVMExternalIP(ctx context.Context,
 	} else if networkProvider == cnsoperatorutil.VDSNetworkProvider {
 		nsxConfiguration = false
 	} else {
-		return "", logger.LogNewErrorf(log, "Unknown network provider. Error: %+v", err)
+		return "", logger.LogNewErrorf(log, "Unknown network provider. Error: %w", err)
 	}
 
 	tkgVMIP, err := cnsoperatorutil.GetTKGVMIP(ctx, r.vmOperatorClient,
 		r.dynamicClient, vm.Namespace, vm.Name, nsxConfiguration)
 	if err != nil {
-		return "", logger.LogNewErrorf(log, "Failed to get external facing IP address for VM %q/%q. Err: %+v",
+		return "", logger.LogNewErrorf(log, "Failed to get external facing IP address for VM %q/%q. Err: %w",
 			vm.Namespace, vm.Name, err)
 	}
 	log.Debugf("Found tkg VMIP %q for VM %q in namespace %q", tkgVMIP, vm.Name, vm.Namespace)
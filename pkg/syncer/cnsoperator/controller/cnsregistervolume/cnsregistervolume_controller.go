@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 	"sync"
 	"time"
 
@@ -71,7 +72,12 @@ var (
 func Add(mgr manager.Manager, clusterFlavor cnstypes.CnsClusterFlavor,
 	configInfo *commonconfig.ConfigurationInfo, volumeManager volumes.Manager) error {
 	ctx, log := logger.GetNewContextWithLogger()
-	if clusterFlavor != cnstypes.CnsClusterFlavorWorkload {
+	if clusterFlavor == cnstypes.CnsClusterFlavorVanilla {
+		if !commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.VanillaVolumeRegistration) {
+			log.Debug("Not initializing the CnsRegisterVolume Controller as vanilla volume registration is disabled")
+			return nil
+		}
+	} else if clusterFlavor != cnstypes.CnsClusterFlavorWorkload {
 		log.Debug("Not initializing the CnsRegisterVolume Controller as its a non-WCP CSI deployment")
 		return nil
 	}
@@ -104,14 +110,15 @@ func Add(mgr manager.Manager, clusterFlavor cnstypes.CnsClusterFlavor,
 		},
 	)
 	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: apis.GroupName})
-	return add(mgr, newReconciler(mgr, configInfo, volumeManager, recorder))
+	return add(mgr, newReconciler(mgr, clusterFlavor, configInfo, volumeManager, recorder))
 }
 
 // newReconciler returns a new reconcile.Reconciler.
-func newReconciler(mgr manager.Manager, configInfo *commonconfig.ConfigurationInfo,
-	volumeManager volumes.Manager, recorder record.EventRecorder) reconcile.Reconciler {
+func newReconciler(mgr manager.Manager, clusterFlavor cnstypes.CnsClusterFlavor,
+	configInfo *commonconfig.ConfigurationInfo, volumeManager volumes.Manager,
+	recorder record.EventRecorder) reconcile.Reconciler {
 	return &ReconcileCnsRegisterVolume{client: mgr.GetClient(), scheme: mgr.GetScheme(),
-		configInfo: configInfo, volumeManager: volumeManager, recorder: recorder}
+		clusterFlavor: clusterFlavor, configInfo: configInfo, volumeManager: volumeManager, recorder: recorder}
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler.
@@ -148,6 +155,7 @@ type ReconcileCnsRegisterVolume struct {
 	// that reads objects from the cache and writes to the apiserver.
 	client        client.Client
 	scheme        *runtime.Scheme
+	clusterFlavor cnstypes.CnsClusterFlavor
 	configInfo    *commonconfig.ConfigurationInfo
 	volumeManager volumes.Manager
 	recorder      record.EventRecorder
@@ -203,10 +211,11 @@ func (r *ReconcileCnsRegisterVolume) Reconcile(ctx context.Context,
 		setInstanceError(ctx, r, instance, err.Error())
 		return reconcile.Result{RequeueAfter: timeout}, nil
 	}
-	// Verify if CnsRegisterVolume request is for block volume registration
-	// Currently file volume registration is not supported.
-	ok := isBlockVolumeRegisterRequest(ctx, instance)
-	if !ok {
+	// Verify if CnsRegisterVolume request is for block volume registration.
+	// File volume registration is only supported in Vanilla clusters.
+	isFileVolume := r.clusterFlavor == cnstypes.CnsClusterFlavorVanilla &&
+		isFileVolumeRegisterRequest(ctx, instance)
+	if !isBlockVolumeRegisterRequest(ctx, instance) && !isFileVolume {
 		msg := fmt.Sprintf("AccessMode: %s is not supported", instance.Spec.AccessMode)
 		log.Error(msg)
 		setInstanceError(ctx, r, instance, msg)
@@ -225,7 +234,7 @@ func (r *ReconcileCnsRegisterVolume) Reconcile(ctx context.Context,
 		pvName   string
 	)
 	// Create Volume for the input CnsRegisterVolume instance.
-	createSpec := constructCreateSpecForInstance(r, instance, vc.Config.Host)
+	createSpec := constructCreateSpecForInstance(r, instance, vc.Config.Host, isFileVolume)
 	log.Infof("Creating CNS volume: %+v for CnsRegisterVolume request with name: %q on namespace: %q",
 		instance, instance.Name, instance.Namespace)
 	log.Debugf("CNS Volume create spec is: %+v", createSpec)
@@ -240,7 +249,9 @@ func (r *ReconcileCnsRegisterVolume) Reconcile(ctx context.Context,
 	volumeID = volInfo.VolumeID.Id
 	log.Infof("Created CNS volume with volumeID: %s", volumeID)
 
-	pvName = staticPvNamePrefix + volumeID
+	// File volume IDs are prefixed with "file:", which is not allowed in PV
+	// names.
+	pvName = staticPvNamePrefix + strings.ReplaceAll(volumeID, ":", "-")
 	// Query volume
 	log.Infof("Querying volume: %s for CnsRegisterVolume request with name: %q on namespace: %q",
 		volumeID, instance.Name, instance.Namespace)
@@ -258,9 +269,11 @@ func (r *ReconcileCnsRegisterVolume) Reconcile(ctx context.Context,
 		return reconcile.Result{RequeueAfter: timeout}, nil
 	}
 
-	// Verify if the volume is accessible to Pacific cluster.
-	isAccessible := isDatastoreAccessibleToCluster(ctx, vc, r.configInfo.Cfg.Global.ClusterID, volume.DatastoreUrl)
-	if !isAccessible {
+	// Verify if the volume is accessible to Pacific cluster. In Vanilla
+	// clusters, ClusterID doesn't identify a vSphere cluster, so the
+	// accessibility of the volume to the nodes is only checked on attach.
+	if r.clusterFlavor == cnstypes.CnsClusterFlavorWorkload &&
+		!isDatastoreAccessibleToCluster(ctx, vc, r.configInfo.Cfg.Global.ClusterID, volume.DatastoreUrl) {
 		log.Errorf("Volume: %s present on datastore: %s is not accessible to all nodes in the cluster: %s",
 			volumeID, volume.DatastoreUrl, r.configInfo.Cfg.Global.ClusterID)
 		setInstanceError(ctx, r, instance, "Volume in the spec is not accessible to all nodes in the cluster")
@@ -272,7 +285,7 @@ func (r *ReconcileCnsRegisterVolume) Reconcile(ctx context.Context,
 		return reconcile.Result{RequeueAfter: timeout}, nil
 	}
 	// Verify if storage policy is empty.
-	if volume.StoragePolicyId == "" {
+	if r.clusterFlavor == cnstypes.CnsClusterFlavorWorkload && volume.StoragePolicyId == "" {
 		log.Errorf("Volume: %s doesn't have storage policy associated with it", volumeID)
 		setInstanceError(ctx, r, instance, "Volume in the spec doesn't have storage policy associated with it")
 		// Untag the CNS volume which was created previously.
@@ -291,17 +304,21 @@ func (r *ReconcileCnsRegisterVolume) Reconcile(ctx context.Context,
 		return reconcile.Result{RequeueAfter: timeout}, nil
 	}
 
-	// Get K8S storageclass name mapping the storagepolicy id.
-	storageClassName, err := getK8sStorageClassName(ctx, k8sclient, volume.StoragePolicyId, request.Namespace)
-	if err != nil {
-		msg := fmt.Sprintf("Failed to find K8S Storageclass mapping storagepolicyId: %s and assigned to namespace: %s",
-			volume.StoragePolicyId, request.Namespace)
-		log.Error(msg)
-		setInstanceError(ctx, r, instance, msg)
-		return reconcile.Result{RequeueAfter: timeout}, nil
+	// Get K8S storageclass name mapping the storagepolicy id. In Vanilla
+	// clusters, the PV and PVC are statically bound without storage class.
+	var storageClassName string
+	if r.clusterFlavor == cnstypes.CnsClusterFlavorWorkload {
+		storageClassName, err = getK8sStorageClassName(ctx, k8sclient, volume.StoragePolicyId, request.Namespace)
+		if err != nil {
+			msg := fmt.Sprintf("Failed to find K8S Storageclass mapping storagepolicyId: %s and assigned to namespace: %s",
+				volume.StoragePolicyId, request.Namespace)
+			log.Error(msg)
+			setInstanceError(ctx, r, instance, msg)
+			return reconcile.Result{RequeueAfter: timeout}, nil
+		}
+		log.Infof("Volume with storagepolicyId: %s is mapping to K8S storage class: %s and assigned to namespace: %s",
+			volume.StoragePolicyId, storageClassName, request.Namespace)
 	}
-	log.Infof("Volume with storagepolicyId: %s is mapping to K8S storage class: %s and assigned to namespace: %s",
-		volume.StoragePolicyId, storageClassName, request.Namespace)
 
 	capacityInMb := volume.BackingObjectDetails.GetCnsBackingObjectDetails().CapacityInMb
 	accessMode := instance.Spec.AccessMode
@@ -322,6 +339,17 @@ func (r *ReconcileCnsRegisterVolume) Reconcile(ctx context.Context,
 			}
 			pvSpec := getPersistentVolumeSpec(pvName, volumeID, capacityInMb,
 				accessMode, storageClassName, claimRef)
+			if r.clusterFlavor == cnstypes.CnsClusterFlavorVanilla {
+				// Imported volumes are not owned by the cluster, so deleting
+				// the PVC must not delete them.
+				pvSpec.Spec.PersistentVolumeReclaimPolicy = v1.PersistentVolumeReclaimRetain
+			}
+			if isFileVolume {
+				pvSpec.Spec.CSI.FSType = common.NfsV4FsType
+				pvSpec.Spec.CSI.VolumeAttributes = map[string]string{
+					common.AttributeDiskType: common.DiskTypeFileVolume,
+				}
+			}
 			log.Debugf("PV spec is: %+v", pvSpec)
 			pv, err = k8sclient.CoreV1().PersistentVolumes().Create(ctx, pvSpec, metav1.CreateOptions{})
 			if err != nil {
@@ -451,6 +479,13 @@ func isBlockVolumeRegisterRequest(ctx context.Context, instance *cnsregistervolu
 	return false
 }
 
+// isFileVolumeRegisterRequest verifies if file volume register is requested
+// via CnsRegisterVolume instance.
+func isFileVolumeRegisterRequest(ctx context.Context, instance *cnsregistervolumev1alpha1.CnsRegisterVolume) bool {
+	return instance.Spec.VolumeID != "" && (instance.Spec.AccessMode == v1.ReadWriteMany ||
+		instance.Spec.AccessMode == v1.ReadOnlyMany)
+}
+
 // setInstanceError sets error and records an event on the CnsRegisterVolume
 // instance.
 func setInstanceError(ctx context.Context, r *ReconcileCnsRegisterVolume,
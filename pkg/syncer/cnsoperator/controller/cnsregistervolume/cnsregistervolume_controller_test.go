@@ -0,0 +1,83 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cnsregistervolume
+
+import (
+	"context"
+	"testing"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	v1 "k8s.io/api/core/v1"
+
+	cnsregistervolumev1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsregistervolume/v1alpha1"
+	commonconfig "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+)
+
+func newTestInstance(volumeID string,
+	accessMode v1.PersistentVolumeAccessMode) *cnsregistervolumev1alpha1.CnsRegisterVolume {
+	return &cnsregistervolumev1alpha1.CnsRegisterVolume{
+		Spec: cnsregistervolumev1alpha1.CnsRegisterVolumeSpec{
+			PvcName:    "pvc-1",
+			VolumeID:   volumeID,
+			AccessMode: accessMode,
+		},
+	}
+}
+
+// TestConstructCreateSpecForInstance tests that block and file volume
+// registrations result in the matching CNS create specs.
+func TestConstructCreateSpecForInstance(t *testing.T) {
+	ctx := context.Background()
+	cfg := &commonconfig.Config{}
+	cfg.Global.ClusterID = "cluster-1"
+	cfg.VirtualCenter = map[string]*commonconfig.VirtualCenterConfig{"vc": {User: "user"}}
+	r := &ReconcileCnsRegisterVolume{
+		clusterFlavor: cnstypes.CnsClusterFlavorVanilla,
+		configInfo:    &commonconfig.ConfigurationInfo{Cfg: cfg},
+	}
+
+	blockInstance := newTestInstance("fcd-1", v1.ReadWriteOnce)
+	if !isBlockVolumeRegisterRequest(ctx, blockInstance) || isFileVolumeRegisterRequest(ctx, blockInstance) {
+		t.Fatal("expected RWO instance to be a block volume register request")
+	}
+	createSpec := constructCreateSpecForInstance(r, blockInstance, "vc", false)
+	if createSpec.VolumeType != common.BlockVolumeType {
+		t.Errorf("expected volume type %q, got %q", common.BlockVolumeType, createSpec.VolumeType)
+	}
+	blockDetails, ok := createSpec.BackingObjectDetails.(*cnstypes.CnsBlockBackingDetails)
+	if !ok || blockDetails.BackingDiskId != "fcd-1" {
+		t.Errorf("expected block backing details for fcd-1, got %+v", createSpec.BackingObjectDetails)
+	}
+	if createSpec.Metadata.ContainerCluster.ClusterFlavor != string(cnstypes.CnsClusterFlavorVanilla) {
+		t.Errorf("expected cluster flavor %q, got %q", cnstypes.CnsClusterFlavorVanilla,
+			createSpec.Metadata.ContainerCluster.ClusterFlavor)
+	}
+
+	fileInstance := newTestInstance("file:share-1", v1.ReadWriteMany)
+	if isBlockVolumeRegisterRequest(ctx, fileInstance) || !isFileVolumeRegisterRequest(ctx, fileInstance) {
+		t.Fatal("expected RWX instance to be a file volume register request")
+	}
+	createSpec = constructCreateSpecForInstance(r, fileInstance, "vc", true)
+	if createSpec.VolumeType != common.FileVolumeType {
+		t.Errorf("expected volume type %q, got %q", common.FileVolumeType, createSpec.VolumeType)
+	}
+	fileDetails, ok := createSpec.BackingObjectDetails.(*cnstypes.CnsVsanFileShareBackingDetails)
+	if !ok || fileDetails.BackingFileId != "file:share-1" {
+		t.Errorf("expected file share backing details for file:share-1, got %+v", createSpec.BackingObjectDetails)
+	}
+}
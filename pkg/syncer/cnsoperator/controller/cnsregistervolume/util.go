@@ -35,7 +35,7 @@ import (
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
-	cnsoperatortypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/syncer/cnsoperator/types"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
 )
 
 const (
@@ -65,7 +65,8 @@ func isDatastoreAccessibleToCluster(ctx context.Context, vc *vsphere.VirtualCent
 
 // constructCreateSpecForInstance creates CNS CreateVolume spec.
 func constructCreateSpecForInstance(r *ReconcileCnsRegisterVolume,
-	instance *cnsregistervolumev1alpha1.CnsRegisterVolume, host string) *cnstypes.CnsVolumeCreateSpec {
+	instance *cnsregistervolumev1alpha1.CnsRegisterVolume, host string,
+	isFileVolume bool) *cnstypes.CnsVolumeCreateSpec {
 	var volumeName string
 	if instance.Spec.VolumeID != "" {
 		volumeName = staticPvNamePrefix + instance.Spec.VolumeID
@@ -75,7 +76,7 @@ func constructCreateSpecForInstance(r *ReconcileCnsRegisterVolume,
 	}
 	containerCluster := vsphere.GetContainerCluster(r.configInfo.Cfg.Global.ClusterID,
 		r.configInfo.Cfg.VirtualCenter[host].User,
-		cnstypes.CnsClusterFlavorWorkload, r.configInfo.Cfg.Global.ClusterDistribution)
+		r.clusterFlavor, r.configInfo.Cfg.Global.ClusterDistribution)
 	createSpec := &cnstypes.CnsVolumeCreateSpec{
 		Name:       volumeName,
 		VolumeType: common.BlockVolumeType,
@@ -83,7 +84,14 @@ func constructCreateSpecForInstance(r *ReconcileCnsRegisterVolume,
 			ContainerCluster: containerCluster,
 		},
 	}
-	if instance.Spec.VolumeID != "" {
+	if isFileVolume {
+		createSpec.VolumeType = common.FileVolumeType
+		createSpec.BackingObjectDetails = &cnstypes.CnsVsanFileShareBackingDetails{
+			CnsFileBackingDetails: cnstypes.CnsFileBackingDetails{
+				BackingFileId: instance.Spec.VolumeID,
+			},
+		}
+	} else if instance.Spec.VolumeID != "" {
 		createSpec.BackingObjectDetails = &cnstypes.CnsBlockBackingDetails{
 			BackingDiskId: instance.Spec.VolumeID,
 		}
@@ -92,11 +100,6 @@ func constructCreateSpecForInstance(r *ReconcileCnsRegisterVolume,
 			BackingDiskUrlPath: instance.Spec.DiskURLPath,
 		}
 	}
-	if instance.Spec.AccessMode == v1.ReadWriteOnce || instance.Spec.AccessMode == "" {
-		createSpec.VolumeType = common.BlockVolumeType
-	} else {
-		createSpec.VolumeType = common.FileVolumeType
-	}
 	return createSpec
 }
 
@@ -107,7 +110,7 @@ func getK8sStorageClassName(ctx context.Context, k8sClient clientset.Interface,
 	log := logger.GetLogger(ctx)
 	scList, err := k8sClient.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
 	if err != nil {
-		return "", logger.LogNewErrorf(log, "Failed to get Storageclasses from API server. Error: %+v", err)
+		return "", logger.LogNewErrorf(log, "Failed to get Storageclasses from API server. Error: %w", err)
 	}
 	var scName string
 	for _, sc := range scList.Items {
@@ -168,7 +171,7 @@ func getPersistentVolumeSpec(volumeName string, volumeID string, capacity int64,
 			},
 			PersistentVolumeSource: v1.PersistentVolumeSource{
 				CSI: &v1.CSIPersistentVolumeSource{
-					Driver:       cnsoperatortypes.VSphereCSIDriverName,
+					Driver:       csitypes.Name,
 					VolumeHandle: volumeID,
 					ReadOnly:     false,
 					FSType:       "ext4",
@@ -183,7 +186,7 @@ func getPersistentVolumeSpec(volumeName string, volumeID string, capacity int64,
 		Status: v1.PersistentVolumeStatus{},
 	}
 	annotations := make(map[string]string)
-	annotations["pv.kubernetes.io/provisioned-by"] = cnsoperatortypes.VSphereCSIDriverName
+	annotations["pv.kubernetes.io/provisioned-by"] = csitypes.Name
 	pv.Annotations = annotations
 	return pv
 }
@@ -233,7 +236,7 @@ func isPVCBound(ctx context.Context, client clientset.Interface, claim *v1.Persi
 			Watch:          true,
 		})
 	if err != nil {
-		errMsg := fmt.Errorf("failed to watch PersistentVolumeClaim %s with Error: %v", pvcName, err)
+		errMsg := fmt.Errorf("failed to watch PersistentVolumeClaim %s with Error: %w", pvcName, err)
 		log.Error(errMsg)
 		return false, errMsg
 	}
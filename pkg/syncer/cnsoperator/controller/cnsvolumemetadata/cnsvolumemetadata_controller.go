@@ -26,12 +26,15 @@ import (
 	"time"
 
 	commonconfig "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common/commonco"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
 
 	"github.com/davecgh/go-spew/spew"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/record"
@@ -96,14 +99,33 @@ func Add(mgr manager.Manager, clusterFlavor cnstypes.CnsClusterFlavor,
 		},
 	)
 	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: cnsoperatorapis.GroupName})
-	return add(mgr, newReconciler(mgr, configInfo, volumeManager, k8sclient, recorder))
+	protectPVCs := commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.GuestPVProtection)
+	if commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.CnsVolumeMetadataGC) {
+		dynamicClient, err := dynamic.NewForConfig(mgr.GetConfig())
+		if err != nil {
+			log.Errorf("Creating dynamic client failed. Err: %v", err)
+			return err
+		}
+		gc := newGarbageCollector(mgr.GetClient(), mgr.GetConfig(), k8sclient.Discovery(), dynamicClient)
+		// The garbage collector runs on the leader once the caches of the
+		// manager are synced.
+		err = mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+			gc.run(ctx)
+			return nil
+		}))
+		if err != nil {
+			log.Errorf("failed to add the CnsVolumeMetadata garbage collector to the manager. Err: %v", err)
+			return err
+		}
+	}
+	return add(mgr, newReconciler(mgr, configInfo, volumeManager, k8sclient, recorder, protectPVCs))
 }
 
 // newReconciler returns a new reconcile.Reconciler.
 func newReconciler(mgr manager.Manager, configInfo *commonconfig.ConfigurationInfo, volumeManager volumes.Manager,
-	k8sclient kubernetes.Interface, recorder record.EventRecorder) reconcile.Reconciler {
+	k8sclient kubernetes.Interface, recorder record.EventRecorder, protectPVCs bool) reconcile.Reconciler {
 	return &ReconcileCnsVolumeMetadata{client: mgr.GetClient(), scheme: mgr.GetScheme(), configInfo: configInfo,
-		volumeManager: volumeManager, k8sclient: k8sclient, recorder: recorder}
+		volumeManager: volumeManager, k8sclient: k8sclient, recorder: recorder, protectPVCs: protectPVCs}
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler.
@@ -172,6 +194,9 @@ type ReconcileCnsVolumeMetadata struct {
 	volumeManager volumes.Manager
 	k8sclient     kubernetes.Interface
 	recorder      record.EventRecorder
+	// protectPVCs is set if the supervisor PVCs backing guest PVs are
+	// protected from deletion until the guest PVs are deleted.
+	protectPVCs bool
 }
 
 // Reconcile reads that state of the cluster for a CnsVolumeMetadata object and
@@ -236,6 +261,16 @@ func (r *ReconcileCnsVolumeMetadata) Reconcile(ctx context.Context,
 			return reconcile.Result{RequeueAfter: timeout}, err
 		}
 
+		// Release the supervisor PVCs of a deleted guest PV. This is done even
+		// if the protection is disabled, so that the PVCs protected before
+		// aren't left behind.
+		if err = unprotectSupervisorPVCs(ctx, r.k8sclient, r.client, instance); err != nil {
+			msg := fmt.Sprintf("ReconcileCnsVolumeMetadata: Failed to remove finalizer %q from the PVCs of %q. "+
+				"Err: %v. Requeueing request.", cnsoperatortypes.GuestPVProtectionFinalizer, instance.Name, err)
+			recordEvent(ctx, r, instance, v1.EventTypeWarning, msg)
+			return reconcile.Result{RequeueAfter: timeout}, err
+		}
+
 		// Remove finalizer as update on CNS was successful.
 		for index, finalizer := range instance.Finalizers {
 			if finalizer == cnsoperatortypes.CNSFinalizer {
@@ -278,6 +313,15 @@ func (r *ReconcileCnsVolumeMetadata) Reconcile(ctx context.Context,
 			return reconcile.Result{RequeueAfter: timeout}, err
 		}
 	} else {
+		// Protect the supervisor PVCs of the guest PV from deletion.
+		if r.protectPVCs {
+			if err = protectSupervisorPVCs(ctx, r.k8sclient, instance); err != nil {
+				msg := fmt.Sprintf("ReconcileCnsVolumeMetadata: Failed to add finalizer %q to the PVCs of %q. "+
+					"Err: %v. Requeueing request.", cnsoperatortypes.GuestPVProtectionFinalizer, instance.Name, err)
+				recordEvent(ctx, r, instance, v1.EventTypeWarning, msg)
+				return reconcile.Result{RequeueAfter: timeout}, err
+			}
+		}
 		// Update CNS volume entry with instance's metadata.
 		if !r.updateCnsMetadata(ctx, instance, false) {
 			// Failed to update CNS.
@@ -0,0 +1,244 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cnsvolumemetadata
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cnsv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsvolumemetadata/v1alpha1"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	k8s "sigs.k8s.io/vsphere-csi-driver/v2/pkg/kubernetes"
+)
+
+const (
+	// garbageCollectionInterval is the interval of the periodic garbage
+	// collection of the CnsVolumeMetadata instances of deleted guest clusters.
+	garbageCollectionInterval = 30 * time.Minute
+	// garbageCollectionGracePeriod is the age under which CnsVolumeMetadata
+	// instances aren't garbage collected, so that the instances created by a
+	// guest cluster while it is being created are left alone.
+	garbageCollectionGracePeriod = 10 * time.Minute
+	// garbageCollectionDiscoveryRetryInterval is the interval at which the
+	// discovery of the guest cluster resources is retried when it fails.
+	garbageCollectionDiscoveryRetryInterval = time.Minute
+)
+
+// guestClusterResources are the resources of the guest clusters owning
+// CnsVolumeMetadata instances, i.e. TanzuKubernetesClusters and Cluster API
+// Clusters, resolved to the preferred version of their group served by the
+// supervisor cluster.
+var guestClusterResources = []schema.GroupResource{
+	{Group: "run.tanzu.vmware.com", Resource: "tanzukubernetesclusters"},
+	{Group: "cluster.x-k8s.io", Resource: "clusters"},
+}
+
+// garbageCollector deletes the CnsVolumeMetadata instances of the guest
+// clusters which no longer exist, e.g. when a guest cluster was deleted
+// before its syncer deleted them. The CnsVolumeMetadata controller then
+// removes the metadata of the deleted instances from CNS.
+type garbageCollector struct {
+	client          client.Client
+	config          *restclient.Config
+	discoveryClient discovery.DiscoveryInterface
+	dynamicClient   dynamic.Interface
+}
+
+// newGarbageCollector returns a garbageCollector of the guest cluster
+// resources discovered with the given discovery client.
+func newGarbageCollector(crClient client.Client, config *restclient.Config,
+	discoveryClient discovery.DiscoveryInterface, dynamicClient dynamic.Interface) *garbageCollector {
+	return &garbageCollector{
+		client:          crClient,
+		config:          config,
+		discoveryClient: discoveryClient,
+		dynamicClient:   dynamicClient,
+	}
+}
+
+// getGuestClusterResources returns the guest cluster resources served by the
+// supervisor cluster, in the preferred version of their group. It fails if
+// the served resources can't be discovered, or if the preferred version of
+// the group of a guest cluster resource doesn't serve it, so that the
+// CnsVolumeMetadata instances of live guest clusters aren't garbage
+// collected because their guest clusters couldn't be listed.
+func (gc *garbageCollector) getGuestClusterResources() ([]schema.GroupVersionResource, error) {
+	groups, err := gc.discoveryClient.ServerGroups()
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover the API groups of the supervisor cluster. Err: %w", err)
+	}
+	var resources []schema.GroupVersionResource
+	for _, groupResource := range guestClusterResources {
+		var groupVersion string
+		for _, group := range groups.Groups {
+			if group.Name == groupResource.Group {
+				groupVersion = group.PreferredVersion.GroupVersion
+				break
+			}
+		}
+		if groupVersion == "" {
+			// The group isn't served, there are no guest clusters of this kind.
+			continue
+		}
+		gv, err := schema.ParseGroupVersion(groupVersion)
+		if err != nil {
+			return nil, err
+		}
+		apiResources, err := gc.discoveryClient.ServerResourcesForGroupVersion(groupVersion)
+		if err != nil {
+			return nil, fmt.Errorf("failed to discover the resources of %s. Err: %w", groupVersion, err)
+		}
+		served := false
+		for _, apiResource := range apiResources.APIResources {
+			if apiResource.Name == groupResource.Resource {
+				served = true
+				break
+			}
+		}
+		if !served {
+			return nil, fmt.Errorf("%s isn't served by the preferred version %s of its group",
+				groupResource, groupVersion)
+		}
+		resources = append(resources, gv.WithResource(groupResource.Resource))
+	}
+	return resources, nil
+}
+
+// run garbage collects the CnsVolumeMetadata instances of the namespace of
+// every deleted guest cluster, and of all namespaces periodically for the
+// guest clusters deleted while the syncer wasn't running, until ctx is done.
+func (gc *garbageCollector) run(ctx context.Context) {
+	log := logger.GetLogger(ctx)
+	var resources []schema.GroupVersionResource
+	err := wait.PollImmediateUntil(garbageCollectionDiscoveryRetryInterval, func() (bool, error) {
+		var err error
+		if resources, err = gc.getGuestClusterResources(); err != nil {
+			log.Warnf("CnsVolumeMetadataGC: failed to discover the guest cluster resources, retrying in %v. "+
+				"Err: %v", garbageCollectionDiscoveryRetryInterval, err)
+			return false, nil
+		}
+		return true, nil
+	}, ctx.Done())
+	if err != nil {
+		return
+	}
+	if len(resources) == 0 {
+		log.Info("CnsVolumeMetadataGC: no guest cluster resource is served. Not garbage collecting " +
+			"CnsVolumeMetadata instances.")
+		return
+	}
+	for _, gvr := range resources {
+		informer, err := k8s.GetDynamicInformer(ctx, gvr.Group, gvr.Version, gvr.Resource,
+			metav1.NamespaceAll, gc.config, true)
+		if err != nil {
+			log.Errorf("CnsVolumeMetadataGC: failed to create the informer of %s. Err: %v", gvr, err)
+			continue
+		}
+		informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			DeleteFunc: func(obj interface{}) {
+				key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+				if err != nil {
+					return
+				}
+				namespace, name, err := cache.SplitMetaNamespaceKey(key)
+				if err != nil {
+					return
+				}
+				log.Infof("CnsVolumeMetadataGC: guest cluster %s/%s deleted", namespace, name)
+				if err := gc.collect(ctx, namespace); err != nil {
+					log.Errorf("CnsVolumeMetadataGC: failed to garbage collect the CnsVolumeMetadata instances "+
+						"of namespace %q. Err: %v", namespace, err)
+				}
+			},
+		})
+		go informer.Informer().Run(ctx.Done())
+	}
+	wait.Until(func() {
+		if err := gc.collect(ctx, metav1.NamespaceAll); err != nil {
+			log.Errorf("CnsVolumeMetadataGC: failed to garbage collect the CnsVolumeMetadata instances. Err: %v",
+				err)
+		}
+	}, garbageCollectionInterval, ctx.Done())
+}
+
+// collect deletes the CnsVolumeMetadata instances of the given namespace, or
+// of all namespaces, whose guest cluster no longer exists in their namespace.
+// Nothing is deleted if the guest cluster resources can't be discovered.
+func (gc *garbageCollector) collect(ctx context.Context, namespace string) error {
+	log := logger.GetLogger(ctx)
+	resources, err := gc.getGuestClusterResources()
+	if err != nil {
+		return err
+	}
+	instances := &cnsv1alpha1.CnsVolumeMetadataList{}
+	if err := gc.client.List(ctx, instances, client.InNamespace(namespace)); err != nil {
+		return err
+	}
+	guestClusters := make(map[string]map[string]bool)
+	for i := range instances.Items {
+		instance := &instances.Items[i]
+		if instance.DeletionTimestamp != nil || instance.Spec.GuestClusterID == "" ||
+			time.Since(instance.CreationTimestamp.Time) < garbageCollectionGracePeriod {
+			continue
+		}
+		uids, ok := guestClusters[instance.Namespace]
+		if !ok {
+			var err error
+			if uids, err = gc.getGuestClusterUIDs(ctx, resources, instance.Namespace); err != nil {
+				return err
+			}
+			guestClusters[instance.Namespace] = uids
+		}
+		if uids[instance.Spec.GuestClusterID] {
+			continue
+		}
+		if err := gc.client.Delete(ctx, instance); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		log.Infof("CnsVolumeMetadataGC: deleted CnsVolumeMetadata %s/%s of deleted guest cluster %q",
+			instance.Namespace, instance.Name, instance.Spec.GuestClusterID)
+	}
+	return nil
+}
+
+// getGuestClusterUIDs returns the UIDs of the guest clusters of the given
+// resources in the given namespace.
+func (gc *garbageCollector) getGuestClusterUIDs(ctx context.Context, resources []schema.GroupVersionResource,
+	namespace string) (map[string]bool, error) {
+	uids := make(map[string]bool)
+	for _, gvr := range resources {
+		guestClusters, err := gc.dynamicClient.Resource(gvr).Namespace(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, guestCluster := range guestClusters.Items {
+			uids[string(guestCluster.GetUID())] = true
+		}
+	}
+	return uids, nil
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cnsvolumemetadata
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	cnsoperatorapis "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator"
+	cnsv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsvolumemetadata/v1alpha1"
+)
+
+func newTestGuestCluster(namespace string, name string, uid string) *unstructured.Unstructured {
+	guestCluster := &unstructured.Unstructured{}
+	guestCluster.SetAPIVersion("run.tanzu.vmware.com/v1alpha2")
+	guestCluster.SetKind("TanzuKubernetesCluster")
+	guestCluster.SetNamespace(namespace)
+	guestCluster.SetName(name)
+	guestCluster.SetUID(types.UID(uid))
+	return guestCluster
+}
+
+func TestGarbageCollectCnsVolumeMetadata(t *testing.T) {
+	ctx := context.Background()
+	old := metav1.NewTime(time.Now().Add(-time.Hour))
+	newInstance := func(namespace string, name string, guestClusterID string,
+		created metav1.Time) *cnsv1alpha1.CnsVolumeMetadata {
+		return &cnsv1alpha1.CnsVolumeMetadata{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, CreationTimestamp: created},
+			Spec:       cnsv1alpha1.CnsVolumeMetadataSpec{GuestClusterID: guestClusterID},
+		}
+	}
+	s := runtime.NewScheme()
+	if err := cnsoperatorapis.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	crClient := fake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(
+		newInstance("ns-1", "live", "gc-1", old),
+		newInstance("ns-1", "stale", "gc-deleted", old),
+		newInstance("ns-1", "recent", "gc-new", metav1.Now()),
+		// The UID of a guest cluster of another namespace.
+		newInstance("ns-2", "other-namespace", "gc-1", old),
+	).Build()
+	// The supervisor cluster serves TanzuKubernetesClusters in v1alpha2, its
+	// preferred version, and v1alpha1.
+	tkcResources := &metav1.APIResourceList{
+		GroupVersion: "run.tanzu.vmware.com/v1alpha2",
+		APIResources: []metav1.APIResource{{Name: "tanzukubernetesclusters", Namespaced: true}},
+	}
+	discoveryClient := &fakeDiscovery{
+		FakeDiscovery: k8sfake.NewSimpleClientset().Discovery().(*fakediscovery.FakeDiscovery),
+	}
+	discoveryClient.Resources = []*metav1.APIResourceList{tkcResources, {
+		GroupVersion: "run.tanzu.vmware.com/v1alpha1",
+		APIResources: []metav1.APIResource{{Name: "tanzukubernetesclusters", Namespaced: true}},
+	}}
+	tkcGVR := schema.GroupVersionResource{
+		Group: "run.tanzu.vmware.com", Version: "v1alpha2", Resource: "tanzukubernetesclusters"}
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{tkcGVR: "TanzuKubernetesClusterList"},
+		newTestGuestCluster("ns-1", "gc-1", "gc-1"))
+
+	gc := newGarbageCollector(crClient, nil, discoveryClient, dynamicClient)
+	resources, err := gc.getGuestClusterResources()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resources) != 1 || resources[0] != tkcGVR {
+		t.Fatalf("expected only the TanzuKubernetesClusters to be served in v1alpha2, got %v", resources)
+	}
+	exists := func(namespace string, name string) bool {
+		err := crClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name},
+			&cnsv1alpha1.CnsVolumeMetadata{})
+		return err == nil
+	}
+
+	// Nothing is garbage collected while the guest cluster resources can't be
+	// discovered.
+	discoveryClient.err = errors.New("etcdserver: request timed out")
+	if err := gc.collect(ctx, "ns-1"); err == nil {
+		t.Errorf("expected the garbage collection to fail while the discovery fails")
+	}
+	discoveryClient.err = nil
+	tkcResources.APIResources = nil
+	if err := gc.collect(ctx, "ns-1"); err == nil {
+		t.Errorf("expected the garbage collection to fail while the preferred version doesn't serve " +
+			"TanzuKubernetesClusters")
+	}
+	if !exists("ns-1", "stale") {
+		t.Fatalf("expected the CnsVolumeMetadata instances to be kept while the discovery fails")
+	}
+	tkcResources.APIResources = []metav1.APIResource{{Name: "tanzukubernetesclusters", Namespaced: true}}
+
+	if err := gc.collect(ctx, "ns-1"); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		namespace string
+		name      string
+		expected  bool
+	}{
+		{"ns-1", "live", true},
+		{"ns-1", "stale", false},
+		{"ns-1", "recent", true},
+		{"ns-2", "other-namespace", true},
+	} {
+		if exists(test.namespace, test.name) != test.expected {
+			t.Errorf("expected CnsVolumeMetadata %s/%s to exist: %v", test.namespace, test.name, test.expected)
+		}
+	}
+
+	if err := gc.collect(ctx, metav1.NamespaceAll); err != nil {
+		t.Fatal(err)
+	}
+	if exists("ns-2", "other-namespace") {
+		t.Errorf("expected the CnsVolumeMetadata of a guest cluster of another namespace to be deleted")
+	}
+	if !exists("ns-1", "live") {
+		t.Errorf("expected the CnsVolumeMetadata of an existing guest cluster to be kept")
+	}
+}
+
+// fakeDiscovery is a fake discovery client whose discovery of the resources
+// of a group version fails with err, if set.
+type fakeDiscovery struct {
+	*fakediscovery.FakeDiscovery
+	err error
+}
+
+func (d *fakeDiscovery) ServerResourcesForGroupVersion(groupVersion string) (*metav1.APIResourceList, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	return d.FakeDiscovery.ServerResourcesForGroupVersion(groupVersion)
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cnsvolumemetadata
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cnsv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsvolumemetadata/v1alpha1"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	cnsoperatortypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/syncer/cnsoperator/types"
+)
+
+// protectSupervisorPVCs adds the guest PV protection finalizer to the
+// supervisor PVCs backing the guest PV of the given CnsVolumeMetadata
+// instance, so that they aren't deleted while the guest PV exists. PVCs
+// being deleted are left as is.
+func protectSupervisorPVCs(ctx context.Context, k8sclient kubernetes.Interface,
+	instance *cnsv1alpha1.CnsVolumeMetadata) error {
+	log := logger.GetLogger(ctx)
+	if instance.Spec.EntityType != cnsv1alpha1.CnsOperatorEntityTypePV {
+		return nil
+	}
+	for _, volume := range instance.Spec.VolumeNames {
+		pvc, err := k8sclient.CoreV1().PersistentVolumeClaims(instance.Namespace).Get(ctx, volume, metav1.GetOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		if pvc.DeletionTimestamp != nil || hasFinalizer(pvc.Finalizers, cnsoperatortypes.GuestPVProtectionFinalizer) {
+			continue
+		}
+		pvc.Finalizers = append(pvc.Finalizers, cnsoperatortypes.GuestPVProtectionFinalizer)
+		if _, err := k8sclient.CoreV1().PersistentVolumeClaims(instance.Namespace).Update(ctx, pvc,
+			metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+		log.Infof("ReconcileCnsVolumeMetadata: Added finalizer %q to PVC %s/%s backing guest PV %q",
+			cnsoperatortypes.GuestPVProtectionFinalizer, pvc.Namespace, pvc.Name, instance.Spec.EntityName)
+	}
+	return nil
+}
+
+// unprotectSupervisorPVCs removes the guest PV protection finalizer from the
+// supervisor PVCs backing the guest PV of the given CnsVolumeMetadata instance
+// being deleted, unless they back the PV of another CnsVolumeMetadata
+// instance of the namespace which isn't being deleted.
+func unprotectSupervisorPVCs(ctx context.Context, k8sclient kubernetes.Interface, crClient client.Client,
+	instance *cnsv1alpha1.CnsVolumeMetadata) error {
+	log := logger.GetLogger(ctx)
+	if instance.Spec.EntityType != cnsv1alpha1.CnsOperatorEntityTypePV {
+		return nil
+	}
+	var instances *cnsv1alpha1.CnsVolumeMetadataList
+	for _, volume := range instance.Spec.VolumeNames {
+		pvc, err := k8sclient.CoreV1().PersistentVolumeClaims(instance.Namespace).Get(ctx, volume, metav1.GetOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		if !hasFinalizer(pvc.Finalizers, cnsoperatortypes.GuestPVProtectionFinalizer) {
+			continue
+		}
+		if instances == nil {
+			instances = &cnsv1alpha1.CnsVolumeMetadataList{}
+			if err := crClient.List(ctx, instances, client.InNamespace(instance.Namespace)); err != nil {
+				return err
+			}
+		}
+		if owner := getOtherGuestPV(instances.Items, instance.Name, volume); owner != nil {
+			log.Infof("ReconcileCnsVolumeMetadata: PVC %s/%s also backs guest PV %q of CnsVolumeMetadata %q. "+
+				"Keeping finalizer %q.", pvc.Namespace, pvc.Name, owner.Spec.EntityName, owner.Name,
+				cnsoperatortypes.GuestPVProtectionFinalizer)
+			continue
+		}
+		var finalizers []string
+		for _, finalizer := range pvc.Finalizers {
+			if finalizer != cnsoperatortypes.GuestPVProtectionFinalizer {
+				finalizers = append(finalizers, finalizer)
+			}
+		}
+		pvc.Finalizers = finalizers
+		if _, err := k8sclient.CoreV1().PersistentVolumeClaims(instance.Namespace).Update(ctx, pvc,
+			metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+		log.Infof("ReconcileCnsVolumeMetadata: Removed finalizer %q from PVC %s/%s backing deleted guest PV %q",
+			cnsoperatortypes.GuestPVProtectionFinalizer, pvc.Namespace, pvc.Name, instance.Spec.EntityName)
+	}
+	return nil
+}
+
+// getOtherGuestPV returns the CnsVolumeMetadata instance of a guest PV backed
+// by the given supervisor PVC, other than the one with the given name and the
+// ones being deleted, or nil if there is none.
+func getOtherGuestPV(instances []cnsv1alpha1.CnsVolumeMetadata, name string,
+	volume string) *cnsv1alpha1.CnsVolumeMetadata {
+	for i, other := range instances {
+		if other.Name == name || other.DeletionTimestamp != nil ||
+			other.Spec.EntityType != cnsv1alpha1.CnsOperatorEntityTypePV {
+			continue
+		}
+		for _, otherVolume := range other.Spec.VolumeNames {
+			if otherVolume == volume {
+				return &instances[i]
+			}
+		}
+	}
+	return nil
+}
+
+// hasFinalizer returns true if the given finalizers contain the given one.
+func hasFinalizer(finalizers []string, finalizer string) bool {
+	for _, f := range finalizers {
+		if f == finalizer {
+			return true
+		}
+	}
+	return false
+}
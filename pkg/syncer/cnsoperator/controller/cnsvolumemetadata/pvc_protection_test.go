@@ -0,0 +1,112 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cnsvolumemetadata
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	cnsoperatorapis "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator"
+	cnsv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsvolumemetadata/v1alpha1"
+	cnsoperatortypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/syncer/cnsoperator/types"
+)
+
+func newTestVolumeMetadata(name string, entityType cnsv1alpha1.CnsOperatorEntityType,
+	volumeName string) *cnsv1alpha1.CnsVolumeMetadata {
+	return &cnsv1alpha1.CnsVolumeMetadata{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "sv-ns", Name: name},
+		Spec: cnsv1alpha1.CnsVolumeMetadataSpec{
+			VolumeNames:    []string{volumeName},
+			GuestClusterID: "gc",
+			EntityType:     entityType,
+			EntityName:     "guest-" + name,
+		},
+	}
+}
+
+func TestSupervisorPVCProtection(t *testing.T) {
+	ctx := context.Background()
+	k8sclient := k8sfake.NewSimpleClientset(
+		&v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Namespace: "sv-ns", Name: "gc-pvc-1",
+			Finalizers: []string{"kubernetes.io/pvc-protection"}}},
+		&v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Namespace: "sv-ns", Name: "gc-pvc-2"}},
+	)
+	pv1 := newTestVolumeMetadata("pv-1", cnsv1alpha1.CnsOperatorEntityTypePV, "gc-pvc-1")
+	pvc1 := newTestVolumeMetadata("pvc-1", cnsv1alpha1.CnsOperatorEntityTypePVC, "gc-pvc-2")
+	// Statically provisioned PVs of two guest clusters backed by the same
+	// supervisor PVC.
+	pv2 := newTestVolumeMetadata("pv-2", cnsv1alpha1.CnsOperatorEntityTypePV, "gc-pvc-2")
+	pv3 := newTestVolumeMetadata("pv-3", cnsv1alpha1.CnsOperatorEntityTypePV, "gc-pvc-2")
+	s := runtime.NewScheme()
+	if err := cnsoperatorapis.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	crClient := fake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(pv1, pvc1, pv2, pv3).Build()
+	getFinalizers := func(name string) []string {
+		pvc, err := k8sclient.CoreV1().PersistentVolumeClaims("sv-ns").Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pvc.Finalizers
+	}
+	protected := []string{"kubernetes.io/pvc-protection", cnsoperatortypes.GuestPVProtectionFinalizer}
+
+	for _, instance := range []*cnsv1alpha1.CnsVolumeMetadata{pv1, pvc1, pv1, pv2, pv3} {
+		if err := protectSupervisorPVCs(ctx, k8sclient, instance); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if finalizers := getFinalizers("gc-pvc-1"); !reflect.DeepEqual(finalizers, protected) {
+		t.Errorf("expected finalizers %v, got %v", protected, finalizers)
+	}
+	if finalizers := getFinalizers("gc-pvc-2"); !reflect.DeepEqual(finalizers,
+		[]string{cnsoperatortypes.GuestPVProtectionFinalizer}) {
+		t.Errorf("expected the PVC of the guest PVs to be protected, got finalizers %v", finalizers)
+	}
+
+	// The PVC of the deleted pv-2 still backs pv-3.
+	if err := unprotectSupervisorPVCs(ctx, k8sclient, crClient, pv2); err != nil {
+		t.Fatal(err)
+	}
+	if finalizers := getFinalizers("gc-pvc-2"); len(finalizers) != 1 {
+		t.Errorf("expected the PVC backing another guest PV to stay protected, got finalizers %v", finalizers)
+	}
+	if err := crClient.Delete(ctx, pv3); err != nil {
+		t.Fatal(err)
+	}
+	if err := unprotectSupervisorPVCs(ctx, k8sclient, crClient, pv2); err != nil {
+		t.Fatal(err)
+	}
+	if finalizers := getFinalizers("gc-pvc-2"); len(finalizers) != 0 {
+		t.Errorf("expected the PVC of the deleted guest PVs to be released, got finalizers %v", finalizers)
+	}
+
+	if err := unprotectSupervisorPVCs(ctx, k8sclient, crClient, pv1); err != nil {
+		t.Fatal(err)
+	}
+	if finalizers := getFinalizers("gc-pvc-1"); !reflect.DeepEqual(finalizers,
+		[]string{"kubernetes.io/pvc-protection"}) {
+		t.Errorf("expected only the guest PV protection finalizer to be removed, got %v", finalizers)
+	}
+}
@@ -0,0 +1,442 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cnsvolumerelocate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	"github.com/vmware/govmomi/vim25/soap"
+	vim25types "github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	apis "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator"
+	cnsvolumerelocatev1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsvolumerelocate/v1alpha1"
+	volumes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/volume"
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	commonconfig "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common/commonco"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+	k8s "sigs.k8s.io/vsphere-csi-driver/v2/pkg/kubernetes"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/syncer"
+)
+
+// defaultMaxWorkerThreadsForVolumeRelocate is kept low as every relocation
+// copies the whole backing disk of a volume.
+const defaultMaxWorkerThreadsForVolumeRelocate = 4
+
+// backOffDuration is a map of cnsvolumerelocate name's to the time after which
+// a request for this instance will be requeued.
+// Initialized to 1 second for new instances and for instances whose latest
+// reconcile operation succeeded.
+// If the reconcile fails, backoff is incremented exponentially.
+var (
+	backOffDuration         map[string]time.Duration
+	backOffDurationMapMutex = sync.Mutex{}
+)
+
+// Add creates a new CnsVolumeRelocate Controller and adds it to the Manager,
+// ConfigurationInfo and VirtualCenterTypes. The Manager will set fields on
+// the Controller and Start it when the Manager is Started.
+func Add(mgr manager.Manager, clusterFlavor cnstypes.CnsClusterFlavor,
+	configInfo *commonconfig.ConfigurationInfo, volumeManager volumes.Manager) error {
+	ctx, log := logger.GetNewContextWithLogger()
+	if clusterFlavor != cnstypes.CnsClusterFlavorVanilla {
+		log.Debug("Not initializing the CnsVolumeRelocate Controller as its a non-Vanilla CSI deployment")
+		return nil
+	}
+	if !commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.VolumeRelocation) {
+		log.Debug("Not initializing the CnsVolumeRelocate Controller as volume relocation is disabled")
+		return nil
+	}
+	// Initializes kubernetes client.
+	k8sclient, err := k8s.NewClient(ctx)
+	if err != nil {
+		log.Errorf("Creating Kubernetes client failed. Err: %v", err)
+		return err
+	}
+
+	// eventBroadcaster broadcasts events on cnsvolumerelocate instances to the
+	// event sink.
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(
+		&typedcorev1.EventSinkImpl{
+			Interface: k8sclient.CoreV1().Events(""),
+		},
+	)
+	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: apis.GroupName})
+	return add(mgr, &ReconcileCnsVolumeRelocate{client: mgr.GetClient(), scheme: mgr.GetScheme(),
+		k8sclient: k8sclient, configInfo: configInfo, volumeManager: volumeManager, recorder: recorder})
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler.
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	_, log := logger.GetNewContextWithLogger()
+
+	// Create a new controller.
+	c, err := controller.New("cnsvolumerelocate-controller", mgr,
+		controller.Options{Reconciler: r, MaxConcurrentReconciles: defaultMaxWorkerThreadsForVolumeRelocate})
+	if err != nil {
+		log.Errorf("Failed to create new CnsVolumeRelocate controller with error: %+v", err)
+		return err
+	}
+
+	backOffDuration = make(map[string]time.Duration)
+
+	// Watch for changes to primary resource CnsVolumeRelocate.
+	err = c.Watch(&source.Kind{Type: &cnsvolumerelocatev1alpha1.CnsVolumeRelocate{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		log.Errorf("Failed to watch for changes to CnsVolumeRelocate resource with error: %+v", err)
+		return err
+	}
+	return nil
+}
+
+// blank assignment to verify that ReconcileCnsVolumeRelocate implements
+// reconcile.Reconciler.
+var _ reconcile.Reconciler = &ReconcileCnsVolumeRelocate{}
+
+// ReconcileCnsVolumeRelocate reconciles a CnsVolumeRelocate object.
+type ReconcileCnsVolumeRelocate struct {
+	// This client, initialized using mgr.Client() above, is a split client
+	// that reads objects from the cache and writes to the apiserver.
+	client        client.Client
+	scheme        *runtime.Scheme
+	k8sclient     clientset.Interface
+	configInfo    *commonconfig.ConfigurationInfo
+	volumeManager volumes.Manager
+	recorder      record.EventRecorder
+}
+
+// Reconcile reads that state of the cluster for a CnsVolumeRelocate object
+// and relocates the volume bound to the PVC in CnsVolumeRelocate.Spec to the
+// datastore in CnsVolumeRelocate.Spec.
+// Note:
+// The Controller will requeue the Request to be processed again if the
+// returned error is non-nil or Result.Requeue is true. Otherwise, upon
+// completion it will remove the work from the queue.
+func (r *ReconcileCnsVolumeRelocate) Reconcile(ctx context.Context,
+	request reconcile.Request) (reconcile.Result, error) {
+	log := logger.GetLogger(ctx)
+	// Fetch the CnsVolumeRelocate instance.
+	instance := &cnsvolumerelocatev1alpha1.CnsVolumeRelocate{}
+	err := r.client.Get(ctx, request.NamespacedName, instance)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Infof("CnsVolumeRelocate resource not found. Ignoring since object must be deleted.")
+			return reconcile.Result{}, nil
+		}
+		log.Errorf("Error reading the CnsVolumeRelocate with name: %q on namespace: %q. Err: %+v",
+			request.Name, request.Namespace, err)
+		// Error reading the object - return with err.
+		return reconcile.Result{}, err
+	}
+	// Initialize backOffDuration for the instance, if required.
+	backOffDurationMapMutex.Lock()
+	var timeout time.Duration
+	if _, exists := backOffDuration[instance.Name]; !exists {
+		backOffDuration[instance.Name] = time.Second
+	}
+	timeout = backOffDuration[instance.Name]
+	backOffDurationMapMutex.Unlock()
+
+	// If the volume of the CnsVolumeRelocate instance is already relocated,
+	// remove the instance from the queue.
+	if instance.Status.Relocated {
+		backOffDurationMapMutex.Lock()
+		delete(backOffDuration, instance.Name)
+		backOffDurationMapMutex.Unlock()
+		return reconcile.Result{}, nil
+	}
+
+	log.Infof("Reconciling CnsVolumeRelocate with instance: %q from namespace: %q. timeout %q seconds",
+		instance.Name, request.Namespace, timeout)
+	pv, err := getBoundBlockPV(ctx, r.k8sclient, instance)
+	if err != nil {
+		log.Error(err.Error())
+		setInstanceError(ctx, r, instance, err.Error())
+		return reconcile.Result{RequeueAfter: timeout}, nil
+	}
+	volumeID := pv.Spec.CSI.VolumeHandle
+
+	volume, err := common.QueryVolumeByID(ctx, r.volumeManager, volumeID)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to query CNS volume: %s with error: %+v", volumeID, err)
+		log.Error(msg)
+		setInstanceError(ctx, r, instance, msg)
+		return reconcile.Result{RequeueAfter: timeout}, nil
+	}
+	sourceDatastoreURL := volume.DatastoreUrl
+	if instance.Status.SourceDatastoreURL != "" {
+		// The volume was relocated by a previous reconcile which failed
+		// afterwards.
+		sourceDatastoreURL = instance.Status.SourceDatastoreURL
+	}
+	vc, err := cnsvsphere.GetVirtualCenterInstance(ctx, r.configInfo, false)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to get virtual center instance with error: %+v", err)
+		log.Error(msg)
+		setInstanceError(ctx, r, instance, "Unable to connect to VC for volume relocation")
+		return reconcile.Result{RequeueAfter: timeout}, nil
+	}
+	if volume.DatastoreUrl != instance.Spec.DatastoreURL {
+		datastore, err := getDatastoreByURL(ctx, vc, instance.Spec.DatastoreURL)
+		if err != nil {
+			log.Error(err.Error())
+			setInstanceError(ctx, r, instance, err.Error())
+			return reconcile.Result{RequeueAfter: timeout}, nil
+		}
+		log.Infof("Relocating volume: %s of PVC: %q on namespace: %q from datastore: %q to datastore: %q",
+			volumeID, instance.Spec.PvcName, instance.Namespace, volume.DatastoreUrl, instance.Spec.DatastoreURL)
+		err = relocateVolume(ctx, r.volumeManager, volumeID, datastore)
+		if err != nil {
+			msg := fmt.Sprintf("Failed to relocate volume: %s to datastore: %q with error: %+v",
+				volumeID, instance.Spec.DatastoreURL, err)
+			log.Error(msg)
+			setInstanceError(ctx, r, instance, msg)
+			return reconcile.Result{RequeueAfter: timeout}, nil
+		}
+		log.Infof("Relocated volume: %s to datastore: %q", volumeID, instance.Spec.DatastoreURL)
+	}
+
+	if pv.Annotations[common.AnnVolumeDatastoreURL] != instance.Spec.DatastoreURL {
+		if pv.Annotations == nil {
+			pv.Annotations = make(map[string]string)
+		}
+		pv.Annotations[common.AnnVolumeDatastoreURL] = instance.Spec.DatastoreURL
+		updatedPV, err := r.k8sclient.CoreV1().PersistentVolumes().Update(ctx, pv, metav1.UpdateOptions{})
+		if err != nil {
+			instance.Status.SourceDatastoreURL = sourceDatastoreURL
+			msg := fmt.Sprintf("Failed to update annotations of PV: %s with error: %+v", pv.Name, err)
+			log.Error(msg)
+			setInstanceError(ctx, r, instance, msg)
+			return reconcile.Result{RequeueAfter: timeout}, nil
+		}
+		pv = updatedPV
+	}
+	// Update the CNS metadata of the volume right away rather than waiting
+	// for the metadata syncer to reflect the PV annotation.
+	err = r.volumeManager.UpdateVolumeMetadata(ctx, getPVMetadataUpdateSpec(ctx, r.configInfo, vc, pv))
+	if err != nil {
+		instance.Status.SourceDatastoreURL = sourceDatastoreURL
+		msg := fmt.Sprintf("Failed to update CNS metadata of volume: %s with error: %+v", volumeID, err)
+		log.Error(msg)
+		setInstanceError(ctx, r, instance, msg)
+		return reconcile.Result{RequeueAfter: timeout}, nil
+	}
+
+	// Update the instance to indicate the volume relocation is successful.
+	msg := fmt.Sprintf("Successfully relocated the volume of PVC: %s to datastore: %s",
+		instance.Spec.PvcName, instance.Spec.DatastoreURL)
+	err = setInstanceSuccess(ctx, r, instance, sourceDatastoreURL, msg)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to update CnsVolumeRelocate instance with error: %+v", err)
+		log.Error(msg)
+		setInstanceError(ctx, r, instance, msg)
+		return reconcile.Result{RequeueAfter: timeout}, nil
+	}
+	backOffDurationMapMutex.Lock()
+	delete(backOffDuration, instance.Name)
+	backOffDurationMapMutex.Unlock()
+	log.Info(msg)
+	return reconcile.Result{}, nil
+}
+
+// getBoundBlockPV returns the PV bound to the PVC of the given
+// CnsVolumeRelocate instance. Returns an error if the PVC isn't bound to a
+// block volume provisioned by the vSphere CSI driver.
+func getBoundBlockPV(ctx context.Context, k8sclient clientset.Interface,
+	instance *cnsvolumerelocatev1alpha1.CnsVolumeRelocate) (*v1.PersistentVolume, error) {
+	if instance.Spec.PvcName == "" || instance.Spec.DatastoreURL == "" {
+		return nil, errors.New("PvcName and DatastoreURL must be specified")
+	}
+	pvc, err := k8sclient.CoreV1().PersistentVolumeClaims(instance.Namespace).Get(ctx,
+		instance.Spec.PvcName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get PVC: %s on namespace: %s with error: %w",
+			instance.Spec.PvcName, instance.Namespace, err)
+	}
+	if pvc.Status.Phase != v1.ClaimBound {
+		return nil, fmt.Errorf("PVC: %s on namespace: %s is not bound", pvc.Name, pvc.Namespace)
+	}
+	pv, err := k8sclient.CoreV1().PersistentVolumes().Get(ctx, pvc.Spec.VolumeName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get PV: %s with error: %w", pvc.Spec.VolumeName, err)
+	}
+	if pv.Spec.CSI == nil || pv.Spec.CSI.Driver != csitypes.Name {
+		return nil, fmt.Errorf("PV: %s is not provisioned by %s", pv.Name, csitypes.Name)
+	}
+	if pv.Spec.CSI.VolumeAttributes[common.AttributeDiskType] == common.DiskTypeFileVolume {
+		return nil, fmt.Errorf("PV: %s is a file volume, only block volumes can be relocated", pv.Name)
+	}
+	return pv, nil
+}
+
+// getPVMetadataUpdateSpec returns the spec updating the CNS metadata of the
+// volume of the given PV with the labels of the PV, which include the
+// datastore the volume was relocated to.
+func getPVMetadataUpdateSpec(ctx context.Context, configInfo *commonconfig.ConfigurationInfo,
+	vc *cnsvsphere.VirtualCenter, pv *v1.PersistentVolume) *cnstypes.CnsVolumeMetadataUpdateSpec {
+	clusterID := configInfo.Cfg.Global.ClusterID
+	pvMetadata := cnsvsphere.GetCnsKubernetesEntityMetaData(pv.Name, syncer.GetPVMetadataLabels(ctx, pv), false,
+		string(cnstypes.CnsKubernetesEntityTypePV), "", clusterID, nil)
+	containerCluster := cnsvsphere.GetContainerCluster(clusterID, vc.Config.Username,
+		cnstypes.CnsClusterFlavorVanilla, configInfo.Cfg.Global.ClusterDistribution)
+	return &cnstypes.CnsVolumeMetadataUpdateSpec{
+		VolumeId: cnstypes.CnsVolumeId{Id: pv.Spec.CSI.VolumeHandle},
+		Metadata: cnstypes.CnsVolumeMetadata{
+			ContainerCluster:      containerCluster,
+			ContainerClusterArray: []cnstypes.CnsContainerCluster{containerCluster},
+			EntityMetadata:        []cnstypes.BaseCnsEntityMetadata{cnstypes.BaseCnsEntityMetadata(pvMetadata)},
+		},
+	}
+}
+
+// getDatastoreByURL returns the datastore with the given URL in the
+// datacenters of the given vCenter.
+func getDatastoreByURL(ctx context.Context, vc *cnsvsphere.VirtualCenter,
+	datastoreURL string) (*cnsvsphere.DatastoreInfo, error) {
+	datacenters, err := vc.GetDatacenters(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get datacenters with error: %w", err)
+	}
+	for _, datacenter := range datacenters {
+		datastores, err := datacenter.GetAllDatastores(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get datastores of datacenter: %s with error: %w",
+				datacenter.InventoryPath, err)
+		}
+		if datastore, exists := datastores[datastoreURL]; exists {
+			return datastore, nil
+		}
+	}
+	return nil, fmt.Errorf("datastore: %q not found in vCenter: %s", datastoreURL, vc.Config.Host)
+}
+
+// relocateVolume relocates the given block volume to the given datastore and
+// waits for the relocation to complete.
+func relocateVolume(ctx context.Context, volumeManager volumes.Manager, volumeID string,
+	datastore *cnsvsphere.DatastoreInfo) error {
+	log := logger.GetLogger(ctx)
+	relocateSpec := cnstypes.NewCnsBlockVolumeRelocateSpec(volumeID, datastore.Reference())
+	task, err := volumeManager.RelocateVolume(ctx, relocateSpec)
+	if err != nil {
+		// The volume is already on the target datastore.
+		if soap.IsSoapFault(err) {
+			if _, isAlreadyExistErr := soap.ToSoapFault(err).VimFault().(vim25types.AlreadyExists); isAlreadyExistErr {
+				return nil
+			}
+		}
+		return err
+	}
+	taskInfo, err := task.WaitForResult(ctx)
+	if err != nil {
+		return err
+	}
+	results, ok := taskInfo.Result.(cnstypes.CnsVolumeOperationBatchResult)
+	if !ok {
+		return fmt.Errorf("unexpected result of relocate task: %+v", taskInfo.Result)
+	}
+	for _, result := range results.VolumeResults {
+		if fault := result.GetCnsVolumeOperationResult().Fault; fault != nil {
+			log.Errorf("Fault: %+v encountered while relocating volume %v", fault, volumeID)
+			return errors.New(fault.LocalizedMessage)
+		}
+	}
+	return nil
+}
+
+// setInstanceError sets error and records an event on the CnsVolumeRelocate
+// instance.
+func setInstanceError(ctx context.Context, r *ReconcileCnsVolumeRelocate,
+	instance *cnsvolumerelocatev1alpha1.CnsVolumeRelocate, errMsg string) {
+	log := logger.GetLogger(ctx)
+	instance.Status.Error = errMsg
+	err := updateCnsVolumeRelocate(ctx, r.client, instance)
+	if err != nil {
+		log.Errorf("updateCnsVolumeRelocate failed. err: %v", err)
+	}
+	recordEvent(ctx, r, instance, v1.EventTypeWarning, errMsg)
+}
+
+// setInstanceSuccess sets instance to success and records an event on the
+// CnsVolumeRelocate instance.
+func setInstanceSuccess(ctx context.Context, r *ReconcileCnsVolumeRelocate,
+	instance *cnsvolumerelocatev1alpha1.CnsVolumeRelocate, sourceDatastoreURL string, msg string) error {
+	instance.Status.Relocated = true
+	instance.Status.SourceDatastoreURL = sourceDatastoreURL
+	instance.Status.Error = ""
+	err := updateCnsVolumeRelocate(ctx, r.client, instance)
+	if err != nil {
+		return err
+	}
+	recordEvent(ctx, r, instance, v1.EventTypeNormal, msg)
+	return nil
+}
+
+// recordEvent records the event, sets the backOffDuration for the instance
+// appropriately and logs the message.
+// backOffDuration is reset to 1 second on success and doubled on failure.
+func recordEvent(ctx context.Context, r *ReconcileCnsVolumeRelocate,
+	instance *cnsvolumerelocatev1alpha1.CnsVolumeRelocate, eventtype string, msg string) {
+	log := logger.GetLogger(ctx)
+	log.Debugf("Event type is %s", eventtype)
+	switch eventtype {
+	case v1.EventTypeWarning:
+		// Double backOff duration.
+		backOffDurationMapMutex.Lock()
+		backOffDuration[instance.Name] = backOffDuration[instance.Name] * 2
+		r.recorder.Event(instance, v1.EventTypeWarning, "CnsVolumeRelocateFailed", msg)
+		backOffDurationMapMutex.Unlock()
+	case v1.EventTypeNormal:
+		// Reset backOff duration to one second.
+		backOffDurationMapMutex.Lock()
+		backOffDuration[instance.Name] = time.Second
+		r.recorder.Event(instance, v1.EventTypeNormal, "CnsVolumeRelocateSucceeded", msg)
+		backOffDurationMapMutex.Unlock()
+	}
+}
+
+// updateCnsVolumeRelocate updates the CnsVolumeRelocate instance in K8S.
+func updateCnsVolumeRelocate(ctx context.Context, client client.Client,
+	instance *cnsvolumerelocatev1alpha1.CnsVolumeRelocate) error {
+	log := logger.GetLogger(ctx)
+	err := client.Update(ctx, instance)
+	if err != nil {
+		log.Errorf("Failed to update CnsVolumeRelocate instance: %q on namespace: %q. Error: %+v",
+			instance.Name, instance.Namespace, err)
+	}
+	return err
+}
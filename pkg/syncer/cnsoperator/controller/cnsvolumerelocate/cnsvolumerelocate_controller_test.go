@@ -0,0 +1,137 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cnsvolumerelocate
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	cnsvolumerelocatev1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsvolumerelocate/v1alpha1"
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	commonconfig "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+// TestGetBoundBlockPV tests that only PVCs bound to block volumes of the
+// vSphere CSI driver can be relocated.
+func TestGetBoundBlockPV(t *testing.T) {
+	ctx := context.Background()
+	newPVC := func(name string, phase v1.PersistentVolumeClaimPhase) *v1.PersistentVolumeClaim {
+		return &v1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"},
+			Spec:       v1.PersistentVolumeClaimSpec{VolumeName: "pv-" + name},
+			Status:     v1.PersistentVolumeClaimStatus{Phase: phase},
+		}
+	}
+	newPV := func(name string, csi *v1.CSIPersistentVolumeSource) *v1.PersistentVolume {
+		return &v1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pv-" + name},
+			Spec: v1.PersistentVolumeSpec{
+				PersistentVolumeSource: v1.PersistentVolumeSource{CSI: csi},
+			},
+		}
+	}
+	k8sclient := fake.NewSimpleClientset(
+		newPVC("block", v1.ClaimBound),
+		newPV("block", &v1.CSIPersistentVolumeSource{Driver: csitypes.Name, VolumeHandle: "fcd-1"}),
+		newPVC("pending", v1.ClaimPending),
+		newPVC("file", v1.ClaimBound),
+		newPV("file", &v1.CSIPersistentVolumeSource{Driver: csitypes.Name, VolumeHandle: "file:share-1",
+			VolumeAttributes: map[string]string{common.AttributeDiskType: common.DiskTypeFileVolume}}),
+		newPVC("other", v1.ClaimBound),
+		newPV("other", &v1.CSIPersistentVolumeSource{Driver: "other.csi.driver", VolumeHandle: "vol-1"}),
+	)
+	tests := []struct {
+		pvcName      string
+		datastoreURL string
+		expectErr    bool
+	}{
+		{pvcName: "block", datastoreURL: "ds:///vmfs/volumes/ds-1/"},
+		{pvcName: "block", expectErr: true},
+		{pvcName: "missing", datastoreURL: "ds:///vmfs/volumes/ds-1/", expectErr: true},
+		{pvcName: "pending", datastoreURL: "ds:///vmfs/volumes/ds-1/", expectErr: true},
+		{pvcName: "file", datastoreURL: "ds:///vmfs/volumes/ds-1/", expectErr: true},
+		{pvcName: "other", datastoreURL: "ds:///vmfs/volumes/ds-1/", expectErr: true},
+	}
+	for _, test := range tests {
+		instance := &cnsvolumerelocatev1alpha1.CnsVolumeRelocate{
+			ObjectMeta: metav1.ObjectMeta{Name: "relocate", Namespace: "ns"},
+			Spec: cnsvolumerelocatev1alpha1.CnsVolumeRelocateSpec{
+				PvcName:      test.pvcName,
+				DatastoreURL: test.datastoreURL,
+			},
+		}
+		pv, err := getBoundBlockPV(ctx, k8sclient, instance)
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("PVC %q: expected an error, got PV %v", test.pvcName, pv.Name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("PVC %q: unexpected error: %v", test.pvcName, err)
+		} else if pv.Spec.CSI.VolumeHandle != "fcd-1" {
+			t.Errorf("PVC %q: expected volume fcd-1, got %s", test.pvcName, pv.Spec.CSI.VolumeHandle)
+		}
+	}
+}
+
+// TestGetPVMetadataUpdateSpec tests that the CNS metadata of a relocated
+// volume holds the datastore it was relocated to.
+func TestGetPVMetadataUpdateSpec(t *testing.T) {
+	ctx := context.Background()
+	configInfo := &commonconfig.ConfigurationInfo{Cfg: &commonconfig.Config{}}
+	configInfo.Cfg.Global.ClusterID = "cluster-1"
+	vc := &cnsvsphere.VirtualCenter{Config: &cnsvsphere.VirtualCenterConfig{Username: "user"}}
+	pv := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "pv-1",
+			Labels:      map[string]string{"app": "db"},
+			Annotations: map[string]string{common.AnnVolumeDatastoreURL: "ds:///vmfs/volumes/ds-2/"},
+		},
+		Spec: v1.PersistentVolumeSpec{
+			PersistentVolumeSource: v1.PersistentVolumeSource{
+				CSI: &v1.CSIPersistentVolumeSource{Driver: csitypes.Name, VolumeHandle: "fcd-1"},
+			},
+		},
+	}
+	updateSpec := getPVMetadataUpdateSpec(ctx, configInfo, vc, pv)
+	if updateSpec.VolumeId.Id != "fcd-1" || updateSpec.Metadata.ContainerCluster.ClusterId != "cluster-1" ||
+		updateSpec.Metadata.ContainerCluster.VSphereUser != "user" {
+		t.Fatalf("unexpected update spec %+v", updateSpec)
+	}
+	if len(updateSpec.Metadata.EntityMetadata) != 1 {
+		t.Fatalf("expected the metadata of the PV only, got %+v", updateSpec.Metadata.EntityMetadata)
+	}
+	pvMetadata := updateSpec.Metadata.EntityMetadata[0].(*cnstypes.CnsKubernetesEntityMetadata)
+	labels := make(map[string]string)
+	for _, label := range pvMetadata.Labels {
+		labels[label.Key] = label.Value
+	}
+	expected := map[string]string{"app": "db", common.AnnVolumeDatastoreURL: "ds:///vmfs/volumes/ds-2/"}
+	if pvMetadata.EntityName != "pv-1" || !reflect.DeepEqual(labels, expected) {
+		t.Errorf("expected the metadata of pv-1 with labels %v, got %s with labels %v", expected,
+			pvMetadata.EntityName, labels)
+	}
+}
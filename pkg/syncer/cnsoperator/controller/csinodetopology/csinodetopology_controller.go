@@ -54,7 +54,14 @@ import (
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/syncer"
 )
 
-const defaultMaxWorkerThreadsForCSINodeTopology = 1
+const (
+	defaultMaxWorkerThreadsForCSINodeTopology = 1
+	// maxBackOffDurationForCSINodeTopology is the maximum duration after which
+	// a failed request for a csinodetopology instance is requeued, so that the
+	// topology lookup is retried while the node plugin is waiting for it in
+	// NodeGetInfo.
+	maxBackOffDurationForCSINodeTopology = 30 * time.Second
+)
 
 // backOffDuration is a map of csinodetopology instance name to the time after
 // which a request for this instance will be requeued. Initialized to 1 second
@@ -172,7 +179,12 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		UpdateFunc: func(e event.UpdateEvent) bool {
 			// The CO calls NodeGetInfo API just once during the node registration,
 			// therefore we do not support updates to the spec after the CR has
-			// been reconciled.
+			// been reconciled. The topology is only discovered again when
+			// requested, e.g. after the node VM was migrated.
+			if _, ok := e.ObjectNew.GetAnnotations()[common.AnnRediscoverNodeTopology]; ok {
+				log.Infof("Rediscovering the topology of CSINodeTopology %q", e.ObjectNew.GetName())
+				return true
+			}
 			log.Debug("Ignoring CSINodeTopology reconciliation on update event")
 			return false
 		},
@@ -246,6 +258,10 @@ func (r *ReconcileCSINodeTopology) reconcileForVanilla(ctx context.Context, requ
 		return reconcile.Result{}, err
 	}
 
+	// The request to discover the topology again is cleared with the update
+	// of the status.
+	delete(instance.Annotations, common.AnnRediscoverNodeTopology)
+
 	// Initialize backOffDuration for the instance, if required.
 	backOffDurationMapMutex.Lock()
 	var timeout time.Duration
@@ -290,7 +306,7 @@ func (r *ReconcileCSINodeTopology) reconcileForVanilla(ctx context.Context, requ
 	}
 
 	// Retrieve topology labels for nodeVM.
-	if r.configInfo.Cfg.Labels.TopologyCategories == "" &&
+	if r.configInfo.Cfg.Labels.TopologyCategories == "" && r.configInfo.Cfg.Labels.TopologyHierarchy == "" &&
 		r.configInfo.Cfg.Labels.Zone == "" && r.configInfo.Cfg.Labels.Region == "" {
 		// Not a topology aware setup.
 		// Set the Status to Success and return.
@@ -300,7 +316,7 @@ func (r *ReconcileCSINodeTopology) reconcileForVanilla(ctx context.Context, requ
 		if err != nil {
 			return reconcile.Result{RequeueAfter: timeout}, nil
 		}
-	} else if r.configInfo.Cfg.Labels.TopologyCategories != "" ||
+	} else if r.configInfo.Cfg.Labels.TopologyCategories != "" || r.configInfo.Cfg.Labels.TopologyHierarchy != "" ||
 		(r.configInfo.Cfg.Labels.Zone != "" && r.configInfo.Cfg.Labels.Region != "") {
 		log.Infof("Detected a topology aware cluster")
 
@@ -408,7 +424,7 @@ func getNodeTopologyInfoForGuest(ctx context.Context, instance *csinodetopologyv
 	var err error
 	if err = vmOperatorClient.Get(ctx, vmKey, virtualMachine); err != nil {
 		return nil, logger.LogNewErrorf(log,
-			"failed to get VirtualMachines for the node: %q. Error: %+v", instance.Name, err)
+			"failed to get VirtualMachines for the node: %q. Error: %w", instance.Name, err)
 	}
 
 	var topologyLabels []csinodetopologyv1alpha1.TopologyLabel
@@ -440,6 +456,9 @@ func updateCRStatus(ctx context.Context, r *ReconcileCSINodeTopology, instance *
 		// Increase backoff duration for the instance.
 		backOffDurationMapMutex.Lock()
 		backOffDuration[instance.Name] = backOffDuration[instance.Name] * 2
+		if backOffDuration[instance.Name] > maxBackOffDurationForCSINodeTopology {
+			backOffDuration[instance.Name] = maxBackOffDurationForCSINodeTopology
+		}
 		backOffDurationMapMutex.Unlock()
 
 		// Record an event on the CR.
@@ -500,12 +519,14 @@ func getNodeTopologyInfo(ctx context.Context, nodeVM *cnsvsphere.VirtualMachine,
 	}
 
 	// Populate topology labels for NodeVM corresponding to each category in topologyCategoriesMap map.
-	err = nodeVM.GetTopologyLabels(ctx, tagManager, topologyCategoriesMap)
-	if err != nil {
-		log.Errorf("failed to get accessibleTopology for nodeVM: %v, Error: %v", nodeVM.Reference(), err)
-		return nil, err
+	if len(topologyCategoriesMap) > 0 {
+		err = nodeVM.GetTopologyLabels(ctx, tagManager, topologyCategoriesMap)
+		if err != nil {
+			log.Errorf("failed to get accessibleTopology for nodeVM: %v, Error: %v", nodeVM.Reference(), err)
+			return nil, err
+		}
+		log.Infof("NodeVM %q belongs to topology: %+v", nodeVM.Reference(), topologyCategoriesMap)
 	}
-	log.Infof("NodeVM %q belongs to topology: %+v", nodeVM.Reference(), topologyCategoriesMap)
 	topologyLabels := make([]csinodetopologyv1alpha1.TopologyLabel, 0)
 	// When zone and region parameters are used in vSphere config,
 	// read the TopologyCategory for labels.
@@ -547,5 +568,19 @@ func getNodeTopologyInfo(ctx context.Context, nodeVM *cnsvsphere.VirtualMachine,
 				csinodetopologyv1alpha1.TopologyLabel{Key: common.TopologyLabelsDomain + "/" + key, Value: val})
 		}
 	}
+	// Add the datacenter, cluster and host of the NodeVM when a topology
+	// hierarchy is given in vSphere config.
+	if hierarchyLevels := cnsconfig.GetTopologyHierarchyLevels(cfg); len(hierarchyLevels) > 0 {
+		hierarchy, err := nodeVM.GetTopologyHierarchy(ctx, hierarchyLevels)
+		if err != nil {
+			log.Errorf("failed to get topology hierarchy for nodeVM: %v, Error: %v", nodeVM.Reference(), err)
+			return nil, err
+		}
+		log.Infof("NodeVM %q belongs to topology hierarchy: %+v", nodeVM.Reference(), hierarchy)
+		for _, level := range hierarchyLevels {
+			topologyLabels = append(topologyLabels, csinodetopologyv1alpha1.TopologyLabel{
+				Key: cnsconfig.GetTopologyHierarchyKey(level), Value: hierarchy[level]})
+		}
+	}
 	return topologyLabels, nil
 }
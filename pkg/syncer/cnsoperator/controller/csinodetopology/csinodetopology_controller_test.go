@@ -190,3 +190,31 @@ func TestCSINodeTopologyControllerForTKGSHA(t *testing.T) {
 		})
 	}
 }
+
+func TestCSINodeTopologyBackOffIsCapped(t *testing.T) {
+	testCSINodeTopology := &csinodetopologyv1alpha1.CSINodeTopology{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-csinodetopology-name"},
+	}
+	s := scheme.Scheme
+	s.AddKnownTypes(csinodetopologyv1alpha1.SchemeGroupVersion, testCSINodeTopology)
+	s.AddKnownTypes(vmoperatortypes.SchemeGroupVersion, &vmoperatortypes.VirtualMachine{})
+	r := &ReconcileCSINodeTopology{
+		client:              fake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(testCSINodeTopology).Build(),
+		scheme:              s,
+		configInfo:          &cnsconfig.ConfigurationInfo{},
+		recorder:            record.NewFakeRecorder(1024),
+		enableTKGsHAinGuest: true,
+		// The VM of the node isn't found.
+		vmOperatorClient:    fake.NewClientBuilder().WithScheme(s).Build(),
+		supervisorNamespace: "test-supervisor-namespace",
+	}
+	backOffDuration = map[string]time.Duration{testCSINodeTopology.Name: 20 * time.Second}
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: testCSINodeTopology.Name}}
+
+	res, err := r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, reconcile.Result{RequeueAfter: 20 * time.Second}, res)
+	res, err = r.Reconcile(context.TODO(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, reconcile.Result{RequeueAfter: maxBackOffDurationForCSINodeTopology}, res)
+}
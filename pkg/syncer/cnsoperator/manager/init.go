@@ -27,11 +27,13 @@ import (
 	cnstypes "github.com/vmware/govmomi/cns/types"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
 	cnsoperatorconfig "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/config"
 	internalapiscnsoperatorconfig "sigs.k8s.io/vsphere-csi-driver/v2/pkg/internalapis/cnsoperator/config"
+	cnsstoragequotaconfig "sigs.k8s.io/vsphere-csi-driver/v2/pkg/internalapis/cnsstoragequota/config"
 	csinodetopologyconfig "sigs.k8s.io/vsphere-csi-driver/v2/pkg/internalapis/csinodetopology/config"
 
 	cnsoperatorv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator"
@@ -45,6 +47,7 @@ import (
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/internalapis"
 	triggercsifullsyncv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/internalapis/cnsoperator/triggercsifullsync/v1alpha1"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/internalapis/cnsstoragequota"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/internalapis/csinodetopology"
 	csinodetopologyv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/internalapis/csinodetopology/v1alpha1"
 	k8s "sigs.k8s.io/vsphere-csi-driver/v2/pkg/kubernetes"
@@ -160,31 +163,72 @@ func InitCnsOperator(ctx context.Context, clusterFlavor cnstypes.CnsClusterFlavo
 		}
 
 		if !stretchedSupervisor {
-			// Clean up routine to cleanup successful CnsRegisterVolume instances.
-			err = watcher(ctx, cnsOperator)
+			err = startCnsRegisterVolumeCleanup(ctx, cnsOperator, restConfig)
 			if err != nil {
-				log.Error("Failed to watch on config file for changes to CnsRegisterVolumesCleanupIntervalInMin. Error: %+v",
-					err)
 				return err
 			}
-			go func() {
-				for {
-					ctx, log = logger.GetNewContextWithLogger()
-					log.Infof("Triggering CnsRegisterVolume cleanup routine")
-					cleanUpCnsRegisterVolumeInstances(ctx, restConfig,
-						cnsOperator.configInfo.Cfg.Global.CnsRegisterVolumesCleanupIntervalInMin)
-					log.Infof("Completed CnsRegisterVolume cleanup")
-					for i := 1; i <= cnsOperator.configInfo.Cfg.Global.CnsRegisterVolumesCleanupIntervalInMin; i++ {
-						time.Sleep(time.Duration(1 * time.Minute))
-					}
-				}
-			}()
 		}
 	} else if clusterFlavor == cnstypes.CnsClusterFlavorVanilla {
+		if cnsOperator.coCommonInterface.IsFSSEnabled(ctx, common.VanillaVolumeRegistration) {
+			// Create CnsRegisterVolume CRD from manifest.
+			err = k8s.CreateCustomResourceDefinitionFromManifest(ctx, cnsoperatorconfig.EmbedCnsRegisterVolumeCRFile,
+				cnsoperatorconfig.EmbedCnsRegisterVolumeCRFileName)
+			if err != nil {
+				log.Errorf("Failed to create %q CRD. Err: %+v", cnsoperatorv1alpha1.CnsRegisterVolumePlural, err)
+				return err
+			}
+			err = startCnsRegisterVolumeCleanup(ctx, cnsOperator, restConfig)
+			if err != nil {
+				return err
+			}
+		}
+		if cnsOperator.coCommonInterface.IsFSSEnabled(ctx, common.VolumeRelocation) {
+			// Create CnsVolumeRelocate CRD from manifest.
+			err = k8s.CreateCustomResourceDefinitionFromManifest(ctx, cnsoperatorconfig.EmbedCnsVolumeRelocateCRFile,
+				cnsoperatorconfig.EmbedCnsVolumeRelocateCRFileName)
+			if err != nil {
+				log.Errorf("Failed to create %q CRD. Err: %+v", cnsoperatorv1alpha1.CnsVolumeRelocatePlural, err)
+				return err
+			}
+		}
+		if cnsOperator.coCommonInterface.IsFSSEnabled(ctx, common.EphemeralInlineVolume) {
+			// Create CnsEphemeralVolume CRD from manifest.
+			err = k8s.CreateCustomResourceDefinitionFromManifest(ctx, cnsoperatorconfig.EmbedCnsEphemeralVolumeCRFile,
+				cnsoperatorconfig.EmbedCnsEphemeralVolumeCRFileName)
+			if err != nil {
+				log.Errorf("Failed to create %q CRD. Err: %+v", cnsoperatorv1alpha1.CnsEphemeralVolumePlural, err)
+				return err
+			}
+			// Initialize node manager so that CnsEphemeralVolume controller
+			// can attach the volumes to the NodeVM of the spec. It is
+			// initialized below along with the CSINodeTopology controller
+			// otherwise.
+			if !cnsOperator.coCommonInterface.IsFSSEnabled(ctx, common.ImprovedVolumeTopology) {
+				nodeMgr := &node.Nodes{}
+				err = nodeMgr.Initialize(ctx, cnsOperator.coCommonInterface.IsFSSEnabled(ctx, common.UseCSINodeId))
+				if err != nil {
+					log.Errorf("failed to initialize nodeManager. Error: %+v", err)
+					return err
+				}
+			}
+		}
 		if cnsOperator.coCommonInterface.IsFSSEnabled(ctx, common.ImprovedVolumeTopology) {
-			// Create CSINodeTopology CRD.
-			err = k8s.CreateCustomResourceDefinitionFromManifest(ctx, csinodetopologyconfig.EmbedCSINodeTopologyFile,
-				csinodetopologyconfig.EmbedCSINodeTopologyFileName)
+			if cnsOperator.coCommonInterface.IsFSSEnabled(ctx, common.CRDConversionWebhook) {
+				// Create CSINodeTopology CRD serving the v1beta1 version too.
+				// v1alpha1 stays the storage version, so that drivers which
+				// only know v1alpha1 can still read the instances on rollback.
+				var caBundle []byte
+				caBundle, err = k8s.GetValidatingWebhookCABundle(ctx, common.ValidatingWebhookConfigName)
+				if err == nil {
+					err = k8s.CreateCustomResourceDefinitionFromManifestWithCABundle(ctx,
+						csinodetopologyconfig.EmbedCSINodeTopologyV1beta1File,
+						csinodetopologyconfig.EmbedCSINodeTopologyV1beta1FileName, caBundle)
+				}
+			} else {
+				// Create CSINodeTopology CRD.
+				err = k8s.CreateCustomResourceDefinitionFromManifest(ctx, csinodetopologyconfig.EmbedCSINodeTopologyFile,
+					csinodetopologyconfig.EmbedCSINodeTopologyFileName)
+			}
 			if err != nil {
 				log.Errorf("Failed to create %q CRD. Error: %+v", csinodetopology.CRDSingular, err)
 				return err
@@ -202,11 +246,34 @@ func InitCnsOperator(ctx context.Context, clusterFlavor cnstypes.CnsClusterFlavo
 				return err
 			}
 		}
+		if cnsOperator.coCommonInterface.IsFSSEnabled(ctx, common.StorageQuotaValidation) {
+			// Create CnsStorageQuota CRD which is used by the webhook to
+			// validate PVC requests.
+			err = k8s.CreateCustomResourceDefinitionFromManifest(ctx, cnsstoragequotaconfig.EmbedCnsStorageQuotaFile,
+				cnsstoragequotaconfig.EmbedCnsStorageQuotaFileName)
+			if err != nil {
+				log.Errorf("Failed to create %q CRD. Error: %+v", cnsstoragequota.CRDSingular, err)
+				return err
+			}
+		}
 	} else if clusterFlavor == cnstypes.CnsClusterFlavorGuest {
 		if cnsOperator.coCommonInterface.IsFSSEnabled(ctx, common.TKGsHA) {
-			// Create CSINodeTopology CRD.
-			err = k8s.CreateCustomResourceDefinitionFromManifest(ctx, csinodetopologyconfig.EmbedCSINodeTopologyFile,
-				csinodetopologyconfig.EmbedCSINodeTopologyFileName)
+			if cnsOperator.coCommonInterface.IsFSSEnabled(ctx, common.CRDConversionWebhook) {
+				// Create CSINodeTopology CRD serving the v1beta1 version too.
+				// v1alpha1 stays the storage version, so that drivers which
+				// only know v1alpha1 can still read the instances on rollback.
+				var caBundle []byte
+				caBundle, err = k8s.GetValidatingWebhookCABundle(ctx, common.ValidatingWebhookConfigName)
+				if err == nil {
+					err = k8s.CreateCustomResourceDefinitionFromManifestWithCABundle(ctx,
+						csinodetopologyconfig.EmbedCSINodeTopologyV1beta1File,
+						csinodetopologyconfig.EmbedCSINodeTopologyV1beta1FileName, caBundle)
+				}
+			} else {
+				// Create CSINodeTopology CRD.
+				err = k8s.CreateCustomResourceDefinitionFromManifest(ctx, csinodetopologyconfig.EmbedCSINodeTopologyFile,
+					csinodetopologyconfig.EmbedCSINodeTopologyFileName)
+			}
 			if err != nil {
 				log.Errorf("Failed to create %q CRD. Error: %+v", csinodetopology.CRDSingular, err)
 				return err
@@ -295,7 +362,8 @@ func InitCommonModules(ctx context.Context, clusterFlavor cnstypes.CnsClusterFla
 		// Check if TriggerCsiFullSync instance is present. If not present,
 		// create the TriggerCsiFullSync instance with name "csifullsync".
 		// If present, update the TriggerCsiFullSync.Status.InProgress to false if
-		// a full sync is already running.
+		// a full sync is already running, and clear the completion time of the
+		// initial full sync, which is tracked since the syncer started.
 		triggerCsiFullSyncInstance := &triggercsifullsyncv1alpha1.TriggerCsiFullSync{}
 		key := k8stypes.NamespacedName{Namespace: "", Name: common.TriggerCsiFullSyncCRName}
 		if err := cnsOperatorClient.Get(ctx, key, triggerCsiFullSyncInstance); err != nil {
@@ -314,11 +382,12 @@ func InitCommonModules(ctx context.Context, clusterFlavor cnstypes.CnsClusterFla
 				return err
 			}
 		}
-		if triggerCsiFullSyncInstance.Status.InProgress {
-			log.Infof("Found %q instance with InProgress set to true on syncer startup. "+
-				"Resetting InProgress field to false as no full sync is currently running",
-				common.TriggerCsiFullSyncCRName)
+		if triggerCsiFullSyncInstance.Status.InProgress ||
+			triggerCsiFullSyncInstance.Status.InitialFullSyncCompletedTimeStamp != nil {
+			log.Infof("Resetting InProgress and InitialFullSyncCompletedTimeStamp fields of %q instance "+
+				"on syncer startup as no full sync has run yet", common.TriggerCsiFullSyncCRName)
 			triggerCsiFullSyncInstance.Status.InProgress = false
+			triggerCsiFullSyncInstance.Status.InitialFullSyncCompletedTimeStamp = nil
 			if err := cnsOperatorClient.Update(ctx, triggerCsiFullSyncInstance); err != nil {
 				log.Errorf("Failed to update TriggerCsiFullSync instance: %q with Status.InProgress set to false. "+
 					"Error: %v", common.TriggerCsiFullSyncCRName, err)
@@ -331,6 +400,32 @@ func InitCommonModules(ctx context.Context, clusterFlavor cnstypes.CnsClusterFla
 
 // watcher watches on the vsphere.conf file mounted as secret within the syncer
 // container.
+// startCnsRegisterVolumeCleanup starts the routine cleaning up successful
+// CnsRegisterVolume instances, along with the watcher reloading the cleanup
+// interval from the config.
+func startCnsRegisterVolumeCleanup(ctx context.Context, cnsOperator *cnsOperator, restConfig *rest.Config) error {
+	log := logger.GetLogger(ctx)
+	err := watcher(ctx, cnsOperator)
+	if err != nil {
+		log.Errorf("Failed to watch on config file for changes to CnsRegisterVolumesCleanupIntervalInMin. Error: %+v",
+			err)
+		return err
+	}
+	go func() {
+		for {
+			ctx, log := logger.GetNewContextWithLogger()
+			log.Infof("Triggering CnsRegisterVolume cleanup routine")
+			cleanUpCnsRegisterVolumeInstances(ctx, restConfig,
+				cnsOperator.configInfo.Cfg.Global.CnsRegisterVolumesCleanupIntervalInMin)
+			log.Infof("Completed CnsRegisterVolume cleanup")
+			for i := 1; i <= cnsOperator.configInfo.Cfg.Global.CnsRegisterVolumesCleanupIntervalInMin; i++ {
+				time.Sleep(time.Duration(1 * time.Minute))
+			}
+		}
+	}()
+	return nil
+}
+
 func watcher(ctx context.Context, cnsOperator *cnsOperator) error {
 	log := logger.GetLogger(ctx)
 	cfgPath := common.GetConfigPath(ctx)
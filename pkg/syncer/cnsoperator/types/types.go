@@ -20,12 +20,21 @@ const (
 	// CNSFinalizer is the finalizer on CNSNodeVmAttachment and CnsVolumeMetadata controllers
 	CNSFinalizer = "cns.vmware.com"
 
+	// GuestPVProtectionFinalizer is the finalizer on the supervisor PVCs
+	// backing the PVs of guest clusters, set by the CnsVolumeMetadata
+	// controller until the guest PVs are deleted.
+	GuestPVProtectionFinalizer = "cns.vmware.com/guest-pv-protection"
+
 	// GCAPIVersion is the APIVersion for TanzuKubernetes Cluster
 	GCAPIVersion = "run.tanzu.vmware.com/v1alpha1"
 
 	// GCKind is the Kind value for TanzuKubernetes Cluster
 	GCKind = "TanzuKubernetesCluster"
 
-	// VSphereCSIDriverName is the vsphere CSI driver name
-	VSphereCSIDriverName = "csi.vsphere.vmware.com"
+	// ClusterAPIVersion is the APIVersion for ClusterClass based guest
+	// clusters provisioned through Cluster API (TKG 2.0).
+	ClusterAPIVersion = "cluster.x-k8s.io/v1beta1"
+
+	// ClusterKind is the Kind value for ClusterClass based guest clusters.
+	ClusterKind = "Cluster"
 )
@@ -174,7 +174,7 @@ func GetNetworkProvider(ctx context.Context) (string, error) {
 	}
 	cm, err := k8sclient.CoreV1().ConfigMaps(kubeSystemNamespace).Get(ctx, wcpNetworkConfigMap, metav1.GetOptions{})
 	if err != nil {
-		return "", fmt.Errorf("failed to get config map %q in namespace %q. Err: %v",
+		return "", fmt.Errorf("failed to get config map %q in namespace %q. Err: %w",
 			wcpNetworkConfigMap, kubeSystemNamespace, err)
 	}
 
@@ -0,0 +1,206 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/prometheus"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+const (
+	// event reason for the consistency audit finding no mismatch
+	reasonConsistencyAuditPassed = "ConsistencyAuditPassed"
+	// event reason for the consistency audit finding mismatches
+	reasonConsistencyAuditFailed = "ConsistencyAuditFailed"
+	// maximum number of objects listed per mismatch type in the audit event
+	consistencyAuditMaxExamples = 5
+)
+
+// consistencyAuditReport holds the mismatches found by the consistency audit,
+// by mismatch type. Each mismatch is identified by the name of the PV or
+// VolumeAttachment, or by the volume ID of the CNS volume, involved.
+type consistencyAuditReport map[string][]string
+
+// consistencyAuditMismatchTypes lists the mismatch types of the consistency
+// audit, in the order they are reported.
+var consistencyAuditMismatchTypes = []string{
+	prometheus.PrometheusPVMissingInCNS,
+	prometheus.PrometheusCNSVolumeMissingPV,
+	prometheus.PrometheusVolumeAttachmentMissingPV,
+	prometheus.PrometheusVolumeAttachmentMissingInCNS,
+}
+
+// runStartupConsistencyAudit compares the PVs, the VolumeAttachments and the
+// CNS volumes of the cluster once, so that operators get visibility into the
+// drift accumulated while the syncer was down. Mismatches are published
+// through prometheus.ConsistencyAuditMismatchGaugeVec and summarized in an
+// event on the CSIDriver object. The audit only reports mismatches; fixing
+// them is left to full sync and to the operators.
+func runStartupConsistencyAudit(ctx context.Context, k8sclient clientset.Interface,
+	metadataSyncer *metadataSyncInformer) {
+	log := logger.GetLogger(ctx)
+	log.Info("ConsistencyAudit: start")
+	pvs, err := metadataSyncer.pvLister.List(labels.Everything())
+	if err != nil {
+		log.Errorf("ConsistencyAudit: failed to get PVs from kubernetes. Err: %v", err)
+		return
+	}
+	volumeAttachments, err := k8sclient.StorageV1().VolumeAttachments().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		log.Errorf("ConsistencyAudit: failed to get VolumeAttachments from kubernetes. Err: %v", err)
+		return
+	}
+	queryResults, err := fullSyncGetQueryResults(ctx, nil, metadataSyncer.configInfo.Cfg.Global.ClusterID,
+		metadataSyncer.volumeManager, metadataSyncer)
+	if err != nil {
+		log.Errorf("ConsistencyAudit: failed to query volumes from CNS. Err: %v", err)
+		return
+	}
+	cnsVolumeIDs := make(map[string]bool)
+	for _, queryResult := range queryResults {
+		for _, volume := range queryResult.Volumes {
+			// Ephemeral inline volumes have no PV.
+			if strings.HasPrefix(volume.Name, common.EphemeralVolumeNamePrefix) {
+				continue
+			}
+			cnsVolumeIDs[volume.VolumeId.Id] = true
+		}
+	}
+
+	report := auditVolumeConsistency(pvs, volumeAttachments.Items, cnsVolumeIDs)
+	total := 0
+	for _, mismatchType := range consistencyAuditMismatchTypes {
+		prometheus.ConsistencyAuditMismatchGaugeVec.WithLabelValues(mismatchType).Set(
+			float64(len(report[mismatchType])))
+		total += len(report[mismatchType])
+	}
+
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(
+		&typedcorev1.EventSinkImpl{
+			Interface: k8sclient.CoreV1().Events(""),
+		},
+	)
+	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: csitypes.Name})
+	csiDriverRef := &v1.ObjectReference{
+		APIVersion: storagev1.SchemeGroupVersion.String(),
+		Kind:       "CSIDriver",
+		Name:       csitypes.Name,
+	}
+	if total == 0 {
+		log.Infof("ConsistencyAudit: no mismatch found between %d PVs, %d VolumeAttachments and %d CNS volumes",
+			len(pvs), len(volumeAttachments.Items), len(cnsVolumeIDs))
+		recorder.Eventf(csiDriverRef, v1.EventTypeNormal, reasonConsistencyAuditPassed,
+			"No mismatch found between %d PVs, %d VolumeAttachments and %d CNS volumes",
+			len(pvs), len(volumeAttachments.Items), len(cnsVolumeIDs))
+		return
+	}
+	summary := report.summary()
+	log.Warnf("ConsistencyAudit: found %d mismatches: %s", total, summary)
+	recorder.Eventf(csiDriverRef, v1.EventTypeWarning, reasonConsistencyAuditFailed,
+		"Found %d mismatches between PVs, VolumeAttachments and CNS volumes: %s", total, summary)
+}
+
+// auditVolumeConsistency returns the mismatches between the given PVs,
+// VolumeAttachments and the IDs of the CNS volumes of the cluster.
+func auditVolumeConsistency(pvs []*v1.PersistentVolume, volumeAttachments []storagev1.VolumeAttachment,
+	cnsVolumeIDs map[string]bool) consistencyAuditReport {
+	report := make(consistencyAuditReport)
+	pvsByName := make(map[string]*v1.PersistentVolume)
+	pvVolumeHandles := make(map[string]bool)
+	for _, pv := range pvs {
+		pvsByName[pv.Name] = pv
+		// Migrated in-tree PVs are known to CNS by the FCD of their vmdk,
+		// which full sync takes care of, so only CSI PVs are audited.
+		if pv.Spec.CSI == nil || pv.Spec.CSI.Driver != csitypes.Name {
+			continue
+		}
+		pvVolumeHandles[pv.Spec.CSI.VolumeHandle] = true
+		// Volumes of PVs still being provisioned or released may not be in
+		// CNS yet, or anymore.
+		if pv.Status.Phase != v1.VolumeBound && pv.Status.Phase != v1.VolumeAvailable {
+			continue
+		}
+		if !cnsVolumeIDs[pv.Spec.CSI.VolumeHandle] {
+			report[prometheus.PrometheusPVMissingInCNS] = append(report[prometheus.PrometheusPVMissingInCNS],
+				pv.Name)
+		}
+	}
+	for volumeID := range cnsVolumeIDs {
+		if !pvVolumeHandles[volumeID] {
+			report[prometheus.PrometheusCNSVolumeMissingPV] = append(
+				report[prometheus.PrometheusCNSVolumeMissingPV], volumeID)
+		}
+	}
+	for _, va := range volumeAttachments {
+		if va.Spec.Attacher != csitypes.Name || va.Spec.Source.PersistentVolumeName == nil {
+			continue
+		}
+		pv, ok := pvsByName[*va.Spec.Source.PersistentVolumeName]
+		if !ok {
+			report[prometheus.PrometheusVolumeAttachmentMissingPV] = append(
+				report[prometheus.PrometheusVolumeAttachmentMissingPV], va.Name)
+			continue
+		}
+		if va.Status.Attached && pv.Spec.CSI != nil && !cnsVolumeIDs[pv.Spec.CSI.VolumeHandle] {
+			report[prometheus.PrometheusVolumeAttachmentMissingInCNS] = append(
+				report[prometheus.PrometheusVolumeAttachmentMissingInCNS], va.Name)
+		}
+	}
+	for _, mismatches := range report {
+		sort.Strings(mismatches)
+	}
+	return report
+}
+
+// summary returns a one-line summary of the report, listing a few of the
+// objects involved in each type of mismatch.
+func (report consistencyAuditReport) summary() string {
+	var parts []string
+	for _, mismatchType := range consistencyAuditMismatchTypes {
+		mismatches := report[mismatchType]
+		if len(mismatches) == 0 {
+			continue
+		}
+		examples := mismatches
+		if len(examples) > consistencyAuditMaxExamples {
+			examples = examples[:consistencyAuditMaxExamples]
+		}
+		part := fmt.Sprintf("%s: %d (%s", mismatchType, len(mismatches), strings.Join(examples, ", "))
+		if len(mismatches) > len(examples) {
+			part += ", ..."
+		}
+		parts = append(parts, part+")")
+	}
+	return strings.Join(parts, "; ")
+}
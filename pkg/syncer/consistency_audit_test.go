@@ -0,0 +1,80 @@
+package syncer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/prometheus"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+func newAuditPV(name, volumeHandle string, phase v1.PersistentVolumePhase) *v1.PersistentVolume {
+	return &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v1.PersistentVolumeSpec{
+			PersistentVolumeSource: v1.PersistentVolumeSource{
+				CSI: &v1.CSIPersistentVolumeSource{Driver: csitypes.Name, VolumeHandle: volumeHandle},
+			},
+		},
+		Status: v1.PersistentVolumeStatus{Phase: phase},
+	}
+}
+
+func newAuditVolumeAttachment(name, attacher, pvName string, attached bool) storagev1.VolumeAttachment {
+	return storagev1.VolumeAttachment{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: storagev1.VolumeAttachmentSpec{
+			Attacher: attacher,
+			Source:   storagev1.VolumeAttachmentSource{PersistentVolumeName: &pvName},
+		},
+		Status: storagev1.VolumeAttachmentStatus{Attached: attached},
+	}
+}
+
+func TestAuditVolumeConsistency(t *testing.T) {
+	pvs := []*v1.PersistentVolume{
+		newAuditPV("pv-ok", "vol-ok", v1.VolumeBound),
+		newAuditPV("pv-missing", "vol-missing", v1.VolumeBound),
+		newAuditPV("pv-released", "vol-released", v1.VolumeReleased),
+		newAuditPV("pv-pending", "vol-pending", v1.VolumePending),
+	}
+	volumeAttachments := []storagev1.VolumeAttachment{
+		newAuditVolumeAttachment("va-ok", csitypes.Name, "pv-ok", true),
+		newAuditVolumeAttachment("va-no-pv", csitypes.Name, "pv-deleted", true),
+		newAuditVolumeAttachment("va-missing", csitypes.Name, "pv-missing", true),
+		newAuditVolumeAttachment("va-detached", csitypes.Name, "pv-pending", false),
+		newAuditVolumeAttachment("va-other-driver", "other.csi.driver", "pv-other", true),
+	}
+	cnsVolumeIDs := map[string]bool{"vol-ok": true, "vol-orphan": true}
+	expected := consistencyAuditReport{
+		prometheus.PrometheusPVMissingInCNS:               {"pv-missing"},
+		prometheus.PrometheusCNSVolumeMissingPV:           {"vol-orphan"},
+		prometheus.PrometheusVolumeAttachmentMissingPV:    {"va-no-pv"},
+		prometheus.PrometheusVolumeAttachmentMissingInCNS: {"va-missing"},
+	}
+	report := auditVolumeConsistency(pvs, volumeAttachments, cnsVolumeIDs)
+	if !reflect.DeepEqual(report, expected) {
+		t.Errorf("expected %v, got %v", expected, report)
+	}
+}
+
+func TestConsistencyAuditReportSummary(t *testing.T) {
+	report := consistencyAuditReport{
+		prometheus.PrometheusCNSVolumeMissingPV: {"vol-1", "vol-2", "vol-3", "vol-4", "vol-5", "vol-6"},
+		prometheus.PrometheusPVMissingInCNS:     {"pv-1"},
+	}
+	summary := report.summary()
+	expected := prometheus.PrometheusPVMissingInCNS + ": 1 (pv-1); " +
+		prometheus.PrometheusCNSVolumeMissingPV + ": 6 (vol-1, vol-2, vol-3, vol-4, vol-5, ...)"
+	if summary != expected {
+		t.Errorf("expected summary %q, got %q", expected, summary)
+	}
+	if strings.Contains(summary, "vol-6") {
+		t.Errorf("expected summary to be truncated, got %q", summary)
+	}
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+
+	vimtypes "github.com/vmware/govmomi/vim25/types"
+
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+)
+
+// datastoreAccessibilityWatcher watches the datastore accessibility events of
+// vCenter, e.g. a datastore in all paths down (APD) condition on a host, and
+// refreshes the volume health and the storage capacity right away, instead of
+// waiting for their next periodic refresh.
+type datastoreAccessibilityWatcher struct {
+	// refreshers are called after the accessibility of datastores changed.
+	refreshers []func(ctx context.Context)
+	// changed is signaled when the accessibility of a datastore changed, and
+	// the refreshers weren't called since.
+	changed chan struct{}
+}
+
+// newDatastoreAccessibilityWatcher returns a datastoreAccessibilityWatcher
+// calling the given refreshers.
+func newDatastoreAccessibilityWatcher(refreshers []func(ctx context.Context)) *datastoreAccessibilityWatcher {
+	return &datastoreAccessibilityWatcher{
+		refreshers: refreshers,
+		changed:    make(chan struct{}, 1),
+	}
+}
+
+// startDatastoreAccessibilityWatcher watches the datastore accessibility
+// events of vCenter until the given context is canceled, retrying when the
+// watch fails, and calls the given refreshers after the events.
+func startDatastoreAccessibilityWatcher(ctx context.Context, metadataSyncer *metadataSyncInformer,
+	refreshers []func(ctx context.Context)) {
+	log := logger.GetLogger(ctx)
+	watcher := newDatastoreAccessibilityWatcher(refreshers)
+	go watcher.run(ctx.Done())
+	go func() {
+		for {
+			vc, err := cnsvsphere.GetVirtualCenterInstance(ctx, metadataSyncer.configInfo, false)
+			if err == nil {
+				log.Info("DatastoreAccessibilityWatcher: watching the datastore accessibility events")
+				err = cnsvsphere.WatchDatastoreAccessibilityEvents(ctx, vc, func(e vimtypes.BaseEvent) {
+					ctx, _ := logger.GetNewContextWithLogger()
+					watcher.eventReceived(ctx, e)
+				})
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			log.Warnf("DatastoreAccessibilityWatcher: failed to watch the datastore accessibility events, "+
+				"retrying in %v. Err: %v", datastoreAccessibilityWatchRetryInterval, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-syncerClock.After(datastoreAccessibilityWatchRetryInterval):
+			}
+		}
+	}()
+}
+
+// eventReceived requests the refreshers to be called for the given datastore
+// accessibility event.
+func (w *datastoreAccessibilityWatcher) eventReceived(ctx context.Context, e vimtypes.BaseEvent) {
+	log := logger.GetLogger(ctx)
+	log.Infof("DatastoreAccessibilityWatcher: received event %T: %s", e, e.GetEvent().FullFormattedMessage)
+	select {
+	case w.changed <- struct{}{}:
+	default:
+		// A refresh is already pending.
+	}
+}
+
+// run calls the refreshers after each change of the accessibility of the
+// datastores until stopCh is closed. The changes received within
+// datastoreAccessibilityRefreshDelay of each other are refreshed at once.
+func (w *datastoreAccessibilityWatcher) run(stopCh <-chan struct{}) {
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-w.changed:
+		}
+		select {
+		case <-stopCh:
+			return
+		case <-syncerClock.After(datastoreAccessibilityRefreshDelay):
+		}
+		// The changes received during the delay are covered by this refresh.
+		select {
+		case <-w.changed:
+		default:
+		}
+		ctx, log := logger.GetNewContextWithLogger()
+		log.Info("DatastoreAccessibilityWatcher: refreshing the volume health and the storage capacity")
+		for _, refresh := range w.refreshers {
+			refresh(ctx)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package syncer
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	vimtypes "github.com/vmware/govmomi/vim25/types"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestDatastoreAccessibilityWatcherCoalescesEvents(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Now())
+	defer func(c clock.Clock) { syncerClock = c }(syncerClock)
+	syncerClock = fakeClock
+
+	var refreshes int32
+	watcher := newDatastoreAccessibilityWatcher([]func(ctx context.Context){
+		func(ctx context.Context) { atomic.AddInt32(&refreshes, 1) },
+	})
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go watcher.run(stopCh)
+
+	ctx := context.Background()
+	apd := &vimtypes.EventEx{EventTypeId: "esx.problem.storage.apd.start"}
+	removed := &vimtypes.DatastoreRemovedOnHostEvent{}
+	watcher.eventReceived(ctx, apd)
+	// Wait for the watcher to wait for the refresh delay.
+	waitForClockWaiters(t, fakeClock)
+	watcher.eventReceived(ctx, removed)
+	watcher.eventReceived(ctx, apd)
+	fakeClock.Step(datastoreAccessibilityRefreshDelay)
+	waitForRefreshes(t, &refreshes, 1)
+
+	// The events received during the delay were refreshed at once.
+	fakeClock.Step(datastoreAccessibilityRefreshDelay)
+	time.Sleep(100 * time.Millisecond)
+	if n := atomic.LoadInt32(&refreshes); n != 1 {
+		t.Fatalf("expected 1 refresh, got %d", n)
+	}
+
+	watcher.eventReceived(ctx, removed)
+	waitForClockWaiters(t, fakeClock)
+	fakeClock.Step(datastoreAccessibilityRefreshDelay)
+	waitForRefreshes(t, &refreshes, 2)
+}
+
+func waitForClockWaiters(t *testing.T, fakeClock *clock.FakeClock) {
+	err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		return fakeClock.HasWaiters(), nil
+	})
+	if err != nil {
+		t.Fatalf("timed out waiting for the watcher to wait for the refresh delay")
+	}
+}
+
+func waitForRefreshes(t *testing.T, refreshes *int32, expected int32) {
+	err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		return atomic.LoadInt32(refreshes) == expected, nil
+	})
+	if err != nil {
+		t.Fatalf("expected %d refreshes, got %d", expected, atomic.LoadInt32(refreshes))
+	}
+}
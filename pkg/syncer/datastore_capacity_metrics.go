@@ -0,0 +1,104 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	vim25types "github.com/vmware/govmomi/vim25/types"
+
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/prometheus"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+)
+
+// csiReportDatastoreCapacity publishes the capacity and free space of the
+// datastores holding the volumes of the cluster in CNS, through
+// prometheus.DatastoreCapacityGaugeVec and prometheus.DatastoreFreeSpaceGaugeVec.
+func csiReportDatastoreCapacity(ctx context.Context, metadataSyncer *metadataSyncInformer) {
+	log := logger.GetLogger(ctx)
+	queryResults, err := fullSyncGetQueryResults(ctx, nil, metadataSyncer.configInfo.Cfg.Global.ClusterID,
+		metadataSyncer.volumeManager, metadataSyncer)
+	if err != nil {
+		log.Errorf("DatastoreCapacityMetrics: failed to query volumes. Err: %v", err)
+		return
+	}
+	var volumes []cnstypes.CnsVolume
+	for _, queryResult := range queryResults {
+		volumes = append(volumes, queryResult.Volumes...)
+	}
+
+	vc, err := cnsvsphere.GetVirtualCenterInstance(ctx, metadataSyncer.configInfo, false)
+	if err != nil {
+		log.Errorf("DatastoreCapacityMetrics: failed to get vCenter instance. Err: %v", err)
+		return
+	}
+	datacenters, err := vc.GetDatacenters(ctx)
+	if err != nil {
+		log.Errorf("DatastoreCapacityMetrics: failed to get datacenters. Err: %v", err)
+		return
+	}
+	datastoreSummaries := make(map[string]vim25types.DatastoreSummary)
+	for _, datacenter := range datacenters {
+		dcDatastoreSummaries, err := datacenter.GetAllDatastoreSummaries(ctx)
+		if err != nil {
+			log.Errorf("DatastoreCapacityMetrics: failed to get datastores of datacenter %s. Err: %v",
+				datacenter.InventoryPath, err)
+			return
+		}
+		for url, summary := range dcDatastoreSummaries {
+			datastoreSummaries[url] = summary
+		}
+	}
+
+	usedDatastores := getVolumeDatastoreSummaries(ctx, volumes, datastoreSummaries)
+	// Reset the gauges so that datastores without volumes anymore are
+	// dropped.
+	prometheus.DatastoreCapacityGaugeVec.Reset()
+	prometheus.DatastoreFreeSpaceGaugeVec.Reset()
+	for url, summary := range usedDatastores {
+		prometheus.DatastoreCapacityGaugeVec.WithLabelValues(url, summary.Name).Set(float64(summary.Capacity))
+		prometheus.DatastoreFreeSpaceGaugeVec.WithLabelValues(url, summary.Name).Set(float64(summary.FreeSpace))
+	}
+	log.Infof("DatastoreCapacityMetrics: published capacity metrics for %d datastores", len(usedDatastores))
+}
+
+// getVolumeDatastoreSummaries returns the summaries of the datastores holding
+// the given volumes, by datastore URL. Datastores missing from the given
+// summaries, e.g. inaccessible ones, are skipped.
+func getVolumeDatastoreSummaries(ctx context.Context, volumes []cnstypes.CnsVolume,
+	datastoreSummaries map[string]vim25types.DatastoreSummary) map[string]vim25types.DatastoreSummary {
+	log := logger.GetLogger(ctx)
+	usedDatastores := make(map[string]vim25types.DatastoreSummary)
+	for _, volume := range volumes {
+		if volume.DatastoreUrl == "" {
+			continue
+		}
+		if _, ok := usedDatastores[volume.DatastoreUrl]; ok {
+			continue
+		}
+		summary, ok := datastoreSummaries[volume.DatastoreUrl]
+		if !ok {
+			log.Debugf("DatastoreCapacityMetrics: datastore %q of volume %q not found", volume.DatastoreUrl,
+				volume.VolumeId.Id)
+			continue
+		}
+		usedDatastores[volume.DatastoreUrl] = summary
+	}
+	return usedDatastores
+}
@@ -0,0 +1,42 @@
+package syncer
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	vim25types "github.com/vmware/govmomi/vim25/types"
+)
+
+func TestGetVolumeDatastoreSummaries(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	vsanSummary := vim25types.DatastoreSummary{Name: "vsanDatastore", Url: "ds:///vmfs/volumes/vsan:1/",
+		Capacity: 100, FreeSpace: 40}
+	vmfsSummary := vim25types.DatastoreSummary{Name: "vmfsDatastore", Url: "ds:///vmfs/volumes/vmfs-1/",
+		Capacity: 50, FreeSpace: 10}
+	unusedSummary := vim25types.DatastoreSummary{Name: "unusedDatastore", Url: "ds:///vmfs/volumes/vmfs-2/",
+		Capacity: 50, FreeSpace: 50}
+	datastoreSummaries := map[string]vim25types.DatastoreSummary{
+		vsanSummary.Url:   vsanSummary,
+		vmfsSummary.Url:   vmfsSummary,
+		unusedSummary.Url: unusedSummary,
+	}
+	volumes := []cnstypes.CnsVolume{
+		{VolumeId: cnstypes.CnsVolumeId{Id: "vol-1"}, DatastoreUrl: vsanSummary.Url},
+		{VolumeId: cnstypes.CnsVolumeId{Id: "vol-2"}, DatastoreUrl: vsanSummary.Url},
+		{VolumeId: cnstypes.CnsVolumeId{Id: "vol-3"}, DatastoreUrl: vmfsSummary.Url},
+		{VolumeId: cnstypes.CnsVolumeId{Id: "vol-4"}, DatastoreUrl: "ds:///vmfs/volumes/inaccessible/"},
+		{VolumeId: cnstypes.CnsVolumeId{Id: "vol-5"}},
+	}
+	expected := map[string]vim25types.DatastoreSummary{
+		vsanSummary.Url: vsanSummary,
+		vmfsSummary.Url: vmfsSummary,
+	}
+	if summaries := getVolumeDatastoreSummaries(ctx, volumes, datastoreSummaries); !reflect.DeepEqual(summaries,
+		expected) {
+		t.Errorf("expected datastore summaries %v, got %v", expected, summaries)
+	}
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/debugserver"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+)
+
+const (
+	// FullSyncDebugPath is the debug path serving the progress of full sync.
+	FullSyncDebugPath = "/debug/syncer/fullsync"
+	// CnsMapsDebugPath is the debug path serving the volumes pending creation
+	// and deletion in CNS.
+	CnsMapsDebugPath = "/debug/syncer/cnsmaps"
+
+	fullSyncPhaseListingVolumes   = "listing volumes"
+	fullSyncPhaseComputingChanges = "computing volume operations"
+	fullSyncPhaseExecuting        = "executing volume operations"
+	fullSyncPhaseCompleted        = "completed"
+	fullSyncPhaseFailed           = "failed"
+)
+
+// fullSyncProgress is the progress of the running or last full sync.
+type fullSyncProgress struct {
+	StartTime time.Time `json:"startTime"`
+	// EndTime is zero while full sync is running.
+	EndTime         time.Time `json:"endTime,omitempty"`
+	Phase           string    `json:"phase"`
+	VolumesToCreate int       `json:"volumesToCreate"`
+	VolumesToUpdate int       `json:"volumesToUpdate"`
+	VolumesToDelete int       `json:"volumesToDelete"`
+	Error           string    `json:"error,omitempty"`
+}
+
+// cnsMapsSnapshot is a copy of cnsCreationMap and cnsDeletionMap. Full sync
+// accesses them without lock, so they are copied by full sync itself when
+// they are not being modified.
+type cnsMapsSnapshot struct {
+	SnapshotTime   time.Time `json:"snapshotTime"`
+	CnsCreationMap []string  `json:"cnsCreationMap"`
+	CnsDeletionMap []string  `json:"cnsDeletionMap"`
+}
+
+var (
+	// debugLock guards currentFullSyncProgress and lastCnsMapsSnapshot.
+	debugLock               sync.Mutex
+	currentFullSyncProgress fullSyncProgress
+	lastCnsMapsSnapshot     cnsMapsSnapshot
+)
+
+// DebugHandlers returns the debug endpoints of the syncer, by path.
+func DebugHandlers() map[string]http.Handler {
+	return map[string]http.Handler{
+		FullSyncDebugPath: http.HandlerFunc(fullSyncDebugHandler),
+		CnsMapsDebugPath:  http.HandlerFunc(cnsMapsDebugHandler),
+	}
+}
+
+func fullSyncDebugHandler(w http.ResponseWriter, r *http.Request) {
+	debugLock.Lock()
+	progress := currentFullSyncProgress
+	debugLock.Unlock()
+	debugserver.WriteJSON(logger.NewContextWithLogger(r.Context()), w, progress)
+}
+
+func cnsMapsDebugHandler(w http.ResponseWriter, r *http.Request) {
+	debugLock.Lock()
+	snapshot := lastCnsMapsSnapshot
+	debugLock.Unlock()
+	debugserver.WriteJSON(logger.NewContextWithLogger(r.Context()), w, snapshot)
+}
+
+// recordFullSyncStart resets the progress of full sync.
+func recordFullSyncStart(startTime time.Time) {
+	debugLock.Lock()
+	defer debugLock.Unlock()
+	currentFullSyncProgress = fullSyncProgress{StartTime: startTime, Phase: fullSyncPhaseListingVolumes}
+}
+
+// recordFullSyncPhase records the phase full sync entered.
+func recordFullSyncPhase(phase string) {
+	debugLock.Lock()
+	defer debugLock.Unlock()
+	currentFullSyncProgress.Phase = phase
+}
+
+// recordFullSyncOperations records the number of volume operations full sync
+// is about to execute, and snapshots the CNS maps.
+func recordFullSyncOperations(volumesToCreate, volumesToUpdate, volumesToDelete int) {
+	snapshot := snapshotCnsMaps()
+	debugLock.Lock()
+	defer debugLock.Unlock()
+	currentFullSyncProgress.Phase = fullSyncPhaseExecuting
+	currentFullSyncProgress.VolumesToCreate = volumesToCreate
+	currentFullSyncProgress.VolumesToUpdate = volumesToUpdate
+	currentFullSyncProgress.VolumesToDelete = volumesToDelete
+	lastCnsMapsSnapshot = snapshot
+}
+
+// recordFullSyncEnd records the end of full sync, and snapshots the CNS maps
+// if it succeeded.
+func recordFullSyncEnd(endTime time.Time, err error) {
+	var snapshot cnsMapsSnapshot
+	if err == nil {
+		snapshot = snapshotCnsMaps()
+	}
+	debugLock.Lock()
+	defer debugLock.Unlock()
+	currentFullSyncProgress.EndTime = endTime
+	if err != nil {
+		currentFullSyncProgress.Phase = fullSyncPhaseFailed
+		currentFullSyncProgress.Error = err.Error()
+		return
+	}
+	currentFullSyncProgress.Phase = fullSyncPhaseCompleted
+	lastCnsMapsSnapshot = snapshot
+}
+
+// snapshotCnsMaps copies the volume IDs of cnsCreationMap and cnsDeletionMap.
+// It must not be called while full sync modifies them.
+func snapshotCnsMaps() cnsMapsSnapshot {
+	return cnsMapsSnapshot{
+		SnapshotTime:   time.Now(),
+		CnsCreationMap: sortedVolumeIDs(cnsCreationMap),
+		CnsDeletionMap: sortedVolumeIDs(cnsDeletionMap),
+	}
+}
+
+func sortedVolumeIDs(volumes map[string]bool) []string {
+	volumeIDs := make([]string, 0, len(volumes))
+	for volumeID := range volumes {
+		volumeIDs = append(volumeIDs, volumeID)
+	}
+	sort.Strings(volumeIDs)
+	return volumeIDs
+}
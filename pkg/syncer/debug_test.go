@@ -0,0 +1,73 @@
+package syncer
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFullSyncDebugHandlers(t *testing.T) {
+	savedCreationMap, savedDeletionMap := cnsCreationMap, cnsDeletionMap
+	defer func() {
+		cnsCreationMap, cnsDeletionMap = savedCreationMap, savedDeletionMap
+	}()
+	cnsCreationMap = map[string]bool{"vol-2": true, "vol-1": true}
+	cnsDeletionMap = map[string]bool{"vol-3": true}
+
+	startTime := time.Now()
+	recordFullSyncStart(startTime)
+	recordFullSyncPhase(fullSyncPhaseComputingChanges)
+	recordFullSyncOperations(2, 3, 1)
+
+	var progress fullSyncProgress
+	getDebugJSON(t, FullSyncDebugPath, &progress)
+	if progress.Phase != fullSyncPhaseExecuting || progress.VolumesToCreate != 2 ||
+		progress.VolumesToUpdate != 3 || progress.VolumesToDelete != 1 || !progress.EndTime.IsZero() {
+		t.Errorf("unexpected full sync progress %+v", progress)
+	}
+	var snapshot cnsMapsSnapshot
+	getDebugJSON(t, CnsMapsDebugPath, &snapshot)
+	if !reflect.DeepEqual(snapshot.CnsCreationMap, []string{"vol-1", "vol-2"}) ||
+		!reflect.DeepEqual(snapshot.CnsDeletionMap, []string{"vol-3"}) {
+		t.Errorf("unexpected CNS maps snapshot %+v", snapshot)
+	}
+
+	// A failed full sync keeps the last snapshot of the CNS maps.
+	delete(cnsCreationMap, "vol-1")
+	recordFullSyncEnd(time.Now(), errors.New("query failed"))
+	getDebugJSON(t, FullSyncDebugPath, &progress)
+	if progress.Phase != fullSyncPhaseFailed || progress.Error != "query failed" || progress.EndTime.IsZero() {
+		t.Errorf("unexpected full sync progress %+v", progress)
+	}
+	getDebugJSON(t, CnsMapsDebugPath, &snapshot)
+	if len(snapshot.CnsCreationMap) != 2 {
+		t.Errorf("expected CNS maps snapshot of the last successful phase, got %+v", snapshot)
+	}
+
+	recordFullSyncStart(time.Now())
+	recordFullSyncEnd(time.Now(), nil)
+	progress = fullSyncProgress{}
+	getDebugJSON(t, FullSyncDebugPath, &progress)
+	if progress.Phase != fullSyncPhaseCompleted || progress.Error != "" {
+		t.Errorf("unexpected full sync progress %+v", progress)
+	}
+	getDebugJSON(t, CnsMapsDebugPath, &snapshot)
+	if !reflect.DeepEqual(snapshot.CnsCreationMap, []string{"vol-2"}) {
+		t.Errorf("unexpected CNS maps snapshot %+v", snapshot)
+	}
+}
+
+func getDebugJSON(t *testing.T, path string, v interface{}) {
+	recorder := httptest.NewRecorder()
+	DebugHandlers()[path].ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status %d for %s, got %d", http.StatusOK, path, recorder.Code)
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), v); err != nil {
+		t.Fatalf("failed to decode response of %s. Err: %v", path, err)
+	}
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+)
+
+// getEphemeralVolumeOwnerPod returns the name of the pod owning the given
+// PVC if it was created for a generic ephemeral volume of the pod, or an
+// empty string otherwise. Kubernetes creates the PVCs of generic ephemeral
+// volumes with the pod as their controller.
+func getEphemeralVolumeOwnerPod(pvc *v1.PersistentVolumeClaim) string {
+	owner := metav1.GetControllerOf(pvc)
+	if owner == nil || owner.APIVersion != "v1" || owner.Kind != "Pod" {
+		return ""
+	}
+	return owner.Name
+}
+
+// getEphemeralVolumeOwnerPodMetadata returns the CNS metadata of the pod
+// owning the given PVC if it was created for a generic ephemeral volume, so
+// that the pod shows up as the top-level entity of the volume in vCenter even
+// while it isn't running. It returns nil for other PVCs.
+func getEphemeralVolumeOwnerPodMetadata(pvc *v1.PersistentVolumeClaim,
+	clusterID string) *cnstypes.CnsKubernetesEntityMetadata {
+	podName := getEphemeralVolumeOwnerPod(pvc)
+	if podName == "" {
+		return nil
+	}
+	pvcEntityReference := cnsvsphere.CreateCnsKuberenetesEntityReference(
+		string(cnstypes.CnsKubernetesEntityTypePVC), pvc.Name, pvc.Namespace, clusterID)
+	return cnsvsphere.GetCnsKubernetesEntityMetaData(podName, nil, false,
+		string(cnstypes.CnsKubernetesEntityTypePOD), pvc.Namespace, clusterID,
+		[]cnstypes.CnsKubernetesEntityReference{pvcEntityReference})
+}
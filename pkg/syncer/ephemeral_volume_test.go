@@ -0,0 +1,71 @@
+package syncer
+
+import (
+	"context"
+	"testing"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newEphemeralVolumePVC(ownerKind string, controller bool) *v1.PersistentVolumeClaim {
+	return &v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "web-scratch",
+			Namespace: "default",
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "v1",
+				Kind:       ownerKind,
+				Name:       "web",
+				Controller: &controller,
+			}},
+		},
+	}
+}
+
+func TestGetEphemeralVolumeOwnerPod(t *testing.T) {
+	if name := getEphemeralVolumeOwnerPod(newEphemeralVolumePVC("Pod", true)); name != "web" {
+		t.Errorf("expected owner pod %q, got %q", "web", name)
+	}
+	if name := getEphemeralVolumeOwnerPod(newEphemeralVolumePVC("Pod", false)); name != "" {
+		t.Errorf("expected no owner pod for a PVC not controlled by a pod, got %q", name)
+	}
+	if name := getEphemeralVolumeOwnerPod(newEphemeralVolumePVC("StatefulSet", true)); name != "" {
+		t.Errorf("expected no owner pod for a PVC controlled by a StatefulSet, got %q", name)
+	}
+	if name := getEphemeralVolumeOwnerPod(&v1.PersistentVolumeClaim{}); name != "" {
+		t.Errorf("expected no owner pod for a PVC without owner, got %q", name)
+	}
+}
+
+func TestBuildCnsMetadataListEphemeralVolume(t *testing.T) {
+	ctx := context.Background()
+	pvc := newEphemeralVolumePVC("Pod", true)
+	pv := &v1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "pv-1"}}
+	pvToPVCMap := pvcMap{pv.Name: pvc}
+	getPodNames := func(metadataList []cnstypes.BaseCnsEntityMetadata) []string {
+		var names []string
+		for _, metadata := range metadataList {
+			entity := metadata.(*cnstypes.CnsKubernetesEntityMetadata)
+			if entity.EntityType == string(cnstypes.CnsKubernetesEntityTypePOD) {
+				names = append(names, entity.EntityName)
+			}
+		}
+		return names
+	}
+
+	if names := getPodNames(buildCnsMetadataList(ctx, pv, pvToPVCMap, podMap{}, "cluster", false)); len(names) != 0 {
+		t.Errorf("expected no pod metadata with the feature disabled, got %v", names)
+	}
+	names := getPodNames(buildCnsMetadataList(ctx, pv, pvToPVCMap, podMap{}, "cluster", true))
+	if len(names) != 1 || names[0] != "web" {
+		t.Errorf("expected metadata of the owner pod, got %v", names)
+	}
+	// The owner pod isn't recorded twice when it is running.
+	runningPods := podMap{"default/web-scratch": {{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}}}
+	names = getPodNames(buildCnsMetadataList(ctx, pv, pvToPVCMap, runningPods, "cluster", true))
+	if len(names) != 1 || names[0] != "web" {
+		t.Errorf("expected metadata of the owner pod once, got %v", names)
+	}
+}
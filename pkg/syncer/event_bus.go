@@ -0,0 +1,240 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	k8s "sigs.k8s.io/vsphere-csi-driver/v2/pkg/kubernetes"
+)
+
+// resourceKind is the kind of the Kubernetes resources published on the
+// event bus.
+type resourceKind string
+
+const (
+	pvResource  resourceKind = "PersistentVolume"
+	pvcResource resourceKind = "PersistentVolumeClaim"
+	podResource resourceKind = "Pod"
+)
+
+// resourceEventType is the type of change of a resourceEvent.
+type resourceEventType string
+
+const (
+	resourceAdded   resourceEventType = "add"
+	resourceUpdated resourceEventType = "update"
+	resourceDeleted resourceEventType = "delete"
+)
+
+const (
+	// defaultEventBusReplaySize is the number of recent events replayed to
+	// subscribers asking for it.
+	defaultEventBusReplaySize = 1024
+	// eventBusQueueSize is the number of events queued per subscriber before
+	// publishing blocks.
+	eventBusQueueSize = 256
+	// eventBusDrainPollInterval is the interval at which draining the event
+	// bus checks whether the subscribers handled their events.
+	eventBusDrainPollInterval = 100 * time.Millisecond
+)
+
+// resourceEvent is a change of a Kubernetes resource. For added resources
+// only newObj is set, for deleted resources only oldObj is set.
+type resourceEvent struct {
+	kind      resourceKind
+	eventType resourceEventType
+	oldObj    interface{}
+	newObj    interface{}
+}
+
+// eventBus dispatches the PV, PVC and Pod changes of one informer pipeline
+// to the syncer components subscribed to them. Each subscriber receives its
+// events in order from its own goroutine, so a slow subscriber only holds
+// back publishing once its queue is full. The most recent events are kept to
+// be replayed to subscribers starting after the informers.
+type eventBus struct {
+	lock        sync.Mutex
+	replaySize  int
+	replay      []resourceEvent
+	subscribers map[*eventSubscriber]bool
+}
+
+// eventSubscriber is a subscription to the events of some resource kinds.
+type eventSubscriber struct {
+	name    string
+	kinds   map[resourceKind]bool
+	handler func(resourceEvent)
+	queue   chan resourceEvent
+	// done is closed when the subscription is cancelled.
+	done chan struct{}
+	// pending is the number of published events queued to the subscriber
+	// and not handled yet.
+	pending int64
+}
+
+// resourceEventBus is the event bus of the syncer, fed by the informers of
+// the metadata syncer.
+var resourceEventBus = newEventBus(defaultEventBusReplaySize)
+
+func newEventBus(replaySize int) *eventBus {
+	return &eventBus{
+		replaySize:  replaySize,
+		subscribers: make(map[*eventSubscriber]bool),
+	}
+}
+
+// watchInformers publishes the PV, PVC and Pod changes seen by the given
+// informer manager on the bus. It must be called before the informers are
+// started.
+func (b *eventBus) watchInformers(im *k8s.InformerManager) {
+	for kind, addListener := range map[resourceKind]func(func(interface{}),
+		func(interface{}, interface{}), func(interface{})){
+		pvResource:  im.AddPVListener,
+		pvcResource: im.AddPVCListener,
+		podResource: im.AddPodListener,
+	} {
+		kind := kind
+		addListener(
+			func(obj interface{}) { // Add.
+				b.publish(resourceEvent{kind: kind, eventType: resourceAdded, newObj: obj})
+			},
+			func(oldObj interface{}, newObj interface{}) { // Update.
+				b.publish(resourceEvent{kind: kind, eventType: resourceUpdated, oldObj: oldObj, newObj: newObj})
+			},
+			func(obj interface{}) { // Delete.
+				b.publish(resourceEvent{kind: kind, eventType: resourceDeleted, oldObj: obj})
+			})
+	}
+}
+
+// publish records the given event for replay and queues it to the
+// subscribers of its kind. The event is queued outside of the lock of the
+// bus, so that a subscriber with a full queue only holds back the publisher
+// of the event, and not the subscriptions and the draining of the bus.
+func (b *eventBus) publish(event resourceEvent) {
+	var subscribers []*eventSubscriber
+	b.lock.Lock()
+	if b.replaySize > 0 {
+		if len(b.replay) == b.replaySize {
+			b.replay = b.replay[1:]
+		}
+		b.replay = append(b.replay, event)
+	}
+	for subscriber := range b.subscribers {
+		if subscriber.kinds[event.kind] {
+			atomic.AddInt64(&subscriber.pending, 1)
+			subscribers = append(subscribers, subscriber)
+		}
+	}
+	b.lock.Unlock()
+	for _, subscriber := range subscribers {
+		select {
+		case subscriber.queue <- event:
+		case <-subscriber.done:
+			atomic.AddInt64(&subscriber.pending, -1)
+		}
+	}
+}
+
+// subscribe calls handler with the events of the given kinds, starting with
+// the recorded events if replay is true. The returned function cancels the
+// subscription.
+func (b *eventBus) subscribe(name string, kinds []resourceKind, replay bool,
+	handler func(resourceEvent)) func() {
+	subscriber := &eventSubscriber{
+		name:    name,
+		kinds:   make(map[resourceKind]bool),
+		handler: handler,
+		queue:   make(chan resourceEvent, eventBusQueueSize),
+		done:    make(chan struct{}),
+	}
+	for _, kind := range kinds {
+		subscriber.kinds[kind] = true
+	}
+	b.lock.Lock()
+	var replayed []resourceEvent
+	if replay {
+		for _, event := range b.replay {
+			if subscriber.kinds[event.kind] {
+				replayed = append(replayed, event)
+			}
+		}
+	}
+	b.subscribers[subscriber] = true
+	b.lock.Unlock()
+	logger.GetLoggerWithNoContext().Infof("%s subscribed to %v events, replaying %d events",
+		name, kinds, len(replayed))
+
+	go func() {
+		for _, event := range replayed {
+			subscriber.handler(event)
+		}
+		for {
+			select {
+			case event := <-subscriber.queue:
+				subscriber.handler(event)
+				atomic.AddInt64(&subscriber.pending, -1)
+			case <-subscriber.done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.lock.Lock()
+			delete(b.subscribers, subscriber)
+			b.lock.Unlock()
+			close(subscriber.done)
+		})
+	}
+}
+
+// drain waits until the subscribers handled the events published so far, or
+// ctx is done. It returns the number of events left unhandled.
+func (b *eventBus) drain(ctx context.Context) int64 {
+	ticker := syncerClock.NewTicker(eventBusDrainPollInterval)
+	defer ticker.Stop()
+	for {
+		pending := b.pending()
+		if pending == 0 {
+			return 0
+		}
+		select {
+		case <-ctx.Done():
+			return pending
+		case <-ticker.C():
+		}
+	}
+}
+
+// pending returns the number of events queued to the subscribers and not
+// handled yet.
+func (b *eventBus) pending() int64 {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	var pending int64
+	for subscriber := range b.subscribers {
+		pending += atomic.LoadInt64(&subscriber.pending)
+	}
+	return pending
+}
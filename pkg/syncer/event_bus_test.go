@@ -0,0 +1,122 @@
+package syncer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/util/workqueue"
+
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+func TestEventBus(t *testing.T) {
+	bus := newEventBus(2)
+	bus.publish(resourceEvent{kind: pvResource, eventType: resourceAdded, newObj: "pv-1"})
+	bus.publish(resourceEvent{kind: pvcResource, eventType: resourceAdded, newObj: "pvc-1"})
+	bus.publish(resourceEvent{kind: pvResource, eventType: resourceAdded, newObj: "pv-2"})
+
+	received := make(chan resourceEvent, 10)
+	unsubscribe := bus.subscribe("test", []resourceKind{pvResource}, true, func(event resourceEvent) {
+		received <- event
+	})
+	bus.publish(resourceEvent{kind: podResource, eventType: resourceAdded, newObj: "pod-1"})
+	bus.publish(resourceEvent{kind: pvResource, eventType: resourceDeleted, oldObj: "pv-2"})
+
+	// Only the last two events are kept for replay, out of which only pv-2
+	// is a PV event.
+	expected := []resourceEvent{
+		{kind: pvResource, eventType: resourceAdded, newObj: "pv-2"},
+		{kind: pvResource, eventType: resourceDeleted, oldObj: "pv-2"},
+	}
+	for _, expectedEvent := range expected {
+		select {
+		case event := <-received:
+			if event != expectedEvent {
+				t.Errorf("expected event %+v, got %+v", expectedEvent, event)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("timed out waiting for event %+v", expectedEvent)
+		}
+	}
+
+	unsubscribe()
+	bus.publish(resourceEvent{kind: pvResource, eventType: resourceAdded, newObj: "pv-3"})
+	select {
+	case event := <-received:
+		t.Errorf("unexpected event %+v after unsubscribing", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestEventBusDrain(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bus := newEventBus(0)
+	release := make(chan struct{})
+	unsubscribe := bus.subscribe("test", []resourceKind{pvResource}, false, func(event resourceEvent) {
+		<-release
+	})
+	defer unsubscribe()
+	bus.publish(resourceEvent{kind: pvResource, eventType: resourceAdded, newObj: "pv-1"})
+	bus.publish(resourceEvent{kind: pvResource, eventType: resourceAdded, newObj: "pv-2"})
+
+	timeoutCtx, timeoutCancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer timeoutCancel()
+	if pending := bus.drain(timeoutCtx); pending != 2 {
+		t.Errorf("expected 2 events pending, got %d", pending)
+	}
+
+	close(release)
+	timeoutCtx, timeoutCancel = context.WithTimeout(ctx, 10*time.Second)
+	defer timeoutCancel()
+	if pending := bus.drain(timeoutCtx); pending != 0 {
+		t.Errorf("expected no event pending, got %d", pending)
+	}
+}
+
+func TestVolumeHealthReconcilerEventReplay(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	newPV := func(phase v1.PersistentVolumePhase) *v1.PersistentVolume {
+		return &v1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pv-1"},
+			Spec: v1.PersistentVolumeSpec{
+				PersistentVolumeSource: v1.PersistentVolumeSource{
+					CSI: &v1.CSIPersistentVolumeSource{Driver: csitypes.Name, VolumeHandle: "svc-pvc-1"},
+				},
+			},
+			Status: v1.PersistentVolumeStatus{Phase: phase},
+		}
+	}
+	// The PV is bound before the reconciler subscribes to the bus.
+	bus := newEventBus(defaultEventBusReplaySize)
+	bus.publish(resourceEvent{kind: pvResource, eventType: resourceUpdated,
+		oldObj: newPV(v1.VolumePending), newObj: newPV(v1.VolumeBound)})
+
+	tkgClient := testclient.NewSimpleClientset()
+	svcClient := testclient.NewSimpleClientset()
+	rc, err := NewVolumeHealthReconciler(tkgClient, func() kubernetes.Interface { return svcClient }, 0,
+		informers.NewSharedInformerFactory(tkgClient, 0), informers.NewSharedInformerFactory(svcClient, 0), bus,
+		workqueue.DefaultControllerRateLimiter(), "test-ns", stopCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reconciler := rc.(*volumeHealthReconciler)
+	deadline := time.Now().Add(10 * time.Second)
+	for len(reconciler.volumeHandleToPVs.get("svc-pvc-1")) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the replayed PV to be handled")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	key, _ := reconciler.svcClaimQueue.Get()
+	if key != "test-ns/svc-pvc-1" {
+		t.Errorf("expected the supervisor PVC test-ns/svc-pvc-1 to be queued, got %v", key)
+	}
+}
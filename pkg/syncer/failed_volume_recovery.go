@@ -0,0 +1,123 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	pbmtypes "github.com/vmware/govmomi/pbm/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	clientset "k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+// csiRecoverFailedVolumes restores vSphere CSI PVs which were marked Failed,
+// typically during a temporary VC outage, once CNS reports their backing FCD
+// as healthy again. Only PVs still bound to an existing PVC are recovered.
+// Restoring the PV to Bound lets the PV controller move PVCs marked Lost back
+// to Bound as well, without requiring manual PV edits.
+func csiRecoverFailedVolumes(ctx context.Context, k8sclient clientset.Interface,
+	metadataSyncer *metadataSyncInformer) {
+	log := logger.GetLogger(ctx)
+	log.Debug("csiRecoverFailedVolumes: start")
+	allPVs, err := metadataSyncer.pvLister.List(labels.Everything())
+	if err != nil {
+		log.Errorf("csiRecoverFailedVolumes: Failed to get PVs from kubernetes. Err: %+v", err)
+		return
+	}
+	volumeHandleToPvMap := getRecoverableFailedPVs(ctx, allPVs, metadataSyncer.pvcLister)
+	if len(volumeHandleToPvMap) == 0 {
+		log.Debug("csiRecoverFailedVolumes: no failed PVs to recover")
+		return
+	}
+
+	queryFilter := cnstypes.CnsQueryFilter{}
+	for volumeHandle := range volumeHandleToPvMap {
+		queryFilter.VolumeIds = append(queryFilter.VolumeIds, cnstypes.CnsVolumeId{Id: volumeHandle})
+	}
+	querySelection := cnstypes.CnsQuerySelection{
+		Names: []string{
+			string(cnstypes.QuerySelectionNameTypeHealthStatus),
+		},
+	}
+	// A failure here means VC is still not reachable. Failed PVs will be
+	// retried in the next cycle.
+	queryAllResult, err := metadataSyncer.volumeManager.QueryAllVolume(ctx, queryFilter, querySelection)
+	if err != nil {
+		log.Warnf("csiRecoverFailedVolumes: failed to QueryAllVolume with err=%+v. Will retry in next cycle.", err)
+		return
+	}
+
+	for _, vol := range queryAllResult.Volumes {
+		pv, ok := volumeHandleToPvMap[vol.VolumeId.Id]
+		if !ok {
+			continue
+		}
+		if vol.HealthStatus == string(pbmtypes.PbmHealthStatusForEntityRed) {
+			log.Infof("csiRecoverFailedVolumes: volume %q backing pv %s is not healthy. Skipping recovery.",
+				vol.VolumeId.Id, pv.Name)
+			continue
+		}
+		recoveredPV := pv.DeepCopy()
+		recoveredPV.Status.Phase = v1.VolumeBound
+		recoveredPV.Status.Reason = ""
+		recoveredPV.Status.Message = ""
+		_, err := k8sclient.CoreV1().PersistentVolumes().UpdateStatus(ctx, recoveredPV, metav1.UpdateOptions{})
+		if err != nil {
+			log.Errorf("csiRecoverFailedVolumes: failed to update status of pv %s. Err: %+v", pv.Name, err)
+			continue
+		}
+		log.Infof("csiRecoverFailedVolumes: pv %s with volumeHandle %q recovered from phase %q to %q",
+			pv.Name, vol.VolumeId.Id, pv.Status.Phase, v1.VolumeBound)
+	}
+	log.Debug("csiRecoverFailedVolumes: end")
+}
+
+// getRecoverableFailedPVs returns the vSphere CSI PVs in Failed phase which
+// are still bound to an existing PVC, keyed by their volume handle. Failed PVs
+// whose PVC no longer exists are left to the PV controller.
+func getRecoverableFailedPVs(ctx context.Context, pvs []*v1.PersistentVolume,
+	pvcLister corelisters.PersistentVolumeClaimLister) map[string]*v1.PersistentVolume {
+	log := logger.GetLogger(ctx)
+	volumeHandleToPvMap := make(map[string]*v1.PersistentVolume)
+	for _, pv := range pvs {
+		if pv.Spec.CSI == nil || pv.Spec.CSI.Driver != csitypes.Name || pv.Status.Phase != v1.VolumeFailed {
+			continue
+		}
+		if pv.Spec.ClaimRef == nil {
+			continue
+		}
+		pvc, err := pvcLister.PersistentVolumeClaims(pv.Spec.ClaimRef.Namespace).Get(pv.Spec.ClaimRef.Name)
+		if err != nil {
+			log.Debugf("getRecoverableFailedPVs: failed to get pvc %s/%s for pv %s. err=%+v",
+				pv.Spec.ClaimRef.Namespace, pv.Spec.ClaimRef.Name, pv.Name, err)
+			continue
+		}
+		if pvc.UID != pv.Spec.ClaimRef.UID || pvc.Spec.VolumeName != pv.Name {
+			log.Debugf("getRecoverableFailedPVs: pvc %s/%s is not bound to pv %s", pvc.Namespace, pvc.Name, pv.Name)
+			continue
+		}
+		volumeHandleToPvMap[pv.Spec.CSI.VolumeHandle] = pv
+	}
+	return volumeHandleToPvMap
+}
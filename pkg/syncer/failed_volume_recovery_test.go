@@ -0,0 +1,83 @@
+package syncer
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+func newFailedVolumeRecoveryTestPV(name string, volumeHandle string, phase v1.PersistentVolumePhase,
+	claimName string, claimUID types.UID) *v1.PersistentVolume {
+	pv := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: v1.PersistentVolumeSpec{
+			PersistentVolumeSource: v1.PersistentVolumeSource{
+				CSI: &v1.CSIPersistentVolumeSource{
+					Driver:       csitypes.Name,
+					VolumeHandle: volumeHandle,
+				},
+			},
+		},
+		Status: v1.PersistentVolumeStatus{
+			Phase: phase,
+		},
+	}
+	if claimName != "" {
+		pv.Spec.ClaimRef = &v1.ObjectReference{
+			Namespace: "default",
+			Name:      claimName,
+			UID:       claimUID,
+		}
+	}
+	return pv
+}
+
+func TestGetRecoverableFailedPVs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pvcIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, pvc := range []*v1.PersistentVolumeClaim{
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pvc-bound", UID: "uid-bound"},
+			Spec:       v1.PersistentVolumeClaimSpec{VolumeName: "pv-failed"},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pvc-recreated", UID: "uid-new"},
+			Spec:       v1.PersistentVolumeClaimSpec{VolumeName: "pv-recreated-claim"},
+		},
+	} {
+		if err := pvcIndexer.Add(pvc); err != nil {
+			t.Fatalf("failed to add pvc %s to indexer. Err: %v", pvc.Name, err)
+		}
+	}
+	pvcLister := corelisters.NewPersistentVolumeClaimLister(pvcIndexer)
+
+	pvs := []*v1.PersistentVolume{
+		newFailedVolumeRecoveryTestPV("pv-failed", "vol-1", v1.VolumeFailed, "pvc-bound", "uid-bound"),
+		newFailedVolumeRecoveryTestPV("pv-bound", "vol-2", v1.VolumeBound, "pvc-bound", "uid-bound"),
+		newFailedVolumeRecoveryTestPV("pv-no-claim", "vol-3", v1.VolumeFailed, "", ""),
+		newFailedVolumeRecoveryTestPV("pv-deleted-claim", "vol-4", v1.VolumeFailed, "pvc-deleted", "uid-deleted"),
+		newFailedVolumeRecoveryTestPV("pv-recreated-claim", "vol-5", v1.VolumeFailed, "pvc-recreated", "uid-old"),
+	}
+	inTreePV := newFailedVolumeRecoveryTestPV("pv-in-tree", "vol-6", v1.VolumeFailed, "pvc-bound", "uid-bound")
+	inTreePV.Spec.CSI = nil
+	pvs = append(pvs, inTreePV)
+
+	recoverablePVs := getRecoverableFailedPVs(ctx, pvs, pvcLister)
+	if len(recoverablePVs) != 1 {
+		t.Fatalf("expected 1 recoverable pv, got %d: %v", len(recoverablePVs), recoverablePVs)
+	}
+	if pv, ok := recoverablePVs["vol-1"]; !ok || pv.Name != "pv-failed" {
+		t.Errorf("expected pv-failed to be recoverable, got %v", recoverablePVs)
+	}
+}
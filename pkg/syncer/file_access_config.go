@@ -0,0 +1,194 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	clientset "k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cnsfileaccessconfigv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsfileaccessconfig/v1alpha1"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+// fileAccessConfigReconciler creates and deletes the CnsFileAccessConfig
+// instances of the supervisor namespace so that the ACLs of the file shares
+// used by the guest cluster follow the placement of the pods using them.
+// Instances are created for the file volumes used by the pods scheduled on a
+// node, and deleted once no pod of the node uses the volume and the volume is
+// no longer attached to the node. ControllerPublishVolume and
+// ControllerUnpublishVolume manage the same instances, the reconciler catches
+// up with the instances they missed or leaked.
+type fileAccessConfigReconciler struct {
+	k8sClient           clientset.Interface
+	supervisorNamespace string
+}
+
+// newFileAccessConfigReconciler returns a fileAccessConfigReconciler for the
+// CnsFileAccessConfig instances of the given supervisor namespace.
+func newFileAccessConfigReconciler(k8sclient clientset.Interface,
+	supervisorNamespace string) *fileAccessConfigReconciler {
+	return &fileAccessConfigReconciler{
+		k8sClient:           k8sclient,
+		supervisorNamespace: supervisorNamespace,
+	}
+}
+
+// getFileAccessConfigName returns the name of the CnsFileAccessConfig
+// instance of the given node VM and supervisor PVC, as named by
+// ControllerPublishVolume.
+func getFileAccessConfigName(vmName string, pvcName string) string {
+	return vmName + "-" + pvcName
+}
+
+// reconcile creates the missing CnsFileAccessConfig instances and deletes the
+// ones no longer used by the pods of the guest cluster.
+func (r *fileAccessConfigReconciler) reconcile(ctx context.Context, metadataSyncer *metadataSyncInformer) {
+	log := logger.GetLogger(ctx)
+	log.Debug("FileAccessConfig: start")
+	nodeList, err := r.k8sClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		log.Errorf("FileAccessConfig: Failed to get nodes from kubernetes. Err: %+v", err)
+		return
+	}
+	nodes := make(map[string]bool)
+	for _, node := range nodeList.Items {
+		nodes[node.Name] = true
+	}
+	pods, err := metadataSyncer.podLister.List(labels.Everything())
+	if err != nil {
+		log.Errorf("FileAccessConfig: Failed to get pods from kubernetes. Err: %+v", err)
+		return
+	}
+	volumeAttachments, err := r.k8sClient.StorageV1().VolumeAttachments().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		log.Errorf("FileAccessConfig: Failed to get volume attachments from kubernetes. Err: %+v", err)
+		return
+	}
+	fileAccessConfigList := &cnsfileaccessconfigv1alpha1.CnsFileAccessConfigList{}
+	if err := metadataSyncer.getCnsOperatorClient().List(ctx, fileAccessConfigList,
+		client.InNamespace(r.supervisorNamespace)); err != nil {
+		log.Errorf("FileAccessConfig: Failed to get CnsFileAccessConfig instances from namespace %s. Err: %+v",
+			r.supervisorNamespace, err)
+		return
+	}
+
+	expected := getExpectedFileAccessConfigs(metadataSyncer, pods, nodes)
+	attached := getAttachedFileAccessConfigs(metadataSyncer, volumeAttachments.Items)
+	existing := make(map[string]bool)
+	for i := range fileAccessConfigList.Items {
+		fileAccessConfig := &fileAccessConfigList.Items[i]
+		existing[fileAccessConfig.Name] = true
+		if !isStaleFileAccessConfig(fileAccessConfig, nodes, expected, attached) {
+			continue
+		}
+		log.Infof("FileAccessConfig: Deleting CnsFileAccessConfig %s/%s of volume %s no longer used on node %s",
+			r.supervisorNamespace, fileAccessConfig.Name, fileAccessConfig.Spec.PvcName, fileAccessConfig.Spec.VMName)
+		if err := metadataSyncer.getCnsOperatorClient().Delete(ctx, fileAccessConfig); err != nil &&
+			!apierrors.IsNotFound(err) {
+			log.Errorf("FileAccessConfig: Failed to delete CnsFileAccessConfig %s/%s. Err: %+v",
+				r.supervisorNamespace, fileAccessConfig.Name, err)
+		}
+	}
+	for name, spec := range expected {
+		if existing[name] {
+			continue
+		}
+		log.Infof("FileAccessConfig: Creating CnsFileAccessConfig %s/%s of volume %s used on node %s",
+			r.supervisorNamespace, name, spec.PvcName, spec.VMName)
+		fileAccessConfig := &cnsfileaccessconfigv1alpha1.CnsFileAccessConfig{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: r.supervisorNamespace,
+			},
+			Spec: spec,
+		}
+		if err := metadataSyncer.getCnsOperatorClient().Create(ctx, fileAccessConfig); err != nil &&
+			!apierrors.IsAlreadyExists(err) {
+			log.Errorf("FileAccessConfig: Failed to create CnsFileAccessConfig %s/%s. Err: %+v",
+				r.supervisorNamespace, name, err)
+		}
+	}
+	log.Debug("FileAccessConfig: end")
+}
+
+// getExpectedFileAccessConfigs returns the specs of the CnsFileAccessConfig
+// instances of the file volumes used by the given pods scheduled on the given
+// nodes, by instance name.
+func getExpectedFileAccessConfigs(metadataSyncer *metadataSyncInformer, pods []*v1.Pod,
+	nodes map[string]bool) map[string]cnsfileaccessconfigv1alpha1.CnsFileAccessConfigSpec {
+	expected := make(map[string]cnsfileaccessconfigv1alpha1.CnsFileAccessConfigSpec)
+	for _, pod := range pods {
+		if !nodes[pod.Spec.NodeName] || pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+		for _, volume := range getPodVolumes(metadataSyncer, pod) {
+			if !volume.multiAttach {
+				continue
+			}
+			// The volume handle of guest volumes is the name of their
+			// supervisor PVC.
+			expected[getFileAccessConfigName(pod.Spec.NodeName, volume.volumeID)] =
+				cnsfileaccessconfigv1alpha1.CnsFileAccessConfigSpec{
+					PvcName: volume.volumeID,
+					VMName:  pod.Spec.NodeName,
+				}
+		}
+	}
+	return expected
+}
+
+// getAttachedFileAccessConfigs returns the names of the CnsFileAccessConfig
+// instances of the volumes attached to nodes by the given volume attachments.
+// They are deleted by ControllerUnpublishVolume once the volume is detached.
+func getAttachedFileAccessConfigs(metadataSyncer *metadataSyncInformer,
+	volumeAttachments []storagev1.VolumeAttachment) map[string]bool {
+	attached := make(map[string]bool)
+	for _, va := range volumeAttachments {
+		if va.Spec.Attacher != csitypes.Name || va.Spec.Source.PersistentVolumeName == nil {
+			continue
+		}
+		pv, err := metadataSyncer.pvLister.Get(*va.Spec.Source.PersistentVolumeName)
+		if err != nil || pv.Spec.CSI == nil {
+			continue
+		}
+		attached[getFileAccessConfigName(va.Spec.NodeName, pv.Spec.CSI.VolumeHandle)] = true
+	}
+	return attached
+}
+
+// isStaleFileAccessConfig returns true if the given CnsFileAccessConfig
+// instance is managed by the guest cluster, i.e. it grants a node of the
+// guest cluster access to a volume, and is neither expected nor attached.
+func isStaleFileAccessConfig(fileAccessConfig *cnsfileaccessconfigv1alpha1.CnsFileAccessConfig,
+	nodes map[string]bool, expected map[string]cnsfileaccessconfigv1alpha1.CnsFileAccessConfigSpec,
+	attached map[string]bool) bool {
+	if fileAccessConfig.DeletionTimestamp != nil || !nodes[fileAccessConfig.Spec.VMName] ||
+		fileAccessConfig.Name != getFileAccessConfigName(fileAccessConfig.Spec.VMName,
+			fileAccessConfig.Spec.PvcName) {
+		return false
+	}
+	_, ok := expected[fileAccessConfig.Name]
+	return !ok && !attached[fileAccessConfig.Name]
+}
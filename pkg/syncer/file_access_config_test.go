@@ -0,0 +1,137 @@
+package syncer
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	cnsoperatorv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator"
+	cnsfileaccessconfigv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsfileaccessconfig/v1alpha1"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+func newTestFileAccessConfig(vmName string, pvcName string) *cnsfileaccessconfigv1alpha1.CnsFileAccessConfig {
+	return &cnsfileaccessconfigv1alpha1.CnsFileAccessConfig{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "sv-ns", Name: getFileAccessConfigName(vmName, pvcName)},
+		Spec:       cnsfileaccessconfigv1alpha1.CnsFileAccessConfigSpec{PvcName: pvcName, VMName: vmName},
+	}
+}
+
+func TestFileAccessConfigReconcile(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pvIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	pvcIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	podIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for i, accessMode := range []v1.PersistentVolumeAccessMode{v1.ReadWriteMany, v1.ReadWriteMany,
+		v1.ReadWriteOnce} {
+		name := strconv.Itoa(i + 1)
+		if err := pvIndexer.Add(&v1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pv-" + name},
+			Spec: v1.PersistentVolumeSpec{
+				AccessModes: []v1.PersistentVolumeAccessMode{accessMode},
+				PersistentVolumeSource: v1.PersistentVolumeSource{
+					CSI: &v1.CSIPersistentVolumeSource{Driver: csitypes.Name, VolumeHandle: "sv-pvc-" + name},
+				},
+			},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if err := pvcIndexer.Add(&v1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pvc-" + name},
+			Spec:       v1.PersistentVolumeClaimSpec{VolumeName: "pv-" + name},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, pod := range []*v1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pod-rwx"},
+			Spec: v1.PodSpec{NodeName: "node-1", Volumes: []v1.Volume{{Name: "data", VolumeSource: v1.VolumeSource{
+				PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "pvc-1"},
+			}}}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pod-rwo"},
+			Spec: v1.PodSpec{NodeName: "node-2", Volumes: []v1.Volume{{Name: "data", VolumeSource: v1.VolumeSource{
+				PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "pvc-3"},
+			}}}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pod-completed"},
+			Spec: v1.PodSpec{NodeName: "node-2", Volumes: []v1.Volume{{Name: "data", VolumeSource: v1.VolumeSource{
+				PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "pvc-1"},
+			}}}},
+			Status: v1.PodStatus{Phase: v1.PodSucceeded},
+		},
+	} {
+		if err := podIndexer.Add(pod); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pvName := "pv-2"
+	k8sclient := k8sfake.NewSimpleClientset(
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-2"}},
+		&storagev1.VolumeAttachment{
+			ObjectMeta: metav1.ObjectMeta{Name: "va-2"},
+			Spec: storagev1.VolumeAttachmentSpec{
+				Attacher: csitypes.Name,
+				NodeName: "node-2",
+				Source:   storagev1.VolumeAttachmentSource{PersistentVolumeName: &pvName},
+			},
+		},
+	)
+	s := runtime.NewScheme()
+	if err := cnsoperatorv1alpha1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	cnsOperatorClient := fake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(
+		// Stale, the pod using the volume on node-2 completed.
+		newTestFileAccessConfig("node-2", "sv-pvc-1"),
+		// Kept until the volume is detached from node-2.
+		newTestFileAccessConfig("node-2", "sv-pvc-2"),
+		// Kept, the VM is not a node of the guest cluster.
+		newTestFileAccessConfig("other-vm", "sv-pvc-1"),
+	).Build()
+	metadataSyncer := &metadataSyncInformer{
+		cnsOperatorClient: cnsOperatorClient,
+		pvLister:          corelisters.NewPersistentVolumeLister(pvIndexer),
+		pvcLister:         corelisters.NewPersistentVolumeClaimLister(pvcIndexer),
+		podLister:         corelisters.NewPodLister(podIndexer),
+	}
+
+	newFileAccessConfigReconciler(k8sclient, "sv-ns").reconcile(ctx, metadataSyncer)
+
+	fileAccessConfigList := &cnsfileaccessconfigv1alpha1.CnsFileAccessConfigList{}
+	if err := cnsOperatorClient.List(ctx, fileAccessConfigList, client.InNamespace("sv-ns")); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, fileAccessConfig := range fileAccessConfigList.Items {
+		names = append(names, fileAccessConfig.Name)
+	}
+	sort.Strings(names)
+	expected := []string{"node-1-sv-pvc-1", "node-2-sv-pvc-2", "other-vm-sv-pvc-1"}
+	if len(names) != len(expected) {
+		t.Fatalf("expected CnsFileAccessConfig instances %v, got %v", expected, names)
+	}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Fatalf("expected CnsFileAccessConfig instances %v, got %v", expected, names)
+		}
+	}
+}
@@ -18,6 +18,7 @@ package syncer
 
 import (
 	"context"
+	"strings"
 	"sync"
 	"time"
 
@@ -25,21 +26,79 @@ import (
 	"github.com/vmware/govmomi/cns"
 	cnstypes "github.com/vmware/govmomi/cns/types"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/migration"
+	volumes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/volume"
 	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/prometheus"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
 )
 
+var (
+	// initialFullSyncCompletionTime is the completion time of the first
+	// successful full sync since the syncer started. It is zero until then.
+	initialFullSyncCompletionTime time.Time
+	// initialFullSyncCompletionRecorded is set once the completion time of
+	// the initial full sync is set on the TriggerCsiFullSync instance.
+	initialFullSyncCompletionRecorded bool
+	initialFullSyncLock               sync.Mutex
+	// triggerCsiFullSyncClient is the client of the TriggerCsiFullSync
+	// instance. It is nil if the TriggerCsiFullSync feature is disabled.
+	triggerCsiFullSyncClient client.Client
+)
+
+// recordFullSyncSuccess records the completion of a successful full sync,
+// and sets the completion time of the initial full sync on the
+// TriggerCsiFullSync instance, whichever way the full sync was triggered.
+func recordFullSyncSuccess(ctx context.Context, completionTime time.Time) {
+	log := logger.GetLogger(ctx)
+	initialFullSyncLock.Lock()
+	defer initialFullSyncLock.Unlock()
+	if initialFullSyncCompletionTime.IsZero() {
+		initialFullSyncCompletionTime = completionTime
+		prometheus.FullSyncInitialCompletedGauge.Set(1)
+	}
+	prometheus.FullSyncLastSuccessTimestampGauge.Set(float64(completionTime.Unix()))
+	recordLastFullSyncSuccess(completionTime)
+	if initialFullSyncCompletionRecorded || triggerCsiFullSyncClient == nil {
+		return
+	}
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		instance, err := getTriggerCsiFullSyncInstance(ctx, triggerCsiFullSyncClient)
+		if err != nil {
+			return err
+		}
+		instance.Status.InitialFullSyncCompletedTimeStamp = &metav1.Time{Time: initialFullSyncCompletionTime}
+		return updateTriggerCsiFullSyncInstance(ctx, triggerCsiFullSyncClient, instance)
+	})
+	if err != nil {
+		log.Warnf("Failed to set the completion time of the initial full sync on TriggerCsiFullSync instance %q. "+
+			"Err: %+v", common.TriggerCsiFullSyncCRName, err)
+		return
+	}
+	initialFullSyncCompletionRecorded = true
+}
+
+// GetInitialFullSyncCompletionTime returns the completion time of the first
+// successful full sync since the syncer started, and whether it completed.
+func GetInitialFullSyncCompletionTime() (time.Time, bool) {
+	initialFullSyncLock.Lock()
+	defer initialFullSyncLock.Unlock()
+	return initialFullSyncCompletionTime, !initialFullSyncCompletionTime.IsZero()
+}
+
 // CsiFullSync reconciles volume metadata on a vanilla k8s cluster with volume
 // metadata on CNS.
 func CsiFullSync(ctx context.Context, metadataSyncer *metadataSyncInformer) error {
 	log := logger.GetLogger(ctx)
 	log.Infof("FullSync: start")
-	fullSyncStartTime := time.Now()
+	fullSyncStartTime := syncerClock.Now()
+	recordFullSyncStart(fullSyncStartTime)
 	var migrationFeatureStateForFullSync bool
 	var err error
 	// Fetch CSI migration feature state, before performing full sync operations.
@@ -52,7 +111,11 @@ func CsiFullSync(ctx context.Context, metadataSyncer *metadataSyncInformer) erro
 			fullSyncStatus = prometheus.PrometheusFailStatus
 		}
 		prometheus.FullSyncOpsHistVec.WithLabelValues(fullSyncStatus).Observe(
-			(time.Since(fullSyncStartTime)).Seconds())
+			(syncerClock.Since(fullSyncStartTime)).Seconds())
+		if err == nil {
+			recordFullSyncSuccess(ctx, syncerClock.Now())
+		}
+		recordFullSyncEnd(syncerClock.Now(), err)
 	}()
 
 	// Get K8s PVs in State "Bound", "Available" or "Released".
@@ -95,6 +158,13 @@ func CsiFullSync(ctx context.Context, metadataSyncer *metadataSyncInformer) erro
 			k8sPVMap[volumeHandle] = ""
 		}
 	}
+	// Volumes bound to PVCs of namespaces which are not synced are left
+	// as-is in CNS. They stay in k8sPVMap so that they are not deleted.
+	k8sPVs = metadataSyncer.namespaceScope.filterPVs(k8sPVs)
+	// Volumes in maintenance are left as-is in CNS too. The maintenance of
+	// the volumes for which it ended is cleared.
+	removeExpiredVolumeMaintenance(ctx, k8sPVs)
+	k8sPVs = filterPVsNotInMaintenance(ctx, k8sPVs)
 	// pvToPVCMap maps pv name to corresponding PVC.
 	// pvcToPodMap maps pvc to the mounted Pod.
 	pvToPVCMap, pvcToPodMap, err := buildPVCMapPodMap(ctx, k8sPVs, metadataSyncer)
@@ -116,6 +186,7 @@ func CsiFullSync(ctx context.Context, metadataSyncer *metadataSyncInformer) erro
 		return err
 	}
 
+	recordFullSyncPhase(fullSyncPhaseComputingChanges)
 	volumeToCnsEntityMetadataMap, volumeToK8sEntityMetadataMap, volumeClusterDistributionMap, err :=
 		fullSyncConstructVolumeMaps(ctx, k8sPVs, queryAllResult.Volumes, pvToPVCMap,
 			pvcToPodMap, metadataSyncer, migrationFeatureStateForFullSync)
@@ -146,12 +217,26 @@ func CsiFullSync(ctx context.Context, metadataSyncer *metadataSyncInformer) erro
 		return err
 	}
 
+	if metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorVanilla &&
+		metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.VolumeTagSync) {
+		csiSyncVolumeTagsToPVLabels(ctx, metadataSyncer, vcenter, k8sPVs)
+	}
+	if metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.VolumeCountMetrics) {
+		csiReportVolumeCounts(ctx, metadataSyncer, vcenter)
+	}
+	if volumeTopologyChecker != nil &&
+		metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.TopologyFullSyncValidation) {
+		volumeTopologyChecker.validate(ctx, k8sPVs, queryAllResult.Volumes)
+	}
+
+	recordFullSyncOperations(len(createSpecArray), len(updateSpecArray), len(volToBeDeleted))
+	dryRun := isFullSyncDryRun(ctx)
 	wg := sync.WaitGroup{}
 	wg.Add(3)
 	// Perform operations.
-	go fullSyncCreateVolumes(ctx, createSpecArray, metadataSyncer, &wg, migrationFeatureStateForFullSync)
-	go fullSyncUpdateVolumes(ctx, updateSpecArray, metadataSyncer, &wg)
-	go fullSyncDeleteVolumes(ctx, volToBeDeleted, metadataSyncer, &wg, migrationFeatureStateForFullSync)
+	go fullSyncCreateVolumes(ctx, createSpecArray, metadataSyncer, &wg, migrationFeatureStateForFullSync, dryRun)
+	go fullSyncUpdateVolumes(ctx, updateSpecArray, metadataSyncer, &wg, dryRun)
+	go fullSyncDeleteVolumes(ctx, volToBeDeleted, metadataSyncer, &wg, migrationFeatureStateForFullSync, dryRun)
 	wg.Wait()
 
 	cleanupCnsMaps(k8sPVMap)
@@ -164,13 +249,21 @@ func CsiFullSync(ctx context.Context, metadataSyncer *metadataSyncInformer) erro
 // fullSyncCreateVolumes creates volumes with given array of createSpec.
 // Before creating a volume, all current K8s volumes are retrieved.
 // If the volume is successfully created, it is removed from cnsCreationMap.
+// In dry run mode, the volumes are only logged.
 func fullSyncCreateVolumes(ctx context.Context, createSpecArray []cnstypes.CnsVolumeCreateSpec,
-	metadataSyncer *metadataSyncInformer, wg *sync.WaitGroup, migrationFeatureStateForFullSync bool) {
+	metadataSyncer *metadataSyncInformer, wg *sync.WaitGroup, migrationFeatureStateForFullSync bool, dryRun bool) {
 	log := logger.GetLogger(ctx)
 	defer wg.Done()
 	currentK8sPVMap := make(map[string]bool)
-	volumeOperationsLock.Lock()
-	defer volumeOperationsLock.Unlock()
+	var volumeIDs []string
+	for _, createSpec := range createSpecArray {
+		if volumeID := getCreateSpecVolumeID(&createSpec); volumeID != "" {
+			volumeIDs = append(volumeIDs, volumeID)
+		}
+	}
+	// Lock the volumes before getting the K8s PVs, so that they can't change
+	// until the volumes are created.
+	defer volumeOperationsLocks.LockAll(volumeIDs)()
 	// Get all K8s PVs.
 	currentK8sPV, err := getPVsInBoundAvailableOrReleased(ctx, metadataSyncer)
 	if err != nil {
@@ -198,19 +291,18 @@ func fullSyncCreateVolumes(ctx context.Context, createSpecArray []cnstypes.CnsVo
 	}
 	for _, createSpec := range createSpecArray {
 		// Create volume if present in currentK8sPVMap.
-		var volumeID string
-		if createSpec.VolumeType == common.BlockVolumeType && createSpec.BackingObjectDetails != nil &&
-			createSpec.BackingObjectDetails.(*cnstypes.CnsBlockBackingDetails) != nil {
-			volumeID = createSpec.BackingObjectDetails.(*cnstypes.CnsBlockBackingDetails).BackingDiskId
-		} else if createSpec.VolumeType == common.FileVolumeType && createSpec.BackingObjectDetails != nil &&
-			createSpec.BackingObjectDetails.(*cnstypes.CnsVsanFileShareBackingDetails) != nil {
-			volumeID = createSpec.BackingObjectDetails.(*cnstypes.CnsVsanFileShareBackingDetails).BackingFileId
-		} else {
+		volumeID := getCreateSpecVolumeID(&createSpec)
+		if volumeID == "" {
 			log.Warnf("Skipping createSpec: %+v as VolumeType is unknown or BackingObjectDetails is not valid",
 				spew.Sdump(createSpec))
 			continue
 		}
 		if _, existsInK8s := currentK8sPVMap[volumeID]; existsInK8s {
+			if dryRun {
+				log.Infof("FullSync: dry run: would call CreateVolume for volume id: %q with createSpec %+v",
+					volumeID, spew.Sdump(createSpec))
+				continue
+			}
 			log.Debugf("FullSync: Calling CreateVolume for volume id: %q with createSpec %+v",
 				volumeID, spew.Sdump(createSpec))
 			_, _, err := metadataSyncer.volumeManager.CreateVolume(ctx, &createSpec)
@@ -226,17 +318,39 @@ func fullSyncCreateVolumes(ctx context.Context, createSpecArray []cnstypes.CnsVo
 
 }
 
+// getCreateSpecVolumeID returns the ID of the volume created by the given
+// createSpec, or an empty string if the VolumeType is unknown or the
+// BackingObjectDetails are not valid.
+func getCreateSpecVolumeID(createSpec *cnstypes.CnsVolumeCreateSpec) string {
+	if createSpec.VolumeType == common.BlockVolumeType && createSpec.BackingObjectDetails != nil {
+		if details, ok := createSpec.BackingObjectDetails.(*cnstypes.CnsBlockBackingDetails); ok && details != nil {
+			return details.BackingDiskId
+		}
+	} else if createSpec.VolumeType == common.FileVolumeType && createSpec.BackingObjectDetails != nil {
+		if details, ok := createSpec.BackingObjectDetails.(*cnstypes.CnsVsanFileShareBackingDetails); ok && details != nil {
+			return details.BackingFileId
+		}
+	}
+	return ""
+}
+
 // fullSyncDeleteVolumes deletes volumes with given array of volumeId.
 // Before deleting a volume, all current K8s volumes are retrieved.
 // If the volume is successfully deleted, it is removed from cnsDeletionMap.
+// In dry run mode, the volumes are only logged.
 func fullSyncDeleteVolumes(ctx context.Context, volumeIDDeleteArray []cnstypes.CnsVolumeId,
-	metadataSyncer *metadataSyncInformer, wg *sync.WaitGroup, migrationFeatureStateForFullSync bool) {
+	metadataSyncer *metadataSyncInformer, wg *sync.WaitGroup, migrationFeatureStateForFullSync bool, dryRun bool) {
 	defer wg.Done()
 	log := logger.GetLogger(ctx)
 	deleteDisk := false
 	currentK8sPVMap := make(map[string]bool)
-	volumeOperationsLock.Lock()
-	defer volumeOperationsLock.Unlock()
+	var volumeIDs []string
+	for _, volumeID := range volumeIDDeleteArray {
+		volumeIDs = append(volumeIDs, volumeID.Id)
+	}
+	// Lock the volumes before getting the K8s PVs, so that they can't change
+	// until the volumes are deleted.
+	defer volumeOperationsLocks.LockAll(volumeIDs)()
 	// Get all K8s PVs.
 	currentK8sPV, err := getPVsInBoundAvailableOrReleased(ctx, metadataSyncer)
 	if err != nil {
@@ -296,10 +410,19 @@ func fullSyncDeleteVolumes(ctx context.Context, volumeIDDeleteArray []cnstypes.C
 				}
 			}
 			if !inUsebyOtherK8SCluster {
+				if dryRun {
+					log.Infof("FullSync: fullSyncDeleteVolumes: dry run: would call DeleteVolume for volume %v "+
+						"with delete disk %v", volume.VolumeId.Id, deleteDisk)
+					continue
+				}
 				log.Infof("FullSync: fullSyncDeleteVolumes: Calling DeleteVolume for volume %v with delete disk %v",
 					volume.VolumeId.Id, deleteDisk)
-				_, err := metadataSyncer.volumeManager.DeleteVolume(ctx, volume.VolumeId.Id, deleteDisk)
-				if err != nil {
+				_, err := deleteVolume(ctx, metadataSyncer, volume.VolumeId.Id, deleteDisk)
+				if volumes.IsErrorKind(err, volumes.ErrorKindNotFound) {
+					// The volume is already gone from CNS.
+					log.Infof("FullSync: fullSyncDeleteVolumes: volume %s is not found in CNS. Err: %+v",
+						volume.VolumeId.Id, err)
+				} else if err != nil {
 					log.Warnf("FullSync: fullSyncDeleteVolumes: Failed to delete volume %s with error %+v",
 						volume.VolumeId.Id, err)
 					continue
@@ -323,28 +446,85 @@ func fullSyncDeleteVolumes(ctx context.Context, volumeIDDeleteArray []cnstypes.C
 }
 
 // fullSyncUpdateVolumes update metadata for volumes with given array of
-// createSpec.
+// createSpec. The updates are sent to CNS in batches of at most
+// fullSyncUpdateBatchSize volumes. In dry run mode, the updates are only
+// logged.
 func fullSyncUpdateVolumes(ctx context.Context, updateSpecArray []cnstypes.CnsVolumeMetadataUpdateSpec,
-	metadataSyncer *metadataSyncInformer, wg *sync.WaitGroup) {
+	metadataSyncer *metadataSyncInformer, wg *sync.WaitGroup, dryRun bool) {
 	defer wg.Done()
 	log := logger.GetLogger(ctx)
+	if dryRun {
+		for _, updateSpec := range updateSpecArray {
+			log.Infof("FullSync: dry run: would call UpdateVolumeMetadata for volume %s with updateSpec: %+v",
+				updateSpec.VolumeId.Id, spew.Sdump(updateSpec))
+		}
+		return
+	}
+	for _, batch := range getUpdateVolumeMetadataBatches(updateSpecArray, fullSyncUpdateBatchSize) {
+		log.Debugf("FullSync: Calling BatchUpdateVolumeMetadata for %d volumes with updateSpecs: %+v",
+			len(batch), spew.Sdump(batch))
+		// Full sync found the CNS metadata out of date, so the updates are
+		// always pushed, regardless of the metadata cache.
+		errs := metadataSyncer.volumeManager.BatchUpdateVolumeMetadata(ctx, batch)
+		for i := range batch {
+			updateSpec := &batch[i]
+			if err := errs[i]; err != nil {
+				metadataCache.forget(updateSpec.VolumeId.Id)
+				if volumes.IsErrorKind(err, volumes.ErrorKindNotFound) {
+					// The volume was deleted since it was queried, the next full
+					// sync does not find it anymore.
+					log.Infof("FullSync: volume %s is not found in CNS, skipping UpdateVolumeMetadata. Err: %v",
+						updateSpec.VolumeId.Id, err)
+					continue
+				}
+				log.Warnf("FullSync:UpdateVolumeMetadata failed for volume %s with err %v", updateSpec.VolumeId.Id, err)
+				continue
+			}
+			metadataCache.record(updateSpec)
+		}
+	}
+}
+
+// getUpdateVolumeMetadataBatches splits the given update specs in batches of
+// at most batchSize specs. CNS only accepts one spec per volume in a request,
+// so the specs of a volume updated several times, e.g. once per pod using a
+// block volume, are put in successive batches, in their order.
+func getUpdateVolumeMetadataBatches(updateSpecArray []cnstypes.CnsVolumeMetadataUpdateSpec,
+	batchSize int) [][]cnstypes.CnsVolumeMetadataUpdateSpec {
+	// rounds[i] holds the i-th update spec of each volume.
+	var rounds [][]cnstypes.CnsVolumeMetadataUpdateSpec
+	volumeSpecCount := make(map[string]int)
 	for _, updateSpec := range updateSpecArray {
-		log.Debugf("FullSync: Calling UpdateVolumeMetadata for volume %s with updateSpec: %+v",
-			updateSpec.VolumeId.Id, spew.Sdump(updateSpec))
-		if err := metadataSyncer.volumeManager.UpdateVolumeMetadata(ctx, &updateSpec); err != nil {
-			log.Warnf("FullSync:UpdateVolumeMetadata failed with err %v", err)
+		round := volumeSpecCount[updateSpec.VolumeId.Id]
+		volumeSpecCount[updateSpec.VolumeId.Id]++
+		if round == len(rounds) {
+			rounds = append(rounds, nil)
+		}
+		rounds[round] = append(rounds[round], updateSpec)
+	}
+	var batches [][]cnstypes.CnsVolumeMetadataUpdateSpec
+	for _, round := range rounds {
+		for start := 0; start < len(round); start += batchSize {
+			end := start + batchSize
+			if end > len(round) {
+				end = len(round)
+			}
+			batches = append(batches, round[start:end])
 		}
 	}
+	return batches
 }
 
 // buildCnsMetadataList build metadata list for given PV.
 // Metadata list may include PV metadata, PVC metadata and POD metadata.
+// If ephemeralOwnerMetadata is set, the metadata of the pod owning a PVC of
+// a generic ephemeral volume is included even if the pod isn't running.
 func buildCnsMetadataList(ctx context.Context, pv *v1.PersistentVolume, pvToPVCMap pvcMap,
-	pvcToPodMap podMap, clusterID string) []cnstypes.BaseCnsEntityMetadata {
+	pvcToPodMap podMap, clusterID string, ephemeralOwnerMetadata bool) []cnstypes.BaseCnsEntityMetadata {
 	log := logger.GetLogger(ctx)
 	var metadataList []cnstypes.BaseCnsEntityMetadata
 	// Get pv metadata.
-	pvMetadata := cnsvsphere.GetCnsKubernetesEntityMetaData(pv.Name, pv.GetLabels(),
+	pvMetadata := cnsvsphere.GetCnsKubernetesEntityMetaData(pv.Name, GetPVMetadataLabels(ctx, pv),
 		false, string(cnstypes.CnsKubernetesEntityTypePV), "", clusterID, nil)
 	metadataList = append(metadataList, pvMetadata)
 	if pvc, ok := pvToPVCMap[pv.Name]; ok {
@@ -357,6 +537,8 @@ func buildCnsMetadataList(ctx context.Context, pv *v1.PersistentVolume, pvToPVCM
 		metadataList = append(metadataList, cnstypes.BaseCnsEntityMetadata(pvcMetadata))
 
 		key := pvc.Namespace + "/" + pvc.Name
+		ownerPodFound := false
+		ownerPodName := getEphemeralVolumeOwnerPod(pvc)
 		if pods, ok := pvcToPodMap[key]; ok {
 			for _, pod := range pods {
 				// Get pod metadata.
@@ -366,6 +548,14 @@ func buildCnsMetadataList(ctx context.Context, pv *v1.PersistentVolume, pvToPVCM
 					nil, false, string(cnstypes.CnsKubernetesEntityTypePOD), pod.Namespace,
 					clusterID, []cnstypes.CnsKubernetesEntityReference{pvcEntityReference})
 				metadataList = append(metadataList, cnstypes.BaseCnsEntityMetadata(podMetadata))
+				if pod.Name == ownerPodName {
+					ownerPodFound = true
+				}
+			}
+		}
+		if ephemeralOwnerMetadata && !ownerPodFound {
+			if podMetadata := getEphemeralVolumeOwnerPodMetadata(pvc, clusterID); podMetadata != nil {
+				metadataList = append(metadataList, cnstypes.BaseCnsEntityMetadata(podMetadata))
 			}
 		}
 	}
@@ -394,9 +584,11 @@ func fullSyncConstructVolumeMaps(ctx context.Context, pvList []*v1.PersistentVol
 	}
 	var err error
 	var queryVolumeIds []cnstypes.CnsVolumeId
+	ephemeralOwnerMetadata := metadataSyncer.coCommonInterface.IsFSSEnabled(ctx,
+		common.GenericEphemeralVolumeMetadata)
 	for _, pv := range pvList {
 		k8sMetadata := buildCnsMetadataList(ctx, pv, pvToPVCMap, pvcToPodMap,
-			metadataSyncer.configInfo.Cfg.Global.ClusterID)
+			metadataSyncer.configInfo.Cfg.Global.ClusterID, ephemeralOwnerMetadata)
 		var volumeHandle string
 		if pv.Spec.CSI != nil {
 			volumeHandle = pv.Spec.CSI.VolumeHandle
@@ -657,6 +849,11 @@ func getVolumesToBeDeleted(ctx context.Context, cnsVolumeList []cnstypes.CnsVolu
 		}
 	}
 	for _, vol := range cnsVolumeList {
+		// Ephemeral inline volumes have no PV. They are deleted along with
+		// their CnsEphemeralVolume instance.
+		if strings.HasPrefix(vol.Name, common.EphemeralVolumeNamePrefix) {
+			continue
+		}
 		if _, existsInK8s := k8sPVMap[vol.VolumeId.Id]; !existsInK8s {
 			if _, existsInCnsDeletionMap := cnsDeletionMap[vol.VolumeId.Id]; existsInCnsDeletionMap {
 				// Volume does not exist in K8s across two fullsync cycles, because
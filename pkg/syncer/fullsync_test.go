@@ -0,0 +1,79 @@
+package syncer
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/internalapis"
+	triggercsifullsyncv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/internalapis/cnsoperator/triggercsifullsync/v1alpha1"
+)
+
+func TestGetUpdateVolumeMetadataBatches(t *testing.T) {
+	newSpec := func(volumeID string) cnstypes.CnsVolumeMetadataUpdateSpec {
+		return cnstypes.CnsVolumeMetadataUpdateSpec{VolumeId: cnstypes.CnsVolumeId{Id: volumeID}}
+	}
+	// vol-1 is updated once per pod using it.
+	updateSpecs := []cnstypes.CnsVolumeMetadataUpdateSpec{
+		newSpec("vol-1"), newSpec("vol-1"), newSpec("vol-2"), newSpec("vol-3"), newSpec("vol-1"), newSpec("vol-4"),
+	}
+	batches := getUpdateVolumeMetadataBatches(updateSpecs, 2)
+	var batchVolumeIDs [][]string
+	for _, batch := range batches {
+		var volumeIDs []string
+		for _, updateSpec := range batch {
+			volumeIDs = append(volumeIDs, updateSpec.VolumeId.Id)
+		}
+		batchVolumeIDs = append(batchVolumeIDs, volumeIDs)
+	}
+	expected := [][]string{{"vol-1", "vol-2"}, {"vol-3", "vol-4"}, {"vol-1"}, {"vol-1"}}
+	if !reflect.DeepEqual(batchVolumeIDs, expected) {
+		t.Errorf("expected batches %v, got %v", expected, batchVolumeIDs)
+	}
+
+	if batches := getUpdateVolumeMetadataBatches(nil, 2); len(batches) != 0 {
+		t.Errorf("expected no batch, got %v", batches)
+	}
+}
+
+func TestRecordFullSyncSuccess(t *testing.T) {
+	defer resetSyncerHealth()
+	defer func() { triggerCsiFullSyncClient = nil }()
+	resetSyncerHealth()
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := internalapis.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	triggerCsiFullSyncClient = fake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(
+		&triggercsifullsyncv1alpha1.TriggerCsiFullSync{
+			ObjectMeta: metav1.ObjectMeta{Name: common.TriggerCsiFullSyncCRName},
+		}).Build()
+	getCompletionTime := func() *metav1.Time {
+		instance, err := getTriggerCsiFullSyncInstance(ctx, triggerCsiFullSyncClient)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return instance.Status.InitialFullSyncCompletedTimeStamp
+	}
+
+	initial := time.Now().Add(-time.Hour).Truncate(time.Second)
+	recordFullSyncSuccess(ctx, initial)
+	if completionTime := getCompletionTime(); completionTime == nil || !completionTime.Time.Equal(initial) {
+		t.Errorf("expected the initial full sync to have completed at %v, got %v", initial, completionTime)
+	}
+	recordFullSyncSuccess(ctx, time.Now())
+	if completionTime := getCompletionTime(); completionTime == nil || !completionTime.Time.Equal(initial) {
+		t.Errorf("expected the completion time of the initial full sync to be kept, got %v", completionTime)
+	}
+	if completionTime, completed := GetInitialFullSyncCompletionTime(); !completed || !completionTime.Equal(initial) {
+		t.Errorf("expected the initial full sync to have completed at %v, got %v", initial, completionTime)
+	}
+}
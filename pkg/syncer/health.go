@@ -0,0 +1,247 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+)
+
+const (
+	// HealthzPath is the path of the liveness endpoint of the syncer.
+	HealthzPath = "/healthz"
+	// ReadyzPath is the path of the readiness endpoint of the syncer.
+	ReadyzPath = "/readyz"
+
+	// fullSyncStallIntervals is the number of full sync intervals without any
+	// full sync starting, after which the metadata syncer is considered stuck.
+	fullSyncStallIntervals = 3
+	// vcSessionCheckTimeout is the timeout of the check of the vCenter
+	// sessions by the readiness endpoint.
+	vcSessionCheckTimeout = 5 * time.Second
+)
+
+// syncerHealth is the response of the health endpoints of the syncer.
+type syncerHealth struct {
+	Healthy bool `json:"healthy"`
+	// Leader is false if this instance hasn't initialized the metadata syncer,
+	// e.g. while waiting to be elected leader. Such an instance is always
+	// healthy and ready.
+	Leader bool `json:"leader"`
+	// InformersSynced is true once the informer caches of the metadata syncer
+	// synced.
+	InformersSynced bool `json:"informersSynced"`
+	// LastFullSyncStartTime is the start time of the running or last full
+	// sync.
+	LastFullSyncStartTime time.Time `json:"lastFullSyncStartTime,omitempty"`
+	// LastFullSyncSuccessTime is the completion time of the last successful
+	// full sync.
+	LastFullSyncSuccessTime time.Time `json:"lastFullSyncSuccessTime,omitempty"`
+	// VCSessions is the status of the vCenter sessions. It is only reported
+	// by the readiness endpoint.
+	VCSessions []cnsvsphere.SessionStatus `json:"vcSessions,omitempty"`
+	// SupervisorConnected is the result of the last probe of the connection
+	// to the supervisor cluster. It is only reported by the readiness
+	// endpoint of guest clusters.
+	SupervisorConnected *bool `json:"supervisorConnected,omitempty"`
+	// Failures lists the reasons why the syncer isn't healthy or ready.
+	Failures []string `json:"failures,omitempty"`
+}
+
+var (
+	// healthLock guards the variables below.
+	healthLock sync.Mutex
+	// metadataSyncerStarted is true once this instance started initializing
+	// the metadata syncer.
+	metadataSyncerStarted bool
+	// informersSyncedTime is the time the informer caches of the metadata
+	// syncer synced. It is zero until then.
+	informersSyncedTime time.Time
+	// fullSyncStallTimeout is the duration without any full sync starting,
+	// after which the metadata syncer is considered stuck.
+	fullSyncStallTimeout time.Duration
+	// lastFullSyncSuccessTime is the completion time of the last successful
+	// full sync.
+	lastFullSyncSuccessTime time.Time
+	// supervisorProbed is true once the connection to the supervisor cluster
+	// was probed.
+	supervisorProbed bool
+	// supervisorConnectionErr is the error of the last probe of the
+	// connection to the supervisor cluster.
+	supervisorConnectionErr error
+)
+
+// recordMetadataSyncerStart records that this instance started initializing
+// the metadata syncer.
+func recordMetadataSyncerStart() {
+	healthLock.Lock()
+	defer healthLock.Unlock()
+	metadataSyncerStarted = true
+}
+
+// recordInformersSynced records that the informer caches of the metadata
+// syncer synced, and the full sync interval used by the metadata syncer.
+func recordInformersSynced(syncedTime time.Time, fullSyncInterval time.Duration) {
+	healthLock.Lock()
+	defer healthLock.Unlock()
+	informersSyncedTime = syncedTime
+	fullSyncStallTimeout = fullSyncStallIntervals * fullSyncInterval
+}
+
+// recordLastFullSyncSuccess records the completion time of the last
+// successful full sync.
+func recordLastFullSyncSuccess(completionTime time.Time) {
+	healthLock.Lock()
+	defer healthLock.Unlock()
+	lastFullSyncSuccessTime = completionTime
+}
+
+// recordSupervisorConnection records the result of a probe of the connection
+// to the supervisor cluster.
+func recordSupervisorConnection(err error) {
+	healthLock.Lock()
+	defer healthLock.Unlock()
+	supervisorProbed = true
+	supervisorConnectionErr = err
+}
+
+// getSyncerHealth returns the liveness of the syncer at the given time.
+func getSyncerHealth(now time.Time) syncerHealth {
+	healthLock.Lock()
+	health := syncerHealth{
+		Healthy:                 true,
+		Leader:                  metadataSyncerStarted,
+		InformersSynced:         !informersSyncedTime.IsZero(),
+		LastFullSyncSuccessTime: lastFullSyncSuccessTime,
+	}
+	syncedTime := informersSyncedTime
+	stallTimeout := fullSyncStallTimeout
+	healthLock.Unlock()
+	debugLock.Lock()
+	health.LastFullSyncStartTime = currentFullSyncProgress.StartTime
+	debugLock.Unlock()
+
+	if !health.InformersSynced {
+		// Informers may take a while to sync on large clusters, and a failure
+		// to sync them makes the syncer exit.
+		return health
+	}
+	// Full sync starts right after the informers synced, then on every
+	// interval, so not starting any for several intervals means the metadata
+	// syncer is stuck.
+	lastActivity := syncedTime
+	if health.LastFullSyncStartTime.After(lastActivity) {
+		lastActivity = health.LastFullSyncStartTime
+	}
+	if stallTimeout > 0 && now.Sub(lastActivity) > stallTimeout {
+		health.Healthy = false
+		health.Failures = append(health.Failures, fmt.Sprintf("no full sync started since %v",
+			lastActivity.Format(time.RFC3339)))
+	}
+	return health
+}
+
+// getSyncerReadiness returns the readiness of the syncer at the given time,
+// given the status of the vCenter sessions.
+func getSyncerReadiness(now time.Time, vcSessions []cnsvsphere.SessionStatus) syncerHealth {
+	health := getSyncerHealth(now)
+	if !health.Leader {
+		return health
+	}
+	if !health.InformersSynced {
+		health.Healthy = false
+		health.Failures = append(health.Failures, "informer caches not synced")
+		return health
+	}
+	if _, completed := GetInitialFullSyncCompletionTime(); !completed {
+		health.Healthy = false
+		health.Failures = append(health.Failures, "initial full sync not completed")
+	}
+	health.VCSessions = vcSessions
+	for _, vcSession := range vcSessions {
+		if !vcSession.Authenticated {
+			health.Healthy = false
+			health.Failures = append(health.Failures, fmt.Sprintf("vCenter %q not connected", vcSession.Host))
+		}
+	}
+	healthLock.Lock()
+	probed, connectionErr := supervisorProbed, supervisorConnectionErr
+	healthLock.Unlock()
+	if probed {
+		connected := connectionErr == nil
+		health.SupervisorConnected = &connected
+		if !connected {
+			health.Healthy = false
+			health.Failures = append(health.Failures, fmt.Sprintf("supervisor cluster not connected: %v",
+				connectionErr))
+		}
+	}
+	return health
+}
+
+// getVCSessionStatuses returns the status of the sessions of the registered
+// vCenters.
+func getVCSessionStatuses(ctx context.Context) []cnsvsphere.SessionStatus {
+	ctx, cancel := context.WithTimeout(ctx, vcSessionCheckTimeout)
+	defer cancel()
+	var statuses []cnsvsphere.SessionStatus
+	for _, vc := range cnsvsphere.GetVirtualCenterManager(ctx).GetAllVirtualCenters() {
+		statuses = append(statuses, vc.GetSessionStatus(ctx))
+	}
+	return statuses
+}
+
+// HealthzHandler serves the liveness of the syncer. It fails if the metadata
+// syncer stopped starting full syncs.
+func HealthzHandler(w http.ResponseWriter, r *http.Request) {
+	writeSyncerHealth(logger.NewContextWithLogger(r.Context()), w, getSyncerHealth(syncerClock.Now()))
+}
+
+// ReadyzHandler serves the readiness of the syncer. It fails until the
+// informer caches synced and the initial full sync completed, and while the
+// vCenter sessions aren't authenticated or the supervisor cluster of a guest
+// cluster can't be reached.
+func ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := logger.NewContextWithLogger(r.Context())
+	healthLock.Lock()
+	leader := metadataSyncerStarted
+	healthLock.Unlock()
+	var vcSessions []cnsvsphere.SessionStatus
+	if leader {
+		vcSessions = getVCSessionStatuses(ctx)
+	}
+	writeSyncerHealth(ctx, w, getSyncerReadiness(syncerClock.Now(), vcSessions))
+}
+
+func writeSyncerHealth(ctx context.Context, w http.ResponseWriter, health syncerHealth) {
+	log := logger.GetLogger(ctx)
+	w.Header().Set("Content-Type", "application/json")
+	if !health.Healthy {
+		log.Warnf("Syncer health check failed: %v", health.Failures)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(health); err != nil {
+		log.Errorf("failed to write the health of the syncer. Err: %v", err)
+	}
+}
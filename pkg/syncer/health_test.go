@@ -0,0 +1,107 @@
+package syncer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+)
+
+func resetSyncerHealth() {
+	healthLock.Lock()
+	metadataSyncerStarted = false
+	informersSyncedTime = time.Time{}
+	fullSyncStallTimeout = 0
+	lastFullSyncSuccessTime = time.Time{}
+	supervisorProbed = false
+	supervisorConnectionErr = nil
+	healthLock.Unlock()
+	debugLock.Lock()
+	currentFullSyncProgress = fullSyncProgress{}
+	debugLock.Unlock()
+	initialFullSyncLock.Lock()
+	initialFullSyncCompletionTime = time.Time{}
+	initialFullSyncCompletionRecorded = false
+	initialFullSyncLock.Unlock()
+}
+
+func TestGetSyncerHealth(t *testing.T) {
+	defer resetSyncerHealth()
+	now := time.Now()
+
+	resetSyncerHealth()
+	if health := getSyncerHealth(now); !health.Healthy || health.Leader {
+		t.Errorf("expected a standby instance to be healthy, got %+v", health)
+	}
+
+	recordMetadataSyncerStart()
+	recordInformersSynced(now.Add(-time.Hour), 10*time.Minute)
+	if health := getSyncerHealth(now); health.Healthy {
+		t.Errorf("expected the syncer to be unhealthy without any full sync started, got %+v", health)
+	}
+
+	recordFullSyncStart(now.Add(-25 * time.Minute))
+	if health := getSyncerHealth(now); !health.Healthy || !health.LastFullSyncStartTime.Equal(now.Add(-25*time.Minute)) {
+		t.Errorf("expected the syncer to be healthy with a recent full sync, got %+v", health)
+	}
+	if health := getSyncerHealth(now.Add(10 * time.Minute)); health.Healthy {
+		t.Errorf("expected the syncer to be unhealthy without any full sync started since 35 minutes, got %+v",
+			health)
+	}
+}
+
+func TestGetSyncerReadiness(t *testing.T) {
+	defer resetSyncerHealth()
+	now := time.Now()
+
+	resetSyncerHealth()
+	if health := getSyncerReadiness(now, nil); !health.Healthy {
+		t.Errorf("expected a standby instance to be ready, got %+v", health)
+	}
+
+	recordMetadataSyncerStart()
+	if health := getSyncerReadiness(now, nil); health.Healthy {
+		t.Errorf("expected the syncer not to be ready before the informers synced, got %+v", health)
+	}
+
+	recordInformersSynced(now, 30*time.Minute)
+	recordFullSyncStart(now)
+	if health := getSyncerReadiness(now, nil); health.Healthy {
+		t.Errorf("expected the syncer not to be ready before the initial full sync, got %+v", health)
+	}
+
+	recordFullSyncSuccess(context.Background(), now)
+	vcSessions := []cnsvsphere.SessionStatus{{Host: "vc1", Authenticated: true}}
+	health := getSyncerReadiness(now, vcSessions)
+	if !health.Healthy || !health.LastFullSyncSuccessTime.Equal(now) {
+		t.Errorf("expected the syncer to be ready, got %+v", health)
+	}
+
+	vcSessions = append(vcSessions, cnsvsphere.SessionStatus{Host: "vc2", Error: "not connected"})
+	if health := getSyncerReadiness(now, vcSessions); health.Healthy || len(health.Failures) != 1 {
+		t.Errorf("expected the syncer not to be ready with a disconnected vCenter, got %+v", health)
+	}
+}
+
+func TestHealthzHandler(t *testing.T) {
+	defer resetSyncerHealth()
+	resetSyncerHealth()
+	recordMetadataSyncerStart()
+	recordInformersSynced(time.Now().Add(-time.Hour), time.Minute)
+
+	recorder := httptest.NewRecorder()
+	HealthzHandler(recorder, httptest.NewRequest(http.MethodGet, HealthzPath, nil))
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, recorder.Code)
+	}
+
+	recordFullSyncStart(time.Now())
+	recorder = httptest.NewRecorder()
+	HealthzHandler(recorder, httptest.NewRequest(http.MethodGet, HealthzPath, nil))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, recorder.Code)
+	}
+}
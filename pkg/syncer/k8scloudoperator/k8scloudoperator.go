@@ -148,7 +148,7 @@ func (k8sCloudOperator *k8sCloudOperator) GetPodVMUUIDAnnotation(ctx context.Con
 	})
 	if err != nil {
 		return nil, logger.LogNewErrorf(log,
-			"Unable to find pod %s and annotation %s on namespace %s in timeout: %d. Err: %+v",
+			"Unable to find pod %s and annotation %s on namespace %s in timeout: %d. Err: %w",
 			podName, vmUUIDLabel, podNamespace, timeout, err)
 	}
 	log.Infof("Found the %s: %s annotation on Pod: %s referring to VolumeID: %s running on node: %s",
@@ -217,7 +217,7 @@ func (k8sCloudOperator *k8sCloudOperator) getPod(ctx context.Context, pvcName st
 	})
 
 	if err != nil {
-		return nil, logger.LogNewErrorf(log, "Cannot find pod with namespace: %s running on node: %s with error %+v",
+		return nil, logger.LogNewErrorf(log, "Cannot find pod with namespace: %s running on node: %s with error %w",
 			pvcNamespace, nodeName, err)
 	}
 	log.Debugf("Returned pods: %+v with namespace: %s running on node: %s", spew.Sdump(pods), pvcNamespace, nodeName)
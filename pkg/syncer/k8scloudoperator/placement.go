@@ -278,7 +278,7 @@ func GetSVMotionPlan(ctx context.Context, client kubernetes.Interface,
 
 	spList, err := getStoragePoolList(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get StoragePools list. Error: %v", err)
+		return nil, fmt.Errorf("failed to get StoragePools list. Error: %w", err)
 	}
 	if len(spList.Items) == 0 {
 		return nil, fmt.Errorf("could not find any StoragePool to migrate volumes")
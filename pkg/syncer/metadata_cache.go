@@ -0,0 +1,165 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"sync"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	"github.com/vmware/govmomi/vim25/types"
+
+	volumes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/volume"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/retry"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+)
+
+// volumeMetadataCache holds, per volume, the hashes of the entity metadata
+// last pushed to CNS by the syncer. CNS merges the entity metadata of an
+// update into the existing metadata of the volume, so the hashes are tracked
+// per entity rather than per update.
+type volumeMetadataCache struct {
+	lock sync.Mutex
+	// volumeID -> entity key -> hash of the last pushed entity metadata.
+	hashes map[string]map[string]string
+}
+
+// metadataCache is the cache of the metadata pushed to CNS by the syncer.
+var metadataCache = newVolumeMetadataCache()
+
+func newVolumeMetadataCache() *volumeMetadataCache {
+	return &volumeMetadataCache{hashes: make(map[string]map[string]string)}
+}
+
+// isUnchanged returns true if every entity of the given update spec was
+// already pushed to CNS with the same metadata.
+func (c *volumeMetadataCache) isUnchanged(updateSpec *cnstypes.CnsVolumeMetadataUpdateSpec) bool {
+	entityHashes, ok := getEntityMetadataHashes(updateSpec)
+	if !ok || len(entityHashes) == 0 {
+		return false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	cached := c.hashes[updateSpec.VolumeId.Id]
+	for key, hash := range entityHashes {
+		if cached[key] != hash {
+			return false
+		}
+	}
+	return true
+}
+
+// record stores the hashes of the entities of an update spec that was
+// successfully pushed to CNS.
+func (c *volumeMetadataCache) record(updateSpec *cnstypes.CnsVolumeMetadataUpdateSpec) {
+	entityHashes, ok := getEntityMetadataHashes(updateSpec)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if !ok {
+		// The update can't be tracked, so the cached hashes of the volume
+		// can't be trusted anymore.
+		delete(c.hashes, updateSpec.VolumeId.Id)
+		return
+	}
+	cached, exists := c.hashes[updateSpec.VolumeId.Id]
+	if !exists {
+		cached = make(map[string]string)
+		c.hashes[updateSpec.VolumeId.Id] = cached
+	}
+	for key, hash := range entityHashes {
+		cached[key] = hash
+	}
+}
+
+// forget removes the cached hashes of the given volume.
+func (c *volumeMetadataCache) forget(volumeID string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.hashes, volumeID)
+}
+
+// getEntityMetadataHashes returns the hash of the metadata of each entity of
+// the given update spec, keyed by entity. Returns false if the update spec
+// holds entity metadata which can't be hashed.
+func getEntityMetadataHashes(updateSpec *cnstypes.CnsVolumeMetadataUpdateSpec) (map[string]string, bool) {
+	entityHashes := make(map[string]string)
+	for _, baseEntity := range updateSpec.Metadata.EntityMetadata {
+		entity, ok := baseEntity.(*cnstypes.CnsKubernetesEntityMetadata)
+		if !ok || entity == nil {
+			return nil, false
+		}
+		// Labels are built from maps, so their order isn't stable across
+		// updates.
+		normalized := *entity
+		normalized.Labels = append([]types.KeyValue(nil), entity.Labels...)
+		sort.Slice(normalized.Labels, func(i, j int) bool {
+			return normalized.Labels[i].Key < normalized.Labels[j].Key
+		})
+		normalized.ReferredEntity = append([]cnstypes.CnsKubernetesEntityReference(nil), entity.ReferredEntity...)
+		sort.Slice(normalized.ReferredEntity, func(i, j int) bool {
+			a, b := normalized.ReferredEntity[i], normalized.ReferredEntity[j]
+			if a.EntityType != b.EntityType {
+				return a.EntityType < b.EntityType
+			}
+			if a.Namespace != b.Namespace {
+				return a.Namespace < b.Namespace
+			}
+			return a.EntityName < b.EntityName
+		})
+		data, err := json.Marshal(struct {
+			ContainerCluster      cnstypes.CnsContainerCluster
+			ContainerClusterArray []cnstypes.CnsContainerCluster
+			Entity                cnstypes.CnsKubernetesEntityMetadata
+		}{updateSpec.Metadata.ContainerCluster, updateSpec.Metadata.ContainerClusterArray, normalized})
+		if err != nil {
+			return nil, false
+		}
+		sum := sha256.Sum256(data)
+		key := entity.EntityType + "/" + entity.Namespace + "/" + entity.EntityName
+		entityHashes[key] = hex.EncodeToString(sum[:])
+	}
+	return entityHashes, true
+}
+
+// updateVolumeMetadata pushes the given update spec to CNS, unless the same
+// metadata was already pushed for every entity of the update. The update is
+// retried while CNS is temporarily unavailable.
+func updateVolumeMetadata(ctx context.Context, metadataSyncer *metadataSyncInformer,
+	updateSpec *cnstypes.CnsVolumeMetadataUpdateSpec) error {
+	log := logger.GetLogger(ctx)
+	if metadataCache.isUnchanged(updateSpec) {
+		log.Debugf("Metadata of volume %q is unchanged. Skipping UpdateVolumeMetadata.", updateSpec.VolumeId.Id)
+		return nil
+	}
+	err := retry.Do(ctx, "UpdateVolumeMetadata", retry.SyncerCnsCall, func() error {
+		err := metadataSyncer.volumeManager.UpdateVolumeMetadata(ctx, updateSpec)
+		if err != nil && !volumes.IsRetryableError("", err) {
+			return retry.Permanent(err)
+		}
+		return err
+	})
+	if err != nil {
+		metadataCache.forget(updateSpec.VolumeId.Id)
+		return err
+	}
+	metadataCache.record(updateSpec)
+	return nil
+}
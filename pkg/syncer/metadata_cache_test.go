@@ -0,0 +1,72 @@
+package syncer
+
+import (
+	"testing"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+)
+
+func newTestUpdateSpec(volumeID string, labels map[string]string,
+	entities ...*cnstypes.CnsKubernetesEntityMetadata) *cnstypes.CnsVolumeMetadataUpdateSpec {
+	containerCluster := cnsvsphere.GetContainerCluster("cluster", "user", cnstypes.CnsClusterFlavorVanilla, "")
+	var metadataList []cnstypes.BaseCnsEntityMetadata
+	metadataList = append(metadataList, cnsvsphere.GetCnsKubernetesEntityMetaData("pv-1", labels, false,
+		string(cnstypes.CnsKubernetesEntityTypePV), "", "cluster", nil))
+	for _, entity := range entities {
+		metadataList = append(metadataList, entity)
+	}
+	return &cnstypes.CnsVolumeMetadataUpdateSpec{
+		VolumeId: cnstypes.CnsVolumeId{Id: volumeID},
+		Metadata: cnstypes.CnsVolumeMetadata{
+			ContainerCluster:      containerCluster,
+			ContainerClusterArray: []cnstypes.CnsContainerCluster{containerCluster},
+			EntityMetadata:        metadataList,
+		},
+	}
+}
+
+func TestVolumeMetadataCache(t *testing.T) {
+	cache := newVolumeMetadataCache()
+	labels := map[string]string{"a": "1", "b": "2", "c": "3", "d": "4"}
+	spec := newTestUpdateSpec("vol-1", labels)
+	if cache.isUnchanged(spec) {
+		t.Fatal("expected metadata not yet pushed to be reported as changed")
+	}
+	cache.record(spec)
+	// Labels built from the same map must hash the same regardless of order.
+	for i := 0; i < 10; i++ {
+		if !cache.isUnchanged(newTestUpdateSpec("vol-1", labels)) {
+			t.Fatal("expected identical metadata to be reported as unchanged")
+		}
+	}
+	if cache.isUnchanged(newTestUpdateSpec("vol-2", labels)) {
+		t.Error("expected metadata of another volume to be reported as changed")
+	}
+	if cache.isUnchanged(newTestUpdateSpec("vol-1", map[string]string{"a": "2"})) {
+		t.Error("expected updated labels to be reported as changed")
+	}
+
+	// An update of a new entity is changed, even though the PV entity isn't.
+	pvc := cnsvsphere.GetCnsKubernetesEntityMetaData("pvc-1", nil, false,
+		string(cnstypes.CnsKubernetesEntityTypePVC), "ns", "cluster", nil)
+	withPVC := newTestUpdateSpec("vol-1", labels, pvc)
+	if cache.isUnchanged(withPVC) {
+		t.Error("expected metadata with a new entity to be reported as changed")
+	}
+	cache.record(withPVC)
+	if !cache.isUnchanged(spec) || !cache.isUnchanged(withPVC) {
+		t.Error("expected recorded metadata to be reported as unchanged")
+	}
+	deletedPVC := cnsvsphere.GetCnsKubernetesEntityMetaData("pvc-1", nil, true,
+		string(cnstypes.CnsKubernetesEntityTypePVC), "ns", "cluster", nil)
+	if cache.isUnchanged(newTestUpdateSpec("vol-1", labels, deletedPVC)) {
+		t.Error("expected deleted entity to be reported as changed")
+	}
+
+	cache.forget("vol-1")
+	if cache.isUnchanged(spec) {
+		t.Error("expected metadata of a forgotten volume to be reported as changed")
+	}
+}
@@ -18,6 +18,7 @@ package syncer
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -32,7 +33,6 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8stypes "k8s.io/apimachinery/pkg/types"
-	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/informers"
 	clientset "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/util/workqueue"
@@ -41,9 +41,11 @@ import (
 
 	cnsoperatorv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/migration"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/node"
 	volumes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/volume"
 	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
 	cnsconfig "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/retry"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/utils"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common/commonco"
@@ -102,6 +104,27 @@ func getFullSyncIntervalInMin(ctx context.Context) int {
 	return fullSyncIntervalInMin
 }
 
+// isFullSyncDryRun returns true if environment variable
+// X_CSI_FULL_SYNC_DRY_RUN is set to true, in which case full sync only logs
+// the operations it would execute.
+func isFullSyncDryRun(ctx context.Context) bool {
+	log := logger.GetLogger(ctx)
+	v := os.Getenv(csitypes.EnvVarFullSyncDryRun)
+	if v == "" {
+		return false
+	}
+	dryRun, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Warnf("FullSync: dry run mode set in env variable %s %s is invalid, dry run mode is disabled",
+			csitypes.EnvVarFullSyncDryRun, v)
+		return false
+	}
+	if dryRun {
+		log.Infof("FullSync: dry run mode is enabled, volume operations are only logged")
+	}
+	return dryRun
+}
+
 // getVolumeHealthIntervalInMin returns the VolumeHealthInterval.
 // If environment variable VOLUME_HEALTH_STATUS_INTERVAL_MINUTES is set and valid,
 // return the interval value read from environment variable.
@@ -149,6 +172,174 @@ func getPVtoBackingDiskObjectIdIntervalInMin(ctx context.Context) int {
 	return pvtoBackingDiskObjectIdIntervalInMin
 }
 
+// getFailedVolumeRecoveryIntervalInMin returns the failed volume recovery
+// interval.
+func getFailedVolumeRecoveryIntervalInMin(ctx context.Context) int {
+	log := logger.GetLogger(ctx)
+	failedVolumeRecoveryIntervalInMin := defaultFailedVolumeRecoveryIntervalInMin
+	if v := os.Getenv("FAILED_VOLUME_RECOVERY_INTERVAL_MINUTES"); v != "" {
+		if value, err := strconv.Atoi(v); err == nil {
+			if value <= 0 {
+				log.Warnf("FailedVolumeRecovery: FailedVolumeRecovery interval set in env variable "+
+					"FAILED_VOLUME_RECOVERY_INTERVAL_MINUTES %s is equal or less than 0, will use the "+
+					"default interval", v)
+			} else {
+				failedVolumeRecoveryIntervalInMin = value
+				log.Infof("FailedVolumeRecovery: FailedVolumeRecovery interval is set to %d minutes",
+					failedVolumeRecoveryIntervalInMin)
+			}
+		} else {
+			log.Warnf("FailedVolumeRecovery: FailedVolumeRecovery interval set in env variable "+
+				"FAILED_VOLUME_RECOVERY_INTERVAL_MINUTES %s is invalid, will use the default interval", v)
+		}
+	}
+	return failedVolumeRecoveryIntervalInMin
+}
+
+// getStoragePolicyComplianceIntervalInMin returns the interval of the storage
+// policy compliance check.
+func getStoragePolicyComplianceIntervalInMin(ctx context.Context) int {
+	log := logger.GetLogger(ctx)
+	storagePolicyComplianceIntervalInMin := defaultStoragePolicyComplianceIntervalInMin
+	if v := os.Getenv("STORAGE_POLICY_COMPLIANCE_INTERVAL_MINUTES"); v != "" {
+		if value, err := strconv.Atoi(v); err == nil {
+			if value <= 0 {
+				log.Warnf("StoragePolicyCompliance: StoragePolicyCompliance interval set in env variable "+
+					"STORAGE_POLICY_COMPLIANCE_INTERVAL_MINUTES %s is equal or less than 0, will use the "+
+					"default interval", v)
+			} else {
+				storagePolicyComplianceIntervalInMin = value
+				log.Infof("StoragePolicyCompliance: StoragePolicyCompliance interval is set to %d minutes",
+					storagePolicyComplianceIntervalInMin)
+			}
+		} else {
+			log.Warnf("StoragePolicyCompliance: StoragePolicyCompliance interval set in env variable "+
+				"STORAGE_POLICY_COMPLIANCE_INTERVAL_MINUTES %s is invalid, will use the default interval", v)
+		}
+	}
+	return storagePolicyComplianceIntervalInMin
+}
+
+// getStaleReleasedVolumeIntervalInMin returns the interval of the check of
+// PVs staying in Released phase.
+func getStaleReleasedVolumeIntervalInMin(ctx context.Context) int {
+	log := logger.GetLogger(ctx)
+	staleReleasedVolumeIntervalInMin := defaultStaleReleasedVolumeIntervalInMin
+	if v := os.Getenv("STALE_RELEASED_VOLUME_INTERVAL_MINUTES"); v != "" {
+		if value, err := strconv.Atoi(v); err == nil {
+			if value <= 0 {
+				log.Warnf("StaleReleasedVolume: StaleReleasedVolume interval set in env variable "+
+					"STALE_RELEASED_VOLUME_INTERVAL_MINUTES %s is equal or less than 0, will use the "+
+					"default interval", v)
+			} else {
+				staleReleasedVolumeIntervalInMin = value
+				log.Infof("StaleReleasedVolume: StaleReleasedVolume interval is set to %d minutes",
+					staleReleasedVolumeIntervalInMin)
+			}
+		} else {
+			log.Warnf("StaleReleasedVolume: StaleReleasedVolume interval set in env variable "+
+				"STALE_RELEASED_VOLUME_INTERVAL_MINUTES %s is invalid, will use the default interval", v)
+		}
+	}
+	return staleReleasedVolumeIntervalInMin
+}
+
+// getPodVolumeUsageIntervalInMin returns the interval of the refresh of the
+// storage usage annotations of pods.
+func getPodVolumeUsageIntervalInMin(ctx context.Context) int {
+	log := logger.GetLogger(ctx)
+	podVolumeUsageIntervalInMin := defaultPodVolumeUsageIntervalInMin
+	if v := os.Getenv("POD_VOLUME_USAGE_INTERVAL_MINUTES"); v != "" {
+		if value, err := strconv.Atoi(v); err == nil {
+			if value <= 0 {
+				log.Warnf("PodVolumeUsage: PodVolumeUsage interval set in env variable "+
+					"POD_VOLUME_USAGE_INTERVAL_MINUTES %s is equal or less than 0, will use the "+
+					"default interval", v)
+			} else {
+				podVolumeUsageIntervalInMin = value
+				log.Infof("PodVolumeUsage: PodVolumeUsage interval is set to %d minutes",
+					podVolumeUsageIntervalInMin)
+			}
+		} else {
+			log.Warnf("PodVolumeUsage: PodVolumeUsage interval set in env variable "+
+				"POD_VOLUME_USAGE_INTERVAL_MINUTES %s is invalid, will use the default interval", v)
+		}
+	}
+	return podVolumeUsageIntervalInMin
+}
+
+// getFileAccessConfigIntervalInMin returns the interval of the reconciliation
+// of the CnsFileAccessConfig instances of guest clusters.
+func getFileAccessConfigIntervalInMin(ctx context.Context) int {
+	log := logger.GetLogger(ctx)
+	fileAccessConfigIntervalInMin := defaultFileAccessConfigIntervalInMin
+	if v := os.Getenv("FILE_ACCESS_CONFIG_INTERVAL_MINUTES"); v != "" {
+		if value, err := strconv.Atoi(v); err == nil {
+			if value <= 0 {
+				log.Warnf("FileAccessConfig: FileAccessConfig interval set in env variable "+
+					"FILE_ACCESS_CONFIG_INTERVAL_MINUTES %s is equal or less than 0, will use the "+
+					"default interval", v)
+			} else {
+				fileAccessConfigIntervalInMin = value
+				log.Infof("FileAccessConfig: FileAccessConfig interval is set to %d minutes",
+					fileAccessConfigIntervalInMin)
+			}
+		} else {
+			log.Warnf("FileAccessConfig: FileAccessConfig interval set in env variable "+
+				"FILE_ACCESS_CONFIG_INTERVAL_MINUTES %s is invalid, will use the default interval", v)
+		}
+	}
+	return fileAccessConfigIntervalInMin
+}
+
+// getDatastoreCapacityMetricsIntervalInMin returns the interval of the
+// refresh of the datastore capacity metrics.
+func getDatastoreCapacityMetricsIntervalInMin(ctx context.Context) int {
+	log := logger.GetLogger(ctx)
+	datastoreCapacityMetricsIntervalInMin := defaultDatastoreCapacityMetricsIntervalInMin
+	if v := os.Getenv("DATASTORE_CAPACITY_METRICS_INTERVAL_MINUTES"); v != "" {
+		if value, err := strconv.Atoi(v); err == nil {
+			if value <= 0 {
+				log.Warnf("DatastoreCapacityMetrics: DatastoreCapacityMetrics interval set in env variable "+
+					"DATASTORE_CAPACITY_METRICS_INTERVAL_MINUTES %s is equal or less than 0, will use the "+
+					"default interval", v)
+			} else {
+				datastoreCapacityMetricsIntervalInMin = value
+				log.Infof("DatastoreCapacityMetrics: DatastoreCapacityMetrics interval is set to %d minutes",
+					datastoreCapacityMetricsIntervalInMin)
+			}
+		} else {
+			log.Warnf("DatastoreCapacityMetrics: DatastoreCapacityMetrics interval set in env variable "+
+				"DATASTORE_CAPACITY_METRICS_INTERVAL_MINUTES %s is invalid, will use the default interval", v)
+		}
+	}
+	return datastoreCapacityMetricsIntervalInMin
+}
+
+// getStoragePolicyDeletionIntervalInMin returns the interval of the check of
+// the storage policies of the StorageClasses.
+func getStoragePolicyDeletionIntervalInMin(ctx context.Context) int {
+	log := logger.GetLogger(ctx)
+	storagePolicyDeletionIntervalInMin := defaultStoragePolicyDeletionIntervalInMin
+	if v := os.Getenv("STORAGE_POLICY_DELETION_INTERVAL_MINUTES"); v != "" {
+		if value, err := strconv.Atoi(v); err == nil {
+			if value <= 0 {
+				log.Warnf("StoragePolicyDeletion: StoragePolicyDeletion interval set in env variable "+
+					"STORAGE_POLICY_DELETION_INTERVAL_MINUTES %s is equal or less than 0, will use the "+
+					"default interval", v)
+			} else {
+				storagePolicyDeletionIntervalInMin = value
+				log.Infof("StoragePolicyDeletion: StoragePolicyDeletion interval is set to %d minutes",
+					storagePolicyDeletionIntervalInMin)
+			}
+		} else {
+			log.Warnf("StoragePolicyDeletion: StoragePolicyDeletion interval set in env variable "+
+				"STORAGE_POLICY_DELETION_INTERVAL_MINUTES %s is invalid, will use the default interval", v)
+		}
+	}
+	return storagePolicyDeletionIntervalInMin
+}
+
 // InitMetadataSyncer initializes the Metadata Sync Informer.
 func InitMetadataSyncer(ctx context.Context, clusterFlavor cnstypes.CnsClusterFlavor,
 	configInfo *cnsconfig.ConfigurationInfo) error {
@@ -157,6 +348,7 @@ func InitMetadataSyncer(ctx context.Context, clusterFlavor cnstypes.CnsClusterFl
 	log.Infof("Initializing MetadataSyncer")
 	metadataSyncer := newInformer()
 	MetadataSyncer = metadataSyncer
+	recordMetadataSyncerStart()
 
 	// Create the kubernetes client from config.
 	k8sClient, err := k8s.NewClient(ctx)
@@ -173,6 +365,7 @@ func InitMetadataSyncer(ctx context.Context, clusterFlavor cnstypes.CnsClusterFl
 		return err
 	}
 	metadataSyncer.clusterFlavor = clusterFlavor
+	metadataSyncer.namespaceScope = getSyncNamespaceScope(ctx)
 
 	if metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorWorkload {
 		if commonco.ContainerOrchestratorUtility.IsFSSEnabled(ctx, common.TKGsHA) {
@@ -197,21 +390,13 @@ func InitMetadataSyncer(ctx context.Context, clusterFlavor cnstypes.CnsClusterFl
 	if metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorGuest {
 		// Initialize client to supervisor cluster, if metadata syncer is being
 		// initialized for guest clusters.
-		restClientConfig := k8s.GetRestClientConfigForSupervisor(ctx,
+		cnsOperatorClient, supervisorClient, err := newSupervisorClients(ctx,
 			metadataSyncer.configInfo.Cfg.GC.Endpoint, metadataSyncer.configInfo.Cfg.GC.Port)
-		metadataSyncer.cnsOperatorClient, err = k8s.NewClientForGroup(ctx,
-			restClientConfig, cnsoperatorv1alpha1.GroupName)
-		if err != nil {
-			log.Errorf("Creating Cns Operator client failed. Err: %v", err)
-			return err
-		}
-
-		// Initialize supervisor cluser client.
-		metadataSyncer.supervisorClient, err = k8s.NewSupervisorClient(ctx, restClientConfig)
 		if err != nil {
-			log.Errorf("Failed to create supervisorClient. Error: %+v", err)
+			log.Errorf("Creating supervisor clients failed. Err: %v", err)
 			return err
 		}
+		metadataSyncer.setSupervisorClients(cnsOperatorClient, supervisorClient)
 	} else {
 		// Initialize volume manager with vcenter credentials, if metadata syncer
 		// is being intialized for Vanilla or Supervisor clusters.
@@ -269,7 +454,7 @@ func InitMetadataSyncer(ctx context.Context, clusterFlavor cnstypes.CnsClusterFl
 							break
 						}
 						log.Errorf("failed to reload configuration will retry again in 5 seconds. err: %+v", reloadConfigErr)
-						time.Sleep(5 * time.Second)
+						syncerClock.Sleep(5 * time.Second)
 					}
 				}
 				// Handling create event for reconnecting to VC when ca file is
@@ -290,7 +475,7 @@ func InitMetadataSyncer(ctx context.Context, clusterFlavor cnstypes.CnsClusterFl
 						}
 						log.Errorf("failed to re-establish VC connection. Will retry again in 60 seconds. err: %+v",
 							reconnectVCErr)
-						time.Sleep(60 * time.Second)
+						syncerClock.Sleep(60 * time.Second)
 					}
 				}
 			case err, ok := <-watcher.Errors:
@@ -330,41 +515,86 @@ func InitMetadataSyncer(ctx context.Context, clusterFlavor cnstypes.CnsClusterFl
 
 	// Set up kubernetes resource listeners for metadata syncer.
 	metadataSyncer.k8sInformerManager = k8s.NewInformer(k8sClient)
-	metadataSyncer.k8sInformerManager.AddPVCListener(
-		nil, // Add.
-		func(oldObj interface{}, newObj interface{}) { // Update.
-			pvcUpdated(oldObj, newObj, metadataSyncer)
-		},
-		func(obj interface{}) { // Delete.
-			pvcDeleted(obj, metadataSyncer)
+	// The PV, PVC and Pod changes are published on resourceEventBus, which
+	// other syncer components subscribe to as well.
+	resourceEventBus.watchInformers(metadataSyncer.k8sInformerManager)
+	resourceEventBus.subscribe("metadata-syncer-pvc", []resourceKind{pvcResource}, false,
+		func(event resourceEvent) {
+			switch event.eventType {
+			case resourceUpdated:
+				pvcUpdated(event.oldObj, event.newObj, metadataSyncer)
+			case resourceDeleted:
+				pvcDeleted(event.oldObj, metadataSyncer)
+			}
 		})
-	metadataSyncer.k8sInformerManager.AddPVListener(
-		nil, // Add.
-		func(oldObj interface{}, newObj interface{}) { // Update.
-			pvUpdated(oldObj, newObj, metadataSyncer)
-		},
-		func(obj interface{}) { // Delete.
-			pvDeleted(obj, metadataSyncer)
+	resourceEventBus.subscribe("metadata-syncer-pv", []resourceKind{pvResource}, false,
+		func(event resourceEvent) {
+			switch event.eventType {
+			case resourceUpdated:
+				pvUpdated(event.oldObj, event.newObj, metadataSyncer)
+			case resourceDeleted:
+				pvDeleted(event.oldObj, metadataSyncer)
+			}
 		})
-	metadataSyncer.k8sInformerManager.AddPodListener(
-		nil, // Add.
-		func(oldObj interface{}, newObj interface{}) { // Update.
-			podUpdated(oldObj, newObj, metadataSyncer)
-		},
-		func(obj interface{}) { // Delete.
-			podDeleted(obj, metadataSyncer)
+	resourceEventBus.subscribe("metadata-syncer-pod", []resourceKind{podResource}, false,
+		func(event resourceEvent) {
+			switch event.eventType {
+			case resourceUpdated:
+				podUpdated(event.oldObj, event.newObj, metadataSyncer)
+			case resourceDeleted:
+				podDeleted(event.oldObj, metadataSyncer)
+			}
 		})
 	metadataSyncer.pvLister = metadataSyncer.k8sInformerManager.GetPVLister()
 	metadataSyncer.pvcLister = metadataSyncer.k8sInformerManager.GetPVCLister()
 	metadataSyncer.podLister = metadataSyncer.k8sInformerManager.GetPodLister()
+	if metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorVanilla {
+		startVolumeHandleRepair(k8sClient, metadataSyncer)
+		if metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.CSIMigration) {
+			startMigratedVolumeTopologySync(k8sClient)
+		}
+		if metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.VolumePolicyUpdate) {
+			startStoragePolicyUpdate(k8sClient, metadataSyncer)
+		}
+		if metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.VolumeEncryption) {
+			startVolumeEncryptionAnnotation(k8sClient, metadataSyncer)
+		}
+	}
 	stopCh := metadataSyncer.k8sInformerManager.Listen()
 	if stopCh == nil {
 		return logger.LogNewError(log, "Failed to sync informer caches")
 	}
+	fullSyncInterval := time.Duration(getFullSyncIntervalInMin(ctx)) * time.Minute
+	recordInformersSynced(syncerClock.Now(), fullSyncInterval)
 	log.Infof("Initialized metadata syncer")
+	if metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorVanilla &&
+		metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.StartupConsistencyAudit) {
+		go runStartupConsistencyAudit(ctx, k8sClient, metadataSyncer)
+	}
+
+	// Initialize the node manager used to look up the node VMs.
+	var nodeMgr *node.Nodes
+	if metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorVanilla &&
+		(metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.NodeDrainDetach) ||
+			metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.TopologyFullSyncValidation) ||
+			metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.StorageCapacityTracking) ||
+			metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.NodeRegistrySync) ||
+			metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.NodeFailureDetach) ||
+			metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.VMMigrationEventWatcher)) {
+		nodeMgr = &node.Nodes{}
+		err = nodeMgr.Initialize(ctx, metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.UseCSINodeId))
+		if err != nil {
+			log.Errorf("failed to initialize nodeManager. Error: %+v", err)
+			return err
+		}
+	}
+
+	// Validate the node affinity of the PVs during full sync.
+	if metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorVanilla &&
+		metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.TopologyFullSyncValidation) {
+		volumeTopologyChecker = newVolumeTopologyValidator(k8sClient, nodeMgr)
+	}
 
-	fullSyncTicker := time.NewTicker(time.Duration(getFullSyncIntervalInMin(ctx)) * time.Minute)
-	defer fullSyncTicker.Stop()
 	// Trigger full sync.
 	// If TriggerCsiFullSync feature gate is enabled, use TriggerCsiFullSync to
 	// trigger full sync. If not, directly invoke full sync methods.
@@ -383,61 +613,57 @@ func InitMetadataSyncer(ctx context.Context, clusterFlavor cnstypes.CnsClusterFl
 			log.Errorf("Failed to create CnsOperator client. Err: %+v", err)
 			return err
 		}
-		go func() {
-			for ; true; <-fullSyncTicker.C {
-				ctx, log = logger.GetNewContextWithLogger()
-				log.Infof("periodic fullSync is triggered")
-				triggerCsiFullSyncInstance, err := getTriggerCsiFullSyncInstance(ctx, cnsOperatorClient)
-				if err != nil {
-					log.Warnf("Unable to get the trigger full sync instance. Err: %+v", err)
-					continue
-				}
+		triggerCsiFullSyncClient = cnsOperatorClient
+		go runPeriodically(syncerClock, fullSyncInterval, stopCh, func() {
+			ctx, log := logger.GetNewContextWithLogger()
+			log.Infof("periodic fullSync is triggered")
+			triggerCsiFullSyncInstance, err := getTriggerCsiFullSyncInstance(ctx, cnsOperatorClient)
+			if err != nil {
+				log.Warnf("Unable to get the trigger full sync instance. Err: %+v", err)
+				return
+			}
 
-				// Update TriggerCsiFullSync instance if full sync is not already in progress
-				if triggerCsiFullSyncInstance.Status.InProgress {
-					log.Infof("There is a full sync already in progress. Ignoring this current cycle of periodic full sync")
+			// Update TriggerCsiFullSync instance if full sync is not already in progress
+			if triggerCsiFullSyncInstance.Status.InProgress {
+				log.Infof("There is a full sync already in progress. Ignoring this current cycle of periodic full sync")
+			} else {
+				triggerCsiFullSyncInstance.Spec.TriggerSyncID = triggerCsiFullSyncInstance.Spec.TriggerSyncID + 1
+				err = updateTriggerCsiFullSyncInstance(ctx, cnsOperatorClient, triggerCsiFullSyncInstance)
+				if err != nil {
+					log.Errorf("Failed to update TriggerCsiFullSync instance: %+v to increment the TriggerFullSyncId. "+
+						"Error: %v", triggerCsiFullSyncInstance, err)
 				} else {
-					triggerCsiFullSyncInstance.Spec.TriggerSyncID = triggerCsiFullSyncInstance.Spec.TriggerSyncID + 1
-					err = updateTriggerCsiFullSyncInstance(ctx, cnsOperatorClient, triggerCsiFullSyncInstance)
-					if err != nil {
-						log.Errorf("Failed to update TriggerCsiFullSync instance: %+v to increment the TriggerFullSyncId. "+
-							"Error: %v", triggerCsiFullSyncInstance, err)
-					} else {
-						log.Infof("Incremented TriggerSyncID from %d to %d as part of periodic run to trigger full sync",
-							triggerCsiFullSyncInstance.Spec.TriggerSyncID-1, triggerCsiFullSyncInstance.Spec.TriggerSyncID)
-					}
+					log.Infof("Incremented TriggerSyncID from %d to %d as part of periodic run to trigger full sync",
+						triggerCsiFullSyncInstance.Spec.TriggerSyncID-1, triggerCsiFullSyncInstance.Spec.TriggerSyncID)
 				}
 			}
-		}()
+		})
 	} else {
 		log.Infof("%q feature flag is not enabled. Using the traditional way to directly invoke full sync",
 			common.TriggerCsiFullSync)
 
-		go func() {
-			for ; true; <-fullSyncTicker.C {
-				log.Infof("fullSync is triggered")
-				if metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorGuest {
-					err := PvcsiFullSync(ctx, metadataSyncer)
-					if err != nil {
-						log.Infof("pvCSI full sync failed with error: %+v", err)
-					}
-				} else {
-					err := CsiFullSync(ctx, metadataSyncer)
-					if err != nil {
-						log.Infof("CSI full sync failed with error: %+v", err)
-					}
+		go runPeriodically(syncerClock, fullSyncInterval, stopCh, func() {
+			ctx, log := logger.GetNewContextWithLogger()
+			log.Infof("fullSync is triggered")
+			if metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorGuest {
+				err := PvcsiFullSync(ctx, metadataSyncer)
+				if err != nil {
+					log.Infof("pvCSI full sync failed with error: %+v", err)
+				}
+			} else {
+				err := CsiFullSync(ctx, metadataSyncer)
+				if err != nil {
+					log.Infof("CSI full sync failed with error: %+v", err)
 				}
 			}
-		}()
+		})
 	}
 
 	// Trigger get pv to backingDiskObjectId mapping on vanilla cluster
 	pvToBackingDiskObjectIdFSSEnabled := metadataSyncer.coCommonInterface.IsFSSEnabled(ctx,
 		common.PVtoBackingDiskObjectIdMapping)
 	if metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorVanilla && pvToBackingDiskObjectIdFSSEnabled {
-		pvToBackingDiskObjectIdMappingTicker := time.NewTicker(time.Duration(
-			getPVtoBackingDiskObjectIdIntervalInMin(ctx)) * time.Minute)
-		defer pvToBackingDiskObjectIdMappingTicker.Stop()
+		pvToBackingDiskObjectIdMappingInterval := time.Duration(getPVtoBackingDiskObjectIdIntervalInMin(ctx)) * time.Minute
 
 		var pvToBackingDiskObjectIdSupportCheck bool
 		vCenter, err := cnsvsphere.GetVirtualCenterInstance(ctx, configInfo, false)
@@ -447,74 +673,257 @@ func InitMetadataSyncer(ctx context.Context, clusterFlavor cnstypes.CnsClusterFl
 		pvToBackingDiskObjectIdSupportCheck = common.CheckPVtoBackingDiskObjectIdSupport(ctx, vCenter)
 
 		if pvToBackingDiskObjectIdSupportCheck {
-			go func() {
-				for ; true; <-pvToBackingDiskObjectIdMappingTicker.C {
-					ctx, log = logger.GetNewContextWithLogger()
-					log.Info("get pv to backingDiskObjectId mapping is triggered")
-					csiGetPVtoBackingDiskObjectIdMapping(ctx, k8sClient, metadataSyncer)
-				}
-			}()
+			go runPeriodically(syncerClock, pvToBackingDiskObjectIdMappingInterval, stopCh, func() {
+				ctx, log := logger.GetNewContextWithLogger()
+				log.Info("get pv to backingDiskObjectId mapping is triggered")
+				csiGetPVtoBackingDiskObjectIdMapping(ctx, k8sClient, metadataSyncer)
+			})
+		}
+	}
+
+	// Trigger recovery of PVs marked Failed during a temporary VC outage.
+	if metadataSyncer.clusterFlavor != cnstypes.CnsClusterFlavorGuest &&
+		metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.FailedVolumeRecovery) {
+		failedVolumeRecoveryInterval := time.Duration(getFailedVolumeRecoveryIntervalInMin(ctx)) * time.Minute
+		go runPeriodically(syncerClock, failedVolumeRecoveryInterval, stopCh, func() {
+			ctx, log := logger.GetNewContextWithLogger()
+			log.Debug("failed volume recovery is triggered")
+			csiRecoverFailedVolumes(ctx, k8sClient, metadataSyncer)
+		})
+	}
+
+	// Trigger the storage policy compliance check of volumes.
+	if metadataSyncer.clusterFlavor != cnstypes.CnsClusterFlavorGuest &&
+		metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.StoragePolicyCompliance) {
+		storagePolicyComplianceInterval := time.Duration(getStoragePolicyComplianceIntervalInMin(ctx)) * time.Minute
+		complianceChecker := newStoragePolicyComplianceChecker(k8sClient)
+		go runPeriodically(syncerClock, storagePolicyComplianceInterval, stopCh, func() {
+			ctx, log := logger.GetNewContextWithLogger()
+			log.Debug("storage policy compliance check is triggered")
+			complianceChecker.check(ctx, metadataSyncer)
+		})
+	}
+
+	// Trigger the check of PVs staying in Released phase for too long.
+	if metadataSyncer.clusterFlavor != cnstypes.CnsClusterFlavorGuest &&
+		metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.StaleReleasedVolumeCleaner) {
+		staleReleasedVolumeInterval := time.Duration(getStaleReleasedVolumeIntervalInMin(ctx)) * time.Minute
+		staleReleasedVolumeCleaner := newStaleReleasedVolumeCleaner(ctx, k8sClient)
+		go runPeriodically(syncerClock, staleReleasedVolumeInterval, stopCh, func() {
+			ctx, log := logger.GetNewContextWithLogger()
+			log.Debug("stale released volume check is triggered")
+			staleReleasedVolumeCleaner.clean(ctx, metadataSyncer)
+		})
+	}
+
+	// Trigger the refresh of the storage usage annotations of pods.
+	if metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorVanilla &&
+		metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.PodVolumeUsageAnnotations) {
+		podVolumeUsageInterval := time.Duration(getPodVolumeUsageIntervalInMin(ctx)) * time.Minute
+		podVolumeUsageAnnotator := newPodVolumeUsageAnnotator(k8sClient)
+		go runPeriodically(syncerClock, podVolumeUsageInterval, stopCh, func() {
+			ctx, log := logger.GetNewContextWithLogger()
+			log.Debug("pod volume usage refresh is triggered")
+			podVolumeUsageAnnotator.annotate(ctx, metadataSyncer)
+		})
+	}
+
+	// Trigger the reconciliation of the CnsFileAccessConfig instances of the
+	// guest cluster.
+	if metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorGuest &&
+		metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.FileAccessConfigAutomation) {
+		supervisorNamespace, err := cnsconfig.GetSupervisorNamespace(ctx)
+		if err != nil {
+			log.Errorf("could not get supervisor namespace in which guest cluster was deployed. Err: %v", err)
+			return err
 		}
+		fileAccessConfigInterval := time.Duration(getFileAccessConfigIntervalInMin(ctx)) * time.Minute
+		fileAccessConfigReconciler := newFileAccessConfigReconciler(k8sClient, supervisorNamespace)
+		go runPeriodically(syncerClock, fileAccessConfigInterval, stopCh, func() {
+			ctx, log := logger.GetNewContextWithLogger()
+			log.Debug("file access config reconciliation is triggered")
+			fileAccessConfigReconciler.reconcile(ctx, metadataSyncer)
+		})
 	}
 
-	volumeHealthTicker := time.NewTicker(time.Duration(getVolumeHealthIntervalInMin(ctx)) * time.Minute)
-	defer volumeHealthTicker.Stop()
+	// Trigger the refresh of the datastore capacity metrics.
+	if metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorVanilla &&
+		metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.DatastoreCapacityMetrics) {
+		datastoreCapacityInterval := time.Duration(getDatastoreCapacityMetricsIntervalInMin(ctx)) * time.Minute
+		go runPeriodically(syncerClock, datastoreCapacityInterval, stopCh, func() {
+			ctx, log := logger.GetNewContextWithLogger()
+			log.Debug("datastore capacity metrics refresh is triggered")
+			csiReportDatastoreCapacity(ctx, metadataSyncer)
+		})
+	}
+
+	// Trigger the check of the storage policies of the StorageClasses.
+	if metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorVanilla &&
+		metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.StoragePolicyDeletionCheck) {
+		storagePolicyDeletionInterval := time.Duration(getStoragePolicyDeletionIntervalInMin(ctx)) * time.Minute
+		storagePolicyDeletionChecker := newStoragePolicyDeletionChecker(k8sClient)
+		go runPeriodically(syncerClock, storagePolicyDeletionInterval, stopCh, func() {
+			ctx, log := logger.GetNewContextWithLogger()
+			log.Debug("storage policy deletion check is triggered")
+			storagePolicyDeletionChecker.check(ctx, metadataSyncer)
+		})
+	}
+
+	// Start detaching the volumes of the pods evicted from cordoned nodes.
+	if metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorVanilla &&
+		metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.NodeDrainDetach) {
+		startNodeDrainDetach(ctx, k8sClient, nodeMgr, metadataSyncer)
+	}
+
+	// Start detaching the volumes of the NotReady nodes whose VM is powered
+	// off.
+	if metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorVanilla &&
+		metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.NodeFailureDetach) {
+		startNodeFailureDetach(ctx, k8sClient, nodeMgr, metadataSyncer)
+	}
+
+	// Start watching the VM migration events of vCenter.
+	if metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorVanilla &&
+		metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.VMMigrationEventWatcher) {
+		if err := startVMMigrationWatcher(ctx, k8sClient, nodeMgr, metadataSyncer); err != nil {
+			log.Errorf("failed to start the VM migration watcher. Error: %+v", err)
+			return err
+		}
+	}
+
+	// Trigger the version skew check between the controller and the node
+	// plugins.
+	if metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorVanilla &&
+		metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.VersionSkewCheck) {
+		apiextensionsClient, err := k8s.NewAPIExtensionsClient(ctx)
+		if err != nil {
+			log.Errorf("Creating apiextensions client failed. Err: %v", err)
+			return err
+		}
+		versionSkewChecker := newVersionSkewChecker(k8sClient, apiextensionsClient)
+		go runPeriodically(syncerClock, time.Duration(defaultVersionSkewCheckIntervalInMin)*time.Minute, stopCh,
+			func() {
+				ctx, log := logger.GetNewContextWithLogger()
+				log.Debug("version skew check is triggered")
+				versionSkewChecker.check(ctx, Version)
+			})
+	}
+
+	// Trigger the publication of the CSIStorageCapacity objects.
+	var capacityPublisher *storageCapacityPublisher
+	if metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorVanilla &&
+		metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.StorageCapacityTracking) {
+		capacityPublisher = newStorageCapacityPublisher(k8sClient, nodeMgr)
+		go runPeriodically(syncerClock, time.Duration(defaultStorageCapacityIntervalInMin)*time.Minute, stopCh,
+			func() {
+				ctx, log := logger.GetNewContextWithLogger()
+				log.Debug("storage capacity publication is triggered")
+				capacityPublisher.publish(ctx, metadataSyncer)
+			})
+	}
+
+	// Keep the node registry current with the Nodes.
+	if metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorVanilla &&
+		metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.NodeRegistrySync) {
+		nodeRegistry, err := startNodeRegistrySync(ctx, k8sClient, metadataSyncer.k8sInformerManager,
+			metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.UseCSINodeId))
+		if err != nil {
+			log.Errorf("failed to start the node registry sync. Err: %v", err)
+			return err
+		}
+		go runPeriodically(syncerClock, time.Duration(defaultNodeRegistrySyncIntervalInMin)*time.Minute, stopCh,
+			func() {
+				ctx, log := logger.GetNewContextWithLogger()
+				log.Debug("node registry sync is triggered")
+				nodeRegistry.sync(ctx)
+			})
+	}
+
+	volumeHealthInterval := time.Duration(getVolumeHealthIntervalInMin(ctx)) * time.Minute
 
 	// Trigger get volume health status.
 	if metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorWorkload {
-		go func() {
-			for ; true; <-volumeHealthTicker.C {
-				ctx, log = logger.GetNewContextWithLogger()
-				if !metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.VolumeHealth) {
-					log.Warnf("VolumeHealth feature is disabled on the cluster")
-				} else {
-					log.Infof("getVolumeHealthStatus is triggered")
-					csiGetVolumeHealthStatus(ctx, k8sClient, metadataSyncer)
-				}
+		go runPeriodically(syncerClock, volumeHealthInterval, stopCh, func() {
+			ctx, log := logger.GetNewContextWithLogger()
+			if !metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.VolumeHealth) {
+				log.Warnf("VolumeHealth feature is disabled on the cluster")
+			} else {
+				log.Infof("getVolumeHealthStatus is triggered")
+				csiGetVolumeHealthStatus(ctx, k8sClient, metadataSyncer)
 			}
-		}()
+		})
+	}
+	// Refresh the volume health and the storage capacity on the datastore
+	// accessibility events of vCenter.
+	if (metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorVanilla ||
+		metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorWorkload) &&
+		metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.DatastoreAccessibilityWatcher) {
+		var refreshers []func(ctx context.Context)
+		if metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorWorkload &&
+			metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.VolumeHealth) {
+			refreshers = append(refreshers, func(ctx context.Context) {
+				csiGetVolumeHealthStatus(ctx, k8sClient, metadataSyncer)
+			})
+		}
+		if capacityPublisher != nil {
+			refreshers = append(refreshers, func(ctx context.Context) {
+				capacityPublisher.publish(ctx, metadataSyncer)
+			})
+		}
+		if len(refreshers) > 0 {
+			startDatastoreAccessibilityWatcher(ctx, metadataSyncer, refreshers)
+		} else {
+			log.Infof("Neither the volume health nor the storage capacity tracking is enabled. " +
+				"Not watching the datastore accessibility events.")
+		}
 	}
 	if metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorGuest {
-		volumeHealthEnablementTicker := time.NewTicker(common.DefaultFeatureEnablementCheckInterval)
-		defer volumeHealthEnablementTicker.Stop()
+		// Probe the connection to the supervisor cluster.
+		supervisorNamespace, err := cnsconfig.GetSupervisorNamespace(ctx)
+		if err != nil {
+			log.Errorf("failed to get the supervisor namespace. Err: %v", err)
+			return err
+		}
+		supervisorProber := newSupervisorConnectionProber(supervisorNamespace)
+		go runPeriodically(syncerClock, supervisorConnectionProbeInterval, stopCh, func() {
+			ctx, log := logger.GetNewContextWithLogger()
+			log.Debug("supervisor connection probe is triggered")
+			supervisorProber.probe(ctx, metadataSyncer)
+		})
+
 		// Trigger volume health reconciler.
-		go func() {
-			for ; true; <-volumeHealthEnablementTicker.C {
-				ctx, log = logger.GetNewContextWithLogger()
-				if !metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.VolumeHealth) {
-					log.Debugf("VolumeHealth feature is disabled on the cluster")
-				} else {
-					if err := initVolumeHealthReconciler(ctx, k8sClient, metadataSyncer.supervisorClient); err != nil {
-						log.Warnf("Error while initializing volume health reconciler. Err:%+v. Retry will be triggered at %v",
-							err, time.Now().Add(common.DefaultFeatureEnablementCheckInterval))
-						continue
-					}
-					break
-				}
+		go runPeriodicallyUntilDone(syncerClock, common.DefaultFeatureEnablementCheckInterval, stopCh, func() bool {
+			ctx, log := logger.GetNewContextWithLogger()
+			if !metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.VolumeHealth) {
+				log.Debugf("VolumeHealth feature is disabled on the cluster")
+				return false
 			}
-		}()
+			if err := initVolumeHealthReconciler(ctx, k8sClient, metadataSyncer.getSupervisorClient); err != nil {
+				log.Warnf("Error while initializing volume health reconciler. Err:%+v. Retry will be triggered at %v",
+					err, syncerClock.Now().Add(common.DefaultFeatureEnablementCheckInterval))
+				return false
+			}
+			return true
+		})
 
-		volumeResizeEnablementTicker := time.NewTicker(common.DefaultFeatureEnablementCheckInterval)
-		defer volumeResizeEnablementTicker.Stop()
 		// Trigger resize reconciler.
-		go func() {
-			for ; true; <-volumeResizeEnablementTicker.C {
-				ctx, log = logger.GetNewContextWithLogger()
-				if !metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.VolumeExtend) {
-					log.Debugf("ExpandVolume feature is disabled on the cluster")
-				} else {
-					if err := initResizeReconciler(ctx, k8sClient, metadataSyncer.supervisorClient); err != nil {
-						log.Warnf("Error while initializing volume resize reconciler. Err:%+v. Retry will be triggered at %v",
-							err, time.Now().Add(common.DefaultFeatureEnablementCheckInterval))
-						continue
-					}
-					break
-				}
+		go runPeriodicallyUntilDone(syncerClock, common.DefaultFeatureEnablementCheckInterval, stopCh, func() bool {
+			ctx, log := logger.GetNewContextWithLogger()
+			if !metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.VolumeExtend) {
+				log.Debugf("ExpandVolume feature is disabled on the cluster")
+				return false
+			}
+			if err := initResizeReconciler(ctx, k8sClient, metadataSyncer.getSupervisorClient); err != nil {
+				log.Warnf("Error while initializing volume resize reconciler. Err:%+v. Retry will be triggered at %v",
+					err, syncerClock.Now().Add(common.DefaultFeatureEnablementCheckInterval))
+				return false
 			}
-		}()
+			return true
+		})
 	}
 
 	<-stopCh
+	shutdownMetadataSyncer(ctx)
 	return nil
 }
 
@@ -552,26 +961,19 @@ func ReloadConfiguration(metadataSyncer *metadataSyncInformer, reconnectToVCFrom
 	log.Info("Reloading Configuration")
 	cfg, err := common.GetConfig(ctx)
 	if err != nil {
-		return logger.LogNewErrorf(log, "failed to read config. Error: %+v", err)
+		return logger.LogNewErrorf(log, "failed to read config. Error: %w", err)
 	}
 	if metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorGuest {
-		var err error
-		restClientConfig := k8s.GetRestClientConfigForSupervisor(ctx,
+		cnsOperatorClient, supervisorClient, err := newSupervisorClients(ctx,
 			cfg.GC.Endpoint, metadataSyncer.configInfo.Cfg.GC.Port)
-		metadataSyncer.cnsOperatorClient, err = k8s.NewClientForGroup(ctx,
-			restClientConfig, cnsoperatorv1alpha1.GroupName)
-		if err != nil {
-			return logger.LogNewErrorf(log, "failed to create cns operator client. Err: %v", err)
-		}
-
-		metadataSyncer.supervisorClient, err = k8s.NewSupervisorClient(ctx, restClientConfig)
 		if err != nil {
-			return logger.LogNewErrorf(log, "failed to create supervisorClient. Error: %+v", err)
+			return logger.LogNewErrorf(log, "failed to create supervisor clients. Err: %w", err)
 		}
+		metadataSyncer.setSupervisorClients(cnsOperatorClient, supervisorClient)
 	} else {
 		newVCConfig, err := cnsvsphere.GetVirtualCenterConfig(ctx, cfg)
 		if err != nil {
-			return logger.LogNewErrorf(log, "failed to get VirtualCenterConfig. err=%v", err)
+			return logger.LogNewErrorf(log, "failed to get VirtualCenterConfig. err=%w", err)
 		}
 		if newVCConfig != nil {
 			var vcenter *cnsvsphere.VirtualCenter
@@ -585,7 +987,7 @@ func ReloadConfiguration(metadataSyncer *metadataSyncInformer, reconnectToVCFrom
 				newVC := &cnsvsphere.VirtualCenter{Config: newVCConfig}
 				if err = newVC.Connect(ctx); err != nil {
 					return logger.LogNewErrorf(log,
-						"failed to connect to VirtualCenter host: %s using new credentials, Err: %+v",
+						"failed to connect to VirtualCenter host: %s using new credentials, Err: %w",
 						newVCConfig.Host, err)
 				}
 
@@ -594,14 +996,14 @@ func ReloadConfiguration(metadataSyncer *metadataSyncInformer, reconnectToVCFrom
 				log.Info("Obtaining new vCenterInstance using new credentials")
 				vcenter, err = cnsvsphere.GetVirtualCenterInstance(ctx, &cnsconfig.ConfigurationInfo{Cfg: cfg}, true)
 				if err != nil {
-					return logger.LogNewErrorf(log, "failed to get VirtualCenter. err=%v", err)
+					return logger.LogNewErrorf(log, "failed to get VirtualCenter. err=%w", err)
 				}
 			} else {
 				// If it's not a VC host or VC credentials update, same singleton
 				// instance can be used and it's Config field can be updated.
 				vcenter, err = cnsvsphere.GetVirtualCenterInstance(ctx, &cnsconfig.ConfigurationInfo{Cfg: cfg}, false)
 				if err != nil {
-					return logger.LogNewErrorf(log, "failed to get VirtualCenter. err=%v", err)
+					return logger.LogNewErrorf(log, "failed to get VirtualCenter. err=%w", err)
 				}
 				vcenter.Config = newVCConfig
 			}
@@ -647,6 +1049,10 @@ func pvcUpdated(oldObj, newObj interface{}, metadataSyncer *metadataSyncInformer
 		return
 	}
 	log.Debugf("PVCUpdated: PVC Updated from %+v to %+v", oldPvc, newPvc)
+	if !metadataSyncer.namespaceScope.includes(newPvc.Namespace) {
+		log.Debugf("PVCUpdated: namespace %s is not synced. Skipping PVC %s", newPvc.Namespace, newPvc.Name)
+		return
+	}
 	if newPvc.Status.Phase != v1.ClaimBound {
 		log.Debugf("PVCUpdated: New PVC not in Bound phase")
 		return
@@ -676,6 +1082,11 @@ func pvcUpdated(oldObj, newObj interface{}, metadataSyncer *metadataSyncInformer
 		}
 		log.Debugf("PVCUpdated: Found Persistent Volume %s from API server", newPvc.Spec.VolumeName)
 	}
+	if isVolumeInMaintenance(ctx, pv) {
+		log.Infof("PVCUpdated: pv %s is in maintenance. Skipping update of PVC %s in namespace %s",
+			pv.Name, newPvc.Name, newPvc.Namespace)
+		return
+	}
 	migrationEnabled := metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.CSIMigration)
 	// Verify if csi migration is ON and check if there is any label update or
 	// migrated-to annotation was received for the PVC.
@@ -741,6 +1152,10 @@ func pvcDeleted(obj interface{}, metadataSyncer *metadataSyncInformer) {
 		return
 	}
 	log.Debugf("PVCDeleted: %+v", pvc)
+	if !metadataSyncer.namespaceScope.includes(pvc.Namespace) {
+		log.Debugf("PVCDeleted: namespace %s is not synced. Skipping PVC %s", pvc.Namespace, pvc.Name)
+		return
+	}
 	if pvc.Status.Phase != v1.ClaimBound {
 		return
 	}
@@ -751,6 +1166,11 @@ func pvcDeleted(obj interface{}, metadataSyncer *metadataSyncInformer) {
 			pvc.Name, pvc.Namespace, err)
 		return
 	}
+	if isVolumeInMaintenance(ctx, pv) {
+		log.Infof("PVCDeleted: pv %s is in maintenance. Skipping deletion of PVC %s in namespace %s",
+			pv.Name, pvc.Name, pvc.Namespace)
+		return
+	}
 	migrationEnabled := metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.CSIMigration)
 	if migrationEnabled && pv.Spec.VsphereVolume != nil {
 		if !isValidvSphereVolumeClaim(ctx, pvc.ObjectMeta) {
@@ -795,6 +1215,15 @@ func pvUpdated(oldObj, newObj interface{}, metadataSyncer *metadataSyncInformer)
 		return
 	}
 	log.Debugf("PVUpdated: PV Updated from %+v to %+v", oldPv, newPv)
+	if !metadataSyncer.namespaceScope.includesPV(newPv) {
+		log.Debugf("PVUpdated: namespace %s of the claim of PV %s is not synced. Skipping update",
+			newPv.Spec.ClaimRef.Namespace, newPv.Name)
+		return
+	}
+	if isVolumeInMaintenance(ctx, newPv) {
+		log.Infof("PVUpdated: pv %s is in maintenance. Skipping update", newPv.Name)
+		return
+	}
 
 	// Return if new PV status is Pending or Failed.
 	if newPv.Status.Phase == v1.VolumePending || newPv.Status.Phase == v1.VolumeFailed {
@@ -838,9 +1267,10 @@ func pvUpdated(oldObj, newObj interface{}, metadataSyncer *metadataSyncInformer)
 			log.Debugf("PVUpdated: PV is not a vSphere CSI Volume: %+v", newPv)
 			return
 		}
-		// Return if labels are unchanged.
+		// Return if labels, including the ones set through annotations, are
+		// unchanged.
 		if (oldPv.Status.Phase == v1.VolumeAvailable || oldPv.Status.Phase == v1.VolumeBound) &&
-			reflect.DeepEqual(newPv.GetLabels(), oldPv.GetLabels()) {
+			reflect.DeepEqual(GetPVMetadataLabels(ctx, newPv), GetPVMetadataLabels(ctx, oldPv)) {
 			log.Debugf("PVUpdated: PV labels have not changed")
 			return
 		}
@@ -872,6 +1302,10 @@ func pvDeleted(obj interface{}, metadataSyncer *metadataSyncInformer) {
 		return
 	}
 	log.Debugf("PVDeleted: PV: %+v", pv)
+	if isVolumeInMaintenance(ctx, pv) {
+		log.Infof("PVDeleted: pv %s is in maintenance. Skipping deletion of PV metadata", pv.Name)
+		return
+	}
 
 	migrationEnabled := metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.CSIMigration)
 	if migrationEnabled && pv.Spec.VsphereVolume != nil {
@@ -914,6 +1348,10 @@ func podUpdated(oldObj, newObj interface{}, metadataSyncer *metadataSyncInformer
 		log.Warnf("PodUpdated: unrecognized new object %+v", newObj)
 		return
 	}
+	if !metadataSyncer.namespaceScope.includes(newPod.Namespace) {
+		log.Debugf("PodUpdated: namespace %s is not synced. Skipping pod %s", newPod.Namespace, newPod.Name)
+		return
+	}
 
 	// If old pod is in pending state and new pod is running, update metadata.
 	if oldPod.Status.Phase == v1.PodPending && newPod.Status.Phase == v1.PodRunning {
@@ -933,6 +1371,10 @@ func podDeleted(obj interface{}, metadataSyncer *metadataSyncInformer) {
 		log.Warnf("PodDeleted: unrecognized new object %+v", obj)
 		return
 	}
+	if !metadataSyncer.namespaceScope.includes(pod.Namespace) {
+		log.Debugf("PodDeleted: namespace %s is not synced. Skipping pod %s", pod.Namespace, pod.Name)
+		return
+	}
 
 	log.Debugf("PodDeleted: Pod %s calling updatePodMetadata", pod.Name)
 	// Update pod metadata.
@@ -978,7 +1420,8 @@ func csiPVCUpdated(ctx context.Context, pvc *v1.PersistentVolumeClaim,
 		// pvcUpdated and pvUpdated. This helps avoid race condition between
 		// pvUpdated and pvcUpdated handlers when static PV and PVC is created
 		// almost at the same time using single YAML file.
-		err := wait.Poll(5*time.Second, time.Minute, func() (bool, error) {
+		errVolumeNotFound := errors.New("volume not found")
+		err := retry.Do(ctx, "PVCUpdatedVolumeLookup", retry.SyncerVolumeLookup, func() error {
 			queryFilter := cnstypes.CnsQueryFilter{
 				VolumeIds: []cnstypes.CnsVolumeId{{Id: volumeHandle}},
 			}
@@ -987,22 +1430,23 @@ func csiPVCUpdated(ctx context.Context, pvc *v1.PersistentVolumeClaim,
 			queryResult, err := metadataSyncer.volumeManager.QueryAllVolume(ctx, queryFilter, cnstypes.CnsQuerySelection{})
 			if err != nil {
 				log.Errorf("PVCUpdated: QueryVolume failed for volume %q with err=%+v", volumeHandle, err.Error())
-				return false, err
+				return retry.Permanent(err)
 			}
 			if queryResult != nil && len(queryResult.Volumes) == 1 && queryResult.Volumes[0].VolumeId.Id == volumeHandle {
 				log.Infof("PVCUpdated: volume %q found", volumeHandle)
 				volumeFound = true
+				return nil
 			}
-			return volumeFound, nil
+			return errVolumeNotFound
 		})
-		if err != nil {
+		if err != nil && err != errVolumeNotFound {
 			log.Errorf("PVCUpdated: Error occurred while polling to check if volume is marked as container volume. "+
 				"err: %+v", err)
 			return
 		}
 
 		if !volumeFound {
-			// volumeFound will be false when wait poll times out.
+			// volumeFound will be false when the retries are exhausted.
 			log.Errorf("PVCUpdated: volume: %q is not marked as the container volume. Skipping PVC entity metadata update",
 				volumeHandle)
 			return
@@ -1018,6 +1462,12 @@ func csiPVCUpdated(ctx context.Context, pvc *v1.PersistentVolumeClaim,
 		[]cnstypes.CnsKubernetesEntityReference{entityReference})
 
 	metadataList = append(metadataList, cnstypes.BaseCnsEntityMetadata(pvcMetadata))
+	if metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.GenericEphemeralVolumeMetadata) {
+		podMetadata := getEphemeralVolumeOwnerPodMetadata(pvc, metadataSyncer.configInfo.Cfg.Global.ClusterID)
+		if podMetadata != nil {
+			metadataList = append(metadataList, cnstypes.BaseCnsEntityMetadata(podMetadata))
+		}
+	}
 	containerCluster := cnsvsphere.GetContainerCluster(metadataSyncer.configInfo.Cfg.Global.ClusterID,
 		metadataSyncer.configInfo.Cfg.VirtualCenter[metadataSyncer.host].User, metadataSyncer.clusterFlavor,
 		metadataSyncer.configInfo.Cfg.Global.ClusterDistribution)
@@ -1034,7 +1484,7 @@ func csiPVCUpdated(ctx context.Context, pvc *v1.PersistentVolumeClaim,
 	}
 
 	log.Debugf("PVCUpdated: Calling UpdateVolumeMetadata with updateSpec: %+v", spew.Sdump(updateSpec))
-	if err := metadataSyncer.volumeManager.UpdateVolumeMetadata(ctx, updateSpec); err != nil {
+	if err := updateVolumeMetadata(ctx, metadataSyncer, updateSpec); err != nil {
 		log.Errorf("PVCUpdated: UpdateVolumeMetadata failed with err %v", err)
 	}
 }
@@ -1093,7 +1543,7 @@ func csiPVCDeleted(ctx context.Context, pvc *v1.PersistentVolumeClaim,
 
 	log.Debugf("PVCDeleted: Calling UpdateVolumeMetadata for volume %s with updateSpec: %+v",
 		updateSpec.VolumeId.Id, spew.Sdump(updateSpec))
-	if err := metadataSyncer.volumeManager.UpdateVolumeMetadata(ctx, updateSpec); err != nil {
+	if err := updateVolumeMetadata(ctx, metadataSyncer, updateSpec); err != nil {
 		log.Errorf("PVCDeleted: UpdateVolumeMetadata failed with err %v", err)
 	}
 }
@@ -1104,7 +1554,7 @@ func csiPVUpdated(ctx context.Context, newPv *v1.PersistentVolume, oldPv *v1.Per
 	metadataSyncer *metadataSyncInformer) {
 	log := logger.GetLogger(ctx)
 	var metadataList []cnstypes.BaseCnsEntityMetadata
-	pvMetadata := cnsvsphere.GetCnsKubernetesEntityMetaData(newPv.Name, newPv.GetLabels(), false,
+	pvMetadata := cnsvsphere.GetCnsKubernetesEntityMetaData(newPv.Name, GetPVMetadataLabels(ctx, newPv), false,
 		string(cnstypes.CnsKubernetesEntityTypePV), "", metadataSyncer.configInfo.Cfg.Global.ClusterID, nil)
 	metadataList = append(metadataList, cnstypes.BaseCnsEntityMetadata(pvMetadata))
 	var volumeHandle string
@@ -1131,20 +1581,7 @@ func csiPVUpdated(ctx context.Context, newPv *v1.PersistentVolume, oldPv *v1.Per
 		volumeHandle = newPv.Spec.CSI.VolumeHandle
 	}
 
-	// TODO: Revisit the logic for static PV update once we have a specific
-	// return code from CNS for UpdateVolumeMetadata if the volume is not
-	// registered as CNS volume. The issue is being tracked here:
-	// https://github.com/kubernetes-sigs/vsphere-csi-driver/issues/579
-
-	// Dynamically provisioned PVs have a volume attribute called
-	// 'storage.kubernetes.io/csiProvisionerIdentity' in their CSI spec, which
-	// is set by external-provisioner.
-	var isdynamicCSIPV bool
-	if newPv.Spec.CSI != nil {
-		_, isdynamicCSIPV = newPv.Spec.CSI.VolumeAttributes[attribCSIProvisionerID]
-	}
-	if oldPv.Status.Phase == v1.VolumePending && newPv.Status.Phase == v1.VolumeAvailable &&
-		!isdynamicCSIPV && newPv.Spec.CSI != nil {
+	if isStaticPVCreated(ctx, metadataSyncer, oldPv, newPv) {
 		// Static PV is Created.
 		var volumeType string
 		if IsMultiAttachAllowed(oldPv) {
@@ -1157,8 +1594,8 @@ func csiPVUpdated(ctx context.Context, newPv *v1.PersistentVolume, oldPv *v1.Per
 		queryFilter := cnstypes.CnsQueryFilter{
 			VolumeIds: []cnstypes.CnsVolumeId{{Id: oldPv.Spec.CSI.VolumeHandle}},
 		}
-		volumeOperationsLock.Lock()
-		defer volumeOperationsLock.Unlock()
+		volumeOperationsLocks.Lock(oldPv.Spec.CSI.VolumeHandle)
+		defer volumeOperationsLocks.Unlock(oldPv.Spec.CSI.VolumeHandle)
 		// QueryAll with no selection will return only the volume ID.
 		queryResult, err := metadataSyncer.volumeManager.QueryAllVolume(ctx, queryFilter, cnstypes.CnsQuerySelection{})
 		if err != nil {
@@ -1228,7 +1665,7 @@ func csiPVUpdated(ctx context.Context, newPv *v1.PersistentVolume, oldPv *v1.Per
 
 	log.Debugf("PVUpdated: Calling UpdateVolumeMetadata for volume %q with updateSpec: %+v",
 		updateSpec.VolumeId.Id, spew.Sdump(updateSpec))
-	if err := metadataSyncer.volumeManager.UpdateVolumeMetadata(ctx, updateSpec); err != nil {
+	if err := updateVolumeMetadata(ctx, metadataSyncer, updateSpec); err != nil {
 		log.Errorf("PVUpdated: UpdateVolumeMetadata failed with err %v", err)
 		return
 	}
@@ -1236,6 +1673,32 @@ func csiPVUpdated(ctx context.Context, newPv *v1.PersistentVolume, oldPv *v1.Per
 		updateSpec.VolumeId.Id, spew.Sdump(updateSpec))
 }
 
+// isStaticPVCreated returns whether the given PV update shows a PV was
+// statically created for an existing volume, which must then be registered in
+// CNS. Once volumes are imported with CnsRegisterVolume, in Vanilla clusters
+// with the vanilla-volume-registration feature, static PVs created by hand
+// are no longer guessed from their updates, but registered by the full sync.
+func isStaticPVCreated(ctx context.Context, metadataSyncer *metadataSyncInformer,
+	oldPv *v1.PersistentVolume, newPv *v1.PersistentVolume) bool {
+	if metadataSyncer.clusterFlavor == cnstypes.CnsClusterFlavorVanilla &&
+		metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.VanillaVolumeRegistration) {
+		return false
+	}
+	// TODO: Revisit the logic for static PV update once we have a specific
+	// return code from CNS for UpdateVolumeMetadata if the volume is not
+	// registered as CNS volume. The issue is being tracked here:
+	// https://github.com/kubernetes-sigs/vsphere-csi-driver/issues/579
+
+	// Dynamically provisioned PVs have a volume attribute called
+	// 'storage.kubernetes.io/csiProvisionerIdentity' in their CSI spec, which
+	// is set by external-provisioner.
+	if newPv.Spec.CSI == nil {
+		return false
+	}
+	_, isdynamicCSIPV := newPv.Spec.CSI.VolumeAttributes[attribCSIProvisionerID]
+	return oldPv.Status.Phase == v1.VolumePending && newPv.Status.Phase == v1.VolumeAvailable && !isdynamicCSIPV
+}
+
 // csiPVDeleted deletes volume metadata on VC when volume has been deleted on
 // Vanills k8s and supervisor cluster.
 func csiPVDeleted(ctx context.Context, pv *v1.PersistentVolume, metadataSyncer *metadataSyncInformer) {
@@ -1245,11 +1708,10 @@ func csiPVDeleted(ctx context.Context, pv *v1.PersistentVolume, metadataSyncer *
 		log.Debugf("PVDeleted: Volume deletion will be handled by Controller")
 		return
 	}
-	volumeOperationsLock.Lock()
-	defer volumeOperationsLock.Unlock()
-
 	if IsMultiAttachAllowed(pv) {
 		// If PV is file share volume.
+		volumeOperationsLocks.Lock(pv.Spec.CSI.VolumeHandle)
+		defer volumeOperationsLocks.Unlock(pv.Spec.CSI.VolumeHandle)
 		log.Debugf("PVDeleted: vSphere CSI Driver is calling UpdateVolumeMetadata to "+
 			"delete volume metadata references for PV: %q", pv.Name)
 		var metadataList []cnstypes.BaseCnsEntityMetadata
@@ -1273,7 +1735,7 @@ func csiPVDeleted(ctx context.Context, pv *v1.PersistentVolume, metadataSyncer *
 
 		log.Debugf("PVDeleted: Calling UpdateVolumeMetadata for volume %s with updateSpec: %+v",
 			updateSpec.VolumeId.Id, spew.Sdump(updateSpec))
-		if err := metadataSyncer.volumeManager.UpdateVolumeMetadata(ctx, updateSpec); err != nil {
+		if err := updateVolumeMetadata(ctx, metadataSyncer, updateSpec); err != nil {
 			log.Errorf("PVDeleted: UpdateVolumeMetadata failed with err %v", err)
 			return
 		}
@@ -1294,7 +1756,7 @@ func csiPVDeleted(ctx context.Context, pv *v1.PersistentVolume, metadataSyncer *
 			len(queryResult.Volumes[0].Metadata.EntityMetadata) == 0 {
 			log.Infof("PVDeleted: Volume: %q is not in use by any other entity. Removing CNS tag.",
 				pv.Spec.CSI.VolumeHandle)
-			_, err := metadataSyncer.volumeManager.DeleteVolume(ctx, pv.Spec.CSI.VolumeHandle, false)
+			_, err := deleteVolume(ctx, metadataSyncer, pv.Spec.CSI.VolumeHandle, false)
 			if err != nil {
 				log.Errorf("PVDeleted: Failed to delete volume %q with error %+v", pv.Spec.CSI.VolumeHandle, err)
 				return
@@ -1325,9 +1787,11 @@ func csiPVDeleted(ctx context.Context, pv *v1.PersistentVolume, metadataSyncer *
 			volumeHandle = pv.Spec.CSI.VolumeHandle
 		}
 
+		volumeOperationsLocks.Lock(volumeHandle)
+		defer volumeOperationsLocks.Unlock(volumeHandle)
 		log.Debugf("PVDeleted: vSphere CSI Driver is deleting volume %v", pv)
 
-		if _, err := metadataSyncer.volumeManager.DeleteVolume(ctx, volumeHandle, false); err != nil {
+		if _, err := deleteVolume(ctx, metadataSyncer, volumeHandle, false); err != nil {
 			log.Errorf("PVDeleted: Failed to delete disk %s with error %+v", volumeHandle, err)
 		}
 		if migrationFeatureEnabled && pv.Spec.VsphereVolume != nil {
@@ -1352,7 +1816,7 @@ func csiUpdatePod(ctx context.Context, pod *v1.Pod, metadataSyncer *metadataSync
 		var podMetadata *cnstypes.CnsKubernetesEntityMetadata
 		if volume.PersistentVolumeClaim != nil {
 			valid, pv, pvc := IsValidVolume(ctx, volume, pod, metadataSyncer)
-			if valid {
+			if valid && !isVolumeInMaintenance(ctx, pv) {
 				if !deleteFlag {
 					// We need to update metadata for pods having corresponding PVC
 					// as an entity reference.
@@ -1454,7 +1918,7 @@ func csiUpdatePod(ctx context.Context, pod *v1.Pod, metadataSyncer *metadataSync
 
 		log.Debugf("Calling UpdateVolumeMetadata for volume %s with updateSpec: %+v",
 			updateSpec.VolumeId.Id, spew.Sdump(updateSpec))
-		if err := metadataSyncer.volumeManager.UpdateVolumeMetadata(ctx, updateSpec); err != nil {
+		if err := updateVolumeMetadata(ctx, metadataSyncer, updateSpec); err != nil {
 			log.Errorf("UpdateVolumeMetadata failed for volume %s with err: %v", volume.Name, err)
 		}
 
@@ -1462,7 +1926,7 @@ func csiUpdatePod(ctx context.Context, pod *v1.Pod, metadataSyncer *metadataSync
 }
 
 func initVolumeHealthReconciler(ctx context.Context, tkgKubeClient clientset.Interface,
-	svcKubeClient clientset.Interface) error {
+	getSvcKubeClient func() clientset.Interface) error {
 	log := logger.GetLogger(ctx)
 	// Get the supervisor namespace in which the guest cluster is deployed.
 	supervisorNamespace, err := cnsconfig.GetSupervisorNamespace(ctx)
@@ -1473,12 +1937,12 @@ func initVolumeHealthReconciler(ctx context.Context, tkgKubeClient clientset.Int
 	log.Infof("supervisorNamespace %s", supervisorNamespace)
 	log.Infof("initVolumeHealthReconciler is triggered")
 	tkgInformerFactory := informers.NewSharedInformerFactory(tkgKubeClient, volumeHealthResyncPeriod)
-	svcInformerFactory := informers.NewSharedInformerFactoryWithOptions(svcKubeClient,
+	svcInformerFactory := informers.NewSharedInformerFactoryWithOptions(getSvcKubeClient(),
 		volumeHealthResyncPeriod, informers.WithNamespace(supervisorNamespace))
 	stopCh := make(chan struct{})
 	defer close(stopCh)
-	rc, err := NewVolumeHealthReconciler(tkgKubeClient, svcKubeClient, volumeHealthResyncPeriod,
-		tkgInformerFactory, svcInformerFactory,
+	rc, err := NewVolumeHealthReconciler(tkgKubeClient, getSvcKubeClient, volumeHealthResyncPeriod,
+		tkgInformerFactory, svcInformerFactory, resourceEventBus,
 		workqueue.NewItemExponentialFailureRateLimiter(volumeHealthRetryIntervalStart, volumeHealthRetryIntervalMax),
 		supervisorNamespace, stopCh,
 	)
@@ -1490,7 +1954,7 @@ func initVolumeHealthReconciler(ctx context.Context, tkgKubeClient clientset.Int
 }
 
 func initResizeReconciler(ctx context.Context, tkgClient clientset.Interface,
-	supervisorClient clientset.Interface) error {
+	getSupervisorClient func() clientset.Interface) error {
 	log := logger.GetLogger(ctx)
 	supervisorNamespace, err := cnsconfig.GetSupervisorNamespace(ctx)
 	if err != nil {
@@ -1505,7 +1969,8 @@ func initResizeReconciler(ctx context.Context, tkgClient clientset.Interface,
 	// https://github.com/kubernetes-sigs/vsphere-csi-driver/issues/585
 	informerFactory := informers.NewSharedInformerFactory(tkgClient, resizeResyncPeriod)
 
-	rc, err := newResizeReconciler(tkgClient, supervisorClient, supervisorNamespace, resizeResyncPeriod, informerFactory,
+	rc, err := newResizeReconciler(tkgClient, getSupervisorClient, supervisorNamespace, resizeResyncPeriod,
+		informerFactory,
 		workqueue.NewItemExponentialFailureRateLimiter(resizeRetryIntervalStart, resizeRetryIntervalMax),
 		stopCh,
 	)
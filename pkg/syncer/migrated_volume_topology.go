@@ -0,0 +1,125 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	clientset "k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+)
+
+// migratedVolumeTopologyLabels maps the zone and region labels set on
+// in-tree vSphere PVs by the vSphere cloud provider to the topology keys of
+// the CSI driver they are translated to by the CSI translation library.
+var migratedVolumeTopologyLabels = []struct {
+	legacy   string
+	ga       string
+	csiLabel string
+}{
+	{
+		legacy:   v1.LabelFailureDomainBetaZone,
+		ga:       v1.LabelTopologyZone,
+		csiLabel: common.TopologyLabelsDomain + "/zone",
+	},
+	{
+		legacy:   v1.LabelFailureDomainBetaRegion,
+		ga:       v1.LabelTopologyRegion,
+		csiLabel: common.TopologyLabelsDomain + "/region",
+	},
+}
+
+// startMigratedVolumeTopologySync keeps the legacy zone and region labels of
+// the in-tree vSphere PVs and their CSI topology labels in sync as the PVs
+// get added or updated, so that migrated volumes are placed alike by the
+// in-tree scheduling of the legacy labels and by the CSI topology, while
+// nodes and PVs move between both during the migration.
+func startMigratedVolumeTopologySync(k8sclient clientset.Interface) {
+	resourceEventBus.subscribe("migrated-volume-topology", []resourceKind{pvResource}, false,
+		func(event resourceEvent) {
+			if event.eventType == resourceDeleted {
+				return
+			}
+			pv, ok := event.newObj.(*v1.PersistentVolume)
+			if !ok || pv == nil || pv.DeletionTimestamp != nil {
+				return
+			}
+			labels := getMigratedVolumeTopologyLabels(pv)
+			if len(labels) == 0 {
+				return
+			}
+			ctx, log := logger.GetNewContextWithLogger()
+			if err := patchPVLabels(ctx, k8sclient, pv.Name, labels); err != nil {
+				log.Errorf("MigratedVolumeTopology: failed to update topology labels of pv %s. Err: %v",
+					pv.Name, err)
+				return
+			}
+			log.Infof("MigratedVolumeTopology: updated topology labels of pv %s with %v", pv.Name, labels)
+		})
+}
+
+// getMigratedVolumeTopologyLabels returns the topology labels to set on the
+// given in-tree vSphere PV to keep its legacy and CSI topology labels in
+// sync, or nil if they are already. The legacy labels, falling back to their
+// GA equivalents, take precedence over the CSI ones, as they are the ones
+// set by the vSphere cloud provider. The legacy labels are only set from the
+// CSI ones when the PV has neither the legacy nor the GA label.
+func getMigratedVolumeTopologyLabels(pv *v1.PersistentVolume) map[string]interface{} {
+	if pv.Spec.VsphereVolume == nil {
+		return nil
+	}
+	labels := make(map[string]interface{})
+	for _, keys := range migratedVolumeTopologyLabels {
+		value, ok := pv.Labels[keys.legacy]
+		if !ok {
+			value, ok = pv.Labels[keys.ga]
+		}
+		csiValue, csiOk := pv.Labels[keys.csiLabel]
+		switch {
+		case ok && (!csiOk || csiValue != value):
+			labels[keys.csiLabel] = value
+		case !ok && csiOk:
+			labels[keys.legacy] = csiValue
+		}
+	}
+	if len(labels) == 0 {
+		return nil
+	}
+	return labels
+}
+
+// patchPVLabels merges the given labels into the labels of the given PV.
+func patchPVLabels(ctx context.Context, k8sclient clientset.Interface, pvName string,
+	labels map[string]interface{}) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": labels,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = k8sclient.CoreV1().PersistentVolumes().Patch(ctx, pvName, k8stypes.MergePatchType, patch,
+		metav1.PatchOptions{})
+	return err
+}
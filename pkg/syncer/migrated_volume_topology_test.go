@@ -0,0 +1,67 @@
+package syncer
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetMigratedVolumeTopologyLabels(t *testing.T) {
+	newPV := func(labels map[string]string) *v1.PersistentVolume {
+		return &v1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pv-1", Labels: labels},
+			Spec: v1.PersistentVolumeSpec{
+				PersistentVolumeSource: v1.PersistentVolumeSource{
+					VsphereVolume: &v1.VsphereVirtualDiskVolumeSource{VolumePath: "[vsanDatastore] kubevols/disk.vmdk"},
+				},
+			},
+		}
+	}
+	csiPV := newPV(map[string]string{v1.LabelFailureDomainBetaZone: "zone-a"})
+	csiPV.Spec.VsphereVolume = nil
+	csiPV.Spec.CSI = &v1.CSIPersistentVolumeSource{VolumeHandle: "vol-1"}
+
+	tests := []struct {
+		name     string
+		pv       *v1.PersistentVolume
+		expected map[string]interface{}
+	}{
+		{"no topology", newPV(nil), nil},
+		{"CSI volume", csiPV, nil},
+		{"legacy labels", newPV(map[string]string{
+			v1.LabelFailureDomainBetaZone:   "zone-a",
+			v1.LabelFailureDomainBetaRegion: "region-1",
+		}), map[string]interface{}{
+			"topology.csi.vmware.com/zone":   "zone-a",
+			"topology.csi.vmware.com/region": "region-1",
+		}},
+		{"GA labels", newPV(map[string]string{
+			v1.LabelTopologyZone: "zone-a",
+		}), map[string]interface{}{
+			"topology.csi.vmware.com/zone": "zone-a",
+		}},
+		{"in sync", newPV(map[string]string{
+			v1.LabelFailureDomainBetaZone:  "zone-a",
+			"topology.csi.vmware.com/zone": "zone-a",
+		}), nil},
+		{"legacy label changed", newPV(map[string]string{
+			v1.LabelFailureDomainBetaZone:  "zone-b",
+			"topology.csi.vmware.com/zone": "zone-a",
+		}), map[string]interface{}{
+			"topology.csi.vmware.com/zone": "zone-b",
+		}},
+		{"CSI labels only", newPV(map[string]string{
+			"topology.csi.vmware.com/zone": "zone-a",
+		}), map[string]interface{}{
+			v1.LabelFailureDomainBetaZone: "zone-a",
+		}},
+	}
+	for _, test := range tests {
+		labels := getMigratedVolumeTopologyLabels(test.pv)
+		if !reflect.DeepEqual(labels, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, labels)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+// syncNamespaceScope restricts the namespaces whose PVCs and pods are synced
+// to CNS. A nil syncNamespaceScope includes all namespaces.
+type syncNamespaceScope struct {
+	// included is the set of the synced namespaces. All namespaces which are
+	// not excluded are synced if empty.
+	included map[string]struct{}
+	// excluded is the set of the namespaces which are not synced.
+	excluded map[string]struct{}
+}
+
+// getSyncNamespaceScope returns the scope set in the X_CSI_SYNC_NAMESPACES and
+// X_CSI_SYNC_EXCLUDED_NAMESPACES env variables, or nil if none is set.
+func getSyncNamespaceScope(ctx context.Context) *syncNamespaceScope {
+	log := logger.GetLogger(ctx)
+	included := parseNamespaceList(os.Getenv(csitypes.EnvVarSyncNamespaces))
+	excluded := parseNamespaceList(os.Getenv(csitypes.EnvVarSyncExcludedNamespaces))
+	if len(included) == 0 && len(excluded) == 0 {
+		return nil
+	}
+	log.Infof("Syncing the PVCs and pods of namespaces %v, excluding namespaces %v",
+		namespaceSetToList(included), namespaceSetToList(excluded))
+	return &syncNamespaceScope{included: included, excluded: excluded}
+}
+
+// parseNamespaceList returns the set of the namespaces in the given comma
+// separated list.
+func parseNamespaceList(list string) map[string]struct{} {
+	namespaces := make(map[string]struct{})
+	for _, namespace := range strings.Split(list, ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			namespaces[namespace] = struct{}{}
+		}
+	}
+	return namespaces
+}
+
+// namespaceSetToList returns the namespaces of the given set, for logging.
+func namespaceSetToList(namespaces map[string]struct{}) []string {
+	list := make([]string, 0, len(namespaces))
+	for namespace := range namespaces {
+		list = append(list, namespace)
+	}
+	return list
+}
+
+// includes returns true if the PVCs and pods of the given namespace are
+// synced.
+func (s *syncNamespaceScope) includes(namespace string) bool {
+	if s == nil {
+		return true
+	}
+	if _, ok := s.excluded[namespace]; ok {
+		return false
+	}
+	if len(s.included) == 0 {
+		return true
+	}
+	_, ok := s.included[namespace]
+	return ok
+}
+
+// includesPV returns true if the given PV is synced, i.e. it is not bound to
+// a PVC of a namespace which is not synced.
+func (s *syncNamespaceScope) includesPV(pv *v1.PersistentVolume) bool {
+	return pv.Spec.ClaimRef == nil || s.includes(pv.Spec.ClaimRef.Namespace)
+}
+
+// filterPVs returns the given PVs which are synced.
+func (s *syncNamespaceScope) filterPVs(pvs []*v1.PersistentVolume) []*v1.PersistentVolume {
+	if s == nil {
+		return pvs
+	}
+	var filtered []*v1.PersistentVolume
+	for _, pv := range pvs {
+		if s.includesPV(pv) {
+			filtered = append(filtered, pv)
+		}
+	}
+	return filtered
+}
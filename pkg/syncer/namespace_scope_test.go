@@ -0,0 +1,65 @@
+package syncer
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+func TestSyncNamespaceScope(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tests := []struct {
+		name      string
+		included  string
+		excluded  string
+		namespace string
+		expected  bool
+	}{
+		{name: "NoScope", namespace: "ns-1", expected: true},
+		{name: "Included", included: "ns-1, ns-2", namespace: "ns-2", expected: true},
+		{name: "NotIncluded", included: "ns-1,ns-2", namespace: "ns-3", expected: false},
+		{name: "Excluded", excluded: "ns-1,ns-2", namespace: "ns-1", expected: false},
+		{name: "NotExcluded", excluded: "ns-1,ns-2", namespace: "ns-3", expected: true},
+		{name: "IncludedAndExcluded", included: "ns-1,ns-2", excluded: "ns-2", namespace: "ns-2", expected: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			os.Setenv(csitypes.EnvVarSyncNamespaces, test.included)
+			os.Setenv(csitypes.EnvVarSyncExcludedNamespaces, test.excluded)
+			defer os.Unsetenv(csitypes.EnvVarSyncNamespaces)
+			defer os.Unsetenv(csitypes.EnvVarSyncExcludedNamespaces)
+			scope := getSyncNamespaceScope(ctx)
+			if test.included == "" && test.excluded == "" && scope != nil {
+				t.Errorf("expected no scope, got %+v", scope)
+			}
+			if included := scope.includes(test.namespace); included != test.expected {
+				t.Errorf("expected namespace %s to be included: %t, got %t", test.namespace, test.expected, included)
+			}
+		})
+	}
+}
+
+func TestSyncNamespaceScopeFilterPVs(t *testing.T) {
+	scope := &syncNamespaceScope{excluded: parseNamespaceList("other-storage")}
+	unboundPV := &v1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "pv-1"}}
+	syncedPV := &v1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "pv-2"},
+		Spec: v1.PersistentVolumeSpec{ClaimRef: &v1.ObjectReference{Namespace: "default", Name: "pvc-2"}}}
+	excludedPV := &v1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "pv-3"},
+		Spec: v1.PersistentVolumeSpec{ClaimRef: &v1.ObjectReference{Namespace: "other-storage", Name: "pvc-3"}}}
+
+	filtered := scope.filterPVs([]*v1.PersistentVolume{unboundPV, syncedPV, excludedPV})
+	if len(filtered) != 2 || filtered[0] != unboundPV || filtered[1] != syncedPV {
+		t.Errorf("expected PVs pv-1 and pv-2, got %v", filtered)
+	}
+	var noScope *syncNamespaceScope
+	if filtered := noScope.filterPVs([]*v1.PersistentVolume{excludedPV}); len(filtered) != 1 {
+		t.Errorf("expected all PVs without scope, got %v", filtered)
+	}
+}
@@ -0,0 +1,408 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+const (
+	// event reason for the volumes detached from a cordoned node by the node
+	// drain detacher
+	reasonDrainVolumesDetached = "DrainVolumesDetached"
+	// event reason for the volumes detached from a node by the node drain
+	// detacher which are attached again after the node is uncordoned
+	reasonDrainVolumesReattached = "DrainVolumesReattached"
+)
+
+// nodeVMGetter returns the VM of a node.
+type nodeVMGetter interface {
+	GetNodeByName(ctx context.Context, nodeName string) (*cnsvsphere.VirtualMachine, error)
+}
+
+// drainDetachment is a volume attached to a cordoned node which can be
+// detached ahead of the attach/detach controller.
+type drainDetachment struct {
+	// attachment is the name of the VolumeAttachment of the volume.
+	attachment string
+	pvName     string
+	volumeID   string
+}
+
+// nodeDrainDetacher detaches the volumes of the pods evicted from cordoned
+// nodes, e.g. by kubectl drain, without waiting for the attach/detach
+// controller to detach them one by one. The volumes of a node are detached in
+// parallel batches, each detached by a single CNS task. A volume is only
+// detached once no pod running on the node uses it and kubelet reports it
+// unmounted. Its VolumeAttachment is annotated as detached. The
+// attach/detach controller still deletes the VolumeAttachment, and the detach
+// requested by the CSI attacher then finds it detached. If the node is
+// uncordoned first, the annotated VolumeAttachments are deleted so that the
+// volumes are attached again to the pods still using them.
+type nodeDrainDetacher struct {
+	k8sClient   clientset.Interface
+	nodeManager nodeVMGetter
+	recorder    record.EventRecorder
+	// batchSize is the maximum number of volumes detached by a CNS task.
+	batchSize int
+	// nodeQueue holds the names of the nodes to check.
+	nodeQueue workqueue.RateLimitingInterface
+}
+
+// newNodeDrainDetacher returns a nodeDrainDetacher resolving the VMs of the
+// nodes with the given node manager.
+func newNodeDrainDetacher(ctx context.Context, k8sClient clientset.Interface,
+	nodeManager nodeVMGetter) *nodeDrainDetacher {
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(
+		&typedcorev1.EventSinkImpl{
+			Interface: k8sClient.CoreV1().Events(""),
+		},
+	)
+	return &nodeDrainDetacher{
+		k8sClient:   k8sClient,
+		nodeManager: nodeManager,
+		recorder:    eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: csitypes.Name}),
+		batchSize:   getNodeDrainDetachBatchSize(ctx),
+		nodeQueue: workqueue.NewNamedRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(
+			nodeDrainDetachRetryIntervalStart, nodeDrainDetachRetryIntervalMax), "node-drain-detach"),
+	}
+}
+
+// getNodeDrainDetachBatchSize returns the maximum number of volumes detached
+// from a node by a CNS task.
+func getNodeDrainDetachBatchSize(ctx context.Context) int {
+	log := logger.GetLogger(ctx)
+	batchSize := defaultNodeDrainDetachBatchSize
+	if v := os.Getenv("NODE_DRAIN_DETACH_BATCH_SIZE"); v != "" {
+		if value, err := strconv.Atoi(v); err == nil && value > 0 {
+			batchSize = value
+			log.Infof("NodeDrainDetach: batch size is set to %d", batchSize)
+		} else {
+			log.Warnf("NodeDrainDetach: batch size set in env variable NODE_DRAIN_DETACH_BATCH_SIZE %s is "+
+				"invalid, will use the default batch size %d", v, batchSize)
+		}
+	}
+	return batchSize
+}
+
+// startNodeDrainDetach checks the node of the pods as they get deleted, and
+// the cordoned nodes periodically, and detaches their volumes no longer in
+// use. The nodes uncordoned since are checked periodically too, to attach
+// their volumes again.
+func startNodeDrainDetach(ctx context.Context, k8sClient clientset.Interface, nodeManager nodeVMGetter,
+	metadataSyncer *metadataSyncInformer) {
+	detacher := newNodeDrainDetacher(ctx, k8sClient, nodeManager)
+	resourceEventBus.subscribe("node-drain-detach", []resourceKind{podResource}, false,
+		func(event resourceEvent) {
+			if event.eventType != resourceDeleted {
+				return
+			}
+			obj := event.oldObj
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if pod, ok := obj.(*v1.Pod); ok && pod != nil && pod.Spec.NodeName != "" {
+				detacher.nodeQueue.Add(pod.Spec.NodeName)
+			}
+		})
+	for i := 0; i < nodeDrainDetachWorkers; i++ {
+		go wait.Until(func() { detacher.processNextNode(metadataSyncer) }, 0, ctx.Done())
+	}
+	go wait.Until(func() {
+		ctx, log := logger.GetNewContextWithLogger()
+		if err := detacher.queueNodes(ctx); err != nil {
+			log.Errorf("NodeDrainDetach: failed to list the nodes to check. Err: %v", err)
+		}
+	}, nodeDrainDetachResyncPeriod, ctx.Done())
+}
+
+// queueNodes queues the nodes which are cordoned, and the nodes with
+// VolumeAttachments annotated as detached.
+func (d *nodeDrainDetacher) queueNodes(ctx context.Context) error {
+	nodes, err := d.k8sClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes.Items {
+		if node.Spec.Unschedulable {
+			d.nodeQueue.Add(node.Name)
+		}
+	}
+	attachments, err := d.k8sClient.StorageV1().VolumeAttachments().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, attachment := range attachments.Items {
+		if attachment.Annotations[annDetachedFromDrainedNode] != "" {
+			d.nodeQueue.Add(attachment.Spec.NodeName)
+		}
+	}
+	return nil
+}
+
+// processNextNode checks the next node of the queue.
+func (d *nodeDrainDetacher) processNextNode(metadataSyncer *metadataSyncInformer) {
+	key, quit := d.nodeQueue.Get()
+	if quit {
+		return
+	}
+	defer d.nodeQueue.Done(key)
+	ctx, log := logger.GetNewContextWithLogger()
+	if err := d.syncNode(ctx, key.(string), metadataSyncer); err != nil {
+		log.Errorf("NodeDrainDetach: failed to detach the volumes of node %s. Err: %v", key, err)
+		d.nodeQueue.AddRateLimited(key)
+		return
+	}
+	d.nodeQueue.Forget(key)
+}
+
+// syncNode detaches the volumes of the given node if it is cordoned, and
+// checks it again later while some of its volumes are still in use. If the
+// node was uncordoned, the volumes detached from it are attached again.
+func (d *nodeDrainDetacher) syncNode(ctx context.Context, nodeName string,
+	metadataSyncer *metadataSyncInformer) error {
+	log := logger.GetLogger(ctx)
+	node, err := d.k8sClient.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			// The attach/detach controller deletes the VolumeAttachments of
+			// the deleted node.
+			return nil
+		}
+		return err
+	}
+	attachments, err := d.k8sClient.StorageV1().VolumeAttachments().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	if !node.Spec.Unschedulable {
+		return d.reattachNode(ctx, node, attachments.Items)
+	}
+	pods, err := metadataSyncer.podLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	getPV := func(name string) *v1.PersistentVolume {
+		pv, err := metadataSyncer.pvLister.Get(name)
+		if err != nil {
+			return nil
+		}
+		return pv
+	}
+	detachable, pending := getDrainDetachableAttachments(node, attachments.Items, pods, getPV)
+	if len(detachable) > 0 {
+		vm, err := d.nodeManager.GetNodeByName(ctx, nodeName)
+		if err != nil {
+			return err
+		}
+		log.Infof("NodeDrainDetach: detaching %d volumes no longer in use from cordoned node %s",
+			len(detachable), nodeName)
+		newlyDetached := detachVolumesInBatches(ctx, metadataSyncer, vm, detachable, d.batchSize,
+			"NodeDrainDetach")
+		d.setDetached(ctx, nodeName, newlyDetached)
+		if len(newlyDetached) > 0 {
+			d.recorder.Eventf(node, v1.EventTypeNormal, reasonDrainVolumesDetached,
+				"Detached %d volumes no longer in use from the cordoned node", len(newlyDetached))
+		}
+		// The volumes which failed to detach are retried on the next check.
+		pending += len(detachable) - len(newlyDetached)
+	}
+	if pending > 0 {
+		d.nodeQueue.AddAfter(nodeName, nodeDrainDetachRecheckInterval)
+	}
+	return nil
+}
+
+// detachVolumesInBatches detaches the given volumes from the vm in parallel
+// batches of at most batchSize volumes, and returns the ones which were
+// detached. The logs are prefixed with the given feature name.
+func detachVolumesInBatches(ctx context.Context, metadataSyncer *metadataSyncInformer,
+	vm *cnsvsphere.VirtualMachine, detachments []drainDetachment, batchSize int,
+	feature string) []drainDetachment {
+	log := logger.GetLogger(ctx)
+	var (
+		wg       sync.WaitGroup
+		lock     sync.Mutex
+		detached []drainDetachment
+	)
+	for start := 0; start < len(detachments); start += batchSize {
+		end := start + batchSize
+		if end > len(detachments) {
+			end = len(detachments)
+		}
+		batch := detachments[start:end]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			volumeIDs := make([]string, 0, len(batch))
+			for _, detachment := range batch {
+				volumeIDs = append(volumeIDs, detachment.volumeID)
+			}
+			results := metadataSyncer.volumeManager.BatchDetachVolumes(ctx, vm, volumeIDs)
+			lock.Lock()
+			defer lock.Unlock()
+			for _, detachment := range batch {
+				if result, ok := results[detachment.volumeID]; !ok || result.Err != nil {
+					log.Warnf("%s: failed to detach volume %s of pv %s from node VM %s. Err: %v",
+						feature, detachment.volumeID, detachment.pvName, vm.String(), result)
+					continue
+				}
+				log.Infof("%s: detached volume %s of pv %s from node VM %s",
+					feature, detachment.volumeID, detachment.pvName, vm.String())
+				detached = append(detached, detachment)
+			}
+		}()
+	}
+	wg.Wait()
+	return detached
+}
+
+// setDetached annotates the VolumeAttachments of the given detachments of the
+// given node as detached. The VolumeAttachments which fail to be annotated
+// are detached again on the next check.
+func (d *nodeDrainDetacher) setDetached(ctx context.Context, nodeName string, detachments []drainDetachment) {
+	log := logger.GetLogger(ctx)
+	patch := []byte(fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`, annDetachedFromDrainedNode, nodeName))
+	for _, detachment := range detachments {
+		_, err := d.k8sClient.StorageV1().VolumeAttachments().Patch(ctx, detachment.attachment,
+			k8stypes.MergePatchType, patch, metav1.PatchOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.Errorf("NodeDrainDetach: failed to annotate VolumeAttachment %s as detached. Err: %v",
+				detachment.attachment, err)
+		}
+	}
+}
+
+// reattachNode deletes the VolumeAttachments of the given uncordoned node
+// annotated as detached. The attach/detach controller considers their volumes
+// attached as long as they exist, so that the pods still using them, or
+// scheduled on the node again, would find them detached. Once deleted, the
+// volumes are attached again to the pods using them.
+func (d *nodeDrainDetacher) reattachNode(ctx context.Context, node *v1.Node,
+	attachments []storagev1.VolumeAttachment) error {
+	log := logger.GetLogger(ctx)
+	var failed, deleted int
+	for _, attachment := range attachments {
+		if attachment.Spec.NodeName != node.Name || attachment.Annotations[annDetachedFromDrainedNode] == "" ||
+			attachment.DeletionTimestamp != nil {
+			continue
+		}
+		log.Infof("NodeDrainDetach: node %s was uncordoned. Deleting VolumeAttachment %s whose volume was "+
+			"detached, so that it is attached again.", node.Name, attachment.Name)
+		err := d.k8sClient.StorageV1().VolumeAttachments().Delete(ctx, attachment.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.Errorf("NodeDrainDetach: failed to delete VolumeAttachment %s. Err: %v", attachment.Name, err)
+			failed++
+			continue
+		}
+		deleted++
+	}
+	if deleted > 0 {
+		d.recorder.Eventf(node, v1.EventTypeNormal, reasonDrainVolumesReattached,
+			"Deleted %d VolumeAttachments of volumes detached while the node was cordoned, "+
+				"so that they are attached again", deleted)
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to delete %d VolumeAttachments of volumes detached from node %s",
+			failed, node.Name)
+	}
+	return nil
+}
+
+// getDrainDetachableAttachments returns the vSphere CSI block volumes
+// attached to the given cordoned node which can be detached: no pod still
+// running on the node uses them, kubelet reports them unmounted, and they
+// weren't detached already. It also returns the number of volumes which are
+// still in use.
+func getDrainDetachableAttachments(node *v1.Node, attachments []storagev1.VolumeAttachment, pods []*v1.Pod,
+	getPV func(name string) *v1.PersistentVolume) ([]drainDetachment, int) {
+	volumesInUse := make(map[string]struct{})
+	for _, volume := range node.Status.VolumesInUse {
+		volumesInUse[string(volume)] = struct{}{}
+	}
+	claimsInUse := make(map[string]struct{})
+	for _, pod := range pods {
+		if pod.Spec.NodeName != node.Name || pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+		for _, volume := range pod.Spec.Volumes {
+			if volume.PersistentVolumeClaim != nil {
+				claimsInUse[pod.Namespace+"/"+volume.PersistentVolumeClaim.ClaimName] = struct{}{}
+			}
+		}
+	}
+
+	var detachable []drainDetachment
+	pending := 0
+	for _, attachment := range attachments {
+		if attachment.Spec.Attacher != csitypes.Name || attachment.Spec.NodeName != node.Name ||
+			!attachment.Status.Attached || attachment.DeletionTimestamp != nil ||
+			attachment.Annotations[annDetachedFromDrainedNode] != "" ||
+			attachment.Spec.Source.PersistentVolumeName == nil {
+			continue
+		}
+		pv := getPV(*attachment.Spec.Source.PersistentVolumeName)
+		if pv == nil || pv.Spec.CSI == nil || IsMultiAttachAllowed(pv) {
+			continue
+		}
+		// Kubelet reports the vSphere CSI volumes mounted on the node as
+		// kubernetes.io/csi/<driver name>^<volume handle>.
+		if _, ok := volumesInUse["kubernetes.io/csi/"+csitypes.Name+"^"+pv.Spec.CSI.VolumeHandle]; ok {
+			pending++
+			continue
+		}
+		if pv.Spec.ClaimRef != nil {
+			if _, ok := claimsInUse[pv.Spec.ClaimRef.Namespace+"/"+pv.Spec.ClaimRef.Name]; ok {
+				pending++
+				continue
+			}
+		}
+		detachable = append(detachable, drainDetachment{
+			attachment: attachment.Name,
+			pvName:     pv.Name,
+			volumeID:   pv.Spec.CSI.VolumeHandle,
+		})
+	}
+	sort.Slice(detachable, func(i, j int) bool {
+		return detachable[i].attachment < detachable[j].attachment
+	})
+	return detachable, pending
+}
@@ -0,0 +1,161 @@
+package syncer
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+func TestGetDrainDetachableAttachments(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+		Spec:       v1.NodeSpec{Unschedulable: true},
+		Status: v1.NodeStatus{
+			VolumesInUse: []v1.UniqueVolumeName{v1.UniqueVolumeName("kubernetes.io/csi/" + csitypes.Name + "^vol-mounted")},
+		},
+	}
+	newPV := func(name, volumeID string, accessMode v1.PersistentVolumeAccessMode) *v1.PersistentVolume {
+		return &v1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: v1.PersistentVolumeSpec{
+				AccessModes: []v1.PersistentVolumeAccessMode{accessMode},
+				PersistentVolumeSource: v1.PersistentVolumeSource{
+					CSI: &v1.CSIPersistentVolumeSource{Driver: csitypes.Name, VolumeHandle: volumeID},
+				},
+				ClaimRef: &v1.ObjectReference{Namespace: "default", Name: "claim-" + name},
+			},
+		}
+	}
+	pvs := map[string]*v1.PersistentVolume{
+		"pv-free":    newPV("pv-free", "vol-free", v1.ReadWriteOnce),
+		"pv-free2":   newPV("pv-free2", "vol-free2", v1.ReadWriteOnce),
+		"pv-mounted": newPV("pv-mounted", "vol-mounted", v1.ReadWriteOnce),
+		"pv-used":    newPV("pv-used", "vol-used", v1.ReadWriteOnce),
+		"pv-file":    newPV("pv-file", "file:vol-file", v1.ReadWriteMany),
+		"pv-done":    newPV("pv-done", "vol-done", v1.ReadWriteOnce),
+	}
+	newAttachment := func(name, pvName, nodeName string, attached bool) storagev1.VolumeAttachment {
+		attachment := storagev1.VolumeAttachment{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: storagev1.VolumeAttachmentSpec{
+				Attacher: csitypes.Name,
+				NodeName: nodeName,
+				Source:   storagev1.VolumeAttachmentSource{PersistentVolumeName: &pvName},
+			},
+			Status: storagev1.VolumeAttachmentStatus{Attached: attached},
+		}
+		if name == "va-done" {
+			attachment.Annotations = map[string]string{annDetachedFromDrainedNode: nodeName}
+		}
+		return attachment
+	}
+	attachments := []storagev1.VolumeAttachment{
+		newAttachment("va-free2", "pv-free2", "node1", true),
+		newAttachment("va-free", "pv-free", "node1", true),
+		newAttachment("va-mounted", "pv-mounted", "node1", true),
+		newAttachment("va-used", "pv-used", "node1", true),
+		newAttachment("va-file", "pv-file", "node1", true),
+		newAttachment("va-done", "pv-done", "node1", true),
+		newAttachment("va-other-node", "pv-free", "node2", true),
+		newAttachment("va-not-attached", "pv-free", "node1", false),
+		newAttachment("va-no-pv", "pv-missing", "node1", true),
+	}
+	newPod := func(name, nodeName, claimName string, phase v1.PodPhase) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Spec: v1.PodSpec{
+				NodeName: nodeName,
+				Volumes: []v1.Volume{{
+					Name: "data",
+					VolumeSource: v1.VolumeSource{
+						PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: claimName},
+					},
+				}},
+			},
+			Status: v1.PodStatus{Phase: phase},
+		}
+	}
+	pods := []*v1.Pod{
+		newPod("running", "node1", "claim-pv-used", v1.PodRunning),
+		newPod("completed", "node1", "claim-pv-free", v1.PodSucceeded),
+		newPod("elsewhere", "node2", "claim-pv-free2", v1.PodRunning),
+	}
+	getPV := func(name string) *v1.PersistentVolume {
+		return pvs[name]
+	}
+
+	detachable, pending := getDrainDetachableAttachments(node, attachments, pods, getPV)
+	expected := []drainDetachment{
+		{attachment: "va-free", pvName: "pv-free", volumeID: "vol-free"},
+		{attachment: "va-free2", pvName: "pv-free2", volumeID: "vol-free2"},
+	}
+	if !reflect.DeepEqual(detachable, expected) {
+		t.Errorf("expected detachable attachments %+v, got %+v", expected, detachable)
+	}
+	if pending != 2 {
+		t.Errorf("expected 2 pending volumes, got %d", pending)
+	}
+}
+
+func TestNodeDrainDetacherReattachesUncordonedNodes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	newAttachment := func(name, nodeName string, detached bool) *storagev1.VolumeAttachment {
+		attachment := &storagev1.VolumeAttachment{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       storagev1.VolumeAttachmentSpec{Attacher: csitypes.Name, NodeName: nodeName},
+			Status:     storagev1.VolumeAttachmentStatus{Attached: true},
+		}
+		if detached {
+			attachment.Annotations = map[string]string{annDetachedFromDrainedNode: nodeName}
+		}
+		return attachment
+	}
+	k8sClient := testclient.NewSimpleClientset(
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}},
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node2"}, Spec: v1.NodeSpec{Unschedulable: true}},
+		newAttachment("va-uncordoned", "node1", true),
+		newAttachment("va-attached", "node1", false),
+		newAttachment("va-cordoned", "node2", true),
+	)
+	d := &nodeDrainDetacher{
+		k8sClient: k8sClient,
+		recorder:  record.NewFakeRecorder(10),
+		nodeQueue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "test"),
+	}
+	defer d.nodeQueue.ShutDown()
+
+	// The uncordoned node is checked again for its detached volumes.
+	if err := d.queueNodes(ctx); err != nil {
+		t.Fatalf("queueNodes failed. Err: %v", err)
+	}
+	if d.nodeQueue.Len() != 2 {
+		t.Errorf("expected 2 queued nodes, got %d", d.nodeQueue.Len())
+	}
+	if err := d.syncNode(ctx, "node1", &metadataSyncInformer{}); err != nil {
+		t.Fatalf("syncNode failed. Err: %v", err)
+	}
+	attachments, err := k8sClient.StorageV1().VolumeAttachments().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, attachment := range attachments.Items {
+		names = append(names, attachment.Name)
+	}
+	sort.Strings(names)
+	expected := []string{"va-attached", "va-cordoned"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected VolumeAttachments %v, got %v", expected, names)
+	}
+}
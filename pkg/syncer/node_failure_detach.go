@@ -0,0 +1,313 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+// event reason for the volumes detached from the VM of a failed node by the
+// node failure detacher
+const reasonFailedNodeVolumesDetached = "FailedNodeVolumesDetached"
+
+// nodeFailureDetacher detaches the volumes of the nodes which are NotReady
+// for longer than a timeout, once their VM is confirmed powered off or
+// deleted in vCenter, and their pods are evicted or the node is marked out of
+// service, so that the volumes are released before the attach/detach
+// controller force-detaches them. The VolumeAttachments of the detached
+// volumes are annotated, and deleted if the node is Ready again before the
+// attach/detach controller deleted them, so that their volumes are attached
+// again.
+type nodeFailureDetacher struct {
+	k8sClient   clientset.Interface
+	nodeManager nodeVMGetter
+	recorder    record.EventRecorder
+	// timeout is the duration for which a node is NotReady before its volumes
+	// are detached.
+	timeout time.Duration
+}
+
+// newNodeFailureDetacher returns a nodeFailureDetacher resolving the VMs of
+// the nodes with the given node manager.
+func newNodeFailureDetacher(ctx context.Context, k8sClient clientset.Interface,
+	nodeManager nodeVMGetter) *nodeFailureDetacher {
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(
+		&typedcorev1.EventSinkImpl{
+			Interface: k8sClient.CoreV1().Events(""),
+		},
+	)
+	return &nodeFailureDetacher{
+		k8sClient:   k8sClient,
+		nodeManager: nodeManager,
+		recorder:    eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: csitypes.Name}),
+		timeout:     getNodeFailureDetachTimeout(ctx),
+	}
+}
+
+// getNodeFailureDetachTimeout returns the duration for which a node is
+// NotReady before its volumes are detached.
+func getNodeFailureDetachTimeout(ctx context.Context) time.Duration {
+	log := logger.GetLogger(ctx)
+	timeoutInMin := defaultNodeFailureDetachTimeoutInMin
+	if v := os.Getenv("NODE_FAILURE_DETACH_TIMEOUT_MINUTES"); v != "" {
+		if value, err := strconv.Atoi(v); err == nil && value > 0 {
+			timeoutInMin = value
+			log.Infof("NodeFailureDetach: timeout is set to %d minute(s)", timeoutInMin)
+		} else {
+			log.Warnf("NodeFailureDetach: timeout set in env variable NODE_FAILURE_DETACH_TIMEOUT_MINUTES %s is "+
+				"invalid, will use the default timeout of %d minute(s)", v, timeoutInMin)
+		}
+	}
+	return time.Duration(timeoutInMin) * time.Minute
+}
+
+// startNodeFailureDetach checks the NotReady nodes periodically, and detaches
+// the volumes of the ones whose VM is powered off or deleted.
+func startNodeFailureDetach(ctx context.Context, k8sClient clientset.Interface, nodeManager nodeVMGetter,
+	metadataSyncer *metadataSyncInformer) {
+	detacher := newNodeFailureDetacher(ctx, k8sClient, nodeManager)
+	go runPeriodically(syncerClock, nodeFailureDetachInterval, ctx.Done(), func() {
+		ctx, log := logger.GetNewContextWithLogger()
+		if err := detacher.check(ctx, metadataSyncer); err != nil {
+			log.Errorf("NodeFailureDetach: failed to check the NotReady nodes. Err: %v", err)
+		}
+	})
+}
+
+// check detaches the volumes of the nodes NotReady for longer than the
+// timeout, and gets the volumes detached from the nodes which are Ready again
+// attached back.
+func (d *nodeFailureDetacher) check(ctx context.Context, metadataSyncer *metadataSyncInformer) error {
+	log := logger.GetLogger(ctx)
+	nodes, err := d.k8sClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	attachments, err := d.k8sClient.StorageV1().VolumeAttachments().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	now := syncerClock.Now()
+	readyNodes := make(map[string]struct{})
+	var failedNodes []*v1.Node
+	for i := range nodes.Items {
+		if isNodeReady(&nodes.Items[i]) {
+			readyNodes[nodes.Items[i].Name] = struct{}{}
+		} else if isNodeFailed(&nodes.Items[i], now, d.timeout) {
+			failedNodes = append(failedNodes, &nodes.Items[i])
+		}
+	}
+	// The attach/detach controller considers the volumes detached by the
+	// node failure detacher attached as long as their VolumeAttachment
+	// exists. Delete the VolumeAttachments of the nodes which are Ready again,
+	// so that their volumes are attached again to the pods still using them.
+	for i := range attachments.Items {
+		attachment := &attachments.Items[i]
+		if _, ok := readyNodes[attachment.Spec.NodeName]; !ok ||
+			attachment.Annotations[annDetachedFromFailedNode] == "" || attachment.DeletionTimestamp != nil {
+			continue
+		}
+		log.Infof("NodeFailureDetach: node %s is Ready again. Deleting VolumeAttachment %s whose volume was "+
+			"detached, so that it is attached again.", attachment.Spec.NodeName, attachment.Name)
+		err := d.k8sClient.StorageV1().VolumeAttachments().Delete(ctx, attachment.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.Errorf("NodeFailureDetach: failed to delete VolumeAttachment %s. Err: %v", attachment.Name, err)
+		}
+	}
+	if len(failedNodes) == 0 {
+		return nil
+	}
+	pods, err := metadataSyncer.podLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	for _, node := range failedNodes {
+		d.detachNode(ctx, node, attachments.Items, pods, metadataSyncer)
+	}
+	return nil
+}
+
+// detachNode detaches the volumes attached to the given failed node if its VM
+// is powered off. The volumes of a deleted VM were already detached from it
+// by vCenter.
+func (d *nodeFailureDetacher) detachNode(ctx context.Context, node *v1.Node,
+	attachments []storagev1.VolumeAttachment, pods []*v1.Pod, metadataSyncer *metadataSyncInformer) {
+	log := logger.GetLogger(ctx)
+	getPV := func(name string) *v1.PersistentVolume {
+		pv, err := metadataSyncer.pvLister.Get(name)
+		if err != nil {
+			return nil
+		}
+		return pv
+	}
+	detachments := getNodeFailureDetachments(node, attachments, pods, getPV)
+	if len(detachments) == 0 {
+		return
+	}
+	vm, err := d.nodeManager.GetNodeByName(ctx, node.Name)
+	if err != nil {
+		log.Warnf("NodeFailureDetach: failed to get the VM of NotReady node %s. Err: %v", node.Name, err)
+		return
+	}
+	powerState, err := vm.GetPowerState(ctx)
+	if err != nil {
+		if !cnsvsphere.IsManagedObjectNotFound(err, vm.Reference()) {
+			log.Warnf("NodeFailureDetach: failed to get the power state of the VM of NotReady node %s. Err: %v",
+				node.Name, err)
+			return
+		}
+		log.Infof("NodeFailureDetach: VM %s of NotReady node %s was deleted, its %d volumes were detached with it",
+			vm.String(), node.Name, len(detachments))
+		d.setDetached(ctx, node.Name, detachments)
+		return
+	}
+	if powerState != types.VirtualMachinePowerStatePoweredOff {
+		log.Debugf("NodeFailureDetach: VM %s of NotReady node %s is %s. Not detaching its volumes.",
+			vm.String(), node.Name, powerState)
+		return
+	}
+	log.Infof("NodeFailureDetach: detaching %d volumes from the powered off VM of node %s NotReady for more than %v",
+		len(detachments), node.Name, d.timeout)
+	detached := detachVolumesInBatches(ctx, metadataSyncer, vm, detachments, defaultNodeDrainDetachBatchSize,
+		"NodeFailureDetach")
+	if len(detached) > 0 {
+		d.setDetached(ctx, node.Name, detached)
+		d.recorder.Eventf(node, v1.EventTypeNormal, reasonFailedNodeVolumesDetached,
+			"Detached %d volumes from the powered off VM of the NotReady node", len(detached))
+	}
+}
+
+// setDetached annotates the VolumeAttachments of the given detachments of the
+// given node as detached. The VolumeAttachments which fail to be annotated
+// are detached again on the next check.
+func (d *nodeFailureDetacher) setDetached(ctx context.Context, nodeName string, detachments []drainDetachment) {
+	log := logger.GetLogger(ctx)
+	patch := []byte(fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`, annDetachedFromFailedNode, nodeName))
+	for _, detachment := range detachments {
+		_, err := d.k8sClient.StorageV1().VolumeAttachments().Patch(ctx, detachment.attachment,
+			k8stypes.MergePatchType, patch, metav1.PatchOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.Errorf("NodeFailureDetach: failed to annotate VolumeAttachment %s as detached. Err: %v",
+				detachment.attachment, err)
+		}
+	}
+}
+
+// isNodeReady returns true if the Ready condition of the given node is true.
+func isNodeReady(node *v1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// isNodeFailed returns true if the Ready condition of the given node isn't
+// true since longer than the given timeout.
+func isNodeFailed(node *v1.Node, now time.Time, timeout time.Duration) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			return condition.Status != v1.ConditionTrue && now.Sub(condition.LastTransitionTime.Time) >= timeout
+		}
+	}
+	return false
+}
+
+// isNodeOutOfService returns true if the given node has the out-of-service
+// taint of a non-graceful node shutdown.
+func isNodeOutOfService(node *v1.Node) bool {
+	for _, taint := range node.Spec.Taints {
+		if taint.Key == common.TaintNodeOutOfService {
+			return true
+		}
+	}
+	return false
+}
+
+// getNodeFailureDetachments returns the vSphere CSI block volumes attached to
+// the given failed node which weren't detached already. Unless the node is
+// out of service, the volumes used by a pod of the node which isn't being
+// deleted, i.e. evicted, are left attached, so that the pods of a node whose
+// VM is powered on again before they are evicted find their volumes.
+func getNodeFailureDetachments(node *v1.Node, attachments []storagev1.VolumeAttachment, pods []*v1.Pod,
+	getPV func(name string) *v1.PersistentVolume) []drainDetachment {
+	claimsInUse := make(map[string]struct{})
+	if !isNodeOutOfService(node) {
+		for _, pod := range pods {
+			if pod.Spec.NodeName != node.Name || pod.DeletionTimestamp != nil ||
+				pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+				continue
+			}
+			for _, volume := range pod.Spec.Volumes {
+				if volume.PersistentVolumeClaim != nil {
+					claimsInUse[pod.Namespace+"/"+volume.PersistentVolumeClaim.ClaimName] = struct{}{}
+				}
+			}
+		}
+	}
+	var detachments []drainDetachment
+	for _, attachment := range attachments {
+		if attachment.Spec.Attacher != csitypes.Name || attachment.Spec.NodeName != node.Name ||
+			!attachment.Status.Attached || attachment.DeletionTimestamp != nil ||
+			attachment.Annotations[annDetachedFromFailedNode] != "" ||
+			attachment.Spec.Source.PersistentVolumeName == nil {
+			continue
+		}
+		pv := getPV(*attachment.Spec.Source.PersistentVolumeName)
+		if pv == nil || pv.Spec.CSI == nil || IsMultiAttachAllowed(pv) {
+			continue
+		}
+		if pv.Spec.ClaimRef != nil {
+			if _, ok := claimsInUse[pv.Spec.ClaimRef.Namespace+"/"+pv.Spec.ClaimRef.Name]; ok {
+				continue
+			}
+		}
+		detachments = append(detachments, drainDetachment{
+			attachment: attachment.Name,
+			pvName:     pv.Name,
+			volumeID:   pv.Spec.CSI.VolumeHandle,
+		})
+	}
+	sort.Slice(detachments, func(i, j int) bool {
+		return detachments[i].attachment < detachments[j].attachment
+	})
+	return detachments
+}
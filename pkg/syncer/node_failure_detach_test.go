@@ -0,0 +1,201 @@
+package syncer
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+func TestIsNodeFailed(t *testing.T) {
+	now := time.Now()
+	newNode := func(status v1.ConditionStatus, since time.Duration) *v1.Node {
+		return &v1.Node{Status: v1.NodeStatus{Conditions: []v1.NodeCondition{{
+			Type:               v1.NodeReady,
+			Status:             status,
+			LastTransitionTime: metav1.NewTime(now.Add(-since)),
+		}}}}
+	}
+	tests := []struct {
+		name     string
+		node     *v1.Node
+		expected bool
+	}{
+		{"ready", newNode(v1.ConditionTrue, time.Hour), false},
+		{"not ready beyond timeout", newNode(v1.ConditionFalse, 5*time.Minute), true},
+		{"unknown beyond timeout", newNode(v1.ConditionUnknown, 5*time.Minute), true},
+		{"unknown within timeout", newNode(v1.ConditionUnknown, time.Minute), false},
+		{"no ready condition", &v1.Node{}, false},
+	}
+	for _, test := range tests {
+		if failed := isNodeFailed(test.node, now, 3*time.Minute); failed != test.expected {
+			t.Errorf("%s: expected failed %t, got %t", test.name, test.expected, failed)
+		}
+	}
+}
+
+func TestGetNodeFailureDetachments(t *testing.T) {
+	newPV := func(name, volumeID string, accessMode v1.PersistentVolumeAccessMode) *v1.PersistentVolume {
+		return &v1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: v1.PersistentVolumeSpec{
+				AccessModes: []v1.PersistentVolumeAccessMode{accessMode},
+				PersistentVolumeSource: v1.PersistentVolumeSource{
+					CSI: &v1.CSIPersistentVolumeSource{Driver: csitypes.Name, VolumeHandle: volumeID},
+				},
+			},
+		}
+	}
+	pvs := map[string]*v1.PersistentVolume{
+		"pv-1":    newPV("pv-1", "vol-1", v1.ReadWriteOnce),
+		"pv-2":    newPV("pv-2", "vol-2", v1.ReadWriteOnce),
+		"pv-file": newPV("pv-file", "file:vol-file", v1.ReadWriteMany),
+		"pv-done": newPV("pv-done", "vol-done", v1.ReadWriteOnce),
+	}
+	pvs["pv-1"].Spec.ClaimRef = &v1.ObjectReference{Namespace: "ns", Name: "pvc-1"}
+	pvs["pv-2"].Spec.ClaimRef = &v1.ObjectReference{Namespace: "ns", Name: "pvc-2"}
+	newAttachment := func(name, pvName, nodeName string, attached bool) storagev1.VolumeAttachment {
+		return storagev1.VolumeAttachment{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: storagev1.VolumeAttachmentSpec{
+				Attacher: csitypes.Name,
+				NodeName: nodeName,
+				Source:   storagev1.VolumeAttachmentSource{PersistentVolumeName: &pvName},
+			},
+			Status: storagev1.VolumeAttachmentStatus{Attached: attached},
+		}
+	}
+	done := newAttachment("va-done", "pv-done", "node1", true)
+	done.Annotations = map[string]string{annDetachedFromFailedNode: "node1"}
+	attachments := []storagev1.VolumeAttachment{
+		newAttachment("va-2", "pv-2", "node1", true),
+		newAttachment("va-1", "pv-1", "node1", true),
+		newAttachment("va-file", "pv-file", "node1", true),
+		done,
+		newAttachment("va-other-node", "pv-1", "node2", true),
+		newAttachment("va-not-attached", "pv-1", "node1", false),
+		newAttachment("va-no-pv", "pv-missing", "node1", true),
+	}
+	getPV := func(name string) *v1.PersistentVolume {
+		return pvs[name]
+	}
+	newPod := func(name, claimName string, evicted bool) *v1.Pod {
+		pod := &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name},
+			Spec: v1.PodSpec{
+				NodeName: "node1",
+				Volumes: []v1.Volume{{
+					Name: "data",
+					VolumeSource: v1.VolumeSource{
+						PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: claimName},
+					},
+				}},
+			},
+		}
+		if evicted {
+			deletionTimestamp := metav1.Now()
+			pod.DeletionTimestamp = &deletionTimestamp
+		}
+		return pod
+	}
+	pods := []*v1.Pod{newPod("pod-1", "pvc-1", true), newPod("pod-2", "pvc-2", false)}
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}}
+
+	// The volume of the pod which isn't evicted is left attached.
+	detachments := getNodeFailureDetachments(node, attachments, pods, getPV)
+	expected := []drainDetachment{
+		{attachment: "va-1", pvName: "pv-1", volumeID: "vol-1"},
+	}
+	if !reflect.DeepEqual(detachments, expected) {
+		t.Errorf("expected detachments %+v, got %+v", expected, detachments)
+	}
+
+	// All the volumes of an out of service node are detached.
+	node.Spec.Taints = []v1.Taint{{Key: common.TaintNodeOutOfService, Effect: v1.TaintEffectNoExecute}}
+	detachments = getNodeFailureDetachments(node, attachments, pods, getPV)
+	expected = []drainDetachment{
+		{attachment: "va-1", pvName: "pv-1", volumeID: "vol-1"},
+		{attachment: "va-2", pvName: "pv-2", volumeID: "vol-2"},
+	}
+	if !reflect.DeepEqual(detachments, expected) {
+		t.Errorf("expected detachments %+v, got %+v", expected, detachments)
+	}
+}
+
+func TestNodeFailureDetacherReattachesRecoveredNodes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	newNode := func(name string, status v1.ConditionStatus) *v1.Node {
+		return &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: v1.NodeStatus{Conditions: []v1.NodeCondition{{
+				Type:               v1.NodeReady,
+				Status:             status,
+				LastTransitionTime: metav1.Now(),
+			}}},
+		}
+	}
+	newAttachment := func(name, nodeName string, detached bool) *storagev1.VolumeAttachment {
+		attachment := &storagev1.VolumeAttachment{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       storagev1.VolumeAttachmentSpec{Attacher: csitypes.Name, NodeName: nodeName},
+			Status:     storagev1.VolumeAttachmentStatus{Attached: true},
+		}
+		if detached {
+			attachment.Annotations = map[string]string{annDetachedFromFailedNode: nodeName}
+		}
+		return attachment
+	}
+	k8sClient := testclient.NewSimpleClientset(
+		newNode("node1", v1.ConditionTrue),
+		newNode("node2", v1.ConditionFalse),
+		newAttachment("va-recovered", "node1", true),
+		newAttachment("va-attached", "node1", false),
+		newAttachment("va-still-failed", "node2", true),
+	)
+	d := &nodeFailureDetacher{k8sClient: k8sClient, timeout: time.Hour}
+	if err := d.check(ctx, &metadataSyncInformer{}); err != nil {
+		t.Fatalf("check failed. Err: %v", err)
+	}
+	attachments, err := k8sClient.StorageV1().VolumeAttachments().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, attachment := range attachments.Items {
+		names = append(names, attachment.Name)
+	}
+	sort.Strings(names)
+	expected := []string{"va-attached", "va-still-failed"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected VolumeAttachments %v, got %v", expected, names)
+	}
+}
+
+func TestGetNodeFailureDetachTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	defer os.Unsetenv("NODE_FAILURE_DETACH_TIMEOUT_MINUTES")
+
+	if timeout := getNodeFailureDetachTimeout(ctx); timeout != defaultNodeFailureDetachTimeoutInMin*time.Minute {
+		t.Errorf("expected the default timeout, got %v", timeout)
+	}
+	os.Setenv("NODE_FAILURE_DETACH_TIMEOUT_MINUTES", "10")
+	if timeout := getNodeFailureDetachTimeout(ctx); timeout != 10*time.Minute {
+		t.Errorf("expected a timeout of 10m, got %v", timeout)
+	}
+	os.Setenv("NODE_FAILURE_DETACH_TIMEOUT_MINUTES", "-1")
+	if timeout := getNodeFailureDetachTimeout(ctx); timeout != defaultNodeFailureDetachTimeoutInMin*time.Minute {
+		t.Errorf("expected the default timeout for an invalid value, got %v", timeout)
+	}
+}
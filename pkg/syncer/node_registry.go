@@ -0,0 +1,163 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/node"
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	csinodetopologyv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/internalapis/csinodetopology/v1alpha1"
+	k8s "sigs.k8s.io/vsphere-csi-driver/v2/pkg/kubernetes"
+)
+
+// nodeRegisterer registers the VMs of nodes.
+type nodeRegisterer interface {
+	GetNodeByName(ctx context.Context, nodeName string) (*cnsvsphere.VirtualMachine, error)
+	RegisterNode(ctx context.Context, nodeUUID string, nodeName string) error
+}
+
+// nodeRegistrySyncer keeps the node registry of the syncer current with the
+// Nodes of the cluster. The node manager registers and unregisters the node
+// VMs as Nodes are added and deleted; the syncer registers the Nodes whose
+// registration was missed, and deletes the CSINodeTopology instances of the
+// deleted Nodes, including the ones deleted while the syncer was down.
+type nodeRegistrySyncer struct {
+	k8sClient   clientset.Interface
+	crClient    client.Client
+	nodeManager nodeRegisterer
+	// useNodeUUID is set if the node UUIDs are read from the CSINodes rather
+	// than the Nodes.
+	useNodeUUID bool
+}
+
+// startNodeRegistrySync deletes the CSINodeTopology instances of the Nodes
+// as they get deleted, and returns a nodeRegistrySyncer to sync the node
+// registry periodically.
+func startNodeRegistrySync(ctx context.Context, k8sClient clientset.Interface,
+	informerManager *k8s.InformerManager, useNodeUUID bool) (*nodeRegistrySyncer, error) {
+	log := logger.GetLogger(ctx)
+	restConfig, err := k8s.GetKubeConfig(ctx)
+	if err != nil {
+		log.Errorf("NodeRegistrySync: failed to get Kubernetes config. Err: %v", err)
+		return nil, err
+	}
+	crClient, err := k8s.NewClientForGroup(ctx, restConfig, csinodetopologyv1alpha1.GroupName)
+	if err != nil {
+		log.Errorf("NodeRegistrySync: failed to create CSINodeTopology client. Err: %v", err)
+		return nil, err
+	}
+	syncer := &nodeRegistrySyncer{
+		k8sClient:   k8sClient,
+		crClient:    crClient,
+		nodeManager: node.GetManager(ctx),
+		useNodeUUID: useNodeUUID,
+	}
+	informerManager.AddNodeListener(nil, nil, func(obj interface{}) {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		if k8sNode, ok := obj.(*v1.Node); ok && k8sNode != nil {
+			ctx, _ := logger.GetNewContextWithLogger()
+			syncer.deleteCSINodeTopology(ctx, k8sNode.Name, k8sNode.UID)
+		}
+	})
+	informerManager.Listen()
+	return syncer, nil
+}
+
+// sync registers the Nodes which aren't registered with the node manager,
+// and deletes the CSINodeTopology instances of the Nodes which don't exist
+// anymore.
+func (s *nodeRegistrySyncer) sync(ctx context.Context) {
+	log := logger.GetLogger(ctx)
+	nodeList, err := s.k8sClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		log.Errorf("NodeRegistrySync: failed to list Nodes. Err: %v", err)
+		return
+	}
+	nodeNames := make(map[string]bool)
+	for _, k8sNode := range nodeList.Items {
+		nodeNames[k8sNode.Name] = true
+		_, err := s.nodeManager.GetNodeByName(ctx, k8sNode.Name)
+		if err == nil {
+			continue
+		}
+		if err != node.ErrNodeNotFound {
+			log.Warnf("NodeRegistrySync: failed to get the VM of node %q. Err: %v", k8sNode.Name, err)
+			continue
+		}
+		nodeUUID, err := k8s.GetNodeUUID(ctx, s.k8sClient, k8sNode.Name, s.useNodeUUID)
+		if err != nil || nodeUUID == "" {
+			log.Warnf("NodeRegistrySync: failed to get the UUID of node %q. Err: %v", k8sNode.Name, err)
+			continue
+		}
+		log.Infof("NodeRegistrySync: registering node %q missing from the node manager", k8sNode.Name)
+		if err := s.nodeManager.RegisterNode(ctx, nodeUUID, k8sNode.Name); err != nil {
+			log.Warnf("NodeRegistrySync: failed to register node %q. Err: %v", k8sNode.Name, err)
+		}
+	}
+
+	csiNodeTopologies := &csinodetopologyv1alpha1.CSINodeTopologyList{}
+	if err := s.crClient.List(ctx, csiNodeTopologies); err != nil {
+		log.Errorf("NodeRegistrySync: failed to list CSINodeTopology instances. Err: %v", err)
+		return
+	}
+	for _, csiNodeTopology := range csiNodeTopologies.Items {
+		if !nodeNames[csiNodeTopology.Name] {
+			s.deleteCSINodeTopology(ctx, csiNodeTopology.Name, "")
+		}
+	}
+}
+
+// deleteCSINodeTopology deletes the CSINodeTopology instance of the given
+// deleted Node. If uid is set, the instance is only deleted if it isn't
+// owned by another Node, e.g. a Node recreated with the same name.
+func (s *nodeRegistrySyncer) deleteCSINodeTopology(ctx context.Context, nodeName string, uid types.UID) {
+	log := logger.GetLogger(ctx)
+	csiNodeTopology := &csinodetopologyv1alpha1.CSINodeTopology{}
+	err := s.crClient.Get(ctx, client.ObjectKey{Name: nodeName}, csiNodeTopology)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			log.Errorf("NodeRegistrySync: failed to get CSINodeTopology %q. Err: %v", nodeName, err)
+		}
+		return
+	}
+	if uid != "" {
+		for _, owner := range csiNodeTopology.OwnerReferences {
+			if owner.Kind == "Node" && owner.UID != uid {
+				log.Infof("NodeRegistrySync: CSINodeTopology %q is owned by another Node %q. Not deleting it.",
+					nodeName, owner.UID)
+				return
+			}
+		}
+	}
+	if err := s.crClient.Delete(ctx, csiNodeTopology); err != nil && !apierrors.IsNotFound(err) {
+		log.Errorf("NodeRegistrySync: failed to delete CSINodeTopology %q of deleted Node. Err: %v", nodeName, err)
+		return
+	}
+	log.Infof("NodeRegistrySync: deleted CSINodeTopology %q of deleted Node", nodeName)
+}
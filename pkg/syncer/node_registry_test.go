@@ -0,0 +1,118 @@
+package syncer
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/node"
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	csinodetopologyv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/internalapis/csinodetopology/v1alpha1"
+)
+
+// fakeNodeRegisterer registers the nodes in memory.
+type fakeNodeRegisterer struct {
+	nodeUUIDs map[string]string
+}
+
+func (f *fakeNodeRegisterer) GetNodeByName(ctx context.Context, nodeName string) (*cnsvsphere.VirtualMachine,
+	error) {
+	if _, ok := f.nodeUUIDs[nodeName]; !ok {
+		return nil, node.ErrNodeNotFound
+	}
+	return &cnsvsphere.VirtualMachine{}, nil
+}
+
+func (f *fakeNodeRegisterer) RegisterNode(ctx context.Context, nodeUUID string, nodeName string) error {
+	f.nodeUUIDs[nodeName] = nodeUUID
+	return nil
+}
+
+func newTestCSINodeTopology(name string, ownerUID types.UID) *csinodetopologyv1alpha1.CSINodeTopology {
+	return &csinodetopologyv1alpha1.CSINodeTopology{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: "v1", Kind: "Node", Name: name, UID: ownerUID},
+			},
+		},
+	}
+}
+
+func newTestNodeRegistrySyncer(t *testing.T, nodes []runtime.Object,
+	csiNodeTopologies ...runtime.Object) *nodeRegistrySyncer {
+	s := runtime.NewScheme()
+	if err := csinodetopologyv1alpha1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	return &nodeRegistrySyncer{
+		k8sClient:   k8sfake.NewSimpleClientset(nodes...),
+		crClient:    fake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(csiNodeTopologies...).Build(),
+		nodeManager: &fakeNodeRegisterer{nodeUUIDs: map[string]string{"node-1": "uuid-1"}},
+	}
+}
+
+func TestNodeRegistrySync(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	syncer := newTestNodeRegistrySyncer(t,
+		[]runtime.Object{
+			&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+			// Added while the node manager missed it.
+			&v1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "node-2"},
+				Spec:       v1.NodeSpec{ProviderID: "vsphere://uuid-2"},
+			},
+		},
+		newTestCSINodeTopology("node-1", "uid-1"),
+		newTestCSINodeTopology("node-2", "uid-2"),
+		// Node deleted while the syncer was down.
+		newTestCSINodeTopology("node-3", "uid-3"),
+	)
+	syncer.sync(ctx)
+
+	nodeUUIDs := syncer.nodeManager.(*fakeNodeRegisterer).nodeUUIDs
+	if nodeUUIDs["node-2"] != "uuid-2" {
+		t.Errorf("expected node-2 to be registered with UUID %q, got %q", "uuid-2", nodeUUIDs["node-2"])
+	}
+	for name, exists := range map[string]bool{"node-1": true, "node-2": true, "node-3": false} {
+		err := syncer.crClient.Get(ctx, client.ObjectKey{Name: name}, &csinodetopologyv1alpha1.CSINodeTopology{})
+		if exists && err != nil {
+			t.Errorf("expected CSINodeTopology %q to be kept, got %v", name, err)
+		}
+		if !exists && !apierrors.IsNotFound(err) {
+			t.Errorf("expected CSINodeTopology %q to be deleted, got %v", name, err)
+		}
+	}
+}
+
+func TestDeleteCSINodeTopology(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	syncer := newTestNodeRegistrySyncer(t, nil, newTestCSINodeTopology("node-1", "uid-new"))
+	// The Node was recreated with the same name before its deletion was
+	// handled.
+	syncer.deleteCSINodeTopology(ctx, "node-1", "uid-old")
+	if err := syncer.crClient.Get(ctx, client.ObjectKey{Name: "node-1"},
+		&csinodetopologyv1alpha1.CSINodeTopology{}); err != nil {
+		t.Errorf("expected CSINodeTopology of the recreated Node to be kept, got %v", err)
+	}
+
+	syncer.deleteCSINodeTopology(ctx, "node-1", "uid-new")
+	err := syncer.crClient.Get(ctx, client.ObjectKey{Name: "node-1"}, &csinodetopologyv1alpha1.CSINodeTopology{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected CSINodeTopology to be deleted, got %v", err)
+	}
+	// Deleting it again is a no-op.
+	syncer.deleteCSINodeTopology(ctx, "node-1", "uid-new")
+}
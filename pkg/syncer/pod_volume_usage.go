@@ -0,0 +1,285 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	clientset "k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+// podVolume is a vSphere CSI volume referenced by a pod.
+type podVolume struct {
+	pvcName  string
+	volumeID string
+	// capacity of the PV, used when CNS doesn't report the volume
+	pvCapacity int64
+	// multiAttach is true for volumes with a ReadWriteMany or ReadOnlyMany
+	// access mode, i.e. file volumes
+	multiAttach bool
+}
+
+// kubeletStatsSummary is the subset of the summary of the kubelet stats API
+// holding the usage of the pod volumes, as reported by NodeGetVolumeStats
+// for CSI volumes.
+type kubeletStatsSummary struct {
+	Pods []kubeletPodStats `json:"pods"`
+}
+
+// kubeletPodStats holds the volume stats of a pod in kubeletStatsSummary.
+type kubeletPodStats struct {
+	PodRef struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+		UID       string `json:"uid"`
+	} `json:"podRef"`
+	Volumes []kubeletVolumeStats `json:"volume"`
+}
+
+// kubeletVolumeStats holds the stats of a pod volume in kubeletStatsSummary.
+type kubeletVolumeStats struct {
+	Name      string  `json:"name"`
+	UsedBytes *uint64 `json:"usedBytes"`
+	PVCRef    *struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"pvcRef"`
+}
+
+// podVolumeUsageAnnotator periodically annotates the pods referencing
+// vSphere CSI volumes with the total storage provisioned for them in CNS and
+// the total storage consumed on their file systems, for showback reporting.
+// Volumes shared by several pods are accounted in full to each of them.
+type podVolumeUsageAnnotator struct {
+	k8sClient clientset.Interface
+	// getNodeStats returns the kubelet stats summary of the given node.
+	getNodeStats func(ctx context.Context, nodeName string) (*kubeletStatsSummary, error)
+}
+
+// newPodVolumeUsageAnnotator returns a podVolumeUsageAnnotator updating pods
+// through the given client and fetching the kubelet stats through the API
+// server node proxy.
+func newPodVolumeUsageAnnotator(k8sclient clientset.Interface) *podVolumeUsageAnnotator {
+	return &podVolumeUsageAnnotator{
+		k8sClient: k8sclient,
+		getNodeStats: func(ctx context.Context, nodeName string) (*kubeletStatsSummary, error) {
+			data, err := k8sclient.CoreV1().RESTClient().Get().Resource("nodes").Name(nodeName).
+				SubResource("proxy").Suffix("stats", "summary").DoRaw(ctx)
+			if err != nil {
+				return nil, err
+			}
+			summary := &kubeletStatsSummary{}
+			if err := json.Unmarshal(data, summary); err != nil {
+				return nil, err
+			}
+			return summary, nil
+		},
+	}
+}
+
+// annotate refreshes the storage usage annotations of all pods.
+func (a *podVolumeUsageAnnotator) annotate(ctx context.Context, metadataSyncer *metadataSyncInformer) {
+	log := logger.GetLogger(ctx)
+	log.Debug("PodVolumeUsage: start")
+	pods, err := metadataSyncer.podLister.List(labels.Everything())
+	if err != nil {
+		log.Errorf("PodVolumeUsage: Failed to get pods from kubernetes. Err: %+v", err)
+		return
+	}
+	podVolumes := make(map[*v1.Pod][]podVolume)
+	var volumeIds []cnstypes.CnsVolumeId
+	nodes := make(map[string]bool)
+	for _, pod := range pods {
+		volumes := getPodVolumes(metadataSyncer, pod)
+		if len(volumes) == 0 {
+			continue
+		}
+		podVolumes[pod] = volumes
+		for _, volume := range volumes {
+			volumeIds = append(volumeIds, cnstypes.CnsVolumeId{Id: volume.volumeID})
+		}
+		if pod.Spec.NodeName != "" && pod.Status.Phase == v1.PodRunning {
+			nodes[pod.Spec.NodeName] = true
+		}
+	}
+
+	capacities := make(map[string]int64)
+	if len(volumeIds) > 0 {
+		queryResults, err := fullSyncGetQueryResults(ctx, volumeIds, metadataSyncer.configInfo.Cfg.Global.ClusterID,
+			metadataSyncer.volumeManager, metadataSyncer)
+		if err != nil {
+			log.Errorf("PodVolumeUsage: Failed to query volumes from CNS. Err: %+v", err)
+			return
+		}
+		for _, queryResult := range queryResults {
+			for _, volume := range queryResult.Volumes {
+				if volume.BackingObjectDetails == nil {
+					continue
+				}
+				capacities[volume.VolumeId.Id] =
+					volume.BackingObjectDetails.GetCnsBackingObjectDetails().CapacityInMb * 1024 * 1024
+			}
+		}
+	}
+
+	usedBytes := make(map[string]map[string]uint64)
+	for nodeName := range nodes {
+		summary, err := a.getNodeStats(ctx, nodeName)
+		if err != nil {
+			log.Warnf("PodVolumeUsage: Failed to get the volume stats of node %s. Err: %+v", nodeName, err)
+			continue
+		}
+		for podUID, pvcUsedBytes := range getPodVolumeUsedBytes(summary) {
+			usedBytes[podUID] = pvcUsedBytes
+		}
+	}
+
+	for _, pod := range pods {
+		annotations := getPodVolumeUsageAnnotations(pod, podVolumes[pod], capacities, usedBytes[string(pod.UID)])
+		if len(annotations) == 0 {
+			continue
+		}
+		if err := patchPodAnnotations(ctx, a.k8sClient, pod.Namespace, pod.Name, annotations); err != nil {
+			log.Errorf("PodVolumeUsage: Failed to update the storage usage of pod %s/%s. Err: %+v",
+				pod.Namespace, pod.Name, err)
+		}
+	}
+	log.Debug("PodVolumeUsage: end")
+}
+
+// getPodVolumes returns the vSphere CSI volumes referenced by the given pod,
+// through PVCs or generic ephemeral volumes.
+func getPodVolumes(metadataSyncer *metadataSyncInformer, pod *v1.Pod) []podVolume {
+	var volumes []podVolume
+	for _, volume := range pod.Spec.Volumes {
+		var pvcName string
+		if volume.PersistentVolumeClaim != nil {
+			pvcName = volume.PersistentVolumeClaim.ClaimName
+		} else if volume.Ephemeral != nil {
+			pvcName = pod.Name + "-" + volume.Name
+		} else {
+			continue
+		}
+		pvc, err := metadataSyncer.pvcLister.PersistentVolumeClaims(pod.Namespace).Get(pvcName)
+		if err != nil || pvc.Spec.VolumeName == "" {
+			continue
+		}
+		pv, err := metadataSyncer.pvLister.Get(pvc.Spec.VolumeName)
+		if err != nil || pv.Spec.CSI == nil || pv.Spec.CSI.Driver != csitypes.Name {
+			continue
+		}
+		pvCapacity := pv.Spec.Capacity[v1.ResourceStorage]
+		volumes = append(volumes, podVolume{
+			pvcName:     pvcName,
+			volumeID:    pv.Spec.CSI.VolumeHandle,
+			pvCapacity:  pvCapacity.Value(),
+			multiAttach: IsMultiAttachAllowed(pv),
+		})
+	}
+	return volumes
+}
+
+// getPodVolumeUsedBytes returns the bytes used on the PVC volumes of the pods
+// of the given kubelet stats summary, by pod UID and PVC name.
+func getPodVolumeUsedBytes(summary *kubeletStatsSummary) map[string]map[string]uint64 {
+	usedBytes := make(map[string]map[string]uint64)
+	for _, podStats := range summary.Pods {
+		for _, volumeStats := range podStats.Volumes {
+			if volumeStats.PVCRef == nil || volumeStats.UsedBytes == nil {
+				continue
+			}
+			if usedBytes[podStats.PodRef.UID] == nil {
+				usedBytes[podStats.PodRef.UID] = make(map[string]uint64)
+			}
+			usedBytes[podStats.PodRef.UID][volumeStats.PVCRef.Name] = *volumeStats.UsedBytes
+		}
+	}
+	return usedBytes
+}
+
+// getPodVolumeUsageAnnotations returns the storage usage annotations to
+// update on the given pod, with a nil value for those to remove, or nil if
+// they are up to date. The consumed storage is only set once the usage of
+// all the volumes of the pod is known.
+func getPodVolumeUsageAnnotations(pod *v1.Pod, volumes []podVolume, capacities map[string]int64,
+	usedBytes map[string]uint64) map[string]interface{} {
+	expected := make(map[string]string)
+	if len(volumes) > 0 {
+		var provisioned int64
+		var consumed uint64
+		consumedKnown := true
+		for _, volume := range volumes {
+			capacity, ok := capacities[volume.volumeID]
+			if !ok {
+				capacity = volume.pvCapacity
+			}
+			provisioned += capacity
+			used, ok := usedBytes[volume.pvcName]
+			consumedKnown = consumedKnown && ok
+			consumed += used
+		}
+		expected[annPodProvisionedStorage] = strconv.FormatInt(provisioned, 10)
+		if consumedKnown {
+			expected[annPodConsumedStorage] = strconv.FormatUint(consumed, 10)
+		} else if value, ok := pod.Annotations[annPodConsumedStorage]; ok {
+			// Keep the last known consumption while the stats are unavailable,
+			// e.g. while the pod is not running.
+			expected[annPodConsumedStorage] = value
+		}
+	}
+	annotations := make(map[string]interface{})
+	for _, key := range []string{annPodProvisionedStorage, annPodConsumedStorage} {
+		value, ok := expected[key]
+		current, found := pod.Annotations[key]
+		if !ok && found {
+			annotations[key] = nil
+		} else if ok && (!found || current != value) {
+			annotations[key] = value
+		}
+	}
+	if len(annotations) == 0 {
+		return nil
+	}
+	return annotations
+}
+
+// patchPodAnnotations sets, or removes for nil values, the given annotations
+// on the given pod.
+func patchPodAnnotations(ctx context.Context, k8sclient clientset.Interface, namespace string, podName string,
+	annotations map[string]interface{}) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": annotations,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = k8sclient.CoreV1().Pods(namespace).Patch(ctx, podName, k8stypes.MergePatchType, patch,
+		metav1.PatchOptions{})
+	return err
+}
@@ -0,0 +1,165 @@
+package syncer
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+func TestGetPodVolumes(t *testing.T) {
+	pvIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	pvcIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	objects := []struct {
+		indexer cache.Indexer
+		obj     interface{}
+	}{
+		{pvIndexer, &v1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pv-1"},
+			Spec: v1.PersistentVolumeSpec{
+				Capacity: v1.ResourceList{v1.ResourceStorage: resource.MustParse("1Gi")},
+				PersistentVolumeSource: v1.PersistentVolumeSource{
+					CSI: &v1.CSIPersistentVolumeSource{Driver: csitypes.Name, VolumeHandle: "fcd-1"},
+				},
+			},
+		}},
+		{pvIndexer, &v1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pv-2"},
+			Spec: v1.PersistentVolumeSpec{
+				Capacity: v1.ResourceList{v1.ResourceStorage: resource.MustParse("2Gi")},
+				PersistentVolumeSource: v1.PersistentVolumeSource{
+					CSI: &v1.CSIPersistentVolumeSource{Driver: csitypes.Name, VolumeHandle: "fcd-2"},
+				},
+			},
+		}},
+		{pvIndexer, &v1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pv-other"},
+			Spec: v1.PersistentVolumeSpec{
+				PersistentVolumeSource: v1.PersistentVolumeSource{
+					CSI: &v1.CSIPersistentVolumeSource{Driver: "other.csi.driver", VolumeHandle: "other"},
+				},
+			},
+		}},
+		{pvcIndexer, &v1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pvc-1"},
+			Spec:       v1.PersistentVolumeClaimSpec{VolumeName: "pv-1"},
+		}},
+		{pvcIndexer, &v1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pod-1-scratch"},
+			Spec:       v1.PersistentVolumeClaimSpec{VolumeName: "pv-2"},
+		}},
+		{pvcIndexer, &v1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pvc-other"},
+			Spec:       v1.PersistentVolumeClaimSpec{VolumeName: "pv-other"},
+		}},
+	}
+	for _, object := range objects {
+		if err := object.indexer.Add(object.obj); err != nil {
+			t.Fatalf("failed to add object to indexer. Err: %v", err)
+		}
+	}
+	metadataSyncer := &metadataSyncInformer{
+		pvLister:  corelisters.NewPersistentVolumeLister(pvIndexer),
+		pvcLister: corelisters.NewPersistentVolumeClaimLister(pvcIndexer),
+	}
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pod-1"},
+		Spec: v1.PodSpec{Volumes: []v1.Volume{
+			{Name: "data", VolumeSource: v1.VolumeSource{
+				PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "pvc-1"},
+			}},
+			{Name: "scratch", VolumeSource: v1.VolumeSource{Ephemeral: &v1.EphemeralVolumeSource{}}},
+			{Name: "other", VolumeSource: v1.VolumeSource{
+				PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "pvc-other"},
+			}},
+			{Name: "missing", VolumeSource: v1.VolumeSource{
+				PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "pvc-missing"},
+			}},
+			{Name: "config", VolumeSource: v1.VolumeSource{ConfigMap: &v1.ConfigMapVolumeSource{}}},
+		}},
+	}
+	expected := []podVolume{
+		{pvcName: "pvc-1", volumeID: "fcd-1", pvCapacity: 1 << 30},
+		{pvcName: "pod-1-scratch", volumeID: "fcd-2", pvCapacity: 2 << 30},
+	}
+	if volumes := getPodVolumes(metadataSyncer, pod); !reflect.DeepEqual(volumes, expected) {
+		t.Errorf("expected volumes %+v, got %+v", expected, volumes)
+	}
+}
+
+func TestGetPodVolumeUsageAnnotations(t *testing.T) {
+	volumes := []podVolume{
+		{pvcName: "pvc-1", volumeID: "fcd-1", pvCapacity: 1 << 30},
+		{pvcName: "pvc-2", volumeID: "fcd-2", pvCapacity: 2 << 30},
+	}
+	capacities := map[string]int64{"fcd-1": 3 << 30}
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		volumes     []podVolume
+		usedBytes   map[string]uint64
+		expected    map[string]interface{}
+	}{
+		{
+			name:      "usage known",
+			volumes:   volumes,
+			usedBytes: map[string]uint64{"pvc-1": 100, "pvc-2": 50},
+			expected: map[string]interface{}{
+				annPodProvisionedStorage: "5368709120",
+				annPodConsumedStorage:    "150",
+			},
+		},
+		{
+			name:        "up to date",
+			annotations: map[string]string{annPodProvisionedStorage: "5368709120", annPodConsumedStorage: "150"},
+			volumes:     volumes,
+			usedBytes:   map[string]uint64{"pvc-1": 100, "pvc-2": 50},
+		},
+		{
+			name:        "usage partially known",
+			annotations: map[string]string{annPodConsumedStorage: "150"},
+			volumes:     volumes,
+			usedBytes:   map[string]uint64{"pvc-1": 100},
+			expected:    map[string]interface{}{annPodProvisionedStorage: "5368709120"},
+		},
+		{
+			name:     "usage unknown",
+			volumes:  volumes,
+			expected: map[string]interface{}{annPodProvisionedStorage: "5368709120"},
+		},
+		{
+			name:        "no volumes",
+			annotations: map[string]string{annPodProvisionedStorage: "5368709120", annPodConsumedStorage: "150"},
+			expected:    map[string]interface{}{annPodProvisionedStorage: nil, annPodConsumedStorage: nil},
+		},
+	}
+	for _, test := range tests {
+		pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: test.annotations}}
+		annotations := getPodVolumeUsageAnnotations(pod, test.volumes, capacities, test.usedBytes)
+		if !reflect.DeepEqual(annotations, test.expected) {
+			t.Errorf("%s: expected annotations %v, got %v", test.name, test.expected, annotations)
+		}
+	}
+}
+
+func TestGetPodVolumeUsedBytes(t *testing.T) {
+	used := uint64(42)
+	summary := &kubeletStatsSummary{}
+	data := `{"pods": [{"podRef": {"name": "pod-1", "namespace": "default", "uid": "uid-1"}, "volume": [
+		{"name": "data", "usedBytes": 42, "pvcRef": {"name": "pvc-1", "namespace": "default"}},
+		{"name": "config", "usedBytes": 7}]}]}`
+	if err := json.Unmarshal([]byte(data), summary); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]map[string]uint64{"uid-1": {"pvc-1": used}}
+	if usedBytes := getPodVolumeUsedBytes(summary); !reflect.DeepEqual(usedBytes, expected) {
+		t.Errorf("expected used bytes %v, got %v", expected, usedBytes)
+	}
+}
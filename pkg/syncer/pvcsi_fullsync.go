@@ -19,9 +19,9 @@ package syncer
 import (
 	"context"
 	"reflect"
-	"time"
 
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/labels"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -37,14 +37,19 @@ func PvcsiFullSync(ctx context.Context, metadataSyncer *metadataSyncInformer) er
 	log := logger.GetLogger(ctx)
 	log.Infof("FullSync: Start")
 	var err error
-	fullSyncStartTime := time.Now()
+	fullSyncStartTime := syncerClock.Now()
+	recordFullSyncStart(fullSyncStartTime)
 	defer func() {
 		fullSyncStatus := prometheus.PrometheusPassStatus
 		if err != nil {
 			fullSyncStatus = prometheus.PrometheusFailStatus
 		}
 		prometheus.FullSyncOpsHistVec.WithLabelValues(fullSyncStatus).Observe(
-			(time.Since(fullSyncStartTime)).Seconds())
+			(syncerClock.Since(fullSyncStartTime)).Seconds())
+		if err == nil {
+			recordFullSyncSuccess(ctx, syncerClock.Now())
+		}
+		recordFullSyncEnd(syncerClock.Now(), err)
 	}()
 
 	// guestCnsVolumeMetadataList is an in-memory list of cnsvolumemetadata
@@ -71,7 +76,7 @@ func PvcsiFullSync(ctx context.Context, metadataSyncer *metadataSyncInformer) er
 	// Get list of cnsvolumemetadata objects that exist in the given supervisor
 	// cluster namespace.
 	supervisorNamespaceList := &cnsvolumemetadatav1alpha1.CnsVolumeMetadataList{}
-	err = metadataSyncer.cnsOperatorClient.List(ctx, supervisorNamespaceList, client.InNamespace(supervisorNamespace))
+	err = metadataSyncer.getCnsOperatorClient().List(ctx, supervisorNamespaceList, client.InNamespace(supervisorNamespace))
 	if err != nil {
 		log.Warnf("FullSync: Failed to get CnsVolumeMetadatas from supervisor cluster. Err: %v", err)
 		return err
@@ -102,15 +107,21 @@ func PvcsiFullSync(ctx context.Context, metadataSyncer *metadataSyncInformer) er
 		supervisorObjectsMap[object.Name] = &supervisorCnsVolumeMetadataList.Items[index]
 	}
 
+	dryRun := isFullSyncDryRun(ctx)
 	// Identify cnsvolumemetadata objects that need to be updated or created
 	// on the supervisor cluster API server.
 	for _, guestObject := range guestCnsVolumeMetadataList.Items {
 		if supervisorObject, exists := supervisorObjectsMap[guestObject.Name]; !exists {
 			// Create objects that do not exist.
+			if dryRun {
+				log.Infof("FullSync: dry run: would create CnsVolumeMetadata %v on the supervisor cluster "+
+					"for entity type %q", guestObject.Name, guestObject.Spec.EntityType)
+				continue
+			}
 			log.Infof("FullSync: Creating CnsVolumeMetadata %v on the supervisor cluster for entity type %q",
 				guestObject.Name, guestObject.Spec.EntityType)
 			guestObject.Namespace = supervisorNamespace
-			if err := metadataSyncer.cnsOperatorClient.Create(ctx, &guestObject); err != nil {
+			if err := metadataSyncer.getCnsOperatorClient().Create(ctx, &guestObject); err != nil {
 				log.Warnf("FullSync: Failed to create CnsVolumeMetadata %v. Err: %v", guestObject.Name, err)
 			}
 		} else {
@@ -118,8 +129,13 @@ func PvcsiFullSync(ctx context.Context, metadataSyncer *metadataSyncInformer) er
 			// Update the supervisor cluster API server if an object is stale.
 			if guestObject.Spec.EntityType != cnsvolumemetadatav1alpha1.CnsOperatorEntityTypePOD &&
 				!compareCnsVolumeMetadatas(&guestObject.Spec, &supervisorObject.Spec) {
+				if dryRun {
+					log.Infof("FullSync: dry run: would update CnsVolumeMetadata %v on the supervisor cluster",
+						guestObject.Name)
+					continue
+				}
 				log.Infof("FullSync: Updating CnsVolumeMetadata %v on the supervisor cluster", guestObject.Name)
-				if err := metadataSyncer.cnsOperatorClient.Update(ctx, supervisorObject); err != nil {
+				if err := metadataSyncer.getCnsOperatorClient().Update(ctx, supervisorObject); err != nil {
 					log.Warnf("FullSync: Failed to update CnsVolumeMetadata %v. Err: %v", supervisorObject.Name, err)
 				}
 			}
@@ -130,9 +146,14 @@ func PvcsiFullSync(ctx context.Context, metadataSyncer *metadataSyncInformer) er
 	// the supervisor cluster API server that shouldn't exist.
 	for _, supervisorObject := range supervisorCnsVolumeMetadataList.Items {
 		if _, exists := guestObjectsMap[supervisorObject.Name]; !exists {
+			if dryRun {
+				log.Infof("FullSync: dry run: would delete CnsVolumeMetadata %v on the supervisor cluster "+
+					"for entity type %q", supervisorObject.Name, supervisorObject.Spec.EntityType)
+				continue
+			}
 			log.Infof("FullSync: Deleting CnsVolumeMetadata %v on the supervisor cluster for entity type %q",
 				supervisorObject.Name, supervisorObject.Spec.EntityType)
-			if err := metadataSyncer.cnsOperatorClient.Delete(ctx, &supervisorObject); err != nil {
+			if err := metadataSyncer.getCnsOperatorClient().Delete(ctx, &supervisorObject); err != nil {
 				log.Warnf("FullSync: Failed to delete CnsVolumeMetadata %v. Err: %v", supervisorObject.Name, err)
 			}
 		}
@@ -156,7 +177,7 @@ func createCnsVolumeMetadataList(ctx context.Context, metadataSyncer *metadataSy
 		return err
 	}
 
-	// Structure to map PVC names to corresponding volume handles.
+	// Structure to map PVC namespaced names to corresponding volume handles.
 	pvcToVolumeName := make(map[string]string)
 
 	// Create cnsvolumemetadata objects for PV and PVC entity types.
@@ -180,7 +201,14 @@ func createCnsVolumeMetadataList(ctx context.Context, metadataSyncer *metadataSy
 			pvc, err := metadataSyncer.pvcLister.PersistentVolumeClaims(pv.Spec.ClaimRef.Namespace).Get(
 				pv.Spec.ClaimRef.Name)
 			if err != nil {
-				log.Errorf("FullSync: Failed to get PVC %q from guest cluster. Err: %v", pvc.Name, err)
+				if apierrors.IsNotFound(err) {
+					// The PVC was deleted, the PV is released next.
+					log.Infof("FullSync: PVC %s/%s bound to PV %q was deleted from guest cluster",
+						pv.Spec.ClaimRef.Namespace, pv.Spec.ClaimRef.Name, pv.Name)
+					continue
+				}
+				log.Errorf("FullSync: Failed to get PVC %s/%s from guest cluster. Err: %v",
+					pv.Spec.ClaimRef.Namespace, pv.Spec.ClaimRef.Name, err)
 				return err
 			}
 			entityReference := cnsvolumemetadatav1alpha1.GetCnsOperatorEntityReference(
@@ -191,7 +219,7 @@ func createCnsVolumeMetadataList(ctx context.Context, metadataSyncer *metadataSy
 				cnsvolumemetadatav1alpha1.CnsOperatorEntityTypePVC, pvc.GetLabels(), pvc.Namespace,
 				[]cnsvolumemetadatav1alpha1.CnsOperatorEntityReference{entityReference})
 			returnList.Items = append(returnList.Items, *pvcObject)
-			pvcToVolumeName[pvc.Name] = pv.Spec.CSI.VolumeHandle
+			pvcToVolumeName[pvc.Namespace+"/"+pvc.Name] = pv.Spec.CSI.VolumeHandle
 		}
 	}
 
@@ -209,7 +237,7 @@ func createCnsVolumeMetadataList(ctx context.Context, metadataSyncer *metadataSy
 			if volume.VolumeSource.PersistentVolumeClaim == nil {
 				continue
 			}
-			volumeName, ok := pvcToVolumeName[volume.VolumeSource.PersistentVolumeClaim.ClaimName]
+			volumeName, ok := pvcToVolumeName[pod.Namespace+"/"+volume.VolumeSource.PersistentVolumeClaim.ClaimName]
 			if !ok {
 				log.Debugf("FullSync: PVC %q claimed by Pod %q is not a CSI vSphere Volume",
 					volume.VolumeSource.PersistentVolumeClaim.ClaimName, pod.Name)
@@ -234,13 +262,19 @@ func createCnsVolumeMetadataList(ctx context.Context, metadataSyncer *metadataSy
 }
 
 // compareCnsVolumeMetadatas compares input cnsvolumemetadata objects
-// and returns false if their labels are not deeply equal.
+// and returns false if their labels, cluster distribution, volume names or
+// entity references are not deeply equal. The supervisor object is then
+// updated with the ones of the guest object.
 func compareCnsVolumeMetadatas(guestObject *cnsvolumemetadatav1alpha1.CnsVolumeMetadataSpec,
 	supervisorObject *cnsvolumemetadatav1alpha1.CnsVolumeMetadataSpec) bool {
 	if !reflect.DeepEqual(guestObject.Labels, supervisorObject.Labels) ||
-		!reflect.DeepEqual(guestObject.ClusterDistribution, supervisorObject.ClusterDistribution) {
+		!reflect.DeepEqual(guestObject.ClusterDistribution, supervisorObject.ClusterDistribution) ||
+		!reflect.DeepEqual(guestObject.VolumeNames, supervisorObject.VolumeNames) ||
+		!reflect.DeepEqual(guestObject.EntityReferences, supervisorObject.EntityReferences) {
 		supervisorObject.Labels = guestObject.Labels
 		supervisorObject.ClusterDistribution = guestObject.ClusterDistribution
+		supervisorObject.VolumeNames = guestObject.VolumeNames
+		supervisorObject.EntityReferences = guestObject.EntityReferences
 		return false
 	}
 	return true
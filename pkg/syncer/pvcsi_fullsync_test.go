@@ -0,0 +1,116 @@
+package syncer
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	cnsvolumemetadatav1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsvolumemetadata/v1alpha1"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+func TestCreateCnsVolumeMetadataList(t *testing.T) {
+	pvIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	pvcIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	podIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	// PVCs with the same name in two namespaces, and a PV whose PVC was
+	// deleted.
+	for _, claim := range []struct{ namespace, name, pvName string }{
+		{"ns-1", "data", "pv-1"},
+		{"ns-2", "data", "pv-2"},
+		{"ns-3", "deleted", "pv-3"},
+	} {
+		if err := pvIndexer.Add(&v1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: claim.pvName, UID: types.UID(claim.pvName)},
+			Spec: v1.PersistentVolumeSpec{
+				PersistentVolumeSource: v1.PersistentVolumeSource{
+					CSI: &v1.CSIPersistentVolumeSource{Driver: csitypes.Name, VolumeHandle: "sv-" + claim.pvName},
+				},
+				ClaimRef: &v1.ObjectReference{Namespace: claim.namespace, Name: claim.name},
+			},
+			Status: v1.PersistentVolumeStatus{Phase: v1.VolumeBound},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if claim.name == "deleted" {
+			continue
+		}
+		if err := pvcIndexer.Add(&v1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Namespace: claim.namespace, Name: claim.name,
+				UID: types.UID("pvc-" + claim.namespace)},
+			Spec: v1.PersistentVolumeClaimSpec{VolumeName: claim.pvName},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := podIndexer.Add(&v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns-2", Name: "pod", UID: "pod"},
+		Spec: v1.PodSpec{Volumes: []v1.Volume{{Name: "data", VolumeSource: v1.VolumeSource{
+			PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "data"},
+		}}}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	metadataSyncer := &metadataSyncInformer{
+		configInfo: &config.ConfigurationInfo{Cfg: &config.Config{
+			GC: config.GCConfig{TanzuKubernetesClusterUID: "gc"},
+		}},
+		pvLister:  corelisters.NewPersistentVolumeLister(pvIndexer),
+		pvcLister: corelisters.NewPersistentVolumeClaimLister(pvcIndexer),
+		podLister: corelisters.NewPodLister(podIndexer),
+	}
+
+	list := cnsvolumemetadatav1alpha1.CnsVolumeMetadataList{}
+	if err := createCnsVolumeMetadataList(context.Background(), metadataSyncer, "sv-ns", &list); err != nil {
+		t.Fatal(err)
+	}
+	volumeNames := make(map[string][]string)
+	for _, object := range list.Items {
+		volumeNames[object.Name] = object.Spec.VolumeNames
+	}
+	expected := map[string][]string{
+		"gc-pv-1":     {"sv-pv-1"},
+		"gc-pv-2":     {"sv-pv-2"},
+		"gc-pv-3":     {"sv-pv-3"},
+		"gc-pvc-ns-1": {"sv-pv-1"},
+		"gc-pvc-ns-2": {"sv-pv-2"},
+		"gc-pod":      {"sv-pv-2"},
+	}
+	if !reflect.DeepEqual(volumeNames, expected) {
+		t.Fatalf("expected CnsVolumeMetadata volume names %v, got %v", expected, volumeNames)
+	}
+}
+
+func TestCompareCnsVolumeMetadatas(t *testing.T) {
+	newSpec := func(volumeName, pvName string) *cnsvolumemetadatav1alpha1.CnsVolumeMetadataSpec {
+		return &cnsvolumemetadatav1alpha1.CnsVolumeMetadataSpec{
+			VolumeNames: []string{volumeName},
+			EntityType:  cnsvolumemetadatav1alpha1.CnsOperatorEntityTypePVC,
+			EntityReferences: []cnsvolumemetadatav1alpha1.CnsOperatorEntityReference{
+				cnsvolumemetadatav1alpha1.GetCnsOperatorEntityReference(pvName, "",
+					cnsvolumemetadatav1alpha1.CnsOperatorEntityTypePV, "gc"),
+			},
+			Labels: map[string]string{"app": "db"},
+		}
+	}
+	if !compareCnsVolumeMetadatas(newSpec("sv-pv-1", "pv-1"), newSpec("sv-pv-1", "pv-1")) {
+		t.Fatal("expected equal CnsVolumeMetadatas")
+	}
+
+	// The PVC was bound to another PV.
+	guestObject := newSpec("sv-pv-2", "pv-2")
+	supervisorObject := newSpec("sv-pv-1", "pv-1")
+	if compareCnsVolumeMetadatas(guestObject, supervisorObject) {
+		t.Fatal("expected CnsVolumeMetadatas with different volumes to differ")
+	}
+	if !reflect.DeepEqual(guestObject, supervisorObject) {
+		t.Fatalf("expected supervisor CnsVolumeMetadata %+v to be updated to %+v", supervisorObject, guestObject)
+	}
+}
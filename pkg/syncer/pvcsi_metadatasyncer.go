@@ -64,10 +64,10 @@ func pvcsiVolumeUpdated(ctx context.Context, resourceType interface{},
 	// cluster.
 	currentMetadata := &cnsvolumemetadatav1alpha1.CnsVolumeMetadata{}
 	key := types.NamespacedName{Namespace: supervisorNamespace, Name: newMetadata.Name}
-	if err := metadataSyncer.cnsOperatorClient.Get(ctx, key, currentMetadata); err != nil {
+	if err := metadataSyncer.getCnsOperatorClient().Get(ctx, key, currentMetadata); err != nil {
 		if apierrors.IsNotFound(err) {
 			newMetadata.Namespace = supervisorNamespace
-			if err := metadataSyncer.cnsOperatorClient.Create(ctx, newMetadata); err != nil {
+			if err := metadataSyncer.getCnsOperatorClient().Create(ctx, newMetadata); err != nil {
 				log.Errorf("pvCSI VolumeUpdated: Failed to create CnsVolumeMetadata: %v. Error: %v", newMetadata.Name, err)
 			}
 			return
@@ -78,7 +78,7 @@ func pvcsiVolumeUpdated(ctx context.Context, resourceType interface{},
 	newMetadata.ResourceVersion = currentMetadata.ResourceVersion
 	newMetadata.Namespace = supervisorNamespace
 	log.Debugf("pvCSI VolumeUpdated: Invoking update on CnsVolumeMetadata with spec: %+v", spew.Sdump(newMetadata))
-	if err := metadataSyncer.cnsOperatorClient.Update(ctx, newMetadata); err != nil {
+	if err := metadataSyncer.getCnsOperatorClient().Update(ctx, newMetadata); err != nil {
 		log.Errorf("pvCSI VolumeUpdated: Failed to update CnsVolumeMetadata: %v. Error: %v", newMetadata.Name, err)
 		return
 	}
@@ -97,7 +97,7 @@ func pvcsiVolumeDeleted(ctx context.Context, uID string, metadataSyncer *metadat
 	volumeMetadataName := cnsvolumemetadatav1alpha1.GetCnsVolumeMetadataName(
 		metadataSyncer.configInfo.Cfg.GC.TanzuKubernetesClusterUID, uID)
 	log.Debugf("pvCSI VolumeDeleted: Invoking delete on CnsVolumeMetadata : %v", volumeMetadataName)
-	err = metadataSyncer.cnsOperatorClient.Delete(ctx, &cnsvolumemetadatav1alpha1.CnsVolumeMetadata{
+	err = metadataSyncer.getCnsOperatorClient().Delete(ctx, &cnsvolumemetadatav1alpha1.CnsVolumeMetadata{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      volumeMetadataName,
 			Namespace: supervisorNamespace,
@@ -125,7 +125,7 @@ func pvcsiUpdatePod(ctx context.Context, pod *v1.Pod, metadataSyncer *metadataSy
 	for _, volume := range pod.Spec.Volumes {
 		if volume.PersistentVolumeClaim != nil {
 			valid, pv, pvc := IsValidVolume(ctx, volume, pod, metadataSyncer)
-			if valid {
+			if valid && !isVolumeInMaintenance(ctx, pv) {
 				entityReferences = append(entityReferences,
 					cnsvolumemetadatav1alpha1.GetCnsOperatorEntityReference(pvc.Name, pvc.Namespace,
 						cnsvolumemetadatav1alpha1.CnsOperatorEntityTypePVC,
@@ -141,7 +141,7 @@ func pvcsiUpdatePod(ctx context.Context, pod *v1.Pod, metadataSyncer *metadataSy
 				cnsvolumemetadatav1alpha1.CnsOperatorEntityTypePOD, nil, pod.Namespace, entityReferences)
 			log.Debugf("pvCSI PodUpdated: Invoking create CnsVolumeMetadata : %v", newMetadata)
 			newMetadata.Namespace = supervisorNamespace
-			if err := metadataSyncer.cnsOperatorClient.Create(ctx, newMetadata); err != nil {
+			if err := metadataSyncer.getCnsOperatorClient().Create(ctx, newMetadata); err != nil {
 				log.Errorf("pvCSI PodUpdated: Failed to create CnsVolumeMetadata: %v. Error: %v", newMetadata.Name, err)
 				return
 			}
@@ -150,7 +150,7 @@ func pvcsiUpdatePod(ctx context.Context, pod *v1.Pod, metadataSyncer *metadataSy
 			volumeMetadataName := cnsvolumemetadatav1alpha1.GetCnsVolumeMetadataName(
 				metadataSyncer.configInfo.Cfg.GC.TanzuKubernetesClusterUID, string(pod.GetUID()))
 			log.Debugf("pvCSI PodDeleted: Invoking delete on CnsVolumeMetadata : %v", volumeMetadataName)
-			err = metadataSyncer.cnsOperatorClient.Delete(ctx, &cnsvolumemetadatav1alpha1.CnsVolumeMetadata{
+			err = metadataSyncer.getCnsOperatorClient().Delete(ctx, &cnsvolumemetadatav1alpha1.CnsVolumeMetadata{
 				ObjectMeta: metav1.ObjectMeta{
 					Name:      volumeMetadataName,
 					Namespace: supervisorNamespace,
@@ -48,8 +48,8 @@ type ResizeReconciler interface {
 type resizeReconciler struct {
 	// Tanzu Kubernetes Grid KubeClient.
 	tkgClient kubernetes.Interface
-	// Supervisor Cluster KubeClient.
-	supervisorClient kubernetes.Interface
+	// getSupervisorClient returns the current Supervisor Cluster KubeClient.
+	getSupervisorClient func() kubernetes.Interface
 	// Supervisor Cluster namespace.
 	supervisorNamespace string
 	// Tanzu Kubernetes Grid claim queue.
@@ -83,8 +83,8 @@ type resizeProcessStatus struct {
 func newResizeReconciler(
 	// Tanzu Kubernetes Grid KubeClient.
 	tkgClient kubernetes.Interface,
-	// Supervisor Cluster KubeClient.
-	supervisorClient kubernetes.Interface,
+	// Returns the current Supervisor Cluster KubeClient.
+	getSupervisorClient func() kubernetes.Interface,
 	// Supervisor Cluster Namespace.
 	supervisorNamespace string,
 	resyncPeriod time.Duration,
@@ -97,7 +97,7 @@ func newResizeReconciler(
 
 	rc := &resizeReconciler{
 		tkgClient:           tkgClient,
-		supervisorClient:    supervisorClient,
+		getSupervisorClient: getSupervisorClient,
 		supervisorNamespace: supervisorNamespace,
 		pvcLister:           pvcInformer.Lister(),
 		pvcSynced:           pvcInformer.Informer().HasSynced,
@@ -214,7 +214,7 @@ func (rc *resizeReconciler) syncPVC(ctx context.Context, key string) error {
 
 	// Get corresponding PVC from the Supervisor Cluster given the pv in the
 	// Tanzu Kubernetes Grid.
-	svcPVC, err := rc.supervisorClient.CoreV1().PersistentVolumeClaims(rc.supervisorNamespace).Get(
+	svcPVC, err := rc.getSupervisorClient().CoreV1().PersistentVolumeClaims(rc.supervisorNamespace).Get(
 		ctx, tkgPV.Spec.CSI.VolumeHandle, metav1.GetOptions{})
 	if err != nil {
 		log.Errorf("Error get supervisor cluster pvc %s from api server in the namespace %s: %v",
@@ -242,7 +242,7 @@ func (rc *resizeReconciler) syncPVC(ctx context.Context, key string) error {
 	}
 
 	if updatePVC {
-		svcUpdatedPVC, err := patchPVCStatus(ctx, svcPVC, svcPvcClone, rc.supervisorClient)
+		svcUpdatedPVC, err := patchPVCStatus(ctx, svcPVC, svcPvcClone, rc.getSupervisorClient())
 		if err != nil {
 			log.Errorf("cannot update Supervisor Cluster PVC  [%s] in namespace [%s]: [%v]",
 				svcUpdatedPVC.Name, rc.supervisorNamespace, err)
@@ -260,7 +260,7 @@ func patchPVCStatus(ctx context.Context,
 	supervisorClient kubernetes.Interface) (*v1.PersistentVolumeClaim, error) {
 	patchBytes, err := createPVCPatch(oldPVC, newPVC)
 	if err != nil {
-		return nil, fmt.Errorf("failed to patch supervisor cluster PVC %q in namespace %s: %v",
+		return nil, fmt.Errorf("failed to patch supervisor cluster PVC %q in namespace %s: %w",
 			oldPVC.Name, oldPVC.Namespace, err)
 	}
 
@@ -278,22 +278,22 @@ func createPVCPatch(
 	newPVC *v1.PersistentVolumeClaim) ([]byte, error) {
 	oldData, err := json.Marshal(oldPVC)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal old data: %v", err)
+		return nil, fmt.Errorf("failed to marshal old data: %w", err)
 	}
 
 	newData, err := json.Marshal(newPVC)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal new data: %v", err)
+		return nil, fmt.Errorf("failed to marshal new data: %w", err)
 	}
 
 	patchBytes, err := strategicpatch.CreateTwoWayMergePatch(oldData, newData, oldPVC)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create 2 way merge patch: %v", err)
+		return nil, fmt.Errorf("failed to create 2 way merge patch: %w", err)
 	}
 
 	patchBytes, err = addResourceVersion(patchBytes, oldPVC.ResourceVersion)
 	if err != nil {
-		return nil, fmt.Errorf("failed to add resource version: %v", err)
+		return nil, fmt.Errorf("failed to add resource version: %w", err)
 	}
 
 	return patchBytes, nil
@@ -303,17 +303,17 @@ func addResourceVersion(patchBytes []byte, resourceVersion string) ([]byte, erro
 	var patchMap map[string]interface{}
 	err := json.Unmarshal(patchBytes, &patchMap)
 	if err != nil {
-		return nil, fmt.Errorf("error unmarshalling patch: %v", err)
+		return nil, fmt.Errorf("error unmarshalling patch: %w", err)
 	}
 	u := unstructured.Unstructured{Object: patchMap}
 	a, err := meta.Accessor(&u)
 	if err != nil {
-		return nil, fmt.Errorf("error creating accessor: %v", err)
+		return nil, fmt.Errorf("error creating accessor: %w", err)
 	}
 	a.SetResourceVersion(resourceVersion)
 	versionBytes, err := json.Marshal(patchMap)
 	if err != nil {
-		return nil, fmt.Errorf("error marshalling json patch: %v", err)
+		return nil, fmt.Errorf("error marshalling json patch: %w", err)
 	}
 	return versionBytes, nil
 }
@@ -0,0 +1,78 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"time"
+
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+)
+
+// fullSyncDrainPollInterval is the interval at which the shutdown of the
+// metadata syncer checks whether full sync completed.
+const fullSyncDrainPollInterval = 100 * time.Millisecond
+
+// shutdownMetadataSyncer drains the work of the metadata syncer once its
+// informers stopped on termination: it waits up to the shutdown timeout for
+// the PV, PVC and Pod events to be handled and for the running full sync, if
+// any, to complete, so that the CNS operations in progress aren't cut short.
+// It then logs out of vCenter.
+func shutdownMetadataSyncer(ctx context.Context) {
+	log := logger.GetLogger(ctx)
+	timeout := common.GetShutdownTimeout(ctx)
+	log.Infof("Shutting down the metadata syncer, waiting up to %v for the work in progress", timeout)
+	drainCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if pending := resourceEventBus.drain(drainCtx); pending > 0 {
+		log.Warnf("%d PV, PVC and Pod events still pending after %v", pending, timeout)
+	}
+	if !waitForFullSync(drainCtx) {
+		log.Warnf("Full sync still running after %v", timeout)
+	}
+
+	logoutCtx, logoutCancel := context.WithTimeout(ctx, common.VCLogoutTimeout)
+	defer logoutCancel()
+	cnsvsphere.LogoutAllVirtualCenters(logoutCtx)
+	log.Info("Metadata syncer shut down")
+}
+
+// waitForFullSync waits until no full sync is running, or ctx is done. It
+// returns whether no full sync is running.
+func waitForFullSync(ctx context.Context) bool {
+	ticker := syncerClock.NewTicker(fullSyncDrainPollInterval)
+	defer ticker.Stop()
+	for {
+		if !isFullSyncRunning() {
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C():
+		}
+	}
+}
+
+// isFullSyncRunning returns whether a full sync started and didn't end yet.
+func isFullSyncRunning() bool {
+	debugLock.Lock()
+	defer debugLock.Unlock()
+	return !currentFullSyncProgress.StartTime.IsZero() && currentFullSyncProgress.EndTime.IsZero()
+}
@@ -0,0 +1,35 @@
+package syncer
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWaitForFullSync(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	defer resetSyncerHealth()
+	resetSyncerHealth()
+
+	if !waitForFullSync(ctx) {
+		t.Error("expected no full sync to be running before any started")
+	}
+
+	recordFullSyncStart(time.Now())
+	timeoutCtx, timeoutCancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer timeoutCancel()
+	if waitForFullSync(timeoutCtx) {
+		t.Error("expected full sync to be running")
+	}
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		recordFullSyncEnd(time.Now(), nil)
+	}()
+	timeoutCtx, timeoutCancel = context.WithTimeout(ctx, 10*time.Second)
+	defer timeoutCancel()
+	if !waitForFullSync(timeoutCtx) {
+		t.Error("expected full sync to complete")
+	}
+}
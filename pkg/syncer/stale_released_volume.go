@@ -0,0 +1,250 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/davecgh/go-spew/spew"
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+const (
+	// staleReleasedVolumePolicyEvent reports stale Released PVs through
+	// events, escalating each time the PV stays Released twice as long.
+	staleReleasedVolumePolicyEvent = "event"
+	// staleReleasedVolumePolicyCleanup removes the CNS metadata of the PVC
+	// bound to stale Released PVs.
+	staleReleasedVolumePolicyCleanup = "cleanup"
+
+	// event reason for PVs staying in Released phase for too long
+	reasonStaleReleasedVolume = "StaleReleasedVolume"
+	// event reason for PVs whose stale PVC metadata was removed from CNS
+	reasonStaleReleasedVolumeCleaned = "StaleReleasedVolumeCleaned"
+)
+
+// staleReleasedVolumeCleaner periodically looks for vSphere CSI PVs with
+// Retain reclaim policy staying in Released phase for longer than a
+// threshold. Depending on its policy, such PVs are either reported through
+// events, or the CNS metadata of the PVC they were bound to is removed so
+// that CNS does not keep referencing deleted PVCs.
+//
+// As the PV status does not record when the PV entered its phase, the time
+// the PV was first found Released is recorded in annReleasedSince.
+type staleReleasedVolumeCleaner struct {
+	k8sClient clientset.Interface
+	recorder  record.EventRecorder
+	threshold time.Duration
+	policy    string
+	now       func() time.Time
+}
+
+// newStaleReleasedVolumeCleaner returns a staleReleasedVolumeCleaner
+// configured from the environment and recording PV events through the given
+// client.
+func newStaleReleasedVolumeCleaner(ctx context.Context, k8sclient clientset.Interface) *staleReleasedVolumeCleaner {
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(
+		&typedcorev1.EventSinkImpl{
+			Interface: k8sclient.CoreV1().Events(""),
+		},
+	)
+	return &staleReleasedVolumeCleaner{
+		k8sClient: k8sclient,
+		recorder:  eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: csitypes.Name}),
+		threshold: time.Duration(getStaleReleasedVolumeThresholdInMin(ctx)) * time.Minute,
+		policy:    getStaleReleasedVolumePolicy(ctx),
+		now:       time.Now,
+	}
+}
+
+// getStaleReleasedVolumeThresholdInMin returns the duration, in minutes,
+// after which a PV in Released phase is stale.
+func getStaleReleasedVolumeThresholdInMin(ctx context.Context) int {
+	log := logger.GetLogger(ctx)
+	thresholdInMin := defaultStaleReleasedVolumeThresholdInMin
+	if v := os.Getenv("STALE_RELEASED_VOLUME_THRESHOLD_MINUTES"); v != "" {
+		if value, err := strconv.Atoi(v); err == nil && value > 0 {
+			thresholdInMin = value
+			log.Infof("StaleReleasedVolume: StaleReleasedVolume threshold is set to %d minutes", thresholdInMin)
+		} else {
+			log.Warnf("StaleReleasedVolume: StaleReleasedVolume threshold set in env variable "+
+				"STALE_RELEASED_VOLUME_THRESHOLD_MINUTES %s is invalid, will use the default threshold", v)
+		}
+	}
+	return thresholdInMin
+}
+
+// getStaleReleasedVolumePolicy returns the policy applied to stale Released
+// PVs, i.e. staleReleasedVolumePolicyEvent or
+// staleReleasedVolumePolicyCleanup.
+func getStaleReleasedVolumePolicy(ctx context.Context) string {
+	log := logger.GetLogger(ctx)
+	v := os.Getenv("STALE_RELEASED_VOLUME_POLICY")
+	switch v {
+	case "", staleReleasedVolumePolicyEvent:
+		return staleReleasedVolumePolicyEvent
+	case staleReleasedVolumePolicyCleanup:
+		log.Infof("StaleReleasedVolume: StaleReleasedVolume policy is set to %q", v)
+		return staleReleasedVolumePolicyCleanup
+	default:
+		log.Warnf("StaleReleasedVolume: StaleReleasedVolume policy set in env variable "+
+			"STALE_RELEASED_VOLUME_POLICY %s is invalid, will use the %q policy", v, staleReleasedVolumePolicyEvent)
+		return staleReleasedVolumePolicyEvent
+	}
+}
+
+// getStaleReleaseLevel returns the escalation level of a PV which has been
+// in Released phase for the given duration: 0 before the threshold, 1 once
+// the threshold is reached, and one more each time the duration doubles.
+func getStaleReleaseLevel(releasedFor, threshold time.Duration) int {
+	level := 0
+	for limit := threshold; releasedFor >= limit; limit *= 2 {
+		level++
+	}
+	return level
+}
+
+// clean checks all vSphere CSI PVs and applies the policy of the cleaner to
+// the stale Released ones.
+func (c *staleReleasedVolumeCleaner) clean(ctx context.Context, metadataSyncer *metadataSyncInformer) {
+	log := logger.GetLogger(ctx)
+	log.Debug("StaleReleasedVolume: start")
+	allPVs, err := metadataSyncer.pvLister.List(labels.Everything())
+	if err != nil {
+		log.Errorf("StaleReleasedVolume: Failed to get PVs from kubernetes. Err: %+v", err)
+		return
+	}
+	for _, pv := range allPVs {
+		if err := c.processPV(ctx, metadataSyncer, pv); err != nil {
+			log.Errorf("StaleReleasedVolume: Failed to process pv %s. Err: %+v", pv.Name, err)
+		}
+	}
+	log.Debug("StaleReleasedVolume: end")
+}
+
+// processPV tracks how long the given PV has been in Released phase and
+// applies the policy of the cleaner once it is stale. The annotations of PVs
+// leaving Released phase are removed.
+func (c *staleReleasedVolumeCleaner) processPV(ctx context.Context, metadataSyncer *metadataSyncInformer,
+	pv *v1.PersistentVolume) error {
+	log := logger.GetLogger(ctx)
+	if pv.Spec.CSI == nil || pv.Spec.CSI.Driver != csitypes.Name {
+		return nil
+	}
+	if pv.Status.Phase != v1.VolumeReleased || pv.Spec.PersistentVolumeReclaimPolicy != v1.PersistentVolumeReclaimRetain {
+		annotations := make(map[string]interface{})
+		for _, key := range []string{annReleasedSince, annStaleReleaseLevel, annStaleReleaseCleaned} {
+			if _, ok := pv.Annotations[key]; ok {
+				annotations[key] = nil
+			}
+		}
+		if len(annotations) == 0 {
+			return nil
+		}
+		return patchPVAnnotations(ctx, c.k8sClient, pv.Name, annotations)
+	}
+
+	now := c.now()
+	releasedSince, err := time.Parse(time.RFC3339, pv.Annotations[annReleasedSince])
+	if err != nil {
+		log.Debugf("StaleReleasedVolume: pv %s is found in Released phase", pv.Name)
+		return patchPVAnnotations(ctx, c.k8sClient, pv.Name, map[string]interface{}{
+			annReleasedSince: now.UTC().Format(time.RFC3339),
+		})
+	}
+	releasedFor := now.Sub(releasedSince)
+	level := getStaleReleaseLevel(releasedFor, c.threshold)
+	if level == 0 {
+		return nil
+	}
+
+	if c.policy == staleReleasedVolumePolicyCleanup {
+		if _, ok := pv.Annotations[annStaleReleaseCleaned]; ok {
+			return nil
+		}
+		if pv.Spec.ClaimRef != nil {
+			if err := deleteStalePVCMetadata(ctx, metadataSyncer, pv); err != nil {
+				return err
+			}
+		}
+		if err := patchPVAnnotations(ctx, c.k8sClient, pv.Name, map[string]interface{}{
+			annStaleReleaseCleaned: now.UTC().Format(time.RFC3339),
+		}); err != nil {
+			return err
+		}
+		log.Infof("StaleReleasedVolume: removed the PVC metadata of pv %s released for %v from CNS",
+			pv.Name, releasedFor.Round(time.Minute))
+		c.recorder.Eventf(pv, v1.EventTypeNormal, reasonStaleReleasedVolumeCleaned,
+			"PV has been released for %v, the metadata of its former PVC was removed from CNS",
+			releasedFor.Round(time.Minute))
+		return nil
+	}
+
+	if previous, err := strconv.Atoi(pv.Annotations[annStaleReleaseLevel]); err == nil && level <= previous {
+		return nil
+	}
+	if err := patchPVAnnotations(ctx, c.k8sClient, pv.Name, map[string]interface{}{
+		annStaleReleaseLevel: strconv.Itoa(level),
+	}); err != nil {
+		return err
+	}
+	log.Infof("StaleReleasedVolume: pv %s has been released for %v", pv.Name, releasedFor.Round(time.Minute))
+	c.recorder.Eventf(pv, v1.EventTypeWarning, reasonStaleReleasedVolume,
+		"PV has been released for %v with Retain reclaim policy, delete it or its volume if no longer needed",
+		releasedFor.Round(time.Minute))
+	return nil
+}
+
+// deleteStalePVCMetadata removes the metadata of the PVC the given Released
+// PV was bound to from its CNS volume.
+func deleteStalePVCMetadata(ctx context.Context, metadataSyncer *metadataSyncInformer,
+	pv *v1.PersistentVolume) error {
+	log := logger.GetLogger(ctx)
+	pvcMetadata := cnsvsphere.GetCnsKubernetesEntityMetaData(pv.Spec.ClaimRef.Name, nil, true,
+		string(cnstypes.CnsKubernetesEntityTypePVC), pv.Spec.ClaimRef.Namespace,
+		metadataSyncer.configInfo.Cfg.Global.ClusterID, nil)
+	containerCluster := cnsvsphere.GetContainerCluster(metadataSyncer.configInfo.Cfg.Global.ClusterID,
+		metadataSyncer.configInfo.Cfg.VirtualCenter[metadataSyncer.host].User,
+		metadataSyncer.clusterFlavor, metadataSyncer.configInfo.Cfg.Global.ClusterDistribution)
+	updateSpec := &cnstypes.CnsVolumeMetadataUpdateSpec{
+		VolumeId: cnstypes.CnsVolumeId{
+			Id: pv.Spec.CSI.VolumeHandle,
+		},
+		Metadata: cnstypes.CnsVolumeMetadata{
+			ContainerCluster:      containerCluster,
+			ContainerClusterArray: []cnstypes.CnsContainerCluster{containerCluster},
+			EntityMetadata:        []cnstypes.BaseCnsEntityMetadata{pvcMetadata},
+		},
+	}
+	log.Debugf("StaleReleasedVolume: Calling UpdateVolumeMetadata for volume %s with updateSpec: %+v",
+		updateSpec.VolumeId.Id, spew.Sdump(updateSpec))
+	return updateVolumeMetadata(ctx, metadataSyncer, updateSpec)
+}
@@ -0,0 +1,105 @@
+package syncer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+func TestGetStaleReleaseLevel(t *testing.T) {
+	threshold := time.Hour
+	tests := []struct {
+		releasedFor   time.Duration
+		expectedLevel int
+	}{
+		{30 * time.Minute, 0},
+		{time.Hour, 1},
+		{90 * time.Minute, 1},
+		{2 * time.Hour, 2},
+		{5 * time.Hour, 3},
+	}
+	for _, test := range tests {
+		if level := getStaleReleaseLevel(test.releasedFor, threshold); level != test.expectedLevel {
+			t.Errorf("released for %v: expected level %d, got %d", test.releasedFor, test.expectedLevel, level)
+		}
+	}
+}
+
+func TestStaleReleasedVolumeEvents(t *testing.T) {
+	ctx := context.Background()
+	pv := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pv-1"},
+		Spec: v1.PersistentVolumeSpec{
+			PersistentVolumeReclaimPolicy: v1.PersistentVolumeReclaimRetain,
+			PersistentVolumeSource: v1.PersistentVolumeSource{
+				CSI: &v1.CSIPersistentVolumeSource{Driver: csitypes.Name, VolumeHandle: "vol-1"},
+			},
+		},
+		Status: v1.PersistentVolumeStatus{Phase: v1.VolumeReleased},
+	}
+	k8sClient := testclient.NewSimpleClientset(pv)
+	recorder := record.NewFakeRecorder(10)
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	cleaner := &staleReleasedVolumeCleaner{
+		k8sClient: k8sClient,
+		recorder:  recorder,
+		threshold: time.Hour,
+		policy:    staleReleasedVolumePolicyEvent,
+		now:       func() time.Time { return now },
+	}
+	process := func() *v1.PersistentVolume {
+		current, err := k8sClient.CoreV1().PersistentVolumes().Get(ctx, pv.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cleaner.processPV(ctx, nil, current); err != nil {
+			t.Fatal(err)
+		}
+		current, err = k8sClient.CoreV1().PersistentVolumes().Get(ctx, pv.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return current
+	}
+
+	current := process()
+	if current.Annotations[annReleasedSince] != "2022-01-01T00:00:00Z" {
+		t.Fatalf("unexpected released since annotation %q", current.Annotations[annReleasedSince])
+	}
+	now = now.Add(90 * time.Minute)
+	current = process()
+	if current.Annotations[annStaleReleaseLevel] != "1" || len(recorder.Events) != 1 {
+		t.Fatalf("expected level 1 and one event, got level %q and %d events",
+			current.Annotations[annStaleReleaseLevel], len(recorder.Events))
+	}
+	<-recorder.Events
+	process()
+	if len(recorder.Events) != 0 {
+		t.Fatalf("unexpected event %s at the same level", <-recorder.Events)
+	}
+	now = now.Add(time.Hour)
+	current = process()
+	if current.Annotations[annStaleReleaseLevel] != "2" || len(recorder.Events) != 1 {
+		t.Fatalf("expected level 2 and one event, got level %q and %d events",
+			current.Annotations[annStaleReleaseLevel], len(recorder.Events))
+	}
+
+	current.Status.Phase = v1.VolumeAvailable
+	if _, err := k8sClient.CoreV1().PersistentVolumes().Update(ctx, current, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	current = process()
+	if _, ok := current.Annotations[annReleasedSince]; ok {
+		t.Errorf("expected annotations to be removed, got %v", current.Annotations)
+	}
+	if _, ok := current.Annotations[annStaleReleaseLevel]; ok {
+		t.Errorf("expected annotations to be removed, got %v", current.Annotations)
+	}
+}
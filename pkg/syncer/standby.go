@@ -0,0 +1,145 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"time"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	k8s "sigs.k8s.io/vsphere-csi-driver/v2/pkg/kubernetes"
+)
+
+// warmStandbySessionRefreshInterval is the interval at which a warm standby
+// checks its vCenter session, and logs in again if it expired.
+const warmStandbySessionRefreshInterval = 5 * time.Minute
+
+// WarmStandby keeps the informer caches and the vCenter session of a syncer
+// instance warm while it waits to be elected leader, so that it doesn't have
+// to initialize them when it takes over. It only reads from the API server
+// and vCenter.
+type WarmStandby struct {
+	informerManager *k8s.InformerManager
+	// cancel stops warming up.
+	cancel context.CancelFunc
+	// done is closed once warming up stopped.
+	done chan struct{}
+	// vc is the vCenter the standby is connected to. It is nil in guest
+	// clusters, and until the standby connected.
+	vc *cnsvsphere.VirtualCenter
+}
+
+// StartWarmStandby starts warming up the PV, PVC and Pod informer caches of
+// the metadata syncer, and the vCenter session in vanilla and supervisor
+// clusters. TakeOver must be called before leading.
+func StartWarmStandby(ctx context.Context, clusterFlavor cnstypes.CnsClusterFlavor) (*WarmStandby, error) {
+	log := logger.GetLogger(ctx)
+	k8sClient, err := k8s.NewClient(ctx)
+	if err != nil {
+		log.Errorf("Creating Kubernetes client failed. Err: %v", err)
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	standby := &WarmStandby{
+		informerManager: k8s.NewInformer(k8sClient),
+		cancel:          cancel,
+		done:            make(chan struct{}),
+	}
+	go standby.warmUp(ctx, clusterFlavor)
+	return standby, nil
+}
+
+// warmUp syncs the informer caches, then keeps the vCenter session alive
+// until ctx is canceled.
+func (s *WarmStandby) warmUp(ctx context.Context, clusterFlavor cnstypes.CnsClusterFlavor) {
+	log := logger.GetLogger(ctx)
+	defer close(s.done)
+	start := syncerClock.Now()
+	if !s.informerManager.WarmUp(ctx.Done()) {
+		log.Infof("WarmStandby: stopped before the informer caches synced")
+		return
+	}
+	log.Infof("WarmStandby: informer caches synced in %v", syncerClock.Since(start))
+	if clusterFlavor == cnstypes.CnsClusterFlavorGuest {
+		return
+	}
+
+	ticker := syncerClock.NewTicker(warmStandbySessionRefreshInterval)
+	defer ticker.Stop()
+	for {
+		if s.vc == nil {
+			s.vc = s.connect(ctx)
+		} else if err := s.vc.Connect(ctx); err != nil {
+			log.Warnf("WarmStandby: failed to refresh the session of vCenter %q. Err: %v", s.vc.Config.Host, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+// connect returns the vCenter instance shared with the metadata syncer,
+// connected, or nil if it failed to connect.
+func (s *WarmStandby) connect(ctx context.Context) *cnsvsphere.VirtualCenter {
+	log := logger.GetLogger(ctx)
+	configInfo, err := common.InitConfigInfo(ctx)
+	if err != nil {
+		log.Warnf("WarmStandby: failed to initialize the configInfo. Err: %v", err)
+		return nil
+	}
+	vc, err := cnsvsphere.GetVirtualCenterInstance(ctx, configInfo, false)
+	if err != nil {
+		log.Warnf("WarmStandby: failed to connect to vCenter. Err: %v", err)
+		return nil
+	}
+	log.Infof("WarmStandby: connected to vCenter %q", vc.Config.Host)
+	return vc
+}
+
+// TakeOver stops warming up, and validates the warmed up state before this
+// instance starts leading. State found stale is initialized by the metadata
+// syncer, as it is by an instance which wasn't warmed up.
+func (s *WarmStandby) TakeOver(ctx context.Context) {
+	log := logger.GetLogger(ctx)
+	s.cancel()
+	<-s.done
+
+	informersSynced := s.informerManager.WarmedUp()
+	if !informersSynced {
+		log.Warnf("WarmStandby: informer caches not synced, the metadata syncer waits for them to sync")
+	}
+	vcSessionAuthenticated := false
+	if s.vc != nil {
+		status := s.vc.GetSessionStatus(ctx)
+		vcSessionAuthenticated = status.Authenticated
+		if !vcSessionAuthenticated {
+			log.Warnf("WarmStandby: session of vCenter %q not authenticated, logging in again. Err: %s",
+				status.Host, status.Error)
+			if err := s.vc.Connect(ctx); err != nil {
+				log.Errorf("WarmStandby: failed to connect to vCenter %q. Err: %v", status.Host, err)
+			}
+		}
+	}
+	log.Infof("WarmStandby: taking over with informer caches synced: %t, vCenter session authenticated: %t",
+		informersSynced, vcSessionAuthenticated)
+}
@@ -0,0 +1,63 @@
+package syncer
+
+import (
+	"context"
+	"testing"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	v1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common/commonco"
+)
+
+// fssCOCommonInterface is a COCommonInterface with the given features
+// enabled.
+type fssCOCommonInterface struct {
+	commonco.COCommonInterface
+	enabled map[string]bool
+}
+
+func (c *fssCOCommonInterface) IsFSSEnabled(ctx context.Context, featureName string) bool {
+	return c.enabled[featureName]
+}
+
+func TestIsStaticPVCreated(t *testing.T) {
+	ctx := context.Background()
+	newPV := func(phase v1.PersistentVolumePhase, attributes map[string]string) *v1.PersistentVolume {
+		return &v1.PersistentVolume{
+			Spec: v1.PersistentVolumeSpec{
+				PersistentVolumeSource: v1.PersistentVolumeSource{
+					CSI: &v1.CSIPersistentVolumeSource{VolumeHandle: "vol-1", VolumeAttributes: attributes},
+				},
+			},
+			Status: v1.PersistentVolumeStatus{Phase: phase},
+		}
+	}
+	dynamicAttributes := map[string]string{attribCSIProvisionerID: "1234-csi.vsphere.vmware.com"}
+	tests := []struct {
+		name         string
+		registration bool
+		oldPV        *v1.PersistentVolume
+		newPV        *v1.PersistentVolume
+		expected     bool
+	}{
+		{"static PV", false, newPV(v1.VolumePending, nil), newPV(v1.VolumeAvailable, nil), true},
+		{"dynamic PV", false, newPV(v1.VolumePending, dynamicAttributes),
+			newPV(v1.VolumeAvailable, dynamicAttributes), false},
+		{"bound PV", false, newPV(v1.VolumeAvailable, nil), newPV(v1.VolumeBound, nil), false},
+		{"static PV with volume registration", true, newPV(v1.VolumePending, nil),
+			newPV(v1.VolumeAvailable, nil), false},
+	}
+	for _, test := range tests {
+		metadataSyncer := &metadataSyncInformer{
+			clusterFlavor: cnstypes.CnsClusterFlavorVanilla,
+			coCommonInterface: &fssCOCommonInterface{
+				enabled: map[string]bool{common.VanillaVolumeRegistration: test.registration},
+			},
+		}
+		if created := isStaticPVCreated(ctx, metadataSyncer, test.oldPV, test.newPV); created != test.expected {
+			t.Errorf("%s: expected %t, got %t", test.name, test.expected, created)
+		}
+	}
+}
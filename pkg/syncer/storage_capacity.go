@@ -0,0 +1,336 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	storagev1beta1 "k8s.io/api/storage/v1beta1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	cnsconfig "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/config"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+const (
+	// storageCapacityDriverLabel is the label of the CSIStorageCapacity
+	// objects holding the name of the driver they are published for.
+	storageCapacityDriverLabel = "csi.storage.k8s.io/drivername"
+	// storageCapacityManagedByLabel is the label of the CSIStorageCapacity
+	// objects holding the name of the component publishing them.
+	storageCapacityManagedByLabel = "csi.storage.k8s.io/managed-by"
+	// storageCapacityManagedBy is the value of storageCapacityManagedByLabel
+	// on the CSIStorageCapacity objects published by the syncer.
+	storageCapacityManagedBy = "vsphere-csi-syncer"
+	// storageCapacityNamePrefix is the prefix of the names of the
+	// CSIStorageCapacity objects published by the syncer.
+	storageCapacityNamePrefix = "vsphere-csisc-"
+	// envCSINamespace is the environment variable holding the namespace of
+	// the driver.
+	envCSINamespace = "CSI_NAMESPACE"
+)
+
+// storageCapacitySegment is a topology segment of the cluster, i.e. a set of
+// topology labels, and the nodes in it.
+type storageCapacitySegment struct {
+	segment   map[string]string
+	nodeNames []string
+}
+
+// storageCapacityPublisher periodically publishes the capacity available to
+// the StorageClasses of the driver in each topology segment of the cluster
+// as CSIStorageCapacity objects, so that the scheduler doesn't place pods
+// with WaitForFirstConsumer volumes in topology segments whose datastores are
+// exhausted. The capacity available to a StorageClass in a segment is the
+// free space of the datastores shared by the nodes of the segment which are
+// compatible with the StorageClass.
+type storageCapacityPublisher struct {
+	k8sClient   clientset.Interface
+	nodeManager nodeVMGetter
+	// namespace is the namespace of the CSIStorageCapacity objects.
+	namespace string
+}
+
+// newStorageCapacityPublisher returns a storageCapacityPublisher listing the
+// Kubernetes objects through the given client, and looking up the node VMs
+// through the given node manager. The CSIStorageCapacity objects are
+// published in the namespace of the driver.
+func newStorageCapacityPublisher(k8sClient clientset.Interface, nodeManager nodeVMGetter) *storageCapacityPublisher {
+	namespace := os.Getenv(envCSINamespace)
+	if namespace == "" {
+		namespace = cnsconfig.DefaultCSINamespace
+	}
+	return &storageCapacityPublisher{
+		k8sClient:   k8sClient,
+		nodeManager: nodeManager,
+		namespace:   namespace,
+	}
+}
+
+// publish computes the capacity available to the StorageClasses of the
+// driver in each topology segment of the cluster, and creates, updates and
+// deletes the CSIStorageCapacity objects accordingly. The objects are left
+// unchanged if the datastores of the segments can't be retrieved, and the
+// objects of a StorageClass are left unchanged if its compatible datastores
+// can't be determined, so that the scheduler keeps using the last published
+// capacity rather than none.
+func (p *storageCapacityPublisher) publish(ctx context.Context, metadataSyncer *metadataSyncInformer) {
+	log := logger.GetLogger(ctx)
+	scList, err := p.k8sClient.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		log.Errorf("StorageCapacity: Failed to list StorageClasses. Err: %v", err)
+		return
+	}
+	var storageClasses []storagev1.StorageClass
+	for _, sc := range scList.Items {
+		if sc.Provisioner == csitypes.Name {
+			storageClasses = append(storageClasses, sc)
+		}
+	}
+	nodeList, err := p.k8sClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		log.Errorf("StorageCapacity: Failed to list Nodes. Err: %v", err)
+		return
+	}
+	csiNodeList, err := p.k8sClient.StorageV1().CSINodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		log.Errorf("StorageCapacity: Failed to list CSINodes. Err: %v", err)
+		return
+	}
+	segments := getStorageCapacitySegments(nodeList.Items, csiNodeList.Items)
+
+	vc, err := cnsvsphere.GetVirtualCenterInstance(ctx, metadataSyncer.configInfo, false)
+	if err != nil {
+		log.Errorf("StorageCapacity: Failed to get vCenter instance. Err: %v", err)
+		return
+	}
+	var desired []*storagev1beta1.CSIStorageCapacity
+	skippedStorageClasses := make(map[string]bool)
+	for _, segment := range segments {
+		datastores, err := p.getSharedDatastores(ctx, segment.nodeNames)
+		if err != nil {
+			log.Errorf("StorageCapacity: failed to get the datastores shared by the nodes %v of topology "+
+				"segment %v. Err: %v", segment.nodeNames, segment.segment, err)
+			return
+		}
+		for _, sc := range storageClasses {
+			if skippedStorageClasses[sc.Name] {
+				continue
+			}
+			scDatastores, err := common.FilterDatastoresByStorageClassParams(ctx, vc,
+				getStorageClassDatastoreParams(&sc), datastores)
+			if err != nil {
+				log.Errorf("StorageCapacity: failed to get the datastores compatible with StorageClass %s. "+
+					"Err: %v", sc.Name, err)
+				skippedStorageClasses[sc.Name] = true
+				continue
+			}
+			capacity, maximumVolumeSize := common.GetDatastoresCapacity(scDatastores)
+			desired = append(desired, p.newCSIStorageCapacity(sc.Name, segment.segment, capacity,
+				maximumVolumeSize))
+		}
+	}
+	if err := p.reconcile(ctx, desired, skippedStorageClasses); err != nil {
+		log.Errorf("StorageCapacity: failed to publish CSIStorageCapacity objects. Err: %v", err)
+		return
+	}
+	log.Infof("StorageCapacity: published capacity of %d StorageClasses in %d topology segments",
+		len(storageClasses)-len(skippedStorageClasses), len(segments))
+}
+
+// getSharedDatastores returns the datastores shared by the node VMs of the
+// given nodes.
+func (p *storageCapacityPublisher) getSharedDatastores(ctx context.Context,
+	nodeNames []string) ([]*cnsvsphere.DatastoreInfo, error) {
+	var nodeVMs []*cnsvsphere.VirtualMachine
+	for _, nodeName := range nodeNames {
+		nodeVM, err := p.nodeManager.GetNodeByName(ctx, nodeName)
+		if err != nil {
+			return nil, err
+		}
+		nodeVMs = append(nodeVMs, nodeVM)
+	}
+	return cnsvsphere.GetSharedDatastoresForVMs(ctx, nodeVMs)
+}
+
+// reconcile creates and updates the given CSIStorageCapacity objects, and
+// deletes the CSIStorageCapacity objects published by the syncer which
+// aren't among them anymore, except those of the given skipped
+// StorageClasses.
+func (p *storageCapacityPublisher) reconcile(ctx context.Context, desired []*storagev1beta1.CSIStorageCapacity,
+	skippedStorageClasses map[string]bool) error {
+	log := logger.GetLogger(ctx)
+	client := p.k8sClient.StorageV1beta1().CSIStorageCapacities(p.namespace)
+	existingList, err := client.List(ctx, metav1.ListOptions{
+		LabelSelector: storageCapacityDriverLabel + "=" + csitypes.Name + "," +
+			storageCapacityManagedByLabel + "=" + storageCapacityManagedBy,
+	})
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]*storagev1beta1.CSIStorageCapacity)
+	for i := range existingList.Items {
+		existing[existingList.Items[i].Name] = &existingList.Items[i]
+	}
+	for _, capacity := range desired {
+		current, ok := existing[capacity.Name]
+		if !ok {
+			if _, err := client.Create(ctx, capacity, metav1.CreateOptions{}); err != nil {
+				return err
+			}
+			log.Debugf("StorageCapacity: created CSIStorageCapacity %s for StorageClass %s", capacity.Name,
+				capacity.StorageClassName)
+			continue
+		}
+		delete(existing, capacity.Name)
+		if isQuantityEqual(current.Capacity, capacity.Capacity) &&
+			isQuantityEqual(current.MaximumVolumeSize, capacity.MaximumVolumeSize) {
+			continue
+		}
+		updated := current.DeepCopy()
+		updated.Capacity = capacity.Capacity
+		updated.MaximumVolumeSize = capacity.MaximumVolumeSize
+		if _, err := client.Update(ctx, updated, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+		log.Debugf("StorageCapacity: updated CSIStorageCapacity %s for StorageClass %s", capacity.Name,
+			capacity.StorageClassName)
+	}
+	for name, capacity := range existing {
+		if skippedStorageClasses[capacity.StorageClassName] {
+			continue
+		}
+		if err := client.Delete(ctx, name, metav1.DeleteOptions{}); err != nil {
+			return err
+		}
+		log.Debugf("StorageCapacity: deleted CSIStorageCapacity %s of StorageClass %s", name,
+			capacity.StorageClassName)
+	}
+	return nil
+}
+
+// newCSIStorageCapacity returns the CSIStorageCapacity object of the given
+// StorageClass in the given topology segment. Its name is derived from the
+// StorageClass and the segment, so that the object of a StorageClass in a
+// segment keeps its name across publications.
+func (p *storageCapacityPublisher) newCSIStorageCapacity(storageClassName string, segment map[string]string,
+	capacity int64, maximumVolumeSize int64) *storagev1beta1.CSIStorageCapacity {
+	nodeTopology := &metav1.LabelSelector{MatchLabels: segment}
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(storageClassName + "/" + metav1.FormatLabelSelector(nodeTopology)))
+	return &storagev1beta1.CSIStorageCapacity{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s%x", storageCapacityNamePrefix, hash.Sum64()),
+			Namespace: p.namespace,
+			Labels: map[string]string{
+				storageCapacityDriverLabel:    csitypes.Name,
+				storageCapacityManagedByLabel: storageCapacityManagedBy,
+			},
+		},
+		NodeTopology:      nodeTopology,
+		StorageClassName:  storageClassName,
+		Capacity:          resource.NewQuantity(capacity, resource.BinarySI),
+		MaximumVolumeSize: resource.NewQuantity(maximumVolumeSize, resource.BinarySI),
+	}
+}
+
+// getStorageCapacitySegments groups the given nodes by topology segment,
+// i.e. by the values of their labels for the topology keys registered by the
+// driver in their CSINode. Nodes on which the driver isn't registered, or
+// missing a label of a topology key, are left out. In clusters without
+// topology, all the nodes are in a single segment without labels, which
+// matches all the nodes.
+func getStorageCapacitySegments(nodes []v1.Node, csiNodes []storagev1.CSINode) []storageCapacitySegment {
+	topologyKeys := make(map[string][]string)
+	for _, csiNode := range csiNodes {
+		for _, driver := range csiNode.Spec.Drivers {
+			if driver.Name == csitypes.Name {
+				topologyKeys[csiNode.Name] = driver.TopologyKeys
+			}
+		}
+	}
+	segments := make(map[string]*storageCapacitySegment)
+	for _, node := range nodes {
+		keys, ok := topologyKeys[node.Name]
+		if !ok {
+			continue
+		}
+		segment := make(map[string]string)
+		for _, key := range keys {
+			value, ok := node.Labels[key]
+			if !ok {
+				segment = nil
+				break
+			}
+			segment[key] = value
+		}
+		if segment == nil {
+			continue
+		}
+		segmentKey := metav1.FormatLabelSelector(&metav1.LabelSelector{MatchLabels: segment})
+		if _, ok := segments[segmentKey]; !ok {
+			segments[segmentKey] = &storageCapacitySegment{segment: segment}
+		}
+		segments[segmentKey].nodeNames = append(segments[segmentKey].nodeNames, node.Name)
+	}
+	segmentKeys := make([]string, 0, len(segments))
+	for segmentKey := range segments {
+		segmentKeys = append(segmentKeys, segmentKey)
+	}
+	sort.Strings(segmentKeys)
+	result := make([]storageCapacitySegment, 0, len(segments))
+	for _, segmentKey := range segmentKeys {
+		result = append(result, *segments[segmentKey])
+	}
+	return result
+}
+
+// getStorageClassDatastoreParams returns the parameters of the given
+// StorageClass restricting the datastores its volumes can be provisioned on.
+func getStorageClassDatastoreParams(sc *storagev1.StorageClass) *common.StorageClassParams {
+	scParams := &common.StorageClassParams{}
+	for param, value := range sc.Parameters {
+		switch strings.ToLower(param) {
+		case common.AttributeDatastoreURL:
+			scParams.DatastoreURL = value
+		case common.AttributeStoragePolicyName:
+			scParams.StoragePolicyName = value
+		}
+	}
+	return scParams
+}
+
+// isQuantityEqual returns whether the given quantities are both nil, or
+// equal.
+func isQuantityEqual(a, b *resource.Quantity) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Cmp(*b) == 0
+}
@@ -0,0 +1,102 @@
+package syncer
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	storagev1beta1 "k8s.io/api/storage/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+func newCSINodeWithTopologyKeys(name string, topologyKeys ...string) storagev1.CSINode {
+	return storagev1.CSINode{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: storagev1.CSINodeSpec{
+			Drivers: []storagev1.CSINodeDriver{{Name: csitypes.Name, NodeID: name, TopologyKeys: topologyKeys}},
+		},
+	}
+}
+
+func TestGetStorageCapacitySegments(t *testing.T) {
+	nodes := []v1.Node{
+		newNodeInZone("node-a1", "zone-a"),
+		newNodeInZone("node-b1", "zone-b"),
+		newNodeInZone("node-a2", "zone-a"),
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-unlabeled"}},
+		newNodeInZone("node-unregistered", "zone-a"),
+	}
+	csiNodes := []storagev1.CSINode{
+		newCSINodeWithTopologyKeys("node-a1", v1.LabelTopologyZone),
+		newCSINodeWithTopologyKeys("node-b1", v1.LabelTopologyZone),
+		newCSINodeWithTopologyKeys("node-a2", v1.LabelTopologyZone),
+		newCSINodeWithTopologyKeys("node-unlabeled", v1.LabelTopologyZone),
+	}
+	expected := []storageCapacitySegment{
+		{segment: map[string]string{v1.LabelTopologyZone: "zone-a"}, nodeNames: []string{"node-a1", "node-a2"}},
+		{segment: map[string]string{v1.LabelTopologyZone: "zone-b"}, nodeNames: []string{"node-b1"}},
+	}
+	if segments := getStorageCapacitySegments(nodes, csiNodes); !reflect.DeepEqual(segments, expected) {
+		t.Errorf("expected segments %+v, got %+v", expected, segments)
+	}
+
+	// Without topology, all the nodes are in a single segment.
+	csiNodes = []storagev1.CSINode{newCSINodeWithTopologyKeys("node-a1"), newCSINodeWithTopologyKeys("node-b1")}
+	expected = []storageCapacitySegment{{segment: map[string]string{}, nodeNames: []string{"node-a1", "node-b1"}}}
+	if segments := getStorageCapacitySegments(nodes, csiNodes); !reflect.DeepEqual(segments, expected) {
+		t.Errorf("expected segments %+v, got %+v", expected, segments)
+	}
+}
+
+func TestStorageCapacityReconcile(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	k8sClient := fake.NewSimpleClientset()
+	publisher := &storageCapacityPublisher{k8sClient: k8sClient, namespace: "vmware-system-csi"}
+	zoneA := map[string]string{v1.LabelTopologyZone: "zone-a"}
+	zoneB := map[string]string{v1.LabelTopologyZone: "zone-b"}
+
+	desired := []*storagev1beta1.CSIStorageCapacity{
+		publisher.newCSIStorageCapacity("gold", zoneA, 40<<30, 30<<30),
+		publisher.newCSIStorageCapacity("gold", zoneB, 10<<30, 10<<30),
+		publisher.newCSIStorageCapacity("silver", zoneA, 5<<30, 5<<30),
+	}
+	if err := publisher.reconcile(ctx, desired, nil); err != nil {
+		t.Fatalf("failed to reconcile: %v", err)
+	}
+	list, err := k8sClient.StorageV1beta1().CSIStorageCapacities("vmware-system-csi").List(ctx,
+		metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("failed to list CSIStorageCapacities: %v", err)
+	}
+	if len(list.Items) != 3 {
+		t.Fatalf("expected 3 CSIStorageCapacities, got %d", len(list.Items))
+	}
+
+	// The capacity of gold in zone-a changes, gold isn't available in zone-b
+	// anymore, and the capacity of silver can't be computed.
+	desired = []*storagev1beta1.CSIStorageCapacity{publisher.newCSIStorageCapacity("gold", zoneA, 20<<30, 20<<30)}
+	if err := publisher.reconcile(ctx, desired, map[string]bool{"silver": true}); err != nil {
+		t.Fatalf("failed to reconcile: %v", err)
+	}
+	list, err = k8sClient.StorageV1beta1().CSIStorageCapacities("vmware-system-csi").List(ctx,
+		metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("failed to list CSIStorageCapacities: %v", err)
+	}
+	capacities := make(map[string]int64)
+	for _, capacity := range list.Items {
+		capacities[capacity.StorageClassName+"/"+capacity.NodeTopology.MatchLabels[v1.LabelTopologyZone]] =
+			capacity.Capacity.Value()
+	}
+	expected := map[string]int64{"gold/zone-a": 20 << 30, "silver/zone-a": 5 << 30}
+	if !reflect.DeepEqual(capacities, expected) {
+		t.Errorf("expected capacities %v, got %v", expected, capacities)
+	}
+}
@@ -0,0 +1,211 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	pbmtypes "github.com/vmware/govmomi/pbm/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/prometheus"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+const (
+	// event reason for PVCs whose volume is not compliant with its storage
+	// policy
+	reasonStoragePolicyNonCompliant = "StoragePolicyNonCompliant"
+	// event reason for PVCs whose volume is compliant with its storage
+	// policy again
+	reasonStoragePolicyCompliant = "StoragePolicyCompliant"
+)
+
+// storagePolicyComplianceChecker periodically checks the storage policy
+// compliance of the vSphere CSI block volumes through SPBM, so that users
+// learn when changes of vSAN policies leave volumes out of compliance.
+// Volumes turning non-compliant, or compliant again, are reported as events
+// on their PVC and the number of volumes per compliance status is exposed
+// through prometheus.VolumeComplianceGaugeVec.
+type storagePolicyComplianceChecker struct {
+	recorder record.EventRecorder
+	// nonCompliantVolumes holds the volume handles found not compliant by the
+	// last check, so that events are only emitted on compliance changes.
+	nonCompliantVolumes map[string]bool
+}
+
+// newStoragePolicyComplianceChecker returns a storagePolicyComplianceChecker
+// recording PVC events through the given client.
+func newStoragePolicyComplianceChecker(k8sclient clientset.Interface) *storagePolicyComplianceChecker {
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(
+		&typedcorev1.EventSinkImpl{
+			Interface: k8sclient.CoreV1().Events(""),
+		},
+	)
+	return &storagePolicyComplianceChecker{
+		recorder:            eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: csitypes.Name}),
+		nonCompliantVolumes: make(map[string]bool),
+	}
+}
+
+// check fetches the compliance of all bound vSphere CSI block volumes and
+// reports the volumes whose compliance changed since the last check.
+func (c *storagePolicyComplianceChecker) check(ctx context.Context, metadataSyncer *metadataSyncInformer) {
+	log := logger.GetLogger(ctx)
+	log.Debug("StoragePolicyCompliance: start")
+	allPVs, err := metadataSyncer.pvLister.List(labels.Everything())
+	if err != nil {
+		log.Errorf("StoragePolicyCompliance: Failed to get PVs from kubernetes. Err: %+v", err)
+		return
+	}
+	volumeHandleToPvMap := make(map[string]*v1.PersistentVolume)
+	var volumeHandles []string
+	for _, pv := range allPVs {
+		// Compliance is checked for FCDs, i.e. block volumes, only.
+		if pv.Spec.CSI == nil || pv.Spec.CSI.Driver != csitypes.Name || IsMultiAttachAllowed(pv) ||
+			pv.Spec.ClaimRef == nil || pv.Status.Phase != v1.VolumeBound {
+			continue
+		}
+		volumeHandleToPvMap[pv.Spec.CSI.VolumeHandle] = pv
+		volumeHandles = append(volumeHandles, pv.Spec.CSI.VolumeHandle)
+	}
+
+	vc, err := cnsvsphere.GetVirtualCenterInstance(ctx, metadataSyncer.configInfo, false)
+	if err != nil {
+		log.Errorf("StoragePolicyCompliance: Failed to get vCenter instance. Err: %+v", err)
+		return
+	}
+	if err = vc.ConnectPbm(ctx); err != nil {
+		log.Errorf("StoragePolicyCompliance: Failed to connect to PBM. Err: %+v", err)
+		return
+	}
+	var results []pbmtypes.PbmComplianceResult
+	for start := 0; start < len(volumeHandles); start += storagePolicyComplianceBatchSize {
+		end := start + storagePolicyComplianceBatchSize
+		if end > len(volumeHandles) {
+			end = len(volumeHandles)
+		}
+		batchResults, err := vc.PbmFetchComplianceResult(ctx, volumeHandles[start:end])
+		if err != nil {
+			// Keep the state of the last check, so that no event is emitted
+			// for volumes whose compliance couldn't be fetched.
+			log.Warnf("StoragePolicyCompliance: failed to fetch compliance results. Err: %+v. "+
+				"Will retry in next cycle.", err)
+			return
+		}
+		results = append(results, batchResults...)
+	}
+
+	compliant, nonCompliant, unknown := classifyComplianceResults(results)
+	for volumeHandle, result := range nonCompliant {
+		pv, ok := volumeHandleToPvMap[volumeHandle]
+		if !ok || c.nonCompliantVolumes[volumeHandle] {
+			continue
+		}
+		log.Infof("StoragePolicyCompliance: volume %q of pv %s is not compliant with its storage policy",
+			volumeHandle, pv.Name)
+		c.recordPVCEvent(pv, v1.EventTypeWarning, reasonStoragePolicyNonCompliant,
+			"Volume %s is not compliant with its storage policy. Violated capabilities: %s", volumeHandle,
+			strings.Join(getViolatedCapabilities(result), ", "))
+	}
+	for volumeHandle := range compliant {
+		pv, ok := volumeHandleToPvMap[volumeHandle]
+		if !ok || !c.nonCompliantVolumes[volumeHandle] {
+			continue
+		}
+		log.Infof("StoragePolicyCompliance: volume %q of pv %s is compliant with its storage policy again",
+			volumeHandle, pv.Name)
+		c.recordPVCEvent(pv, v1.EventTypeNormal, reasonStoragePolicyCompliant,
+			"Volume %s is compliant with its storage policy", volumeHandle)
+	}
+	// Volumes with unknown compliance keep their last known state.
+	nonCompliantVolumes := make(map[string]bool)
+	for volumeHandle := range c.nonCompliantVolumes {
+		if _, ok := volumeHandleToPvMap[volumeHandle]; ok && unknown[volumeHandle] {
+			nonCompliantVolumes[volumeHandle] = true
+		}
+	}
+	for volumeHandle := range nonCompliant {
+		nonCompliantVolumes[volumeHandle] = true
+	}
+	c.nonCompliantVolumes = nonCompliantVolumes
+
+	prometheus.VolumeComplianceGaugeVec.WithLabelValues(
+		prometheus.PrometheusCompliantVolumes).Set(float64(len(compliant)))
+	prometheus.VolumeComplianceGaugeVec.WithLabelValues(
+		prometheus.PrometheusNonCompliantVolumes).Set(float64(len(nonCompliant)))
+	prometheus.VolumeComplianceGaugeVec.WithLabelValues(
+		prometheus.PrometheusUnknownComplianceVolumes).Set(float64(len(unknown)))
+	log.Debug("StoragePolicyCompliance: end")
+}
+
+// recordPVCEvent records an event on the PVC bound to the given PV.
+func (c *storagePolicyComplianceChecker) recordPVCEvent(pv *v1.PersistentVolume, eventType, reason,
+	messageFmt string, args ...interface{}) {
+	pvcRef := &v1.ObjectReference{
+		Kind:      "PersistentVolumeClaim",
+		Namespace: pv.Spec.ClaimRef.Namespace,
+		Name:      pv.Spec.ClaimRef.Name,
+		UID:       pv.Spec.ClaimRef.UID,
+	}
+	c.recorder.Eventf(pvcRef, eventType, reason, messageFmt, args...)
+}
+
+// classifyComplianceResults splits the given compliance results by volume
+// handle into compliant, non-compliant and unknown ones. Volumes whose
+// compliance is out of date, not applicable or unknown are all considered
+// unknown.
+func classifyComplianceResults(results []pbmtypes.PbmComplianceResult) (map[string]bool,
+	map[string]pbmtypes.PbmComplianceResult, map[string]bool) {
+	compliant := make(map[string]bool)
+	nonCompliant := make(map[string]pbmtypes.PbmComplianceResult)
+	unknown := make(map[string]bool)
+	for _, result := range results {
+		volumeHandle := result.Entity.Key
+		switch result.ComplianceStatus {
+		case string(pbmtypes.PbmComplianceStatusCompliant):
+			compliant[volumeHandle] = true
+		case string(pbmtypes.PbmComplianceStatusNonCompliant):
+			nonCompliant[volumeHandle] = result
+		default:
+			unknown[volumeHandle] = true
+		}
+	}
+	return compliant, nonCompliant, unknown
+}
+
+// getViolatedCapabilities returns the sorted, namespace qualified IDs of the
+// storage policy capabilities violated according to the given compliance
+// result, e.g. "VSAN.hostFailuresToTolerate".
+func getViolatedCapabilities(result pbmtypes.PbmComplianceResult) []string {
+	capabilities := make([]string, 0, len(result.ViolatedPolicies))
+	for _, policy := range result.ViolatedPolicies {
+		capabilities = append(capabilities, policy.ExpectedValue.Id.Namespace+"."+policy.ExpectedValue.Id.Id)
+	}
+	sort.Strings(capabilities)
+	return capabilities
+}
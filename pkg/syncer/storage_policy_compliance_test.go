@@ -0,0 +1,56 @@
+package syncer
+
+import (
+	"reflect"
+	"testing"
+
+	pbmtypes "github.com/vmware/govmomi/pbm/types"
+)
+
+func TestClassifyComplianceResults(t *testing.T) {
+	results := []pbmtypes.PbmComplianceResult{
+		{
+			Entity:           pbmtypes.PbmServerObjectRef{Key: "vol-1"},
+			ComplianceStatus: string(pbmtypes.PbmComplianceStatusCompliant),
+		},
+		{
+			Entity:           pbmtypes.PbmServerObjectRef{Key: "vol-2"},
+			ComplianceStatus: string(pbmtypes.PbmComplianceStatusNonCompliant),
+		},
+		{
+			Entity:           pbmtypes.PbmServerObjectRef{Key: "vol-3"},
+			ComplianceStatus: string(pbmtypes.PbmComplianceStatusOutOfDate),
+		},
+		{
+			Entity:           pbmtypes.PbmServerObjectRef{Key: "vol-4"},
+			ComplianceStatus: string(pbmtypes.PbmComplianceStatusNotApplicable),
+		},
+	}
+	compliant, nonCompliant, unknown := classifyComplianceResults(results)
+	if !reflect.DeepEqual(compliant, map[string]bool{"vol-1": true}) {
+		t.Errorf("unexpected compliant volumes %v", compliant)
+	}
+	if _, ok := nonCompliant["vol-2"]; !ok || len(nonCompliant) != 1 {
+		t.Errorf("unexpected non-compliant volumes %v", nonCompliant)
+	}
+	if !reflect.DeepEqual(unknown, map[string]bool{"vol-3": true, "vol-4": true}) {
+		t.Errorf("unexpected volumes with unknown compliance %v", unknown)
+	}
+}
+
+func TestGetViolatedCapabilities(t *testing.T) {
+	result := pbmtypes.PbmComplianceResult{
+		ViolatedPolicies: []pbmtypes.PbmCompliancePolicyStatus{
+			{ExpectedValue: pbmtypes.PbmCapabilityInstance{
+				Id: pbmtypes.PbmCapabilityMetadataUniqueId{Namespace: "VSAN", Id: "stripeWidth"},
+			}},
+			{ExpectedValue: pbmtypes.PbmCapabilityInstance{
+				Id: pbmtypes.PbmCapabilityMetadataUniqueId{Namespace: "VSAN", Id: "hostFailuresToTolerate"},
+			}},
+		},
+	}
+	expected := []string{"VSAN.hostFailuresToTolerate", "VSAN.stripeWidth"}
+	if capabilities := getViolatedCapabilities(result); !reflect.DeepEqual(capabilities, expected) {
+		t.Errorf("expected %v, got %v", expected, capabilities)
+	}
+}
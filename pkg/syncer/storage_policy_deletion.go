@@ -0,0 +1,157 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/prometheus"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+const (
+	// event reason for StorageClasses whose storage policy doesn't exist in
+	// vCenter
+	reasonStoragePolicyNotFound = "StoragePolicyNotFound"
+	// event reason for StorageClasses whose storage policy exists in vCenter
+	// again
+	reasonStoragePolicyFound = "StoragePolicyFound"
+)
+
+// storagePolicyDeletionChecker periodically checks that the storage policies
+// of the vSphere CSI StorageClasses exist in vCenter, so that users learn
+// when a storage policy used to provision volumes was deleted, before
+// provisioning fails. StorageClasses whose storage policy went missing, or
+// exists again, are reported as events on the StorageClass and their number
+// is exposed through prometheus.StorageClassesMissingPolicyGauge.
+type storagePolicyDeletionChecker struct {
+	k8sClient clientset.Interface
+	recorder  record.EventRecorder
+	// missingPolicies holds the storage policy names of the StorageClasses
+	// found missing by the last check, by StorageClass name, so that events
+	// are only emitted on changes.
+	missingPolicies map[string]string
+}
+
+// newStoragePolicyDeletionChecker returns a storagePolicyDeletionChecker
+// listing StorageClasses and recording their events through the given client.
+func newStoragePolicyDeletionChecker(k8sClient clientset.Interface) *storagePolicyDeletionChecker {
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(
+		&typedcorev1.EventSinkImpl{
+			Interface: k8sClient.CoreV1().Events(""),
+		},
+	)
+	return &storagePolicyDeletionChecker{
+		k8sClient:       k8sClient,
+		recorder:        eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: csitypes.Name}),
+		missingPolicies: make(map[string]string),
+	}
+}
+
+// check fetches the storage policies of vCenter and reports the
+// StorageClasses whose storage policy went missing, or exists again, since
+// the last check.
+func (c *storagePolicyDeletionChecker) check(ctx context.Context, metadataSyncer *metadataSyncInformer) {
+	log := logger.GetLogger(ctx)
+	log.Debug("StoragePolicyDeletion: start")
+	scList, err := c.k8sClient.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		log.Errorf("StoragePolicyDeletion: Failed to list StorageClasses. Err: %+v", err)
+		return
+	}
+	vc, err := cnsvsphere.GetVirtualCenterInstance(ctx, metadataSyncer.configInfo, false)
+	if err != nil {
+		log.Errorf("StoragePolicyDeletion: Failed to get vCenter instance. Err: %+v", err)
+		return
+	}
+	// Keep the state of the last check if the storage policies couldn't be
+	// fetched, so that no event is emitted.
+	storagePolicyIDs, err := vc.GetStoragePolicyIDsByName(ctx)
+	if err != nil {
+		log.Warnf("StoragePolicyDeletion: failed to fetch the storage policies. Err: %+v. "+
+			"Will retry in next cycle.", err)
+		return
+	}
+	c.report(ctx, scList.Items, storagePolicyIDs)
+	log.Debug("StoragePolicyDeletion: end")
+}
+
+// report records events on the given StorageClasses whose storage policy is
+// missing from the given storage policies, or no longer missing, since the
+// last check.
+func (c *storagePolicyDeletionChecker) report(ctx context.Context, storageClasses []storagev1.StorageClass,
+	storagePolicyIDs map[string]string) {
+	log := logger.GetLogger(ctx)
+	availablePolicies := strings.Join(cnsvsphere.GetStoragePolicyNames(storagePolicyIDs), ", ")
+	missingPolicies := make(map[string]string)
+	for i := range storageClasses {
+		sc := &storageClasses[i]
+		policyName := getStorageClassPolicyName(sc)
+		if policyName == "" {
+			continue
+		}
+		if _, ok := storagePolicyIDs[policyName]; !ok {
+			missingPolicies[sc.Name] = policyName
+			if c.missingPolicies[sc.Name] == policyName {
+				continue
+			}
+			log.Warnf("StoragePolicyDeletion: storage policy %q of StorageClass %s does not exist in vCenter",
+				policyName, sc.Name)
+			c.recorder.Eventf(sc, v1.EventTypeWarning, reasonStoragePolicyNotFound,
+				"Storage policy %q does not exist in vCenter. Volumes can't be provisioned with this "+
+					"StorageClass until the storage policy is recreated, or the StorageClass is replaced by one "+
+					"using an available storage policy: %s", policyName, availablePolicies)
+			continue
+		}
+		if _, ok := c.missingPolicies[sc.Name]; ok {
+			log.Infof("StoragePolicyDeletion: storage policy %q of StorageClass %s exists in vCenter again",
+				policyName, sc.Name)
+			c.recorder.Eventf(sc, v1.EventTypeNormal, reasonStoragePolicyFound,
+				"Storage policy %q exists in vCenter", policyName)
+		}
+	}
+	c.missingPolicies = missingPolicies
+	prometheus.StorageClassesMissingPolicyGauge.Set(float64(len(missingPolicies)))
+}
+
+// getStorageClassPolicyName returns the storage policy name of the given
+// StorageClass, or "" if it isn't a vSphere CSI StorageClass with a storage
+// policy name.
+func getStorageClassPolicyName(sc *storagev1.StorageClass) string {
+	if sc.Provisioner != csitypes.Name {
+		return ""
+	}
+	for param, value := range sc.Parameters {
+		if strings.ToLower(param) == common.AttributeStoragePolicyName {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,74 @@
+package syncer
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+func TestStoragePolicyDeletionReport(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	storageClasses := []storagev1.StorageClass{
+		{
+			ObjectMeta:  metav1.ObjectMeta{Name: "gold"},
+			Provisioner: csitypes.Name,
+			Parameters:  map[string]string{"storagePolicyName": "Gold"},
+		},
+		{
+			ObjectMeta:  metav1.ObjectMeta{Name: "silver"},
+			Provisioner: csitypes.Name,
+			Parameters:  map[string]string{"storagepolicyname": "Silver"},
+		},
+		{
+			ObjectMeta:  metav1.ObjectMeta{Name: "default"},
+			Provisioner: csitypes.Name,
+			Parameters:  map[string]string{"datastoreurl": "ds:///vmfs/volumes/vsan:1/"},
+		},
+		{
+			ObjectMeta:  metav1.ObjectMeta{Name: "other"},
+			Provisioner: "other.csi.driver",
+			Parameters:  map[string]string{"storagepolicyname": "Bronze"},
+		},
+	}
+	recorder := record.NewFakeRecorder(10)
+	checker := &storagePolicyDeletionChecker{recorder: recorder, missingPolicies: make(map[string]string)}
+
+	checker.report(ctx, storageClasses, map[string]string{"Silver": "silver-id", "Bronze": "bronze-id"})
+	expected := map[string]string{"gold": "Gold"}
+	if !reflect.DeepEqual(checker.missingPolicies, expected) {
+		t.Errorf("expected missing policies %v, got %v", expected, checker.missingPolicies)
+	}
+	if len(recorder.Events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(recorder.Events))
+	}
+	event := <-recorder.Events
+	expectedEvent := "Warning " + reasonStoragePolicyNotFound + " Storage policy \"Gold\" does not exist in vCenter. " +
+		"Volumes can't be provisioned with this StorageClass until the storage policy is recreated, or the " +
+		"StorageClass is replaced by one using an available storage policy: Bronze, Silver"
+	if event != expectedEvent {
+		t.Errorf("expected event %q, got %q", expectedEvent, event)
+	}
+
+	// No event is emitted while the storage policy stays missing.
+	checker.report(ctx, storageClasses, map[string]string{"Silver": "silver-id"})
+	if len(recorder.Events) != 0 {
+		t.Errorf("expected no event, got %d", len(recorder.Events))
+	}
+
+	checker.report(ctx, storageClasses, map[string]string{"Gold": "gold-id", "Silver": "silver-id"})
+	if len(checker.missingPolicies) != 0 {
+		t.Errorf("expected no missing policy, got %v", checker.missingPolicies)
+	}
+	expectedEvent = "Normal " + reasonStoragePolicyFound + " Storage policy \"Gold\" exists in vCenter"
+	if event := <-recorder.Events; event != expectedEvent {
+		t.Errorf("expected event %q, got %q", expectedEvent, event)
+	}
+}
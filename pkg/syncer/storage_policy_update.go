@@ -0,0 +1,160 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	clientset "k8s.io/client-go/kubernetes"
+
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+// startStoragePolicyUpdate changes the storage policy of the volumes of the
+// PVCs annotated with annRequestedStoragePolicy as they get added or
+// updated. The outcome of the change is reflected in the annotations of the
+// PV of the PVC: annStoragePolicy and annStoragePolicyID on success, and
+// annStoragePolicyUpdateError on failure.
+func startStoragePolicyUpdate(k8sclient clientset.Interface, metadataSyncer *metadataSyncInformer) {
+	resourceEventBus.subscribe("storage-policy-update", []resourceKind{pvcResource}, false,
+		func(event resourceEvent) {
+			if event.eventType == resourceDeleted {
+				return
+			}
+			pvc, ok := event.newObj.(*v1.PersistentVolumeClaim)
+			if !ok || pvc == nil || pvc.Spec.VolumeName == "" || pvc.Status.Phase != v1.ClaimBound {
+				return
+			}
+			if _, requested := pvc.Annotations[annRequestedStoragePolicy]; !requested {
+				return
+			}
+			ctx, log := logger.GetNewContextWithLogger()
+			pv, err := metadataSyncer.pvLister.Get(pvc.Spec.VolumeName)
+			if err != nil {
+				log.Errorf("StoragePolicyUpdate: failed to get pv %s of pvc %s/%s. Err: %v",
+					pvc.Spec.VolumeName, pvc.Namespace, pvc.Name, err)
+				return
+			}
+			policyName, required := isStoragePolicyUpdateRequired(pvc, pv)
+			if !required {
+				return
+			}
+			annotations := make(map[string]interface{})
+			policyID, err := updateVolumeStoragePolicy(ctx, metadataSyncer, pv, policyName)
+			if err != nil {
+				log.Errorf("StoragePolicyUpdate: failed to change the storage policy of pv %s to %q. Err: %v",
+					pv.Name, policyName, err)
+				annotations[annStoragePolicyUpdateError] = fmt.Sprintf("failed to change storage policy to %q: %v",
+					policyName, err)
+			} else {
+				log.Infof("StoragePolicyUpdate: changed the storage policy of pv %s to %q (%s)",
+					pv.Name, policyName, policyID)
+				annotations[annStoragePolicy] = policyName
+				annotations[annStoragePolicyID] = policyID
+				// Remove the error of previous attempts, if any.
+				annotations[annStoragePolicyUpdateError] = nil
+			}
+			if err := patchPVAnnotations(ctx, k8sclient, pv.Name, annotations); err != nil {
+				log.Errorf("StoragePolicyUpdate: failed to update annotations of pv %s. Err: %v", pv.Name, err)
+			}
+		})
+}
+
+// isStoragePolicyUpdateRequired returns the storage policy requested on the
+// given PVC and whether it differs from the storage policy applied to its
+// given PV. Only block volumes provisioned by vSphere CSI are considered.
+func isStoragePolicyUpdateRequired(pvc *v1.PersistentVolumeClaim, pv *v1.PersistentVolume) (string, bool) {
+	policyName := strings.TrimSpace(pvc.Annotations[annRequestedStoragePolicy])
+	if policyName == "" || pv.Spec.CSI == nil || pv.Spec.CSI.Driver != csitypes.Name || IsMultiAttachAllowed(pv) {
+		return "", false
+	}
+	return policyName, pv.Annotations[annStoragePolicy] != policyName
+}
+
+// updateVolumeStoragePolicy applies the storage policy with the given name to
+// the volume of the given PV, and returns the ID of the storage policy.
+func updateVolumeStoragePolicy(ctx context.Context, metadataSyncer *metadataSyncInformer,
+	pv *v1.PersistentVolume, policyName string) (string, error) {
+	log := logger.GetLogger(ctx)
+	volumeID := pv.Spec.CSI.VolumeHandle
+	volumeOperationsLocks.Lock(volumeID)
+	defer volumeOperationsLocks.Unlock(volumeID)
+
+	vc, err := cnsvsphere.GetVirtualCenterInstance(ctx, metadataSyncer.configInfo, false)
+	if err != nil {
+		return "", err
+	}
+	policyID, err := vc.GetStoragePolicyIDByName(ctx, policyName)
+	if err != nil {
+		return "", err
+	}
+	volume, err := common.QueryVolumeByID(ctx, metadataSyncer.volumeManager, volumeID)
+	if err != nil {
+		return "", err
+	}
+	if volume.StoragePolicyId == policyID {
+		log.Infof("StoragePolicyUpdate: volume %q already has storage policy %q", volumeID, policyName)
+		return policyID, nil
+	}
+	datacenters, err := vc.GetDatacenters(ctx)
+	if err != nil {
+		return "", err
+	}
+	var datastore *cnsvsphere.Datastore
+	for _, datacenter := range datacenters {
+		if datastore, err = datacenter.GetDatastoreByURL(ctx, volume.DatastoreUrl); err == nil {
+			break
+		}
+	}
+	if datastore == nil {
+		return "", fmt.Errorf("datastore %q of volume %q not found", volume.DatastoreUrl, volumeID)
+	}
+	task, err := vc.UpdateVolumePolicy(ctx, volumeID, datastore.Reference(), policyID)
+	if err != nil {
+		return "", err
+	}
+	if _, err = task.WaitForResult(ctx); err != nil {
+		return "", err
+	}
+	return policyID, nil
+}
+
+// patchPVAnnotations merges the given annotations into the annotations of
+// the PV with the given name. Annotations with a nil value are removed.
+func patchPVAnnotations(ctx context.Context, k8sclient clientset.Interface, pvName string,
+	annotations map[string]interface{}) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": annotations,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = k8sclient.CoreV1().PersistentVolumes().Patch(ctx, pvName, k8stypes.MergePatchType, patch,
+		metav1.PatchOptions{})
+	return err
+}
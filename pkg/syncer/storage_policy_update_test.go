@@ -0,0 +1,89 @@
+package syncer
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+func TestIsStoragePolicyUpdateRequired(t *testing.T) {
+	newPV := func(appliedPolicy string, accessMode v1.PersistentVolumeAccessMode) *v1.PersistentVolume {
+		pv := &v1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pv-1", Annotations: map[string]string{}},
+			Spec: v1.PersistentVolumeSpec{
+				AccessModes: []v1.PersistentVolumeAccessMode{accessMode},
+				PersistentVolumeSource: v1.PersistentVolumeSource{
+					CSI: &v1.CSIPersistentVolumeSource{Driver: csitypes.Name, VolumeHandle: "vol-1"},
+				},
+			},
+		}
+		if appliedPolicy != "" {
+			pv.Annotations[annStoragePolicy] = appliedPolicy
+		}
+		return pv
+	}
+	newPVC := func(requestedPolicy string) *v1.PersistentVolumeClaim {
+		return &v1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "pvc-1",
+				Annotations: map[string]string{annRequestedStoragePolicy: requestedPolicy},
+			},
+		}
+	}
+	tests := []struct {
+		name             string
+		pvc              *v1.PersistentVolumeClaim
+		pv               *v1.PersistentVolume
+		expectedPolicy   string
+		expectedRequired bool
+	}{
+		{"new policy", newPVC("gold"), newPV("", v1.ReadWriteOnce), "gold", true},
+		{"changed policy", newPVC(" gold "), newPV("silver", v1.ReadWriteOnce), "gold", true},
+		{"applied policy", newPVC("gold"), newPV("gold", v1.ReadWriteOnce), "gold", false},
+		{"empty policy", newPVC(""), newPV("", v1.ReadWriteOnce), "", false},
+		{"file volume", newPVC("gold"), newPV("", v1.ReadWriteMany), "", false},
+	}
+	for _, test := range tests {
+		policy, required := isStoragePolicyUpdateRequired(test.pvc, test.pv)
+		if policy != test.expectedPolicy || required != test.expectedRequired {
+			t.Errorf("%s: expected (%q, %v), got (%q, %v)", test.name, test.expectedPolicy,
+				test.expectedRequired, policy, required)
+		}
+	}
+}
+
+func TestPatchPVAnnotations(t *testing.T) {
+	ctx := context.Background()
+	pv := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "pv-1",
+			Annotations: map[string]string{
+				annStoragePolicyUpdateError: "failed",
+				"foo":                       "bar",
+			},
+		},
+	}
+	k8sclient := testclient.NewSimpleClientset(pv)
+	err := patchPVAnnotations(ctx, k8sclient, pv.Name, map[string]interface{}{
+		annStoragePolicy:            "gold",
+		annStoragePolicyUpdateError: nil,
+	})
+	if err != nil {
+		t.Fatalf("patchPVAnnotations failed: %v", err)
+	}
+	pv, err = k8sclient.CoreV1().PersistentVolumes().Get(ctx, pv.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get pv: %v", err)
+	}
+	if pv.Annotations[annStoragePolicy] != "gold" || pv.Annotations["foo"] != "bar" {
+		t.Errorf("unexpected annotations %v", pv.Annotations)
+	}
+	if _, ok := pv.Annotations[annStoragePolicyUpdateError]; ok {
+		t.Errorf("annotation %s not removed", annStoragePolicyUpdateError)
+	}
+}
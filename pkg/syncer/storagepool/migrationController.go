@@ -128,7 +128,7 @@ func (m *migrationController) migrateVolume(ctx context.Context,
 	pvName, found, err := unstructured.NestedString(pvc.Object, "spec", "volumeName")
 	if !found || err != nil {
 		return false, fmt.Errorf(
-			"could not get PV name bounded to PVC %v. PV info present in pvc resource: %v. Error: %v",
+			"could not get PV name bounded to PVC %v. PV info present in pvc resource: %v. Error: %w",
 			pvcName, found, err)
 	}
 	pv, err := k8sDynamicClient.Resource(pvResource).Get(ctx, pvName, metav1.GetOptions{})
@@ -140,13 +140,13 @@ func (m *migrationController) migrateVolume(ctx context.Context,
 	volumeID, found, err := unstructured.NestedString(pv.Object, "spec", "csi", "volumeHandle")
 	if !found || err != nil {
 		return false, fmt.Errorf(
-			"failed to get volumeID corresponding to pv %v. VolumeID info present in spec: %v. Error: %v",
+			"failed to get volumeID corresponding to pv %v. VolumeID info present in spec: %v. Error: %w",
 			pvName, found, err)
 	}
 	targetSPName, found, err := unstructured.NestedString(pvc.Object, "metadata", "annotations", targetSPAnnotationKey)
 	if !found || err != nil {
 		return false, fmt.Errorf(
-			"failed to get target StoragePool of PVC %v. target SP name present in annotations: %v. Error: %v",
+			"failed to get target StoragePool of PVC %v. target SP name present in annotations: %v. Error: %w",
 			pvcName, found, err)
 	}
 	targetSP, err := k8sDynamicClient.Resource(*spResource).Get(ctx, targetSPName, metav1.GetOptions{})
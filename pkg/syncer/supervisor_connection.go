@@ -0,0 +1,171 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cnsoperatorv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator"
+	cnsvolumemetadatav1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator/cnsvolumemetadata/v1alpha1"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	k8s "sigs.k8s.io/vsphere-csi-driver/v2/pkg/kubernetes"
+)
+
+// newSupervisorClients returns the CNS operator client and the Kubernetes
+// client of the supervisor cluster at the given endpoint, authenticated with
+// the token and the CA certificate of the guest cluster config.
+func newSupervisorClients(ctx context.Context, endpoint string, port string) (client.Client,
+	clientset.Interface, error) {
+	restClientConfig := k8s.GetRestClientConfigForSupervisor(ctx, endpoint, port)
+	if restClientConfig == nil {
+		return nil, nil, errors.New("failed to load the token and CA certificate of the supervisor cluster")
+	}
+	cnsOperatorClient, err := k8s.NewClientForGroup(ctx, restClientConfig, cnsoperatorv1alpha1.GroupName)
+	if err != nil {
+		return nil, nil, err
+	}
+	supervisorClient, err := k8s.NewSupervisorClient(ctx, restClientConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cnsOperatorClient, supervisorClient, nil
+}
+
+// getCnsOperatorClient returns the current CNS operator client of the
+// supervisor cluster.
+func (metadataSyncer *metadataSyncInformer) getCnsOperatorClient() client.Client {
+	metadataSyncer.supervisorClientsLock.RLock()
+	defer metadataSyncer.supervisorClientsLock.RUnlock()
+	return metadataSyncer.cnsOperatorClient
+}
+
+// getSupervisorClient returns the current Kubernetes client of the supervisor
+// cluster.
+func (metadataSyncer *metadataSyncInformer) getSupervisorClient() clientset.Interface {
+	metadataSyncer.supervisorClientsLock.RLock()
+	defer metadataSyncer.supervisorClientsLock.RUnlock()
+	return metadataSyncer.supervisorClient
+}
+
+// setSupervisorClients replaces the supervisor clients of the metadata syncer.
+func (metadataSyncer *metadataSyncInformer) setSupervisorClients(cnsOperatorClient client.Client,
+	supervisorClient clientset.Interface) {
+	metadataSyncer.supervisorClientsLock.Lock()
+	defer metadataSyncer.supervisorClientsLock.Unlock()
+	metadataSyncer.cnsOperatorClient = cnsOperatorClient
+	metadataSyncer.supervisorClient = supervisorClient
+}
+
+// newSupervisorPVCInformer returns the constructor of an informer of the PVCs
+// of the given supervisor namespace, listing and watching them with the client
+// returned by getSupervisorClient on every list and watch.
+func newSupervisorPVCInformer(getSupervisorClient func() clientset.Interface,
+	namespace string) func(clientset.Interface, time.Duration) cache.SharedIndexInformer {
+	return func(_ clientset.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+		return cache.NewSharedIndexInformer(
+			&cache.ListWatch{
+				ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+					return getSupervisorClient().CoreV1().PersistentVolumeClaims(namespace).List(
+						context.TODO(), options)
+				},
+				WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+					return getSupervisorClient().CoreV1().PersistentVolumeClaims(namespace).Watch(
+						context.TODO(), options)
+				},
+			},
+			&v1.PersistentVolumeClaim{},
+			resyncPeriod,
+			cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+		)
+	}
+}
+
+// supervisorConnectionProber probes the connection of the metadata syncer of
+// a guest cluster to the supervisor cluster, and rebuilds its supervisor
+// clients when the probe fails, e.g. after the certificate of the supervisor
+// API server or the token of the guest cluster rotated.
+type supervisorConnectionProber struct {
+	// namespace is the supervisor namespace of the guest cluster.
+	namespace string
+	// newClients returns new supervisor clients.
+	newClients func(ctx context.Context) (client.Client, clientset.Interface, error)
+}
+
+// newSupervisorConnectionProber returns a supervisorConnectionProber
+// rebuilding the supervisor clients from the current guest cluster config.
+func newSupervisorConnectionProber(namespace string) *supervisorConnectionProber {
+	return &supervisorConnectionProber{
+		namespace: namespace,
+		newClients: func(ctx context.Context) (client.Client, clientset.Interface, error) {
+			cfg, err := common.GetConfig(ctx)
+			if err != nil {
+				return nil, nil, err
+			}
+			return newSupervisorClients(ctx, cfg.GC.Endpoint, cfg.GC.Port)
+		},
+	}
+}
+
+// probe checks the connection to the supervisor cluster with the CNS operator
+// client of the metadata syncer, replaces the supervisor clients of the
+// metadata syncer with new ones if it fails and they connect, and records the
+// result for the readiness of the syncer.
+func (p *supervisorConnectionProber) probe(ctx context.Context, metadataSyncer *metadataSyncInformer) {
+	log := logger.GetLogger(ctx)
+	err := p.check(ctx, metadataSyncer.getCnsOperatorClient())
+	if err == nil {
+		recordSupervisorConnection(nil)
+		return
+	}
+	log.Warnf("SupervisorConnection: failed to reach the supervisor cluster, rebuilding the supervisor clients. "+
+		"Err: %v", err)
+	cnsOperatorClient, supervisorClient, err := p.newClients(ctx)
+	if err != nil {
+		log.Errorf("SupervisorConnection: failed to create the supervisor clients. Err: %v", err)
+		recordSupervisorConnection(err)
+		return
+	}
+	if err := p.check(ctx, cnsOperatorClient); err != nil {
+		log.Errorf("SupervisorConnection: failed to reach the supervisor cluster with the new clients. Err: %v",
+			err)
+		recordSupervisorConnection(err)
+		return
+	}
+	metadataSyncer.setSupervisorClients(cnsOperatorClient, supervisorClient)
+	log.Info("SupervisorConnection: reconnected to the supervisor cluster with new supervisor clients")
+	recordSupervisorConnection(nil)
+}
+
+// check lists a CnsVolumeMetadata instance of the supervisor namespace with
+// the given client, as the metadata syncer does.
+func (p *supervisorConnectionProber) check(ctx context.Context, cnsOperatorClient client.Client) error {
+	ctx, cancel := context.WithTimeout(ctx, supervisorConnectionProbeTimeout)
+	defer cancel()
+	return cnsOperatorClient.List(ctx, &cnsvolumemetadatav1alpha1.CnsVolumeMetadataList{},
+		client.InNamespace(p.namespace), client.Limit(1))
+}
@@ -0,0 +1,84 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	clientset "k8s.io/client-go/kubernetes"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	cnsoperatorv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/apis/cnsoperator"
+)
+
+// unreachableClient is a client failing to list objects, as when the
+// certificate of the supervisor API server rotated.
+type unreachableClient struct {
+	client.Client
+}
+
+func (c *unreachableClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	return errors.New("x509: certificate signed by unknown authority")
+}
+
+func TestSupervisorConnectionProbe(t *testing.T) {
+	defer resetSyncerHealth()
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	if err := cnsoperatorv1alpha1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	reachable := fake.NewClientBuilder().WithScheme(s).Build()
+	unreachable := &unreachableClient{Client: reachable}
+	newSupervisorClient := k8sfake.NewSimpleClientset()
+	newReadiness := func() syncerHealth {
+		now := time.Now()
+		recordMetadataSyncerStart()
+		recordInformersSynced(now, 30*time.Minute)
+		recordFullSyncStart(now)
+		recordFullSyncSuccess(context.Background(), now)
+		return getSyncerReadiness(now, nil)
+	}
+
+	for _, test := range []struct {
+		name       string
+		current    client.Client
+		newClient  client.Client
+		newErr     error
+		expected   client.Client
+		connected  bool
+		rebuildsTo clientset.Interface
+	}{
+		{"connected", reachable, nil, nil, reachable, true, nil},
+		{"reconnected with new clients", unreachable, reachable, nil, reachable, true, newSupervisorClient},
+		{"new clients failing", unreachable, unreachable, nil, unreachable, false, nil},
+		{"new clients not created", unreachable, nil, errors.New("no token"), unreachable, false, nil},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			resetSyncerHealth()
+			metadataSyncer := &metadataSyncInformer{cnsOperatorClient: test.current}
+			prober := &supervisorConnectionProber{
+				namespace: "sv-ns",
+				newClients: func(ctx context.Context) (client.Client, clientset.Interface, error) {
+					return test.newClient, newSupervisorClient, test.newErr
+				},
+			}
+			prober.probe(ctx, metadataSyncer)
+			if metadataSyncer.getCnsOperatorClient() != test.expected {
+				t.Errorf("unexpected CNS operator client %v", metadataSyncer.getCnsOperatorClient())
+			}
+			if test.rebuildsTo != nil && metadataSyncer.getSupervisorClient() != test.rebuildsTo {
+				t.Errorf("expected the supervisor client to be replaced")
+			}
+			health := newReadiness()
+			if health.Healthy != test.connected || health.SupervisorConnected == nil ||
+				*health.SupervisorConnected != test.connected {
+				t.Errorf("expected the syncer to be ready %v, got %+v", test.connected, health)
+			}
+		})
+	}
+}
@@ -48,6 +48,90 @@ const (
 	// key for PV to backingDiskObjectId mapping annotation on PVC
 	annPVtoBackingDiskObjectId = "cns.vmware.com/pv-to-backingdiskobjectid-mapping"
 
+	// key for the PV annotation holding the description of the CNS volume
+	annVolumeDescription = "cns.vmware.com/volume-description"
+
+	// key for the PV annotation holding additional labels, as a JSON object,
+	// to be set on the CNS volume
+	annVolumeLabels = "cns.vmware.com/volume-labels"
+
+	// key for the PV annotation requesting to replace the volume handle of
+	// the PV with the given volume ID, e.g. after the FCD of the PV got
+	// re-registered with a new ID
+	annReplaceVolumeHandle = "cns.vmware.com/replace-volume-handle"
+
+	// key for the PV annotation holding the volume handle replaced by
+	// annReplaceVolumeHandle
+	annReplacedVolumeHandle = "cns.vmware.com/replaced-volume-handle"
+
+	// key for the PVC annotation holding the PV being recreated by
+	// annReplaceVolumeHandle, until the PV is created again
+	annRecreatedPV = "cns.vmware.com/recreated-pv"
+
+	// key for the PVC annotation requesting to change the storage policy of
+	// the volume of the PVC to the storage policy with the given name
+	annRequestedStoragePolicy = "cns.vmware.com/requested-storage-policy"
+
+	// key for the PV annotation holding the name of the storage policy
+	// applied to the volume through annRequestedStoragePolicy
+	annStoragePolicy = "cns.vmware.com/storage-policy"
+
+	// key for the PV annotation holding the ID of the storage policy applied
+	// to the volume through annRequestedStoragePolicy
+	annStoragePolicyID = "cns.vmware.com/storage-policy-id"
+
+	// key for the PV annotation holding the error of the last failed storage
+	// policy change requested through annRequestedStoragePolicy
+	annStoragePolicyUpdateError = "cns.vmware.com/storage-policy-update-error"
+
+	// key for the PV annotation holding whether the volume of the PV is
+	// encrypted by its storage policy, i.e. "true" or "false"
+	annVolumeEncrypted = "cns.vmware.com/encrypted"
+
+	// key for the PV annotation holding the ID of the storage policy the
+	// annVolumeEncrypted annotation was determined from
+	annVolumeEncryptionPolicyID = "cns.vmware.com/encryption-policy-id"
+
+	// key for the PV annotation holding, in RFC 3339 format, when the syncer
+	// first found the PV in Released phase
+	annReleasedSince = "cns.vmware.com/released-since"
+
+	// key for the PV annotation holding the escalation level of the last
+	// event reported for a PV staying in Released phase for too long
+	annStaleReleaseLevel = "cns.vmware.com/stale-release-level"
+
+	// key for the PV annotation holding, in RFC 3339 format, when the CNS
+	// metadata of the PVC of a stale Released PV was cleaned up
+	annStaleReleaseCleaned = "cns.vmware.com/stale-release-cleaned"
+
+	// key for the pod annotation holding the total capacity, in bytes, of
+	// the vSphere CSI volumes referenced by the pod
+	annPodProvisionedStorage = "cns.vmware.com/provisioned-storage-bytes"
+
+	// key for the pod annotation holding the total bytes used on the file
+	// systems of the vSphere CSI volumes referenced by the pod
+	annPodConsumedStorage = "cns.vmware.com/consumed-storage-bytes"
+
+	// label key under which the volume description is set on the CNS volume
+	cnsVolumeDescriptionLabel = "cns.vmware.com/description"
+
+	// prefix of the PV labels reflecting the vSphere tags attached to the FCD
+	// backing the PV. The tag category is appended to the prefix and the tag
+	// name is the label value.
+	volumeTagLabelPrefix = "tags.cns.vmware.com/"
+
+	// key for the PV annotation holding, in RFC 3339 format, the time until
+	// which the automated operations of the syncer on the volume are paused
+	annMaintenanceUntil = "cns.vmware.com/maintenance-until"
+
+	// key for the VolumeAttachment annotation holding the name of the failed
+	// node whose VM the volume was detached from by the node failure detacher
+	annDetachedFromFailedNode = "cns.vmware.com/detached-from-failed-node"
+
+	// key for the VolumeAttachment annotation holding the name of the
+	// cordoned node the volume was detached from by the node drain detacher
+	annDetachedFromDrainedNode = "cns.vmware.com/detached-from-drained-node"
+
 	// key for expressing timestamp for volume health annotation
 	annVolumeHealthTS = "volumehealth.storage.kubernetes.io/health-timestamp"
 
@@ -67,6 +151,43 @@ const (
 
 	// default interval for pv to backingdiskobjectid mapping
 	defaultPVtoBackingDiskObjectIdIntervalInMin = 10
+
+	// default interval for recovering failed PVs
+	defaultFailedVolumeRecoveryIntervalInMin = 5
+
+	// default interval for checking the storage policy compliance of volumes
+	defaultStoragePolicyComplianceIntervalInMin = 30
+	// maximum number of volumes whose compliance is fetched from SPBM at once
+	storagePolicyComplianceBatchSize = 100
+
+	// default interval for checking PVs staying in Released phase
+	defaultStaleReleasedVolumeIntervalInMin = 60
+	// default duration after which a PV in Released phase is stale
+	defaultStaleReleasedVolumeThresholdInMin = 7 * 24 * 60
+
+	// default interval for refreshing the storage usage annotations of pods
+	defaultPodVolumeUsageIntervalInMin = 15
+
+	// default interval for reconciling the CnsFileAccessConfig instances of
+	// guest clusters
+	defaultFileAccessConfigIntervalInMin = 5
+
+	// default interval for refreshing the datastore capacity metrics
+	defaultDatastoreCapacityMetricsIntervalInMin = 5
+
+	// default interval for checking that the storage policies of the
+	// StorageClasses exist
+	defaultStoragePolicyDeletionIntervalInMin = 10
+
+	// default interval for checking the version skew between the controller
+	// and the node plugins
+	defaultVersionSkewCheckIntervalInMin = 10
+
+	// default interval for publishing the CSIStorageCapacity objects
+	defaultStorageCapacityIntervalInMin = 5
+
+	// default interval for syncing the node registry with the Nodes
+	defaultNodeRegistrySyncIntervalInMin = 30
 )
 
 var (
@@ -80,10 +201,10 @@ var (
 	// the volume is created in CNS
 	cnsCreationMap map[string]bool
 
-	// Metadata syncer and full sync share a global lock
+	// Metadata syncer and full sync share per volume handle locks
 	// to mitigate race conditions related to
 	// static provisioning of volumes
-	volumeOperationsLock sync.Mutex
+	volumeOperationsLocks = newKeyedMutex()
 )
 
 type (
@@ -111,6 +232,12 @@ type metadataSyncInformer struct {
 	pvcLister          corelisters.PersistentVolumeClaimLister
 	podLister          corelisters.PodLister
 	coCommonInterface  commonco.COCommonInterface
+	// supervisorClientsLock protects cnsOperatorClient and supervisorClient,
+	// which are replaced when the connection to the supervisor cluster is
+	// rebuilt.
+	supervisorClientsLock sync.RWMutex
+	// namespaceScope restricts the namespaces whose PVCs and pods are synced.
+	namespaceScope *syncNamespaceScope
 }
 
 const (
@@ -123,3 +250,61 @@ const (
 	// resizeWorkers represents the number of running worker threads
 	resizeWorkers = 10
 )
+
+const (
+	// default maximum number of volumes detached from a cordoned node by a
+	// CNS task
+	defaultNodeDrainDetachBatchSize = 8
+	// nodeDrainDetachRecheckInterval is the interval at which a cordoned node
+	// with volumes still in use is checked again
+	nodeDrainDetachRecheckInterval = 10 * time.Second
+	// nodeDrainDetachResyncPeriod is the interval at which the cordoned nodes
+	// are listed, to check the nodes cordoned after their pods were deleted
+	nodeDrainDetachResyncPeriod = time.Minute
+	// nodeDrainDetachRetryIntervalStart is the start retry interval of the
+	// node drain detacher
+	nodeDrainDetachRetryIntervalStart = time.Second
+	// nodeDrainDetachRetryIntervalMax is the max retry interval of the node
+	// drain detacher
+	nodeDrainDetachRetryIntervalMax = 5 * time.Minute
+	// nodeDrainDetachWorkers is the number of nodes checked concurrently by
+	// the node drain detacher
+	nodeDrainDetachWorkers = 4
+)
+
+const (
+	// default duration for which a node is NotReady before the volumes
+	// attached to its powered off VM are detached
+	defaultNodeFailureDetachTimeoutInMin = 3
+	// nodeFailureDetachInterval is the interval at which the NotReady nodes
+	// are checked
+	nodeFailureDetachInterval = time.Minute
+)
+
+// vmMigrationWatchRetryInterval is the interval at which the watch of the VM
+// migration events is retried after it failed
+const vmMigrationWatchRetryInterval = time.Minute
+
+const (
+	// datastoreAccessibilityRefreshDelay is the delay after a datastore
+	// accessibility event before the volume health and the storage capacity
+	// are refreshed, so that the events of the hosts losing or regaining the
+	// same datastore are handled at once
+	datastoreAccessibilityRefreshDelay = 10 * time.Second
+	// datastoreAccessibilityWatchRetryInterval is the interval at which the
+	// watch of the datastore accessibility events is retried after it failed
+	datastoreAccessibilityWatchRetryInterval = time.Minute
+)
+
+const (
+	// supervisorConnectionProbeInterval is the interval at which the
+	// connection of a guest cluster to the supervisor cluster is probed
+	supervisorConnectionProbeInterval = time.Minute
+	// supervisorConnectionProbeTimeout is the timeout of a probe of the
+	// connection to the supervisor cluster
+	supervisorConnectionProbeTimeout = 10 * time.Second
+)
+
+// maximum number of volumes whose metadata is updated by a single CNS
+// request during full sync
+const fullSyncUpdateBatchSize = 100
@@ -2,6 +2,7 @@ package syncer
 
 import (
 	"context"
+	"encoding/json"
 
 	"google.golang.org/grpc/codes"
 	"k8s.io/client-go/tools/cache"
@@ -45,6 +46,40 @@ func getPVsInBoundAvailableOrReleased(ctx context.Context,
 	return pvsInDesiredState, nil
 }
 
+// GetPVMetadataLabels returns the labels to be set on the CNS volume for the
+// given PV. Besides the PV labels, this includes the labels and description
+// set through the annVolumeLabels and annVolumeDescription annotations. PV
+// labels take precedence over the labels from the annotation.
+func GetPVMetadataLabels(ctx context.Context, pv *v1.PersistentVolume) map[string]string {
+	log := logger.GetLogger(ctx)
+	annotations := pv.GetAnnotations()
+	volumeLabels, hasLabels := annotations[annVolumeLabels]
+	description, hasDescription := annotations[annVolumeDescription]
+	datastoreURL, hasDatastoreURL := annotations[common.AnnVolumeDatastoreURL]
+	if !hasLabels && !hasDescription && !hasDatastoreURL {
+		return pv.GetLabels()
+	}
+	metadataLabels := make(map[string]string)
+	if hasLabels {
+		if err := json.Unmarshal([]byte(volumeLabels), &metadataLabels); err != nil {
+			log.Warnf("ignoring invalid value %q of annotation %q on pv %s. Err: %v",
+				volumeLabels, annVolumeLabels, pv.Name, err)
+			metadataLabels = make(map[string]string)
+		}
+	}
+	if hasDescription {
+		metadataLabels[cnsVolumeDescriptionLabel] = description
+	}
+	if hasDatastoreURL {
+		// Reflects the datastore a volume was relocated to by CnsVolumeRelocate.
+		metadataLabels[common.AnnVolumeDatastoreURL] = datastoreURL
+	}
+	for key, value := range pv.GetLabels() {
+		metadataLabels[key] = value
+	}
+	return metadataLabels
+}
+
 // getBoundPVs is a helper function for VolumeHealthStatus feature and returns
 // PVs in Bound state.
 func getBoundPVs(ctx context.Context, metadataSyncer *metadataSyncInformer) ([]*v1.PersistentVolume, error) {
@@ -287,6 +322,21 @@ func IsMultiAttachAllowed(pv *v1.PersistentVolume) bool {
 	return false
 }
 
+// deleteVolume removes the given volume from CNS. The backing disk is only
+// deleted if deleteDisk is set and disk deletion by the syncer isn't
+// forbidden by the config.
+func deleteVolume(ctx context.Context, metadataSyncer *metadataSyncInformer, volumeID string,
+	deleteDisk bool) (string, error) {
+	log := logger.GetLogger(ctx)
+	if deleteDisk && metadataSyncer.configInfo.Cfg.Global.ForbidDiskDeletionBySyncer {
+		log.Infof("Disk deletion by the syncer is forbidden for volume %q. Deleting volume without its disk.",
+			volumeID)
+		deleteDisk = false
+	}
+	metadataCache.forget(volumeID)
+	return metadataSyncer.volumeManager.DeleteVolume(ctx, volumeID, deleteDisk)
+}
+
 // initVolumeMigrationService is a helper method to initialize
 // volumeMigrationService in Syncer.
 func initVolumeMigrationService(ctx context.Context, metadataSyncer *metadataSyncInformer) error {
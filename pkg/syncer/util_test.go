@@ -8,8 +8,10 @@ import (
 
 	"github.com/google/uuid"
 
+	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
 	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/syncer/k8scloudoperator"
 )
 
@@ -180,3 +182,38 @@ func TestGetSCNameFromPVC(t *testing.T) {
 	}
 	t.Log("testGetSCNameFromPVC: end")
 }
+
+func TestGetPVMetadataLabels(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pv := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "test-pv",
+			Labels: map[string]string{"app": "db"},
+			Annotations: map[string]string{
+				annVolumeLabels:      `{"team": "storage", "app": "overridden"}`,
+				annVolumeDescription: "database volume",
+			},
+		},
+	}
+	expectedLabels := map[string]string{
+		"app":                     "db",
+		"team":                    "storage",
+		cnsVolumeDescriptionLabel: "database volume",
+	}
+	if labels := GetPVMetadataLabels(ctx, pv); !reflect.DeepEqual(labels, expectedLabels) {
+		t.Errorf("expected labels %v, got %v", expectedLabels, labels)
+	}
+
+	pv.Annotations = map[string]string{annVolumeLabels: "invalid"}
+	expectedLabels = map[string]string{"app": "db"}
+	if labels := GetPVMetadataLabels(ctx, pv); !reflect.DeepEqual(labels, expectedLabels) {
+		t.Errorf("expected labels %v, got %v", expectedLabels, labels)
+	}
+
+	pv.Annotations = map[string]string{common.AnnVolumeDatastoreURL: "ds:///vmfs/volumes/vsan:1/"}
+	expectedLabels = map[string]string{"app": "db", common.AnnVolumeDatastoreURL: "ds:///vmfs/volumes/vsan:1/"}
+	if labels := GetPVMetadataLabels(ctx, pv); !reflect.DeepEqual(labels, expectedLabels) {
+		t.Errorf("expected labels %v, got %v", expectedLabels, labels)
+	}
+}
@@ -0,0 +1,221 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/prometheus"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+const (
+	// event reason for nodes whose node plugin version isn't supported by the
+	// controller
+	reasonUnsupportedVersionSkew = "UnsupportedVersionSkew"
+	// event reason for nodes whose node plugin version is supported by the
+	// controller again
+	reasonSupportedVersionSkew = "SupportedVersionSkew"
+	// maxNodePluginMinorVersionSkew is the number of minor versions the node
+	// plugins may lag behind the controller. Node plugins newer than the
+	// controller aren't supported.
+	maxNodePluginMinorVersionSkew = 1
+	// cnsCRDGroup is the API group of the CRDs of the driver.
+	cnsCRDGroup = "cns.vmware.com"
+	// cnsCRDVersion is the version of the CRDs of the driver used by the
+	// controller and the syncer.
+	cnsCRDVersion = "v1alpha1"
+)
+
+// versionSkewChecker periodically checks that the versions of the node
+// plugins, published in the common.NodeConditionNodePluginVersion Node
+// condition, and of the cns.vmware.com CRDs are supported by the controller,
+// so that mixed-version rollouts are reported before they fail volume
+// operations. Nodes whose node plugin version became unsupported, or is
+// supported again, are reported as events on the Node. The number of
+// unsupported node plugins and CRDs is exposed through
+// prometheus.UnsupportedVersionSkewGauge.
+type versionSkewChecker struct {
+	k8sClient           clientset.Interface
+	apiextensionsClient apiextensionsclientset.Interface
+	recorder            record.EventRecorder
+	// skewedNodes holds the node plugin versions of the nodes found
+	// unsupported by the last check, by node name, so that events are only
+	// emitted on changes.
+	skewedNodes map[string]string
+}
+
+// newVersionSkewChecker returns a versionSkewChecker listing Nodes and CRDs
+// and recording their events through the given clients.
+func newVersionSkewChecker(k8sClient clientset.Interface,
+	apiextensionsClient apiextensionsclientset.Interface) *versionSkewChecker {
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(
+		&typedcorev1.EventSinkImpl{
+			Interface: k8sClient.CoreV1().Events(""),
+		},
+	)
+	return &versionSkewChecker{
+		k8sClient:           k8sClient,
+		apiextensionsClient: apiextensionsClient,
+		recorder:            eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: csitypes.Name}),
+		skewedNodes:         make(map[string]string),
+	}
+}
+
+// check compares the versions of the node plugins and the CRDs with the
+// version of the controller, and reports the unsupported ones.
+func (c *versionSkewChecker) check(ctx context.Context, controllerVersion string) {
+	log := logger.GetLogger(ctx)
+	log.Debug("VersionSkew: start")
+	nodeList, err := c.k8sClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		log.Errorf("VersionSkew: Failed to list Nodes. Err: %+v", err)
+	} else {
+		c.reportNodes(ctx, controllerVersion, nodeList.Items)
+	}
+	crdList, err := c.apiextensionsClient.ApiextensionsV1().CustomResourceDefinitions().List(ctx,
+		metav1.ListOptions{})
+	if err != nil {
+		log.Errorf("VersionSkew: Failed to list CRDs. Err: %+v", err)
+	} else {
+		reportCRDs(ctx, crdList.Items)
+	}
+	log.Debug("VersionSkew: end")
+}
+
+// reportNodes records events on the given Nodes whose node plugin version
+// became unsupported by the given controller version, or supported again,
+// since the last check. Nodes whose node plugin didn't publish its version
+// are skipped.
+func (c *versionSkewChecker) reportNodes(ctx context.Context, controllerVersion string, nodes []v1.Node) {
+	log := logger.GetLogger(ctx)
+	skewedNodes := make(map[string]string)
+	for i := range nodes {
+		node := &nodes[i]
+		nodePluginVersion, ok := getNodePluginVersion(node)
+		if !ok {
+			log.Debugf("VersionSkew: node plugin of node %s didn't publish its version", node.Name)
+			continue
+		}
+		if err := checkVersionSkew(controllerVersion, nodePluginVersion); err != nil {
+			skewedNodes[node.Name] = nodePluginVersion
+			if previous, ok := c.skewedNodes[node.Name]; ok && previous == nodePluginVersion {
+				continue
+			}
+			log.Errorf("VersionSkew: unsupported version skew on node %s: %v. Volume operations on the "+
+				"node may fail until the node plugin is upgraded to the version of the controller",
+				node.Name, err)
+			c.recorder.Eventf(node, v1.EventTypeWarning, reasonUnsupportedVersionSkew,
+				"vSphere CSI node plugin %s isn't supported by controller %s: %v. Upgrade the node plugin "+
+					"to the version of the controller", nodePluginVersion, controllerVersion, err)
+			continue
+		}
+		if _, ok := c.skewedNodes[node.Name]; ok {
+			log.Infof("VersionSkew: node plugin %s of node %s is supported by controller %s again",
+				nodePluginVersion, node.Name, controllerVersion)
+			c.recorder.Eventf(node, v1.EventTypeNormal, reasonSupportedVersionSkew,
+				"vSphere CSI node plugin %s is supported by controller %s", nodePluginVersion, controllerVersion)
+		}
+	}
+	c.skewedNodes = skewedNodes
+	prometheus.UnsupportedVersionSkewGauge.WithLabelValues("node").Set(float64(len(skewedNodes)))
+}
+
+// reportCRDs logs the given cns.vmware.com CRDs which don't serve the
+// version used by the controller, e.g. because they were replaced by a newer
+// release of the driver.
+func reportCRDs(ctx context.Context, crds []apiextensionsv1.CustomResourceDefinition) {
+	log := logger.GetLogger(ctx)
+	unsupported := 0
+	for _, crd := range crds {
+		if crd.Spec.Group != cnsCRDGroup {
+			continue
+		}
+		if !isCRDVersionServed(&crd, cnsCRDVersion) {
+			unsupported++
+			log.Errorf("VersionSkew: CRD %s doesn't serve version %s used by the controller. Custom "+
+				"resources of the CRD can't be read until the CRD is restored by the driver", crd.Name,
+				cnsCRDVersion)
+		}
+	}
+	prometheus.UnsupportedVersionSkewGauge.WithLabelValues("crd").Set(float64(unsupported))
+}
+
+// getNodePluginVersion returns the version of the node plugin published in
+// the Node condition of the given node, and whether it is published.
+func getNodePluginVersion(node *v1.Node) (string, bool) {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == common.NodeConditionNodePluginVersion {
+			return condition.Message, true
+		}
+	}
+	return "", false
+}
+
+// isCRDVersionServed returns whether the given CRD serves the given version.
+func isCRDVersionServed(crd *apiextensionsv1.CustomResourceDefinition, version string) bool {
+	for _, crdVersion := range crd.Spec.Versions {
+		if crdVersion.Name == version {
+			return crdVersion.Served
+		}
+	}
+	return false
+}
+
+// checkVersionSkew returns an error describing why the given node plugin
+// version isn't supported by the given controller version, or nil if it is.
+// Node plugins of the same major version, lagging behind the controller by
+// at most maxNodePluginMinorVersionSkew minor versions, are supported.
+func checkVersionSkew(controllerVersion, nodePluginVersion string) error {
+	if controllerVersion == nodePluginVersion {
+		return nil
+	}
+	controller, err := utilversion.ParseGeneric(controllerVersion)
+	if err != nil {
+		return fmt.Errorf("cannot compare controller version %q: %v", controllerVersion, err)
+	}
+	nodePlugin, err := utilversion.ParseGeneric(nodePluginVersion)
+	if err != nil {
+		return fmt.Errorf("cannot compare node plugin version %q: %v", nodePluginVersion, err)
+	}
+	if nodePlugin.Major() != controller.Major() {
+		return fmt.Errorf("major version %d of the node plugin differs from major version %d of the controller",
+			nodePlugin.Major(), controller.Major())
+	}
+	if nodePlugin.Minor() > controller.Minor() {
+		return fmt.Errorf("node plugin is newer than the controller")
+	}
+	if controller.Minor()-nodePlugin.Minor() > maxNodePluginMinorVersionSkew {
+		return fmt.Errorf("node plugin is more than %d minor version behind the controller",
+			maxNodePluginMinorVersionSkew)
+	}
+	return nil
+}
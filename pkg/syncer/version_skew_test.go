@@ -0,0 +1,117 @@
+package syncer
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+)
+
+func TestCheckVersionSkew(t *testing.T) {
+	tests := []struct {
+		controllerVersion string
+		nodePluginVersion string
+		supported         bool
+	}{
+		{controllerVersion: "v2.6.0", nodePluginVersion: "v2.6.0", supported: true},
+		{controllerVersion: "v2.6.1", nodePluginVersion: "v2.6.0", supported: true},
+		{controllerVersion: "v2.6.0", nodePluginVersion: "v2.6.2", supported: true},
+		{controllerVersion: "v2.6.0", nodePluginVersion: "v2.5.3", supported: true},
+		{controllerVersion: "v2.6.0-rc.1", nodePluginVersion: "v2.5.0", supported: true},
+		{controllerVersion: "v2.6.0", nodePluginVersion: "v2.4.0", supported: false},
+		{controllerVersion: "v2.5.0", nodePluginVersion: "v2.6.0", supported: false},
+		{controllerVersion: "v3.0.0", nodePluginVersion: "v2.6.0", supported: false},
+		{controllerVersion: "dev", nodePluginVersion: "dev", supported: true},
+		{controllerVersion: "v2.6.0", nodePluginVersion: "dev", supported: false},
+	}
+	for _, test := range tests {
+		err := checkVersionSkew(test.controllerVersion, test.nodePluginVersion)
+		if (err == nil) != test.supported {
+			t.Errorf("expected node plugin %s to be supported by controller %s: %t, got error %v",
+				test.nodePluginVersion, test.controllerVersion, test.supported, err)
+		}
+	}
+}
+
+func newNodeWithPluginVersion(name string, version string) v1.Node {
+	node := v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	if version != "" {
+		node.Status.Conditions = []v1.NodeCondition{
+			{Type: v1.NodeReady, Status: v1.ConditionTrue},
+			{Type: common.NodeConditionNodePluginVersion, Status: v1.ConditionTrue, Message: version},
+		}
+	}
+	return node
+}
+
+func TestVersionSkewReportNodes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	nodes := []v1.Node{
+		newNodeWithPluginVersion("node-1", "v2.6.0"),
+		newNodeWithPluginVersion("node-2", "v2.4.0"),
+		newNodeWithPluginVersion("node-3", ""),
+	}
+	recorder := record.NewFakeRecorder(10)
+	checker := &versionSkewChecker{recorder: recorder, skewedNodes: make(map[string]string)}
+
+	checker.reportNodes(ctx, "v2.6.0", nodes)
+	expected := map[string]string{"node-2": "v2.4.0"}
+	if !reflect.DeepEqual(checker.skewedNodes, expected) {
+		t.Errorf("expected skewed nodes %v, got %v", expected, checker.skewedNodes)
+	}
+	if len(recorder.Events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(recorder.Events))
+	}
+	event := <-recorder.Events
+	if !strings.HasPrefix(event, "Warning "+reasonUnsupportedVersionSkew+
+		" vSphere CSI node plugin v2.4.0 isn't supported by controller v2.6.0") {
+		t.Errorf("unexpected event %q", event)
+	}
+
+	// No event is emitted while the skew stays unsupported.
+	checker.reportNodes(ctx, "v2.6.0", nodes)
+	if len(recorder.Events) != 0 {
+		t.Errorf("expected no event, got %d", len(recorder.Events))
+	}
+
+	nodes[1] = newNodeWithPluginVersion("node-2", "v2.6.0")
+	checker.reportNodes(ctx, "v2.6.0", nodes)
+	if len(checker.skewedNodes) != 0 {
+		t.Errorf("expected no skewed node, got %v", checker.skewedNodes)
+	}
+	expectedEvent := "Normal " + reasonSupportedVersionSkew +
+		" vSphere CSI node plugin v2.6.0 is supported by controller v2.6.0"
+	if event := <-recorder.Events; event != expectedEvent {
+		t.Errorf("expected event %q, got %q", expectedEvent, event)
+	}
+}
+
+func TestIsCRDVersionServed(t *testing.T) {
+	crd := &apiextensionsv1.CustomResourceDefinition{
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: cnsCRDGroup,
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{Name: "v1alpha1", Served: false},
+				{Name: "v1beta1", Served: true, Storage: true},
+			},
+		},
+	}
+	if isCRDVersionServed(crd, "v1alpha1") {
+		t.Error("expected v1alpha1 not to be served")
+	}
+	if !isCRDVersionServed(crd, "v1beta1") {
+		t.Error("expected v1beta1 to be served")
+	}
+	if isCRDVersionServed(crd, "v1") {
+		t.Error("expected v1 not to be served")
+	}
+}
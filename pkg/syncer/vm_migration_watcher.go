@@ -0,0 +1,240 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"fmt"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	vimtypes "github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+	csinodetopologyv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/internalapis/csinodetopology/v1alpha1"
+	k8s "sigs.k8s.io/vsphere-csi-driver/v2/pkg/kubernetes"
+)
+
+// migratedNodeResolver resolves the nodes of the migrated VMs.
+type migratedNodeResolver interface {
+	GetAllNodes(ctx context.Context) ([]*cnsvsphere.VirtualMachine, error)
+	GetNodeNameByUUID(ctx context.Context, nodeUUID string) (string, error)
+}
+
+// vmMigrationWatcher watches the VM migration events of vCenter, and updates
+// the datastore annotation of the volumes attached to the migrated node VMs,
+// which the metadata syncer reflects in their CNS metadata, and requests the
+// topology of the migrated node VMs to be discovered again, without waiting
+// for the next full sync.
+type vmMigrationWatcher struct {
+	k8sClient   clientset.Interface
+	nodeManager migratedNodeResolver
+	// crClient is the client of the CSINodeTopology instances. It is nil if
+	// the topology of the nodes isn't discovered by the syncer.
+	crClient       client.Client
+	metadataSyncer *metadataSyncInformer
+}
+
+// startVMMigrationWatcher watches the VM migration events of vCenter until the
+// given context is canceled, retrying when the watch fails.
+func startVMMigrationWatcher(ctx context.Context, k8sClient clientset.Interface, nodeManager migratedNodeResolver,
+	metadataSyncer *metadataSyncInformer) error {
+	log := logger.GetLogger(ctx)
+	watcher := &vmMigrationWatcher{
+		k8sClient:      k8sClient,
+		nodeManager:    nodeManager,
+		metadataSyncer: metadataSyncer,
+	}
+	if metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.ImprovedVolumeTopology) {
+		restConfig, err := k8s.GetKubeConfig(ctx)
+		if err != nil {
+			log.Errorf("VMMigrationWatcher: failed to get Kubernetes config. Err: %v", err)
+			return err
+		}
+		watcher.crClient, err = k8s.NewClientForGroup(ctx, restConfig, csinodetopologyv1alpha1.GroupName)
+		if err != nil {
+			log.Errorf("VMMigrationWatcher: failed to create CSINodeTopology client. Err: %v", err)
+			return err
+		}
+	}
+	go func() {
+		for {
+			vc, err := cnsvsphere.GetVirtualCenterInstance(ctx, metadataSyncer.configInfo, false)
+			if err == nil {
+				log.Info("VMMigrationWatcher: watching the VM migration events")
+				err = cnsvsphere.WatchVMMigrationEvents(ctx, vc, func(vmRef vimtypes.ManagedObjectReference) {
+					ctx, _ := logger.GetNewContextWithLogger()
+					watcher.vmMigrated(ctx, vmRef)
+				})
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			log.Warnf("VMMigrationWatcher: failed to watch the VM migration events, retrying in %v. Err: %v",
+				vmMigrationWatchRetryInterval, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-syncerClock.After(vmMigrationWatchRetryInterval):
+			}
+		}
+	}()
+	return nil
+}
+
+// vmMigrated updates the volumes and the topology of the node of the given
+// migrated VM. VMs which aren't nodes are ignored.
+func (w *vmMigrationWatcher) vmMigrated(ctx context.Context, vmRef vimtypes.ManagedObjectReference) {
+	log := logger.GetLogger(ctx)
+	vms, err := w.nodeManager.GetAllNodes(ctx)
+	if err != nil {
+		log.Errorf("VMMigrationWatcher: failed to get the node VMs. Err: %v", err)
+		return
+	}
+	var nodeName string
+	for _, vm := range vms {
+		if vm.Reference() == vmRef {
+			if nodeName, err = w.nodeManager.GetNodeNameByUUID(ctx, vm.UUID); err != nil {
+				log.Errorf("VMMigrationWatcher: failed to get the node of VM %v. Err: %v", vm, err)
+				return
+			}
+			break
+		}
+	}
+	if nodeName == "" {
+		log.Debugf("VMMigrationWatcher: ignoring the migration of VM %v which isn't a node", vmRef)
+		return
+	}
+	log.Infof("VMMigrationWatcher: VM %v of node %q was migrated", vmRef, nodeName)
+	if err := w.updateVolumeDatastores(ctx, nodeName); err != nil {
+		log.Errorf("VMMigrationWatcher: failed to update the datastore of the volumes of node %q. Err: %v",
+			nodeName, err)
+	}
+	if w.crClient != nil {
+		if err := w.rediscoverNodeTopology(ctx, nodeName); err != nil {
+			log.Errorf("VMMigrationWatcher: failed to request the topology of node %q to be discovered. Err: %v",
+				nodeName, err)
+		}
+	}
+}
+
+// updateVolumeDatastores sets the datastore annotation of the PVs of the
+// volumes attached to the given node to the datastore of the volumes in CNS.
+func (w *vmMigrationWatcher) updateVolumeDatastores(ctx context.Context, nodeName string) error {
+	log := logger.GetLogger(ctx)
+	attachments, err := w.k8sClient.StorageV1().VolumeAttachments().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	pvs := getNodeAttachedPVs(nodeName, attachments.Items, func(name string) *v1.PersistentVolume {
+		pv, err := w.metadataSyncer.pvLister.Get(name)
+		if err != nil {
+			return nil
+		}
+		return pv
+	})
+	if len(pvs) == 0 {
+		return nil
+	}
+	var volumeIDs []cnstypes.CnsVolumeId
+	for _, pv := range pvs {
+		volumeIDs = append(volumeIDs, cnstypes.CnsVolumeId{Id: pv.Spec.CSI.VolumeHandle})
+	}
+	result, err := w.metadataSyncer.volumeManager.QueryVolume(ctx, cnstypes.CnsQueryFilter{VolumeIds: volumeIDs})
+	if err != nil {
+		return err
+	}
+	for pvName, datastoreURL := range getVolumeDatastoreUpdates(pvs, result.Volumes) {
+		pv, err := w.k8sClient.CoreV1().PersistentVolumes().Get(ctx, pvName, metav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		if pv.Annotations == nil {
+			pv.Annotations = make(map[string]string)
+		}
+		pv.Annotations[common.AnnVolumeDatastoreURL] = datastoreURL
+		if _, err := w.k8sClient.CoreV1().PersistentVolumes().Update(ctx, pv, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to update the annotations of pv %s. Err: %v", pvName, err)
+		}
+		log.Infof("VMMigrationWatcher: volume of pv %s is on datastore %q", pvName, datastoreURL)
+	}
+	return nil
+}
+
+// rediscoverNodeTopology requests the CSINodeTopology controller to discover
+// the topology of the given node again.
+func (w *vmMigrationWatcher) rediscoverNodeTopology(ctx context.Context, nodeName string) error {
+	csiNodeTopology := &csinodetopologyv1alpha1.CSINodeTopology{}
+	if err := w.crClient.Get(ctx, client.ObjectKey{Name: nodeName}, csiNodeTopology); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if csiNodeTopology.Annotations == nil {
+		csiNodeTopology.Annotations = make(map[string]string)
+	}
+	csiNodeTopology.Annotations[common.AnnRediscoverNodeTopology] = "true"
+	return w.crClient.Update(ctx, csiNodeTopology)
+}
+
+// getNodeAttachedPVs returns the PVs of the vSphere CSI block volumes attached
+// to the given node.
+func getNodeAttachedPVs(nodeName string, attachments []storagev1.VolumeAttachment,
+	getPV func(name string) *v1.PersistentVolume) []*v1.PersistentVolume {
+	var pvs []*v1.PersistentVolume
+	for _, attachment := range attachments {
+		if attachment.Spec.Attacher != csitypes.Name || attachment.Spec.NodeName != nodeName ||
+			!attachment.Status.Attached || attachment.Spec.Source.PersistentVolumeName == nil {
+			continue
+		}
+		pv := getPV(*attachment.Spec.Source.PersistentVolumeName)
+		if pv == nil || pv.Spec.CSI == nil || IsMultiAttachAllowed(pv) {
+			continue
+		}
+		pvs = append(pvs, pv)
+	}
+	return pvs
+}
+
+// getVolumeDatastoreUpdates returns the datastore URL of the given CNS
+// volumes, by name of the given PVs whose datastore annotation differs.
+func getVolumeDatastoreUpdates(pvs []*v1.PersistentVolume, volumes []cnstypes.CnsVolume) map[string]string {
+	datastoreURLs := make(map[string]string)
+	for _, volume := range volumes {
+		datastoreURLs[volume.VolumeId.Id] = volume.DatastoreUrl
+	}
+	updates := make(map[string]string)
+	for _, pv := range pvs {
+		datastoreURL := datastoreURLs[pv.Spec.CSI.VolumeHandle]
+		if datastoreURL != "" && pv.Annotations[common.AnnVolumeDatastoreURL] != datastoreURL {
+			updates[pv.Name] = datastoreURL
+		}
+	}
+	return updates
+}
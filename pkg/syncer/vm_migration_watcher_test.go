@@ -0,0 +1,111 @@
+package syncer
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+	csinodetopologyv1alpha1 "sigs.k8s.io/vsphere-csi-driver/v2/pkg/internalapis/csinodetopology/v1alpha1"
+)
+
+func TestGetVolumeDatastoreUpdates(t *testing.T) {
+	newPV := func(name, volumeID, datastoreURL string, accessMode v1.PersistentVolumeAccessMode) *v1.PersistentVolume {
+		pv := &v1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: v1.PersistentVolumeSpec{
+				AccessModes: []v1.PersistentVolumeAccessMode{accessMode},
+				PersistentVolumeSource: v1.PersistentVolumeSource{
+					CSI: &v1.CSIPersistentVolumeSource{Driver: csitypes.Name, VolumeHandle: volumeID},
+				},
+			},
+		}
+		if datastoreURL != "" {
+			pv.Annotations = map[string]string{common.AnnVolumeDatastoreURL: datastoreURL}
+		}
+		return pv
+	}
+	pvs := map[string]*v1.PersistentVolume{
+		"pv-moved":     newPV("pv-moved", "vol-moved", "ds:///vmfs/volumes/ds1/", v1.ReadWriteOnce),
+		"pv-unchanged": newPV("pv-unchanged", "vol-unchanged", "ds:///vmfs/volumes/ds1/", v1.ReadWriteOnce),
+		"pv-new":       newPV("pv-new", "vol-new", "", v1.ReadWriteOnce),
+		"pv-unknown":   newPV("pv-unknown", "vol-unknown", "", v1.ReadWriteOnce),
+		"pv-file":      newPV("pv-file", "file:vol-file", "", v1.ReadWriteMany),
+	}
+	newAttachment := func(pvName, nodeName string) storagev1.VolumeAttachment {
+		return storagev1.VolumeAttachment{
+			ObjectMeta: metav1.ObjectMeta{Name: "va-" + pvName + "-" + nodeName},
+			Spec: storagev1.VolumeAttachmentSpec{
+				Attacher: csitypes.Name,
+				NodeName: nodeName,
+				Source:   storagev1.VolumeAttachmentSource{PersistentVolumeName: &pvName},
+			},
+			Status: storagev1.VolumeAttachmentStatus{Attached: true},
+		}
+	}
+	attachments := []storagev1.VolumeAttachment{
+		newAttachment("pv-moved", "node1"),
+		newAttachment("pv-unchanged", "node1"),
+		newAttachment("pv-new", "node1"),
+		newAttachment("pv-unknown", "node1"),
+		newAttachment("pv-file", "node1"),
+		newAttachment("pv-missing", "node1"),
+		newAttachment("pv-other", "node2"),
+	}
+	attachedPVs := getNodeAttachedPVs("node1", attachments, func(name string) *v1.PersistentVolume {
+		return pvs[name]
+	})
+	if len(attachedPVs) != 4 {
+		t.Fatalf("expected 4 PVs attached to node1, got %d", len(attachedPVs))
+	}
+	volumes := []cnstypes.CnsVolume{
+		{VolumeId: cnstypes.CnsVolumeId{Id: "vol-moved"}, DatastoreUrl: "ds:///vmfs/volumes/ds2/"},
+		{VolumeId: cnstypes.CnsVolumeId{Id: "vol-unchanged"}, DatastoreUrl: "ds:///vmfs/volumes/ds1/"},
+		{VolumeId: cnstypes.CnsVolumeId{Id: "vol-new"}, DatastoreUrl: "ds:///vmfs/volumes/ds1/"},
+	}
+	expected := map[string]string{
+		"pv-moved": "ds:///vmfs/volumes/ds2/",
+		"pv-new":   "ds:///vmfs/volumes/ds1/",
+	}
+	if updates := getVolumeDatastoreUpdates(attachedPVs, volumes); !reflect.DeepEqual(updates, expected) {
+		t.Errorf("expected updates %v, got %v", expected, updates)
+	}
+}
+
+func TestRediscoverNodeTopology(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := runtime.NewScheme()
+	if err := csinodetopologyv1alpha1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	watcher := &vmMigrationWatcher{
+		crClient: fake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(
+			&csinodetopologyv1alpha1.CSINodeTopology{ObjectMeta: metav1.ObjectMeta{Name: "node1"}},
+		).Build(),
+	}
+	if err := watcher.rediscoverNodeTopology(ctx, "node1"); err != nil {
+		t.Fatalf("failed to request the topology of node1 to be discovered: %v", err)
+	}
+	csiNodeTopology := &csinodetopologyv1alpha1.CSINodeTopology{}
+	if err := watcher.crClient.Get(ctx, client.ObjectKey{Name: "node1"}, csiNodeTopology); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := csiNodeTopology.Annotations[common.AnnRediscoverNodeTopology]; !ok {
+		t.Errorf("expected CSINodeTopology node1 to be annotated with %q", common.AnnRediscoverNodeTopology)
+	}
+	// Nodes without CSINodeTopology instance are ignored.
+	if err := watcher.rediscoverNodeTopology(ctx, "node2"); err != nil {
+		t.Errorf("expected no error for a node without CSINodeTopology, got %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"strconv"
+
+	v1 "k8s.io/api/core/v1"
+	clientset "k8s.io/client-go/kubernetes"
+
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+// startVolumeEncryptionAnnotation annotates the vSphere CSI block PVs with
+// whether their volume is encrypted by its storage policy, so that compliance
+// tooling can audit encrypted volumes. PVs are annotated as they get added,
+// and again after their storage policy got changed through
+// annRequestedStoragePolicy.
+func startVolumeEncryptionAnnotation(k8sclient clientset.Interface, metadataSyncer *metadataSyncInformer) {
+	// Storage policies don't change between encrypting and not encrypting,
+	// so whether a policy encrypts is looked up once per policy. The map is
+	// only accessed by the subscriber, which handles one event at a time.
+	encryptionPolicies := make(map[string]bool)
+	resourceEventBus.subscribe("volume-encryption-annotation", []resourceKind{pvResource}, false,
+		func(event resourceEvent) {
+			if event.eventType == resourceDeleted {
+				return
+			}
+			pv, ok := event.newObj.(*v1.PersistentVolume)
+			if !ok || pv == nil || !isVolumeEncryptionAnnotationRequired(pv) {
+				return
+			}
+			ctx, log := logger.GetNewContextWithLogger()
+			volume, err := common.QueryVolumeByID(ctx, metadataSyncer.volumeManager, pv.Spec.CSI.VolumeHandle)
+			if err != nil {
+				log.Errorf("VolumeEncryption: failed to query volume %q of pv %s. Err: %v",
+					pv.Spec.CSI.VolumeHandle, pv.Name, err)
+				return
+			}
+			encrypted := false
+			if volume.StoragePolicyId != "" {
+				var known bool
+				if encrypted, known = encryptionPolicies[volume.StoragePolicyId]; !known {
+					vc, err := cnsvsphere.GetVirtualCenterInstance(ctx, metadataSyncer.configInfo, false)
+					if err != nil {
+						log.Errorf("VolumeEncryption: failed to get vCenter instance. Err: %v", err)
+						return
+					}
+					if encrypted, err = vc.IsEncryptionStoragePolicy(ctx, volume.StoragePolicyId); err != nil {
+						log.Errorf("VolumeEncryption: failed to check encryption of storage policy %q. Err: %v",
+							volume.StoragePolicyId, err)
+						return
+					}
+					encryptionPolicies[volume.StoragePolicyId] = encrypted
+				}
+			}
+			err = patchPVAnnotations(ctx, k8sclient, pv.Name, map[string]interface{}{
+				annVolumeEncrypted:          strconv.FormatBool(encrypted),
+				annVolumeEncryptionPolicyID: volume.StoragePolicyId,
+			})
+			if err != nil {
+				log.Errorf("VolumeEncryption: failed to update annotations of pv %s. Err: %v", pv.Name, err)
+				return
+			}
+			log.Infof("VolumeEncryption: annotated pv %s with encrypted %t for storage policy %q",
+				pv.Name, encrypted, volume.StoragePolicyId)
+		})
+}
+
+// isVolumeEncryptionAnnotationRequired returns whether the encryption status
+// of the given PV needs to be determined, i.e. whether it is a vSphere CSI
+// block PV not annotated yet, or whose storage policy got changed since.
+func isVolumeEncryptionAnnotationRequired(pv *v1.PersistentVolume) bool {
+	if pv.Spec.CSI == nil || pv.Spec.CSI.Driver != csitypes.Name || IsMultiAttachAllowed(pv) {
+		return false
+	}
+	if _, annotated := pv.Annotations[annVolumeEncrypted]; !annotated {
+		return true
+	}
+	policyID, changed := pv.Annotations[annStoragePolicyID]
+	return changed && policyID != pv.Annotations[annVolumeEncryptionPolicyID]
+}
@@ -0,0 +1,51 @@
+package syncer
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+func TestIsVolumeEncryptionAnnotationRequired(t *testing.T) {
+	newPV := func(driver string, annotations map[string]string) *v1.PersistentVolume {
+		return &v1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pv-1", Annotations: annotations},
+			Spec: v1.PersistentVolumeSpec{
+				AccessModes: []v1.PersistentVolumeAccessMode{v1.ReadWriteOnce},
+				PersistentVolumeSource: v1.PersistentVolumeSource{
+					CSI: &v1.CSIPersistentVolumeSource{Driver: driver, VolumeHandle: "vol-1"},
+				},
+			},
+		}
+	}
+	tests := []struct {
+		name     string
+		pv       *v1.PersistentVolume
+		expected bool
+	}{
+		{"not annotated", newPV(csitypes.Name, nil), true},
+		{"other driver", newPV("other.csi.driver", nil), false},
+		{"annotated", newPV(csitypes.Name, map[string]string{
+			annVolumeEncrypted:          "true",
+			annVolumeEncryptionPolicyID: "policy-1",
+		}), false},
+		{"annotated with current policy", newPV(csitypes.Name, map[string]string{
+			annVolumeEncrypted:          "true",
+			annVolumeEncryptionPolicyID: "policy-1",
+			annStoragePolicyID:          "policy-1",
+		}), false},
+		{"policy changed", newPV(csitypes.Name, map[string]string{
+			annVolumeEncrypted:          "true",
+			annVolumeEncryptionPolicyID: "policy-1",
+			annStoragePolicyID:          "policy-2",
+		}), true},
+	}
+	for _, test := range tests {
+		if required := isVolumeEncryptionAnnotationRequired(test.pv); required != test.expected {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, required)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientset "k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/retry"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+// pvDeletionTimeout is the time the repair of a PV waits for the PV to be
+// deleted before recreating it.
+const pvDeletionTimeout = time.Minute
+
+// pvRecreateBackoff is the retry policy of the creation of a PV deleted to
+// replace its volume handle.
+var pvRecreateBackoff = retry.SyncerPVRecreate
+
+// startVolumeHandleRepair repairs the PVs annotated with
+// annReplaceVolumeHandle as they get added or updated.
+//
+// When the FCD of a PV gets re-registered, e.g. during datastore recovery, it
+// gets a new ID and the PV is left pointing to a volume which doesn't exist
+// anymore. The volume handle of a PV is immutable, so the PV is recreated
+// with the same name, spec and claim reference but the new volume handle,
+// after which the PV controller binds the PVC back to it and the metadata
+// syncer pushes the PV and PVC metadata to the new CNS volume.
+//
+// The PV to create is recorded on its PVC with annRecreatedPV before the PV
+// is deleted, so that a PV whose creation failed is created again as its PVC
+// gets updated or the syncer restarts.
+func startVolumeHandleRepair(k8sclient clientset.Interface, metadataSyncer *metadataSyncInformer) {
+	resourceEventBus.subscribe("volume-handle-repair", []resourceKind{pvResource, pvcResource}, false,
+		func(event resourceEvent) {
+			if event.eventType == resourceDeleted {
+				return
+			}
+			if pvc, ok := event.newObj.(*v1.PersistentVolumeClaim); ok && pvc != nil {
+				if _, recreating := pvc.Annotations[annRecreatedPV]; !recreating {
+					return
+				}
+				ctx, log := logger.GetNewContextWithLogger()
+				if err := resumePVRecreation(ctx, k8sclient, pvc); err != nil {
+					log.Errorf("Failed to recreate the PV of PVC %s/%s. Err: %v", pvc.Namespace, pvc.Name, err)
+				}
+				return
+			}
+			pv, ok := event.newObj.(*v1.PersistentVolume)
+			if !ok || pv == nil {
+				return
+			}
+			if _, requested := pv.Annotations[annReplaceVolumeHandle]; !requested {
+				return
+			}
+			ctx, log := logger.GetNewContextWithLogger()
+			if err := repairVolumeHandle(ctx, k8sclient, metadataSyncer, pv); err != nil {
+				log.Errorf("Failed to repair the volume handle of PV %s. Err: %v", pv.Name, err)
+			}
+		})
+}
+
+// repairVolumeHandle replaces the volume handle of the given PV with the
+// volume ID in its annReplaceVolumeHandle annotation. The PV is only repaired
+// if its current volume doesn't exist in CNS anymore and the new one is a
+// block volume registered in CNS.
+func repairVolumeHandle(ctx context.Context, k8sclient clientset.Interface,
+	metadataSyncer *metadataSyncInformer, pv *v1.PersistentVolume) error {
+	log := logger.GetLogger(ctx)
+	newVolumeHandle := strings.TrimSpace(pv.Annotations[annReplaceVolumeHandle])
+	if pv.Spec.CSI == nil || pv.Spec.CSI.Driver != csitypes.Name {
+		return fmt.Errorf("PV %s is not provisioned by %s", pv.Name, csitypes.Name)
+	}
+	oldVolumeHandle := pv.Spec.CSI.VolumeHandle
+	if newVolumeHandle == "" || newVolumeHandle == oldVolumeHandle {
+		return fmt.Errorf("invalid value %q of annotation %s on PV %s", newVolumeHandle,
+			annReplaceVolumeHandle, pv.Name)
+	}
+	defer volumeOperationsLocks.LockAll([]string{oldVolumeHandle, newVolumeHandle})()
+
+	// The PV must be broken, i.e. its volume must not exist in CNS anymore.
+	// Query with empty selection. CNS returns only the volume ID from its
+	// cache.
+	queryResult, err := metadataSyncer.volumeManager.QueryAllVolume(ctx, cnstypes.CnsQueryFilter{
+		VolumeIds: []cnstypes.CnsVolumeId{{Id: oldVolumeHandle}},
+	}, cnstypes.CnsQuerySelection{})
+	if err != nil {
+		return fmt.Errorf("failed to query volume %s. Err: %w", oldVolumeHandle, err)
+	}
+	if len(queryResult.Volumes) != 0 {
+		return fmt.Errorf("volume %s of PV %s still exists in CNS", oldVolumeHandle, pv.Name)
+	}
+	newVolume, err := common.QueryVolumeByID(ctx, metadataSyncer.volumeManager, newVolumeHandle)
+	if err != nil {
+		return fmt.Errorf("failed to query volume %s. Err: %w", newVolumeHandle, err)
+	}
+	if newVolume.VolumeType != common.BlockVolumeType {
+		return fmt.Errorf("volume %s is not a block volume", newVolumeHandle)
+	}
+	if err := validateBackingDiskObjectID(ctx, metadataSyncer, pv, newVolume); err != nil {
+		return err
+	}
+
+	log.Infof("Replacing volume handle %s of PV %s with %s", oldVolumeHandle, pv.Name, newVolumeHandle)
+	if _, err := recreatePVWithVolumeHandle(ctx, k8sclient, pv, newVolumeHandle); err != nil {
+		return err
+	}
+	metadataCache.forget(oldVolumeHandle)
+	log.Infof("Replaced volume handle %s of PV %s with %s", oldVolumeHandle, pv.Name, newVolumeHandle)
+	return nil
+}
+
+// validateBackingDiskObjectID verifies that the new volume of the given PV
+// is backed by the disk recorded in the PV to backingDiskObjectId mapping of
+// its PVC, if any.
+func validateBackingDiskObjectID(ctx context.Context, metadataSyncer *metadataSyncInformer,
+	pv *v1.PersistentVolume, newVolume *cnstypes.CnsVolume) error {
+	log := logger.GetLogger(ctx)
+	if pv.Spec.ClaimRef == nil {
+		return nil
+	}
+	pvc, err := metadataSyncer.pvcLister.PersistentVolumeClaims(pv.Spec.ClaimRef.Namespace).Get(pv.Spec.ClaimRef.Name)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	mapping := pvc.Annotations[annPVtoBackingDiskObjectId]
+	if !validatePvToBackingDiskObjectIdPair(mapping) {
+		return nil
+	}
+	backingDiskObjectID := mapping[strings.Index(mapping, ":")+1:]
+	blockBackingDetails, ok := newVolume.BackingObjectDetails.(*cnstypes.CnsBlockBackingDetails)
+	if !ok || blockBackingDetails.BackingDiskObjectId == "" {
+		log.Debugf("Backing disk object ID of volume %s is unknown. Skipping its validation.",
+			newVolume.VolumeId.Id)
+		return nil
+	}
+	if blockBackingDetails.BackingDiskObjectId != backingDiskObjectID {
+		return fmt.Errorf("volume %s is backed by disk %s, expected disk %s recorded on PVC %s/%s",
+			newVolume.VolumeId.Id, blockBackingDetails.BackingDiskObjectId, backingDiskObjectID,
+			pvc.Namespace, pvc.Name)
+	}
+	return nil
+}
+
+// recreatePVWithVolumeHandle deletes the given PV and creates it again with
+// the given volume handle. The reclaim policy of the PV is set to Retain
+// before deleting it, so that deleting it doesn't delete its volume. The PV
+// to create is recorded on the PVC the PV is bound to, if any, until it is
+// created.
+func recreatePVWithVolumeHandle(ctx context.Context, k8sclient clientset.Interface,
+	pv *v1.PersistentVolume, volumeHandle string) (*v1.PersistentVolume, error) {
+	log := logger.GetLogger(ctx)
+	newPV := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        pv.Name,
+			Labels:      pv.Labels,
+			Annotations: make(map[string]string),
+		},
+		Spec: *pv.Spec.DeepCopy(),
+	}
+	for key, value := range pv.Annotations {
+		if key != annReplaceVolumeHandle {
+			newPV.Annotations[key] = value
+		}
+	}
+	newPV.Annotations[annReplacedVolumeHandle] = pv.Spec.CSI.VolumeHandle
+	newPV.Spec.CSI.VolumeHandle = volumeHandle
+	if newPV.Spec.ClaimRef != nil {
+		// Keep the claim reference, including the UID of the PVC, so that
+		// the PVC gets bound back to the PV.
+		newPV.Spec.ClaimRef.ResourceVersion = ""
+	}
+
+	if pv.Spec.PersistentVolumeReclaimPolicy != v1.PersistentVolumeReclaimRetain {
+		patch := fmt.Sprintf(`{"spec":{"persistentVolumeReclaimPolicy":%q}}`, v1.PersistentVolumeReclaimRetain)
+		_, err := k8sclient.CoreV1().PersistentVolumes().Patch(ctx, pv.Name, k8stypes.MergePatchType,
+			[]byte(patch), metav1.PatchOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to set the reclaim policy of PV %s to %s. Err: %w", pv.Name,
+				v1.PersistentVolumeReclaimRetain, err)
+		}
+	}
+	if err := recordPVRecreation(ctx, k8sclient, newPV); err != nil {
+		return nil, err
+	}
+	err := k8sclient.CoreV1().PersistentVolumes().Delete(ctx, pv.Name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to delete PV %s. Err: %w", pv.Name, err)
+	}
+	// The PV protection finalizer holds the deletion of PVs bound to a PVC.
+	_, err = k8sclient.CoreV1().PersistentVolumes().Patch(ctx, pv.Name, k8stypes.MergePatchType,
+		[]byte(`{"metadata":{"finalizers":null}}`), metav1.PatchOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to remove the finalizers of PV %s. Err: %w", pv.Name, err)
+	}
+	err = wait.PollImmediate(time.Second, pvDeletionTimeout, func() (bool, error) {
+		_, err := k8sclient.CoreV1().PersistentVolumes().Get(ctx, pv.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to wait for PV %s to be deleted. Err: %w", pv.Name, err)
+	}
+	log.Infof("Deleted PV %s with volume handle %s", pv.Name, pv.Spec.CSI.VolumeHandle)
+	return createRecreatedPV(ctx, k8sclient, newPV)
+}
+
+// recordPVRecreation records the given PV to create on the PVC it is bound
+// to. PVs not bound to a PVC are not recorded.
+func recordPVRecreation(ctx context.Context, k8sclient clientset.Interface, pv *v1.PersistentVolume) error {
+	log := logger.GetLogger(ctx)
+	if pv.Spec.ClaimRef == nil {
+		log.Warnf("PV %s is not bound to a PVC. Its recreation is not recorded.", pv.Name)
+		return nil
+	}
+	pvJSON, err := json.Marshal(pv)
+	if err != nil {
+		return fmt.Errorf("failed to serialize PV %s. Err: %w", pv.Name, err)
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{annRecreatedPV: string(pvJSON)},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = k8sclient.CoreV1().PersistentVolumeClaims(pv.Spec.ClaimRef.Namespace).Patch(ctx,
+		pv.Spec.ClaimRef.Name, k8stypes.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Warnf("PVC %s/%s of PV %s not found. The recreation of the PV is not recorded.",
+				pv.Spec.ClaimRef.Namespace, pv.Spec.ClaimRef.Name, pv.Name)
+			return nil
+		}
+		return fmt.Errorf("failed to record the recreation of PV %s on PVC %s/%s. Err: %w", pv.Name,
+			pv.Spec.ClaimRef.Namespace, pv.Spec.ClaimRef.Name, err)
+	}
+	return nil
+}
+
+// createRecreatedPV creates the given PV, retrying on failure, and removes
+// its record from its PVC once created.
+func createRecreatedPV(ctx context.Context, k8sclient clientset.Interface,
+	pv *v1.PersistentVolume) (*v1.PersistentVolume, error) {
+	var createdPV *v1.PersistentVolume
+	err := retry.Do(ctx, "RecreatePV", pvRecreateBackoff, func() error {
+		var err error
+		createdPV, err = k8sclient.CoreV1().PersistentVolumes().Create(ctx, pv, metav1.CreateOptions{})
+		if apierrors.IsAlreadyExists(err) {
+			return retry.Permanent(err)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to recreate PV %s with volume handle %s. Err: %w", pv.Name,
+			pv.Spec.CSI.VolumeHandle, err)
+	}
+	if pv.Spec.ClaimRef != nil {
+		if err := forgetPVRecreation(ctx, k8sclient, pv.Spec.ClaimRef.Namespace, pv.Spec.ClaimRef.Name); err != nil {
+			return nil, err
+		}
+	}
+	return createdPV, nil
+}
+
+// forgetPVRecreation removes the record of a PV to create from the given PVC.
+func forgetPVRecreation(ctx context.Context, k8sclient clientset.Interface, namespace, name string) error {
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:null}}}`, annRecreatedPV)
+	_, err := k8sclient.CoreV1().PersistentVolumeClaims(namespace).Patch(ctx, name, k8stypes.MergePatchType,
+		[]byte(patch), metav1.PatchOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to remove annotation %s from PVC %s/%s. Err: %w", annRecreatedPV,
+			namespace, name, err)
+	}
+	return nil
+}
+
+// resumePVRecreation creates the PV recorded on the given PVC with
+// annRecreatedPV if it doesn't exist, i.e. if its creation failed after it
+// was deleted. The record is removed if the PV was created meanwhile.
+func resumePVRecreation(ctx context.Context, k8sclient clientset.Interface, pvc *v1.PersistentVolumeClaim) error {
+	log := logger.GetLogger(ctx)
+	pv := &v1.PersistentVolume{}
+	if err := json.Unmarshal([]byte(pvc.Annotations[annRecreatedPV]), pv); err != nil ||
+		pv.Spec.CSI == nil || pv.Spec.ClaimRef == nil {
+		return fmt.Errorf("invalid value of annotation %s. Err: %v", annRecreatedPV, err)
+	}
+	existingPV, err := k8sclient.CoreV1().PersistentVolumes().Get(ctx, pv.Name, metav1.GetOptions{})
+	if err == nil {
+		if existingPV.Spec.CSI != nil && existingPV.Spec.CSI.VolumeHandle == pv.Spec.CSI.VolumeHandle {
+			return forgetPVRecreation(ctx, k8sclient, pvc.Namespace, pvc.Name)
+		}
+		// The PV was not deleted yet. Its repair is retried from the PV.
+		return nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return err
+	}
+	log.Infof("Recreating PV %s with volume handle %s recorded on PVC %s/%s", pv.Name,
+		pv.Spec.CSI.VolumeHandle, pvc.Namespace, pvc.Name)
+	_, err = createRecreatedPV(ctx, k8sclient, pv)
+	return err
+}
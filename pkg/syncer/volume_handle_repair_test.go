@@ -0,0 +1,174 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/retry"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+func newRepairedPV() *v1.PersistentVolume {
+	return &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "pv-1",
+			Annotations: map[string]string{
+				annReplaceVolumeHandle: "new-volume-id",
+				"foo":                  "bar",
+			},
+			Finalizers: []string{"kubernetes.io/pv-protection"},
+		},
+		Spec: v1.PersistentVolumeSpec{
+			PersistentVolumeReclaimPolicy: v1.PersistentVolumeReclaimDelete,
+			PersistentVolumeSource: v1.PersistentVolumeSource{
+				CSI: &v1.CSIPersistentVolumeSource{
+					Driver:       csitypes.Name,
+					VolumeHandle: "old-volume-id",
+				},
+			},
+			ClaimRef: &v1.ObjectReference{
+				Namespace:       "default",
+				Name:            "pvc-1",
+				UID:             "pvc-uid",
+				ResourceVersion: "12",
+			},
+		},
+	}
+}
+
+func TestRecreatePVWithVolumeHandle(t *testing.T) {
+	ctx := context.Background()
+	pv := newRepairedPV()
+	k8sclient := testclient.NewSimpleClientset(pv)
+
+	newPV, err := recreatePVWithVolumeHandle(ctx, k8sclient, pv, "new-volume-id")
+	if err != nil {
+		t.Fatalf("recreatePVWithVolumeHandle failed: %v", err)
+	}
+	newPV, err = k8sclient.CoreV1().PersistentVolumes().Get(ctx, newPV.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get the recreated PV: %v", err)
+	}
+	if newPV.Spec.CSI.VolumeHandle != "new-volume-id" {
+		t.Errorf("expected volume handle new-volume-id, got %s", newPV.Spec.CSI.VolumeHandle)
+	}
+	if newPV.Spec.PersistentVolumeReclaimPolicy != v1.PersistentVolumeReclaimDelete {
+		t.Errorf("expected reclaim policy %s, got %s", v1.PersistentVolumeReclaimDelete,
+			newPV.Spec.PersistentVolumeReclaimPolicy)
+	}
+	if newPV.Spec.ClaimRef == nil || newPV.Spec.ClaimRef.UID != "pvc-uid" || newPV.Spec.ClaimRef.ResourceVersion != "" {
+		t.Errorf("unexpected claim reference %+v", newPV.Spec.ClaimRef)
+	}
+	if _, ok := newPV.Annotations[annReplaceVolumeHandle]; ok {
+		t.Errorf("annotation %s not removed", annReplaceVolumeHandle)
+	}
+	if newPV.Annotations[annReplacedVolumeHandle] != "old-volume-id" {
+		t.Errorf("expected annotation %s to be old-volume-id, got %q", annReplacedVolumeHandle,
+			newPV.Annotations[annReplacedVolumeHandle])
+	}
+	if newPV.Annotations["foo"] != "bar" {
+		t.Errorf("annotation foo not preserved")
+	}
+	if pv.Spec.CSI.VolumeHandle != "old-volume-id" {
+		t.Errorf("original PV object was modified")
+	}
+}
+
+// failPVCreates makes the given number of PV creations of k8sclient fail.
+func failPVCreates(k8sclient *testclient.Clientset, failures int) {
+	k8sclient.PrependReactor("create", "persistentvolumes",
+		func(action k8stesting.Action) (bool, runtime.Object, error) {
+			if failures == 0 {
+				return false, nil, nil
+			}
+			failures--
+			return true, nil, errors.New("API server unavailable")
+		})
+}
+
+func TestRecreatePVWithVolumeHandleRetriesCreate(t *testing.T) {
+	ctx := context.Background()
+	defer func(backoff retry.Backoff) { pvRecreateBackoff = backoff }(pvRecreateBackoff)
+	pvRecreateBackoff = retry.Backoff{Initial: time.Millisecond, Factor: 1, Steps: 3}
+	pv := newRepairedPV()
+	pvc := &v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pvc-1"}}
+	k8sclient := testclient.NewSimpleClientset(pv, pvc)
+	failPVCreates(k8sclient, 2)
+
+	if _, err := recreatePVWithVolumeHandle(ctx, k8sclient, pv, "new-volume-id"); err != nil {
+		t.Fatalf("recreatePVWithVolumeHandle failed: %v", err)
+	}
+	newPV, err := k8sclient.CoreV1().PersistentVolumes().Get(ctx, pv.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get the recreated PV: %v", err)
+	}
+	if newPV.Spec.CSI.VolumeHandle != "new-volume-id" {
+		t.Errorf("expected volume handle new-volume-id, got %s", newPV.Spec.CSI.VolumeHandle)
+	}
+	pvc, err = k8sclient.CoreV1().PersistentVolumeClaims("default").Get(ctx, "pvc-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := pvc.Annotations[annRecreatedPV]; ok {
+		t.Errorf("annotation %s not removed from the PVC", annRecreatedPV)
+	}
+}
+
+func TestRecreatePVWithVolumeHandleCreateFailure(t *testing.T) {
+	ctx := context.Background()
+	defer func(backoff retry.Backoff) { pvRecreateBackoff = backoff }(pvRecreateBackoff)
+	pvRecreateBackoff = retry.Backoff{Initial: time.Millisecond, Factor: 1, Steps: 3}
+	pv := newRepairedPV()
+	pvc := &v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pvc-1"}}
+	k8sclient := testclient.NewSimpleClientset(pv, pvc)
+	failPVCreates(k8sclient, 3)
+
+	// The PV is deleted, but every attempt to create it again fails.
+	if _, err := recreatePVWithVolumeHandle(ctx, k8sclient, pv, "new-volume-id"); err == nil {
+		t.Fatal("expected recreatePVWithVolumeHandle to fail")
+	}
+	_, err := k8sclient.CoreV1().PersistentVolumes().Get(ctx, pv.Name, metav1.GetOptions{})
+	if !apierrors.IsNotFound(err) {
+		t.Fatalf("expected PV %s to be deleted, got %v", pv.Name, err)
+	}
+	pvc, err = k8sclient.CoreV1().PersistentVolumeClaims("default").Get(ctx, "pvc-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := pvc.Annotations[annRecreatedPV]; !ok {
+		t.Fatalf("expected the PV to create to be recorded on the PVC")
+	}
+
+	// The PV is created from its record on the PVC.
+	if err := resumePVRecreation(ctx, k8sclient, pvc); err != nil {
+		t.Fatalf("resumePVRecreation failed: %v", err)
+	}
+	newPV, err := k8sclient.CoreV1().PersistentVolumes().Get(ctx, pv.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get the recreated PV: %v", err)
+	}
+	if newPV.Spec.CSI.VolumeHandle != "new-volume-id" || newPV.Spec.ClaimRef == nil ||
+		newPV.Spec.ClaimRef.UID != "pvc-uid" {
+		t.Errorf("unexpected recreated PV %+v", newPV.Spec)
+	}
+	if newPV.Annotations[annReplacedVolumeHandle] != "old-volume-id" {
+		t.Errorf("expected annotation %s to be old-volume-id, got %q", annReplacedVolumeHandle,
+			newPV.Annotations[annReplacedVolumeHandle])
+	}
+	pvc, err = k8sclient.CoreV1().PersistentVolumeClaims("default").Get(ctx, "pvc-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := pvc.Annotations[annRecreatedPV]; ok {
+		t.Errorf("annotation %s not removed from the PVC", annRecreatedPV)
+	}
+}
@@ -66,6 +66,10 @@ func csiGetVolumeHealthStatus(ctx context.Context, k8sclient clientset.Interface
 	volumeHandleToPvcMap := make(volumeHandlePVCMap, len(k8sPVs))
 
 	for _, pv := range k8sPVs {
+		// The health of volumes in maintenance is left as-is.
+		if isVolumeInMaintenance(ctx, pv) {
+			continue
+		}
 		if pv.Spec.ClaimRef != nil && pv.Status.Phase == v1.VolumeBound {
 			pvc, err := metadataSyncer.pvcLister.PersistentVolumeClaims(
 				pv.Spec.ClaimRef.Namespace).Get(pv.Spec.ClaimRef.Name)
@@ -99,8 +99,8 @@ func (m *volumeHandleToPVs) get(volumeHandle string) []string {
 type volumeHealthReconciler struct {
 	// Tanzu Kubernetes Grid KubeClient.
 	tkgKubeClient kubernetes.Interface
-	// Supervisor Cluster KubeClient.
-	svcKubeClient kubernetes.Interface
+	// getSvcKubeClient returns the current Supervisor Cluster KubeClient.
+	getSvcKubeClient func() kubernetes.Interface
 	// Supervisor Cluster claim queue.
 	svcClaimQueue workqueue.RateLimitingInterface
 
@@ -123,13 +123,21 @@ type volumeHealthReconciler struct {
 func NewVolumeHealthReconciler(
 	// Tanzu Kubernetes Grid KubeClient.
 	tkgKubeClient kubernetes.Interface,
-	// Supervisor Cluster KubeClient.
-	svcKubeClient kubernetes.Interface,
+	// Returns the current Supervisor Cluster KubeClient.
+	getSvcKubeClient func() kubernetes.Interface,
 	resyncPeriod time.Duration,
 	tkgInformerFactory informers.SharedInformerFactory,
 	svcInformerFactory informers.SharedInformerFactory,
+	// Event bus publishing the Tanzu Kubernetes Grid PV changes. The PV
+	// changes are watched on tkgInformerFactory if nil.
+	tkgEvents *eventBus,
 	svcPVCRateLimiter workqueue.RateLimiter,
 	supervisorNamespace string, stopCh <-chan struct{}) (VolumeHealthReconciler, error) {
+	// List and watch the supervisor PVCs with the current client, so that the
+	// informer resumes with the new client once the supervisor clients are
+	// rebuilt.
+	svcInformerFactory.InformerFor(&v1.PersistentVolumeClaim{},
+		newSupervisorPVCInformer(getSvcKubeClient, supervisorNamespace))
 	svcPVCInformer := svcInformerFactory.Core().V1().PersistentVolumeClaims()
 	tkgPVInformer := tkgInformerFactory.Core().V1().PersistentVolumes()
 
@@ -137,8 +145,8 @@ func NewVolumeHealthReconciler(
 		svcPVCRateLimiter, "volume-health-pvc")
 
 	rc := &volumeHealthReconciler{
-		tkgKubeClient: tkgKubeClient,
-		svcKubeClient: svcKubeClient,
+		tkgKubeClient:    tkgKubeClient,
+		getSvcKubeClient: getSvcKubeClient,
 		// TODO: Discuss pros and cons of having a single controller
 		// vs separate controllers to handle metadata sync, resize,
 		// volume health, and any other logic that is triggered by
@@ -161,11 +169,31 @@ func NewVolumeHealthReconciler(
 		DeleteFunc: rc.svcAddPVC,
 	}, resyncPeriod)
 
-	tkgPVInformer.Informer().AddEventHandlerWithResyncPeriod(cache.ResourceEventHandlerFuncs{
-		AddFunc:    nil,
-		UpdateFunc: rc.tkgUpdatePV,
-		DeleteFunc: rc.tkgDeletePV,
-	}, resyncPeriod)
+	if tkgEvents != nil {
+		// The PV changes published before this reconciler started are
+		// replayed, so that the supervisor PVCs of the PVs bound meanwhile
+		// are queued. Resyncing the PVs is not needed, as only their
+		// transition to Bound is handled.
+		unsubscribe := tkgEvents.subscribe("volume-health-reconciler", []resourceKind{pvResource}, true,
+			func(event resourceEvent) {
+				switch event.eventType {
+				case resourceUpdated:
+					rc.tkgUpdatePV(event.oldObj, event.newObj)
+				case resourceDeleted:
+					rc.tkgDeletePV(event.oldObj)
+				}
+			})
+		go func() {
+			<-stopCh
+			unsubscribe()
+		}()
+	} else {
+		tkgPVInformer.Informer().AddEventHandlerWithResyncPeriod(cache.ResourceEventHandlerFuncs{
+			AddFunc:    nil,
+			UpdateFunc: rc.tkgUpdatePV,
+			DeleteFunc: rc.tkgDeletePV,
+		}, resyncPeriod)
+	}
 
 	ctx, log := logger.GetNewContextWithLogger()
 
@@ -414,7 +442,7 @@ func (rc *volumeHealthReconciler) updateTKGPVC(ctx context.Context,
 	tkgPVCObj, err := rc.tkgKubeClient.CoreV1().PersistentVolumeClaims(tkgPV.Spec.ClaimRef.Namespace).
 		Get(ctx, tkgPV.Spec.ClaimRef.Name, metav1.GetOptions{})
 	if err != nil {
-		return fmt.Errorf("error get pvc %s/%s from api server: %v",
+		return fmt.Errorf("error get pvc %s/%s from api server: %w",
 			tkgPV.Spec.ClaimRef.Namespace, tkgPV.Spec.ClaimRef.Name, err)
 	}
 	log.Debugf("updateTKGPVC: Found Tanzu Kubernetes Grid PVC %s/%s", tkgPVCObj.Namespace, tkgPVCObj.Name)
@@ -0,0 +1,89 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"sort"
+	"sync"
+)
+
+// keyedMutex provides a mutex per key. The mutex of a key is only kept in
+// memory while it is held or waited for.
+type keyedMutex struct {
+	lock    sync.Mutex
+	mutexes map[string]*refCountedMutex
+}
+
+type refCountedMutex struct {
+	sync.Mutex
+	refs int
+}
+
+func newKeyedMutex() *keyedMutex {
+	return &keyedMutex{mutexes: make(map[string]*refCountedMutex)}
+}
+
+// Lock locks the mutex of the given key.
+func (m *keyedMutex) Lock(key string) {
+	m.lock.Lock()
+	mutex, ok := m.mutexes[key]
+	if !ok {
+		mutex = &refCountedMutex{}
+		m.mutexes[key] = mutex
+	}
+	mutex.refs++
+	m.lock.Unlock()
+	mutex.Lock()
+}
+
+// Unlock unlocks the mutex of the given key.
+func (m *keyedMutex) Unlock(key string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	mutex, ok := m.mutexes[key]
+	if !ok {
+		return
+	}
+	mutex.Unlock()
+	mutex.refs--
+	if mutex.refs == 0 {
+		delete(m.mutexes, key)
+	}
+}
+
+// LockAll locks the mutexes of the given keys and returns a function
+// unlocking them. Keys are locked in sorted order, so that concurrent callers
+// of LockAll can't deadlock.
+func (m *keyedMutex) LockAll(keys []string) func() {
+	sortedKeys := make([]string, 0, len(keys))
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		if !seen[key] {
+			seen[key] = true
+			sortedKeys = append(sortedKeys, key)
+		}
+	}
+	sort.Strings(sortedKeys)
+	for _, key := range sortedKeys {
+		m.Lock(key)
+	}
+	return func() {
+		for _, key := range sortedKeys {
+			m.Unlock(key)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package syncer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeyedMutex(t *testing.T) {
+	m := newKeyedMutex()
+	m.Lock("vol-1")
+
+	// A different key must not be blocked.
+	done := make(chan struct{})
+	go func() {
+		m.Lock("vol-2")
+		m.Unlock("vol-2")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("lock of vol-2 blocked by lock of vol-1")
+	}
+
+	// The same key must be blocked until unlocked.
+	acquired := make(chan struct{})
+	released := make(chan struct{})
+	go func() {
+		unlock := m.LockAll([]string{"vol-3", "vol-1", "vol-3"})
+		close(acquired)
+		unlock()
+		close(released)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("LockAll acquired vol-1 while it was held")
+	case <-time.After(50 * time.Millisecond):
+	}
+	m.Unlock("vol-1")
+	select {
+	case <-acquired:
+	case <-time.After(5 * time.Second):
+		t.Fatal("LockAll blocked after vol-1 was unlocked")
+	}
+
+	<-released
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if len(m.mutexes) != 0 {
+		t.Errorf("expected no mutexes left, got %d", len(m.mutexes))
+	}
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	k8s "sigs.k8s.io/vsphere-csi-driver/v2/pkg/kubernetes"
+)
+
+// maxVolumeMaintenanceDuration bounds how far in the future the end of the
+// maintenance of a volume can be set, so that a forgotten annMaintenanceUntil
+// annotation doesn't pause the volume for good.
+const maxVolumeMaintenanceDuration = 7 * 24 * time.Hour
+
+// volumeMaintenanceState is the state of the maintenance of a PV, as set in
+// its annMaintenanceUntil annotation.
+type volumeMaintenanceState int
+
+const (
+	// volumeMaintenanceNone means the PV has no annMaintenanceUntil
+	// annotation.
+	volumeMaintenanceNone volumeMaintenanceState = iota
+	// volumeMaintenanceActive means the PV is in maintenance.
+	volumeMaintenanceActive
+	// volumeMaintenanceExpired means the maintenance of the PV ended.
+	volumeMaintenanceExpired
+	// volumeMaintenanceInvalid means the annMaintenanceUntil annotation of
+	// the PV isn't an RFC 3339 time within maxVolumeMaintenanceDuration.
+	volumeMaintenanceInvalid
+)
+
+// getVolumeMaintenanceState returns the state of the maintenance of the given
+// PV at the given time.
+func getVolumeMaintenanceState(pv *v1.PersistentVolume, now time.Time) volumeMaintenanceState {
+	value, ok := pv.Annotations[annMaintenanceUntil]
+	if !ok {
+		return volumeMaintenanceNone
+	}
+	until, err := time.Parse(time.RFC3339, value)
+	if err != nil || until.After(now.Add(maxVolumeMaintenanceDuration)) {
+		return volumeMaintenanceInvalid
+	}
+	if !now.Before(until) {
+		return volumeMaintenanceExpired
+	}
+	return volumeMaintenanceActive
+}
+
+// isVolumeInMaintenance returns true if the automated operations of the
+// syncer on the given PV are paused. An invalid annMaintenanceUntil
+// annotation doesn't pause them.
+func isVolumeInMaintenance(ctx context.Context, pv *v1.PersistentVolume) bool {
+	log := logger.GetLogger(ctx)
+	switch getVolumeMaintenanceState(pv, syncerClock.Now()) {
+	case volumeMaintenanceActive:
+		log.Debugf("pv %s is in maintenance until %s", pv.Name, pv.Annotations[annMaintenanceUntil])
+		return true
+	case volumeMaintenanceInvalid:
+		log.Warnf("Ignoring annotation %s=%q of pv %s, expected an RFC 3339 time within %v", annMaintenanceUntil,
+			pv.Annotations[annMaintenanceUntil], pv.Name, maxVolumeMaintenanceDuration)
+	}
+	return false
+}
+
+// filterPVsNotInMaintenance returns the given PVs which are not in
+// maintenance.
+func filterPVsNotInMaintenance(ctx context.Context, pvs []*v1.PersistentVolume) []*v1.PersistentVolume {
+	var filtered []*v1.PersistentVolume
+	for _, pv := range pvs {
+		if !isVolumeInMaintenance(ctx, pv) {
+			filtered = append(filtered, pv)
+		}
+	}
+	return filtered
+}
+
+// removeExpiredVolumeMaintenance removes the annMaintenanceUntil annotation
+// of the given PVs whose maintenance ended.
+func removeExpiredVolumeMaintenance(ctx context.Context, pvs []*v1.PersistentVolume) {
+	log := logger.GetLogger(ctx)
+	var expired []*v1.PersistentVolume
+	now := syncerClock.Now()
+	for _, pv := range pvs {
+		if getVolumeMaintenanceState(pv, now) == volumeMaintenanceExpired {
+			expired = append(expired, pv)
+		}
+	}
+	if len(expired) == 0 {
+		return
+	}
+	k8sClient, err := k8s.NewClient(ctx)
+	if err != nil {
+		log.Errorf("FullSync: Creating Kubernetes client failed. Err: %v", err)
+		return
+	}
+	for _, pv := range expired {
+		if err := patchPVAnnotations(ctx, k8sClient, pv.Name, map[string]interface{}{
+			annMaintenanceUntil: nil,
+		}); err != nil {
+			log.Warnf("FullSync: failed to remove the expired annotation %s of pv %s. Err: %v",
+				annMaintenanceUntil, pv.Name, err)
+			continue
+		}
+		log.Infof("FullSync: maintenance of pv %s ended at %s, resuming its sync", pv.Name,
+			pv.Annotations[annMaintenanceUntil])
+	}
+}
@@ -0,0 +1,59 @@
+package syncer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newMaintenancePV(name string, annotations map[string]string) *v1.PersistentVolume {
+	return &v1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: annotations}}
+}
+
+func TestGetVolumeMaintenanceState(t *testing.T) {
+	now := time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		expected    volumeMaintenanceState
+	}{
+		{"no annotation", nil, volumeMaintenanceNone},
+		{"active", map[string]string{annMaintenanceUntil: "2022-05-01T14:00:00Z"}, volumeMaintenanceActive},
+		{"ended now in another time zone", map[string]string{annMaintenanceUntil: "2022-05-01T14:00:00+02:00"},
+			volumeMaintenanceExpired},
+		{"ended now", map[string]string{annMaintenanceUntil: "2022-05-01T12:00:00Z"}, volumeMaintenanceExpired},
+		{"expired", map[string]string{annMaintenanceUntil: "2022-04-30T12:00:00Z"}, volumeMaintenanceExpired},
+		{"at max duration", map[string]string{annMaintenanceUntil: "2022-05-08T12:00:00Z"}, volumeMaintenanceActive},
+		{"beyond max duration", map[string]string{annMaintenanceUntil: "2022-05-08T12:00:01Z"},
+			volumeMaintenanceInvalid},
+		{"not a time", map[string]string{annMaintenanceUntil: "2h"}, volumeMaintenanceInvalid},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state := getVolumeMaintenanceState(newMaintenancePV("pv", test.annotations), now)
+			if state != test.expected {
+				t.Errorf("expected state %d, got %d", test.expected, state)
+			}
+		})
+	}
+}
+
+func TestFilterPVsNotInMaintenance(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pvs := []*v1.PersistentVolume{
+		newMaintenancePV("pv-1", nil),
+		newMaintenancePV("pv-2", map[string]string{
+			annMaintenanceUntil: time.Now().Add(time.Hour).Format(time.RFC3339)}),
+		newMaintenancePV("pv-3", map[string]string{
+			annMaintenanceUntil: time.Now().Add(-time.Hour).Format(time.RFC3339)}),
+		newMaintenancePV("pv-4", map[string]string{annMaintenanceUntil: "forever"}),
+	}
+	filtered := filterPVsNotInMaintenance(ctx, pvs)
+	if len(filtered) != 3 || filtered[0].Name != "pv-1" || filtered[1].Name != "pv-3" || filtered[2].Name != "pv-4" {
+		t.Errorf("expected pvs pv-1, pv-3 and pv-4, got %v", filtered)
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/prometheus"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+)
+
+// unknownMetricLabelValue is the value of volume count labels whose value
+// couldn't be determined, e.g. the storage policy of volumes created without
+// one.
+const unknownMetricLabelValue = "unknown"
+
+// volumeCountKey is the set of labels volumes are counted by in
+// prometheus.VolumeCountGaugeVec.
+type volumeCountKey struct {
+	volumeType    string
+	storagePolicy string
+	datastoreType string
+}
+
+// csiReportVolumeCounts publishes the number of volumes of the cluster in
+// CNS, by volume type, storage policy, datastore type and cluster flavor,
+// through prometheus.VolumeCountGaugeVec.
+func csiReportVolumeCounts(ctx context.Context, metadataSyncer *metadataSyncInformer,
+	vc *cnsvsphere.VirtualCenter) {
+	log := logger.GetLogger(ctx)
+	queryResults, err := fullSyncGetQueryResults(ctx, nil, metadataSyncer.configInfo.Cfg.Global.ClusterID,
+		metadataSyncer.volumeManager, metadataSyncer)
+	if err != nil {
+		log.Errorf("FullSync: failed to query volumes for volume count metrics. Err: %v", err)
+		return
+	}
+	var volumes []cnstypes.CnsVolume
+	for _, queryResult := range queryResults {
+		volumes = append(volumes, queryResult.Volumes...)
+	}
+
+	datastoreTypes := make(map[string]string)
+	datacenters, err := vc.GetDatacenters(ctx)
+	if err != nil {
+		log.Errorf("FullSync: failed to get datacenters for volume count metrics. Err: %v", err)
+		return
+	}
+	for _, datacenter := range datacenters {
+		dcDatastoreTypes, err := datacenter.GetAllDatastoreTypes(ctx)
+		if err != nil {
+			log.Errorf("FullSync: failed to get datastore types for volume count metrics. Err: %v", err)
+			return
+		}
+		for url, datastoreType := range dcDatastoreTypes {
+			datastoreTypes[url] = datastoreType
+		}
+	}
+
+	policyNames := make(map[string]string)
+	if err := vc.ConnectPbm(ctx); err != nil {
+		log.Warnf("FullSync: failed to connect to PBM. Volume count metrics use storage policy IDs. Err: %v", err)
+	} else {
+		for _, volume := range volumes {
+			if _, ok := policyNames[volume.StoragePolicyId]; ok || volume.StoragePolicyId == "" {
+				continue
+			}
+			policyName, err := vc.PbmClient.GetProfileNameByID(ctx, volume.StoragePolicyId)
+			if err != nil {
+				log.Debugf("FullSync: failed to get name of storage policy %q. Err: %v", volume.StoragePolicyId, err)
+				policyName = volume.StoragePolicyId
+			}
+			policyNames[volume.StoragePolicyId] = policyName
+		}
+	}
+
+	counts := countVolumes(volumes, datastoreTypes, policyNames)
+	clusterFlavor := string(metadataSyncer.clusterFlavor)
+	// Reset the gauges so that combinations without volumes anymore are
+	// dropped.
+	prometheus.VolumeCountGaugeVec.Reset()
+	for key, count := range counts {
+		prometheus.VolumeCountGaugeVec.WithLabelValues(key.volumeType, key.storagePolicy, key.datastoreType,
+			clusterFlavor).Set(float64(count))
+	}
+	log.Infof("FullSync: published volume count metrics for %d volumes", len(volumes))
+}
+
+// countVolumes counts the given volumes by volume type, storage policy and
+// datastore type. Storage policies are identified by their name in the given
+// policyNames map, or by their ID if missing from it.
+func countVolumes(volumes []cnstypes.CnsVolume, datastoreTypes map[string]string,
+	policyNames map[string]string) map[volumeCountKey]int {
+	counts := make(map[volumeCountKey]int)
+	for _, volume := range volumes {
+		key := volumeCountKey{
+			volumeType:    prometheus.PrometheusUnknownVolumeType,
+			storagePolicy: unknownMetricLabelValue,
+			datastoreType: unknownMetricLabelValue,
+		}
+		switch volume.VolumeType {
+		case common.BlockVolumeType:
+			key.volumeType = prometheus.PrometheusBlockVolumeType
+		case common.FileVolumeType:
+			key.volumeType = prometheus.PrometheusFileVolumeType
+		}
+		if volume.StoragePolicyId != "" {
+			key.storagePolicy = volume.StoragePolicyId
+			if name, ok := policyNames[volume.StoragePolicyId]; ok {
+				key.storagePolicy = name
+			}
+		}
+		if datastoreType, ok := datastoreTypes[volume.DatastoreUrl]; ok {
+			key.datastoreType = datastoreType
+		}
+		counts[key]++
+	}
+	return counts
+}
@@ -0,0 +1,35 @@
+package syncer
+
+import (
+	"reflect"
+	"testing"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/prometheus"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+)
+
+func TestCountVolumes(t *testing.T) {
+	volumes := []cnstypes.CnsVolume{
+		{VolumeType: common.BlockVolumeType, StoragePolicyId: "policy-1", DatastoreUrl: "ds:///vsan/"},
+		{VolumeType: common.BlockVolumeType, StoragePolicyId: "policy-1", DatastoreUrl: "ds:///vsan/"},
+		{VolumeType: common.BlockVolumeType, StoragePolicyId: "policy-2", DatastoreUrl: "ds:///vmfs/"},
+		{VolumeType: common.FileVolumeType, StoragePolicyId: "policy-1", DatastoreUrl: "ds:///vsan/"},
+		{VolumeType: common.BlockVolumeType, DatastoreUrl: "ds:///unknown/"},
+	}
+	datastoreTypes := map[string]string{
+		"ds:///vsan/": "vsan",
+		"ds:///vmfs/": "VMFS",
+	}
+	policyNames := map[string]string{"policy-1": "gold"}
+	expected := map[volumeCountKey]int{
+		{prometheus.PrometheusBlockVolumeType, "gold", "vsan"}:                                   2,
+		{prometheus.PrometheusBlockVolumeType, "policy-2", "VMFS"}:                               1,
+		{prometheus.PrometheusFileVolumeType, "gold", "vsan"}:                                    1,
+		{prometheus.PrometheusBlockVolumeType, unknownMetricLabelValue, unknownMetricLabelValue}: 1,
+	}
+	if counts := countVolumes(volumes, datastoreTypes, policyNames); !reflect.DeepEqual(counts, expected) {
+		t.Errorf("expected %v, got %v", expected, counts)
+	}
+}
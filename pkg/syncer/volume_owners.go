@@ -0,0 +1,164 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+// VolumeOwnersPath is the http path under which the Kubernetes objects using
+// a volume are served. The volume handle, i.e. the FCD ID for block volumes,
+// is appended to the path.
+const VolumeOwnersPath = "/volumeowners/"
+
+// VolumeOwners holds the Kubernetes objects using a CNS volume.
+type VolumeOwners struct {
+	VolumeHandle string `json:"volumeHandle"`
+	PVName       string `json:"pvName"`
+	PVCName      string `json:"pvcName,omitempty"`
+	PVCNamespace string `json:"pvcNamespace,omitempty"`
+	// Pods are the names of the pods in PVCNamespace using the PVC.
+	Pods []string `json:"pods,omitempty"`
+}
+
+// ServeVolumeOwners starts an http server serving the Kubernetes objects using
+// a volume handle on the address set in X_CSI_VOLUME_OWNERS_ADDRESS, if any.
+// The objects are served on their own listener, rather than on the metrics
+// one, as they expose the names of PVCs, namespaces and pods.
+func ServeVolumeOwners(ctx context.Context) {
+	log := logger.GetLogger(ctx)
+	address := os.Getenv(csitypes.EnvVarVolumeOwnersAddress)
+	if address == "" {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(VolumeOwnersPath, VolumeOwnersHandler)
+	go func() {
+		for {
+			log.Infof("Starting the http server to expose volume owners on %s..", address)
+			err := http.ListenAndServe(address, mux)
+			if err != nil {
+				log.Warnf("Http server that exposes volume owners exited with err: %+v", err)
+			}
+			log.Info("Restarting http server to expose volume owners..")
+			time.Sleep(time.Second)
+		}
+	}()
+}
+
+// VolumeOwnersHandler serves the Kubernetes objects using the volume handle
+// given in the request path, resolved from the metadata syncer caches. This
+// allows vSphere admins to map alerts raised on FCD IDs back to Kubernetes
+// objects. It is only served by the syncer instance running the metadata
+// syncer.
+func VolumeOwnersHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, log := logger.GetNewContextWithLogger()
+	if r.Method != http.MethodGet {
+		http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	metadataSyncer := MetadataSyncer
+	if metadataSyncer == nil || metadataSyncer.pvLister == nil {
+		http.Error(w, "metadata syncer is not running on this instance", http.StatusServiceUnavailable)
+		return
+	}
+	if !metadataSyncer.coCommonInterface.IsFSSEnabled(ctx, common.VolumeOwnerLookup) {
+		http.Error(w, "volume owner lookup is not enabled", http.StatusNotFound)
+		return
+	}
+	volumeHandle := strings.TrimPrefix(r.URL.Path, VolumeOwnersPath)
+	if volumeHandle == "" {
+		http.Error(w, "volume handle is not specified", http.StatusBadRequest)
+		return
+	}
+	owners, err := getVolumeOwners(ctx, metadataSyncer, volumeHandle)
+	if err != nil {
+		log.Errorf("failed to get owners of volume %q. Err: %v", volumeHandle, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if owners == nil {
+		http.Error(w, "no PV found for volume "+volumeHandle, http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(owners); err != nil {
+		log.Errorf("failed to write owners of volume %q. Err: %v", volumeHandle, err)
+	}
+}
+
+// getVolumeOwners returns the PV, PVC and pods using the given vSphere CSI
+// volume handle. Nil is returned if no PV is found for the volume handle.
+func getVolumeOwners(ctx context.Context, metadataSyncer *metadataSyncInformer,
+	volumeHandle string) (*VolumeOwners, error) {
+	log := logger.GetLogger(ctx)
+	pvs, err := metadataSyncer.pvLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	var pv *v1.PersistentVolume
+	for _, candidate := range pvs {
+		if candidate.Spec.CSI != nil && candidate.Spec.CSI.Driver == csitypes.Name &&
+			candidate.Spec.CSI.VolumeHandle == volumeHandle {
+			pv = candidate
+			break
+		}
+	}
+	if pv == nil {
+		return nil, nil
+	}
+	owners := &VolumeOwners{
+		VolumeHandle: volumeHandle,
+		PVName:       pv.Name,
+	}
+	if pv.Spec.ClaimRef == nil {
+		return owners, nil
+	}
+	pvc, err := metadataSyncer.pvcLister.PersistentVolumeClaims(pv.Spec.ClaimRef.Namespace).Get(pv.Spec.ClaimRef.Name)
+	if err != nil {
+		log.Debugf("failed to get pvc %s/%s for pv %s. Err: %v", pv.Spec.ClaimRef.Namespace,
+			pv.Spec.ClaimRef.Name, pv.Name, err)
+		return owners, nil
+	}
+	owners.PVCName = pvc.Name
+	owners.PVCNamespace = pvc.Namespace
+	pods, err := metadataSyncer.podLister.Pods(pvc.Namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, pod := range pods {
+		for _, volume := range pod.Spec.Volumes {
+			if volume.PersistentVolumeClaim != nil && volume.PersistentVolumeClaim.ClaimName == pvc.Name {
+				owners.Pods = append(owners.Pods, pod.Name)
+				break
+			}
+		}
+	}
+	return owners, nil
+}
@@ -0,0 +1,103 @@
+package syncer
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+func TestGetVolumeOwners(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pvIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	pvcIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	podIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	objects := []struct {
+		indexer cache.Indexer
+		obj     interface{}
+	}{
+		{pvIndexer, &v1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pv-1"},
+			Spec: v1.PersistentVolumeSpec{
+				PersistentVolumeSource: v1.PersistentVolumeSource{
+					CSI: &v1.CSIPersistentVolumeSource{Driver: csitypes.Name, VolumeHandle: "fcd-1"},
+				},
+				ClaimRef: &v1.ObjectReference{Namespace: "default", Name: "pvc-1"},
+			},
+		}},
+		{pvIndexer, &v1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pv-2"},
+			Spec: v1.PersistentVolumeSpec{
+				PersistentVolumeSource: v1.PersistentVolumeSource{
+					CSI: &v1.CSIPersistentVolumeSource{Driver: csitypes.Name, VolumeHandle: "fcd-2"},
+				},
+			},
+		}},
+		{pvcIndexer, &v1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pvc-1"},
+		}},
+		{podIndexer, &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pod-1"},
+			Spec: v1.PodSpec{Volumes: []v1.Volume{{
+				Name: "data",
+				VolumeSource: v1.VolumeSource{
+					PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "pvc-1"},
+				},
+			}}},
+		}},
+		{podIndexer, &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pod-2"},
+		}},
+	}
+	for _, object := range objects {
+		if err := object.indexer.Add(object.obj); err != nil {
+			t.Fatalf("failed to add object to indexer. Err: %v", err)
+		}
+	}
+	metadataSyncer := &metadataSyncInformer{
+		pvLister:  corelisters.NewPersistentVolumeLister(pvIndexer),
+		pvcLister: corelisters.NewPersistentVolumeClaimLister(pvcIndexer),
+		podLister: corelisters.NewPodLister(podIndexer),
+	}
+
+	tests := []struct {
+		volumeHandle   string
+		expectedOwners *VolumeOwners
+	}{
+		{
+			volumeHandle: "fcd-1",
+			expectedOwners: &VolumeOwners{
+				VolumeHandle: "fcd-1",
+				PVName:       "pv-1",
+				PVCName:      "pvc-1",
+				PVCNamespace: "default",
+				Pods:         []string{"pod-1"},
+			},
+		},
+		{
+			volumeHandle:   "fcd-2",
+			expectedOwners: &VolumeOwners{VolumeHandle: "fcd-2", PVName: "pv-2"},
+		},
+		{
+			volumeHandle:   "fcd-unknown",
+			expectedOwners: nil,
+		},
+	}
+	for _, test := range tests {
+		owners, err := getVolumeOwners(ctx, metadataSyncer, test.volumeHandle)
+		if err != nil {
+			t.Fatalf("failed to get owners of volume %q. Err: %v", test.volumeHandle, err)
+		}
+		if !reflect.DeepEqual(owners, test.expectedOwners) {
+			t.Errorf("volume %q: expected owners %+v, got %+v", test.volumeHandle, test.expectedOwners, owners)
+		}
+	}
+}
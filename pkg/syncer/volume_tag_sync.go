@@ -0,0 +1,141 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	vim25types "github.com/vmware/govmomi/vim25/types"
+	"github.com/vmware/govmomi/vslm"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	cnsvsphere "sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/cns-lib/vsphere"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	k8s "sigs.k8s.io/vsphere-csi-driver/v2/pkg/kubernetes"
+)
+
+// csiSyncVolumeTagsToPVLabels reflects the vSphere tags of the configured
+// categories attached to the FCDs backing the given PVs as labels on the PVs,
+// so that Kubernetes policies can key off vSphere-side classification. Labels
+// of tags detached from the FCD are removed from the PV.
+func csiSyncVolumeTagsToPVLabels(ctx context.Context, metadataSyncer *metadataSyncInformer,
+	vc *cnsvsphere.VirtualCenter, k8sPVs []*v1.PersistentVolume) {
+	log := logger.GetLogger(ctx)
+	categories := getVolumeTagCategories(metadataSyncer.configInfo.Cfg.Global.VolumeTagCategories)
+	if len(categories) == 0 {
+		log.Debug("FullSync: no volume tag categories configured. Skipping volume tag sync.")
+		return
+	}
+	k8sClient, err := k8s.NewClient(ctx)
+	if err != nil {
+		log.Errorf("FullSync: Creating Kubernetes client failed. Err: %v", err)
+		return
+	}
+	objectManager := vslm.NewObjectManager(vc.Client.Client)
+	for _, pv := range k8sPVs {
+		// Tags can only be attached to FCDs, i.e. block volumes.
+		if pv.Spec.CSI == nil || IsMultiAttachAllowed(pv) {
+			continue
+		}
+		tags, err := objectManager.ListAttachedTags(ctx, pv.Spec.CSI.VolumeHandle)
+		if err != nil {
+			log.Warnf("FullSync: failed to list tags attached to volume %q of pv %s. Err: %v",
+				pv.Spec.CSI.VolumeHandle, pv.Name, err)
+			continue
+		}
+		labels, updateRequired := getPVLabelsWithVolumeTags(ctx, pv, tags, categories)
+		if !updateRequired {
+			continue
+		}
+		updatedPV := pv.DeepCopy()
+		updatedPV.Labels = labels
+		_, err = k8sClient.CoreV1().PersistentVolumes().Update(ctx, updatedPV, metav1.UpdateOptions{})
+		if err != nil {
+			log.Errorf("FullSync: failed to update labels of pv %s with volume tags. Err: %v", pv.Name, err)
+			continue
+		}
+		log.Infof("FullSync: updated labels of pv %s with tags of volume %q", pv.Name, pv.Spec.CSI.VolumeHandle)
+	}
+}
+
+// getVolumeTagCategories returns the set of tag categories in the given
+// comma separated list.
+func getVolumeTagCategories(volumeTagCategories string) map[string]bool {
+	categories := make(map[string]bool)
+	for _, category := range strings.Split(volumeTagCategories, ",") {
+		category = strings.TrimSpace(category)
+		if category != "" {
+			categories[category] = true
+		}
+	}
+	return categories
+}
+
+// getPVLabelsWithVolumeTags returns the labels of the PV with the volume tag
+// labels replaced by the given tags of the given categories, and whether they
+// differ from the current labels of the PV. If several tags of a category
+// are attached, the first tag name in lexical order is used. Tags which do
+// not make a valid label are skipped.
+func getPVLabelsWithVolumeTags(ctx context.Context, pv *v1.PersistentVolume, tags []vim25types.VslmTagEntry,
+	categories map[string]bool) (map[string]string, bool) {
+	log := logger.GetLogger(ctx)
+	tagLabels := make(map[string]string)
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].TagName < tags[j].TagName
+	})
+	for _, tag := range tags {
+		if !categories[tag.ParentCategoryName] {
+			continue
+		}
+		key := volumeTagLabelPrefix + tag.ParentCategoryName
+		if _, ok := tagLabels[key]; ok {
+			continue
+		}
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			log.Warnf("tag category %q of pv %s is not a valid label key: %v", tag.ParentCategoryName, pv.Name, errs)
+			continue
+		}
+		if errs := validation.IsValidLabelValue(tag.TagName); len(errs) > 0 {
+			log.Warnf("tag %q of pv %s is not a valid label value: %v", tag.TagName, pv.Name, errs)
+			continue
+		}
+		tagLabels[key] = tag.TagName
+	}
+
+	labels := make(map[string]string)
+	updateRequired := false
+	for key, value := range pv.Labels {
+		if strings.HasPrefix(key, volumeTagLabelPrefix) {
+			if tagValue, ok := tagLabels[key]; !ok || tagValue != value {
+				updateRequired = true
+			}
+			continue
+		}
+		labels[key] = value
+	}
+	for key, value := range tagLabels {
+		if pvValue, ok := pv.Labels[key]; !ok || pvValue != value {
+			updateRequired = true
+		}
+		labels[key] = value
+	}
+	return labels, updateRequired
+}
@@ -0,0 +1,92 @@
+package syncer
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	vim25types "github.com/vmware/govmomi/vim25/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetPVLabelsWithVolumeTags(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	categories := getVolumeTagCategories(" backup-tier, ,cost-center")
+	tests := []struct {
+		name                   string
+		pvLabels               map[string]string
+		tags                   []vim25types.VslmTagEntry
+		expectedLabels         map[string]string
+		expectedUpdateRequired bool
+	}{
+		{
+			name:     "tags of configured categories are added",
+			pvLabels: map[string]string{"app": "db"},
+			tags: []vim25types.VslmTagEntry{
+				{ParentCategoryName: "backup-tier", TagName: "gold"},
+				{ParentCategoryName: "other", TagName: "ignored"},
+			},
+			expectedLabels: map[string]string{
+				"app":                                "db",
+				volumeTagLabelPrefix + "backup-tier": "gold",
+			},
+			expectedUpdateRequired: true,
+		},
+		{
+			name: "unchanged tags do not require update",
+			pvLabels: map[string]string{
+				volumeTagLabelPrefix + "backup-tier": "gold",
+			},
+			tags: []vim25types.VslmTagEntry{
+				{ParentCategoryName: "backup-tier", TagName: "gold"},
+			},
+			expectedLabels: map[string]string{
+				volumeTagLabelPrefix + "backup-tier": "gold",
+			},
+			expectedUpdateRequired: false,
+		},
+		{
+			name: "labels of detached tags are removed",
+			pvLabels: map[string]string{
+				"app":                                "db",
+				volumeTagLabelPrefix + "backup-tier": "gold",
+			},
+			expectedLabels:         map[string]string{"app": "db"},
+			expectedUpdateRequired: true,
+		},
+		{
+			name: "first tag of a category in lexical order is used",
+			tags: []vim25types.VslmTagEntry{
+				{ParentCategoryName: "cost-center", TagName: "team-b"},
+				{ParentCategoryName: "cost-center", TagName: "team-a"},
+			},
+			expectedLabels: map[string]string{
+				volumeTagLabelPrefix + "cost-center": "team-a",
+			},
+			expectedUpdateRequired: true,
+		},
+		{
+			name: "tags which are not valid label values are skipped",
+			tags: []vim25types.VslmTagEntry{
+				{ParentCategoryName: "backup-tier", TagName: "not a label value"},
+			},
+			expectedLabels:         map[string]string{},
+			expectedUpdateRequired: false,
+		},
+	}
+	for _, test := range tests {
+		pv := &v1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pv-1", Labels: test.pvLabels},
+		}
+		labels, updateRequired := getPVLabelsWithVolumeTags(ctx, pv, test.tags, categories)
+		if updateRequired != test.expectedUpdateRequired {
+			t.Errorf("%s: expected updateRequired %t, got %t", test.name, test.expectedUpdateRequired, updateRequired)
+		}
+		if !reflect.DeepEqual(labels, test.expectedLabels) {
+			t.Errorf("%s: expected labels %v, got %v", test.name, test.expectedLabels, labels)
+		}
+	}
+}
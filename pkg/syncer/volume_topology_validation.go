@@ -0,0 +1,233 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	cnstypes "github.com/vmware/govmomi/cns/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	corev1helpers "k8s.io/component-helpers/scheduling/corev1"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/common/prometheus"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/logger"
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+const (
+	// event reason for PVs whose node affinity doesn't match the
+	// accessibility of the datastore of their volume
+	reasonStaleVolumeTopology = "StaleVolumeTopology"
+	// event reason for PVs whose node affinity matches the accessibility of
+	// the datastore of their volume again
+	reasonVolumeTopologyRestored = "VolumeTopologyRestored"
+)
+
+// volumeTopologyChecker is the volumeTopologyValidator used by full sync. It
+// is only set when the TopologyFullSyncValidation feature is enabled.
+var volumeTopologyChecker *volumeTopologyValidator
+
+// volumeTopologyValidator checks during full sync that the node affinity
+// recorded in the PVs of block volumes still matches the accessibility of the
+// datastores of their volumes, which changes when node VMs are migrated with
+// vMotion or clusters are reconfigured. PVs whose node affinity became stale,
+// or valid again, are reported as events on the PV. The number of PVs with a
+// stale node affinity is exposed through prometheus.StaleVolumeTopologyGauge.
+type volumeTopologyValidator struct {
+	k8sClient   clientset.Interface
+	nodeManager nodeVMGetter
+	recorder    record.EventRecorder
+	// stalePVs holds the reasons of the PVs found stale by the last check, by
+	// PV name, so that events are only emitted on changes.
+	stalePVs map[string]string
+}
+
+// newVolumeTopologyValidator returns a volumeTopologyValidator listing Nodes
+// and recording PV events through the given client, and looking up the
+// node VMs through the given node manager.
+func newVolumeTopologyValidator(k8sClient clientset.Interface,
+	nodeManager nodeVMGetter) *volumeTopologyValidator {
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(
+		&typedcorev1.EventSinkImpl{
+			Interface: k8sClient.CoreV1().Events(""),
+		},
+	)
+	return &volumeTopologyValidator{
+		k8sClient:   k8sClient,
+		nodeManager: nodeManager,
+		recorder:    eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: csitypes.Name}),
+		stalePVs:    make(map[string]string),
+	}
+}
+
+// validate checks the node affinity of the given PVs against the
+// accessibility of the datastores of their volumes, as returned by CNS, from
+// the node VMs of the cluster.
+func (c *volumeTopologyValidator) validate(ctx context.Context, k8sPVs []*v1.PersistentVolume,
+	cnsVolumes []cnstypes.CnsVolume) {
+	log := logger.GetLogger(ctx)
+	datastoreURLs := make(map[string]string)
+	for _, volume := range cnsVolumes {
+		if volume.VolumeType == common.BlockVolumeType && volume.DatastoreUrl != "" {
+			datastoreURLs[volume.VolumeId.Id] = volume.DatastoreUrl
+		}
+	}
+	var pvs []*v1.PersistentVolume
+	for _, pv := range k8sPVs {
+		if pv.Spec.CSI == nil || pv.Spec.CSI.Driver != csitypes.Name ||
+			pv.Spec.NodeAffinity == nil || pv.Spec.NodeAffinity.Required == nil {
+			continue
+		}
+		if _, ok := datastoreURLs[pv.Spec.CSI.VolumeHandle]; ok {
+			pvs = append(pvs, pv)
+		}
+	}
+	if len(pvs) == 0 {
+		log.Debug("FullSync: no PV with node affinity. Skipping volume topology validation.")
+		c.reportPVs(ctx, nil, nil)
+		return
+	}
+
+	nodeList, err := c.k8sClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		log.Errorf("FullSync: Failed to list Nodes for volume topology validation. Err: %v", err)
+		return
+	}
+	nodeDatastores := make(map[string]map[string]bool)
+	for _, node := range nodeList.Items {
+		datastores, err := c.getNodeDatastoreURLs(ctx, node.Name)
+		if err != nil {
+			// The node is left out rather than reporting the PVs as stale.
+			log.Warnf("FullSync: failed to get the datastores accessible from node %s. Err: %v", node.Name, err)
+			continue
+		}
+		nodeDatastores[node.Name] = datastores
+	}
+
+	stalePVs := make(map[string]string)
+	for _, pv := range pvs {
+		reason := getStaleVolumeTopologyReason(pv, datastoreURLs[pv.Spec.CSI.VolumeHandle], nodeList.Items,
+			nodeDatastores)
+		if reason != "" {
+			stalePVs[pv.Name] = reason
+		}
+	}
+	c.reportPVs(ctx, pvs, stalePVs)
+}
+
+// getNodeDatastoreURLs returns the set of URLs of the datastores accessible
+// from the node VM of the given node.
+func (c *volumeTopologyValidator) getNodeDatastoreURLs(ctx context.Context,
+	nodeName string) (map[string]bool, error) {
+	nodeVM, err := c.nodeManager.GetNodeByName(ctx, nodeName)
+	if err != nil {
+		return nil, err
+	}
+	datastores, err := nodeVM.GetAllAccessibleDatastores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	urls := make(map[string]bool)
+	for _, datastore := range datastores {
+		urls[datastore.Info.Url] = true
+	}
+	return urls, nil
+}
+
+// reportPVs records events on the given PVs whose node affinity became
+// stale, or valid again, since the last check. stalePVs holds the reasons of
+// the PVs found stale, by PV name.
+func (c *volumeTopologyValidator) reportPVs(ctx context.Context, pvs []*v1.PersistentVolume,
+	stalePVs map[string]string) {
+	log := logger.GetLogger(ctx)
+	for _, pv := range pvs {
+		reason, stale := stalePVs[pv.Name]
+		previous, wasStale := c.stalePVs[pv.Name]
+		if stale {
+			if wasStale && previous == reason {
+				continue
+			}
+			log.Errorf("FullSync: node affinity of pv %s is stale: %s", pv.Name, reason)
+			c.recorder.Eventf(pv, v1.EventTypeWarning, reasonStaleVolumeTopology,
+				"Node affinity of the PV doesn't match the accessibility of volume %q: %s. Pods using the "+
+					"PV may be scheduled on nodes which can't attach the volume", pv.Spec.CSI.VolumeHandle, reason)
+			continue
+		}
+		if wasStale {
+			log.Infof("FullSync: node affinity of pv %s matches the accessibility of its volume again", pv.Name)
+			c.recorder.Eventf(pv, v1.EventTypeNormal, reasonVolumeTopologyRestored,
+				"Node affinity of the PV matches the accessibility of volume %q", pv.Spec.CSI.VolumeHandle)
+		}
+	}
+	if stalePVs == nil {
+		stalePVs = make(map[string]string)
+	}
+	c.stalePVs = stalePVs
+	prometheus.StaleVolumeTopologyGauge.Set(float64(len(stalePVs)))
+}
+
+// getStaleVolumeTopologyReason returns why the node affinity of the given PV
+// doesn't match the accessibility of the given datastore of its volume from
+// the given nodes, or an empty string if it does. The node affinity is stale
+// if nodes matching it can't access the datastore, or if the datastore is
+// only accessible from nodes not matching it. nodeDatastores holds the sets
+// of datastore URLs accessible from the nodes, by node name; nodes missing
+// from it are ignored.
+func getStaleVolumeTopologyReason(pv *v1.PersistentVolume, datastoreURL string, nodes []v1.Node,
+	nodeDatastores map[string]map[string]bool) string {
+	var inaccessibleNodes []string
+	matchingNodes := 0
+	accessibleFromOtherNodes := false
+	for i := range nodes {
+		node := &nodes[i]
+		datastores, ok := nodeDatastores[node.Name]
+		if !ok {
+			continue
+		}
+		matches, err := corev1helpers.MatchNodeSelectorTerms(node, pv.Spec.NodeAffinity.Required)
+		if err != nil {
+			return fmt.Sprintf("invalid node affinity: %v", err)
+		}
+		if matches {
+			matchingNodes++
+			if !datastores[datastoreURL] {
+				inaccessibleNodes = append(inaccessibleNodes, node.Name)
+			}
+		} else if datastores[datastoreURL] {
+			accessibleFromOtherNodes = true
+		}
+	}
+	if len(inaccessibleNodes) > 0 {
+		sort.Strings(inaccessibleNodes)
+		return fmt.Sprintf("datastore %s isn't accessible from nodes %v matching the node affinity",
+			datastoreURL, inaccessibleNodes)
+	}
+	if matchingNodes == 0 && accessibleFromOtherNodes {
+		return fmt.Sprintf("datastore %s is only accessible from nodes not matching the node affinity",
+			datastoreURL)
+	}
+	return ""
+}
@@ -0,0 +1,129 @@
+package syncer
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	csitypes "sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/types"
+)
+
+func newPVWithZoneAffinity(name string, zone string) *v1.PersistentVolume {
+	return &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v1.PersistentVolumeSpec{
+			PersistentVolumeSource: v1.PersistentVolumeSource{
+				CSI: &v1.CSIPersistentVolumeSource{Driver: csitypes.Name, VolumeHandle: name + "-volume"},
+			},
+			NodeAffinity: &v1.VolumeNodeAffinity{
+				Required: &v1.NodeSelector{
+					NodeSelectorTerms: []v1.NodeSelectorTerm{{
+						MatchExpressions: []v1.NodeSelectorRequirement{{
+							Key:      v1.LabelTopologyZone,
+							Operator: v1.NodeSelectorOpIn,
+							Values:   []string{zone},
+						}},
+					}},
+				},
+			},
+		},
+	}
+}
+
+func newNodeInZone(name string, zone string) v1.Node {
+	return v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{v1.LabelTopologyZone: zone}}}
+}
+
+func TestGetStaleVolumeTopologyReason(t *testing.T) {
+	nodes := []v1.Node{
+		newNodeInZone("node-a1", "zone-a"),
+		newNodeInZone("node-a2", "zone-a"),
+		newNodeInZone("node-b1", "zone-b"),
+		newNodeInZone("node-c1", "zone-c"),
+	}
+	nodeDatastores := map[string]map[string]bool{
+		"node-a1": {"ds:///vmfs/volumes/ds-a/": true, "ds:///vmfs/volumes/ds-ab/": true},
+		"node-a2": {"ds:///vmfs/volumes/ds-a/": true},
+		"node-b1": {"ds:///vmfs/volumes/ds-b/": true, "ds:///vmfs/volumes/ds-ab/": true},
+		// The datastores of node-c1 couldn't be retrieved.
+	}
+	tests := []struct {
+		name           string
+		zone           string
+		datastoreURL   string
+		expectedReason string
+	}{
+		{
+			name:         "AccessibleFromAllMatchingNodes",
+			zone:         "zone-a",
+			datastoreURL: "ds:///vmfs/volumes/ds-a/",
+		},
+		{
+			name:           "InaccessibleFromMatchingNodes",
+			zone:           "zone-a",
+			datastoreURL:   "ds:///vmfs/volumes/ds-ab/",
+			expectedReason: "isn't accessible from nodes [node-a2]",
+		},
+		{
+			name:           "OnlyAccessibleFromOtherNodes",
+			zone:           "zone-d",
+			datastoreURL:   "ds:///vmfs/volumes/ds-b/",
+			expectedReason: "is only accessible from nodes not matching",
+		},
+		{
+			name:         "MatchingNodesUnknown",
+			zone:         "zone-c",
+			datastoreURL: "ds:///vmfs/volumes/ds-c/",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pv := newPVWithZoneAffinity("pv-1", test.zone)
+			reason := getStaleVolumeTopologyReason(pv, test.datastoreURL, nodes, nodeDatastores)
+			if test.expectedReason == "" && reason != "" {
+				t.Errorf("expected no stale reason, got %q", reason)
+			}
+			if !strings.Contains(reason, test.expectedReason) {
+				t.Errorf("expected stale reason containing %q, got %q", test.expectedReason, reason)
+			}
+		})
+	}
+}
+
+func TestVolumeTopologyReportPVs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pvs := []*v1.PersistentVolume{newPVWithZoneAffinity("pv-1", "zone-a"), newPVWithZoneAffinity("pv-2", "zone-a")}
+	recorder := record.NewFakeRecorder(10)
+	validator := &volumeTopologyValidator{recorder: recorder, stalePVs: make(map[string]string)}
+
+	validator.reportPVs(ctx, pvs, map[string]string{"pv-2": "datastore ds-a is stale"})
+	if len(recorder.Events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(recorder.Events))
+	}
+	if event := <-recorder.Events; !strings.HasPrefix(event, "Warning "+reasonStaleVolumeTopology+
+		" Node affinity of the PV doesn't match the accessibility of volume \"pv-2-volume\"") {
+		t.Errorf("unexpected event %q", event)
+	}
+
+	// No event is emitted while the node affinity stays stale.
+	validator.reportPVs(ctx, pvs, map[string]string{"pv-2": "datastore ds-a is stale"})
+	if len(recorder.Events) != 0 {
+		t.Errorf("expected no event, got %d", len(recorder.Events))
+	}
+
+	validator.reportPVs(ctx, pvs, map[string]string{})
+	if len(validator.stalePVs) != 0 {
+		t.Errorf("expected no stale PV, got %v", validator.stalePVs)
+	}
+	expectedEvent := "Normal " + reasonVolumeTopologyRestored +
+		" Node affinity of the PV matches the accessibility of volume \"pv-2-volume\""
+	if event := <-recorder.Events; event != expectedEvent {
+		t.Errorf("expected event %q, got %q", expectedEvent, event)
+	}
+}
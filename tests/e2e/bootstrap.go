@@ -43,6 +43,8 @@ func bootstrap(withoutDc ...bool) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	connect(ctx, &e2eVSphere)
+	err = initInfraOperations()
+	gomega.Expect(err).NotTo(gomega.HaveOccurred())
 	if framework.TestContext.RepoRoot != "" {
 		testfiles.AddFileSource(testfiles.RootFileSource{Root: framework.TestContext.RepoRoot})
 	}
@@ -0,0 +1,247 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	snapV1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
+	snapclient "github.com/kubernetes-csi/external-snapshotter/client/v4/clientset/versioned"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25/mo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/kubernetes/test/e2e/framework"
+	fnodes "k8s.io/kubernetes/test/e2e/framework/node"
+	fpod "k8s.io/kubernetes/test/e2e/framework/pod"
+	fpv "k8s.io/kubernetes/test/e2e/framework/pv"
+
+	"sigs.k8s.io/vsphere-csi-driver/v2/pkg/csi/service/common"
+)
+
+/*
+	Compatibility matrix probe.
+
+	Runs a minimal conformance suite against the vCenter of the testbed and
+	records the features found working or failing for its vCenter and ESX
+	versions in the compatibility report at COMPATIBILITY_REPORT_PATH. The
+	results of other versions already in the report are kept, so that running
+	the suite against each testbed of a matrix builds the report of the
+	matrix. The report can be given to the controller through the
+	VSPHERE_CSI_COMPATIBILITY_REPORT env variable, to not report capabilities
+	of features found failing on its vCenter version.
+
+	Steps
+	1. Create a StorageClass and a PVC, and wait for the volume to be created in CNS.
+	2. Create a pod using the PVC, verify the volume is attached to the node,
+	   then delete the pod and verify the volume is detached.
+	3. Expand the PVC and wait for the PV to be resized.
+	4. Create a snapshot of the PVC, wait for it to be ready to use and delete it.
+	5. Delete the PVC and wait for the volume to be deleted from CNS.
+	6. Record the outcome of each step in the compatibility report.
+*/
+
+var _ = ginkgo.Describe("[csi-compatibility-matrix] Compatibility Matrix Probe", func() {
+	f := framework.NewDefaultFramework("compatibility-matrix")
+	var (
+		client       clientset.Interface
+		namespace    string
+		reportPath   string
+		datastoreURL string
+	)
+
+	ginkgo.BeforeEach(func() {
+		bootstrap()
+		client = f.ClientSet
+		namespace = getNamespaceToRunTests(f)
+		reportPath = GetAndExpectStringEnvVar(envCompatibilityReportPath)
+		datastoreURL = GetAndExpectStringEnvVar(envSharedDatastoreURL)
+		nodeList, err := fnodes.GetReadySchedulableNodes(f.ClientSet)
+		framework.ExpectNoError(err, "Unable to find ready and schedulable Node")
+		if !(len(nodeList.Items) > 0) {
+			framework.Failf("Unable to find ready and schedulable Node")
+		}
+	})
+
+	ginkgo.It("Probe block volume features and record them in the compatibility report", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		about := e2eVSphere.Client.ServiceContent.About
+		result := common.CompatibilityResult{
+			VCenterVersion: about.Version,
+			VCenterBuild:   about.Build,
+			ESXVersions:    getESXVersions(ctx),
+			Features:       make(map[string]common.FeatureCompatibility),
+		}
+		probe := func(feature string, check func() error) bool {
+			ginkgo.By(fmt.Sprintf("Probing %q on vCenter %s build %s", feature, about.Version, about.Build))
+			if err := check(); err != nil {
+				framework.Logf("Feature %q is failing on vCenter %s. Err: %v", feature, about.Version, err)
+				result.Features[feature] = common.FeatureCompatibility{Error: err.Error()}
+				return false
+			}
+			result.Features[feature] = common.FeatureCompatibility{Working: true}
+			return true
+		}
+		// The report is written even if the probe is interrupted, with the
+		// features probed so far.
+		defer func() {
+			ginkgo.By(fmt.Sprintf("Recording the compatibility of vCenter %s in %s", about.Version, reportPath))
+			report, err := common.LoadCompatibilityReport(reportPath)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			report.SetResult(result)
+			gomega.Expect(report.Write(reportPath)).To(gomega.Succeed())
+		}()
+
+		scParameters := map[string]string{scParamDatastoreURL: datastoreURL}
+		storageclass, err := client.StorageV1().StorageClasses().Create(ctx,
+			getVSphereStorageClassSpec("", scParameters, nil, "", "", true), metav1.CreateOptions{})
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		defer func() {
+			err := client.StorageV1().StorageClasses().Delete(ctx, storageclass.Name, *metav1.NewDeleteOptions(0))
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		}()
+
+		var pvclaim *v1.PersistentVolumeClaim
+		var pv *v1.PersistentVolume
+		created := probe(common.CompatibilityFeatureCreateDeleteVolume, func() error {
+			var err error
+			pvclaim, err = client.CoreV1().PersistentVolumeClaims(namespace).Create(ctx,
+				getPersistentVolumeClaimSpecWithStorageClass(namespace, diskSize, storageclass, nil, ""),
+				metav1.CreateOptions{})
+			if err != nil {
+				return err
+			}
+			pvs, err := fpv.WaitForPVClaimBoundPhase(client, []*v1.PersistentVolumeClaim{pvclaim},
+				framework.ClaimProvisionTimeout)
+			if err != nil {
+				return err
+			}
+			pv = pvs[0]
+			return e2eVSphere.waitForCNSVolumeToBeCreated(pv.Spec.CSI.VolumeHandle)
+		})
+		if !created {
+			if pvclaim != nil {
+				err := fpv.DeletePersistentVolumeClaim(client, pvclaim.Name, namespace)
+				gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			}
+			return
+		}
+		volHandle := pv.Spec.CSI.VolumeHandle
+
+		probe(common.CompatibilityFeatureAttachDetachVolume, func() error {
+			pod, err := createPod(client, namespace, nil, []*v1.PersistentVolumeClaim{pvclaim}, false, "")
+			if err != nil {
+				return err
+			}
+			vmUUID := getNodeUUID(ctx, client, pod.Spec.NodeName)
+			isAttached, err := e2eVSphere.isVolumeAttachedToVM(client, volHandle, vmUUID)
+			if err == nil && !isAttached {
+				err = fmt.Errorf("volume %q is not attached to the node %q", volHandle, pod.Spec.NodeName)
+			}
+			if deleteErr := fpod.DeletePodWithWait(client, pod); err == nil {
+				err = deleteErr
+			}
+			if err != nil {
+				return err
+			}
+			isDetached, err := e2eVSphere.waitForVolumeDetachedFromNode(client, volHandle, pod.Spec.NodeName)
+			if err == nil && !isDetached {
+				err = fmt.Errorf("volume %q is not detached from the node %q", volHandle, pod.Spec.NodeName)
+			}
+			return err
+		})
+
+		probe(common.CompatibilityFeatureExpandVolume, func() error {
+			newSize := pvclaim.Spec.Resources.Requests[v1.ResourceStorage]
+			newSize.Add(resource.MustParse("1Gi"))
+			if _, err := expandPVCSize(pvclaim, newSize, client); err != nil {
+				return err
+			}
+			return waitForPvResize(pv, client, newSize, totalResizeWaitPeriod)
+		})
+
+		probe(common.CompatibilityFeatureVolumeSnapshot, func() error {
+			snapc, err := snapclient.NewForConfig(getRestConfigClient())
+			if err != nil {
+				return err
+			}
+			volumeSnapshotClass, err := snapc.SnapshotV1().VolumeSnapshotClasses().Create(ctx,
+				getVolumeSnapshotClassSpec(snapV1.DeletionPolicy("Delete"), nil), metav1.CreateOptions{})
+			if err != nil {
+				return err
+			}
+			defer func() {
+				err := snapc.SnapshotV1().VolumeSnapshotClasses().Delete(ctx, volumeSnapshotClass.Name,
+					metav1.DeleteOptions{})
+				gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			}()
+			volumeSnapshot, err := snapc.SnapshotV1().VolumeSnapshots(namespace).Create(ctx,
+				getVolumeSnapshotSpec(namespace, volumeSnapshotClass.Name, pvclaim.Name), metav1.CreateOptions{})
+			if err != nil {
+				return err
+			}
+			_, err = waitForVolumeSnapshotReadyToUse(*snapc, ctx, namespace, volumeSnapshot.Name)
+			if deleteErr := snapc.SnapshotV1().VolumeSnapshots(namespace).Delete(ctx, volumeSnapshot.Name,
+				metav1.DeleteOptions{}); err == nil {
+				err = deleteErr
+			}
+			return err
+		})
+
+		// Deleting the volume completes the probe of provisioning.
+		probe(common.CompatibilityFeatureCreateDeleteVolume, func() error {
+			if err := fpv.DeletePersistentVolumeClaim(client, pvclaim.Name, namespace); err != nil {
+				return err
+			}
+			return e2eVSphere.waitForCNSVolumeToBeDeleted(volHandle)
+		})
+	})
+})
+
+// getESXVersions returns the sorted, distinct versions of the ESX hosts of
+// the vCenter.
+func getESXVersions(ctx context.Context) []string {
+	m := view.NewManager(e2eVSphere.Client.Client)
+	v, err := m.CreateContainerView(ctx, e2eVSphere.Client.ServiceContent.RootFolder, []string{"HostSystem"}, true)
+	gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	defer func() {
+		_ = v.Destroy(ctx)
+	}()
+	var hosts []mo.HostSystem
+	err = v.Retrieve(ctx, []string{"HostSystem"}, []string{"summary.config.product"}, &hosts)
+	gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	versions := make(map[string]bool)
+	for _, host := range hosts {
+		if host.Summary.Config.Product != nil {
+			versions[host.Summary.Config.Product.Version] = true
+		}
+	}
+	var esxVersions []string
+	for version := range versions {
+		esxVersions = append(esxVersions, version)
+	}
+	sort.Strings(esxVersions)
+	return esxVersions
+}
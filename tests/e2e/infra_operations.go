@@ -0,0 +1,283 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vmware/govmomi/object"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/kubernetes/test/e2e/framework"
+	fssh "k8s.io/kubernetes/test/e2e/framework/ssh"
+)
+
+const (
+	// infraOperationsTestbed manipulates vCenter and ESX hosts over SSH.
+	infraOperationsTestbed = "testbed"
+	// infraOperationsSimulator emulates the manipulation of vCenter and ESX
+	// hosts, e.g. for vcsim based environments where SSH isn't available.
+	infraOperationsSimulator = "simulator"
+)
+
+// infraOperations manipulates the vCenter and ESX hosts of the test
+// environment. Destructive tests should go through infraOps rather than SSH
+// into vCenter or ESX hosts directly, so that they can run against simulated
+// environments as well.
+type infraOperations interface {
+	// vCenterServiceControl invokes the given service-control command
+	// (start, stop, restart) for the given service on the vCenter host.
+	vCenterServiceControl(command, service, host string) error
+	// waitVCenterServiceState waits for the given vCenter service to be in
+	// the given state.
+	waitVCenterServiceState(service, host, state string) error
+	// rebootVCenter reboots the vCenter host.
+	rebootVCenter(host string) error
+	// stopHostD stops hostd on the given ESX host and waits for the host to
+	// stop responding.
+	stopHostD(ctx context.Context, addr string) error
+	// startHostD starts hostd on the given ESX host and waits for the host
+	// to be connected.
+	startHostD(ctx context.Context, addr string) error
+	// psodHost crashes the given ESX host. The host comes back after
+	// psodTime seconds.
+	psodHost(ctx context.Context, addr string) error
+}
+
+// infraOps is the infraOperations of the test environment, selected by the
+// INFRA_OPERATIONS environment variable.
+var infraOps infraOperations = &testbedInfraOperations{}
+
+// initInfraOperations sets infraOps according to the INFRA_OPERATIONS
+// environment variable. Real testbeds are assumed if it isn't set.
+func initInfraOperations() error {
+	switch mode := os.Getenv(envInfraOperations); mode {
+	case "", infraOperationsTestbed:
+		infraOps = &testbedInfraOperations{}
+	case infraOperationsSimulator:
+		infraOps = newSimulatedInfraOperations()
+	default:
+		return fmt.Errorf("unknown %s value %q, expected %q or %q", envInfraOperations, mode,
+			infraOperationsTestbed, infraOperationsSimulator)
+	}
+	return nil
+}
+
+// testbedInfraOperations manipulates real vCenter and ESX hosts over SSH.
+type testbedInfraOperations struct{}
+
+func (t *testbedInfraOperations) vCenterServiceControl(command, service, host string) error {
+	sshCmd := fmt.Sprintf("service-control --%s %s", command, service)
+	framework.Logf("Invoking command %v on vCenter host %v", sshCmd, host)
+	result, err := fssh.SSH(sshCmd, host, framework.TestContext.Provider)
+	if err != nil || result.Code != 0 {
+		fssh.LogResult(result)
+		return fmt.Errorf("couldn't execute command: %s on vCenter host: %v", sshCmd, err)
+	}
+	return nil
+}
+
+func (t *testbedInfraOperations) waitVCenterServiceState(service, host, state string) error {
+	return wait.PollImmediate(poll, pollTimeoutShort, func() (bool, error) {
+		sshCmd := fmt.Sprintf("service-control --%s %s", "status", service)
+		framework.Logf("Invoking command %v on vCenter host %v", sshCmd, host)
+		result, err := fssh.SSH(sshCmd, host, framework.TestContext.Provider)
+
+		if err != nil || result.Code != 0 {
+			fssh.LogResult(result)
+			return false, fmt.Errorf("couldn't execute command: %s on vCenter host: %v", sshCmd, err)
+		}
+		if strings.Contains(result.Stdout, state) {
+			fssh.LogResult(result)
+			framework.Logf("Found service %v in %v state", service, state)
+			return true, nil
+		}
+		framework.Logf("Command %v output is %v", sshCmd, result.Stdout)
+		return false, nil
+	})
+}
+
+func (t *testbedInfraOperations) rebootVCenter(host string) error {
+	sshCmd := "reboot"
+	framework.Logf("Invoking command %v on vCenter host %v", sshCmd, host)
+	result, err := fssh.SSH(sshCmd, host, framework.TestContext.Provider)
+	if err != nil || result.Code != 0 {
+		fssh.LogResult(result)
+		return fmt.Errorf("couldn't execute command: %s on vCenter host: %v", sshCmd, err)
+	}
+	return nil
+}
+
+func (t *testbedInfraOperations) stopHostD(ctx context.Context, addr string) error {
+	return t.hostDServiceControl(ctx, addr, stopOperation, "notResponding", "hostd is not running.")
+}
+
+func (t *testbedInfraOperations) startHostD(ctx context.Context, addr string) error {
+	return t.hostDServiceControl(ctx, addr, startOperation, "connected", "hostd is running.")
+}
+
+// hostDServiceControl runs the given hostd operation on the given ESX host,
+// then waits for the host to be in the given connection state and checks
+// the hostd status.
+func (t *testbedInfraOperations) hostDServiceControl(ctx context.Context, addr, operation, connectionState,
+	expectedStatus string) error {
+	_, err := runCommandOnESX("root", addr, fmt.Sprintf("/etc/init.d/hostd %s", operation))
+	if err != nil {
+		return err
+	}
+	if err = waitForHostConnectionState(ctx, addr, connectionState); err != nil {
+		return err
+	}
+	output, err := runCommandOnESX("root", addr, fmt.Sprintf("/etc/init.d/hostd %s", statusOperation))
+	framework.Logf("hostd status command output is : " + output)
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(output, expectedStatus) {
+		return fmt.Errorf("unexpected hostd status on host %s: %s", addr, output)
+	}
+	return nil
+}
+
+func (t *testbedInfraOperations) psodHost(ctx context.Context, addr string) error {
+	sshCmd := fmt.Sprintf("vsish -e set /config/Misc/intOpts/BlueScreenTimeout %s", psodTime)
+	op, err := runCommandOnESX("root", addr, sshCmd)
+	framework.Logf(op)
+	if err != nil {
+		return err
+	}
+	op, err = runCommandOnESX("root", addr, "vsish -e set /reliability/crashMe/Panic 1")
+	framework.Logf(op)
+	return err
+}
+
+// simulatedInfraOperations emulates the manipulation of vCenter and ESX
+// hosts. vCenter services are tracked in memory, and ESX hosts going down
+// are emulated by disconnecting them through the vSphere API.
+type simulatedInfraOperations struct {
+	lock sync.Mutex
+	// stoppedServices holds the vCenter services stopped by the tests.
+	stoppedServices map[string]bool
+}
+
+func newSimulatedInfraOperations() *simulatedInfraOperations {
+	return &simulatedInfraOperations{stoppedServices: make(map[string]bool)}
+}
+
+func (s *simulatedInfraOperations) vCenterServiceControl(command, service, host string) error {
+	framework.Logf("Simulating service-control --%s %s on vCenter host %v", command, service, host)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	switch command {
+	case stopOperation:
+		s.stoppedServices[service] = true
+	case startOperation, "restart":
+		delete(s.stoppedServices, service)
+	default:
+		return fmt.Errorf("unsupported service-control command %q", command)
+	}
+	return nil
+}
+
+func (s *simulatedInfraOperations) waitVCenterServiceState(service, host, state string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	current := svcRunningMessage
+	if s.stoppedServices[service] {
+		current = svcStoppedMessage
+	}
+	if !strings.Contains(current, state) {
+		return fmt.Errorf("service %s on vCenter host %s is %q, expected %q", service, host, current, state)
+	}
+	return nil
+}
+
+func (s *simulatedInfraOperations) rebootVCenter(host string) error {
+	framework.Logf("Simulating reboot of vCenter host %v", host)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.stoppedServices = make(map[string]bool)
+	return nil
+}
+
+func (s *simulatedInfraOperations) stopHostD(ctx context.Context, addr string) error {
+	host, err := findClusterHost(ctx, addr)
+	if err != nil {
+		return err
+	}
+	framework.Logf("Simulating hostd stop on host %s by disconnecting it", addr)
+	task, err := host.Disconnect(ctx)
+	if err != nil {
+		return err
+	}
+	if err = task.Wait(ctx); err != nil {
+		return err
+	}
+	return waitForHostConnectionState(ctx, addr, "disconnected")
+}
+
+func (s *simulatedInfraOperations) startHostD(ctx context.Context, addr string) error {
+	host, err := findClusterHost(ctx, addr)
+	if err != nil {
+		return err
+	}
+	framework.Logf("Simulating hostd start on host %s by reconnecting it", addr)
+	task, err := host.Reconnect(ctx, nil, nil)
+	if err != nil {
+		return err
+	}
+	if err = task.Wait(ctx); err != nil {
+		return err
+	}
+	return waitForHostConnectionState(ctx, addr, "connected")
+}
+
+func (s *simulatedInfraOperations) psodHost(ctx context.Context, addr string) error {
+	if err := s.stopHostD(ctx, addr); err != nil {
+		return err
+	}
+	timeout, err := strconv.Atoi(psodTime)
+	if err != nil {
+		return err
+	}
+	// The host comes back once the PSOD timeout expires, as real hosts do.
+	time.AfterFunc(time.Duration(timeout)*time.Second, func() {
+		if err := s.startHostD(context.Background(), addr); err != nil {
+			framework.Logf("Failed to bring back host %s after simulated PSOD: %v", addr, err)
+		}
+	})
+	return nil
+}
+
+// findClusterHost returns the host with the given name in the vSAN cluster.
+func findClusterHost(ctx context.Context, addr string) (*object.HostSystem, error) {
+	hosts, err := e2eVSphere.getVsanClusterResource(ctx).Hosts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, host := range hosts {
+		if host.Name() == addr {
+			return host, nil
+		}
+	}
+	return nil, fmt.Errorf("host not found %s", addr)
+}
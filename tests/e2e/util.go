@@ -1227,27 +1227,13 @@ func getPersistentVolumeSpecForRWX(fcdID string, persistentVolumeReclaimPolicy v
 
 // invokeVCenterReboot invokes reboot command on the given vCenter over SSH.
 func invokeVCenterReboot(host string) error {
-	sshCmd := "reboot"
-	framework.Logf("Invoking command %v on vCenter host %v", sshCmd, host)
-	result, err := fssh.SSH(sshCmd, host, framework.TestContext.Provider)
-	if err != nil || result.Code != 0 {
-		fssh.LogResult(result)
-		return fmt.Errorf("couldn't execute command: %s on vCenter host: %v", sshCmd, err)
-	}
-	return nil
+	return infraOps.rebootVCenter(host)
 }
 
 // invokeVCenterServiceControl invokes the given command for the given service
 // via service-control on the given vCenter host over SSH.
 func invokeVCenterServiceControl(command, service, host string) error {
-	sshCmd := fmt.Sprintf("service-control --%s %s", command, service)
-	framework.Logf("Invoking command %v on vCenter host %v", sshCmd, host)
-	result, err := fssh.SSH(sshCmd, host, framework.TestContext.Provider)
-	if err != nil || result.Code != 0 {
-		fssh.LogResult(result)
-		return fmt.Errorf("couldn't execute command: %s on vCenter host: %v", sshCmd, err)
-	}
-	return nil
+	return infraOps.vCenterServiceControl(command, service, host)
 }
 
 // isFssEnabled invokes the given command to check if vCenter
@@ -1269,24 +1255,7 @@ func isFssEnabled(host, fss string) bool {
 // waitVCenterServiceToBeInState invokes the status check for the given service and waits
 // via service-control on the given vCenter host over SSH.
 func waitVCenterServiceToBeInState(serviceName string, host string, state string) error {
-	waitErr := wait.PollImmediate(poll, pollTimeoutShort, func() (bool, error) {
-		sshCmd := fmt.Sprintf("service-control --%s %s", "status", serviceName)
-		framework.Logf("Invoking command %v on vCenter host %v", sshCmd, host)
-		result, err := fssh.SSH(sshCmd, host, framework.TestContext.Provider)
-
-		if err != nil || result.Code != 0 {
-			fssh.LogResult(result)
-			return false, fmt.Errorf("couldn't execute command: %s on vCenter host: %v", sshCmd, err)
-		}
-		if strings.Contains(result.Stdout, state) {
-			fssh.LogResult(result)
-			framework.Logf("Found service %v in %v state", serviceName, state)
-			return true, nil
-		}
-		framework.Logf("Command %v output is %v", sshCmd, result.Stdout)
-		return false, nil
-	})
-	return waitErr
+	return infraOps.waitVCenterServiceState(serviceName, host, state)
 }
 
 // checkVcenterServicesStatus checks and polls for vCenter essential services status
@@ -3048,16 +3017,8 @@ func psodHostWithPv(ctx context.Context, vs *vSphere, pvName string) string {
 	framework.Logf("hostIP %v", hostIP)
 	gomega.Expect(hostIP).NotTo(gomega.BeEmpty())
 
-	ginkgo.By("PSOD")
-	sshCmd := fmt.Sprintf("vsish -e set /config/Misc/intOpts/BlueScreenTimeout %s", psodTime)
-	op, err := runCommandOnESX("root", hostIP, sshCmd)
-	framework.Logf(op)
-	gomega.Expect(err).NotTo(gomega.HaveOccurred())
-
-	ginkgo.By("Injecting PSOD ")
-	psodCmd := "vsish -e set /reliability/crashMe/Panic 1"
-	op, err = runCommandOnESX("root", hostIP, psodCmd)
-	framework.Logf(op)
+	ginkgo.By("Injecting PSOD")
+	err := infraOps.psodHost(ctx, hostIP)
 	gomega.Expect(err).ShouldNot(gomega.HaveOccurred())
 
 	return hostIP
@@ -3175,39 +3136,15 @@ func runCommandOnESX(username string, addr string, cmd string) (string, error) {
 // stopHostDOnHost executes hostd stop service commands on the given ESX host
 func stopHostDOnHost(ctx context.Context, addr string) {
 	framework.Logf("Stopping hostd service on the host  %s ...", addr)
-	stopHostCmd := fmt.Sprintf("/etc/init.d/hostd %s", stopOperation)
-	_, err := runCommandOnESX("root", addr, stopHostCmd)
+	err := infraOps.stopHostD(ctx, addr)
 	gomega.Expect(err).NotTo(gomega.HaveOccurred())
-
-	err = waitForHostConnectionState(ctx, addr, "notResponding")
-	gomega.Expect(err).NotTo(gomega.HaveOccurred())
-
-	output := getHostDStatusOnHost(addr)
-	gomega.Expect(strings.Contains(output, "hostd is not running.")).NotTo(gomega.BeFalse())
 }
 
 // startHostDOnHost executes hostd start service commands on the given ESX host
 func startHostDOnHost(ctx context.Context, addr string) {
 	framework.Logf("Starting hostd service on the host  %s ...", addr)
-	startHostDCmd := fmt.Sprintf("/etc/init.d/hostd %s", startOperation)
-	_, err := runCommandOnESX("root", addr, startHostDCmd)
-	gomega.Expect(err).NotTo(gomega.HaveOccurred())
-
-	err = waitForHostConnectionState(ctx, addr, "connected")
+	err := infraOps.startHostD(ctx, addr)
 	gomega.Expect(err).NotTo(gomega.HaveOccurred())
-
-	output := getHostDStatusOnHost(addr)
-	gomega.Expect(strings.Contains(output, "hostd is running.")).NotTo(gomega.BeFalse())
-}
-
-// getHostDStatusOnHost executes hostd status service commands on the given ESX host
-func getHostDStatusOnHost(addr string) string {
-	framework.Logf("Running status check on hostd service for the host  %s ...", addr)
-	statusHostDCmd := fmt.Sprintf("/etc/init.d/hostd %s", statusOperation)
-	output, err := runCommandOnESX("root", addr, statusHostDCmd)
-	framework.Logf("hostd status command output is : " + output)
-	gomega.Expect(err).NotTo(gomega.HaveOccurred())
-	return output
 }
 
 // getPersistentVolumeSpecWithStorageclass is to create PV volume spec with